@@ -0,0 +1,80 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// MergeUnstructured merges changes into an apply configuration extracted for a
+// field manager, for example by UnstructuredExtractor.Extract, and returns the
+// result. extracted is not modified. This supports the extract/modify/apply
+// workflow: the result holds the fields the manager already owns, updated with
+// changes, so applying it neither drops the manager's other fields nor takes
+// ownership of fields managed by others.
+//
+// Maps are merged recursively. Any other value in changes, including lists,
+// replaces the extracted value as a whole. A field set to null in changes is
+// removed from the result, releasing its ownership when the result is applied.
+func MergeUnstructured(extracted, changes *unstructured.Unstructured) *unstructured.Unstructured {
+	result := extracted.DeepCopy()
+	if result.Object == nil {
+		result.Object = map[string]interface{}{}
+	}
+	mergeFields(result.Object, runtime.DeepCopyJSON(changes.Object))
+	return result
+}
+
+// MergeInto merges changes into extracted, which are typed apply configurations
+// such as the ones returned by the generated Extract functions, with the same
+// semantics as MergeUnstructured. extracted must be a pointer and is updated
+// in place. changes may be of any type that serializes to the same fields,
+// for example an apply configuration built with the generated With functions.
+func MergeInto(extracted, changes interface{}) error {
+	extractedMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(extracted)
+	if err != nil {
+		return fmt.Errorf("failed to convert extracted configuration: %v", err)
+	}
+	changesMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(changes)
+	if err != nil {
+		return fmt.Errorf("failed to convert changes: %v", err)
+	}
+	mergeFields(extractedMap, changesMap)
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(extractedMap, extracted); err != nil {
+		return fmt.Errorf("failed to convert merged configuration: %v", err)
+	}
+	return nil
+}
+
+func mergeFields(dst, src map[string]interface{}) {
+	for key, value := range src {
+		if value == nil {
+			delete(dst, key)
+			continue
+		}
+		srcMap, srcIsMap := value.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			mergeFields(dstMap, srcMap)
+			continue
+		}
+		dst[key] = value
+	}
+}
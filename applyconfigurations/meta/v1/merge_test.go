@@ -0,0 +1,82 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1_test
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	corev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	metav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+)
+
+func TestMergeUnstructured(t *testing.T) {
+	extracted := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":   "cm",
+			"labels": map[string]interface{}{"a": "1", "b": "2"},
+		},
+		"data": map[string]interface{}{"x": "1"},
+	}}
+	changes := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{"b": nil, "c": "3"},
+		},
+		"data": map[string]interface{}{"y": "2"},
+	}}
+
+	merged := metav1.MergeUnstructured(extracted, changes)
+	expected := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":   "cm",
+			"labels": map[string]interface{}{"a": "1", "c": "3"},
+		},
+		"data": map[string]interface{}{"x": "1", "y": "2"},
+	}
+	if !reflect.DeepEqual(merged.Object, expected) {
+		t.Errorf("expected %#v, got %#v", expected, merged.Object)
+	}
+	if _, ok := extracted.GetLabels()["b"]; !ok {
+		t.Errorf("expected extracted configuration to be left unmodified")
+	}
+}
+
+func TestMergeInto(t *testing.T) {
+	extracted := corev1.ConfigMap("cm", "ns").
+		WithLabels(map[string]string{"a": "1"}).
+		WithData(map[string]string{"x": "1"})
+	changes := corev1.ConfigMap("cm", "ns").
+		WithData(map[string]string{"y": "2"})
+
+	if err := metav1.MergeInto(extracted, changes); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(extracted.Labels, map[string]string{"a": "1"}) {
+		t.Errorf("unexpected labels: %v", extracted.Labels)
+	}
+	if !reflect.DeepEqual(extracted.Data, map[string]string{"x": "1", "y": "2"}) {
+		t.Errorf("unexpected data: %v", extracted.Data)
+	}
+	if *extracted.Name != "cm" || *extracted.Kind != "ConfigMap" {
+		t.Errorf("unexpected identity: %v %v", *extracted.Name, *extracted.Kind)
+	}
+}
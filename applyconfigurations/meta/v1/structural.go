@@ -0,0 +1,353 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/managedfields"
+	"k8s.io/client-go/applyconfigurations/internal"
+	smdschema "sigs.k8s.io/structured-merge-diff/v4/schema"
+	"sigs.k8s.io/structured-merge-diff/v4/typed"
+)
+
+const (
+	objectMetaType = "io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"
+	deducedType    = "__untyped_deduced_"
+)
+
+// StructuralSchema is a structural schema, such as the openAPIV3Schema of a
+// version of a CustomResourceDefinition. Only the fields describing the
+// structure of objects and how they merge are kept, so the schema of a
+// CustomResourceDefinition can be unmarshalled into it from JSON or YAML.
+type StructuralSchema struct {
+	Type                 string                      `json:"type,omitempty"`
+	Properties           map[string]StructuralSchema `json:"properties,omitempty"`
+	AdditionalProperties *StructuralSchema           `json:"additionalProperties,omitempty"`
+	Items                *StructuralSchema           `json:"items,omitempty"`
+
+	XPreserveUnknownFields *bool    `json:"x-kubernetes-preserve-unknown-fields,omitempty"`
+	XEmbeddedResource      bool     `json:"x-kubernetes-embedded-resource,omitempty"`
+	XIntOrString           bool     `json:"x-kubernetes-int-or-string,omitempty"`
+	XListType              *string  `json:"x-kubernetes-list-type,omitempty"`
+	XListMapKeys           []string `json:"x-kubernetes-list-map-keys,omitempty"`
+	XMapType               *string  `json:"x-kubernetes-map-type,omitempty"`
+}
+
+// UnstructuredBuilder builds and extracts the apply configurations of a kind
+// described by a structural schema, for kinds without generated apply
+// configurations such as custom resources. The apply configurations are
+// unstructured objects which are validated against the schema, and merge
+// the way the apiserver merges them.
+type UnstructuredBuilder struct {
+	gvk        schema.GroupVersionKind
+	objectType typed.ParseableType
+}
+
+// NewUnstructuredBuilder creates the builder of the apply configurations of
+// kind gvk, whose objects are described by s. The metadata of the objects,
+// and of their embedded resources, is described by ObjectMeta whatever s
+// says.
+func NewUnstructuredBuilder(gvk schema.GroupVersionKind, s *StructuralSchema) (*UnstructuredBuilder, error) {
+	root, err := s.atom(true)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema of %s: %v", gvk, err)
+	}
+	if root.Map == nil {
+		return nil, fmt.Errorf("invalid schema of %s: objects must be of type object", gvk)
+	}
+
+	builtin := internal.Parser().Schema.Types
+	types := make([]smdschema.TypeDef, len(builtin), len(builtin)+1)
+	copy(types, builtin)
+	name := gvk.String()
+	types = append(types, smdschema.TypeDef{Name: name, Atom: root})
+	return &UnstructuredBuilder{
+		gvk: gvk,
+		objectType: typed.ParseableType{
+			Schema:  &smdschema.Schema{Types: types},
+			TypeRef: smdschema.TypeRef{NamedType: &name},
+		},
+	}, nil
+}
+
+// atom converts s to the type of its values in the merge schema.
+func (s *StructuralSchema) atom(isResource bool) (smdschema.Atom, error) {
+	if s.XIntOrString {
+		untyped := smdschema.Scalar("untyped")
+		return smdschema.Atom{Scalar: &untyped}, nil
+	}
+
+	switch s.Type {
+	case "string":
+		return smdschema.Atom{Scalar: ptrScalar(smdschema.String)}, nil
+	case "integer", "number":
+		return smdschema.Atom{Scalar: ptrScalar(smdschema.Numeric)}, nil
+	case "boolean":
+		return smdschema.Atom{Scalar: ptrScalar(smdschema.Boolean)}, nil
+
+	case "array":
+		if s.Items == nil {
+			return smdschema.Atom{}, fmt.Errorf("arrays must have items")
+		}
+		items, err := s.Items.typeRef()
+		if err != nil {
+			return smdschema.Atom{}, fmt.Errorf("items: %v", err)
+		}
+		list := &smdschema.List{ElementType: items, ElementRelationship: smdschema.Atomic}
+		if s.XListType != nil {
+			switch *s.XListType {
+			case "atomic":
+			case "set":
+				list.ElementRelationship = smdschema.Associative
+			case "map":
+				if len(s.XListMapKeys) == 0 {
+					return smdschema.Atom{}, fmt.Errorf("lists of type map must have keys")
+				}
+				list.ElementRelationship = smdschema.Associative
+				list.Keys = s.XListMapKeys
+			default:
+				return smdschema.Atom{}, fmt.Errorf("unknown list type %q", *s.XListType)
+			}
+		}
+		return smdschema.Atom{List: list}, nil
+
+	case "object", "":
+		isResource = isResource || s.XEmbeddedResource
+		preserveUnknownFields := s.XPreserveUnknownFields != nil && *s.XPreserveUnknownFields
+		if len(s.Properties) == 0 && s.AdditionalProperties == nil && !isResource {
+			if preserveUnknownFields || s.Type == "" {
+				return smdschema.Atom{Map: &smdschema.Map{
+					ElementType:         smdschema.TypeRef{NamedType: ptrString(deducedType)},
+					ElementRelationship: smdschema.Separable,
+				}}, nil
+			}
+		}
+
+		m := &smdschema.Map{ElementRelationship: smdschema.Separable}
+		if s.XMapType != nil {
+			switch *s.XMapType {
+			case "granular":
+			case "atomic":
+				m.ElementRelationship = smdschema.Atomic
+			default:
+				return smdschema.Atom{}, fmt.Errorf("unknown map type %q", *s.XMapType)
+			}
+		}
+		for name, property := range s.Properties {
+			if isResource && (name == "apiVersion" || name == "kind" || name == "metadata") {
+				continue
+			}
+			t, err := property.typeRef()
+			if err != nil {
+				return smdschema.Atom{}, fmt.Errorf("%s: %v", name, err)
+			}
+			m.Fields = append(m.Fields, smdschema.StructField{Name: name, Type: t})
+		}
+		if isResource {
+			m.Fields = append(m.Fields,
+				smdschema.StructField{Name: "apiVersion", Type: smdschema.TypeRef{Inlined: smdschema.Atom{Scalar: ptrScalar(smdschema.String)}}},
+				smdschema.StructField{Name: "kind", Type: smdschema.TypeRef{Inlined: smdschema.Atom{Scalar: ptrScalar(smdschema.String)}}},
+				smdschema.StructField{Name: "metadata", Type: smdschema.TypeRef{NamedType: ptrString(objectMetaType)}},
+			)
+		}
+		switch {
+		case s.AdditionalProperties != nil:
+			t, err := s.AdditionalProperties.typeRef()
+			if err != nil {
+				return smdschema.Atom{}, fmt.Errorf("additionalProperties: %v", err)
+			}
+			m.ElementType = t
+		case preserveUnknownFields:
+			m.ElementType = smdschema.TypeRef{NamedType: ptrString(deducedType)}
+		}
+		return smdschema.Atom{Map: m}, nil
+	}
+	return smdschema.Atom{}, fmt.Errorf("unknown type %q", s.Type)
+}
+
+func (s *StructuralSchema) typeRef() (smdschema.TypeRef, error) {
+	atom, err := s.atom(false)
+	if err != nil {
+		return smdschema.TypeRef{}, err
+	}
+	return smdschema.TypeRef{Inlined: atom}, nil
+}
+
+func ptrScalar(s smdschema.Scalar) *smdschema.Scalar {
+	return &s
+}
+
+func ptrString(s string) *string {
+	return &s
+}
+
+// ApplyConfiguration returns an empty apply configuration of the object with
+// the given name and namespace.
+func (b *UnstructuredBuilder) ApplyConfiguration(name, namespace string) *UnstructuredApplyConfiguration {
+	c := &UnstructuredApplyConfiguration{objectType: b.objectType, object: &unstructured.Unstructured{Object: map[string]interface{}{}}}
+	c.object.SetGroupVersionKind(b.gvk)
+	c.object.SetName(name)
+	if namespace != "" {
+		c.object.SetNamespace(namespace)
+	}
+	return c
+}
+
+// Extract extracts the applied configuration owned by fieldManager from
+// object, with the semantics of the generated Extract functions: if no
+// managed fields are found for fieldManager, the apply configuration only
+// holds the name, namespace, apiVersion and kind of object.
+// Experimental!
+func (b *UnstructuredBuilder) Extract(object *unstructured.Unstructured, fieldManager string) (*UnstructuredApplyConfiguration, error) {
+	return b.extract(object, fieldManager, "")
+}
+
+// ExtractStatus is the same as Extract except
+// that it extracts the status subresource applied configuration.
+// Experimental!
+func (b *UnstructuredBuilder) ExtractStatus(object *unstructured.Unstructured, fieldManager string) (*UnstructuredApplyConfiguration, error) {
+	return b.extract(object, fieldManager, "status")
+}
+
+func (b *UnstructuredBuilder) extract(object *unstructured.Unstructured, fieldManager string, subresource string) (*UnstructuredApplyConfiguration, error) {
+	if gvk := object.GroupVersionKind(); gvk != b.gvk {
+		return nil, fmt.Errorf("unable to extract an apply configuration of %s from an object of %s", b.gvk, gvk)
+	}
+	c := b.ApplyConfiguration(object.GetName(), object.GetNamespace())
+	if err := managedfields.ExtractInto(object, b.objectType, fieldManager, c.object, subresource); err != nil {
+		return nil, fmt.Errorf("failed calling ExtractInto for unstructured: %v", err)
+	}
+	c.object.SetGroupVersionKind(b.gvk)
+	c.object.SetName(object.GetName())
+	c.object.SetNamespace(object.GetNamespace())
+	return c, nil
+}
+
+// UnstructuredApplyConfiguration is an apply configuration built by an
+// UnstructuredBuilder. Its With functions return the receiver, so that
+// objects can be built by chaining them. Fields are validated against the
+// schema of the builder by Unstructured.
+type UnstructuredApplyConfiguration struct {
+	objectType typed.ParseableType
+	object     *unstructured.Unstructured
+	err        error
+}
+
+// WithField sets the field at the given path to value, which must serialize
+// to JSON, and returns the receiver. If called multiple times, the field is
+// set to the value of the last call.
+func (c *UnstructuredApplyConfiguration) WithField(value interface{}, fields ...string) *UnstructuredApplyConfiguration {
+	if c.err != nil {
+		return c
+	}
+	v, err := toJSONValue(value)
+	if err != nil {
+		c.err = fmt.Errorf("invalid value of %s: %v", strings.Join(fields, "."), err)
+		return c
+	}
+	if err := unstructured.SetNestedField(c.object.Object, v, fields...); err != nil {
+		c.err = err
+	}
+	return c
+}
+
+// WithFieldItems adds items to the list at the given path and returns the
+// receiver. If called multiple times, the items of all the calls are added.
+func (c *UnstructuredApplyConfiguration) WithFieldItems(fields []string, items ...interface{}) *UnstructuredApplyConfiguration {
+	if c.err != nil {
+		return c
+	}
+	list, _, err := unstructured.NestedFieldNoCopy(c.object.Object, fields...)
+	if err != nil {
+		c.err = err
+		return c
+	}
+	existing, ok := list.([]interface{})
+	if list != nil && !ok {
+		c.err = fmt.Errorf("%s accessor error: %v is of the type %T, expected []interface{}", strings.Join(fields, "."), list, list)
+		return c
+	}
+	for _, item := range items {
+		v, err := toJSONValue(item)
+		if err != nil {
+			c.err = fmt.Errorf("invalid item of %s: %v", strings.Join(fields, "."), err)
+			return c
+		}
+		existing = append(existing, v)
+	}
+	return c.WithField(existing, fields...)
+}
+
+// WithFieldEntries puts entries in the map at the given path and returns the
+// receiver. If called multiple times, the entries of all the calls are put,
+// overriding the values of the entries of previous calls.
+func (c *UnstructuredApplyConfiguration) WithFieldEntries(fields []string, entries map[string]interface{}) *UnstructuredApplyConfiguration {
+	for key, value := range entries {
+		c.WithField(value, append(append([]string{}, fields...), key)...)
+	}
+	return c
+}
+
+// WithLabels puts entries in the labels of the object and returns the
+// receiver.
+func (c *UnstructuredApplyConfiguration) WithLabels(entries map[string]string) *UnstructuredApplyConfiguration {
+	for key, value := range entries {
+		c.WithField(value, "metadata", "labels", key)
+	}
+	return c
+}
+
+// WithAnnotations puts entries in the annotations of the object and returns
+// the receiver.
+func (c *UnstructuredApplyConfiguration) WithAnnotations(entries map[string]string) *UnstructuredApplyConfiguration {
+	for key, value := range entries {
+		c.WithField(value, "metadata", "annotations", key)
+	}
+	return c
+}
+
+// Unstructured returns the apply configuration as an unstructured object, to
+// be applied with the dynamic client. It returns an error if a With function
+// failed, or if the apply configuration does not match the schema.
+func (c *UnstructuredApplyConfiguration) Unstructured() (*unstructured.Unstructured, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if _, err := c.objectType.FromUnstructured(c.object.Object); err != nil {
+		return nil, fmt.Errorf("invalid apply configuration of %s: %v", c.object.GroupVersionKind(), err)
+	}
+	return c.object.DeepCopy(), nil
+}
+
+// toJSONValue converts value to the types of unstructured objects.
+func toJSONValue(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := utiljson.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,289 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	apimetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	metav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+var widgetKind = schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}
+
+const widgetSchema = `
+type: object
+properties:
+  apiVersion:
+    type: string
+  kind:
+    type: string
+  metadata:
+    type: object
+  spec:
+    type: object
+    properties:
+      size:
+        type: integer
+      color:
+        type: string
+      quantity:
+        x-kubernetes-int-or-string: true
+      ports:
+        type: array
+        x-kubernetes-list-type: map
+        x-kubernetes-list-map-keys: [name]
+        items:
+          type: object
+          properties:
+            name:
+              type: string
+            port:
+              type: integer
+      tags:
+        type: array
+        x-kubernetes-list-type: set
+        items:
+          type: string
+      settings:
+        type: object
+        additionalProperties:
+          type: string
+      template:
+        type: object
+        x-kubernetes-embedded-resource: true
+        x-kubernetes-preserve-unknown-fields: true
+  status:
+    type: object
+    x-kubernetes-preserve-unknown-fields: true
+`
+
+func widgetBuilder(t *testing.T) *metav1.UnstructuredBuilder {
+	s := &metav1.StructuralSchema{}
+	if err := yaml.Unmarshal([]byte(widgetSchema), s); err != nil {
+		t.Fatal(err)
+	}
+	b, err := metav1.NewUnstructuredBuilder(widgetKind, s)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return b
+}
+
+func TestUnstructuredBuilder(t *testing.T) {
+	b := widgetBuilder(t)
+
+	u, err := b.ApplyConfiguration("w", "default").
+		WithLabels(map[string]string{"app": "w"}).
+		WithField(3, "spec", "size").
+		WithField("10%", "spec", "quantity").
+		WithFieldItems([]string{"spec", "ports"}, map[string]interface{}{"name": "http", "port": 80}).
+		WithFieldItems([]string{"spec", "ports"}, map[string]interface{}{"name": "https", "port": 443}).
+		WithFieldEntries([]string{"spec", "settings"}, map[string]interface{}{"a": "1"}).
+		WithField(map[string]interface{}{"apiVersion": "v1", "kind": "Pod", "metadata": map[string]interface{}{"name": "p"}, "anything": true}, "spec", "template").
+		Unstructured()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Widget",
+		"metadata": map[string]interface{}{
+			"name":      "w",
+			"namespace": "default",
+			"labels":    map[string]interface{}{"app": "w"},
+		},
+		"spec": map[string]interface{}{
+			"size":     int64(3),
+			"quantity": "10%",
+			"ports": []interface{}{
+				map[string]interface{}{"name": "http", "port": int64(80)},
+				map[string]interface{}{"name": "https", "port": int64(443)},
+			},
+			"settings": map[string]interface{}{"a": "1"},
+			"template": map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Pod",
+				"metadata":   map[string]interface{}{"name": "p"},
+				"anything":   true,
+			},
+		},
+	}
+	if !reflect.DeepEqual(u.Object, expected) {
+		t.Errorf("expected %#v, got %#v", expected, u.Object)
+	}
+}
+
+func TestUnstructuredBuilderValidation(t *testing.T) {
+	b := widgetBuilder(t)
+
+	tests := []struct {
+		name  string
+		build func(*metav1.UnstructuredApplyConfiguration) *metav1.UnstructuredApplyConfiguration
+		err   string
+	}{
+		{
+			name: "unknown field",
+			build: func(c *metav1.UnstructuredApplyConfiguration) *metav1.UnstructuredApplyConfiguration {
+				return c.WithField("x", "spec", "unknown")
+			},
+			err: "unknown",
+		},
+		{
+			name: "wrong type",
+			build: func(c *metav1.UnstructuredApplyConfiguration) *metav1.UnstructuredApplyConfiguration {
+				return c.WithField("big", "spec", "size")
+			},
+			err: "size",
+		},
+		{
+			name: "duplicate key",
+			build: func(c *metav1.UnstructuredApplyConfiguration) *metav1.UnstructuredApplyConfiguration {
+				return c.WithFieldItems([]string{"spec", "ports"}, map[string]interface{}{"name": "http"}, map[string]interface{}{"name": "http"})
+			},
+			err: "duplicate",
+		},
+		{
+			name: "not a list",
+			build: func(c *metav1.UnstructuredApplyConfiguration) *metav1.UnstructuredApplyConfiguration {
+				return c.WithField("red", "spec", "color").WithFieldItems([]string{"spec", "color"}, "blue")
+			},
+			err: "expected []interface{}",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.build(b.ApplyConfiguration("w", "default")).Unstructured()
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("expected an error containing %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestUnstructuredBuilderExtract(t *testing.T) {
+	b := widgetBuilder(t)
+
+	object := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Widget",
+		"metadata": map[string]interface{}{
+			"name":      "w",
+			"namespace": "default",
+			"labels":    map[string]interface{}{"app": "w", "other": "x"},
+		},
+		"spec": map[string]interface{}{
+			"size":  int64(3),
+			"color": "red",
+			"ports": []interface{}{
+				map[string]interface{}{"name": "http", "port": int64(80)},
+				map[string]interface{}{"name": "https", "port": int64(443)},
+			},
+		},
+		"status": map[string]interface{}{"ready": true},
+	}}
+	object.SetManagedFields([]apimetav1.ManagedFieldsEntry{
+		{
+			Manager:    "mine",
+			Operation:  apimetav1.ManagedFieldsOperationApply,
+			APIVersion: "example.com/v1",
+			FieldsType: "FieldsV1",
+			FieldsV1: &apimetav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{"f:app":{}}},` +
+				`"f:spec":{"f:size":{},"f:ports":{"k:{\"name\":\"http\"}":{".":{},"f:name":{},"f:port":{}}}}}`)},
+		},
+		{
+			Manager:    "theirs",
+			Operation:  apimetav1.ManagedFieldsOperationApply,
+			APIVersion: "example.com/v1",
+			FieldsType: "FieldsV1",
+			FieldsV1: &apimetav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{"f:other":{}}},` +
+				`"f:spec":{"f:color":{},"f:ports":{"k:{\"name\":\"https\"}":{".":{},"f:name":{},"f:port":{}}}}}`)},
+		},
+	})
+
+	extracted, err := b.Extract(object, "mine")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	u, err := extracted.WithField(4, "spec", "size").Unstructured()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Widget",
+		"metadata": map[string]interface{}{
+			"name":      "w",
+			"namespace": "default",
+			"labels":    map[string]interface{}{"app": "w"},
+		},
+		"spec": map[string]interface{}{
+			"size": int64(4),
+			"ports": []interface{}{
+				map[string]interface{}{"name": "http", "port": int64(80)},
+			},
+		},
+	}
+	if !reflect.DeepEqual(u.Object, expected) {
+		t.Errorf("expected %#v, got %#v", expected, u.Object)
+	}
+
+	extracted, err = b.Extract(object, "nobody")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	u, err = extracted.Unstructured()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Widget",
+		"metadata":   map[string]interface{}{"name": "w", "namespace": "default"},
+	}
+	if !reflect.DeepEqual(u.Object, expected) {
+		t.Errorf("expected %#v, got %#v", expected, u.Object)
+	}
+
+	object.SetKind("Gadget")
+	if _, err := b.Extract(object, "mine"); err == nil {
+		t.Errorf("expected an error extracting from an object of another kind")
+	}
+}
+
+func TestNewUnstructuredBuilderInvalidSchema(t *testing.T) {
+	for _, s := range []string{
+		"type: string",
+		"type: object\nproperties:\n  list:\n    type: array",
+		"type: object\nproperties:\n  list:\n    type: array\n    x-kubernetes-list-type: map\n    items:\n      type: object",
+		"type: object\nproperties:\n  m:\n    type: object\n    x-kubernetes-map-type: other",
+		"type: object\nproperties:\n  x:\n    type: float",
+	} {
+		schema := &metav1.StructuralSchema{}
+		if err := yaml.Unmarshal([]byte(s), schema); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := metav1.NewUnstructuredBuilder(widgetKind, schema); err == nil {
+			t.Errorf("expected an error for the schema %q", s)
+		}
+	}
+}
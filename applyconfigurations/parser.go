@@ -0,0 +1,28 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package applyconfigurations
+
+import (
+	"k8s.io/client-go/applyconfigurations/internal"
+	"sigs.k8s.io/structured-merge-diff/v4/typed"
+)
+
+// Parser returns the parser of the schemas of the built-in types, which
+// describe how their apply configurations are merged.
+func Parser() *typed.Parser {
+	return internal.Parser()
+}
@@ -25,7 +25,9 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/discovery"
 	kubeversion "k8s.io/client-go/pkg/version"
 	restclient "k8s.io/client-go/rest"
 	"k8s.io/client-go/testing"
@@ -36,6 +38,22 @@ import (
 type FakeDiscovery struct {
 	*testing.Fake
 	FakedServerVersion *version.Info
+
+	// stale is true if resources were added or removed since the last
+	// Invalidate call.
+	stale bool
+	// changeHandlers are called when resources are added or removed.
+	changeHandlers []func()
+}
+
+var _ discovery.CachedDiscoveryInterface = &FakeDiscovery{}
+
+// resources returns the resources served by the fake, which the methods
+// changing them replace rather than modify.
+func (c *FakeDiscovery) resources() []*metav1.APIResourceList {
+	c.RLock()
+	defer c.RUnlock()
+	return c.Resources
 }
 
 // ServerResourcesForGroupVersion returns the supported resources for a group
@@ -46,7 +64,7 @@ func (c *FakeDiscovery) ServerResourcesForGroupVersion(groupVersion string) (*me
 		Resource: schema.GroupVersionResource{Resource: "resource"},
 	}
 	c.Invokes(action, nil)
-	for _, resourceList := range c.Resources {
+	for _, resourceList := range c.resources() {
 		if resourceList.GroupVersion == groupVersion {
 			return resourceList, nil
 		}
@@ -83,7 +101,7 @@ func (c *FakeDiscovery) ServerGroupsAndResources() ([]*metav1.APIGroup, []*metav
 		Resource: schema.GroupVersionResource{Resource: "resource"},
 	}
 	c.Invokes(action, nil)
-	return resultGroups, c.Resources, nil
+	return resultGroups, c.resources(), nil
 }
 
 // ServerPreferredResources returns the supported resources with the version
@@ -108,8 +126,9 @@ func (c *FakeDiscovery) ServerGroups() (*metav1.APIGroupList, error) {
 	c.Invokes(action, nil)
 
 	groups := map[string]*metav1.APIGroup{}
+	var order []string
 
-	for _, res := range c.Resources {
+	for _, res := range c.resources() {
 		gv, err := schema.ParseGroupVersion(res.GroupVersion)
 		if err != nil {
 			return nil, err
@@ -124,6 +143,7 @@ func (c *FakeDiscovery) ServerGroups() (*metav1.APIGroupList, error) {
 				},
 			}
 			groups[gv.Group] = group
+			order = append(order, gv.Group)
 		}
 
 		group.Versions = append(group.Versions, metav1.GroupVersionForDiscovery{
@@ -133,8 +153,8 @@ func (c *FakeDiscovery) ServerGroups() (*metav1.APIGroupList, error) {
 	}
 
 	list := &metav1.APIGroupList{}
-	for _, apiGroup := range groups {
-		list.Groups = append(list.Groups, *apiGroup)
+	for _, name := range order {
+		list.Groups = append(list.Groups, *groups[name])
 	}
 
 	return list, nil
@@ -166,3 +186,122 @@ func (c *FakeDiscovery) OpenAPISchema() (*openapi_v2.Document, error) {
 func (c *FakeDiscovery) RESTClient() restclient.Interface {
 	return nil
 }
+
+// Fresh returns false if resources were added or removed since the last
+// Invalidate call, so that cached discovery users like
+// restmapper.DeferredDiscoveryRESTMapper retry lookups of new resources.
+func (c *FakeDiscovery) Fresh() bool {
+	c.RLock()
+	defer c.RUnlock()
+	return !c.stale
+}
+
+// Invalidate marks the resources as fresh.
+func (c *FakeDiscovery) Invalidate() {
+	c.Lock()
+	defer c.Unlock()
+	c.stale = false
+}
+
+// AddChangeHandler registers a function called each time resources are
+// added or removed, for example the Reset method of a RESTMapper built from
+// this discovery client.
+func (c *FakeDiscovery) AddChangeHandler(handler func()) {
+	c.Lock()
+	defer c.Unlock()
+	c.changeHandlers = append(c.changeHandlers, handler)
+}
+
+// AddResources adds resources to a group version, adding the group version
+// if it is not served yet. Added resources replace the served resources of
+// the same name.
+func (c *FakeDiscovery) AddResources(groupVersion string, resources ...metav1.APIResource) {
+	c.changeResources(func(lists []*metav1.APIResourceList) []*metav1.APIResourceList {
+		var list *metav1.APIResourceList
+		for i := range lists {
+			if lists[i].GroupVersion == groupVersion {
+				list = lists[i].DeepCopy()
+				lists[i] = list
+				break
+			}
+		}
+		if list == nil {
+			list = &metav1.APIResourceList{GroupVersion: groupVersion}
+			lists = append(lists, list)
+		}
+		for _, resource := range resources {
+			replaced := false
+			for i := range list.APIResources {
+				if list.APIResources[i].Name == resource.Name {
+					list.APIResources[i] = resource
+					replaced = true
+					break
+				}
+			}
+			if !replaced {
+				list.APIResources = append(list.APIResources, resource)
+			}
+		}
+		return lists
+	})
+}
+
+// RemoveResources removes the resources with the given names from a group
+// version. The group version is removed if it has no resources left, or if
+// no names are given.
+func (c *FakeDiscovery) RemoveResources(groupVersion string, names ...string) {
+	removed := sets.NewString(names...)
+	c.changeResources(func(lists []*metav1.APIResourceList) []*metav1.APIResourceList {
+		var result []*metav1.APIResourceList
+		for _, list := range lists {
+			if list.GroupVersion != groupVersion {
+				result = append(result, list)
+				continue
+			}
+			if len(names) == 0 {
+				continue
+			}
+			kept := &metav1.APIResourceList{TypeMeta: list.TypeMeta, GroupVersion: list.GroupVersion}
+			for _, resource := range list.APIResources {
+				if !removed.Has(resource.Name) {
+					kept.APIResources = append(kept.APIResources, *resource.DeepCopy())
+				}
+			}
+			if len(kept.APIResources) > 0 {
+				result = append(result, kept)
+			}
+		}
+		return result
+	})
+}
+
+// RemoveGroup removes all the versions of an API group.
+func (c *FakeDiscovery) RemoveGroup(group string) {
+	c.changeResources(func(lists []*metav1.APIResourceList) []*metav1.APIResourceList {
+		var result []*metav1.APIResourceList
+		for _, list := range lists {
+			if gv, err := schema.ParseGroupVersion(list.GroupVersion); err == nil && gv.Group == group {
+				continue
+			}
+			result = append(result, list)
+		}
+		return result
+	})
+}
+
+// changeResources replaces the served resources with the result of change,
+// which is given a copy of them, and calls the change handlers.
+func (c *FakeDiscovery) changeResources(change func([]*metav1.APIResourceList) []*metav1.APIResourceList) {
+	c.Lock()
+	lists := make([]*metav1.APIResourceList, len(c.Resources))
+	copy(lists, c.Resources)
+	c.Resources = change(lists)
+	c.stale = true
+	handlers := make([]func(), len(c.changeHandlers))
+	copy(handlers, c.changeHandlers)
+	c.Unlock()
+
+	for _, handler := range handlers {
+		handler()
+	}
+}
@@ -19,9 +19,13 @@ package fake_test
 import (
 	"testing"
 
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/version"
 	fakediscovery "k8s.io/client-go/discovery/fake"
 	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/restmapper"
 )
 
 func TestFakingServerVersion(t *testing.T) {
@@ -44,3 +48,53 @@ func TestFakingServerVersion(t *testing.T) {
 		t.Fatalf("unexpected faked discovery return value: %q", sv.GitCommit)
 	}
 }
+
+func TestChangingResources(t *testing.T) {
+	client := fakeclientset.NewSimpleClientset()
+	fakeDiscovery := client.Discovery().(*fakediscovery.FakeDiscovery)
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(fakeDiscovery)
+	changes := 0
+	fakeDiscovery.AddChangeHandler(func() { changes++ })
+
+	widgets := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	if _, err := mapper.KindFor(widgets); !meta.IsNoMatchError(err) {
+		t.Fatalf("expected no match before the resource is added, got %v", err)
+	}
+
+	fakeDiscovery.AddResources("example.com/v1",
+		metav1.APIResource{Name: "widgets", Namespaced: true, Kind: "Widget", Verbs: metav1.Verbs{"get", "list"}},
+		metav1.APIResource{Name: "gadgets", Namespaced: true, Kind: "Gadget", Verbs: metav1.Verbs{"get", "list"}},
+	)
+	// The mapper retries lookups when discovery is not fresh.
+	gvk, err := mapper.KindFor(widgets)
+	if err != nil {
+		t.Fatalf("unexpected error after adding the resource: %v", err)
+	}
+	if want := widgets.GroupVersion().WithKind("Widget"); gvk != want {
+		t.Errorf("expected kind %v, got %v", want, gvk)
+	}
+	groups, err := fakeDiscovery.ServerGroups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups.Groups) != 1 || groups.Groups[0].Name != "example.com" {
+		t.Errorf("expected the example.com group, got %v", groups.Groups)
+	}
+
+	fakeDiscovery.AddChangeHandler(mapper.Reset)
+	fakeDiscovery.RemoveResources("example.com/v1", "widgets")
+	if _, err := mapper.KindFor(widgets); !meta.IsNoMatchError(err) {
+		t.Errorf("expected no match after the resource is removed, got %v", err)
+	}
+	if resources, err := fakeDiscovery.ServerResourcesForGroupVersion("example.com/v1"); err != nil || len(resources.APIResources) != 1 {
+		t.Errorf("expected the other resource of the group version to be kept, got %v, %v", resources, err)
+	}
+
+	fakeDiscovery.RemoveGroup("example.com")
+	if _, err := fakeDiscovery.ServerResourcesForGroupVersion("example.com/v1"); err == nil {
+		t.Error("expected the group version to be removed with its group")
+	}
+	if changes != 3 {
+		t.Errorf("expected 3 calls of the change handler, got %d", changes)
+	}
+}
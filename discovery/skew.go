@@ -0,0 +1,165 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/apimachinery/pkg/version"
+	clientversion "k8s.io/client-go/pkg/version"
+)
+
+// MaxSupportedMinorSkew is the maximum number of minor versions between a
+// client and the servers it supports.
+const MaxSupportedMinorSkew = 1
+
+// developmentVersion is the Kubernetes version client-go is developed
+// against, used when the binary does not tell which version it was built
+// with.
+const developmentVersion = "v1.23.0"
+
+// Capability is a capability of servers, which servers support from a
+// version on.
+type Capability string
+
+const (
+	// CapabilityDryRun is the dry run of mutating requests.
+	CapabilityDryRun Capability = "DryRun"
+	// CapabilityWatchBookmarks is the bookmark events of watches.
+	CapabilityWatchBookmarks Capability = "WatchBookmarks"
+	// CapabilityServerSideApply is the apply patches of server-side apply.
+	CapabilityServerSideApply Capability = "ServerSideApply"
+	// CapabilityServerSideFieldValidation is the field validation of requests
+	// by servers.
+	CapabilityServerSideFieldValidation Capability = "ServerSideFieldValidation"
+	// CapabilityAggregatedDiscovery is the discovery of all the API groups
+	// and their resources in a single response.
+	CapabilityAggregatedDiscovery Capability = "AggregatedDiscovery"
+)
+
+// capabilityVersions are the versions from which servers support the
+// capabilities by default.
+var capabilityVersions = map[Capability]*utilversion.Version{
+	CapabilityDryRun:                    utilversion.MustParseGeneric("1.18"),
+	CapabilityWatchBookmarks:            utilversion.MustParseGeneric("1.17"),
+	CapabilityServerSideApply:           utilversion.MustParseGeneric("1.22"),
+	CapabilityServerSideFieldValidation: utilversion.MustParseGeneric("1.25"),
+	CapabilityAggregatedDiscovery:       utilversion.MustParseGeneric("1.26"),
+}
+
+// VersionSkew is the skew between the Kubernetes versions of a client and a
+// server.
+type VersionSkew struct {
+	// ClientVersion is the version of the client.
+	ClientVersion *utilversion.Version
+	// ServerVersion is the version of the server.
+	ServerVersion *utilversion.Version
+	// MinorSkew is the number of minor versions the server is ahead of the
+	// client, negative if the server is behind.
+	MinorSkew int
+	// Supported is whether the skew is within MaxSupportedMinorSkew, and the
+	// client and the server have the same major version.
+	Supported bool
+}
+
+// Err returns an error if the skew is not supported.
+func (s *VersionSkew) Err() error {
+	if s.Supported {
+		return nil
+	}
+	return fmt.Errorf("the server version %s is not supported by the client version %s, which supports the servers within %d minor versions", s.ServerVersion, s.ClientVersion, MaxSupportedMinorSkew)
+}
+
+// Supports returns whether the server supports the capability c. Unknown
+// capabilities are not supported.
+func (s *VersionSkew) Supports(c Capability) bool {
+	min, ok := capabilityVersions[c]
+	return ok && s.ServerVersion.AtLeast(min)
+}
+
+// Capabilities returns whether the server supports each known capability.
+func (s *VersionSkew) Capabilities() map[Capability]bool {
+	capabilities := make(map[Capability]bool, len(capabilityVersions))
+	for c := range capabilityVersions {
+		capabilities[c] = s.Supports(c)
+	}
+	return capabilities
+}
+
+// NewVersionSkew returns the skew between clientVersion and serverVersion.
+func NewVersionSkew(clientVersion, serverVersion *utilversion.Version) *VersionSkew {
+	skew := &VersionSkew{
+		ClientVersion: clientVersion,
+		ServerVersion: serverVersion,
+		MinorSkew:     int(serverVersion.Minor()) - int(clientVersion.Minor()),
+	}
+	skew.Supported = clientVersion.Major() == serverVersion.Major() &&
+		skew.MinorSkew <= MaxSupportedMinorSkew && skew.MinorSkew >= -MaxSupportedMinorSkew
+	return skew
+}
+
+// CheckVersionSkew discovers the version of the server of client, and
+// returns its skew with ClientVersion.
+func CheckVersionSkew(client ServerVersionInterface) (*VersionSkew, error) {
+	info, err := client.ServerVersion()
+	if err != nil {
+		return nil, err
+	}
+	serverVersion, err := ParseVersionInfo(info)
+	if err != nil {
+		return nil, err
+	}
+	return NewVersionSkew(ClientVersion(), serverVersion), nil
+}
+
+// ParseVersionInfo returns the version of info, read from its git version,
+// or else from its major and minor versions.
+func ParseVersionInfo(info *version.Info) (*utilversion.Version, error) {
+	if v, err := utilversion.ParseGeneric(info.GitVersion); err == nil {
+		return v, nil
+	}
+	// the minor versions of some providers end with "+"
+	v, err := utilversion.ParseGeneric(info.Major + "." + strings.TrimSuffix(info.Minor, "+"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the version %q (%s.%s): %v", info.GitVersion, info.Major, info.Minor, err)
+	}
+	return v, nil
+}
+
+// ClientVersion returns the Kubernetes version of client-go. It is the version
+// set when building the binary, or else the version of the client-go module
+// the binary was built with, v0.X.Y being Kubernetes 1.X.Y, or else the
+// version client-go is developed against.
+func ClientVersion() *utilversion.Version {
+	if v, err := utilversion.ParseGeneric(clientversion.Get().GitVersion); err == nil && v.Major() > 0 {
+		return v
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, module := range info.Deps {
+			if module.Path != "k8s.io/client-go" {
+				continue
+			}
+			if v, err := utilversion.ParseGeneric(module.Version); err == nil && v.Major() == 0 && v.Minor() > 0 {
+				return v.WithMajor(1)
+			}
+		}
+	}
+	return utilversion.MustParseGeneric(developmentVersion)
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"errors"
+	"testing"
+
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/apimachinery/pkg/version"
+)
+
+type fakeServerVersion struct {
+	info *version.Info
+	err  error
+}
+
+func (f fakeServerVersion) ServerVersion() (*version.Info, error) {
+	return f.info, f.err
+}
+
+func TestNewVersionSkew(t *testing.T) {
+	tests := []struct {
+		client, server  string
+		expectSkew      int
+		expectSupported bool
+	}{
+		{client: "1.23.0", server: "1.23.4", expectSkew: 0, expectSupported: true},
+		{client: "1.23.0", server: "1.24.0", expectSkew: 1, expectSupported: true},
+		{client: "1.23.0", server: "1.22.9", expectSkew: -1, expectSupported: true},
+		{client: "1.23.0", server: "1.25.0", expectSkew: 2, expectSupported: false},
+		{client: "1.23.0", server: "1.20.0", expectSkew: -3, expectSupported: false},
+		{client: "1.23.0", server: "2.23.0", expectSkew: 0, expectSupported: false},
+	}
+	for _, test := range tests {
+		skew := NewVersionSkew(utilversion.MustParseGeneric(test.client), utilversion.MustParseGeneric(test.server))
+		if skew.MinorSkew != test.expectSkew || skew.Supported != test.expectSupported {
+			t.Errorf("%s -> %s: expected skew %d and supported %t, got %d and %t", test.client, test.server, test.expectSkew, test.expectSupported, skew.MinorSkew, skew.Supported)
+		}
+		if (skew.Err() == nil) != test.expectSupported {
+			t.Errorf("%s -> %s: unexpected error %v", test.client, test.server, skew.Err())
+		}
+	}
+}
+
+func TestVersionSkewCapabilities(t *testing.T) {
+	skew := NewVersionSkew(utilversion.MustParseGeneric("1.23.0"), utilversion.MustParseGeneric("1.25.3"))
+	expected := map[Capability]bool{
+		CapabilityDryRun:                    true,
+		CapabilityWatchBookmarks:            true,
+		CapabilityServerSideApply:           true,
+		CapabilityServerSideFieldValidation: true,
+		CapabilityAggregatedDiscovery:       false,
+	}
+	capabilities := skew.Capabilities()
+	if len(capabilities) != len(expected) {
+		t.Errorf("expected %d capabilities, got %v", len(expected), capabilities)
+	}
+	for c, supported := range expected {
+		if capabilities[c] != supported || skew.Supports(c) != supported {
+			t.Errorf("%s: expected supported %t, got %t", c, supported, capabilities[c])
+		}
+	}
+	if skew.Supports(Capability("Unknown")) {
+		t.Error("expected unknown capabilities not to be supported")
+	}
+}
+
+func TestCheckVersionSkew(t *testing.T) {
+	client := ClientVersion()
+	server := client.WithMinor(client.Minor() + 1)
+	skew, err := CheckVersionSkew(fakeServerVersion{info: &version.Info{GitVersion: "v" + server.String() + "-gke.1"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if skew.MinorSkew != 1 || !skew.Supported {
+		t.Errorf("expected a supported skew of 1, got %#v", skew)
+	}
+
+	if _, err := CheckVersionSkew(fakeServerVersion{err: errors.New("unreachable")}); err == nil {
+		t.Error("expected the error of the server version")
+	}
+}
+
+func TestParseVersionInfo(t *testing.T) {
+	tests := []struct {
+		info        version.Info
+		expected    string
+		expectError bool
+	}{
+		{info: version.Info{GitVersion: "v1.23.1", Major: "1", Minor: "23"}, expected: "1.23.1"},
+		{info: version.Info{GitVersion: "v1.22.4-eks-1", Major: "1", Minor: "22+"}, expected: "1.22.4"},
+		{info: version.Info{GitVersion: "custom", Major: "1", Minor: "21+"}, expected: "1.21"},
+		{info: version.Info{GitVersion: "custom"}, expectError: true},
+	}
+	for _, test := range tests {
+		v, err := ParseVersionInfo(&test.info)
+		if test.expectError {
+			if err == nil {
+				t.Errorf("%#v: expected an error, got %v", test.info, v)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%#v: unexpected error: %v", test.info, err)
+			continue
+		}
+		if v.String() != test.expected {
+			t.Errorf("%#v: expected %s, got %s", test.info, test.expected, v)
+		}
+	}
+}
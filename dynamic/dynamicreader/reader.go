@@ -0,0 +1,176 @@
+/*
+Copyright 2014 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dynamicreader reads objects from informer caches when they are
+// synced, and from the server otherwise.
+package dynamicreader // import "k8s.io/client-go/dynamic/dynamicreader"
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/dynamic/dynamiclister"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Interface reads resources.
+type Interface interface {
+	Resource(resource schema.GroupVersionResource) NamespaceableResourceInterface
+}
+
+// ResourceInterface reads objects of a resource.
+type ResourceInterface interface {
+	Get(ctx context.Context, name string, options metav1.GetOptions, subresources ...string) (*unstructured.Unstructured, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error)
+}
+
+// NamespaceableResourceInterface reads objects of a resource, in all
+// namespaces or in a given namespace.
+type NamespaceableResourceInterface interface {
+	Namespace(string) ResourceInterface
+	ResourceInterface
+}
+
+type delegatingReader struct {
+	client    dynamic.Interface
+	informers map[schema.GroupVersionResource]cache.SharedIndexInformer
+}
+
+var _ Interface = &delegatingReader{}
+
+// NewDelegatingReader returns a reader of the given resources from the caches
+// of the informers of factory, which is started by the caller. Until an
+// informer has synced, and for all other resources, objects are read with
+// client. The informers of factory must not be restricted to a namespace or
+// filtered by list options, otherwise reads would miss objects.
+//
+// Reads from caches return deep copies of the cached objects, which may be
+// older than the objects on the server. Reads of subresources, and lists that
+// select fields, request a specific resource version or are paginated are
+// always sent to the server.
+func NewDelegatingReader(client dynamic.Interface, factory dynamicinformer.DynamicSharedInformerFactory, resources ...schema.GroupVersionResource) Interface {
+	r := &delegatingReader{
+		client:    client,
+		informers: map[schema.GroupVersionResource]cache.SharedIndexInformer{},
+	}
+	for _, resource := range resources {
+		r.informers[resource] = factory.ForResource(resource).Informer()
+	}
+	return r
+}
+
+func (r *delegatingReader) Resource(resource schema.GroupVersionResource) NamespaceableResourceInterface {
+	return &resourceReader{reader: r, resource: resource}
+}
+
+type resourceReader struct {
+	reader    *delegatingReader
+	resource  schema.GroupVersionResource
+	namespace string
+}
+
+func (r *resourceReader) Namespace(namespace string) ResourceInterface {
+	return &resourceReader{reader: r.reader, resource: r.resource, namespace: namespace}
+}
+
+// synced returns the informer of the resource if it has synced.
+func (r *resourceReader) synced() (cache.SharedIndexInformer, bool) {
+	informer, ok := r.reader.informers[r.resource]
+	if !ok || !informer.HasSynced() {
+		return nil, false
+	}
+	return informer, true
+}
+
+func (r *resourceReader) client() dynamic.ResourceInterface {
+	if len(r.namespace) == 0 {
+		return r.reader.client.Resource(r.resource)
+	}
+	return r.reader.client.Resource(r.resource).Namespace(r.namespace)
+}
+
+func (r *resourceReader) Get(ctx context.Context, name string, options metav1.GetOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	informer, ok := r.synced()
+	if !ok || len(subresources) > 0 {
+		return r.client().Get(ctx, name, options, subresources...)
+	}
+	lister := dynamiclister.New(informer.GetIndexer(), r.resource)
+	var obj *unstructured.Unstructured
+	var err error
+	if len(r.namespace) == 0 {
+		obj, err = lister.Get(name)
+	} else {
+		obj, err = lister.Namespace(r.namespace).Get(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return obj.DeepCopy(), nil
+}
+
+func (r *resourceReader) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	informer, ok := r.synced()
+	if !ok || !cacheable(opts) {
+		return r.client().List(ctx, opts)
+	}
+	selector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+	lister := dynamiclister.New(informer.GetIndexer(), r.resource)
+	var items []*unstructured.Unstructured
+	if len(r.namespace) == 0 {
+		items, err = lister.List(selector)
+	} else {
+		items, err = lister.Namespace(r.namespace).List(selector)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// servers list objects in the order of their keys
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].GetNamespace() != items[j].GetNamespace() {
+			return items[i].GetNamespace() < items[j].GetNamespace()
+		}
+		return items[i].GetName() < items[j].GetName()
+	})
+	list := &unstructured.UnstructuredList{Object: map[string]interface{}{}}
+	list.SetResourceVersion(informer.LastSyncResourceVersion())
+	for _, item := range items {
+		list.Items = append(list.Items, *item.DeepCopy())
+	}
+	if len(items) > 0 {
+		list.SetAPIVersion(items[0].GetAPIVersion())
+		list.SetKind(items[0].GetKind() + "List")
+	}
+	return list, nil
+}
+
+// cacheable returns true if a list with opts can be served from a cache.
+func cacheable(opts metav1.ListOptions) bool {
+	if len(strings.TrimSpace(opts.FieldSelector)) > 0 || opts.Limit > 0 || len(opts.Continue) > 0 {
+		return false
+	}
+	return len(opts.ResourceVersionMatch) == 0 && (opts.ResourceVersion == "" || opts.ResourceVersion == "0")
+}
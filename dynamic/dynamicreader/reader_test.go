@@ -0,0 +1,134 @@
+/*
+Copyright 2014 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dynamicreader
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+var (
+	pods       = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	configMaps = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+)
+
+func newObject(kind, namespace, name string, labels map[string]string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetLabels(labels)
+	return obj
+}
+
+func names(list *unstructured.UnstructuredList) []string {
+	var result []string
+	for _, item := range list.Items {
+		result = append(result, item.GetNamespace()+"/"+item.GetName())
+	}
+	return result
+}
+
+func TestDelegatingReader(t *testing.T) {
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{pods: "PodList", configMaps: "ConfigMapList"},
+		newObject("Pod", "a", "one", map[string]string{"app": "web"}),
+		newObject("Pod", "a", "two", nil),
+		newObject("Pod", "b", "three", map[string]string{"app": "web"}),
+		newObject("ConfigMap", "a", "config", nil),
+	)
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(client, 0)
+	reader := NewDelegatingReader(client, factory, pods)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the informer has not synced yet
+	if _, err := reader.Resource(pods).Namespace("a").Get(ctx, "one", metav1.GetOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if actions := client.Actions(); len(actions) != 1 || actions[0].GetVerb() != "get" {
+		t.Fatalf("expected a get from the server, got %v", actions)
+	}
+
+	factory.Start(ctx.Done())
+	for resource, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			t.Fatalf("%v did not sync", resource)
+		}
+	}
+	client.ClearActions()
+
+	obj, err := reader.Resource(pods).Namespace("a").Get(ctx, "one", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj.GetName() != "one" || obj.GetNamespace() != "a" {
+		t.Errorf("unexpected object %v", obj)
+	}
+	// the cached object is not modified
+	obj.SetName("modified")
+	if obj, _ := reader.Resource(pods).Namespace("a").Get(ctx, "one", metav1.GetOptions{}); obj.GetName() != "one" {
+		t.Errorf("the cached object was modified")
+	}
+	if _, err := reader.Resource(pods).Namespace("b").Get(ctx, "one", metav1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+
+	list, err := reader.Resource(pods).List(ctx, metav1.ListOptions{LabelSelector: "app=web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names(list), []string{"a/one", "b/three"}) {
+		t.Errorf("unexpected items %v", names(list))
+	}
+	if list.GetKind() != "PodList" {
+		t.Errorf("unexpected list %v", list.Object)
+	}
+	list, err = reader.Resource(pods).Namespace("a").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names(list), []string{"a/one", "a/two"}) {
+		t.Errorf("unexpected items %v", names(list))
+	}
+	if _, err := reader.Resource(pods).List(ctx, metav1.ListOptions{LabelSelector: "app in ("}); err == nil {
+		t.Error("expected an error for an invalid selector")
+	}
+	if actions := client.Actions(); len(actions) != 0 {
+		t.Errorf("expected reads from the cache, got %v", actions)
+	}
+
+	// reads that cannot be served from the cache
+	reader.Resource(pods).Namespace("a").List(ctx, metav1.ListOptions{FieldSelector: "metadata.name=one"})
+	reader.Resource(pods).List(ctx, metav1.ListOptions{Limit: 1})
+	reader.Resource(pods).List(ctx, metav1.ListOptions{ResourceVersion: "10"})
+	reader.Resource(pods).Namespace("a").Get(ctx, "one", metav1.GetOptions{}, "status")
+	reader.Resource(configMaps).Namespace("a").Get(ctx, "config", metav1.GetOptions{})
+	if actions := client.Actions(); len(actions) != 5 {
+		t.Errorf("expected reads from the server, got %v", actions)
+	}
+}
@@ -0,0 +1,391 @@
+/*
+Copyright 2014 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dynamicshim backs typed clientsets with a dynamic client, optionally
+// converting the objects of the versions used by the typed clients to the
+// versions served by the server. This lets typed code be used with servers
+// that serve other versions of an API than the generated code, for example
+// while custom resource definitions lag behind or run ahead of it.
+package dynamicshim // import "k8s.io/client-go/dynamic/dynamicshim"
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// Conversion maps a resource of typed clients to a resource served by the
+// server.
+type Conversion struct {
+	// Served is the resource served by the server.
+	Served schema.GroupVersionResource
+	// ToServed converts objects of the typed clients to the served version.
+	// If nil, only the apiVersion of objects is changed.
+	ToServed func(obj *unstructured.Unstructured) (*unstructured.Unstructured, error)
+	// FromServed converts objects of the served version to the version of
+	// the typed clients. If nil, only the apiVersion of objects is changed.
+	FromServed func(obj *unstructured.Unstructured) (*unstructured.Unstructured, error)
+}
+
+// Options configures a shim.
+type Options struct {
+	// Conversions maps the resources of typed clients to served resources.
+	// Requests for other resources are sent to the same resource.
+	Conversions map[schema.GroupVersionResource]Conversion
+}
+
+// host is the host of the configs of typed clients, which is never dialed.
+const host = "http://dynamic-shim"
+
+// parameterCodec decodes the options of requests from their query.
+var parameterCodec runtime.ParameterCodec
+
+func init() {
+	scheme := runtime.NewScheme()
+	metav1.AddToGroupVersion(scheme, optionsVersion)
+	parameterCodec = runtime.NewParameterCodec(scheme)
+}
+
+var optionsVersion = schema.GroupVersion{Version: "v1"}
+
+// Config returns the config of typed clients using the HTTP client returned
+// by NewHTTPClient, for example
+//
+//	versioned.NewForConfigAndClient(dynamicshim.Config(), dynamicshim.NewHTTPClient(client, options))
+//
+// Requests are rate limited by the dynamic client only.
+func Config() *rest.Config {
+	return &rest.Config{
+		Host:          host,
+		ContentConfig: rest.ContentConfig{ContentType: runtime.ContentTypeJSON},
+		QPS:           -1,
+	}
+}
+
+// NewForDynamicClient returns a clientset backed by client.
+func NewForDynamicClient(client dynamic.Interface, options Options) (*kubernetes.Clientset, error) {
+	return kubernetes.NewForConfigAndClient(Config(), NewHTTPClient(client, options))
+}
+
+// NewHTTPClient returns an HTTP client for typed clients configured with
+// Config, which serves the requests of the typed clients with client.
+//
+// Objects are exchanged as JSON. Requests of the subresources of objects, like
+// status and scale, are supported, but requests of other subresources, like
+// the logs of pods, and discovery requests are not.
+func NewHTTPClient(client dynamic.Interface, options Options) *http.Client {
+	return &http.Client{Transport: &shim{client: client, conversions: options.Conversions}}
+}
+
+type shim struct {
+	client      dynamic.Interface
+	conversions map[schema.GroupVersionResource]Conversion
+}
+
+// request is a request of a typed client.
+type request struct {
+	resource     schema.GroupVersionResource
+	namespace    string
+	name         string
+	subresources []string
+}
+
+func (s *shim) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		defer req.Body.Close()
+	}
+	r, ok := parsePath(req.URL.Path)
+	if !ok {
+		return statusResponse(req, errors.NewNotFound(schema.GroupResource{}, req.URL.Path)), nil
+	}
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+	}
+	if len(body) > 0 && req.Method != http.MethodPatch {
+		if contentType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type")); contentType != runtime.ContentTypeJSON {
+			return statusResponse(req, errors.NewBadRequest(fmt.Sprintf("unsupported content type %q", contentType))), nil
+		}
+	}
+
+	conversion, converted := s.conversions[r.resource]
+	served := r.resource
+	if converted {
+		served = conversion.Served
+	}
+	client := s.client.Resource(served)
+	var resourceClient dynamic.ResourceInterface = client
+	if len(r.namespace) > 0 {
+		resourceClient = client.Namespace(r.namespace)
+	}
+
+	ctx := req.Context()
+	var obj runtime.Object
+	var err error
+	switch {
+	case req.Method == http.MethodGet && len(r.name) > 0:
+		var opts metav1.GetOptions
+		if err = decodeOptions(req.URL.Query(), &opts); err == nil {
+			obj, err = resourceClient.Get(ctx, r.name, opts, r.subresources...)
+		}
+	case req.Method == http.MethodGet && isWatch(req.URL.Query()):
+		var opts metav1.ListOptions
+		if err = decodeOptions(req.URL.Query(), &opts); err == nil {
+			var w watch.Interface
+			if w, err = resourceClient.Watch(ctx, opts); err == nil {
+				return s.watchResponse(req, w, r.resource, conversion, converted), nil
+			}
+		}
+	case req.Method == http.MethodGet:
+		var opts metav1.ListOptions
+		if err = decodeOptions(req.URL.Query(), &opts); err == nil {
+			obj, err = resourceClient.List(ctx, opts)
+		}
+	case req.Method == http.MethodPost:
+		var opts metav1.CreateOptions
+		var in *unstructured.Unstructured
+		if err = decodeOptions(req.URL.Query(), &opts); err == nil {
+			if in, err = s.decodeObject(body, conversion, converted); err == nil {
+				obj, err = resourceClient.Create(ctx, in, opts, r.subresources...)
+			}
+		}
+	case req.Method == http.MethodPut:
+		var opts metav1.UpdateOptions
+		var in *unstructured.Unstructured
+		if err = decodeOptions(req.URL.Query(), &opts); err == nil {
+			if in, err = s.decodeObject(body, conversion, converted); err == nil {
+				obj, err = resourceClient.Update(ctx, in, opts, r.subresources...)
+			}
+		}
+	case req.Method == http.MethodPatch:
+		var opts metav1.PatchOptions
+		if err = decodeOptions(req.URL.Query(), &opts); err == nil {
+			patchType := types.PatchType(req.Header.Get("Content-Type"))
+			obj, err = resourceClient.Patch(ctx, r.name, patchType, body, opts, r.subresources...)
+		}
+	case req.Method == http.MethodDelete:
+		var opts metav1.DeleteOptions
+		if len(body) > 0 {
+			err = json.Unmarshal(body, &opts)
+		}
+		if err == nil && len(r.name) > 0 {
+			err = resourceClient.Delete(ctx, r.name, opts, r.subresources...)
+		} else if err == nil {
+			var listOpts metav1.ListOptions
+			if err = decodeOptions(req.URL.Query(), &listOpts); err == nil {
+				err = resourceClient.DeleteCollection(ctx, opts, listOpts)
+			}
+		}
+		if err == nil {
+			obj = &metav1.Status{Status: metav1.StatusSuccess}
+		}
+	default:
+		err = errors.NewMethodNotSupported(r.resource.GroupResource(), req.Method)
+	}
+	if err != nil {
+		return statusResponse(req, err), nil
+	}
+
+	if converted {
+		if obj, err = convertFromServed(obj, r.resource, conversion); err != nil {
+			return statusResponse(req, err), nil
+		}
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return statusResponse(req, err), nil
+	}
+	return response(req, http.StatusOK, ioutil.NopCloser(bytes.NewReader(data))), nil
+}
+
+// parsePath parses the path of a request of a typed client.
+func parsePath(path string) (request, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	var r request
+	switch {
+	case len(segments) >= 3 && segments[0] == "api":
+		r.resource.Version = segments[1]
+		segments = segments[2:]
+	case len(segments) >= 4 && segments[0] == "apis":
+		r.resource.Group = segments[1]
+		r.resource.Version = segments[2]
+		segments = segments[3:]
+	default:
+		return r, false
+	}
+	// namespaces have the subresources status and finalize
+	if segments[0] == "namespaces" && len(segments) >= 3 &&
+		!(len(segments) == 3 && (segments[2] == "status" || segments[2] == "finalize")) {
+		r.namespace = segments[1]
+		segments = segments[2:]
+	}
+	r.resource.Resource = segments[0]
+	if len(segments) > 1 {
+		r.name = segments[1]
+	}
+	if len(segments) > 2 {
+		r.subresources = segments[2:]
+	}
+	return r, true
+}
+
+func isWatch(query url.Values) bool {
+	watch := query.Get("watch")
+	return watch == "true" || watch == "1"
+}
+
+func decodeOptions(query url.Values, into runtime.Object) error {
+	if err := parameterCodec.DecodeParameters(query, optionsVersion, into); err != nil {
+		return errors.NewBadRequest(err.Error())
+	}
+	return nil
+}
+
+// decodeObject decodes the object of a typed client in body, converted to
+// the served version.
+func (s *shim) decodeObject(body []byte, conversion Conversion, converted bool) (*unstructured.Unstructured, error) {
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(body); err != nil {
+		return nil, errors.NewBadRequest(err.Error())
+	}
+	if !converted {
+		return obj, nil
+	}
+	if conversion.ToServed != nil {
+		return conversion.ToServed(obj)
+	}
+	obj.SetAPIVersion(conversion.Served.GroupVersion().String())
+	return obj, nil
+}
+
+// convertFromServed converts the objects of the served version in obj to the
+// version of resource.
+func convertFromServed(obj runtime.Object, resource schema.GroupVersionResource, conversion Conversion) (runtime.Object, error) {
+	convert := func(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+		if conversion.FromServed != nil {
+			return conversion.FromServed(obj)
+		}
+		obj.SetAPIVersion(resource.GroupVersion().String())
+		return obj, nil
+	}
+	switch obj := obj.(type) {
+	case *unstructured.Unstructured:
+		return convert(obj)
+	case *unstructured.UnstructuredList:
+		for i := range obj.Items {
+			item, err := convert(&obj.Items[i])
+			if err != nil {
+				return nil, err
+			}
+			obj.Items[i] = *item
+		}
+		obj.SetAPIVersion(resource.GroupVersion().String())
+		return obj, nil
+	}
+	return obj, nil
+}
+
+// watchResponse streams the events of w to a typed client.
+func (s *shim) watchResponse(req *http.Request, w watch.Interface, resource schema.GroupVersionResource, conversion Conversion, converted bool) *http.Response {
+	reader, writer := io.Pipe()
+	go func() {
+		defer w.Stop()
+		ctx := req.Context()
+		encoder := json.NewEncoder(writer)
+		for {
+			var event watch.Event
+			var ok bool
+			select {
+			case <-ctx.Done():
+				writer.CloseWithError(ctx.Err())
+				return
+			case event, ok = <-w.ResultChan():
+				if !ok {
+					writer.Close()
+					return
+				}
+			}
+			obj := event.Object
+			if converted && event.Type != watch.Error {
+				var err error
+				if obj, err = convertFromServed(obj, resource, conversion); err != nil {
+					event.Type, obj = watch.Error, &errors.NewInternalError(err).ErrStatus
+				}
+			}
+			data, err := json.Marshal(obj)
+			if err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+			// fails once the typed client closed the body
+			if err := encoder.Encode(&metav1.WatchEvent{Type: string(event.Type), Object: runtime.RawExtension{Raw: data}}); err != nil {
+				return
+			}
+		}
+	}()
+	return response(req, http.StatusOK, reader)
+}
+
+// statusResponse returns err to a typed client.
+func statusResponse(req *http.Request, err error) *http.Response {
+	var status *metav1.Status
+	if apiStatus, ok := err.(errors.APIStatus); ok {
+		s := apiStatus.Status()
+		status = &s
+	} else {
+		status = &errors.NewInternalError(err).ErrStatus
+	}
+	status.Kind, status.APIVersion = "Status", "v1"
+	code := int(status.Code)
+	if code == 0 {
+		code = http.StatusInternalServerError
+	}
+	data, _ := json.Marshal(status)
+	return response(req, code, ioutil.NopCloser(bytes.NewReader(data)))
+}
+
+func response(req *http.Request, code int, body io.ReadCloser) *http.Response {
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", code, http.StatusText(code)),
+		StatusCode: code,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{"Content-Type": []string{runtime.ContentTypeJSON}},
+		Body:       body,
+		Request:    req,
+	}
+}
@@ -0,0 +1,205 @@
+/*
+Copyright 2014 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dynamicshim
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+var (
+	pods            = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	namespaces      = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+	cronJobs        = schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "cronjobs"}
+	cronJobsV1beta1 = schema.GroupVersionResource{Group: "batch", Version: "v1beta1", Resource: "cronjobs"}
+)
+
+func newObject(apiVersion, kind, namespace, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+func newDynamicClient(objects ...runtime.Object) *fake.FakeDynamicClient {
+	return fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		pods:       "PodList",
+		namespaces: "NamespaceList",
+		cronJobs:   "CronJobList",
+	}, objects...)
+}
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected request
+		ok       bool
+	}{
+		{path: "/api/v1/pods", expected: request{resource: pods}, ok: true},
+		{path: "/api/v1/namespaces/ns/pods", expected: request{resource: pods, namespace: "ns"}, ok: true},
+		{path: "/api/v1/namespaces/ns/pods/a/status", expected: request{resource: pods, namespace: "ns", name: "a", subresources: []string{"status"}}, ok: true},
+		{path: "/api/v1/namespaces/ns", expected: request{resource: namespaces, name: "ns"}, ok: true},
+		{path: "/api/v1/namespaces/ns/finalize", expected: request{resource: namespaces, name: "ns", subresources: []string{"finalize"}}, ok: true},
+		{path: "/apis/batch/v1/namespaces/ns/cronjobs/a", expected: request{resource: cronJobs, namespace: "ns", name: "a"}, ok: true},
+		{path: "/apis/batch/v1", ok: false},
+		{path: "/version", ok: false},
+	}
+	for _, test := range tests {
+		r, ok := parsePath(test.path)
+		if ok != test.ok || (ok && !reflect.DeepEqual(r, test.expected)) {
+			t.Errorf("%s: expected %#v (%t), got %#v (%t)", test.path, test.expected, test.ok, r, ok)
+		}
+	}
+}
+
+func TestClientset(t *testing.T) {
+	client := newDynamicClient(newObject("v1", "Pod", "ns", "a"), newObject("v1", "Pod", "ns", "b"))
+	cs, err := NewForDynamicClient(client, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	podClient := cs.CoreV1().Pods("ns")
+
+	pod, err := podClient.Get(ctx, "a", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pod.Name != "a" || pod.Namespace != "ns" {
+		t.Errorf("unexpected pod %#v", pod)
+	}
+	if _, err := podClient.Get(ctx, "missing", metav1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+
+	w, err := podClient.Watch(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	created, err := podClient.Create(ctx, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "c"},
+		Spec:       corev1.PodSpec{NodeName: "node"},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Spec.NodeName != "node" {
+		t.Errorf("unexpected pod %#v", created)
+	}
+	event := <-w.ResultChan()
+	if event.Type != watch.Added || event.Object.(*corev1.Pod).Name != "c" {
+		t.Errorf("unexpected event %#v", event)
+	}
+
+	list, err := podClient.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 3 {
+		t.Errorf("expected 3 pods, got %d", len(list.Items))
+	}
+
+	created.Labels = map[string]string{"app": "web"}
+	if updated, err := podClient.Update(ctx, created, metav1.UpdateOptions{}); err != nil || updated.Labels["app"] != "web" {
+		t.Errorf("unexpected update %#v (%v)", updated, err)
+	}
+	patched, err := podClient.Patch(ctx, "c", types.MergePatchType, []byte(`{"spec":{"nodeName":"other"}}`), metav1.PatchOptions{})
+	if err != nil || patched.Spec.NodeName != "other" {
+		t.Errorf("unexpected patch %#v (%v)", patched, err)
+	}
+	if err := podClient.Delete(ctx, "c", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := podClient.Get(ctx, "c", metav1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("expected the pod to be deleted, got %v", err)
+	}
+
+	// discovery is not served
+	if _, err := cs.Discovery().ServerVersion(); err == nil {
+		t.Error("expected an error for discovery requests")
+	}
+}
+
+func TestClientsetConversions(t *testing.T) {
+	client := newDynamicClient(newObject("batch/v1", "CronJob", "ns", "a"))
+	var toServed, fromServed int
+	cs, err := NewForDynamicClient(client, Options{Conversions: map[schema.GroupVersionResource]Conversion{
+		cronJobsV1beta1: {
+			Served: cronJobs,
+			ToServed: func(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+				toServed++
+				obj.SetAPIVersion("batch/v1")
+				return obj, nil
+			},
+			FromServed: func(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+				fromServed++
+				obj.SetAPIVersion("batch/v1beta1")
+				obj.SetAnnotations(map[string]string{"converted": "true"})
+				return obj, nil
+			},
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	cronJobClient := cs.BatchV1beta1().CronJobs("ns")
+
+	cronJob, err := cronJobClient.Get(ctx, "a", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cronJob.Annotations["converted"] != "true" {
+		t.Errorf("expected a converted object, got %#v", cronJob)
+	}
+	if _, err := cronJobClient.Create(ctx, &batchv1beta1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "b"}}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	served, err := client.Resource(cronJobs).Namespace("ns").Get(ctx, "b", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if served.GetAPIVersion() != "batch/v1" {
+		t.Errorf("expected the served version to be stored, got %s", served.GetAPIVersion())
+	}
+	list, err := cronJobClient.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 2 || list.Items[1].Annotations["converted"] != "true" {
+		t.Errorf("unexpected list %#v", list)
+	}
+	if toServed != 1 || fromServed != 4 {
+		t.Errorf("unexpected conversions: %d to and %d from the served version", toServed, fromServed)
+	}
+}
@@ -23,12 +23,14 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/diff"
+	clienttesting "k8s.io/client-go/testing"
 )
 
 const (
@@ -84,6 +86,19 @@ func TestGet(t *testing.T) {
 	}
 }
 
+func TestChaos(t *testing.T) {
+	client := NewSimpleDynamicClient(runtime.NewScheme(), newUnstructured("group/version", "TheKind", "ns-foo", "name-foo"))
+	client.SetChaos(&clienttesting.Chaos{InternalError: 1})
+	resource := client.Resource(schema.GroupVersionResource{Group: "group", Version: "version", Resource: "thekinds"}).Namespace("ns-foo")
+	if _, err := resource.Get(context.TODO(), "name-foo", metav1.GetOptions{}); !errors.IsInternalError(err) {
+		t.Errorf("expected an internal error, got %v", err)
+	}
+	client.SetChaos(nil)
+	if _, err := resource.Get(context.TODO(), "name-foo", metav1.GetOptions{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
 func TestListDecoding(t *testing.T) {
 	// this the duplication of logic from the real List API.  This will prove that our dynamic client actually returns the gvk
 	uncastObj, err := runtime.Decode(unstructured.UnstructuredJSONScheme, []byte(`{"apiVersion": "group/version", "kind": "TheKindList", "items":[]}`))
@@ -149,6 +164,44 @@ func TestList(t *testing.T) {
 	}
 }
 
+func TestListPagination(t *testing.T) {
+	scheme := runtime.NewScheme()
+
+	client := NewSimpleDynamicClientWithCustomListKinds(scheme,
+		map[schema.GroupVersionResource]string{
+			{Group: "group", Version: "version", Resource: "thekinds"}: "TheKindList",
+		},
+		newUnstructured("group/version", "TheKind", "ns-foo", "name-foo"),
+		newUnstructured("group/version", "TheKind", "ns-foo", "name-bar"),
+		newUnstructured("group/version", "TheKind", "ns-foo", "name-baz"),
+	)
+	resource := client.Resource(schema.GroupVersionResource{Group: "group", Version: "version", Resource: "thekinds"})
+	listFirst, err := resource.List(context.TODO(), metav1.ListOptions{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listFirst.Items) != 2 || listFirst.GetContinue() == "" {
+		t.Fatalf("expected a first page of 2 items with a continue token, got %d items and %q", len(listFirst.Items), listFirst.GetContinue())
+	}
+	if remaining := listFirst.GetRemainingItemCount(); remaining == nil || *remaining != 1 {
+		t.Errorf("expected 1 remaining item, got %v", remaining)
+	}
+
+	listSecond, err := resource.List(context.TODO(), metav1.ListOptions{Limit: 2, Continue: listFirst.GetContinue()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []unstructured.Unstructured{
+		*newUnstructured("group/version", "TheKind", "ns-foo", "name-foo"),
+	}
+	if !equality.Semantic.DeepEqual(listSecond.Items, expected) {
+		t.Fatal(diff.ObjectGoPrintDiff(expected, listSecond.Items))
+	}
+	if listSecond.GetContinue() != "" {
+		t.Errorf("expected no continue token on the last page, got %q", listSecond.GetContinue())
+	}
+}
+
 func Test_ListKind(t *testing.T) {
 	scheme := runtime.NewScheme()
 
@@ -0,0 +1,58 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/openapi"
+	"k8s.io/kube-openapi/pkg/util/proto"
+)
+
+// ValidateAgainstSchemas makes the client validate the objects it creates
+// and updates against the OpenAPI models of their kind, and reject invalid
+// objects with an Invalid error, like the apiserver does for custom
+// resources. Objects of kinds without model are not validated. Models can
+// be built from an OpenAPI document with proto.NewOpenAPIData.
+func (c *FakeDynamicClient) ValidateAgainstSchemas(models proto.Models) {
+	c.PrependReactorWithOptions("*", "*", schemaValidationReaction(openapi.NewValidator(models)), testing.ReactorOptions{Name: "schema validation"})
+}
+
+func schemaValidationReaction(validator *openapi.Validator) testing.ReactionFunc {
+	return func(action testing.Action) (bool, runtime.Object, error) {
+		var obj runtime.Object
+		switch action := action.(type) {
+		case testing.CreateActionImpl:
+			obj = action.GetObject()
+		case testing.UpdateActionImpl:
+			obj = action.GetObject()
+		default:
+			return false, nil, nil
+		}
+		u, ok := obj.(runtime.Unstructured)
+		if !ok {
+			return false, nil, nil
+		}
+		if _, ok := validator.SchemaFor(obj.GetObjectKind().GroupVersionKind()); !ok {
+			return false, nil, nil
+		}
+		if err := validator.Validate(u); err != nil {
+			return true, nil, err
+		}
+		return false, nil, nil
+	}
+}
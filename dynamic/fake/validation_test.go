@@ -0,0 +1,115 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"testing"
+
+	openapi_v2 "github.com/googleapis/gnostic/openapiv2"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/kube-openapi/pkg/util/proto"
+)
+
+const widgetSchema = `
+swagger: "2.0"
+info:
+  title: widgets
+  version: v1
+paths: {}
+definitions:
+  com.example.v1.Widget:
+    type: object
+    x-kubernetes-group-version-kind:
+    - group: example.com
+      version: v1
+      kind: Widget
+    properties:
+      apiVersion:
+        type: string
+      kind:
+        type: string
+      metadata:
+        description: arbitrary metadata
+      spec:
+        type: object
+        required:
+        - size
+        properties:
+          size:
+            type: integer
+`
+
+func newWidget(name string, spec map[string]interface{}) *unstructured.Unstructured {
+	obj := newUnstructured("example.com/v1", "Widget", "ns", name)
+	obj.Object["spec"] = spec
+	return obj
+}
+
+func TestValidateAgainstSchemas(t *testing.T) {
+	doc, err := openapi_v2.ParseDocument([]byte(widgetSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	models, err := proto.NewOpenAPIData(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewSimpleDynamicClient(runtime.NewScheme(), newWidget("existing", map[string]interface{}{"size": int64(1)}))
+	client.ValidateAgainstSchemas(models)
+	widgets := client.Resource(schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}).Namespace("ns")
+
+	if _, err := widgets.Create(context.TODO(), newWidget("valid", map[string]interface{}{"size": int64(2)}), metav1.CreateOptions{}); err != nil {
+		t.Errorf("unexpected error creating a valid object: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		spec  map[string]interface{}
+		field string
+		typ   metav1.CauseType
+	}{
+		{"missing", map[string]interface{}{}, "spec.size", metav1.CauseTypeFieldValueRequired},
+		{"wrongtype", map[string]interface{}{"size": "big"}, "spec.size", metav1.CauseTypeFieldValueInvalid},
+		{"unknown", map[string]interface{}{"size": int64(1), "color": "red"}, "spec.color", metav1.CauseType(field.ErrorTypeForbidden)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := widgets.Create(context.TODO(), newWidget(test.name, test.spec), metav1.CreateOptions{})
+			if !errors.IsInvalid(err) {
+				t.Fatalf("expected an Invalid error, got %v", err)
+			}
+			causes := err.(errors.APIStatus).Status().Details.Causes
+			if len(causes) != 1 || causes[0].Field != test.field || causes[0].Type != test.typ {
+				t.Errorf("expected a %s cause for %s, got %#v", test.typ, test.field, causes)
+			}
+			if _, err := widgets.Get(context.TODO(), test.name, metav1.GetOptions{}); !errors.IsNotFound(err) {
+				t.Errorf("expected the invalid object not to be created, got %v", err)
+			}
+		})
+	}
+
+	if _, err := widgets.Update(context.TODO(), newWidget("existing", map[string]interface{}{}), metav1.UpdateOptions{}); !errors.IsInvalid(err) {
+		t.Errorf("expected an Invalid error updating to an invalid object, got %v", err)
+	}
+}
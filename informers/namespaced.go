@@ -0,0 +1,168 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// NamespacedFactories manages a SharedInformerFactory per namespace, stopping
+// the informers of a namespace once it is deleted, which releases their
+// watches and caches.
+type NamespacedFactories struct {
+	client        kubernetes.Interface
+	defaultResync time.Duration
+	options       []SharedInformerOption
+
+	lock      sync.Mutex
+	factories map[string]*namespacedFactory
+	// stopCh is the channel given to Start, nil until Start is called
+	stopCh   <-chan struct{}
+	handlers []func(namespace string)
+}
+
+type namespacedFactory struct {
+	factory SharedInformerFactory
+	stopCh  chan struct{}
+	stop    sync.Once
+	started bool
+}
+
+// NewNamespacedFactories returns NamespacedFactories creating factories from
+// client with defaultResync and options, limited to their namespace. The
+// factories of namespaces are removed when namespaces deletes them, so
+// namespaces must be started and synced along the factories.
+func NewNamespacedFactories(client kubernetes.Interface, defaultResync time.Duration, namespaces coreinformers.NamespaceInformer, options ...SharedInformerOption) *NamespacedFactories {
+	f := &NamespacedFactories{
+		client:        client,
+		defaultResync: defaultResync,
+		options:       options,
+		factories:     map[string]*namespacedFactory{},
+	}
+	namespaces.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if namespace, ok := obj.(*corev1.Namespace); ok {
+				f.remove(namespace.Name)
+			}
+		},
+	})
+	return f
+}
+
+// ForNamespace returns the factory of namespace, creating it if needed. The
+// informers requested from it are started by the next call to Start.
+func (f *NamespacedFactories) ForNamespace(namespace string) SharedInformerFactory {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if entry, exists := f.factories[namespace]; exists {
+		return entry.factory
+	}
+	options := append([]SharedInformerOption{}, f.options...)
+	entry := &namespacedFactory{
+		factory: NewSharedInformerFactoryWithOptions(f.client, f.defaultResync, append(options, WithNamespace(namespace))...),
+		stopCh:  make(chan struct{}),
+	}
+	f.factories[namespace] = entry
+	return entry.factory
+}
+
+// Namespaces returns the namespaces having a factory.
+func (f *NamespacedFactories) Namespaces() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	namespaces := make([]string, 0, len(f.factories))
+	for namespace := range f.factories {
+		namespaces = append(namespaces, namespace)
+	}
+	return namespaces
+}
+
+// AddRemoveHandler adds a handler called with the namespaces whose factory is
+// removed, for the controllers to drop the listers obtained from it.
+func (f *NamespacedFactories) AddRemoveHandler(handler func(namespace string)) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.handlers = append(f.handlers, handler)
+}
+
+// Start starts the requested informers of all the factories until stopCh is
+// closed or their namespace is deleted. It can be called again to start the
+// informers requested since.
+func (f *NamespacedFactories) Start(stopCh <-chan struct{}) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.stopCh == nil {
+		f.stopCh = stopCh
+	}
+	for _, entry := range f.factories {
+		if !entry.started {
+			entry.started = true
+			go func(entry *namespacedFactory) {
+				select {
+				case <-f.stopCh:
+					entry.stop.Do(func() { close(entry.stopCh) })
+				case <-entry.stopCh:
+				}
+			}(entry)
+		}
+		entry.factory.Start(entry.stopCh)
+	}
+}
+
+// WaitForCacheSync waits for the started informers of all the factories to
+// sync, returning false if stopCh is closed first.
+func (f *NamespacedFactories) WaitForCacheSync(stopCh <-chan struct{}) bool {
+	f.lock.Lock()
+	factories := make([]SharedInformerFactory, 0, len(f.factories))
+	for _, entry := range f.factories {
+		factories = append(factories, entry.factory)
+	}
+	f.lock.Unlock()
+	for _, factory := range factories {
+		for _, synced := range factory.WaitForCacheSync(stopCh) {
+			if !synced {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// remove stops and removes the factory of namespace.
+func (f *NamespacedFactories) remove(namespace string) {
+	f.lock.Lock()
+	entry, exists := f.factories[namespace]
+	delete(f.factories, namespace)
+	handlers := f.handlers
+	f.lock.Unlock()
+	if !exists {
+		return
+	}
+	entry.stop.Do(func() { close(entry.stopCh) })
+	for _, handler := range handlers {
+		handler(namespace)
+	}
+}
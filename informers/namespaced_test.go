@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNamespacedFactories(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns1"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns2"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "a"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns2", Name: "b"}},
+	)
+	clusterFactory := NewSharedInformerFactory(client, 0)
+	factories := NewNamespacedFactories(client, 0, clusterFactory.Core().V1().Namespaces())
+	removed := make(chan string, 2)
+	factories.AddRemoveHandler(func(namespace string) {
+		removed <- namespace
+	})
+
+	ns1 := factories.ForNamespace("ns1")
+	if factories.ForNamespace("ns1") != ns1 {
+		t.Fatalf("expected the factory of a namespace to be reused")
+	}
+	pods1 := ns1.Core().V1().Pods()
+	pods1.Informer()
+	factories.ForNamespace("ns2").Core().V1().Pods().Informer()
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	clusterFactory.Start(stopCh)
+	factories.Start(stopCh)
+	clusterFactory.WaitForCacheSync(stopCh)
+	if !factories.WaitForCacheSync(stopCh) {
+		t.Fatalf("expected the factories to sync")
+	}
+	if pods, _ := pods1.Lister().List(labels.Everything()); len(pods) != 1 || pods[0].Name != "a" {
+		t.Fatalf("expected the pods of ns1, got %v", pods)
+	}
+
+	if err := client.CoreV1().Namespaces().Delete(ctx, "ns1", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case namespace := <-removed:
+		if namespace != "ns1" {
+			t.Fatalf("expected ns1 to be removed, got %s", namespace)
+		}
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatalf("timed out waiting for ns1 to be removed")
+	}
+	if namespaces := factories.Namespaces(); len(namespaces) != 1 || namespaces[0] != "ns2" {
+		t.Fatalf("expected only ns2 to remain, got %v", namespaces)
+	}
+	if factories.ForNamespace("ns1") == ns1 {
+		t.Fatalf("expected a new factory once ns1 was removed")
+	}
+
+	// the informers of removed namespaces stop watching
+	if _, err := client.CoreV1().Pods("ns1").Create(ctx, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "c"}}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, exists, _ := pods1.Informer().GetStore().GetByKey("ns1/c"); exists {
+		t.Errorf("expected the informer of ns1 to be stopped")
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers/internalinterfaces"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ListSelectors restricts the objects listed and watched by informers.
+type ListSelectors struct {
+	// Label selects objects by labels, nil selecting all objects.
+	Label labels.Selector
+	// Field selects objects by fields, nil selecting all objects. The
+	// supported fields depend on the resource, for example spec.nodeName
+	// for pods.
+	Field fields.Selector
+}
+
+// TweakListOptions adds the selectors to the ones of options. It can be
+// passed to WithTweakListOptions to restrict all the informers of a factory.
+func (s ListSelectors) TweakListOptions(options *v1.ListOptions) {
+	if s.Label != nil && !s.Label.Empty() {
+		options.LabelSelector = andSelectors(options.LabelSelector, s.Label.String())
+	}
+	if s.Field != nil && !s.Field.Empty() {
+		options.FieldSelector = andSelectors(options.FieldSelector, s.Field.String())
+	}
+}
+
+// NewFilteredInformerFunc builds an informer restricted to a namespace and
+// to the list options set by tweakListOptions, like the NewFilteredXInformer
+// functions of the informer packages, for example
+// k8s.io/client-go/informers/core/v1.NewFilteredPodInformer.
+type NewFilteredInformerFunc func(client kubernetes.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer
+
+// InformerWithSelectors returns the informer of factory for the type of obj,
+// creating it with newFunc restricted by selectors in addition to the
+// namespace and the list options of the factory. This sets selectors on a
+// single informer type, for example only the pods of a node:
+//
+//	informers.InformerWithSelectors(factory, &corev1.Pod{}, coreinformers.NewFilteredPodInformer,
+//		informers.ListSelectors{Field: fields.OneTermEqualSelector("spec.nodeName", nodeName)})
+//
+// The factory holds a single informer per type, which is then also returned
+// by the typed accessors such as factory.Core().V1().Pods().Informer(), so
+// InformerWithSelectors must be called before them. The informer is indexed
+// by namespace.
+func InformerWithSelectors(factory SharedInformerFactory, obj runtime.Object, newFunc NewFilteredInformerFunc, selectors ListSelectors) cache.SharedIndexInformer {
+	var namespace string
+	var tweakListOptions internalinterfaces.TweakListOptionsFunc
+	if f, ok := factory.(*sharedInformerFactory); ok {
+		namespace = f.namespace
+		tweakListOptions = f.tweakListOptions
+	}
+	return factory.InformerFor(obj, func(client kubernetes.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+		indexers := cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}
+		return newFunc(client, namespace, resyncPeriod, indexers, ChainTweakListOptions(tweakListOptions, selectors.TweakListOptions))
+	})
+}
+
+// ChainTweakListOptions returns a TweakListOptionsFunc calling the non nil
+// tweakListOptions in order.
+func ChainTweakListOptions(tweakListOptions ...internalinterfaces.TweakListOptionsFunc) internalinterfaces.TweakListOptionsFunc {
+	return func(options *v1.ListOptions) {
+		for _, tweak := range tweakListOptions {
+			if tweak != nil {
+				tweak(options)
+			}
+		}
+	}
+}
+
+// andSelectors returns a selector matching both selectors, both label and
+// field selectors requiring all their comma separated terms.
+func andSelectors(selector, other string) string {
+	if selector == "" {
+		return other
+	}
+	return selector + "," + other
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestInformerWithSelectors(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	lists := make(chan metav1.ListOptions, 10)
+	client.PrependReactor("list", "*", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		restrictions := action.(clienttesting.ListAction).GetListRestrictions()
+		lists <- metav1.ListOptions{LabelSelector: restrictions.Labels.String(), FieldSelector: restrictions.Fields.String()}
+		return false, nil, nil
+	})
+
+	factory := NewSharedInformerFactoryWithOptions(client, 0,
+		WithTweakListOptions(ListSelectors{Label: labels.SelectorFromSet(labels.Set{"app": "foo"})}.TweakListOptions),
+	)
+	informer := InformerWithSelectors(factory, &corev1.Pod{}, coreinformers.NewFilteredPodInformer, ListSelectors{Field: fields.OneTermEqualSelector("spec.nodeName", "node")})
+	if factory.Core().V1().Pods().Informer() != informer {
+		t.Errorf("expected the factory to return the informer with selectors")
+	}
+	factory.Core().V1().Services().Informer()
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	factory.Start(stopCh)
+
+	got := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case options := <-lists:
+			got[options.LabelSelector+";"+options.FieldSelector] = true
+		case <-time.After(wait.ForeverTestTimeout):
+			t.Fatalf("timed out waiting for lists, got %v", got)
+		}
+	}
+	for _, expected := range []string{"app=foo;spec.nodeName=node", "app=foo;"} {
+		if !got[expected] {
+			t.Errorf("expected a list with selectors %q, got %v", expected, got)
+		}
+	}
+	for informerType, synced := range factory.WaitForCacheSync(stopCh) {
+		if !synced {
+			t.Errorf("expected %v to sync", informerType)
+		}
+	}
+}
+
+func TestListSelectorsTweakListOptions(t *testing.T) {
+	options := metav1.ListOptions{LabelSelector: "app=foo", FieldSelector: "metadata.name=bar"}
+	ListSelectors{
+		Label: labels.SelectorFromSet(labels.Set{"tier": "web"}),
+		Field: fields.OneTermNotEqualSelector("status.phase", "Failed"),
+	}.TweakListOptions(&options)
+	if options.LabelSelector != "app=foo,tier=web" || options.FieldSelector != "metadata.name=bar,status.phase!=Failed" {
+		t.Errorf("unexpected selectors %q and %q", options.LabelSelector, options.FieldSelector)
+	}
+	ListSelectors{Label: labels.Everything()}.TweakListOptions(&options)
+	if options.LabelSelector != "app=foo,tier=web" {
+		t.Errorf("unexpected label selector %q", options.LabelSelector)
+	}
+}
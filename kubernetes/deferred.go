@@ -0,0 +1,222 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	rest "k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+)
+
+// ErrClusterNotReady is returned, possibly wrapped, by DeferredClientset
+// while the cluster is not reachable or does not authorize the credentials.
+var ErrClusterNotReady = errors.New("cluster is not ready")
+
+// ReadinessPolicy defines how a DeferredClientset serves callers while the
+// cluster is not ready.
+type ReadinessPolicy int
+
+const (
+	// WaitForReadiness blocks callers until the cluster is ready or their
+	// context is done.
+	WaitForReadiness ReadinessPolicy = iota
+	// FailFast fails callers with ErrClusterNotReady until the cluster is
+	// ready.
+	FailFast
+)
+
+// DeferredClientsetOptions configures a DeferredClientset. Zero values
+// select the defaults.
+type DeferredClientsetOptions struct {
+	// Policy is how callers are served while the cluster is not ready,
+	// waiting by default.
+	Policy ReadinessPolicy
+	// ReachableOnly considers the cluster ready as soon as it responds,
+	// whether or not it authorizes the credentials of the config.
+	ReachableOnly bool
+	// Backoff is the backoff between the probes of the cluster. It
+	// defaults to 500ms doubling up to 30s with a jitter of 10%. Its
+	// steps are ignored, the cluster is probed until it is ready.
+	Backoff wait.Backoff
+}
+
+// DeferredClientset constructs a Clientset once the cluster is reachable and
+// authorizes the credentials of the config, probing it with an exponential
+// backoff. It lets agents start before their cluster is up instead of
+// failing at initialization.
+type DeferredClientset struct {
+	config  *rest.Config
+	options DeferredClientsetOptions
+
+	startOnce sync.Once
+	// ready is closed once the clientset is constructed, done once the
+	// probing is over, successfully or not.
+	ready chan struct{}
+	done  chan struct{}
+
+	lock      sync.Mutex
+	clientset *Clientset
+	err       error
+	lastErr   error
+}
+
+// NewDeferredClientset returns a DeferredClientset for config. Nothing is
+// constructed nor requested until it is started.
+func NewDeferredClientset(config *rest.Config, options DeferredClientsetOptions) *DeferredClientset {
+	if options.Backoff.Duration == 0 {
+		options.Backoff = wait.Backoff{
+			Duration: 500 * time.Millisecond,
+			Factor:   2,
+			Jitter:   0.1,
+			Cap:      30 * time.Second,
+		}
+	}
+	options.Backoff.Steps = math.MaxInt32
+	return &DeferredClientset{
+		config:  rest.CopyConfig(config),
+		options: options,
+		ready:   make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start starts probing the cluster in the background, until the cluster is
+// ready or stopCh is closed. It does nothing if the probing was already
+// started, Clientset and Ready starting it too, without stop channel.
+func (d *DeferredClientset) Start(stopCh <-chan struct{}) {
+	d.startOnce.Do(func() {
+		go d.run(stopCh)
+	})
+}
+
+// Ready returns a channel closed once the cluster is ready and the clientset
+// constructed. It is never closed if the config is invalid.
+func (d *DeferredClientset) Ready() <-chan struct{} {
+	d.Start(nil)
+	return d.ready
+}
+
+// Clientset returns the clientset once the cluster is ready. While it is not,
+// it waits until ctx is done or fails immediately, depending on the policy,
+// returning an error wrapping ErrClusterNotReady. With FailFast, the
+// DeferredClientset should be started early so that the cluster has been
+// probed by the time it is needed.
+func (d *DeferredClientset) Clientset(ctx context.Context) (*Clientset, error) {
+	d.Start(nil)
+	select {
+	case <-d.done:
+		return d.result()
+	default:
+	}
+	if d.options.Policy == FailFast {
+		return nil, d.notReadyError()
+	}
+	select {
+	case <-d.done:
+		return d.result()
+	case <-ctx.Done():
+		return nil, d.notReadyError()
+	}
+}
+
+// LastError returns the reason why the cluster was not ready at the last
+// probe, nil if it is ready or was not probed yet.
+func (d *DeferredClientset) LastError() error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.lastErr
+}
+
+func (d *DeferredClientset) result() (*Clientset, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.clientset, d.err
+}
+
+func (d *DeferredClientset) notReadyError() error {
+	if err := d.LastError(); err != nil {
+		return fmt.Errorf("%w: %v", ErrClusterNotReady, err)
+	}
+	return ErrClusterNotReady
+}
+
+// run probes the cluster until it is ready or stopCh is closed.
+func (d *DeferredClientset) run(stopCh <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	backoff := d.options.Backoff
+	for {
+		report, err := rest.ProbeCluster(ctx, d.config)
+		if err != nil {
+			d.finish(nil, err)
+			return
+		}
+		err = d.readinessError(report)
+		if err == nil {
+			d.finish(NewForConfig(d.config))
+			return
+		}
+		klog.V(2).Infof("Cluster %s is not ready: %v", report.Host, err)
+		d.lock.Lock()
+		d.lastErr = err
+		d.lock.Unlock()
+
+		t := time.NewTimer(backoff.Step())
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// readinessError returns why the probed cluster is not ready, nil if it is.
+func (d *DeferredClientset) readinessError(report *rest.ClusterProbeReport) error {
+	if !report.Reachable {
+		return report.ReachabilityError
+	}
+	if !d.options.ReachableOnly && !report.Authorized {
+		return report.AuthError
+	}
+	return nil
+}
+
+func (d *DeferredClientset) finish(clientset *Clientset, err error) {
+	d.lock.Lock()
+	d.clientset, d.err, d.lastErr = clientset, err, nil
+	d.lock.Unlock()
+	if err == nil {
+		close(d.ready)
+	}
+	close(d.done)
+}
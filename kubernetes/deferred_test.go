@@ -0,0 +1,151 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	rest "k8s.io/client-go/rest"
+)
+
+// newFlakyServer returns a server rejecting the credentials of the first
+// failures discovery requests.
+func newFlakyServer(failures int32) (*httptest.Server, *int32) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch req.URL.Path {
+		case "/version":
+			w.Write([]byte(`{"major":"1","minor":"23"}`))
+		case "/api":
+			if atomic.AddInt32(&requests, 1) <= failures {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{}`))
+				return
+			}
+			w.Write([]byte(`{"kind":"APIVersions","versions":["v1"]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return server, &requests
+}
+
+var testBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 2, Cap: 10 * time.Millisecond}
+
+func TestDeferredClientsetWaits(t *testing.T) {
+	server, requests := newFlakyServer(3)
+	defer server.Close()
+
+	d := NewDeferredClientset(&rest.Config{Host: server.URL}, DeferredClientsetOptions{Backoff: testBackoff})
+	ctx, cancel := context.WithTimeout(context.Background(), wait.ForeverTestTimeout)
+	defer cancel()
+	clientset, err := d.Clientset(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientset == nil {
+		t.Fatal("expected a clientset")
+	}
+	if n := atomic.LoadInt32(requests); n != 4 {
+		t.Errorf("expected 4 probes, got %d", n)
+	}
+	select {
+	case <-d.Ready():
+	default:
+		t.Errorf("expected the clientset to be ready")
+	}
+	if again, err := d.Clientset(ctx); err != nil || again != clientset {
+		t.Errorf("expected the same clientset, got %p, %v", again, err)
+	}
+	if err := d.LastError(); err != nil {
+		t.Errorf("unexpected last error: %v", err)
+	}
+}
+
+func TestDeferredClientsetFailFast(t *testing.T) {
+	server, _ := newFlakyServer(1 << 30)
+	defer server.Close()
+
+	d := NewDeferredClientset(&rest.Config{Host: server.URL}, DeferredClientsetOptions{
+		Policy:  FailFast,
+		Backoff: testBackoff,
+	})
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	d.Start(stopCh)
+
+	if err := wait.PollImmediate(time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		return d.LastError() != nil, nil
+	}); err != nil {
+		t.Fatal("the cluster was not probed")
+	}
+	_, err := d.Clientset(context.Background())
+	if !errors.Is(err, ErrClusterNotReady) {
+		t.Errorf("expected ErrClusterNotReady, got %v", err)
+	}
+	select {
+	case <-d.Ready():
+		t.Errorf("unexpected readiness")
+	default:
+	}
+}
+
+func TestDeferredClientsetReachableOnly(t *testing.T) {
+	server, _ := newFlakyServer(1 << 30)
+	defer server.Close()
+
+	d := NewDeferredClientset(&rest.Config{Host: server.URL}, DeferredClientsetOptions{
+		ReachableOnly: true,
+		Backoff:       testBackoff,
+	})
+	select {
+	case <-d.Ready():
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("expected the cluster to be ready")
+	}
+}
+
+func TestDeferredClientsetContextDone(t *testing.T) {
+	server, _ := newFlakyServer(1 << 30)
+	defer server.Close()
+
+	d := NewDeferredClientset(&rest.Config{Host: server.URL}, DeferredClientsetOptions{Backoff: testBackoff})
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	d.Start(stopCh)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := d.Clientset(ctx); !errors.Is(err, ErrClusterNotReady) {
+		t.Errorf("expected ErrClusterNotReady, got %v", err)
+	}
+}
+
+func TestDeferredClientsetInvalidConfig(t *testing.T) {
+	d := NewDeferredClientset(&rest.Config{Host: "http://[::1"}, DeferredClientsetOptions{Backoff: testBackoff})
+	_, err := d.Clientset(context.Background())
+	if err == nil || errors.Is(err, ErrClusterNotReady) {
+		t.Errorf("expected a config error, got %v", err)
+	}
+}
@@ -0,0 +1,318 @@
+/*
+Copyright 2014 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	admissionregistrationv1 "k8s.io/client-go/kubernetes/typed/admissionregistration/v1"
+	admissionregistrationv1beta1 "k8s.io/client-go/kubernetes/typed/admissionregistration/v1beta1"
+	internalv1alpha1 "k8s.io/client-go/kubernetes/typed/apiserverinternal/v1alpha1"
+	appsv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+	appsv1beta1 "k8s.io/client-go/kubernetes/typed/apps/v1beta1"
+	appsv1beta2 "k8s.io/client-go/kubernetes/typed/apps/v1beta2"
+	authenticationv1 "k8s.io/client-go/kubernetes/typed/authentication/v1"
+	authenticationv1beta1 "k8s.io/client-go/kubernetes/typed/authentication/v1beta1"
+	authorizationv1 "k8s.io/client-go/kubernetes/typed/authorization/v1"
+	authorizationv1beta1 "k8s.io/client-go/kubernetes/typed/authorization/v1beta1"
+	autoscalingv1 "k8s.io/client-go/kubernetes/typed/autoscaling/v1"
+	autoscalingv2 "k8s.io/client-go/kubernetes/typed/autoscaling/v2"
+	autoscalingv2beta1 "k8s.io/client-go/kubernetes/typed/autoscaling/v2beta1"
+	autoscalingv2beta2 "k8s.io/client-go/kubernetes/typed/autoscaling/v2beta2"
+	batchv1 "k8s.io/client-go/kubernetes/typed/batch/v1"
+	batchv1beta1 "k8s.io/client-go/kubernetes/typed/batch/v1beta1"
+	certificatesv1 "k8s.io/client-go/kubernetes/typed/certificates/v1"
+	certificatesv1beta1 "k8s.io/client-go/kubernetes/typed/certificates/v1beta1"
+	coordinationv1 "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	coordinationv1beta1 "k8s.io/client-go/kubernetes/typed/coordination/v1beta1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	discoveryv1 "k8s.io/client-go/kubernetes/typed/discovery/v1"
+	discoveryv1beta1 "k8s.io/client-go/kubernetes/typed/discovery/v1beta1"
+	eventsv1 "k8s.io/client-go/kubernetes/typed/events/v1"
+	eventsv1beta1 "k8s.io/client-go/kubernetes/typed/events/v1beta1"
+	extensionsv1beta1 "k8s.io/client-go/kubernetes/typed/extensions/v1beta1"
+	flowcontrolv1alpha1 "k8s.io/client-go/kubernetes/typed/flowcontrol/v1alpha1"
+	flowcontrolv1beta1 "k8s.io/client-go/kubernetes/typed/flowcontrol/v1beta1"
+	flowcontrolv1beta2 "k8s.io/client-go/kubernetes/typed/flowcontrol/v1beta2"
+	networkingv1 "k8s.io/client-go/kubernetes/typed/networking/v1"
+	networkingv1beta1 "k8s.io/client-go/kubernetes/typed/networking/v1beta1"
+	nodev1 "k8s.io/client-go/kubernetes/typed/node/v1"
+	nodev1alpha1 "k8s.io/client-go/kubernetes/typed/node/v1alpha1"
+	nodev1beta1 "k8s.io/client-go/kubernetes/typed/node/v1beta1"
+	policyv1 "k8s.io/client-go/kubernetes/typed/policy/v1"
+	policyv1beta1 "k8s.io/client-go/kubernetes/typed/policy/v1beta1"
+	rbacv1 "k8s.io/client-go/kubernetes/typed/rbac/v1"
+	rbacv1alpha1 "k8s.io/client-go/kubernetes/typed/rbac/v1alpha1"
+	rbacv1beta1 "k8s.io/client-go/kubernetes/typed/rbac/v1beta1"
+	schedulingv1 "k8s.io/client-go/kubernetes/typed/scheduling/v1"
+	schedulingv1alpha1 "k8s.io/client-go/kubernetes/typed/scheduling/v1alpha1"
+	schedulingv1beta1 "k8s.io/client-go/kubernetes/typed/scheduling/v1beta1"
+	storagev1 "k8s.io/client-go/kubernetes/typed/storage/v1"
+	storagev1alpha1 "k8s.io/client-go/kubernetes/typed/storage/v1alpha1"
+	storagev1beta1 "k8s.io/client-go/kubernetes/typed/storage/v1beta1"
+	rest "k8s.io/client-go/rest"
+	flowcontrol "k8s.io/client-go/util/flowcontrol"
+)
+
+// GroupConfigOverrides overrides the configuration of the clients of all
+// versions of an API group in a Clientset. Zero values keep the configuration
+// of the Clientset.
+type GroupConfigOverrides struct {
+	// QPS and Burst give the group a rate limiter separate from the rate
+	// limiter shared by the other groups. If only one of them is set, the
+	// other one is taken from the config of the Clientset.
+	QPS   float32
+	Burst int
+	// Timeout is the maximum duration of requests to the group.
+	Timeout time.Duration
+	// ContentType is the content type of requests to the group, and the
+	// accepted content type of responses unless the config of the Clientset
+	// sets AcceptContentTypes.
+	ContentType string
+}
+
+// NewForConfigWithOverrides creates a new Clientset for the given config like
+// NewForConfig, and configures the clients of the API groups in overrides
+// differently. overrides is keyed by group name, with "" for the core group.
+// The clients of all groups share a transport, and the clients of groups
+// without their own QPS or Burst share a rate limiter as with NewForConfig.
+func NewForConfigWithOverrides(c *rest.Config, overrides map[string]GroupConfigOverrides) (*Clientset, error) {
+	configShallowCopy := *c
+
+	// share the transport between all clients
+	httpClient, err := rest.HTTPClientFor(&configShallowCopy)
+	if err != nil {
+		return nil, err
+	}
+	// create the shared rate limiter here to keep it out of overridden configs
+	if configShallowCopy.RateLimiter == nil && configShallowCopy.QPS > 0 {
+		if configShallowCopy.Burst <= 0 {
+			return nil, fmt.Errorf("burst is required to be greater than 0 when RateLimiter is not set and QPS is set to greater than 0")
+		}
+		configShallowCopy.RateLimiter = flowcontrol.NewTokenBucketRateLimiter(configShallowCopy.QPS, configShallowCopy.Burst)
+	}
+	cs, err := NewForConfigAndClient(&configShallowCopy, httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	for group, o := range overrides {
+		groupConfig := configShallowCopy
+		groupHTTPClient := httpClient
+		if o.QPS > 0 || o.Burst > 0 {
+			if o.QPS > 0 {
+				groupConfig.QPS = o.QPS
+			}
+			if o.Burst > 0 {
+				groupConfig.Burst = o.Burst
+			}
+			qps, burst := groupConfig.QPS, groupConfig.Burst
+			if qps == 0 {
+				qps = rest.DefaultQPS
+			}
+			if burst == 0 {
+				burst = rest.DefaultBurst
+			}
+			// create the rate limiter of the group here so that the clients of
+			// all its versions share it
+			groupConfig.RateLimiter = flowcontrol.NewTokenBucketRateLimiter(qps, burst)
+		}
+		if o.Timeout > 0 {
+			groupConfig.Timeout = o.Timeout
+			timeoutClient := *httpClient
+			timeoutClient.Timeout = o.Timeout
+			groupHTTPClient = &timeoutClient
+		}
+		if len(o.ContentType) > 0 {
+			groupConfig.ContentType = o.ContentType
+		}
+		if err := cs.setGroupClients(group, &groupConfig, groupHTTPClient); err != nil {
+			return nil, err
+		}
+	}
+	return cs, nil
+}
+
+// setGroupClients replaces the clients of all versions of group in cs with
+// clients created for c and httpClient. It lists the groups and versions of
+// NewForConfigAndClient, TestSetGroupClientsCoversClientset checks that it
+// covers all of them.
+func (cs *Clientset) setGroupClients(group string, c *rest.Config, httpClient *http.Client) error {
+	var err error
+	switch group {
+	case "admissionregistration.k8s.io":
+		if cs.admissionregistrationV1, err = admissionregistrationv1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+		if cs.admissionregistrationV1beta1, err = admissionregistrationv1beta1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+	case "internal.apiserver.k8s.io":
+		if cs.internalV1alpha1, err = internalv1alpha1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+	case "apps":
+		if cs.appsV1, err = appsv1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+		if cs.appsV1beta1, err = appsv1beta1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+		if cs.appsV1beta2, err = appsv1beta2.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+	case "authentication.k8s.io":
+		if cs.authenticationV1, err = authenticationv1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+		if cs.authenticationV1beta1, err = authenticationv1beta1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+	case "authorization.k8s.io":
+		if cs.authorizationV1, err = authorizationv1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+		if cs.authorizationV1beta1, err = authorizationv1beta1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+	case "autoscaling":
+		if cs.autoscalingV1, err = autoscalingv1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+		if cs.autoscalingV2, err = autoscalingv2.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+		if cs.autoscalingV2beta1, err = autoscalingv2beta1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+		if cs.autoscalingV2beta2, err = autoscalingv2beta2.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+	case "batch":
+		if cs.batchV1, err = batchv1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+		if cs.batchV1beta1, err = batchv1beta1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+	case "certificates.k8s.io":
+		if cs.certificatesV1, err = certificatesv1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+		if cs.certificatesV1beta1, err = certificatesv1beta1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+	case "coordination.k8s.io":
+		if cs.coordinationV1beta1, err = coordinationv1beta1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+		if cs.coordinationV1, err = coordinationv1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+	case "":
+		if cs.coreV1, err = corev1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+	case "discovery.k8s.io":
+		if cs.discoveryV1, err = discoveryv1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+		if cs.discoveryV1beta1, err = discoveryv1beta1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+	case "events.k8s.io":
+		if cs.eventsV1, err = eventsv1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+		if cs.eventsV1beta1, err = eventsv1beta1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+	case "extensions":
+		if cs.extensionsV1beta1, err = extensionsv1beta1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+	case "flowcontrol.apiserver.k8s.io":
+		if cs.flowcontrolV1alpha1, err = flowcontrolv1alpha1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+		if cs.flowcontrolV1beta1, err = flowcontrolv1beta1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+		if cs.flowcontrolV1beta2, err = flowcontrolv1beta2.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+	case "networking.k8s.io":
+		if cs.networkingV1, err = networkingv1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+		if cs.networkingV1beta1, err = networkingv1beta1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+	case "node.k8s.io":
+		if cs.nodeV1, err = nodev1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+		if cs.nodeV1alpha1, err = nodev1alpha1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+		if cs.nodeV1beta1, err = nodev1beta1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+	case "policy":
+		if cs.policyV1, err = policyv1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+		if cs.policyV1beta1, err = policyv1beta1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+	case "rbac.authorization.k8s.io":
+		if cs.rbacV1, err = rbacv1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+		if cs.rbacV1beta1, err = rbacv1beta1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+		if cs.rbacV1alpha1, err = rbacv1alpha1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+	case "scheduling.k8s.io":
+		if cs.schedulingV1alpha1, err = schedulingv1alpha1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+		if cs.schedulingV1beta1, err = schedulingv1beta1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+		if cs.schedulingV1, err = schedulingv1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+	case "storage.k8s.io":
+		if cs.storageV1beta1, err = storagev1beta1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+		if cs.storageV1, err = storagev1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+		if cs.storageV1alpha1, err = storagev1alpha1.NewForConfigAndClient(c, httpClient); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown API group %q", group)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2014 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	rest "k8s.io/client-go/rest"
+)
+
+func TestNewForConfigWithOverrides(t *testing.T) {
+	var lock sync.Mutex
+	contentTypes := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		contentTypes[req.URL.Path] = req.Header.Get("Content-Type")
+		lock.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	cs, err := NewForConfigWithOverrides(&rest.Config{Host: server.URL, QPS: 10, Burst: 20}, map[string]GroupConfigOverrides{
+		"events.k8s.io": {QPS: 1, Timeout: time.Minute, ContentType: "application/vnd.kubernetes.protobuf"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	core := cs.CoreV1().RESTClient().(*rest.RESTClient)
+	apps := cs.AppsV1().RESTClient().(*rest.RESTClient)
+	events := cs.EventsV1().RESTClient().(*rest.RESTClient)
+	eventsBeta := cs.EventsV1beta1().RESTClient().(*rest.RESTClient)
+
+	if core.GetRateLimiter() != apps.GetRateLimiter() {
+		t.Error("expected groups without overrides to share a rate limiter")
+	}
+	if events.GetRateLimiter() == core.GetRateLimiter() || eventsBeta.GetRateLimiter() == core.GetRateLimiter() {
+		t.Error("expected the events group to have its own rate limiter")
+	}
+	if events.GetRateLimiter() != eventsBeta.GetRateLimiter() {
+		t.Error("expected the versions of the events group to share a rate limiter")
+	}
+	if qps := events.GetRateLimiter().QPS(); qps != 1 {
+		t.Errorf("expected a QPS of 1 for events, got %v", qps)
+	}
+	if core.Client.Timeout != 0 || events.Client.Timeout != time.Minute {
+		t.Errorf("unexpected timeouts %v and %v", core.Client.Timeout, events.Client.Timeout)
+	}
+	if core.Client.Transport != events.Client.Transport {
+		t.Error("expected groups to share the transport")
+	}
+
+	ctx := context.Background()
+	if _, err := cs.EventsV1().Events("ns").Create(ctx, &eventsv1.Event{ObjectMeta: metav1.ObjectMeta{Name: "e"}}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cs.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns"}}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if contentType := contentTypes["/apis/events.k8s.io/v1/namespaces/ns/events"]; contentType != "application/vnd.kubernetes.protobuf" {
+		t.Errorf("unexpected content type of events %q", contentType)
+	}
+	if contentType := contentTypes["/api/v1/namespaces"]; contentType != "application/json" {
+		t.Errorf("unexpected content type of namespaces %q", contentType)
+	}
+}
+
+func TestNewForConfigWithOverridesUnknownGroup(t *testing.T) {
+	_, err := NewForConfigWithOverrides(&rest.Config{Host: "localhost"}, map[string]GroupConfigOverrides{"example.com": {QPS: 1}})
+	if err == nil {
+		t.Error("expected an error for an unknown group")
+	}
+}
+
+// TestSetGroupClientsCoversClientset checks that setGroupClients replaces the
+// clients of every version of every group of the Clientset.
+func TestSetGroupClientsCoversClientset(t *testing.T) {
+	config := &rest.Config{Host: "localhost"}
+	cs, err := NewForConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type groupClient interface {
+		RESTClient() rest.Interface
+	}
+	clients := func(cs *Clientset) map[string]groupClient {
+		result := map[string]groupClient{}
+		v := reflect.ValueOf(cs)
+		for i := 0; i < v.NumMethod(); i++ {
+			method := v.Type().Method(i)
+			if method.Name == "Discovery" || method.Type.NumIn() != 1 || method.Type.NumOut() != 1 {
+				continue
+			}
+			if client, ok := v.Method(i).Call(nil)[0].Interface().(groupClient); ok {
+				result[method.Name] = client
+			}
+		}
+		return result
+	}
+
+	before := clients(cs)
+	groups := map[string]bool{}
+	for _, client := range before {
+		groups[client.RESTClient().APIVersion().Group] = true
+	}
+	for group := range groups {
+		if err := cs.setGroupClients(group, config, http.DefaultClient); err != nil {
+			t.Fatalf("group %q: %v", group, err)
+		}
+	}
+	for name, client := range clients(cs) {
+		if client == before[name] {
+			t.Errorf("expected the client of %s to be replaced", name)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ownergraph builds the graph of the owner references of objects
+// from metadata informers, like the garbage collector does, to tell the
+// children of objects and whether objects are orphaned.
+package ownergraph // import "k8s.io/client-go/metadata/ownergraph"
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/metadata/metadatainformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Node is an object of a Graph.
+type Node struct {
+	// Resource is the resource of the object.
+	Resource        schema.GroupVersionResource
+	Namespace       string
+	Name            string
+	UID             types.UID
+	OwnerReferences []metav1.OwnerReference
+	// BeingDeleted is whether the object has a deletion timestamp.
+	BeingDeleted bool
+}
+
+// Graph is the graph of the owner references of the objects of a set of
+// resources, kept up to date by metadata informers. Objects are identified
+// by their UIDs, like owner references identify their owners.
+type Graph struct {
+	mapper    meta.RESTMapper
+	resources map[schema.GroupResource]bool
+	synced    []cache.InformerSynced
+
+	lock  sync.RWMutex
+	nodes map[types.UID]*Node
+	// children are the UIDs of the children of owners, which may not be in
+	// the graph
+	children map[types.UID]map[types.UID]bool
+}
+
+// NewGraph returns the graph of the objects of resources, watched by the
+// informers of factory, which must be started afterwards. mapper maps the
+// kinds of owner references to their resources, to tell whether the owners
+// missing from the graph exist.
+func NewGraph(factory metadatainformer.SharedInformerFactory, mapper meta.RESTMapper, resources ...schema.GroupVersionResource) *Graph {
+	g := &Graph{
+		mapper:    mapper,
+		resources: map[schema.GroupResource]bool{},
+		nodes:     map[types.UID]*Node{},
+		children:  map[types.UID]map[types.UID]bool{},
+	}
+	for _, resource := range resources {
+		resource := resource
+		g.resources[resource.GroupResource()] = true
+		informer := factory.ForResource(resource).Informer()
+		informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				g.set(resource, obj)
+			},
+			UpdateFunc: func(_, obj interface{}) {
+				g.set(resource, obj)
+			},
+			DeleteFunc: func(obj interface{}) {
+				if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+					obj = tombstone.Obj
+				}
+				g.delete(obj)
+			},
+		})
+		g.synced = append(g.synced, informer.HasSynced)
+	}
+	return g
+}
+
+// HasSynced returns whether the informers of all the resources of the graph
+// have synced.
+func (g *Graph) HasSynced() bool {
+	for _, synced := range g.synced {
+		if !synced() {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *Graph) set(resource schema.GroupVersionResource, obj interface{}) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("unable to add %T to the owner graph: %v", obj, err))
+		return
+	}
+	node := &Node{
+		Resource:        resource,
+		Namespace:       accessor.GetNamespace(),
+		Name:            accessor.GetName(),
+		UID:             accessor.GetUID(),
+		OwnerReferences: accessor.GetOwnerReferences(),
+		BeingDeleted:    accessor.GetDeletionTimestamp() != nil,
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if old, ok := g.nodes[node.UID]; ok {
+		g.removeOwnersLocked(old)
+	}
+	g.nodes[node.UID] = node
+	for _, owner := range node.OwnerReferences {
+		children, ok := g.children[owner.UID]
+		if !ok {
+			children = map[types.UID]bool{}
+			g.children[owner.UID] = children
+		}
+		children[node.UID] = true
+	}
+}
+
+func (g *Graph) delete(obj interface{}) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("unable to remove %T from the owner graph: %v", obj, err))
+		return
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	node, ok := g.nodes[accessor.GetUID()]
+	if !ok {
+		return
+	}
+	g.removeOwnersLocked(node)
+	delete(g.nodes, node.UID)
+}
+
+// removeOwnersLocked removes node from the children of its owners.
+func (g *Graph) removeOwnersLocked(node *Node) {
+	for _, owner := range node.OwnerReferences {
+		children := g.children[owner.UID]
+		delete(children, node.UID)
+		if len(children) == 0 {
+			delete(g.children, owner.UID)
+		}
+	}
+}
+
+// Get returns the object uid, if it is in the graph.
+func (g *Graph) Get(uid types.UID) (Node, bool) {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	node, ok := g.nodes[uid]
+	if !ok {
+		return Node{}, false
+	}
+	return *node, true
+}
+
+// Children returns the objects owned by the object uid, sorted by namespace
+// and name. The object itself may be missing from the graph.
+func (g *Graph) Children(uid types.UID) []Node {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	var children []Node
+	for child := range g.children[uid] {
+		if node, ok := g.nodes[child]; ok {
+			children = append(children, *node)
+		}
+	}
+	sortNodes(children)
+	return children
+}
+
+// Descendants returns the objects owned by the object uid, directly or
+// through other objects, sorted by namespace and name.
+func (g *Graph) Descendants(uid types.UID) []Node {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	var descendants []Node
+	// owner references may form cycles
+	visited := map[types.UID]bool{uid: true}
+	queue := []types.UID{uid}
+	for len(queue) > 0 {
+		owner := queue[0]
+		queue = queue[1:]
+		for child := range g.children[owner] {
+			if visited[child] {
+				continue
+			}
+			visited[child] = true
+			if node, ok := g.nodes[child]; ok {
+				descendants = append(descendants, *node)
+				queue = append(queue, child)
+			}
+		}
+	}
+	sortNodes(descendants)
+	return descendants
+}
+
+// IsOrphaned returns whether the object uid has owner references, and none of
+// its owners exists. It returns an error if the object is not in the graph,
+// or if it has owners of resources the graph does not hold, which it cannot
+// tell exist. The graph should have synced.
+func (g *Graph) IsOrphaned(uid types.UID) (bool, error) {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	node, ok := g.nodes[uid]
+	if !ok {
+		return false, fmt.Errorf("object %s is not in the owner graph", uid)
+	}
+	if len(node.OwnerReferences) == 0 {
+		return false, nil
+	}
+	for _, owner := range node.OwnerReferences {
+		if _, ok := g.nodes[owner.UID]; ok {
+			return false, nil
+		}
+	}
+	// all the owners are missing from the graph, which must hold their
+	// resources for them not to exist
+	for _, owner := range node.OwnerReferences {
+		resource, err := g.resourceFor(owner)
+		if err != nil {
+			return false, err
+		}
+		if !g.resources[resource] {
+			return false, fmt.Errorf("unable to tell whether the owner %s %s of %s/%s exists: %s is not in the owner graph", owner.Kind, owner.Name, node.Namespace, node.Name, resource)
+		}
+	}
+	return true, nil
+}
+
+// resourceFor returns the resource of the kind of owner.
+func (g *Graph) resourceFor(owner metav1.OwnerReference) (schema.GroupResource, error) {
+	gv, err := schema.ParseGroupVersion(owner.APIVersion)
+	if err != nil {
+		return schema.GroupResource{}, err
+	}
+	mapping, err := g.mapper.RESTMapping(gv.WithKind(owner.Kind).GroupKind(), gv.Version)
+	if err != nil {
+		return schema.GroupResource{}, err
+	}
+	return mapping.Resource.GroupResource(), nil
+}
+
+func sortNodes(nodes []Node) {
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Namespace != nodes[j].Namespace {
+			return nodes[i].Namespace < nodes[j].Namespace
+		}
+		return nodes[i].Name < nodes[j].Name
+	})
+}
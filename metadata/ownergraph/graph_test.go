@@ -0,0 +1,161 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ownergraph
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/metadata/fake"
+	"k8s.io/client-go/metadata/metadatainformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+var (
+	deployments = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	replicaSets = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "replicasets"}
+	pods        = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+)
+
+func newObject(apiVersion, kind, name string, uid types.UID, owners ...metav1.OwnerReference) *metav1.PartialObjectMetadata {
+	return &metav1.PartialObjectMetadata{
+		TypeMeta: metav1.TypeMeta{APIVersion: apiVersion, Kind: kind},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       "default",
+			Name:            name,
+			UID:             uid,
+			OwnerReferences: owners,
+		},
+	}
+}
+
+func ownedBy(apiVersion, kind, name string, uid types.UID) metav1.OwnerReference {
+	return metav1.OwnerReference{APIVersion: apiVersion, Kind: kind, Name: name, UID: uid}
+}
+
+func names(nodes []Node) []string {
+	var result []string
+	for _, node := range nodes {
+		result = append(result, node.Name)
+	}
+	return result
+}
+
+func TestGraph(t *testing.T) {
+	scheme := runtime.NewScheme()
+	metav1.AddMetaToScheme(scheme)
+	client := fake.NewSimpleMetadataClient(scheme,
+		newObject("apps/v1", "Deployment", "web", "d1"),
+		newObject("apps/v1", "ReplicaSet", "web-1", "rs1", ownedBy("apps/v1", "Deployment", "web", "d1")),
+		newObject("v1", "Pod", "web-1-a", "p1", ownedBy("apps/v1", "ReplicaSet", "web-1", "rs1")),
+		newObject("v1", "Pod", "web-1-b", "p2", ownedBy("apps/v1", "ReplicaSet", "web-1", "rs1")),
+		newObject("v1", "Pod", "orphan", "p3", ownedBy("apps/v1", "ReplicaSet", "web-0", "rs0")),
+		newObject("v1", "Pod", "configured", "p4", ownedBy("v1", "ConfigMap", "config", "cm1")),
+		newObject("v1", "Pod", "standalone", "p5"),
+	)
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "ReplicaSet"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+
+	factory := metadatainformer.NewSharedInformerFactory(client, 0)
+	graph := NewGraph(factory, mapper, deployments, replicaSets, pods)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), graph.HasSynced) {
+		t.Fatal("the graph did not sync")
+	}
+
+	if node, ok := graph.Get("rs1"); !ok || node.Name != "web-1" || node.Resource != replicaSets {
+		t.Errorf("unexpected node %#v", node)
+	}
+	if children := names(graph.Children("rs1")); !reflect.DeepEqual(children, []string{"web-1-a", "web-1-b"}) {
+		t.Errorf("unexpected children %v", children)
+	}
+	if descendants := names(graph.Descendants("d1")); !reflect.DeepEqual(descendants, []string{"web-1", "web-1-a", "web-1-b"}) {
+		t.Errorf("unexpected descendants %v", descendants)
+	}
+	// the children of owners missing from the graph are known
+	if children := names(graph.Children("rs0")); !reflect.DeepEqual(children, []string{"orphan"}) {
+		t.Errorf("unexpected children %v", children)
+	}
+
+	for uid, expected := range map[types.UID]bool{"d1": false, "rs1": false, "p1": false, "p3": true, "p5": false} {
+		orphaned, err := graph.IsOrphaned(uid)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", uid, err)
+		} else if orphaned != expected {
+			t.Errorf("%s: expected orphaned %t, got %t", uid, expected, orphaned)
+		}
+	}
+	// config maps are not in the graph
+	if _, err := graph.IsOrphaned("p4"); err == nil {
+		t.Error("expected an error for owners of resources missing from the graph")
+	}
+	if _, err := graph.IsOrphaned("unknown"); err == nil {
+		t.Error("expected an error for objects missing from the graph")
+	}
+
+	if err := client.Resource(deployments).Namespace("default").Delete(ctx, "web", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		_, ok := graph.Get("d1")
+		return !ok, nil
+	})
+	if err != nil {
+		t.Fatal("the deleted deployment is still in the graph")
+	}
+	if orphaned, err := graph.IsOrphaned("rs1"); err != nil || !orphaned {
+		t.Errorf("expected the replica set to be orphaned, got %t (%v)", orphaned, err)
+	}
+	if descendants := names(graph.Descendants("d1")); !reflect.DeepEqual(descendants, []string{"web-1", "web-1-a", "web-1-b"}) {
+		t.Errorf("unexpected descendants of the deleted deployment %v", descendants)
+	}
+}
+
+func TestGraphUpdatesOwners(t *testing.T) {
+	g := &Graph{nodes: map[types.UID]*Node{}, children: map[types.UID]map[types.UID]bool{}}
+	pod := newObject("v1", "Pod", "a", "p1", ownedBy("apps/v1", "ReplicaSet", "old", "rs1"))
+	g.set(pods, pod)
+
+	// the pod is adopted by another replica set
+	adopted := pod.DeepCopy()
+	adopted.OwnerReferences = []metav1.OwnerReference{ownedBy("apps/v1", "ReplicaSet", "new", "rs2")}
+	g.set(pods, adopted)
+	if children := g.Children("rs1"); len(children) != 0 {
+		t.Errorf("expected no children of the previous owner, got %v", names(children))
+	}
+	if children := names(g.Children("rs2")); !reflect.DeepEqual(children, []string{"a"}) {
+		t.Errorf("unexpected children of the new owner %v", children)
+	}
+
+	g.delete(adopted)
+	if _, ok := g.Get("p1"); ok || len(g.children) != 0 {
+		t.Errorf("expected the pod to be removed, got %v", g.children)
+	}
+}
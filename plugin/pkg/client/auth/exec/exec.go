@@ -18,6 +18,7 @@ package exec
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
@@ -44,10 +45,12 @@ import (
 	clientauthenticationv1 "k8s.io/client-go/pkg/apis/clientauthentication/v1"
 	clientauthenticationv1alpha1 "k8s.io/client-go/pkg/apis/clientauthentication/v1alpha1"
 	clientauthenticationv1beta1 "k8s.io/client-go/pkg/apis/clientauthentication/v1beta1"
+	"k8s.io/client-go/plugin/pkg/client/auth/exec/internal/fakeplugin"
 	"k8s.io/client-go/tools/clientcmd/api"
 	"k8s.io/client-go/tools/metrics"
 	"k8s.io/client-go/transport"
 	"k8s.io/client-go/util/connrotation"
+	"k8s.io/client-go/util/tokencache"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/clock"
 )
@@ -159,6 +162,10 @@ func (s *sometimes) Do(f func()) {
 	}
 }
 
+// interactiveLock serializes interactive plugin runs across all
+// Authenticators in the process, since they share the same terminal.
+var interactiveLock sync.Mutex
+
 // GetAuthenticator returns an exec-based plugin for providing client credentials.
 func GetAuthenticator(config *api.ExecConfig, cluster *clientauthentication.Cluster) (*Authenticator, error) {
 	return newAuthenticator(globalCache, term.IsTerminal, config, cluster)
@@ -205,6 +212,10 @@ func newAuthenticator(c *cache, isTerminalFunc func(int) bool, config *api.ExecC
 		connTracker:   connTracker,
 	}
 
+	// The credentials are renewed once expired, like before the cache
+	// existed, as plugins may not hand out new credentials before that.
+	a.creds = tokencache.New(a.fetchCreds, tokencache.Options{RenewAfter: 1, Clock: authenticatorClock{a}})
+
 	for _, env := range config.Env {
 		a.env = append(a.env, env.Name+"="+env.Value)
 	}
@@ -212,9 +223,22 @@ func newAuthenticator(c *cache, isTerminalFunc func(int) bool, config *api.ExecC
 	return c.put(key, a), nil
 }
 
+// interactiveMode returns the interactive mode of the plugin. Configs that were
+// not loaded from a kubeconfig file skip defaulting, so the kubeconfig default
+// for older API versions is applied here as well.
+func interactiveMode(config *api.ExecConfig) api.ExecInteractiveMode {
+	if len(config.InteractiveMode) == 0 {
+		switch config.APIVersion {
+		case "client.authentication.k8s.io/v1beta1", "client.authentication.k8s.io/v1alpha1":
+			return api.IfAvailableExecInteractiveMode
+		}
+	}
+	return config.InteractiveMode
+}
+
 func isInteractive(isTerminalFunc func(int) bool, config *api.ExecConfig) (bool, error) {
 	var shouldBeInteractive bool
-	switch config.InteractiveMode {
+	switch mode := interactiveMode(config); mode {
 	case api.NeverExecInteractiveMode:
 		shouldBeInteractive = false
 	case api.IfAvailableExecInteractiveMode:
@@ -233,7 +257,7 @@ func isInteractive(isTerminalFunc func(int) bool, config *api.ExecConfig) (bool,
 		}
 		shouldBeInteractive = true
 	default:
-		return false, fmt.Errorf("unknown interactiveMode: %q", config.InteractiveMode)
+		return false, fmt.Errorf("unknown interactiveMode: %q", mode)
 	}
 
 	return shouldBeInteractive, nil
@@ -268,6 +292,10 @@ type Authenticator struct {
 	// connTracker tracks all connections opened that we need to close when rotating a client certificate
 	connTracker *connrotation.ConnectionTracker
 
+	// creds caches the credentials returned by the plugin, under the empty
+	// key, and runs the plugin once for concurrent callers.
+	creds *tokencache.Cache
+
 	// Cached results.
 	//
 	// The mutex also guards calling the plugin. Since the plugin could be
@@ -275,8 +303,20 @@ type Authenticator struct {
 	mu          sync.Mutex
 	cachedCreds *credentials
 	exp         time.Time
+	// response is the response which made the credentials be refreshed,
+	// passed to the next run of the plugin.
+	response *clientauthentication.Response
 }
 
+// authenticatorClock is the clock of the credentials cache of an
+// Authenticator, following its now func.
+type authenticatorClock struct {
+	a *Authenticator
+}
+
+func (c authenticatorClock) Now() time.Time                  { return c.a.now() }
+func (c authenticatorClock) Since(t time.Time) time.Duration { return c.a.now().Sub(t) }
+
 type credentials struct {
 	token string           `datapolicy:"token"`
 	cert  *tls.Certificate `datapolicy:"secret-key"`
@@ -335,35 +375,32 @@ func (r *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 		return r.base.RoundTrip(req)
 	}
 
-	creds, err := r.a.getCreds()
-	if err != nil {
-		return nil, fmt.Errorf("getting credentials: %v", err)
-	}
-	if creds.token != "" {
-		req.Header.Set("Authorization", "Bearer "+creds.token)
-	}
-
-	res, err := r.base.RoundTrip(req)
-	if err != nil {
-		return nil, err
-	}
-	if res.StatusCode == http.StatusUnauthorized {
+	// On 401 Unauthorized the plugin is run again and, if it succeeds, the
+	// request is retried once with the new credentials.
+	var creds *credentials
+	return transport.RoundTripWithUnauthorizedRetry(req, func(req *http.Request) (*http.Response, error) {
+		var err error
+		creds, err = r.a.getCreds()
+		if err != nil {
+			return nil, fmt.Errorf("getting credentials: %v", err)
+		}
+		if creds.token != "" {
+			req.Header.Set("Authorization", "Bearer "+creds.token)
+		} else {
+			req.Header.Del("Authorization")
+		}
+		return r.base.RoundTrip(req)
+	}, func(res *http.Response) bool {
 		resp := &clientauthentication.Response{
 			Header: res.Header,
 			Code:   int32(res.StatusCode),
 		}
 		if err := r.a.maybeRefreshCreds(creds, resp); err != nil {
 			klog.Errorf("refreshing credentials: %v", err)
+			return false
 		}
-	}
-	return res, nil
-}
-
-func (a *Authenticator) credsExpired() bool {
-	if a.exp.IsZero() {
-		return false
-	}
-	return a.now().After(a.exp)
+		return true
+	})
 }
 
 func (a *Authenticator) cert() (*tls.Certificate, error) {
@@ -375,34 +412,43 @@ func (a *Authenticator) cert() (*tls.Certificate, error) {
 }
 
 func (a *Authenticator) getCreds() (*credentials, error) {
+	creds, err := a.creds.Get(context.Background(), "")
+	if err != nil {
+		return nil, err
+	}
+	return creds.(*credentials), nil
+}
+
+// fetchCreds executes the plugin for the credentials cache.
+func (a *Authenticator) fetchCreds(ctx context.Context, _ string) (interface{}, time.Time, error) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	if a.cachedCreds != nil && !a.credsExpired() {
-		return a.cachedCreds, nil
+	r := a.response
+	a.response = nil
+	if err := a.refreshCredsLocked(r); err != nil {
+		return nil, time.Time{}, err
 	}
-
-	if err := a.refreshCredsLocked(nil); err != nil {
-		return nil, err
-	}
-
-	return a.cachedCreds, nil
+	return a.cachedCreds, a.exp, nil
 }
 
 // maybeRefreshCreds executes the plugin to force a rotation of the
 // credentials, unless they were rotated already.
 func (a *Authenticator) maybeRefreshCreds(creds *credentials, r *clientauthentication.Response) error {
 	a.mu.Lock()
-	defer a.mu.Unlock()
-
 	// Since we're not making a new pointer to a.cachedCreds in getCreds, no
 	// need to do deep comparison.
 	if creds != a.cachedCreds {
 		// Credentials already rotated.
+		a.mu.Unlock()
 		return nil
 	}
+	a.response = r
+	a.mu.Unlock()
 
-	return a.refreshCredsLocked(r)
+	a.creds.Invalidate("")
+	_, err := a.getCreds()
+	return err
 }
 
 // refreshCredsLocked executes the plugin and reads the credentials from
@@ -431,15 +477,18 @@ func (a *Authenticator) refreshCredsLocked(r *clientauthentication.Response) err
 	env = append(env, fmt.Sprintf("%s=%s", execInfoEnv, data))
 
 	stdout := &bytes.Buffer{}
-	cmd := exec.Command(a.cmd, a.args...)
-	cmd.Env = env
-	cmd.Stderr = a.stderr
-	cmd.Stdout = stdout
+	var stdin io.Reader
 	if interactive {
-		cmd.Stdin = a.stdin
+		stdin = a.stdin
+		// Only one interactive plugin may own the terminal at a time, otherwise
+		// prompts from clients refreshing credentials concurrently interleave.
+		interactiveLock.Lock()
 	}
 
-	err = cmd.Run()
+	err = a.run(env, stdin, stdout)
+	if interactive {
+		interactiveLock.Unlock()
+	}
 	incrementCallsMetric(err)
 	if err != nil {
 		return a.wrapCmdRunErrorLocked(err)
@@ -512,6 +561,19 @@ func (a *Authenticator) refreshCredsLocked(r *clientauthentication.Response) err
 	return nil
 }
 
+// run runs the plugin, in-process if it was faked by a test.
+func (a *Authenticator) run(env []string, stdin io.Reader, stdout io.Writer) error {
+	if plugin := fakeplugin.For(a.cmd); plugin != nil {
+		return plugin.Run(a.args, env, stdin, stdout, a.stderr)
+	}
+	cmd := exec.Command(a.cmd, a.args...)
+	cmd.Env = env
+	cmd.Stdin = stdin
+	cmd.Stderr = a.stderr
+	cmd.Stdout = stdout
+	return cmd.Run()
+}
+
 // wrapCmdRunErrorLocked pulls out the code to construct a helpful error message
 // for when the exec plugin's binary fails to Run().
 //
@@ -531,12 +593,11 @@ func (a *Authenticator) wrapCmdRunErrorLocked(err error) error {
 
 		return errors.New(builder.String())
 
-	case *exec.ExitError: // Binary execution failed (see exec.Cmd.Run()).
-		e := err.(*exec.ExitError)
+	case interface{ ExitCode() int }: // Binary execution failed (see exec.Cmd.Run() and fakeplugin.Plugin.Run()).
 		return fmt.Errorf(
 			"exec: executable %s failed with exit code %d",
 			a.cmd,
-			e.ProcessState.ExitCode(),
+			err.(interface{ ExitCode() int }).ExitCode(),
 		)
 
 	default:
@@ -39,6 +39,7 @@ import (
 
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/pkg/apis/clientauthentication"
 	"k8s.io/client-go/tools/clientcmd/api"
 	"k8s.io/client-go/transport"
@@ -1010,6 +1011,28 @@ func TestRefreshCreds(t *testing.T) {
 			wantErr:       true,
 			wantErrSubstr: `exec plugin cannot support interactive mode: unknown interactiveMode: ""`,
 		},
+		{
+			name: "v1beta1-with-missing-interactive-mode-defaults-to-if-available",
+			config: api.ExecConfig{
+				APIVersion: "client.authentication.k8s.io/v1beta1",
+			},
+			isTerminal: true,
+			wantInput: `{
+				"kind":"ExecCredential",
+				"apiVersion":"client.authentication.k8s.io/v1beta1",
+				"spec": {
+					"interactive": true
+				}
+			}`,
+			output: `{
+				"kind": "ExecCredential",
+				"apiVersion": "client.authentication.k8s.io/v1beta1",
+				"status": {
+					"token": "foo-bar"
+				}
+			}`,
+			wantCreds: credentials{token: "foo-bar"},
+		},
 	}
 
 	for _, test := range tests {
@@ -1069,6 +1092,51 @@ func TestRefreshCreds(t *testing.T) {
 	}
 }
 
+func TestInteractivePluginRunsAreSerialized(t *testing.T) {
+	c := api.ExecConfig{
+		Command:         "./testdata/test-plugin.sh",
+		APIVersion:      "client.authentication.k8s.io/v1beta1",
+		InteractiveMode: api.IfAvailableExecInteractiveMode,
+		Env: []api.ExecEnvVar{{
+			Name:  "TEST_OUTPUT",
+			Value: `{"kind":"ExecCredential","apiVersion":"client.authentication.k8s.io/v1beta1","status":{"token":"foo-bar"}}`,
+		}},
+	}
+	a, err := newAuthenticator(newCache(), func(_ int) bool { return true }, &c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.stdin = &bytes.Buffer{}
+	a.stderr = ioutil.Discard
+	a.environ = func() []string { return nil }
+
+	// Simulate another plugin prompting on the terminal.
+	interactiveLock.Lock()
+	done := make(chan error)
+	go func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		done <- a.refreshCredsLocked(nil)
+	}()
+
+	select {
+	case err := <-done:
+		interactiveLock.Unlock()
+		t.Fatalf("interactive plugin ran while another plugin held the terminal: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	interactiveLock.Unlock()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("timed out waiting for the interactive plugin to run")
+	}
+}
+
 func TestRoundTripper(t *testing.T) {
 	wantToken := ""
 
@@ -1155,8 +1223,9 @@ func TestRoundTripper(t *testing.T) {
 	get(t, http.StatusOK)
 
 	wantToken = "token2"
-	// Token is still cached, hits unauthorized but causes token to rotate.
-	get(t, http.StatusUnauthorized)
+	// Token is still cached, hits unauthorized, causes token to rotate and the
+	// request is retried with the rotated token.
+	get(t, http.StatusOK)
 	// Follow up request uses the rotated token.
 	get(t, http.StatusOK)
 
@@ -1170,7 +1239,7 @@ func TestRoundTripper(t *testing.T) {
 	}`)
 	wantToken = "token3"
 	// Token is still cached, hit's unauthorized but causes rotation to token with an expiry.
-	get(t, http.StatusUnauthorized)
+	get(t, http.StatusOK)
 	get(t, http.StatusOK)
 
 	// Move time forward 2 hours, "token3" is now expired.
@@ -0,0 +1,212 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package exectest fakes exec credential plugins in-process, to test how
+// clients rotate credentials and handle the failures of their plugins
+// without building binaries:
+//
+//	plugin := exectest.NewPlugin("my-plugin")
+//	defer plugin.Close()
+//	plugin.Respond(exectest.Response{Token: "token"})
+//
+//	config := &rest.Config{Host: server.URL, ExecProvider: plugin.Config("client.authentication.k8s.io/v1")}
+package exectest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientauthenticationv1 "k8s.io/client-go/pkg/apis/clientauthentication/v1"
+	"k8s.io/client-go/plugin/pkg/client/auth/exec/internal/fakeplugin"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+const execInfoEnv = "KUBERNETES_EXEC_INFO"
+
+// Response is the response of a Plugin to a run.
+type Response struct {
+	// Token is the bearer token returned by the plugin.
+	Token string
+	// ClientCertificateData and ClientKeyData are the PEM-encoded client
+	// certificate and key returned by the plugin.
+	ClientCertificateData string
+	ClientKeyData         string
+	// ExpirationTimestamp is when the returned credentials expire, if set.
+	ExpirationTimestamp time.Time
+
+	// ExitCode fails the run with this exit code, if not 0.
+	ExitCode int
+	// Stderr is written to the standard error of the plugin.
+	Stderr string
+	// Output replaces the ExecCredential written to the standard output of
+	// the plugin if set, to test malformed outputs.
+	Output string
+	// Prompt makes the plugin interactive: it writes Prompt to its standard
+	// error and reads its token from a line of its standard input. The run
+	// fails with exit code 1 if it is not interactive.
+	Prompt string
+}
+
+// Call is a run of a Plugin.
+type Call struct {
+	Args []string
+	Env  []string
+	// APIVersion is the version of the ExecCredential given to the plugin.
+	APIVersion string
+	// Interactive is whether the plugin was run interactively.
+	Interactive bool
+	// Cluster is the cluster given to the plugin, if the exec config
+	// provides cluster info.
+	Cluster *clientauthenticationv1.Cluster
+}
+
+// Plugin is an exec credential plugin returning configured responses. It
+// runs in-process instead of the command it is registered for.
+type Plugin struct {
+	command    string
+	unregister func()
+
+	lock      sync.Mutex
+	responses []Response
+	calls     []Call
+}
+
+var _ fakeplugin.Plugin = &Plugin{}
+
+// NewPlugin returns a Plugin run instead of the given command until it is
+// closed. Commands are not expected to be shared by tests running in
+// parallel, and exec authenticators are cached by config, so each test
+// should use its own command.
+func NewPlugin(command string) *Plugin {
+	p := &Plugin{command: command}
+	p.unregister = fakeplugin.Register(command, p)
+	return p
+}
+
+// Close stops running the plugin instead of its command.
+func (p *Plugin) Close() {
+	p.unregister()
+}
+
+// Config returns an exec config running the plugin with the given API
+// version of ExecCredentials, never interactively.
+func (p *Plugin) Config(apiVersion string) *api.ExecConfig {
+	return &api.ExecConfig{
+		Command:         p.command,
+		APIVersion:      apiVersion,
+		InteractiveMode: api.NeverExecInteractiveMode,
+	}
+}
+
+// Respond queues responses of the next runs of the plugin. Once the queue is
+// empty, the last response is returned by every run.
+func (p *Plugin) Respond(responses ...Response) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.responses = append(p.responses, responses...)
+}
+
+// Calls returns the runs of the plugin, in order.
+func (p *Plugin) Calls() []Call {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return append([]Call(nil), p.calls...)
+}
+
+// Run implements fakeplugin.Plugin.
+func (p *Plugin) Run(args, env []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	var info clientauthenticationv1.ExecCredential
+	for _, v := range env {
+		if strings.HasPrefix(v, execInfoEnv+"=") {
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(v, execInfoEnv+"=")), &info); err != nil {
+				fmt.Fprintf(stderr, "invalid %s: %v\n", execInfoEnv, err)
+				return exitError(1)
+			}
+		}
+	}
+	interactive := info.Spec.Interactive && stdin != nil
+
+	p.lock.Lock()
+	p.calls = append(p.calls, Call{
+		Args:        args,
+		Env:         env,
+		APIVersion:  info.APIVersion,
+		Interactive: interactive,
+		Cluster:     info.Spec.Cluster,
+	})
+	if len(p.responses) == 0 {
+		p.lock.Unlock()
+		fmt.Fprintln(stderr, "no response configured")
+		return exitError(1)
+	}
+	response := p.responses[0]
+	if len(p.responses) > 1 {
+		p.responses = p.responses[1:]
+	}
+	p.lock.Unlock()
+
+	fmt.Fprint(stderr, response.Stderr)
+	if response.ExitCode != 0 {
+		return exitError(response.ExitCode)
+	}
+	if response.Prompt != "" {
+		if !interactive {
+			fmt.Fprintln(stderr, "interactive input required")
+			return exitError(1)
+		}
+		fmt.Fprint(stderr, response.Prompt)
+		line, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		response.Token = strings.TrimSpace(line)
+	}
+	if response.Output != "" {
+		_, err := fmt.Fprint(stdout, response.Output)
+		return err
+	}
+
+	cred := &clientauthenticationv1.ExecCredential{
+		TypeMeta: metav1.TypeMeta{APIVersion: info.APIVersion, Kind: "ExecCredential"},
+		Status: &clientauthenticationv1.ExecCredentialStatus{
+			Token:                 response.Token,
+			ClientCertificateData: response.ClientCertificateData,
+			ClientKeyData:         response.ClientKeyData,
+		},
+	}
+	if !response.ExpirationTimestamp.IsZero() {
+		expiration := metav1.NewTime(response.ExpirationTimestamp)
+		cred.Status.ExpirationTimestamp = &expiration
+	}
+	return json.NewEncoder(stdout).Encode(cred)
+}
+
+// exitError is the exit code of a failed run.
+type exitError int
+
+func (e exitError) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
+func (e exitError) ExitCode() int {
+	return int(e)
+}
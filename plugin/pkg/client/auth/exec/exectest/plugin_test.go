@@ -0,0 +1,185 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exectest
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/plugin/pkg/client/auth/exec"
+	"k8s.io/client-go/transport"
+)
+
+const apiVersion = "client.authentication.k8s.io/v1"
+
+// newClient returns a client of a server accepting the given tokens, which
+// gets its credentials from plugin.
+func newClient(t *testing.T, plugin *Plugin, tokens ...string) (*http.Client, string) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, token := range tokens {
+			if r.Header.Get("Authorization") == "Bearer "+token {
+				return
+			}
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+
+	a, err := exec.GetAuthenticator(plugin.Config(apiVersion), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &transport.Config{}
+	if err := a.UpdateTransportConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	rt, err := transport.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &http.Client{Transport: rt}, server.URL
+}
+
+func get(t *testing.T, client *http.Client, url string) (int, error) {
+	res, err := client.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	res.Body.Close()
+	return res.StatusCode, nil
+}
+
+func TestRotation(t *testing.T) {
+	plugin := NewPlugin("exectest-rotation")
+	defer plugin.Close()
+	plugin.Respond(Response{Token: "revoked"}, Response{Token: "rotated"})
+	client, url := newClient(t, plugin, "rotated")
+
+	for i := 0; i < 2; i++ {
+		if code, err := get(t, client, url); err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d, %v", code, err)
+		}
+	}
+	calls := plugin.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("expected the plugin to run twice, ran %d times", len(calls))
+	}
+	if calls[0].APIVersion != apiVersion || calls[0].Interactive {
+		t.Errorf("unexpected call: %+v", calls[0])
+	}
+}
+
+func TestExpiration(t *testing.T) {
+	plugin := NewPlugin("exectest-expiration")
+	defer plugin.Close()
+	plugin.Respond(
+		Response{Token: "expired", ExpirationTimestamp: time.Now().Add(-time.Minute)},
+		Response{Token: "valid", ExpirationTimestamp: time.Now().Add(time.Hour)},
+	)
+	client, url := newClient(t, plugin, "expired", "valid")
+
+	for i := 0; i < 3; i++ {
+		if code, err := get(t, client, url); err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d, %v", code, err)
+		}
+	}
+	if calls := plugin.Calls(); len(calls) != 2 {
+		t.Errorf("expected the plugin to run twice, ran %d times", len(calls))
+	}
+}
+
+func TestFailures(t *testing.T) {
+	testCases := []struct {
+		name        string
+		response    Response
+		expectedErr string
+	}{
+		{
+			name:        "exit code",
+			response:    Response{ExitCode: 3, Stderr: "failed\n"},
+			expectedErr: "exec: executable exectest-exit-code failed with exit code 3",
+		},
+		{
+			name:        "malformed output",
+			response:    Response{Output: "{"},
+			expectedErr: "decoding stdout",
+		},
+		{
+			name:        "no credentials",
+			response:    Response{},
+			expectedErr: "exec plugin didn't return a token or cert/key pair",
+		},
+		{
+			name:        "not interactive",
+			response:    Response{Prompt: "Password: "},
+			expectedErr: "failed with exit code 1",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			plugin := NewPlugin("exectest-" + strings.ReplaceAll(tc.name, " ", "-"))
+			defer plugin.Close()
+			plugin.Respond(tc.response)
+			client, url := newClient(t, plugin)
+
+			_, err := get(t, client, url)
+			if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+				t.Errorf("expected an error containing %q, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	plugin := NewPlugin("exectest-prompt")
+	defer plugin.Close()
+	plugin.Respond(Response{Prompt: "Password: "})
+
+	env := []string{execInfoEnv + `={"apiVersion":"` + apiVersion + `","kind":"ExecCredential","spec":{"interactive":true}}`}
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := plugin.Run(nil, env, strings.NewReader("secret\n"), stdout, stderr); err != nil {
+		t.Fatal(err)
+	}
+	if stderr.String() != "Password: " {
+		t.Errorf("expected a prompt, got %q", stderr.String())
+	}
+	if !strings.Contains(stdout.String(), `"token":"secret"`) {
+		t.Errorf("expected the token read from stdin, got %s", stdout.String())
+	}
+	if calls := plugin.Calls(); len(calls) != 1 || !calls[0].Interactive {
+		t.Errorf("expected an interactive call, got %+v", calls)
+	}
+}
+
+func TestUnregistered(t *testing.T) {
+	plugin := NewPlugin("exectest-unregistered")
+	plugin.Respond(Response{Token: "token"})
+	plugin.Close()
+	client, url := newClient(t, plugin, "token")
+
+	_, err := get(t, client, url)
+	if err == nil || !strings.Contains(err.Error(), "executable exectest-unregistered not found") {
+		t.Errorf("expected the command to be run, got %v", err)
+	}
+	if calls := plugin.Calls(); len(calls) != 0 {
+		t.Errorf("expected the plugin not to run, got %+v", calls)
+	}
+}
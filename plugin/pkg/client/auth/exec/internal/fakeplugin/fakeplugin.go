@@ -0,0 +1,62 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fakeplugin is a test-only hook running exec credential plugins
+// in-process instead of their commands. It is used by the exectest package
+// and is not meant to be used outside of tests.
+package fakeplugin
+
+import (
+	"io"
+	"sync"
+)
+
+// Plugin is an exec credential plugin run in-process instead of a command,
+// see Register.
+type Plugin interface {
+	// Run runs the plugin like its command would be run, with its arguments
+	// and environment, which includes the KUBERNETES_EXEC_INFO variable.
+	// stdin is nil unless the plugin is run interactively. Errors with an
+	// ExitCode() int method are reported like the exit codes of commands.
+	Run(args, env []string, stdin io.Reader, stdout, stderr io.Writer) error
+}
+
+var plugins = struct {
+	sync.RWMutex
+	m map[string]Plugin
+}{m: map[string]Plugin{}}
+
+// Register makes the exec configs with the given command run plugin instead
+// of the command, until the returned function is called.
+func Register(command string, plugin Plugin) (unregister func()) {
+	plugins.Lock()
+	defer plugins.Unlock()
+	plugins.m[command] = plugin
+	return func() {
+		plugins.Lock()
+		defer plugins.Unlock()
+		if plugins.m[command] == plugin {
+			delete(plugins.m, command)
+		}
+	}
+}
+
+// For returns the plugin registered for command, or nil.
+func For(command string) Plugin {
+	plugins.RLock()
+	defer plugins.RUnlock()
+	return plugins.m[command]
+}
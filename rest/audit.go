@@ -0,0 +1,127 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// auditIDHeader identifies requests, the server using it as the ID of their
+// audit events.
+const auditIDHeader = "Audit-ID"
+
+// AuditRecord describes a request sent by a client.
+type AuditRecord struct {
+	// AuditID identifies the request, in the audit logs of the server too.
+	AuditID string
+	// Verb is the Kubernetes verb of the request, like get, list or watch,
+	// or the lowercase HTTP method for requests that are not to resources.
+	Verb string
+	// Resource is the resource of the request, empty for requests built
+	// without Resource, like the ones to absolute paths.
+	Resource    schema.GroupVersionResource
+	Subresource string
+	Namespace   string
+	Name        string
+	// Path is the path of the request URL.
+	Path      string
+	UserAgent string
+	// Latency is the time until the response headers were received.
+	Latency time.Duration
+	// StatusCode is the status code of the response, or 0 if no response
+	// was received.
+	StatusCode int
+	// Err is the error preventing a response to be received, if any.
+	Err error
+}
+
+// AuditSink receives the audit records of the requests sent by clients. It
+// is called synchronously after every request attempt, including retries,
+// so it should not block.
+type AuditSink interface {
+	Record(record *AuditRecord)
+}
+
+// AuditSinkFunc is an AuditSink calling a function.
+type AuditSinkFunc func(record *AuditRecord)
+
+// Record calls f.
+func (f AuditSinkFunc) Record(record *AuditRecord) {
+	f(record)
+}
+
+// audit sends the audit record of an attempt of the request to the audit
+// sink of the client, if any.
+func (r *Request) audit(req *http.Request, resp *http.Response, err error, latency time.Duration) {
+	if r.c.auditSink == nil {
+		return
+	}
+	record := &AuditRecord{
+		AuditID:     req.Header.Get(auditIDHeader),
+		Verb:        r.auditVerb(),
+		Subresource: r.subresource,
+		Namespace:   r.namespace,
+		Name:        r.resourceName,
+		Path:        req.URL.Path,
+		UserAgent:   req.Header.Get("User-Agent"),
+		Latency:     latency,
+		Err:         err,
+	}
+	if len(record.UserAgent) == 0 {
+		record.UserAgent = r.c.userAgent
+	}
+	if len(r.resource) > 0 {
+		record.Resource = r.c.content.GroupVersion.WithResource(r.resource)
+	}
+	if resp != nil {
+		record.StatusCode = resp.StatusCode
+	}
+	r.c.auditSink.Record(record)
+}
+
+// auditVerb returns the Kubernetes verb of the request.
+func (r *Request) auditVerb() string {
+	if len(r.resource) == 0 {
+		return strings.ToLower(r.verb)
+	}
+	switch r.verb {
+	case "GET":
+		if watch := r.params.Get("watch"); watch == "true" || watch == "1" {
+			return "watch"
+		}
+		if len(r.resourceName) > 0 {
+			return "get"
+		}
+		return "list"
+	case "POST":
+		return "create"
+	case "PUT":
+		return "update"
+	case "PATCH":
+		return "patch"
+	case "DELETE":
+		if len(r.resourceName) > 0 {
+			return "delete"
+		}
+		return "deletecollection"
+	}
+	return strings.ToLower(r.verb)
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+func TestAuditSink(t *testing.T) {
+	var lock sync.Mutex
+	var serverIDs []string
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		serverIDs = append(serverIDs, req.Header.Get(auditIDHeader))
+		lock.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if req.Method == "DELETE" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer testServer.Close()
+
+	var records []*AuditRecord
+	config := &Config{
+		Host:    testServer.URL,
+		APIPath: "/api",
+		ContentConfig: ContentConfig{
+			GroupVersion:         &v1.SchemeGroupVersion,
+			NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		},
+		UserAgent: "test-agent",
+		AuditSink: AuditSinkFunc(func(record *AuditRecord) {
+			records = append(records, record)
+		}),
+	}
+	client, err := RESTClientFor(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	client.Get().Namespace("ns").Resource("pods").Name("foo").Do(ctx)
+	client.Get().Namespace("ns").Resource("pods").Do(ctx)
+	client.Post().Namespace("ns").Resource("pods").Body([]byte(`{}`)).Do(ctx)
+	client.Put().Namespace("ns").Resource("pods").Name("foo").SubResource("status").Body([]byte(`{}`)).Do(ctx)
+	client.Delete().Namespace("ns").Resource("pods").Name("foo").Do(ctx)
+	client.Get().AbsPath("/healthz").Do(ctx)
+
+	pods := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	expected := []AuditRecord{
+		{Verb: "get", Resource: pods, Namespace: "ns", Name: "foo", Path: "/api/v1/namespaces/ns/pods/foo", StatusCode: 200},
+		{Verb: "list", Resource: pods, Namespace: "ns", Path: "/api/v1/namespaces/ns/pods", StatusCode: 200},
+		{Verb: "create", Resource: pods, Namespace: "ns", Path: "/api/v1/namespaces/ns/pods", StatusCode: 200},
+		{Verb: "update", Resource: pods, Subresource: "status", Namespace: "ns", Name: "foo", Path: "/api/v1/namespaces/ns/pods/foo/status", StatusCode: 200},
+		{Verb: "delete", Resource: pods, Namespace: "ns", Name: "foo", Path: "/api/v1/namespaces/ns/pods/foo", StatusCode: 404},
+		{Verb: "get", Path: "/healthz", StatusCode: 200},
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("expected %d records, got %d", len(expected), len(records))
+	}
+	seen := map[string]bool{}
+	for i, record := range records {
+		if len(record.AuditID) == 0 || seen[record.AuditID] {
+			t.Errorf("%d: expected a unique audit ID, got %q", i, record.AuditID)
+		}
+		seen[record.AuditID] = true
+		if record.AuditID != serverIDs[i] {
+			t.Errorf("%d: expected the server to receive audit ID %q, got %q", i, record.AuditID, serverIDs[i])
+		}
+		if record.UserAgent != "test-agent" {
+			t.Errorf("%d: unexpected user agent %q", i, record.UserAgent)
+		}
+		if record.Err != nil {
+			t.Errorf("%d: unexpected error: %v", i, record.Err)
+		}
+		got := *record
+		got.AuditID, got.UserAgent, got.Latency = "", "", 0
+		if got != expected[i] {
+			t.Errorf("%d: expected %#v, got %#v", i, expected[i], got)
+		}
+	}
+}
+
+func TestAuditSinkNotConfigured(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if id := req.Header.Get(auditIDHeader); len(id) > 0 {
+			t.Errorf("unexpected audit ID %q", id)
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer testServer.Close()
+
+	client, err := RESTClientFor(&Config{
+		Host:    testServer.URL,
+		APIPath: "/api",
+		ContentConfig: ContentConfig{
+			GroupVersion:         &v1.SchemeGroupVersion,
+			NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Get().Resource("pods").Do(context.Background())
+}
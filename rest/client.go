@@ -98,6 +98,17 @@ type RESTClient struct {
 	// If not set, defaultWarningHandler is used.
 	warningHandler WarningHandler
 
+	// auditSink, if set, receives the audit records of all requests created
+	// by this client, with userAgent as their user agent.
+	auditSink AuditSink
+	userAgent string
+
+	// readCache, if set, caches the responses of the GETs of named objects
+	// sent by the requests created by this client, under readCacheIdentity
+	// so that clients with other credentials sharing it are not served them.
+	readCache         *ReadCache
+	readCacheIdentity string
+
 	// Set specific behavior of the client.  If not set http.DefaultClient will be used.
 	Client *http.Client
 }
@@ -30,6 +30,8 @@ import (
 	"strings"
 	"time"
 
+	"golang.org/x/oauth2"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -77,6 +79,14 @@ type Config struct {
 	// The last successfully read value takes precedence over BearerToken.
 	BearerTokenFile string
 
+	// TokenSource supplies bearer tokens from an in-process credential source,
+	// such as a cloud SDK credential chain. Tokens are cached until shortly
+	// before they expire and are refreshed after a 401 Unauthorized response.
+	// If TokenSource implements transport.ContextTokenSource, fetching a token
+	// is abandoned when the request is cancelled. TokenSource may not be
+	// combined with BearerToken or BearerTokenFile.
+	TokenSource oauth2.TokenSource
+
 	// Impersonate is the configuration that RESTClient will use for impersonation.
 	Impersonate ImpersonationConfig
 
@@ -128,6 +138,18 @@ type Config struct {
 	// See documentation for SetDefaultWarningHandler() for details.
 	WarningHandler WarningHandler
 
+	// AuditSink, if set, receives an audit record for every request sent by
+	// the clients created from this config, for example to attribute the load
+	// of the server to code paths.
+	AuditSink AuditSink
+
+	// ReadCache, if set, caches the responses of the GETs of named objects
+	// sent by the clients created from this config. Responses are only
+	// served to clients with the same credentials and impersonated user, but
+	// credentials held by TokenSource, WrapTransport or Transport are not
+	// told apart.
+	ReadCache *ReadCache
+
 	// The maximum length of time to wait before giving up on a server request. A value of zero means no timeout.
 	Timeout time.Duration
 
@@ -141,6 +163,27 @@ type Config struct {
 	// socks5 proxying does not currently support spdy streaming endpoints.
 	Proxy func(*http.Request) (*url.URL, error)
 
+	// MaxConnectionLifetime, if non-zero, is the maximum amount of time a
+	// connection to the server is used for new requests. Long-running requests
+	// such as watches finish on their existing connection. Use this to spread
+	// clients across apiservers behind an L4 load balancer.
+	MaxConnectionLifetime time.Duration
+
+	// HTTP3Transport is an EXPERIMENTAL opt-in to send requests over HTTP/3
+	// (QUIC), which can improve watch reconnect behavior over high-latency
+	// links. client-go does not include a QUIC implementation, so the caller
+	// supplies a function building a round tripper that speaks HTTP/3 from
+	// the TLS settings of this config. Requests that fail over HTTP/3 without
+	// a response are retried over HTTP/2.
+	HTTP3Transport transport.HTTP3TransportFunc
+
+	// HostOverrides holds transport settings for requests sent to hosts other
+	// than Host, for example aggregated API servers that present certificates
+	// signed by a different CA. Keys are matched against the request URL host,
+	// including the port if the URL has one. HostOverrides cannot be combined
+	// with Transport.
+	HostOverrides map[string]HostOverride
+
 	// Version forces a specific version to be used (if registered)
 	// Do we need this?
 	// Version string
@@ -160,6 +203,15 @@ func (sanitizedAuthConfigPersister) String() string {
 	return "rest.AuthProviderConfigPersister(--- REDACTED ---)"
 }
 
+type sanitizedTokenSource struct{ oauth2.TokenSource }
+
+func (sanitizedTokenSource) GoString() string {
+	return "oauth2.TokenSource(--- REDACTED ---)"
+}
+func (sanitizedTokenSource) String() string {
+	return "oauth2.TokenSource(--- REDACTED ---)"
+}
+
 type sanitizedObject struct{ runtime.Object }
 
 func (sanitizedObject) GoString() string {
@@ -192,12 +244,27 @@ func (c *Config) String() string {
 	if cc.AuthConfigPersister != nil {
 		cc.AuthConfigPersister = sanitizedAuthConfigPersister{cc.AuthConfigPersister}
 	}
+	if cc.TokenSource != nil {
+		cc.TokenSource = sanitizedTokenSource{cc.TokenSource}
+	}
 	if cc.ExecProvider != nil && cc.ExecProvider.Config != nil {
 		cc.ExecProvider.Config = sanitizedObject{Object: cc.ExecProvider.Config}
 	}
 	return fmt.Sprintf("%#v", cc)
 }
 
+// HostOverride holds the transport settings used for a single host in
+// Config.HostOverrides. Unset fields are inherited from the enclosing Config.
+type HostOverride struct {
+	// TLSClientConfig, if non-nil, replaces the TLS settings of the enclosing
+	// Config for this host.
+	TLSClientConfig *TLSClientConfig
+
+	// Proxy, if non-nil, replaces the proxy func of the enclosing Config for
+	// this host.
+	Proxy func(*http.Request) (*url.URL, error)
+}
+
 // ImpersonationConfig has all the available impersonation options
 type ImpersonationConfig struct {
 	// UserName is the username to impersonate on each request.
@@ -379,6 +446,21 @@ func RESTClientForConfigAndClient(config *Config, httpClient *http.Client) (*RES
 	if err == nil && config.WarningHandler != nil {
 		restClient.warningHandler = config.WarningHandler
 	}
+	if err == nil && config.AuditSink != nil {
+		restClient.auditSink = config.AuditSink
+		restClient.userAgent = config.UserAgent
+		if len(restClient.userAgent) == 0 {
+			restClient.userAgent = DefaultKubernetesUserAgent()
+		}
+	}
+	if err == nil {
+		fingerprint := FingerprintFor(config)
+		if config.ReadCache != nil {
+			restClient.readCache = config.ReadCache
+			restClient.readCacheIdentity = fingerprint.Hash
+		}
+		recordRESTClient(fingerprint)
+	}
 	return restClient, err
 }
 
@@ -446,6 +528,21 @@ func UnversionedRESTClientForConfigAndClient(config *Config, httpClient *http.Cl
 	if err == nil && config.WarningHandler != nil {
 		restClient.warningHandler = config.WarningHandler
 	}
+	if err == nil && config.AuditSink != nil {
+		restClient.auditSink = config.AuditSink
+		restClient.userAgent = config.UserAgent
+		if len(restClient.userAgent) == 0 {
+			restClient.userAgent = DefaultKubernetesUserAgent()
+		}
+	}
+	if err == nil {
+		fingerprint := FingerprintFor(config)
+		if config.ReadCache != nil {
+			restClient.readCache = config.ReadCache
+			restClient.readCacheIdentity = fingerprint.Hash
+		}
+		recordRESTClient(fingerprint)
+	}
 	return restClient, err
 }
 
@@ -600,7 +697,7 @@ func AddUserAgent(config *Config, userAgent string) *Config {
 	return config
 }
 
-// AnonymousClientConfig returns a copy of the given config with all user credentials (cert/key, bearer token, and username/password) and custom transports (WrapTransport, Transport) removed
+// AnonymousClientConfig returns a copy of the given config with all user credentials (cert/key, bearer token, and username/password), custom transports (WrapTransport, Transport, HTTP3Transport) and the ReadCache removed
 func AnonymousClientConfig(config *Config) *Config {
 	// copy only known safe fields
 	return &Config{
@@ -614,16 +711,41 @@ func AnonymousClientConfig(config *Config) *Config {
 			CAData:     config.TLSClientConfig.CAData,
 			NextProtos: config.TLSClientConfig.NextProtos,
 		},
-		RateLimiter:        config.RateLimiter,
-		WarningHandler:     config.WarningHandler,
-		UserAgent:          config.UserAgent,
-		DisableCompression: config.DisableCompression,
-		QPS:                config.QPS,
-		Burst:              config.Burst,
-		Timeout:            config.Timeout,
-		Dial:               config.Dial,
-		Proxy:              config.Proxy,
+		RateLimiter:           config.RateLimiter,
+		WarningHandler:        config.WarningHandler,
+		AuditSink:             config.AuditSink,
+		UserAgent:             config.UserAgent,
+		DisableCompression:    config.DisableCompression,
+		QPS:                   config.QPS,
+		Burst:                 config.Burst,
+		Timeout:               config.Timeout,
+		Dial:                  config.Dial,
+		Proxy:                 config.Proxy,
+		MaxConnectionLifetime: config.MaxConnectionLifetime,
+		HostOverrides:         anonymousHostOverrides(config.HostOverrides),
+	}
+}
+
+// anonymousHostOverrides returns a copy of the given host overrides with
+// client certificates removed.
+func anonymousHostOverrides(overrides map[string]HostOverride) map[string]HostOverride {
+	if overrides == nil {
+		return nil
+	}
+	out := make(map[string]HostOverride, len(overrides))
+	for host, override := range overrides {
+		if override.TLSClientConfig != nil {
+			override.TLSClientConfig = &TLSClientConfig{
+				Insecure:   override.TLSClientConfig.Insecure,
+				ServerName: override.TLSClientConfig.ServerName,
+				CAFile:     override.TLSClientConfig.CAFile,
+				CAData:     override.TLSClientConfig.CAData,
+				NextProtos: override.TLSClientConfig.NextProtos,
+			}
+		}
+		out[host] = override
 	}
+	return out
 }
 
 // CopyConfig returns a copy of the given config
@@ -636,6 +758,7 @@ func CopyConfig(config *Config) *Config {
 		Password:        config.Password,
 		BearerToken:     config.BearerToken,
 		BearerTokenFile: config.BearerTokenFile,
+		TokenSource:     config.TokenSource,
 		Impersonate: ImpersonationConfig{
 			UserName: config.Impersonate.UserName,
 			UID:      config.Impersonate.UID,
@@ -656,17 +779,31 @@ func CopyConfig(config *Config) *Config {
 			CAData:     config.TLSClientConfig.CAData,
 			NextProtos: config.TLSClientConfig.NextProtos,
 		},
-		UserAgent:          config.UserAgent,
-		DisableCompression: config.DisableCompression,
-		Transport:          config.Transport,
-		WrapTransport:      config.WrapTransport,
-		QPS:                config.QPS,
-		Burst:              config.Burst,
-		RateLimiter:        config.RateLimiter,
-		WarningHandler:     config.WarningHandler,
-		Timeout:            config.Timeout,
-		Dial:               config.Dial,
-		Proxy:              config.Proxy,
+		UserAgent:             config.UserAgent,
+		DisableCompression:    config.DisableCompression,
+		Transport:             config.Transport,
+		WrapTransport:         config.WrapTransport,
+		QPS:                   config.QPS,
+		Burst:                 config.Burst,
+		RateLimiter:           config.RateLimiter,
+		WarningHandler:        config.WarningHandler,
+		AuditSink:             config.AuditSink,
+		ReadCache:             config.ReadCache,
+		Timeout:               config.Timeout,
+		Dial:                  config.Dial,
+		Proxy:                 config.Proxy,
+		MaxConnectionLifetime: config.MaxConnectionLifetime,
+		HTTP3Transport:        config.HTTP3Transport,
+	}
+	if config.HostOverrides != nil {
+		c.HostOverrides = make(map[string]HostOverride, len(config.HostOverrides))
+		for host, override := range config.HostOverrides {
+			if override.TLSClientConfig != nil {
+				tlsConfig := *override.TLSClientConfig
+				override.TLSClientConfig = &tlsConfig
+			}
+			c.HostOverrides[host] = override
+		}
 	}
 	if config.ExecProvider != nil && config.ExecProvider.Config != nil {
 		c.ExecProvider.Config = config.ExecProvider.Config.DeepCopyObject()
@@ -18,6 +18,7 @@ package rest
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
@@ -41,6 +42,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	fuzz "github.com/google/gofuzz"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
 )
 
 func TestIsConfigTransportTLS(t *testing.T) {
@@ -258,10 +260,21 @@ var fakeWrapperFunc = func(http.RoundTripper) http.RoundTripper {
 	return &fakeRoundTripper{}
 }
 
+var fakeHTTP3TransportFunc = func(*tls.Config) (http.RoundTripper, error) {
+	return &fakeRoundTripper{}, nil
+}
+
 type fakeWarningHandler struct{}
 
 func (f fakeWarningHandler) HandleWarningHeader(code int, agent string, message string) {}
 
+type fakeAuditSink struct{}
+
+// readCacheComparer compares read caches by identity, configs sharing them.
+var readCacheComparer = cmp.Comparer(func(a, b *ReadCache) bool { return a == b })
+
+func (f fakeAuditSink) Record(record *AuditRecord) {}
+
 type fakeNegotiatedSerializer struct{}
 
 func (n *fakeNegotiatedSerializer) SupportedMediaTypes() []runtime.SerializerInfo {
@@ -286,6 +299,14 @@ func fakeProxyFunc(*http.Request) (*url.URL, error) {
 	return nil, errors.New("fakeproxy")
 }
 
+type fakeTokenSource struct {
+	AccessToken string
+}
+
+func (ts fakeTokenSource) Token() (*oauth2.Token, error) {
+	return &oauth2.Token{AccessToken: ts.AccessToken}, nil
+}
+
 type fakeAuthProviderConfigPersister struct{}
 
 func (fakeAuthProviderConfigPersister) Persist(map[string]string) error {
@@ -294,6 +315,24 @@ func (fakeAuthProviderConfigPersister) Persist(map[string]string) error {
 
 var fakeAuthProviderConfigPersisterError = errors.New("fakeAuthProviderConfigPersisterError")
 
+// dropHostOverrideProxies verifies that the host override proxy funcs were
+// preserved and returns a copy of the overrides without them, since cmp cannot
+// compare funcs.
+func dropHostOverrideProxies(t *testing.T, overrides map[string]HostOverride) map[string]HostOverride {
+	if overrides == nil {
+		return nil
+	}
+	out := make(map[string]HostOverride, len(overrides))
+	for host, override := range overrides {
+		if override.Proxy == nil {
+			t.Fatalf("host override %q dropped the Proxy field", host)
+		}
+		override.Proxy = nil
+		out[host] = override
+	}
+	return out
+}
+
 func TestAnonymousConfig(t *testing.T) {
 	f := fuzz.New().NilChance(0.0).NumElements(1, 1)
 	f.Funcs(
@@ -313,6 +352,9 @@ func TestAnonymousConfig(t *testing.T) {
 		func(fn *transport.WrapperFunc, f fuzz.Continue) {
 			*fn = fakeWrapperFunc
 		},
+		func(fn *transport.HTTP3TransportFunc, f fuzz.Continue) {
+			*fn = fakeHTTP3TransportFunc
+		},
 		func(r *runtime.NegotiatedSerializer, f fuzz.Continue) {
 			serializer := &fakeNegotiatedSerializer{}
 			f.Fuzz(serializer)
@@ -326,6 +368,12 @@ func TestAnonymousConfig(t *testing.T) {
 		func(h *WarningHandler, f fuzz.Continue) {
 			*h = &fakeWarningHandler{}
 		},
+		func(s *AuditSink, f fuzz.Continue) {
+			*s = &fakeAuditSink{}
+		},
+		func(c **ReadCache, f fuzz.Continue) {
+			*c = NewReadCache(ReadCacheOptions{})
+		},
 		// Authentication does not require fuzzer
 		func(r *AuthProviderConfigPersister, f fuzz.Continue) {},
 		func(r *clientcmdapi.AuthProviderConfig, f fuzz.Continue) {
@@ -337,6 +385,9 @@ func TestAnonymousConfig(t *testing.T) {
 		func(r *func(*http.Request) (*url.URL, error), f fuzz.Continue) {
 			*r = fakeProxyFunc
 		},
+		func(r *oauth2.TokenSource, f fuzz.Continue) {
+			*r = fakeTokenSource{AccessToken: f.RandString()}
+		},
 		func(r *runtime.Object, f fuzz.Continue) {
 			unknown := &runtime.Unknown{}
 			f.Fuzz(unknown)
@@ -354,6 +405,7 @@ func TestAnonymousConfig(t *testing.T) {
 		expected.Impersonate = ImpersonationConfig{}
 		expected.BearerToken = ""
 		expected.BearerTokenFile = ""
+		expected.TokenSource = nil
 		expected.Username = ""
 		expected.Password = ""
 		expected.AuthProvider = nil
@@ -365,6 +417,8 @@ func TestAnonymousConfig(t *testing.T) {
 		expected.TLSClientConfig.KeyFile = ""
 		expected.Transport = nil
 		expected.WrapTransport = nil
+		expected.HTTP3Transport = nil
+		expected.ReadCache = nil
 
 		if actual.Dial != nil {
 			_, actualError := actual.Dial(context.Background(), "", "")
@@ -386,7 +440,11 @@ func TestAnonymousConfig(t *testing.T) {
 		actual.Proxy = nil
 		expected.Proxy = nil
 
-		if diff := cmp.Diff(*actual, expected); diff != "" {
+		expected.HostOverrides = anonymousHostOverrides(expected.HostOverrides)
+		actual.HostOverrides = dropHostOverrideProxies(t, actual.HostOverrides)
+		expected.HostOverrides = dropHostOverrideProxies(t, expected.HostOverrides)
+
+		if diff := cmp.Diff(*actual, expected, readCacheComparer); diff != "" {
 			t.Fatalf("AnonymousClientConfig dropped unexpected fields, identify whether they are security related or not (-got, +want): %s", diff)
 		}
 	}
@@ -411,6 +469,9 @@ func TestCopyConfig(t *testing.T) {
 		func(fn *transport.WrapperFunc, f fuzz.Continue) {
 			*fn = fakeWrapperFunc
 		},
+		func(fn *transport.HTTP3TransportFunc, f fuzz.Continue) {
+			*fn = fakeHTTP3TransportFunc
+		},
 		func(r *runtime.NegotiatedSerializer, f fuzz.Continue) {
 			serializer := &fakeNegotiatedSerializer{}
 			f.Fuzz(serializer)
@@ -424,6 +485,12 @@ func TestCopyConfig(t *testing.T) {
 		func(h *WarningHandler, f fuzz.Continue) {
 			*h = &fakeWarningHandler{}
 		},
+		func(s *AuditSink, f fuzz.Continue) {
+			*s = &fakeAuditSink{}
+		},
+		func(c **ReadCache, f fuzz.Continue) {
+			*c = NewReadCache(ReadCacheOptions{})
+		},
 		func(r *AuthProviderConfigPersister, f fuzz.Continue) {
 			*r = fakeAuthProviderConfigPersister{}
 		},
@@ -433,6 +500,9 @@ func TestCopyConfig(t *testing.T) {
 		func(r *func(*http.Request) (*url.URL, error), f fuzz.Continue) {
 			*r = fakeProxyFunc
 		},
+		func(r *oauth2.TokenSource, f fuzz.Continue) {
+			*r = fakeTokenSource{AccessToken: f.RandString()}
+		},
 		func(r *runtime.Object, f fuzz.Continue) {
 			unknown := &runtime.Unknown{}
 			f.Fuzz(unknown)
@@ -456,6 +526,12 @@ func TestCopyConfig(t *testing.T) {
 		actual.WrapTransport = nil
 		expected.WrapTransport = nil
 
+		if actual.HTTP3Transport == nil {
+			t.Fatalf("CopyConfig dropped the HTTP3Transport field")
+		}
+		actual.HTTP3Transport = nil
+		expected.HTTP3Transport = nil
+
 		if actual.Dial != nil {
 			_, actualError := actual.Dial(context.Background(), "", "")
 			_, expectedError := expected.Dial(context.Background(), "", "")
@@ -486,7 +562,10 @@ func TestCopyConfig(t *testing.T) {
 		actual.Proxy = nil
 		expected.Proxy = nil
 
-		if diff := cmp.Diff(*actual, expected); diff != "" {
+		actual.HostOverrides = dropHostOverrideProxies(t, actual.HostOverrides)
+		expected.HostOverrides = dropHostOverrideProxies(t, expected.HostOverrides)
+
+		if diff := cmp.Diff(*actual, expected, readCacheComparer); diff != "" {
 			t.Fatalf("CopyConfig  dropped unexpected fields, identify whether they are security related or not (-got, +want): %s", diff)
 		}
 	}
@@ -525,6 +604,7 @@ func TestConfigStringer(t *testing.T) {
 				Username:    "gopher",
 				Password:    "g0ph3r",
 				BearerToken: "1234567890",
+				TokenSource: fakeTokenSource{AccessToken: "s3cr3t"},
 				TLSClientConfig: TLSClientConfig{
 					CertFile: "a.crt",
 					KeyFile:  "a.key",
@@ -627,7 +707,7 @@ func TestConfigSprint(t *testing.T) {
 		Proxy:          fakeProxyFunc,
 	}
 	want := fmt.Sprintf(
-		`&rest.Config{Host:"localhost:8080", APIPath:"v1", ContentConfig:rest.ContentConfig{AcceptContentTypes:"application/json", ContentType:"application/json", GroupVersion:(*schema.GroupVersion)(nil), NegotiatedSerializer:runtime.NegotiatedSerializer(nil)}, Username:"gopher", Password:"--- REDACTED ---", BearerToken:"--- REDACTED ---", BearerTokenFile:"", Impersonate:rest.ImpersonationConfig{UserName:"gopher2", UID:"uid123", Groups:[]string(nil), Extra:map[string][]string(nil)}, AuthProvider:api.AuthProviderConfig{Name: "gopher", Config: map[string]string{--- REDACTED ---}}, AuthConfigPersister:rest.AuthProviderConfigPersister(--- REDACTED ---), ExecProvider:api.ExecConfig{Command: "sudo", Args: []string{"--- REDACTED ---"}, Env: []ExecEnvVar{--- REDACTED ---}, APIVersion: "", ProvideClusterInfo: true, Config: runtime.Object(--- REDACTED ---), StdinUnavailable: false}, TLSClientConfig:rest.sanitizedTLSClientConfig{Insecure:false, ServerName:"", CertFile:"a.crt", KeyFile:"a.key", CAFile:"", CertData:[]uint8{0x2d, 0x2d, 0x2d, 0x20, 0x54, 0x52, 0x55, 0x4e, 0x43, 0x41, 0x54, 0x45, 0x44, 0x20, 0x2d, 0x2d, 0x2d}, KeyData:[]uint8{0x2d, 0x2d, 0x2d, 0x20, 0x52, 0x45, 0x44, 0x41, 0x43, 0x54, 0x45, 0x44, 0x20, 0x2d, 0x2d, 0x2d}, CAData:[]uint8(nil), NextProtos:[]string{"h2", "http/1.1"}}, UserAgent:"gobot", DisableCompression:false, Transport:(*rest.fakeRoundTripper)(%p), WrapTransport:(transport.WrapperFunc)(%p), QPS:1, Burst:2, RateLimiter:(*rest.fakeLimiter)(%p), WarningHandler:rest.fakeWarningHandler{}, Timeout:3000000000, Dial:(func(context.Context, string, string) (net.Conn, error))(%p), Proxy:(func(*http.Request) (*url.URL, error))(%p)}`,
+		`&rest.Config{Host:"localhost:8080", APIPath:"v1", ContentConfig:rest.ContentConfig{AcceptContentTypes:"application/json", ContentType:"application/json", GroupVersion:(*schema.GroupVersion)(nil), NegotiatedSerializer:runtime.NegotiatedSerializer(nil)}, Username:"gopher", Password:"--- REDACTED ---", BearerToken:"--- REDACTED ---", BearerTokenFile:"", TokenSource:oauth2.TokenSource(nil), Impersonate:rest.ImpersonationConfig{UserName:"gopher2", UID:"uid123", Groups:[]string(nil), Extra:map[string][]string(nil)}, AuthProvider:api.AuthProviderConfig{Name: "gopher", Config: map[string]string{--- REDACTED ---}}, AuthConfigPersister:rest.AuthProviderConfigPersister(--- REDACTED ---), ExecProvider:api.ExecConfig{Command: "sudo", Args: []string{"--- REDACTED ---"}, Env: []ExecEnvVar{--- REDACTED ---}, APIVersion: "", ProvideClusterInfo: true, Config: runtime.Object(--- REDACTED ---), StdinUnavailable: false}, TLSClientConfig:rest.sanitizedTLSClientConfig{Insecure:false, ServerName:"", CertFile:"a.crt", KeyFile:"a.key", CAFile:"", CertData:[]uint8{0x2d, 0x2d, 0x2d, 0x20, 0x54, 0x52, 0x55, 0x4e, 0x43, 0x41, 0x54, 0x45, 0x44, 0x20, 0x2d, 0x2d, 0x2d}, KeyData:[]uint8{0x2d, 0x2d, 0x2d, 0x20, 0x52, 0x45, 0x44, 0x41, 0x43, 0x54, 0x45, 0x44, 0x20, 0x2d, 0x2d, 0x2d}, CAData:[]uint8(nil), NextProtos:[]string{"h2", "http/1.1"}}, UserAgent:"gobot", DisableCompression:false, Transport:(*rest.fakeRoundTripper)(%p), WrapTransport:(transport.WrapperFunc)(%p), QPS:1, Burst:2, RateLimiter:(*rest.fakeLimiter)(%p), WarningHandler:rest.fakeWarningHandler{}, AuditSink:rest.AuditSink(nil), ReadCache:(*rest.ReadCache)(nil), Timeout:3000000000, Dial:(func(context.Context, string, string) (net.Conn, error))(%p), Proxy:(func(*http.Request) (*url.URL, error))(%p), MaxConnectionLifetime:0, HTTP3Transport:(transport.HTTP3TransportFunc)(nil), HostOverrides:map[string]rest.HostOverride(nil)}`,
 		c.Transport, fakeWrapperFunc, c.RateLimiter, fakeDialFunc, fakeProxyFunc,
 	)
 
@@ -27,6 +27,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	fuzz "github.com/google/gofuzz"
+	"golang.org/x/oauth2"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/pkg/apis/clientauthentication"
 	clientauthenticationapi "k8s.io/client-go/pkg/apis/clientauthentication"
@@ -230,6 +231,9 @@ func TestConfigToExecClusterRoundtrip(t *testing.T) {
 		func(fn *transport.WrapperFunc, f fuzz.Continue) {
 			*fn = fakeWrapperFunc
 		},
+		func(fn *transport.HTTP3TransportFunc, f fuzz.Continue) {
+			*fn = fakeHTTP3TransportFunc
+		},
 		func(r *runtime.NegotiatedSerializer, f fuzz.Continue) {
 			serializer := &fakeNegotiatedSerializer{}
 			f.Fuzz(serializer)
@@ -243,8 +247,15 @@ func TestConfigToExecClusterRoundtrip(t *testing.T) {
 		func(h *WarningHandler, f fuzz.Continue) {
 			*h = &fakeWarningHandler{}
 		},
+		func(s *AuditSink, f fuzz.Continue) {
+			*s = &fakeAuditSink{}
+		},
+		func(c **ReadCache, f fuzz.Continue) {
+			*c = NewReadCache(ReadCacheOptions{})
+		},
 		// Authentication does not require fuzzer
 		func(r *AuthProviderConfigPersister, f fuzz.Continue) {},
+		func(r *oauth2.TokenSource, f fuzz.Continue) {},
 		func(r *clientcmdapi.AuthProviderConfig, f fuzz.Continue) {
 			r.Config = map[string]string{}
 		},
@@ -290,8 +301,14 @@ func TestConfigToExecClusterRoundtrip(t *testing.T) {
 		expected.Burst = 0
 		expected.RateLimiter = nil
 		expected.WarningHandler = nil
+		expected.AuditSink = nil
+		expected.ReadCache = nil
 		expected.Timeout = 0
 		expected.Dial = nil
+		expected.MaxConnectionLifetime = 0
+		expected.HTTP3Transport = nil
+		expected.TokenSource = nil
+		expected.HostOverrides = nil
 
 		// Manually set URLs so we don't get an error when parsing these during the roundtrip.
 		if expected.Host != "" {
@@ -0,0 +1,291 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+)
+
+// Fingerprint identifies the server and the identity of the clients created
+// from a config, so that operators can tell how many distinct clients a
+// binary creates. Configs differing only in settings which do not identify
+// clients, like QPS or timeouts, share a fingerprint.
+type Fingerprint struct {
+	// Host is the host of the config.
+	Host string
+	// AuthMethod lists the authentication methods of the config, like
+	// "bearer-token+client-certificate", or "anonymous".
+	AuthMethod string
+	// Hash is a hash of the host, the credentials, the impersonated user
+	// and the TLS settings of the config. It is keyed with a secret generated
+	// when the process starts, so it does not reveal the credentials, even
+	// by guessing them, and is only stable within a process.
+	Hash string
+}
+
+// String returns the host, authentication methods and hash of f.
+func (f Fingerprint) String() string {
+	return fmt.Sprintf("%s %s %s", f.Host, f.AuthMethod, f.Hash)
+}
+
+// fingerprintKey is the secret key of the hashes of fingerprints.
+var fingerprintKey = func() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("failed to generate the key of client fingerprints: %v", err))
+	}
+	return key
+}()
+
+// FingerprintFor returns the fingerprint of config.
+func FingerprintFor(config *Config) Fingerprint {
+	h := hmac.New(sha256.New, fingerprintKey)
+	write := func(name string, values ...string) {
+		fmt.Fprintf(h, "%s=%q;", name, values)
+	}
+	write("host", config.Host)
+	write("basic", config.Username, config.Password)
+	write("token", config.BearerToken, config.BearerTokenFile)
+	write("impersonate", config.Impersonate.UserName, config.Impersonate.UID)
+	write("groups", config.Impersonate.Groups...)
+	extraKeys := make([]string, 0, len(config.Impersonate.Extra))
+	for key := range config.Impersonate.Extra {
+		extraKeys = append(extraKeys, key)
+	}
+	sort.Strings(extraKeys)
+	for _, key := range extraKeys {
+		write("extra", append([]string{key}, config.Impersonate.Extra[key]...)...)
+	}
+	if config.AuthProvider != nil {
+		configKeys := make([]string, 0, len(config.AuthProvider.Config))
+		for key := range config.AuthProvider.Config {
+			configKeys = append(configKeys, key)
+		}
+		sort.Strings(configKeys)
+		write("auth-provider", config.AuthProvider.Name)
+		for _, key := range configKeys {
+			write("auth-provider-config", key, config.AuthProvider.Config[key])
+		}
+	}
+	if config.ExecProvider != nil {
+		write("exec", append([]string{config.ExecProvider.APIVersion, config.ExecProvider.Command}, config.ExecProvider.Args...)...)
+		for _, env := range config.ExecProvider.Env {
+			write("exec-env", env.Name, env.Value)
+		}
+	}
+	write("tls", fmt.Sprint(config.Insecure), config.ServerName, config.CAFile, string(config.CAData))
+	write("cert", config.CertFile, config.KeyFile, string(config.CertData), string(config.KeyData))
+	write("next-protos", config.NextProtos...)
+
+	return Fingerprint{
+		Host:       config.Host,
+		AuthMethod: authMethod(config),
+		Hash:       fmt.Sprintf("%x", h.Sum(nil))[:16],
+	}
+}
+
+// authMethod returns the authentication methods of config joined by "+".
+func authMethod(config *Config) string {
+	var methods []string
+	switch {
+	case config.ExecProvider != nil:
+		methods = append(methods, "exec")
+	case config.AuthProvider != nil:
+		methods = append(methods, "auth-provider:"+config.AuthProvider.Name)
+	case len(config.BearerToken) > 0:
+		methods = append(methods, "bearer-token")
+	case len(config.BearerTokenFile) > 0:
+		methods = append(methods, "bearer-token-file")
+	case config.TokenSource != nil:
+		methods = append(methods, "token-source")
+	case len(config.Username) > 0 || len(config.Password) > 0:
+		methods = append(methods, "basic")
+	}
+	if len(config.CertData) > 0 || len(config.CertFile) > 0 {
+		methods = append(methods, "client-certificate")
+	}
+	if len(methods) == 0 {
+		return "anonymous"
+	}
+	return strings.Join(methods, "+")
+}
+
+// ClientStats are the statistics of the clients created from configs with
+// the same fingerprint.
+type ClientStats struct {
+	Fingerprint Fingerprint
+	// HTTPClients is the number of HTTP clients created by HTTPClientFor,
+	// including the ones created for RESTClientFor and clientsets.
+	HTTPClients int
+	// RESTClients is the number of REST clients created, one per API group
+	// for clientsets.
+	RESTClients int
+	// ConnectionPools is the number of distinct transports, each with its
+	// own pool of connections, underlying the HTTP clients. Transports are
+	// shared by configs with the same TLS settings unless they set Dial,
+	// Proxy or a custom Transport.
+	ConnectionPools int
+}
+
+const (
+	// maxFingerprints is the number of fingerprints whose statistics are
+	// recorded, the clients of further fingerprints are recorded under
+	// overflowFingerprint.
+	maxFingerprints = 1000
+	// maxPools is the number of transports tracked to count connection
+	// pools, further transports are counted as new pools.
+	maxPools = 10000
+)
+
+// overflowFingerprint holds the statistics of the clients created once
+// maxFingerprints fingerprints are recorded.
+var overflowFingerprint = Fingerprint{Host: "<other>", AuthMethod: "<other>"}
+
+// clientStats records the statistics of the clients created by fingerprint.
+var clientStats = struct {
+	lock  sync.Mutex
+	stats map[Fingerprint]*ClientStats
+	// pools holds the fingerprints using each transport, by address so that
+	// the transports can be garbage-collected. The transports created by
+	// client-go are forgotten once they are garbage-collected, so that a new
+	// transport at the same address is counted again.
+	pools map[string]map[Fingerprint]bool
+}{
+	stats: map[Fingerprint]*ClientStats{},
+	pools: map[string]map[Fingerprint]bool{},
+}
+
+// ClientStatistics returns the statistics of the clients created in the
+// process by fingerprint, sorted by host and hash. A large number of HTTP
+// clients or connection pools for a fingerprint usually means that clients
+// are created per request or per object instead of being shared.
+func ClientStatistics() []ClientStats {
+	clientStats.lock.Lock()
+	defer clientStats.lock.Unlock()
+	stats := make([]ClientStats, 0, len(clientStats.stats))
+	for _, s := range clientStats.stats {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Fingerprint.Host != stats[j].Fingerprint.Host {
+			return stats[i].Fingerprint.Host < stats[j].Fingerprint.Host
+		}
+		return stats[i].Fingerprint.Hash < stats[j].Fingerprint.Hash
+	})
+	return stats
+}
+
+// WriteClientStatistics writes the statistics of ClientStatistics to w, one
+// fingerprint per line, for debug endpoints.
+func WriteClientStatistics(w io.Writer) error {
+	for _, s := range ClientStatistics() {
+		if _, err := fmt.Fprintf(w, "%s http_clients=%d rest_clients=%d connection_pools=%d\n", s.Fingerprint, s.HTTPClients, s.RESTClients, s.ConnectionPools); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statsForLocked returns the statistics of fingerprint. It
+// must be called while holding the lock.
+func statsForLocked(fingerprint Fingerprint) *ClientStats {
+	s, ok := clientStats.stats[fingerprint]
+	if !ok {
+		if len(clientStats.stats) >= maxFingerprints {
+			fingerprint = overflowFingerprint
+			if s, ok = clientStats.stats[fingerprint]; ok {
+				return s
+			}
+		}
+		s = &ClientStats{Fingerprint: fingerprint}
+		clientStats.stats[fingerprint] = s
+	}
+	return s
+}
+
+// recordHTTPClient records the creation of an HTTP client for config using
+// rt.
+func recordHTTPClient(config *Config, rt http.RoundTripper) {
+	fingerprint := FingerprintFor(config)
+	transport := innermostRoundTripper(rt)
+	pool := fmt.Sprintf("%p", transport)
+	clientStats.lock.Lock()
+	defer clientStats.lock.Unlock()
+	s := statsForLocked(fingerprint)
+	s.HTTPClients++
+	fingerprints, tracked := clientStats.pools[pool]
+	if !tracked {
+		if len(clientStats.pools) >= maxPools {
+			s.ConnectionPools++
+			return
+		}
+		fingerprints = map[Fingerprint]bool{}
+		clientStats.pools[pool] = fingerprints
+		// The address of a transport may be reused once it is garbage
+		// collected, so forget it then. The transport itself can't carry
+		// the finalizer as HTTP/2 makes it reference itself, but the TLS
+		// config that client-go clones for each transport it creates is
+		// only collected along with it.
+		if t, ok := transport.(*http.Transport); ok && config.Transport == nil && config.WrapTransport == nil && t != http.DefaultTransport && t.TLSClientConfig != nil {
+			runtime.SetFinalizer(t.TLSClientConfig, func(*tls.Config) {
+				clientStats.lock.Lock()
+				defer clientStats.lock.Unlock()
+				delete(clientStats.pools, pool)
+			})
+		}
+	}
+	if !fingerprints[s.Fingerprint] {
+		fingerprints[s.Fingerprint] = true
+		s.ConnectionPools++
+	}
+}
+
+// recordRESTClient records the creation of a REST client for a config of the
+// given fingerprint.
+func recordRESTClient(fingerprint Fingerprint) {
+	clientStats.lock.Lock()
+	defer clientStats.lock.Unlock()
+	statsForLocked(fingerprint).RESTClients++
+}
+
+// innermostRoundTripper unwraps the wrappers of rt, like the ones adding
+// credentials, down to the transport holding the connections.
+func innermostRoundTripper(rt http.RoundTripper) http.RoundTripper {
+	for {
+		wrapper, ok := rt.(utilnet.RoundTripperWrapper)
+		if !ok {
+			return rt
+		}
+		wrapped := wrapper.WrappedRoundTripper()
+		if wrapped == nil {
+			return rt
+		}
+		rt = wrapped
+	}
+}
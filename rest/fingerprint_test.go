@@ -0,0 +1,181 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestFingerprintFor(t *testing.T) {
+	base := &Config{Host: "https://example.com", BearerToken: "secret"}
+	fingerprint := FingerprintFor(base)
+	if fingerprint.Host != "https://example.com" || fingerprint.AuthMethod != "bearer-token" || len(fingerprint.Hash) != 16 {
+		t.Errorf("unexpected fingerprint %v", fingerprint)
+	}
+	if strings.Contains(fingerprint.String(), "secret") {
+		t.Errorf("expected the fingerprint not to reveal the credentials, got %v", fingerprint)
+	}
+
+	same := CopyConfig(base)
+	same.QPS = 100
+	same.Timeout = time.Minute
+	same.UserAgent = "other"
+	if got := FingerprintFor(same); got != fingerprint {
+		t.Errorf("expected configs differing in settings not identifying clients to share a fingerprint, got %v and %v", fingerprint, got)
+	}
+
+	for name, modify := range map[string]func(*Config){
+		"host":          func(c *Config) { c.Host = "https://other.example.com" },
+		"token":         func(c *Config) { c.BearerToken = "other" },
+		"impersonation": func(c *Config) { c.Impersonate.UserName = "alice" },
+		"ca":            func(c *Config) { c.CAData = []byte("ca") },
+		"server name":   func(c *Config) { c.ServerName = "apiserver" },
+	} {
+		other := CopyConfig(base)
+		modify(other)
+		if FingerprintFor(other).Hash == fingerprint.Hash {
+			t.Errorf("expected configs with another %s to have another fingerprint", name)
+		}
+	}
+
+	tests := []struct {
+		config   *Config
+		expected string
+	}{
+		{config: &Config{}, expected: "anonymous"},
+		{config: &Config{Username: "alice", Password: "secret"}, expected: "basic"},
+		{config: &Config{BearerTokenFile: "/token", TLSClientConfig: TLSClientConfig{CertFile: "/cert"}}, expected: "bearer-token-file+client-certificate"},
+		{config: &Config{ExecProvider: &clientcmdapi.ExecConfig{Command: "login"}}, expected: "exec"},
+		{config: &Config{AuthProvider: &clientcmdapi.AuthProviderConfig{Name: "oidc"}}, expected: "auth-provider:oidc"},
+	}
+	for _, test := range tests {
+		if got := FingerprintFor(test.config).AuthMethod; got != test.expected {
+			t.Errorf("expected auth method %q, got %q", test.expected, got)
+		}
+	}
+}
+
+func TestClientStatistics(t *testing.T) {
+	// statistics are global, a host of its own keeps the test repeatable
+	config := &Config{
+		Host:    fmt.Sprintf("https://stats-%d.example.com", time.Now().UnixNano()),
+		APIPath: "/api",
+		ContentConfig: ContentConfig{
+			GroupVersion:         &v1.SchemeGroupVersion,
+			NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		},
+		BearerToken: "stats",
+		// a server name makes clients use a TLS transport, shared by
+		// configs without Dial
+		TLSClientConfig: TLSClientConfig{ServerName: "stats"},
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := RESTClientFor(config); err != nil {
+			t.Fatal(err)
+		}
+	}
+	withDial := CopyConfig(config)
+	withDial.Dial = fakeDialFunc
+	if _, err := RESTClientFor(withDial); err != nil {
+		t.Fatal(err)
+	}
+
+	fingerprint := FingerprintFor(config)
+	var stats *ClientStats
+	for _, s := range ClientStatistics() {
+		if s.Fingerprint == fingerprint {
+			s := s
+			stats = &s
+		}
+	}
+	if stats == nil {
+		t.Fatalf("expected statistics for %v", fingerprint)
+	}
+	expected := ClientStats{Fingerprint: fingerprint, HTTPClients: 4, RESTClients: 4, ConnectionPools: 2}
+	if *stats != expected {
+		t.Errorf("expected %+v, got %+v", expected, *stats)
+	}
+
+	var out bytes.Buffer
+	if err := WriteClientStatistics(&out); err != nil {
+		t.Fatal(err)
+	}
+	if line := fingerprint.String() + " http_clients=4 rest_clients=4 connection_pools=2\n"; !strings.Contains(out.String(), line) {
+		t.Errorf("expected %q in %q", line, out.String())
+	}
+}
+
+func TestClientStatisticsOverflow(t *testing.T) {
+	clientStats.lock.Lock()
+	saved := clientStats.stats
+	clientStats.stats = map[Fingerprint]*ClientStats{}
+	for i := 0; i < maxFingerprints; i++ {
+		fingerprint := Fingerprint{Host: fmt.Sprintf("host-%d", i)}
+		clientStats.stats[fingerprint] = &ClientStats{Fingerprint: fingerprint}
+	}
+	clientStats.lock.Unlock()
+	defer func() {
+		clientStats.lock.Lock()
+		clientStats.stats = saved
+		clientStats.lock.Unlock()
+	}()
+
+	recordRESTClient(FingerprintFor(&Config{Host: "https://overflow.example.com"}))
+	recordRESTClient(FingerprintFor(&Config{Host: "https://overflow.example.com", BearerToken: "other"}))
+	clientStats.lock.Lock()
+	defer clientStats.lock.Unlock()
+	if len(clientStats.stats) != maxFingerprints+1 {
+		t.Errorf("expected the statistics to be bounded, got %d fingerprints", len(clientStats.stats))
+	}
+	if s := clientStats.stats[overflowFingerprint]; s == nil || s.RESTClients != 2 {
+		t.Errorf("expected the clients to be recorded under the overflow fingerprint, got %+v", s)
+	}
+}
+
+func TestClientStatisticsForgetsCollectedTransports(t *testing.T) {
+	config := &Config{Host: fmt.Sprintf("https://collected-%d.example.com", time.Now().UnixNano()), Dial: fakeDialFunc}
+	transport, err := TransportFor(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordHTTPClient(config, transport)
+	pool := fmt.Sprintf("%p", innermostRoundTripper(transport))
+	transport = nil
+
+	tracked := func() bool {
+		clientStats.lock.Lock()
+		defer clientStats.lock.Unlock()
+		_, ok := clientStats.pools[pool]
+		return ok
+	}
+	for i := 0; i < 100 && tracked(); i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if tracked() {
+		t.Errorf("expected the garbage-collected transport to be forgotten")
+	}
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+// MergeConfigs returns a new config composed of base and the overlays, later
+// overlays taking precedence over base and the overlays before them. Fields
+// left to their zero value in an overlay are inherited, so an overlay cannot
+// unset a field nor disable a boolean. Related fields are taken as a group
+// so that configs of different layers are never mixed into an invalid one:
+//
+//   - the credentials (Username, Password, BearerToken, BearerTokenFile,
+//     TokenSource, AuthProvider, AuthConfigPersister and ExecProvider) are
+//     all replaced as soon as an overlay sets any of them.
+//   - the impersonation config is replaced as a whole.
+//   - the client certificate and key, and the server trust (Insecure, CAFile
+//     and CAData) of TLSClientConfig are replaced as groups, the other TLS
+//     fields one by one.
+//   - the transport wrappers are composed, the ones of the overlays wrapping
+//     the ones of the configs before them.
+//   - the host overrides are merged, an overlay replacing the override of a
+//     host as a whole.
+//
+// Neither base nor the overlays are modified, nil overlays are ignored.
+func MergeConfigs(base *Config, overlays ...*Config) *Config {
+	if base == nil {
+		base = &Config{}
+	}
+	merged := CopyConfig(base)
+	for _, overlay := range overlays {
+		if overlay == nil {
+			continue
+		}
+		mergeConfig(merged, CopyConfig(overlay))
+	}
+	return merged
+}
+
+// mergeConfig applies the overlay o to c, o being owned by the caller.
+func mergeConfig(c, o *Config) {
+	if len(o.Host) > 0 {
+		c.Host = o.Host
+	}
+	if len(o.APIPath) > 0 {
+		c.APIPath = o.APIPath
+	}
+	mergeContentConfig(&c.ContentConfig, &o.ContentConfig)
+
+	if hasCredentials(o) {
+		c.Username = o.Username
+		c.Password = o.Password
+		c.BearerToken = o.BearerToken
+		c.BearerTokenFile = o.BearerTokenFile
+		c.TokenSource = o.TokenSource
+		c.AuthProvider = o.AuthProvider
+		c.AuthConfigPersister = o.AuthConfigPersister
+		c.ExecProvider = o.ExecProvider
+	}
+	if len(o.Impersonate.UserName) > 0 || len(o.Impersonate.UID) > 0 || len(o.Impersonate.Groups) > 0 || len(o.Impersonate.Extra) > 0 {
+		c.Impersonate = o.Impersonate
+	}
+	mergeTLSClientConfig(&c.TLSClientConfig, &o.TLSClientConfig)
+
+	if len(o.UserAgent) > 0 {
+		c.UserAgent = o.UserAgent
+	}
+	if o.DisableCompression {
+		c.DisableCompression = true
+	}
+	if o.Transport != nil {
+		c.Transport = o.Transport
+	}
+	if o.WrapTransport != nil {
+		c.Wrap(o.WrapTransport)
+	}
+	if o.QPS != 0 {
+		c.QPS = o.QPS
+	}
+	if o.Burst != 0 {
+		c.Burst = o.Burst
+	}
+	if o.RateLimiter != nil {
+		c.RateLimiter = o.RateLimiter
+	}
+	if o.WarningHandler != nil {
+		c.WarningHandler = o.WarningHandler
+	}
+	if o.AuditSink != nil {
+		c.AuditSink = o.AuditSink
+	}
+	if o.ReadCache != nil {
+		c.ReadCache = o.ReadCache
+	}
+	if o.Timeout != 0 {
+		c.Timeout = o.Timeout
+	}
+	if o.Dial != nil {
+		c.Dial = o.Dial
+	}
+	if o.Proxy != nil {
+		c.Proxy = o.Proxy
+	}
+	if o.MaxConnectionLifetime != 0 {
+		c.MaxConnectionLifetime = o.MaxConnectionLifetime
+	}
+	if o.HTTP3Transport != nil {
+		c.HTTP3Transport = o.HTTP3Transport
+	}
+	if len(o.HostOverrides) > 0 {
+		if c.HostOverrides == nil {
+			c.HostOverrides = make(map[string]HostOverride, len(o.HostOverrides))
+		}
+		for host, override := range o.HostOverrides {
+			c.HostOverrides[host] = override
+		}
+	}
+}
+
+// hasCredentials returns whether any credential is set in c.
+func hasCredentials(c *Config) bool {
+	return len(c.Username) > 0 || len(c.Password) > 0 ||
+		len(c.BearerToken) > 0 || len(c.BearerTokenFile) > 0 || c.TokenSource != nil ||
+		c.AuthProvider != nil || c.AuthConfigPersister != nil || c.ExecProvider != nil
+}
+
+func mergeContentConfig(c, o *ContentConfig) {
+	if len(o.AcceptContentTypes) > 0 {
+		c.AcceptContentTypes = o.AcceptContentTypes
+	}
+	if len(o.ContentType) > 0 {
+		c.ContentType = o.ContentType
+	}
+	if o.GroupVersion != nil {
+		c.GroupVersion = o.GroupVersion
+	}
+	if o.NegotiatedSerializer != nil {
+		c.NegotiatedSerializer = o.NegotiatedSerializer
+	}
+}
+
+func mergeTLSClientConfig(c, o *TLSClientConfig) {
+	if o.Insecure || len(o.CAFile) > 0 || len(o.CAData) > 0 {
+		c.Insecure = o.Insecure
+		c.CAFile = o.CAFile
+		c.CAData = o.CAData
+	}
+	if len(o.CertFile) > 0 || len(o.KeyFile) > 0 || len(o.CertData) > 0 || len(o.KeyData) > 0 {
+		c.CertFile = o.CertFile
+		c.KeyFile = o.KeyFile
+		c.CertData = o.CertData
+		c.KeyData = o.KeyData
+	}
+	if len(o.ServerName) > 0 {
+		c.ServerName = o.ServerName
+	}
+	if len(o.NextProtos) > 0 {
+		c.NextProtos = o.NextProtos
+	}
+}
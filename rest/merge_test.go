@@ -0,0 +1,242 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/transport"
+	"k8s.io/client-go/util/flowcontrol"
+
+	"github.com/google/go-cmp/cmp"
+	fuzz "github.com/google/gofuzz"
+	"golang.org/x/oauth2"
+)
+
+func TestMergeConfigsOntoEmpty(t *testing.T) {
+	f := fuzz.New().NilChance(0.0).NumElements(1, 1)
+	f.Funcs(
+		func(r *runtime.Codec, f fuzz.Continue) {
+			codec := &fakeCodec{}
+			f.Fuzz(codec)
+			*r = codec
+		},
+		func(r *http.RoundTripper, f fuzz.Continue) {
+			roundTripper := &fakeRoundTripper{}
+			f.Fuzz(roundTripper)
+			*r = roundTripper
+		},
+		func(fn *func(http.RoundTripper) http.RoundTripper, f fuzz.Continue) {
+			*fn = fakeWrapperFunc
+		},
+		func(fn *transport.WrapperFunc, f fuzz.Continue) {
+			*fn = fakeWrapperFunc
+		},
+		func(fn *transport.HTTP3TransportFunc, f fuzz.Continue) {
+			*fn = fakeHTTP3TransportFunc
+		},
+		func(r *runtime.NegotiatedSerializer, f fuzz.Continue) {
+			serializer := &fakeNegotiatedSerializer{}
+			f.Fuzz(serializer)
+			*r = serializer
+		},
+		func(r *flowcontrol.RateLimiter, f fuzz.Continue) {
+			limiter := &fakeLimiter{}
+			f.Fuzz(limiter)
+			*r = limiter
+		},
+		func(h *WarningHandler, f fuzz.Continue) {
+			*h = &fakeWarningHandler{}
+		},
+		func(s *AuditSink, f fuzz.Continue) {
+			*s = &fakeAuditSink{}
+		},
+		func(c **ReadCache, f fuzz.Continue) {
+			*c = NewReadCache(ReadCacheOptions{})
+		},
+		func(r *AuthProviderConfigPersister, f fuzz.Continue) {
+			*r = fakeAuthProviderConfigPersister{}
+		},
+		func(r *func(ctx context.Context, network, addr string) (net.Conn, error), f fuzz.Continue) {
+			*r = fakeDialFunc
+		},
+		func(r *func(*http.Request) (*url.URL, error), f fuzz.Continue) {
+			*r = fakeProxyFunc
+		},
+		func(r *oauth2.TokenSource, f fuzz.Continue) {
+			*r = fakeTokenSource{AccessToken: f.RandString()}
+		},
+		func(r *runtime.Object, f fuzz.Continue) {
+			unknown := &runtime.Unknown{}
+			f.Fuzz(unknown)
+			*r = unknown
+		},
+	)
+	for i := 0; i < 20; i++ {
+		overlay := &Config{}
+		f.Fuzz(overlay)
+		actual := MergeConfigs(&Config{}, overlay)
+		expected := *overlay
+
+		// Every field set in an overlay must end up in the merged config, update
+		// mergeConfig if a new field is added to Config.
+		if actual.WrapTransport == nil || !reflect.DeepEqual(actual.WrapTransport(nil), &fakeRoundTripper{}) {
+			t.Fatalf("MergeConfigs dropped the WrapTransport field")
+		}
+		actual.WrapTransport = nil
+		expected.WrapTransport = nil
+
+		if actual.HTTP3Transport == nil {
+			t.Fatalf("MergeConfigs dropped the HTTP3Transport field")
+		}
+		actual.HTTP3Transport = nil
+		expected.HTTP3Transport = nil
+
+		if actual.Dial == nil {
+			t.Fatalf("MergeConfigs dropped the Dial field")
+		}
+		actual.Dial = nil
+		expected.Dial = nil
+
+		if actual.Proxy == nil {
+			t.Fatalf("MergeConfigs dropped the Proxy field")
+		}
+		actual.Proxy = nil
+		expected.Proxy = nil
+
+		actual.HostOverrides = dropHostOverrideProxies(t, actual.HostOverrides)
+		expected.HostOverrides = dropHostOverrideProxies(t, expected.HostOverrides)
+
+		if diff := cmp.Diff(*actual, expected, readCacheComparer); diff != "" {
+			t.Fatalf("MergeConfigs dropped unexpected fields (-got, +want): %s", diff)
+		}
+	}
+}
+
+func TestMergeConfigs(t *testing.T) {
+	base := &Config{
+		Host:      "https://base",
+		APIPath:   "/api",
+		Username:  "user",
+		Password:  "pass",
+		UserAgent: "base-agent",
+		QPS:       5,
+		Burst:     10,
+		Impersonate: ImpersonationConfig{
+			UserName: "someone",
+			Groups:   []string{"group"},
+		},
+		TLSClientConfig: TLSClientConfig{
+			Insecure:   true,
+			ServerName: "base-server",
+			CertFile:   "base.crt",
+			KeyFile:    "base.key",
+		},
+		HostOverrides: map[string]HostOverride{
+			"a": {TLSClientConfig: &TLSClientConfig{ServerName: "a"}},
+			"b": {TLSClientConfig: &TLSClientConfig{ServerName: "b"}},
+		},
+	}
+	tenant := &Config{
+		BearerToken: "token",
+		QPS:         50,
+		Impersonate: ImpersonationConfig{
+			UID: "uid",
+		},
+		TLSClientConfig: TLSClientConfig{
+			CAData:   []byte("ca"),
+			CertData: []byte("cert"),
+			KeyData:  []byte("key"),
+		},
+		HostOverrides: map[string]HostOverride{
+			"b": {TLSClientConfig: &TLSClientConfig{ServerName: "tenant-b"}},
+		},
+	}
+	request := &Config{
+		UserAgent: "request-agent",
+	}
+
+	actual := MergeConfigs(base, nil, tenant, request)
+	expected := &Config{
+		Host:        "https://base",
+		APIPath:     "/api",
+		BearerToken: "token",
+		UserAgent:   "request-agent",
+		QPS:         50,
+		Burst:       10,
+		Impersonate: ImpersonationConfig{
+			UID: "uid",
+		},
+		TLSClientConfig: TLSClientConfig{
+			ServerName: "base-server",
+			CAData:     []byte("ca"),
+			CertData:   []byte("cert"),
+			KeyData:    []byte("key"),
+		},
+		HostOverrides: map[string]HostOverride{
+			"a": {TLSClientConfig: &TLSClientConfig{ServerName: "a"}},
+			"b": {TLSClientConfig: &TLSClientConfig{ServerName: "tenant-b"}},
+		},
+	}
+	if diff := cmp.Diff(actual, expected); diff != "" {
+		t.Errorf("unexpected merged config (-got, +want): %s", diff)
+	}
+
+	if base.UserAgent != "base-agent" || base.HostOverrides["b"].TLSClientConfig.ServerName != "b" {
+		t.Errorf("MergeConfigs modified the base config: %v", base)
+	}
+	if len(tenant.HostOverrides) != 1 {
+		t.Errorf("MergeConfigs modified an overlay: %v", tenant)
+	}
+}
+
+func TestMergeConfigsComposesWrappers(t *testing.T) {
+	var order []string
+	wrapper := func(name string) transport.WrapperFunc {
+		return func(rt http.RoundTripper) http.RoundTripper {
+			order = append(order, name)
+			return rt
+		}
+	}
+
+	merged := MergeConfigs(
+		&Config{WrapTransport: wrapper("base")},
+		&Config{},
+		&Config{WrapTransport: wrapper("tenant")},
+		&Config{WrapTransport: wrapper("request")},
+	)
+	merged.WrapTransport(nil)
+	if expected := []string{"base", "tenant", "request"}; !reflect.DeepEqual(order, expected) {
+		t.Errorf("expected the wrappers to be applied in order %v, got %v", expected, order)
+	}
+}
+
+func TestMergeConfigsNilBase(t *testing.T) {
+	merged := MergeConfigs(nil, &Config{Host: "https://overlay"})
+	if merged.Host != "https://overlay" {
+		t.Errorf("unexpected host %q", merged.Host)
+	}
+	if merged := MergeConfigs(nil); merged == nil {
+		t.Errorf("expected an empty config, got nil")
+	}
+}
@@ -21,11 +21,16 @@ import (
 	"net/http"
 	"sync"
 
+	"golang.org/x/oauth2"
 	"k8s.io/klog/v2"
 
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/transport"
 )
 
+// AuthProvider is the interface implemented by auth provider plugins. A plugin
+// is selected by the name in the kubeconfig user's auth-provider stanza and
+// must be registered with RegisterAuthProviderPlugin before a client is built.
 type AuthProvider interface {
 	// WrapTransport allows the plugin to create a modified RoundTripper that
 	// attaches authorization headers (or other info) to requests.
@@ -58,6 +63,11 @@ func (n *noopPersister) Persist(_ map[string]string) error {
 var pluginsLock sync.Mutex
 var plugins = make(map[string]Factory)
 
+// RegisterAuthProviderPlugin registers a Factory under the given name. The
+// name is matched against AuthProviderConfig.Name when a transport is built
+// from a rest.Config, so registration normally happens from an init function
+// of the package providing the plugin. Registering the same name twice is an
+// error.
 func RegisterAuthProviderPlugin(name string, plugin Factory) error {
 	pluginsLock.Lock()
 	defer pluginsLock.Unlock()
@@ -69,6 +79,9 @@ func RegisterAuthProviderPlugin(name string, plugin Factory) error {
 	return nil
 }
 
+// GetAuthProvider returns an AuthProvider built by the Factory registered under
+// apc.Name. If persister is nil, configuration updates made by the plugin are
+// discarded.
 func GetAuthProvider(clusterAddress string, apc *clientcmdapi.AuthProviderConfig, persister AuthProviderConfigPersister) (AuthProvider, error) {
 	pluginsLock.Lock()
 	defer pluginsLock.Unlock()
@@ -81,3 +94,41 @@ func GetAuthProvider(clusterAddress string, apc *clientcmdapi.AuthProviderConfig
 	}
 	return p(clusterAddress, apc.Config, persister)
 }
+
+// TokenSourceFunc returns an oauth2.TokenSource for the given cluster address
+// and auth provider configuration.
+type TokenSourceFunc func(clusterAddress string, config map[string]string) (oauth2.TokenSource, error)
+
+// NewTokenSourceAuthProviderFactory returns a Factory that adapts an in-process
+// token source to the AuthProvider interface. Tokens are cached until they
+// expire and are discarded when the server responds with 401 Unauthorized, so
+// the source is asked for a fresh token on the next request.
+//
+// This allows embedders that already hold a credential chain to register it by
+// name, for example:
+//
+//	rest.RegisterAuthProviderPlugin("my-sdk", rest.NewTokenSourceAuthProviderFactory(newSource))
+func NewTokenSourceAuthProviderFactory(newSource TokenSourceFunc) Factory {
+	return func(clusterAddress string, config map[string]string, _ AuthProviderConfigPersister) (AuthProvider, error) {
+		ts, err := newSource(clusterAddress, config)
+		if err != nil {
+			return nil, err
+		}
+		if ts == nil {
+			return nil, fmt.Errorf("token source for cluster %q is nil", clusterAddress)
+		}
+		return &tokenSourceAuthProvider{ts: transport.NewCachedTokenSource(ts)}, nil
+	}
+}
+
+type tokenSourceAuthProvider struct {
+	ts transport.ResettableTokenSource
+}
+
+func (p *tokenSourceAuthProvider) WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	return transport.ResettableTokenSourceWrapTransport(p.ts)(rt)
+}
+
+func (p *tokenSourceAuthProvider) Login() error {
+	return nil
+}
@@ -23,6 +23,8 @@ import (
 	"strconv"
 	"testing"
 
+	"golang.org/x/oauth2"
+
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
@@ -193,6 +195,43 @@ func Test_WhenNilPersister_NoOpPersisterIsAssigned(t *testing.T) {
 
 }
 
+func TestTokenSourceAuthProviderFactory(t *testing.T) {
+	factory := NewTokenSourceAuthProviderFactory(func(clusterAddress string, config map[string]string) (oauth2.TokenSource, error) {
+		if config["token"] == "" {
+			return nil, fmt.Errorf("no token configured for %s", clusterAddress)
+		}
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: config["token"]}), nil
+	})
+	if err := RegisterAuthProviderPlugin("tokenSourcePlugin", factory); err != nil {
+		t.Fatalf("unexpected error: failed to register 'tokenSourcePlugin': %v", err)
+	}
+
+	if _, err := GetAuthProvider("127.0.0.1", &clientcmdapi.AuthProviderConfig{Name: "tokenSourcePlugin"}, nil); err == nil {
+		t.Errorf("expected error for missing token config")
+	}
+
+	c := Config{AuthProvider: &clientcmdapi.AuthProviderConfig{
+		Name:   "tokenSourcePlugin",
+		Config: map[string]string{"token": "my-token"},
+	}}
+	tConfig, err := c.TransportConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var gotAuth string
+	rt := tConfig.WrapTransport(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		gotAuth = req.Header.Get("Authorization")
+		return &http.Response{StatusCode: http.StatusOK, Header: make(http.Header)}, nil
+	}))
+	req, _ := http.NewRequest("GET", "https://127.0.0.1", nil)
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotAuth != "Bearer my-token" {
+		t.Errorf("expected bearer token to be injected, got %q", gotAuth)
+	}
+}
+
 // emptyTransport provides an empty http.Response with an initialized header
 // to allow wrapping RoundTrippers to set header values.
 type emptyTransport struct{}
@@ -331,3 +370,9 @@ func (p *pluginPersist) Login() error {
 func pluginPersistProvider(_ string, config map[string]string, persister AuthProviderConfigPersister) (AuthProvider, error) {
 	return &pluginPersist{config, persister}, nil
 }
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/version"
+)
+
+// maxProbeErrorBodyLength is the maximum length of the response bodies quoted
+// in the errors of a ClusterProbeReport.
+const maxProbeErrorBodyLength = 256
+
+// ClusterProbeReport is the report of ProbeCluster.
+type ClusterProbeReport struct {
+	// Host is the URL of the server probed.
+	Host string
+
+	// Reachable is whether the server responded.
+	Reachable bool
+	// ReachabilityError is the error of requesting the server, if it did
+	// not respond.
+	ReachabilityError error
+	// Latency is the round-trip time of the version request.
+	Latency time.Duration
+
+	// Authenticated is whether the server accepted the credentials of the
+	// config. Servers allowing anonymous requests accept configs without
+	// credentials.
+	Authenticated bool
+	// Authorized is whether the authenticated user may read the API
+	// discovery, which all the users of a cluster usually may.
+	Authorized bool
+	// AuthError is the error of the discovery request, if the user was not
+	// authenticated or not authorized.
+	AuthError error
+
+	// ServerVersion is the version of the server, if it could be read.
+	ServerVersion *version.Info
+	// VersionError is the error of reading the version of the server.
+	VersionError error
+
+	// ClockSkew is the estimated difference between the clock of the server
+	// and the local clock, positive when the clock of the server is ahead.
+	// It is estimated from the Date header of the version response, whose
+	// resolution is a second: its accuracy is about half a second plus half
+	// the latency.
+	ClockSkew time.Duration
+	// ClockSkewKnown is whether the server responded with a Date header from
+	// which ClockSkew was estimated.
+	ClockSkewKnown bool
+}
+
+// Err returns the aggregate of the errors of the checks which failed, or
+// nil if all the checks succeeded.
+func (r *ClusterProbeReport) Err() error {
+	var errs []error
+	for _, err := range []error{r.ReachabilityError, r.AuthError, r.VersionError} {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// ProbeCluster checks in one call whether the server of config is reachable,
+// whether it accepts the credentials of config, its version and the skew of
+// its clock. The failures of the checks are reported in the returned report;
+// an error is only returned if config is invalid.
+func ProbeCluster(ctx context.Context, config *Config) (*ClusterProbeReport, error) {
+	client, err := HTTPClientFor(config)
+	if err != nil {
+		return nil, err
+	}
+	base, _, err := defaultServerUrlFor(config)
+	if err != nil {
+		return nil, err
+	}
+	report := &ClusterProbeReport{Host: base.String()}
+
+	start := time.Now()
+	resp, body, err := probeGet(ctx, client, base, "/version")
+	if err != nil {
+		report.ReachabilityError = err
+		return report, nil
+	}
+	report.Reachable = true
+	report.Latency = time.Since(start)
+	if date, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+		// the server time is between the date and the next second, and the
+		// server responded half the latency after the request was sent
+		serverTime := date.Add(500 * time.Millisecond)
+		report.ClockSkew = serverTime.Sub(start.Add(report.Latency / 2))
+		report.ClockSkewKnown = true
+	}
+	if resp.StatusCode == http.StatusOK {
+		var info version.Info
+		if err := json.Unmarshal(body, &info); err != nil {
+			report.VersionError = fmt.Errorf("unable to decode the server version: %v", err)
+		} else {
+			report.ServerVersion = &info
+		}
+	} else {
+		report.VersionError = probeResponseError(resp, body)
+	}
+
+	resp, body, err = probeGet(ctx, client, base, "/api")
+	switch {
+	case err != nil:
+		report.AuthError = err
+	case resp.StatusCode == http.StatusUnauthorized:
+		report.AuthError = probeResponseError(resp, body)
+	case resp.StatusCode == http.StatusForbidden:
+		report.Authenticated = true
+		report.AuthError = probeResponseError(resp, body)
+	case resp.StatusCode == http.StatusOK:
+		report.Authenticated = true
+		report.Authorized = true
+	default:
+		report.AuthError = probeResponseError(resp, body)
+	}
+	return report, nil
+}
+
+// probeGet requests the path of the server at base.
+func probeGet(ctx context.Context, client *http.Client, base *url.URL, p string) (*http.Response, []byte, error) {
+	u := *base
+	u.Path = path.Join(u.Path, p)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, body, nil
+}
+
+// probeResponseError returns the error of an unexpected response.
+func probeResponseError(resp *http.Response, body []byte) error {
+	if len(body) > maxProbeErrorBodyLength {
+		body = append(body[:maxProbeErrorBodyLength:maxProbeErrorBodyLength], "..."...)
+	}
+	return fmt.Errorf("%s %s: %s: %s", resp.Request.Method, resp.Request.URL.Path, resp.Status, body)
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/version"
+)
+
+func TestProbeCluster(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/version":
+			w.Header().Set("Date", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+			json.NewEncoder(w).Encode(version.Info{Major: "1", Minor: "23", GitVersion: "v1.23.0"})
+		case "/api":
+			switch req.Header.Get("Authorization") {
+			case "Bearer admin":
+				w.Write([]byte(`{"kind":"APIVersions","versions":["v1"]}`))
+			case "Bearer nobody":
+				http.Error(w, "forbidden", http.StatusForbidden)
+			default:
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+			}
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name                string
+		token               string
+		expectAuthenticated bool
+		expectAuthorized    bool
+		expectAuthError     string
+	}{
+		{
+			name:                "authorized",
+			token:               "admin",
+			expectAuthenticated: true,
+			expectAuthorized:    true,
+		},
+		{
+			name:                "forbidden",
+			token:               "nobody",
+			expectAuthenticated: true,
+			expectAuthError:     "403 Forbidden",
+		},
+		{
+			name:            "unauthorized",
+			token:           "expired",
+			expectAuthError: "401 Unauthorized",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			report, err := ProbeCluster(context.Background(), &Config{Host: server.URL, BearerToken: test.token})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !report.Reachable || report.ReachabilityError != nil {
+				t.Errorf("expected the server to be reachable, got %v", report.ReachabilityError)
+			}
+			if report.ServerVersion == nil || report.ServerVersion.GitVersion != "v1.23.0" || report.VersionError != nil {
+				t.Errorf("unexpected server version %v (%v)", report.ServerVersion, report.VersionError)
+			}
+			if !report.ClockSkewKnown || report.ClockSkew < time.Hour-2*time.Second || report.ClockSkew > time.Hour+2*time.Second {
+				t.Errorf("expected a clock skew of about an hour, got %v (known: %t)", report.ClockSkew, report.ClockSkewKnown)
+			}
+			if report.Authenticated != test.expectAuthenticated || report.Authorized != test.expectAuthorized {
+				t.Errorf("expected authenticated %t and authorized %t, got %t and %t", test.expectAuthenticated, test.expectAuthorized, report.Authenticated, report.Authorized)
+			}
+			if len(test.expectAuthError) == 0 {
+				if report.AuthError != nil || report.Err() != nil {
+					t.Errorf("unexpected errors %v", report.Err())
+				}
+				return
+			}
+			if report.AuthError == nil || !strings.Contains(report.AuthError.Error(), test.expectAuthError) {
+				t.Errorf("expected an auth error containing %q, got %v", test.expectAuthError, report.AuthError)
+			}
+			if report.Err() == nil {
+				t.Error("expected the report to hold an error")
+			}
+		})
+	}
+}
+
+func TestProbeClusterUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	host := server.URL
+	server.Close()
+
+	report, err := ProbeCluster(context.Background(), &Config{Host: host})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Reachable || report.ReachabilityError == nil || report.Err() == nil {
+		t.Errorf("expected the server to be unreachable, got %#v", report)
+	}
+	if report.Host != host {
+		t.Errorf("expected host %s, got %s", host, report.Host)
+	}
+}
+
+func TestProbeClusterInvalidConfig(t *testing.T) {
+	if _, err := ProbeCluster(context.Background(), &Config{Host: "https://localhost", TLSClientConfig: TLSClientConfig{CAData: []byte("invalid")}}); err == nil {
+		t.Error("expected an error for an invalid config")
+	}
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/utils/clock"
+)
+
+const (
+	// DefaultReadCacheMaxAge is the default duration for which a ReadCache
+	// serves responses without contacting the server.
+	DefaultReadCacheMaxAge = time.Second
+	// DefaultReadCacheMaxEntries is the default number of responses a
+	// ReadCache holds.
+	DefaultReadCacheMaxEntries = 1024
+)
+
+// ReadCacheOptions configures a ReadCache. The zero value is valid.
+type ReadCacheOptions struct {
+	// MaxAge is the duration for which responses are served without
+	// contacting the server, DefaultReadCacheMaxAge if 0. Older responses
+	// carrying an ETag are revalidated with If-None-Match, and others are
+	// fetched again.
+	MaxAge time.Duration
+	// MaxEntries bounds the number of cached responses,
+	// DefaultReadCacheMaxEntries if 0. The least recently validated
+	// responses are evicted first.
+	MaxEntries int
+}
+
+// ReadCache caches the responses of the GETs of named objects, like the ones
+// of the Get methods of clientsets, to cut the reads of loops fetching the
+// same objects repeatedly. Responses are keyed by URL, which holds the group,
+// version, resource, namespace and name of the object, by accepted content
+// type and by the fingerprint of the config of the client, so that configs
+// copied with other credentials or impersonating other users can share it.
+// The responses of an object are dropped when a client sharing the cache
+// writes to it, but writes of other clients are only seen once responses are
+// older than MaxAge.
+//
+// The credentials held by a TokenSource, WrapTransport or Transport are not
+// part of fingerprints, so configs relying on them must only share a
+// ReadCache when they authenticate as the same user. It is safe for
+// concurrent use.
+type ReadCache struct {
+	maxAge     time.Duration
+	maxEntries int
+	clock      clock.PassiveClock
+
+	lock    sync.Mutex
+	entries map[string]*readCacheEntry
+	// generation is incremented by Invalidate, to discard the responses of
+	// the requests sent before it as they may predate the write.
+	generation uint64
+}
+
+type readCacheEntry struct {
+	// path is the URL path of the response, to drop it on writes
+	path      string
+	result    Result
+	etag      string
+	validated time.Time
+}
+
+// NewReadCache returns an empty ReadCache.
+func NewReadCache(options ReadCacheOptions) *ReadCache {
+	return newReadCache(options, clock.RealClock{})
+}
+
+func newReadCache(options ReadCacheOptions, clock clock.PassiveClock) *ReadCache {
+	if options.MaxAge == 0 {
+		options.MaxAge = DefaultReadCacheMaxAge
+	}
+	if options.MaxEntries == 0 {
+		options.MaxEntries = DefaultReadCacheMaxEntries
+	}
+	return &ReadCache{
+		maxAge:     options.MaxAge,
+		maxEntries: options.MaxEntries,
+		clock:      clock,
+		entries:    map[string]*readCacheEntry{},
+	}
+}
+
+// Invalidate drops the responses of path and of the paths below it, like the
+// ones of the objects of a collection, and of the paths above it up to the
+// object they are subresources of.
+func (c *ReadCache) Invalidate(path string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.generation++
+	for key, entry := range c.entries {
+		if entry.path == path || strings.HasPrefix(entry.path, path+"/") || strings.HasPrefix(path, entry.path+"/") {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// cacheable returns whether the response of r may be cached.
+func (c *ReadCache) cacheable(r *Request) bool {
+	if r.err != nil || r.verb != "GET" || len(r.resourceName) == 0 {
+		return false
+	}
+	watch := r.params.Get("watch")
+	return watch != "true" && watch != "1"
+}
+
+// invalidateReadCache drops the responses of the object written by r from the
+// read cache of its client.
+func (r *Request) invalidateReadCache() {
+	if r.err != nil || r.verb == "GET" || r.verb == "HEAD" {
+		return
+	}
+	r.c.readCache.Invalidate(r.URL().Path)
+}
+
+// do returns the cached response of r if it is fresh, or sends r to fetch or
+// revalidate it.
+func (c *ReadCache) do(ctx context.Context, r *Request) Result {
+	url := r.URL()
+	key := r.c.readCacheIdentity + " " + r.headers.Get("Accept") + " " + url.String()
+
+	c.lock.Lock()
+	entry, cached := c.entries[key]
+	if cached && c.clock.Since(entry.validated) < c.maxAge {
+		result := entry.result
+		c.lock.Unlock()
+		return copyResult(result)
+	}
+	generation := c.generation
+	c.lock.Unlock()
+
+	if cached && len(entry.etag) > 0 {
+		r.SetHeader("If-None-Match", entry.etag)
+	}
+	var result Result
+	var etag string
+	notModified := false
+	err := r.request(ctx, func(req *http.Request, resp *http.Response) {
+		if cached && resp.StatusCode == http.StatusNotModified {
+			notModified = true
+			return
+		}
+		result = r.transformResponse(resp, req)
+		etag = resp.Header.Get("ETag")
+	})
+	if err != nil {
+		return Result{err: err}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if notModified {
+		if c.generation == generation {
+			entry.validated = c.clock.Now()
+			c.entries[key] = entry
+		}
+		return copyResult(entry.result)
+	}
+	if result.err != nil || result.statusCode != http.StatusOK {
+		delete(c.entries, key)
+		return result
+	}
+	if c.generation != generation {
+		// the response may predate a write sent while it was fetched
+		return result
+	}
+	if _, cached := c.entries[key]; !cached && len(c.entries) >= c.maxEntries {
+		c.evictLocked()
+	}
+	c.entries[key] = &readCacheEntry{
+		path:      url.Path,
+		result:    copyResult(result),
+		etag:      etag,
+		validated: c.clock.Now(),
+	}
+	return result
+}
+
+// evictLocked drops the least recently validated response. It must be
+// called while holding the lock.
+func (c *ReadCache) evictLocked() {
+	var oldestKey string
+	var oldest time.Time
+	for key, entry := range c.entries {
+		if len(oldestKey) == 0 || entry.validated.Before(oldest) {
+			oldestKey, oldest = key, entry.validated
+		}
+	}
+	delete(c.entries, oldestKey)
+}
+
+// copyResult returns a copy of result whose body can be modified without
+// modifying the body of result.
+func copyResult(result Result) Result {
+	result.body = append([]byte(nil), result.body...)
+	return result
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+// readCacheServer serves pods, counting the requests by method and path and
+// answering If-None-Match with 304 when etag is set. onRequest, if set, is
+// called before answering.
+type readCacheServer struct {
+	lock      sync.Mutex
+	requests  map[string]int
+	etag      string
+	onRequest func()
+}
+
+func (s *readCacheServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.lock.Lock()
+	s.requests[req.Method+" "+req.URL.Path]++
+	etag := s.etag
+	s.lock.Unlock()
+	if s.onRequest != nil {
+		s.onRequest()
+	}
+	if len(etag) > 0 {
+		if req.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"kind":"Pod","apiVersion":"v1","metadata":{"name":"foo","namespace":"ns"}}`))
+}
+
+func (s *readCacheServer) count(request string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.requests[request]
+}
+
+func newReadCacheClient(t *testing.T, server *readCacheServer, cache *ReadCache) (*RESTClient, func()) {
+	testServer := httptest.NewServer(server)
+	client, err := RESTClientFor(readCacheConfig(testServer.URL, cache))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, testServer.Close
+}
+
+func readCacheConfig(host string, cache *ReadCache) *Config {
+	return &Config{
+		Host:    host,
+		APIPath: "/api",
+		ContentConfig: ContentConfig{
+			GroupVersion:         &v1.SchemeGroupVersion,
+			NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		},
+		ReadCache: cache,
+	}
+}
+
+func TestReadCache(t *testing.T) {
+	server := &readCacheServer{requests: map[string]int{}}
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	client, stop := newReadCacheClient(t, server, newReadCache(ReadCacheOptions{}, fakeClock))
+	defer stop()
+	ctx := context.Background()
+
+	get := func() {
+		t.Helper()
+		pod := &v1.Pod{}
+		if err := client.Get().Namespace("ns").Resource("pods").Name("foo").Do(ctx).Into(pod); err != nil {
+			t.Fatal(err)
+		}
+		if pod.Name != "foo" {
+			t.Fatalf("unexpected pod %#v", pod)
+		}
+	}
+	const getFoo = "GET /api/v1/namespaces/ns/pods/foo"
+
+	get()
+	get()
+	if got := server.count(getFoo); got != 1 {
+		t.Errorf("expected the second get to be served from the cache, got %d requests", got)
+	}
+
+	fakeClock.Step(DefaultReadCacheMaxAge)
+	get()
+	if got := server.count(getFoo); got != 2 {
+		t.Errorf("expected the response to be fetched again once older than its max age, got %d requests", got)
+	}
+
+	client.Put().Namespace("ns").Resource("pods").Name("foo").SubResource("status").Body([]byte(`{}`)).Do(ctx)
+	get()
+	if got := server.count(getFoo); got != 3 {
+		t.Errorf("expected the response to be dropped on writes, got %d requests", got)
+	}
+
+	// lists are not cached
+	client.Get().Namespace("ns").Resource("pods").Do(ctx)
+	client.Get().Namespace("ns").Resource("pods").Do(ctx)
+	if got := server.count("GET /api/v1/namespaces/ns/pods"); got != 2 {
+		t.Errorf("expected lists not to be cached, got %d requests", got)
+	}
+}
+
+func TestReadCacheRevalidation(t *testing.T) {
+	server := &readCacheServer{requests: map[string]int{}, etag: `"1"`}
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	cache := newReadCache(ReadCacheOptions{MaxAge: time.Minute}, fakeClock)
+	client, stop := newReadCacheClient(t, server, cache)
+	defer stop()
+	ctx := context.Background()
+
+	first, err := client.Get().Namespace("ns").Resource("pods").Name("foo").Do(ctx).Raw()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fakeClock.Step(time.Minute)
+	second, err := client.Get().Namespace("ns").Resource("pods").Name("foo").Do(ctx).Raw()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(first) != string(second) {
+		t.Errorf("expected the revalidated response %s, got %s", first, second)
+	}
+	if got := server.count("GET /api/v1/namespaces/ns/pods/foo"); got != 2 {
+		t.Errorf("expected the response to be revalidated, got %d requests", got)
+	}
+	if got := len(cache.entries); got != 1 {
+		t.Errorf("expected the revalidated response to stay cached, got %d responses", got)
+	}
+}
+
+func TestReadCacheMaxEntries(t *testing.T) {
+	server := &readCacheServer{requests: map[string]int{}}
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	cache := newReadCache(ReadCacheOptions{MaxEntries: 2}, fakeClock)
+	client, stop := newReadCacheClient(t, server, cache)
+	defer stop()
+
+	for _, name := range []string{"a", "b", "c"} {
+		client.Get().Namespace("ns").Resource("pods").Name(name).Do(context.Background())
+		fakeClock.Step(time.Millisecond)
+	}
+	if got := len(cache.entries); got != 2 {
+		t.Fatalf("expected 2 cached responses, got %d", got)
+	}
+	for _, entry := range cache.entries {
+		if entry.path == "/api/v1/namespaces/ns/pods/a" {
+			t.Errorf("expected the oldest response to be evicted")
+		}
+	}
+}
+
+func TestReadCacheIdentity(t *testing.T) {
+	server := &readCacheServer{requests: map[string]int{}}
+	testServer := httptest.NewServer(server)
+	defer testServer.Close()
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	config := readCacheConfig(testServer.URL, newReadCache(ReadCacheOptions{}, fakeClock))
+
+	impersonating := CopyConfig(config)
+	impersonating.Impersonate.UserName = "other"
+	withToken := CopyConfig(config)
+	withToken.BearerToken = "token"
+	for i, config := range []*Config{config, CopyConfig(config), impersonating, withToken} {
+		client, err := RESTClientFor(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client.Get().Namespace("ns").Resource("pods").Name("foo").Do(context.Background())
+		if got, expected := server.count("GET /api/v1/namespaces/ns/pods/foo"), []int{1, 1, 2, 3}[i]; got != expected {
+			t.Errorf("%d: expected %d requests, got %d", i, expected, got)
+		}
+	}
+
+	config.ReadCache = nil
+	client, err := RESTClientFor(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.readCacheIdentity != "" {
+		t.Errorf("expected no read cache identity without a read cache, got %q", client.readCacheIdentity)
+	}
+}
+
+func TestReadCacheWriteDuringGet(t *testing.T) {
+	server := &readCacheServer{requests: map[string]int{}}
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	cache := newReadCache(ReadCacheOptions{}, fakeClock)
+	client, stop := newReadCacheClient(t, server, cache)
+	defer stop()
+
+	// the object is written while its first get is in flight
+	server.onRequest = func() {
+		server.onRequest = nil
+		cache.Invalidate("/api/v1/namespaces/ns/pods/foo")
+	}
+	client.Get().Namespace("ns").Resource("pods").Name("foo").Do(context.Background())
+	client.Get().Namespace("ns").Resource("pods").Name("foo").Do(context.Background())
+	if got := server.count("GET /api/v1/namespaces/ns/pods/foo"); got != 2 {
+		t.Errorf("expected the response fetched across the write not to be cached, got %d requests", got)
+	}
+}
@@ -40,6 +40,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer/streaming"
 	"k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/apimachinery/pkg/watch"
 	restclientwatch "k8s.io/client-go/rest/watch"
 	"k8s.io/client-go/tools/metrics"
@@ -669,6 +670,32 @@ func (b *throttledLogger) Infof(message string, args ...interface{}) {
 // Watch attempts to begin watching the requested location.
 // Returns a watch.Interface, or an error.
 func (r *Request) Watch(ctx context.Context) (watch.Interface, error) {
+	return r.watch(ctx, r.newStreamWatcher, watch.NewEmptyWatch)
+}
+
+// WatchWithErrors is like Watch, but the returned watcher reports the events
+// that cannot be decoded and the errors of the underlying stream on its
+// Errors channel, with the raw bytes of undecodable events attached, instead
+// of sending synthetic watch.Error events.
+func (r *Request) WatchWithErrors(ctx context.Context) (restclientwatch.Interface, error) {
+	newWatcher := func(resp *http.Response) (watch.Interface, error) {
+		decoder, err := r.newWatchDecoder(resp)
+		if err != nil {
+			return nil, err
+		}
+		return restclientwatch.NewStreamWatcher(decoder), nil
+	}
+	newEmptyWatcher := func() watch.Interface {
+		return restclientwatch.NewEmptyWatcher()
+	}
+	w, err := r.watch(ctx, newWatcher, newEmptyWatcher)
+	if err != nil {
+		return nil, err
+	}
+	return w.(restclientwatch.Interface), nil
+}
+
+func (r *Request) watch(ctx context.Context, newWatcher func(*http.Response) (watch.Interface, error), newEmptyWatcher func() watch.Interface) (watch.Interface, error) {
 	// We specifically don't want to rate limit watches, so we
 	// don't use r.rateLimiter here.
 	if r.err != nil {
@@ -707,8 +734,10 @@ func (r *Request) Watch(ctx context.Context) (watch.Interface, error) {
 			retryAfter = nil
 		}
 
+		attemptStart := time.Now()
 		resp, err := client.Do(req)
 		updateURLMetrics(ctx, r, resp, err)
+		r.audit(req, resp, err, time.Since(attemptStart))
 		if r.c.base != nil {
 			if err != nil {
 				r.backoff.UpdateBackoff(r.c.base, err, 0)
@@ -717,7 +746,7 @@ func (r *Request) Watch(ctx context.Context) (watch.Interface, error) {
 			}
 		}
 		if err == nil && resp.StatusCode == http.StatusOK {
-			return r.newStreamWatcher(resp)
+			return newWatcher(resp)
 		}
 
 		done, transformErr := func() (bool, error) {
@@ -744,7 +773,7 @@ func (r *Request) Watch(ctx context.Context) (watch.Interface, error) {
 		}()
 		if done {
 			if isErrRetryableFunc(req, err) {
-				return watch.NewEmptyWatch(), nil
+				return newEmptyWatcher(), nil
 			}
 			if err == nil {
 				// if the server sent us an HTTP Response object,
@@ -757,6 +786,19 @@ func (r *Request) Watch(ctx context.Context) (watch.Interface, error) {
 }
 
 func (r *Request) newStreamWatcher(resp *http.Response) (watch.Interface, error) {
+	decoder, err := r.newWatchDecoder(resp)
+	if err != nil {
+		return nil, err
+	}
+	return watch.NewStreamWatcher(
+		decoder,
+		// use 500 to indicate that the cause of the error is unknown - other error codes
+		// are more specific to HTTP interactions, and set a reason
+		errors.NewClientErrorReporter(http.StatusInternalServerError, r.verb, "ClientWatchDecoding"),
+	), nil
+}
+
+func (r *Request) newWatchDecoder(resp *http.Response) (*restclientwatch.Decoder, error) {
 	contentType := resp.Header.Get("Content-Type")
 	mediaType, params, err := mime.ParseMediaType(contentType)
 	if err != nil {
@@ -771,13 +813,7 @@ func (r *Request) newStreamWatcher(resp *http.Response) (watch.Interface, error)
 
 	frameReader := framer.NewFrameReader(resp.Body)
 	watchEventDecoder := streaming.NewDecoder(frameReader, streamingSerializer)
-
-	return watch.NewStreamWatcher(
-		restclientwatch.NewDecoder(watchEventDecoder, objectDecoder),
-		// use 500 to indicate that the cause of the error is unknown - other error codes
-		// are more specific to HTTP interactions, and set a reason
-		errors.NewClientErrorReporter(http.StatusInternalServerError, r.verb, "ClientWatchDecoding"),
-	), nil
+	return restclientwatch.NewDecoder(watchEventDecoder, objectDecoder), nil
 }
 
 // updateURLMetrics is a convenience function for pushing metrics.
@@ -798,6 +834,18 @@ func updateURLMetrics(ctx context.Context, req *Request, resp *http.Response, er
 	}
 }
 
+// observeResponse reports the response to the rate limiter if it adapts to server responses.
+// Only regular requests are reported, the responses of watches and streams say little about
+// the load of the server since they are held open.
+func (r *Request) observeResponse(resp *http.Response, err error) {
+	observer, ok := r.rateLimiter.(flowcontrol.ResponseObserver)
+	if !ok || err != nil {
+		return
+	}
+	retryAfter, _ := retryAfterSeconds(resp)
+	observer.ObserveResponse(resp.StatusCode, time.Duration(retryAfter)*time.Second)
+}
+
 // Stream formats and executes the request, and offers streaming of the response.
 // Returns io.ReadCloser which could be used for streaming of the response, or an error
 // Any non-2xx http status code causes an error.  If we get a non-2xx code, we try to convert the body into an APIStatus object.
@@ -838,8 +886,10 @@ func (r *Request) Stream(ctx context.Context) (io.ReadCloser, error) {
 			retryAfter = nil
 		}
 
+		attemptStart := time.Now()
 		resp, err := client.Do(req)
 		updateURLMetrics(ctx, r, resp, err)
+		r.audit(req, resp, err, time.Since(attemptStart))
 		if r.c.base != nil {
 			if err != nil {
 				r.backoff.UpdateBackoff(r.URL(), err, 0)
@@ -918,6 +968,14 @@ func (r *Request) newHTTPRequest(ctx context.Context) (*http.Request, error) {
 	}
 	req = req.WithContext(ctx)
 	req.Header = r.headers
+	if r.c.auditSink != nil {
+		// identify the request in the audit logs of the server too
+		req.Header = req.Header.Clone()
+		if req.Header == nil {
+			req.Header = http.Header{}
+		}
+		req.Header.Set(auditIDHeader, string(uuid.NewUUID()))
+	}
 	return req, nil
 }
 
@@ -977,8 +1035,11 @@ func (r *Request) request(ctx context.Context, fn func(*http.Request, *http.Resp
 			}
 			retryAfter = nil
 		}
+		attemptStart := time.Now()
 		resp, err := client.Do(req)
 		updateURLMetrics(ctx, r, resp, err)
+		r.audit(req, resp, err, time.Since(attemptStart))
+		r.observeResponse(resp, err)
 		if err != nil {
 			r.backoff.UpdateBackoff(r.URL(), err, 0)
 		} else {
@@ -1034,6 +1095,12 @@ func (r *Request) request(ctx context.Context, fn func(*http.Request, *http.Resp
 //  * If the server responds with a status: *errors.StatusError or *errors.UnexpectedObjectError
 //  * http.Client.Do errors are returned directly.
 func (r *Request) Do(ctx context.Context) Result {
+	if cache := r.c.readCache; cache != nil {
+		if cache.cacheable(r) {
+			return cache.do(ctx, r)
+		}
+		defer r.invalidateReadCache()
+	}
 	var result Result
 	err := r.request(ctx, func(req *http.Request, resp *http.Response) {
 		result = r.transformResponse(resp, req)
@@ -1046,6 +1113,9 @@ func (r *Request) Do(ctx context.Context) Result {
 
 // DoRaw executes the request but does not process the response body.
 func (r *Request) DoRaw(ctx context.Context) ([]byte, error) {
+	if r.c.readCache != nil {
+		defer r.invalidateReadCache()
+	}
 	var result Result
 	err := r.request(ctx, func(req *http.Request, resp *http.Response) {
 		result.body, result.err = ioutil.ReadAll(resp.Body)
@@ -2238,6 +2238,69 @@ func TestStream(t *testing.T) {
 	}
 }
 
+type observingRateLimiter struct {
+	flowcontrol.RateLimiter
+	codes       []int
+	retryAfters []time.Duration
+}
+
+func (o *observingRateLimiter) ObserveResponse(statusCode int, retryAfter time.Duration) {
+	o.codes = append(o.codes, statusCode)
+	o.retryAfters = append(o.retryAfters, retryAfter)
+}
+
+func TestRequestObservesResponses(t *testing.T) {
+	limiter := &observingRateLimiter{RateLimiter: flowcontrol.NewFakeAlwaysRateLimiter()}
+	req := &Request{
+		c: &RESTClient{
+			Client: clientForFunc(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusTooManyRequests,
+					Header:     http.Header{"Retry-After": []string{"3"}},
+					Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+				}, nil
+			}),
+			base: &url.URL{},
+		},
+		rateLimiter: limiter,
+		backoff:     &NoBackoff{},
+		retry:       &withRetry{},
+	}
+	req.Do(context.Background())
+	if !reflect.DeepEqual(limiter.codes, []int{http.StatusTooManyRequests}) {
+		t.Errorf("unexpected observed status codes: %v", limiter.codes)
+	}
+	if !reflect.DeepEqual(limiter.retryAfters, []time.Duration{3 * time.Second}) {
+		t.Errorf("unexpected observed Retry-After: %v", limiter.retryAfters)
+	}
+}
+
+func TestRequestDoesNotObserveStreamResponses(t *testing.T) {
+	limiter := &observingRateLimiter{RateLimiter: flowcontrol.NewFakeAlwaysRateLimiter()}
+	newRequest := func() *Request {
+		return &Request{
+			c: &RESTClient{
+				Client: clientForFunc(func(req *http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: http.StatusTooManyRequests,
+						Header:     http.Header{"Retry-After": []string{"3"}},
+						Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+					}, nil
+				}),
+				base: &url.URL{},
+			},
+			rateLimiter: limiter,
+			backoff:     &NoBackoff{},
+			retry:       &withRetry{},
+		}
+	}
+	newRequest().Stream(context.Background())
+	newRequest().Watch(context.Background())
+	if len(limiter.codes) != 0 {
+		t.Errorf("expected the responses of streams and watches not to be observed, got %v", limiter.codes)
+	}
+}
+
 func testRESTClientWithConfig(t testing.TB, srv *httptest.Server, contentConfig ClientContentConfig) *RESTClient {
 	base, _ := url.Parse("http://localhost")
 	var c *http.Client
@@ -2740,6 +2803,57 @@ func TestRequestStreamWithRetry(t *testing.T) {
 	})
 }
 
+func TestWatchWithErrors(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"type":"ADDED","object":{"apiVersion":"v1","kind":"Pod","metadata":{"name":"first"}}}` + "\n"))
+		w.Write([]byte(`{"type":"ADDED","object":{"apiVersion":"v1","kind":"Unknown"}}` + "\n"))
+		w.Write([]byte(`{"type":"DELETED","object":{"apiVersion":"v1","kind":"Pod","metadata":{"name":"last"}}}` + "\n"))
+	}))
+	defer testServer.Close()
+
+	s := testRESTClient(t, testServer)
+	watching, err := s.Get().Prefix("path/to/watch/thing").WatchWithErrors(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var events []watch.EventType
+	var errs []error
+	resultCh, errCh := watching.ResultChan(), watching.Errors()
+	for resultCh != nil || errCh != nil {
+		select {
+		case event, ok := <-resultCh:
+			if !ok {
+				resultCh = nil
+				continue
+			}
+			events = append(events, event.Type)
+		case err, ok := <-errCh:
+			if !ok {
+				errCh = nil
+				continue
+			}
+			errs = append(errs, err)
+		}
+	}
+
+	if e, a := []watch.EventType{watch.Added, watch.Deleted}, events; !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected events %v, got %v", e, a)
+	}
+	if len(errs) != 1 {
+		t.Fatalf("Expected one error, got %v", errs)
+	}
+	decodeErr, ok := errs[0].(*restclientwatch.DecodeError)
+	if !ok {
+		t.Fatalf("Expected a decode error, got %#v", errs[0])
+	}
+	if e, a := `{"apiVersion":"v1","kind":"Unknown"}`, string(decodeErr.Raw); e != a {
+		t.Errorf("Expected raw event %s, got %s", e, a)
+	}
+}
+
 func TestRequestWatchWithRetry(t *testing.T) {
 	testRequestWithRetry(t, "Watch", func(ctx context.Context, r *Request) {
 		w, err := r.Watch(ctx)
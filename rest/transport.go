@@ -43,6 +43,7 @@ func HTTPClientFor(config *Config) (*http.Client, error) {
 	} else {
 		httpClient = http.DefaultClient
 	}
+	recordHTTPClient(config, transport)
 
 	return httpClient, nil
 }
@@ -102,14 +103,38 @@ func (c *Config) TransportConfig() (*transport.Config, error) {
 		Password:        c.Password,
 		BearerToken:     c.BearerToken,
 		BearerTokenFile: c.BearerTokenFile,
+		TokenSource:     c.TokenSource,
 		Impersonate: transport.ImpersonationConfig{
 			UserName: c.Impersonate.UserName,
 			UID:      c.Impersonate.UID,
 			Groups:   c.Impersonate.Groups,
 			Extra:    c.Impersonate.Extra,
 		},
-		Dial:  c.Dial,
-		Proxy: c.Proxy,
+		Dial:                  c.Dial,
+		Proxy:                 c.Proxy,
+		MaxConnectionLifetime: c.MaxConnectionLifetime,
+		HTTP3Transport:        c.HTTP3Transport,
+	}
+
+	if len(c.HostOverrides) > 0 {
+		conf.HostOverrides = make(map[string]transport.HostOverride, len(c.HostOverrides))
+		for host, override := range c.HostOverrides {
+			hostOverride := transport.HostOverride{Proxy: override.Proxy}
+			if tlsConfig := override.TLSClientConfig; tlsConfig != nil {
+				hostOverride.TLS = &transport.TLSConfig{
+					Insecure:   tlsConfig.Insecure,
+					ServerName: tlsConfig.ServerName,
+					CAFile:     tlsConfig.CAFile,
+					CAData:     tlsConfig.CAData,
+					CertFile:   tlsConfig.CertFile,
+					CertData:   tlsConfig.CertData,
+					KeyFile:    tlsConfig.KeyFile,
+					KeyData:    tlsConfig.KeyData,
+					NextProtos: tlsConfig.NextProtos,
+				}
+			}
+			conf.HostOverrides[host] = hostOverride
+		}
 	}
 
 	if c.ExecProvider != nil && c.AuthProvider != nil {
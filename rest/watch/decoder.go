@@ -25,6 +25,23 @@ import (
 	"k8s.io/apimachinery/pkg/watch"
 )
 
+// DecodeError is returned by Decoder when a watch event was read from the
+// stream but could not be decoded. The stream itself is still usable.
+type DecodeError struct {
+	// Raw holds the undecoded object of the event.
+	Raw []byte
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
 // Decoder implements the watch.Decoder interface for io.ReadClosers that
 // have contents which consist of a series of watchEvent objects encoded
 // with the given streaming decoder. The internal objects will be then
@@ -56,12 +73,12 @@ func (d *Decoder) Decode() (watch.EventType, runtime.Object, error) {
 	switch got.Type {
 	case string(watch.Added), string(watch.Modified), string(watch.Deleted), string(watch.Error), string(watch.Bookmark):
 	default:
-		return "", nil, fmt.Errorf("got invalid watch event type: %v", got.Type)
+		return "", nil, &DecodeError{Raw: got.Object.Raw, Err: fmt.Errorf("got invalid watch event type: %v", got.Type)}
 	}
 
 	obj, err := runtime.Decode(d.embeddedDecoder, got.Object.Raw)
 	if err != nil {
-		return "", nil, fmt.Errorf("unable to decode watch event: %v", err)
+		return "", nil, &DecodeError{Raw: got.Object.Raw, Err: fmt.Errorf("unable to decode watch event: %v", err)}
 	}
 	return watch.EventType(got.Type), obj, nil
 }
@@ -0,0 +1,136 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package versioned
+
+import (
+	"io"
+	"sync"
+
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// Interface is a watch.Interface reporting the errors of the watch stream on
+// a separate channel instead of as watch.Error events. Events of type
+// watch.Error are still delivered when they are sent by the server.
+type Interface interface {
+	watch.Interface
+
+	// Errors returns a channel receiving the errors of the watch stream.
+	// Events that could not be decoded are reported as *DecodeError holding
+	// the raw bytes of the event and the watch continues with the next event.
+	// Any other error ends the watch. The channel is closed when the watch
+	// ends, after the result channel. Consumers must receive from both
+	// channels until they are closed or stop the watch.
+	Errors() <-chan error
+}
+
+// StreamWatcher turns a stream for which a watch.Decoder can be written
+// into an Interface.
+type StreamWatcher struct {
+	lock   sync.Mutex
+	source watch.Decoder
+	result chan watch.Event
+	errors chan error
+	done   chan struct{}
+}
+
+var _ Interface = &StreamWatcher{}
+
+// NewStreamWatcher creates a StreamWatcher from the given decoder.
+func NewStreamWatcher(d watch.Decoder) *StreamWatcher {
+	sw := &StreamWatcher{
+		source: d,
+		result: make(chan watch.Event),
+		errors: make(chan error),
+		done:   make(chan struct{}),
+	}
+	go sw.receive()
+	return sw
+}
+
+// ResultChan implements watch.Interface.
+func (sw *StreamWatcher) ResultChan() <-chan watch.Event {
+	return sw.result
+}
+
+// Errors implements Interface.
+func (sw *StreamWatcher) Errors() <-chan error {
+	return sw.errors
+}
+
+// Stop implements watch.Interface.
+func (sw *StreamWatcher) Stop() {
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+	select {
+	case <-sw.done:
+	default:
+		close(sw.done)
+		sw.source.Close()
+	}
+}
+
+// receive reads from the decoder in a loop and sends down the result and
+// errors channels.
+func (sw *StreamWatcher) receive() {
+	defer utilruntime.HandleCrash()
+	defer close(sw.errors)
+	defer close(sw.result)
+	defer sw.Stop()
+	for {
+		action, obj, err := sw.source.Decode()
+		if err != nil {
+			if err == io.EOF {
+				// watch closed normally
+				return
+			}
+			select {
+			case <-sw.done:
+				return
+			case sw.errors <- err:
+			}
+			if _, ok := err.(*DecodeError); ok {
+				continue
+			}
+			return
+		}
+		select {
+		case <-sw.done:
+			return
+		case sw.result <- watch.Event{Type: action, Object: obj}:
+		}
+	}
+}
+
+// NewEmptyWatcher returns an Interface whose channels are closed.
+func NewEmptyWatcher() Interface {
+	result := make(chan watch.Event)
+	close(result)
+	errors := make(chan error)
+	close(errors)
+	return &emptyWatcher{result: result, errors: errors}
+}
+
+type emptyWatcher struct {
+	result chan watch.Event
+	errors chan error
+}
+
+func (w *emptyWatcher) Stop()                          {}
+func (w *emptyWatcher) ResultChan() <-chan watch.Event { return w.result }
+func (w *emptyWatcher) Errors() <-chan error           { return w.errors }
@@ -0,0 +1,84 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package versioned
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+type fakeDecoder struct {
+	results []error
+	closed  bool
+}
+
+func (d *fakeDecoder) Decode() (watch.EventType, runtime.Object, error) {
+	if len(d.results) == 0 {
+		return "", nil, io.EOF
+	}
+	err := d.results[0]
+	d.results = d.results[1:]
+	if err != nil {
+		return "", nil, err
+	}
+	return watch.Added, &runtime.Unknown{}, nil
+}
+
+func (d *fakeDecoder) Close() {
+	d.closed = true
+}
+
+func TestStreamWatcherErrors(t *testing.T) {
+	transportErr := errors.New("connection reset")
+	decodeErr := &DecodeError{Raw: []byte("raw"), Err: errors.New("bad event")}
+	decoder := &fakeDecoder{results: []error{nil, decodeErr, nil, transportErr, nil}}
+	sw := NewStreamWatcher(decoder)
+
+	var events int
+	var errs []error
+	resultCh, errCh := sw.ResultChan(), sw.Errors()
+	for resultCh != nil || errCh != nil {
+		select {
+		case _, ok := <-resultCh:
+			if !ok {
+				resultCh = nil
+				continue
+			}
+			events++
+		case err, ok := <-errCh:
+			if !ok {
+				errCh = nil
+				continue
+			}
+			errs = append(errs, err)
+		}
+	}
+
+	if events != 2 {
+		t.Errorf("expected 2 events before the transport error, got %d", events)
+	}
+	if len(errs) != 2 || errs[0] != decodeErr || errs[1] != transportErr {
+		t.Errorf("expected decode and transport errors, got %v", errs)
+	}
+	if !decoder.closed {
+		t.Errorf("expected decoder to be closed")
+	}
+}
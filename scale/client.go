@@ -19,8 +19,10 @@ package scale
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	autoscaling "k8s.io/api/autoscaling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -52,6 +54,11 @@ type scaleClient struct {
 	apiPathResolverFunc dynamic.APIPathResolverFunc
 	scaleKindResolver   ScaleKindResolver
 	clientBase          restclient.Interface
+
+	// resources caches the preferred resources resolved by the mapper,
+	// which may query discovery.
+	resources     map[schema.GroupResource]schema.GroupVersionResource
+	resourcesLock sync.RWMutex
 }
 
 // NewForConfig creates a new ScalesGetter which resolves kinds
@@ -83,6 +90,8 @@ func New(baseClient restclient.Interface, mapper PreferredResourceMapper, resolv
 		apiPathResolverFunc: resolver,
 		scaleKindResolver:   scaleKindResolver,
 		clientBase:          baseClient,
+
+		resources: make(map[schema.GroupResource]schema.GroupVersionResource),
 	}
 }
 
@@ -102,9 +111,19 @@ func (c *scaleClient) apiPathFor(groupVer schema.GroupVersion) string {
 // pathAndVersionFor returns the appropriate base path and the associated full GroupVersionResource
 // for the given GroupResource
 func (c *scaleClient) pathAndVersionFor(resource schema.GroupResource) (string, schema.GroupVersionResource, error) {
-	gvr, err := c.mapper.ResourceFor(resource.WithVersion(""))
-	if err != nil {
-		return "", gvr, fmt.Errorf("unable to get full preferred group-version-resource for %s: %v", resource.String(), err)
+	c.resourcesLock.RLock()
+	gvr, isCached := c.resources[resource]
+	c.resourcesLock.RUnlock()
+	if !isCached {
+		var err error
+		gvr, err = c.mapper.ResourceFor(resource.WithVersion(""))
+		if err != nil {
+			return "", gvr, fmt.Errorf("unable to get full preferred group-version-resource for %s: %v", resource.String(), err)
+		}
+
+		c.resourcesLock.Lock()
+		c.resources[resource] = gvr
+		c.resourcesLock.Unlock()
 	}
 
 	groupVer := gvr.GroupVersion()
@@ -112,6 +131,18 @@ func (c *scaleClient) pathAndVersionFor(resource schema.GroupResource) (string,
 	return c.apiPathFor(groupVer), gvr, nil
 }
 
+// forgetResourceOnNotFound forgets the preferred resource cached for
+// resource when err is a NotFound error, so that the next request resolves
+// it again, in case the resource is no longer served in this version.
+func (c *scaleClient) forgetResourceOnNotFound(resource schema.GroupResource, err error) {
+	if !apierrors.IsNotFound(err) {
+		return
+	}
+	c.resourcesLock.Lock()
+	defer c.resourcesLock.Unlock()
+	delete(c.resources, resource)
+}
+
 // namespacedScaleClient is an ScaleInterface for fetching
 // Scales in a given namespace.
 type namespacedScaleClient struct {
@@ -167,6 +198,7 @@ func (c *namespacedScaleClient) Get(ctx context.Context, resource schema.GroupRe
 		SpecificallyVersionedParams(&opts, dynamicParameterCodec, versionV1).
 		Do(ctx)
 	if err := result.Error(); err != nil {
+		c.client.forgetResourceOnNotFound(resource, err)
 		return nil, err
 	}
 
@@ -213,6 +245,7 @@ func (c *namespacedScaleClient) Update(ctx context.Context, resource schema.Grou
 		// propagate "raw" error from the API
 		// this allows callers to interpret underlying Reason field
 		// for example: errors.IsConflict(err)
+		c.client.forgetResourceOnNotFound(resource, err)
 		return nil, err
 	}
 
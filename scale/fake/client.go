@@ -26,6 +26,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/scale"
 	"k8s.io/client-go/testing"
 )
@@ -42,6 +43,8 @@ func (f *FakeScaleClient) Scales(namespace string) scale.ScaleInterface {
 	}
 }
 
+var _ scale.ListWatchInterface = &fakeNamespacedScaleClient{}
+
 type fakeNamespacedScaleClient struct {
 	namespace string
 	fake      *testing.Fake
@@ -79,3 +82,21 @@ func (f *fakeNamespacedScaleClient) Patch(ctx context.Context, gvr schema.GroupV
 
 	return obj.(*autoscalingapi.Scale), err
 }
+
+func (f *fakeNamespacedScaleClient) List(ctx context.Context, resource schema.GroupResource, opts metav1.ListOptions) (*metav1.List, error) {
+	action := testing.NewListAction(resource.WithVersion(""), autoscalingapi.SchemeGroupVersion.WithKind("Scale"), f.namespace, opts)
+	action.Subresource = "scale"
+	obj, err := f.fake.Invokes(action, &metav1.List{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return obj.(*metav1.List), err
+}
+
+func (f *fakeNamespacedScaleClient) Watch(ctx context.Context, resource schema.GroupResource, opts metav1.ListOptions) (watch.Interface, error) {
+	action := testing.NewWatchAction(resource.WithVersion(""), f.namespace, opts)
+	action.Subresource = "scale"
+	return f.fake.InvokesWatch(action)
+}
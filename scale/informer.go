@@ -0,0 +1,77 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scale
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	autoscaling "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// NewScaleInformer constructs a new informer for the scales of the objects
+// of resource. The ScaleInterfaces of client must implement
+// ListWatchInterface, otherwise the informer fails to list.
+func NewScaleInformer(client ScalesGetter, resource schema.GroupResource, namespace string, resyncPeriod time.Duration, indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredScaleInformer(client, resource, namespace, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredScaleInformer constructs a new informer for the scales of the
+// objects of resource. The list options are those of the objects.
+func NewFilteredScaleInformer(client ScalesGetter, resource schema.GroupResource, namespace string, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions func(*metav1.ListOptions)) cache.SharedIndexInformer {
+	listWatch := func() (ListWatchInterface, error) {
+		scales := client.Scales(namespace)
+		lw, ok := scales.(ListWatchInterface)
+		if !ok {
+			return nil, fmt.Errorf("%T can not list and watch the scales of %s", scales, resource.String())
+		}
+		return lw, nil
+	}
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				lw, err := listWatch()
+				if err != nil {
+					return nil, err
+				}
+				return lw.List(context.TODO(), resource, options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				lw, err := listWatch()
+				if err != nil {
+					return nil, err
+				}
+				return lw.Watch(context.TODO(), resource, options)
+			},
+		},
+		&autoscaling.Scale{},
+		resyncPeriod,
+		indexers,
+	)
+}
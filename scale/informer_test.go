@@ -0,0 +1,79 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scale_test
+
+import (
+	"testing"
+	"time"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/scale"
+	fakescale "k8s.io/client-go/scale/fake"
+	coretesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestScaleInformer(t *testing.T) {
+	newScale := func(name string, replicas int32) *autoscalingv1.Scale {
+		return &autoscalingv1.Scale{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", ResourceVersion: "1"},
+			Spec:       autoscalingv1.ScaleSpec{Replicas: replicas},
+		}
+	}
+
+	client := &fakescale.FakeScaleClient{}
+	client.AddReactor("list", "deployments", func(action coretesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "scale" {
+			t.Errorf("expected a list of scales, got %v", action)
+		}
+		return true, &metav1.List{
+			ListMeta: metav1.ListMeta{ResourceVersion: "1"},
+			Items:    []runtime.RawExtension{{Object: newScale("a", 1)}},
+		}, nil
+	})
+	watcher := watch.NewFake()
+	client.AddWatchReactor("deployments", coretesting.DefaultWatchReactor(watcher, nil))
+
+	deployments := schema.GroupResource{Group: "apps", Resource: "deployments"}
+	informer := scale.NewScaleInformer(client, deployments, "default", 0, cache.Indexers{})
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go informer.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, informer.HasSynced) {
+		t.Fatal("informer did not sync")
+	}
+
+	watcher.Add(newScale("b", 2))
+	watcher.Modify(newScale("a", 3))
+
+	err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		replicas := map[string]int32{}
+		for _, obj := range informer.GetStore().List() {
+			scale := obj.(*autoscalingv1.Scale)
+			replicas[scale.Name] = scale.Spec.Replicas
+		}
+		return len(replicas) == 2 && replicas["a"] == 3 && replicas["b"] == 2, nil
+	})
+	if err != nil {
+		t.Errorf("informer did not observe the scales: %v", err)
+	}
+}
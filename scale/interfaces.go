@@ -23,6 +23,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
 )
 
 // ScalesGetter can produce a ScaleInterface
@@ -45,3 +46,19 @@ type ScaleInterface interface {
 	// Patch patches the scale of the given scalable resource.
 	Patch(ctx context.Context, gvr schema.GroupVersionResource, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions) (*autoscalingapi.Scale, error)
 }
+
+// ListWatchInterface can list and watch the scales of the objects of a
+// resource, in a particular namespace. The ScaleInterfaces of the clients
+// returned by New implement it. The apiserver does not list or watch scale
+// subresources, so the objects are listed and watched instead, in their
+// metadata-only form, and their scales are fetched when they change. The
+// resource must support listing and watching.
+type ListWatchInterface interface {
+	// List lists the scales of the objects of the given scalable resource.
+	// The items of the list are *autoscalingapi.Scale objects.
+	List(ctx context.Context, resource schema.GroupResource, opts metav1.ListOptions) (*metav1.List, error)
+
+	// Watch watches the scales of the objects of the given scalable
+	// resource.
+	Watch(ctx context.Context, resource schema.GroupResource, opts metav1.ListOptions) (watch.Interface, error)
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scale
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	autoscaling "k8s.io/api/autoscaling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+const (
+	metadataListAccept = "application/json;as=PartialObjectMetadataList;g=meta.k8s.io;v=v1,application/json"
+	metadataAccept     = "application/json;as=PartialObjectMetadata;g=meta.k8s.io;v=v1,application/json"
+)
+
+var _ ListWatchInterface = &namespacedScaleClient{}
+
+// List lists the objects of resource in the metadata-only form, and gets
+// their scales. The scales have the resource versions of their objects.
+func (c *namespacedScaleClient) List(ctx context.Context, resource schema.GroupResource, opts metav1.ListOptions) (*metav1.List, error) {
+	path, gvr, err := c.client.pathAndVersionFor(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := c.client.clientBase.Get().
+		AbsPath(path).
+		NamespaceIfScoped(c.namespace, c.namespace != "").
+		Resource(gvr.Resource).
+		SetHeader("Accept", metadataListAccept).
+		SpecificallyVersionedParams(&opts, dynamicParameterCodec, versionV1).
+		DoRaw(ctx)
+	if err != nil {
+		c.client.forgetResourceOnNotFound(resource, err)
+		return nil, err
+	}
+	objects := &metav1.PartialObjectMetadataList{}
+	if err := json.Unmarshal(data, objects); err != nil {
+		return nil, err
+	}
+
+	list := &metav1.List{ListMeta: objects.ListMeta}
+	for i := range objects.Items {
+		object := &objects.Items[i]
+		scale, err := c.Get(ctx, resource, object.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			// deleted since it was listed
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		scale.ResourceVersion = object.ResourceVersion
+		list.Items = append(list.Items, runtime.RawExtension{Object: scale})
+	}
+	return list, nil
+}
+
+// Watch watches the objects of resource in the metadata-only form, and gets
+// the scales of the added and modified ones. The scales have the resource
+// versions of the events. Deleted events and bookmarks only hold the
+// metadata of their scales.
+func (c *namespacedScaleClient) Watch(ctx context.Context, resource schema.GroupResource, opts metav1.ListOptions) (watch.Interface, error) {
+	path, gvr, err := c.client.pathAndVersionFor(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Watch = true
+	ctx, cancel := context.WithCancel(ctx)
+	body, err := c.client.clientBase.Get().
+		AbsPath(path).
+		NamespaceIfScoped(c.namespace, c.namespace != "").
+		Resource(gvr.Resource).
+		SetHeader("Accept", metadataAccept).
+		SpecificallyVersionedParams(&opts, dynamicParameterCodec, versionV1).
+		Stream(ctx)
+	if err != nil {
+		cancel()
+		c.client.forgetResourceOnNotFound(resource, err)
+		return nil, err
+	}
+	decoder := &scaleWatchDecoder{
+		ctx:      ctx,
+		cancel:   cancel,
+		body:     body,
+		decoder:  json.NewDecoder(body),
+		client:   c,
+		resource: resource,
+	}
+	return watch.NewStreamWatcher(decoder, apierrors.NewClientErrorReporter(http.StatusInternalServerError, "GET", "ClientWatchDecoding")), nil
+}
+
+// scaleWatchDecoder decodes the metadata-only watch events of objects into
+// events of their scales.
+type scaleWatchDecoder struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	body     io.ReadCloser
+	decoder  *json.Decoder
+	client   *namespacedScaleClient
+	resource schema.GroupResource
+}
+
+var _ watch.Decoder = &scaleWatchDecoder{}
+
+func (d *scaleWatchDecoder) Decode() (watch.EventType, runtime.Object, error) {
+	for {
+		var event metav1.WatchEvent
+		if err := d.decoder.Decode(&event); err != nil {
+			return "", nil, err
+		}
+		eventType := watch.EventType(event.Type)
+		if eventType == watch.Error {
+			status := &metav1.Status{}
+			if err := json.Unmarshal(event.Object.Raw, status); err != nil {
+				return "", nil, err
+			}
+			return eventType, status, nil
+		}
+
+		object := &metav1.PartialObjectMetadata{}
+		if err := json.Unmarshal(event.Object.Raw, object); err != nil {
+			return "", nil, err
+		}
+		if eventType == watch.Deleted || eventType == watch.Bookmark {
+			return eventType, &autoscaling.Scale{ObjectMeta: object.ObjectMeta}, nil
+		}
+
+		scale, err := d.client.Get(d.ctx, d.resource, object.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			// deleted since the event, the deletion is the next event
+			continue
+		}
+		if err != nil {
+			status, ok := err.(apierrors.APIStatus)
+			if !ok {
+				status = apierrors.NewInternalError(err)
+			}
+			s := status.Status()
+			return watch.Error, &s, nil
+		}
+		scale.ResourceVersion = object.ResourceVersion
+		return eventType, scale, nil
+	}
+}
+
+func (d *scaleWatchDecoder) Close() {
+	d.cancel()
+	d.body.Close()
+}
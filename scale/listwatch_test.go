@@ -0,0 +1,251 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scale
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	fakerest "k8s.io/client-go/rest/fake"
+)
+
+type countingMapper struct {
+	resources map[schema.GroupResource]schema.GroupVersionResource
+	calls     int
+}
+
+func (m *countingMapper) ResourceFor(resource schema.GroupVersionResource) (schema.GroupVersionResource, error) {
+	m.calls++
+	gvr, ok := m.resources[resource.GroupResource()]
+	if !ok {
+		return schema.GroupVersionResource{}, fmt.Errorf("no resource %s", resource.String())
+	}
+	return gvr, nil
+}
+
+var replicationControllers = schema.GroupResource{Resource: "replicationcontrollers"}
+
+// listWatchScaleClient returns a client of an apiserver serving the
+// metadata of the replication controllers a and b, the scales of a and
+// c, and a watch of the given events.
+func listWatchScaleClient(t *testing.T, events []metav1.WatchEvent) (*scaleClient, *countingMapper) {
+	const path = "/api/v1/namespaces/default/replicationcontrollers"
+	metadata := func(name, resourceVersion string) metav1.PartialObjectMetadata {
+		return metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "meta.k8s.io/v1", Kind: "PartialObjectMetadata"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", ResourceVersion: resourceVersion},
+		}
+	}
+	scales := map[string]*autoscalingv1.Scale{}
+	for _, name := range []string{"a", "c"} {
+		scales[path+"/"+name+"/scale"] = &autoscalingv1.Scale{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "autoscaling/v1", Kind: "Scale"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", ResourceVersion: "scale"},
+			Spec:       autoscalingv1.ScaleSpec{Replicas: 3},
+		}
+	}
+
+	handler := func(req *http.Request) (*http.Response, error) {
+		if req.Method != "GET" {
+			return nil, fmt.Errorf("unexpected request for URL %q with method %q", req.URL.String(), req.Method)
+		}
+		if scale, ok := scales[req.URL.Path]; ok {
+			body, err := json.Marshal(scale)
+			if err != nil {
+				return nil, err
+			}
+			return &http.Response{StatusCode: http.StatusOK, Header: defaultHeaders(), Body: bytesBody(body)}, nil
+		}
+		if req.URL.Path != path {
+			name := strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, path+"/"), "/scale")
+			status := apierrors.NewNotFound(corev1.Resource("replicationcontrollers"), name).Status()
+			body, err := json.Marshal(&status)
+			if err != nil {
+				return nil, err
+			}
+			return &http.Response{StatusCode: http.StatusNotFound, Header: defaultHeaders(), Body: bytesBody(body)}, nil
+		}
+		if accept := req.Header.Get("Accept"); !strings.Contains(accept, "as=PartialObjectMetadata") {
+			return nil, fmt.Errorf("unexpected Accept header %q", accept)
+		}
+
+		var body []byte
+		if req.URL.Query().Get("watch") == "true" {
+			for _, event := range events {
+				data, err := json.Marshal(&event)
+				if err != nil {
+					return nil, err
+				}
+				body = append(body, data...)
+			}
+		} else {
+			list := &metav1.PartialObjectMetadataList{
+				TypeMeta: metav1.TypeMeta{APIVersion: "meta.k8s.io/v1", Kind: "PartialObjectMetadataList"},
+				ListMeta: metav1.ListMeta{ResourceVersion: "10"},
+				Items:    []metav1.PartialObjectMetadata{metadata("a", "5"), metadata("b", "6")},
+			}
+			var err error
+			if body, err = json.Marshal(list); err != nil {
+				return nil, err
+			}
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: defaultHeaders(), Body: bytesBody(body)}, nil
+	}
+
+	mapper := &countingMapper{resources: map[schema.GroupResource]schema.GroupVersionResource{
+		replicationControllers: corev1.SchemeGroupVersion.WithResource("replicationcontrollers"),
+	}}
+	fakeClient := &fakerest.RESTClient{
+		Client:               fakerest.CreateHTTPClient(handler),
+		NegotiatedSerializer: codecs.WithoutConversion(),
+		GroupVersion:         schema.GroupVersion{},
+		VersionedAPIPath:     "/not/a/real/path",
+	}
+	client := New(fakeClient, mapper, dynamic.LegacyAPIPathResolverFunc, fixedScaleKindResolver{}).(*scaleClient)
+	return client, mapper
+}
+
+type fixedScaleKindResolver struct{}
+
+func (fixedScaleKindResolver) ScaleForResource(resource schema.GroupVersionResource) (schema.GroupVersionKind, error) {
+	return autoscalingv1.SchemeGroupVersion.WithKind("Scale"), nil
+}
+
+func rawEvent(t *testing.T, eventType watch.EventType, obj interface{}) metav1.WatchEvent {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return metav1.WatchEvent{Type: string(eventType), Object: runtime.RawExtension{Raw: data}}
+}
+
+func TestResourceResolutionCache(t *testing.T) {
+	client, mapper := listWatchScaleClient(t, nil)
+	scales := client.Scales("default")
+
+	for i := 0; i < 3; i++ {
+		if _, err := scales.Get(context.TODO(), replicationControllers, "a", metav1.GetOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if mapper.calls != 1 {
+		t.Errorf("expected the mapper to be called once, got %d calls", mapper.calls)
+	}
+
+	if _, err := scales.Get(context.TODO(), replicationControllers, "b", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected a NotFound error, got %v", err)
+	}
+	if _, err := scales.Get(context.TODO(), replicationControllers, "a", metav1.GetOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mapper.calls != 2 {
+		t.Errorf("expected the mapper to be called again after a NotFound error, got %d calls", mapper.calls)
+	}
+}
+
+func TestListScales(t *testing.T) {
+	client, _ := listWatchScaleClient(t, nil)
+
+	list, err := client.Scales("default").(ListWatchInterface).List(context.TODO(), replicationControllers, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if list.ResourceVersion != "10" {
+		t.Errorf("expected the resource version of the list, got %q", list.ResourceVersion)
+	}
+	// b has no scale, as if it was deleted after the list
+	if len(list.Items) != 1 {
+		t.Fatalf("expected 1 scale, got %d", len(list.Items))
+	}
+	scale, ok := list.Items[0].Object.(*autoscalingv1.Scale)
+	if !ok {
+		t.Fatalf("expected a scale, got %T", list.Items[0].Object)
+	}
+	if scale.Name != "a" || scale.ResourceVersion != "5" || scale.Spec.Replicas != 3 {
+		t.Errorf("unexpected scale %#v", scale)
+	}
+}
+
+func TestWatchScales(t *testing.T) {
+	metadata := func(name, resourceVersion string) *metav1.PartialObjectMetadata {
+		return &metav1.PartialObjectMetadata{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", ResourceVersion: resourceVersion},
+		}
+	}
+	forbidden := apierrors.NewForbidden(corev1.Resource("replicationcontrollers"), "", fmt.Errorf("nope")).Status()
+	client, _ := listWatchScaleClient(t, []metav1.WatchEvent{
+		rawEvent(t, watch.Added, metadata("a", "11")),
+		rawEvent(t, watch.Modified, metadata("b", "12")),
+		rawEvent(t, watch.Modified, metadata("c", "13")),
+		rawEvent(t, watch.Deleted, metadata("a", "14")),
+		rawEvent(t, watch.Bookmark, metadata("", "15")),
+		rawEvent(t, watch.Error, &forbidden),
+	})
+
+	w, err := client.Scales("default").(ListWatchInterface).Watch(context.TODO(), replicationControllers, metav1.ListOptions{ResourceVersion: "10"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer w.Stop()
+
+	type event struct {
+		eventType       watch.EventType
+		name            string
+		resourceVersion string
+		replicas        int32
+	}
+	expected := []event{
+		{watch.Added, "a", "11", 3},
+		// b has no scale, as if it was deleted after the event
+		{watch.Modified, "c", "13", 3},
+		{watch.Deleted, "a", "14", 0},
+		{watch.Bookmark, "", "15", 0},
+	}
+	for _, e := range expected {
+		got, ok := <-w.ResultChan()
+		if !ok {
+			t.Fatalf("watch closed, expected %v", e)
+		}
+		scale, ok := got.Object.(*autoscalingv1.Scale)
+		if !ok {
+			t.Fatalf("expected a scale, got %T", got.Object)
+		}
+		actual := event{got.Type, scale.Name, scale.ResourceVersion, scale.Spec.Replicas}
+		if actual != e {
+			t.Errorf("expected event %v, got %v", e, actual)
+		}
+	}
+	got := <-w.ResultChan()
+	if status, ok := got.Object.(*metav1.Status); got.Type != watch.Error || !ok || status.Reason != metav1.StatusReasonForbidden {
+		t.Errorf("expected a forbidden error event, got %#v", got)
+	}
+	if _, ok := <-w.ResultChan(); ok {
+		t.Errorf("expected the watch to be closed at the end of the stream")
+	}
+}
@@ -76,6 +76,7 @@ func NewRootListAction(resource schema.GroupVersionResource, kind schema.GroupVe
 	action.Kind = kind
 	labelSelector, fieldSelector, _ := ExtractFromListOptions(opts)
 	action.ListRestrictions = ListRestrictions{labelSelector, fieldSelector}
+	action.ListOptions = opts.(metav1.ListOptions)
 
 	return action
 }
@@ -88,6 +89,7 @@ func NewListAction(resource schema.GroupVersionResource, kind schema.GroupVersio
 	action.Namespace = namespace
 	labelSelector, fieldSelector, _ := ExtractFromListOptions(opts)
 	action.ListRestrictions = ListRestrictions{labelSelector, fieldSelector}
+	action.ListOptions = opts.(metav1.ListOptions)
 
 	return action
 }
@@ -201,6 +203,46 @@ func NewPatchSubresourceAction(resource schema.GroupVersionResource, namespace,
 	return action
 }
 
+// NewRootPatchActionWithOptions returns a patch action for a cluster-scoped
+// resource that carries the options of the request, like the field manager of
+// an apply patch.
+func NewRootPatchActionWithOptions(resource schema.GroupVersionResource, name string, pt types.PatchType, patch []byte, opts metav1.PatchOptions) PatchActionImpl {
+	action := NewRootPatchAction(resource, name, pt, patch)
+	action.PatchOptions = opts
+
+	return action
+}
+
+// NewPatchActionWithOptions returns a patch action for a namespaced resource
+// that carries the options of the request, like the field manager of an apply
+// patch.
+func NewPatchActionWithOptions(resource schema.GroupVersionResource, namespace string, name string, pt types.PatchType, patch []byte, opts metav1.PatchOptions) PatchActionImpl {
+	action := NewPatchAction(resource, namespace, name, pt, patch)
+	action.PatchOptions = opts
+
+	return action
+}
+
+// NewRootPatchSubresourceActionWithOptions returns a patch action for a
+// subresource of a cluster-scoped resource that carries the options of the
+// request.
+func NewRootPatchSubresourceActionWithOptions(resource schema.GroupVersionResource, name string, pt types.PatchType, patch []byte, opts metav1.PatchOptions, subresources ...string) PatchActionImpl {
+	action := NewRootPatchSubresourceAction(resource, name, pt, patch, subresources...)
+	action.PatchOptions = opts
+
+	return action
+}
+
+// NewPatchSubresourceActionWithOptions returns a patch action for a
+// subresource of a namespaced resource that carries the options of the
+// request.
+func NewPatchSubresourceActionWithOptions(resource schema.GroupVersionResource, namespace, name string, pt types.PatchType, patch []byte, opts metav1.PatchOptions, subresources ...string) PatchActionImpl {
+	action := NewPatchSubresourceAction(resource, namespace, name, pt, patch, subresources...)
+	action.PatchOptions = opts
+
+	return action
+}
+
 func NewRootUpdateSubresourceAction(resource schema.GroupVersionResource, subresource string, object runtime.Object) UpdateActionImpl {
 	action := UpdateActionImpl{}
 	action.Verb = "update"
@@ -297,7 +339,7 @@ func NewRootWatchAction(resource schema.GroupVersionResource, opts interface{})
 	action.Verb = "watch"
 	action.Resource = resource
 	labelSelector, fieldSelector, resourceVersion := ExtractFromListOptions(opts)
-	action.WatchRestrictions = WatchRestrictions{labelSelector, fieldSelector, resourceVersion}
+	action.WatchRestrictions = WatchRestrictions{labelSelector, fieldSelector, resourceVersion, opts.(metav1.ListOptions).AllowWatchBookmarks}
 
 	return action
 }
@@ -333,7 +375,7 @@ func NewWatchAction(resource schema.GroupVersionResource, namespace string, opts
 	action.Resource = resource
 	action.Namespace = namespace
 	labelSelector, fieldSelector, resourceVersion := ExtractFromListOptions(opts)
-	action.WatchRestrictions = WatchRestrictions{labelSelector, fieldSelector, resourceVersion}
+	action.WatchRestrictions = WatchRestrictions{labelSelector, fieldSelector, resourceVersion, opts.(metav1.ListOptions).AllowWatchBookmarks}
 
 	return action
 }
@@ -356,9 +398,10 @@ type ListRestrictions struct {
 	Fields fields.Selector
 }
 type WatchRestrictions struct {
-	Labels          labels.Selector
-	Fields          fields.Selector
-	ResourceVersion string
+	Labels              labels.Selector
+	Fields              fields.Selector
+	ResourceVersion     string
+	AllowWatchBookmarks bool
 }
 
 type Action interface {
@@ -388,6 +431,13 @@ type ListAction interface {
 	GetListRestrictions() ListRestrictions
 }
 
+// ListOptionsAction is implemented by list actions that carry the options of
+// their request, like its limit and continue token.
+type ListOptionsAction interface {
+	ListAction
+	GetListOptions() metav1.ListOptions
+}
+
 type CreateAction interface {
 	Action
 	GetObject() runtime.Object
@@ -416,6 +466,14 @@ type PatchAction interface {
 	GetPatch() []byte
 }
 
+// PatchOptionsAction is implemented by patch actions that carry the options
+// of their request, like the field manager of an apply patch. The actions of
+// generated fake clients carry empty options.
+type PatchOptionsAction interface {
+	PatchAction
+	GetPatchOptions() metav1.PatchOptions
+}
+
 type WatchAction interface {
 	Action
 	GetWatchRestrictions() WatchRestrictions
@@ -506,6 +564,7 @@ type ListActionImpl struct {
 	Kind             schema.GroupVersionKind
 	Name             string
 	ListRestrictions ListRestrictions
+	ListOptions      metav1.ListOptions
 }
 
 func (a ListActionImpl) GetKind() schema.GroupVersionKind {
@@ -516,6 +575,10 @@ func (a ListActionImpl) GetListRestrictions() ListRestrictions {
 	return a.ListRestrictions
 }
 
+func (a ListActionImpl) GetListOptions() metav1.ListOptions {
+	return a.ListOptions
+}
+
 func (a ListActionImpl) DeepCopy() Action {
 	return ListActionImpl{
 		ActionImpl: a.ActionImpl.DeepCopy().(ActionImpl),
@@ -525,6 +588,7 @@ func (a ListActionImpl) DeepCopy() Action {
 			Labels: a.ListRestrictions.Labels.DeepCopySelector(),
 			Fields: a.ListRestrictions.Fields.DeepCopySelector(),
 		},
+		ListOptions: *a.ListOptions.DeepCopy(),
 	}
 }
 
@@ -564,9 +628,10 @@ func (a UpdateActionImpl) DeepCopy() Action {
 
 type PatchActionImpl struct {
 	ActionImpl
-	Name      string
-	PatchType types.PatchType
-	Patch     []byte
+	Name         string
+	PatchType    types.PatchType
+	Patch        []byte
+	PatchOptions metav1.PatchOptions
 }
 
 func (a PatchActionImpl) GetName() string {
@@ -581,14 +646,19 @@ func (a PatchActionImpl) GetPatchType() types.PatchType {
 	return a.PatchType
 }
 
+func (a PatchActionImpl) GetPatchOptions() metav1.PatchOptions {
+	return a.PatchOptions
+}
+
 func (a PatchActionImpl) DeepCopy() Action {
 	patch := make([]byte, len(a.Patch))
 	copy(patch, a.Patch)
 	return PatchActionImpl{
-		ActionImpl: a.ActionImpl.DeepCopy().(ActionImpl),
-		Name:       a.Name,
-		PatchType:  a.PatchType,
-		Patch:      patch,
+		ActionImpl:   a.ActionImpl.DeepCopy().(ActionImpl),
+		Name:         a.Name,
+		PatchType:    a.PatchType,
+		Patch:        patch,
+		PatchOptions: *a.PatchOptions.DeepCopy(),
 	}
 }
 
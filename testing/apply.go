@@ -0,0 +1,328 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"bytes"
+	encodingjson "encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/json"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
+	"sigs.k8s.io/structured-merge-diff/v4/merge"
+	"sigs.k8s.io/structured-merge-diff/v4/typed"
+	"sigs.k8s.io/yaml"
+)
+
+// NewObjectTrackerWithParser returns an ObjectTracker like NewObjectTracker,
+// except that apply patches to the types described by parser are merged
+// following their schema, like the apiserver does: for example, the items
+// of list maps are owned separately. Other types are merged with a schema
+// deduced from the objects, where all lists are atomic.
+func NewObjectTrackerWithParser(scheme ObjectScheme, decoder runtime.Decoder, parser *typed.Parser) ObjectTracker {
+	t := NewObjectTracker(scheme, decoder).(*tracker)
+	t.parser = parser
+	return t
+}
+
+// identityConverter is the merge.Converter used to apply patches. The
+// tracker stores a single version of each object, so no conversion is done.
+type identityConverter struct{}
+
+func (identityConverter) Convert(object *typed.TypedValue, version fieldpath.APIVersion) (*typed.TypedValue, error) {
+	return object, nil
+}
+
+func (identityConverter) IsMissingVersionError(error) bool {
+	return false
+}
+
+// applyPatch merges the apply patch of action into the object it targets,
+// tracking the fields owned by each field manager in the managed fields of
+// the object. The object is created if it does not exist. Like the
+// apiserver, a Conflict error is returned if the patch changes fields owned
+// by another manager, unless the patch is forced. The field manager comes
+// from the options of the action, which the dynamic and metadata fake
+// clients set; the generated typed fake clients do not pass them yet, so
+// their apply requests are rejected.
+func applyPatch(objectTracker ObjectTracker, action PatchActionImpl) (runtime.Object, error) {
+	gvr := action.GetResource()
+	ns := action.GetNamespace()
+	opts := action.GetPatchOptions()
+	if opts.FieldManager == "" {
+		return nil, errors.NewBadRequest("PatchOptions.fieldManager is required for apply requests")
+	}
+
+	patchJSON, err := yaml.YAMLToJSON(action.GetPatch())
+	if err != nil {
+		return nil, errors.NewBadRequest(fmt.Sprintf("error decoding YAML: %v", err))
+	}
+	patch := &unstructured.Unstructured{}
+	if err := json.Unmarshal(patchJSON, &patch.Object); err != nil {
+		return nil, errors.NewBadRequest(fmt.Sprintf("error decoding YAML: %v", err))
+	}
+	if patch.GetName() != action.GetName() {
+		return nil, errors.NewBadRequest(fmt.Sprintf("the name of the object (%s) does not match the name on the URL (%s)", patch.GetName(), action.GetName()))
+	}
+	if ns != "" && patch.GetNamespace() == "" {
+		patch.SetNamespace(ns)
+	}
+	patch.SetManagedFields(nil)
+
+	t, _ := objectTracker.(*tracker)
+	live, err := objectTracker.Get(gvr, ns, action.GetName())
+	exists := err == nil
+	if errors.IsNotFound(err) {
+		live, err = newApplyObject(t, patch.GroupVersionKind())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	liveContent, err := toUnstructuredContent(live)
+	if err != nil {
+		return nil, err
+	}
+	liveObj := &unstructured.Unstructured{Object: liveContent}
+	managed, err := decodeManagedFields(liveObj.GetManagedFields())
+	if err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+	liveObj.SetManagedFields(nil)
+
+	pt := applyParseableType(t, live)
+	liveTyped, err := pt.FromUnstructured(liveObj.Object)
+	if err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+	patchTyped, err := pt.FromUnstructured(patch.Object)
+	if err != nil {
+		return nil, errors.NewBadRequest(err.Error())
+	}
+
+	manager, err := managerIdentifier(metav1.ManagedFieldsEntry{
+		Manager:     opts.FieldManager,
+		Operation:   metav1.ManagedFieldsOperationApply,
+		Subresource: action.GetSubresource(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	force := opts.Force != nil && *opts.Force
+	updater := merge.Updater{Converter: identityConverter{}}
+	version := fieldpath.APIVersion(gvr.GroupVersion().String())
+	newTyped, managed, err := updater.Apply(liveTyped, patchTyped, version, managed, manager, force)
+	if err != nil {
+		if conflicts, ok := err.(merge.Conflicts); ok {
+			return nil, newApplyConflictError(conflicts)
+		}
+		return nil, errors.NewBadRequest(err.Error())
+	}
+	if newTyped == nil {
+		newTyped = liveTyped
+	}
+
+	newObj := &unstructured.Unstructured{}
+	if content, ok := newTyped.AsValue().Unstructured().(map[string]interface{}); ok {
+		newObj.Object = content
+	}
+	managedFields, err := encodeManagedFields(managed, liveObj.GetManagedFields(), manager)
+	if err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+	newObj.SetManagedFields(managedFields)
+
+	obj := live
+	if _, ok := obj.(runtime.Unstructured); ok {
+		obj = newObj
+	} else {
+		obj = reflect.New(reflect.TypeOf(live).Elem()).Interface().(runtime.Object)
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(newObj.Object, obj); err != nil {
+			return nil, errors.NewBadRequest(err.Error())
+		}
+	}
+
+	if exists {
+		err = objectTracker.Update(gvr, obj, ns)
+	} else if obj, err = prepareForCreate(objectTracker, gvr, ns, obj); err == nil {
+		err = objectTracker.Create(gvr, obj, ns)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return objectTracker.Get(gvr, ns, action.GetName())
+}
+
+// newApplyObject returns an empty object of kind gvk, to apply a patch
+// creating an object to. It is unstructured unless the scheme of the tracker
+// knows the kind.
+func newApplyObject(t *tracker, gvk schema.GroupVersionKind) (runtime.Object, error) {
+	if t != nil {
+		if obj, err := t.scheme.New(gvk); err == nil {
+			return obj, nil
+		}
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	return obj, nil
+}
+
+func toUnstructuredContent(obj runtime.Object) (map[string]interface{}, error) {
+	if u, ok := obj.(runtime.Unstructured); ok {
+		return runtime.DeepCopyJSON(u.UnstructuredContent()), nil
+	}
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+}
+
+// applyParseableType returns the schema used to merge apply patches to obj.
+func applyParseableType(t *tracker, obj runtime.Object) typed.ParseableType {
+	if t == nil || t.parser == nil {
+		return typed.DeducedParseableType
+	}
+	if _, ok := obj.(runtime.Unstructured); ok {
+		return typed.DeducedParseableType
+	}
+	pt := t.parser.Type(schemaTypeName(reflect.TypeOf(obj).Elem()))
+	if !pt.IsValid() {
+		return typed.DeducedParseableType
+	}
+	return pt
+}
+
+// schemaTypeName returns the name of the OpenAPI schema of a Go type, which
+// is derived from its package path in reverse domain notation, for example
+// io.k8s.api.core.v1.Pod for k8s.io/api/core/v1.Pod.
+func schemaTypeName(t reflect.Type) string {
+	path := strings.Split(t.PkgPath(), "/")
+	domain := strings.Split(path[0], ".")
+	for i, j := 0, len(domain)-1; i < j; i, j = i+1, j-1 {
+		domain[i], domain[j] = domain[j], domain[i]
+	}
+	return strings.Join(append(append(domain, path[1:]...), t.Name()), ".")
+}
+
+// managerIdentifier returns the key of the manager of entry in
+// fieldpath.ManagedFields, which is the JSON representation of the entry
+// without its fields and time, like in the apiserver. The API version of
+// appliers is not part of their identity.
+func managerIdentifier(entry metav1.ManagedFieldsEntry) (string, error) {
+	entry.FieldsType = ""
+	entry.FieldsV1 = nil
+	entry.Time = nil
+	if entry.Operation == metav1.ManagedFieldsOperationApply {
+		entry.APIVersion = ""
+	}
+	manager, err := encodingjson.Marshal(entry)
+	if err != nil {
+		return "", fmt.Errorf("error encoding manager: %v", err)
+	}
+	return string(manager), nil
+}
+
+func decodeManagedFields(entries []metav1.ManagedFieldsEntry) (fieldpath.ManagedFields, error) {
+	managed := fieldpath.ManagedFields{}
+	for _, entry := range entries {
+		manager, err := managerIdentifier(entry)
+		if err != nil {
+			return nil, err
+		}
+		set := &fieldpath.Set{}
+		if entry.FieldsV1 != nil {
+			if err := set.FromJSON(bytes.NewReader(entry.FieldsV1.Raw)); err != nil {
+				return nil, fmt.Errorf("error decoding fields of manager %q: %v", entry.Manager, err)
+			}
+		}
+		managed[manager] = fieldpath.NewVersionedSet(set, fieldpath.APIVersion(entry.APIVersion), entry.Operation == metav1.ManagedFieldsOperationApply)
+	}
+	return managed, nil
+}
+
+// encodeManagedFields returns the managed fields entries of managed. Entries
+// keep the time of previous, except for the one of updated, which is set to
+// now. Managers owning no fields are dropped.
+func encodeManagedFields(managed fieldpath.ManagedFields, previous []metav1.ManagedFieldsEntry, updated string) ([]metav1.ManagedFieldsEntry, error) {
+	times := map[string]*metav1.Time{}
+	for _, entry := range previous {
+		manager, err := managerIdentifier(entry)
+		if err != nil {
+			return nil, err
+		}
+		times[manager] = entry.Time
+	}
+	now := metav1.Now()
+	times[updated] = &now
+
+	entries := []metav1.ManagedFieldsEntry{}
+	for manager, versionedSet := range managed {
+		if versionedSet.Set().Empty() {
+			continue
+		}
+		var entry metav1.ManagedFieldsEntry
+		if err := encodingjson.Unmarshal([]byte(manager), &entry); err != nil {
+			return nil, fmt.Errorf("error decoding manager %q: %v", manager, err)
+		}
+		fields, err := versionedSet.Set().ToJSON()
+		if err != nil {
+			return nil, fmt.Errorf("error encoding fields of manager %q: %v", entry.Manager, err)
+		}
+		entry.APIVersion = string(versionedSet.APIVersion())
+		entry.FieldsType = "FieldsV1"
+		entry.FieldsV1 = &metav1.FieldsV1{Raw: fields}
+		entry.Time = times[manager]
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].Time.Equal(entries[j].Time) {
+			return entries[i].Time.Before(entries[j].Time)
+		}
+		return entries[i].Manager < entries[j].Manager
+	})
+	return entries, nil
+}
+
+// newApplyConflictError returns the Conflict error the apiserver returns for
+// conflicts.
+func newApplyConflictError(conflicts merge.Conflicts) error {
+	causes := []metav1.StatusCause{}
+	messages := []string{}
+	for _, conflict := range conflicts {
+		var entry metav1.ManagedFieldsEntry
+		manager := conflict.Manager
+		if err := encodingjson.Unmarshal([]byte(conflict.Manager), &entry); err == nil {
+			manager = fmt.Sprintf("%q", entry.Manager)
+			if entry.Operation == metav1.ManagedFieldsOperationUpdate {
+				manager = fmt.Sprintf("%s using %s", manager, entry.APIVersion)
+			}
+		}
+		cause := metav1.StatusCause{
+			Type:    metav1.CauseTypeFieldManagerConflict,
+			Message: fmt.Sprintf("conflict with %s", manager),
+			Field:   conflict.Path.String(),
+		}
+		causes = append(causes, cause)
+		messages = append(messages, fmt.Sprintf("%s: %s", cause.Message, cause.Field))
+	}
+	return errors.NewApplyConflict(causes, fmt.Sprintf("Apply failed with %d conflict(s): %s", len(conflicts), strings.Join(messages, "\n")))
+}
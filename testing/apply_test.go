@@ -0,0 +1,161 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	serializer "k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/applyconfigurations"
+)
+
+var configMapsResource = corev1.SchemeGroupVersion.WithResource("configmaps")
+
+func newApplyTracker(t *testing.T, withParser bool) ObjectTracker {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	decoder := serializer.NewCodecFactory(scheme).UniversalDecoder()
+	if withParser {
+		return NewObjectTrackerWithParser(scheme, decoder, applyconfigurations.Parser())
+	}
+	return NewObjectTracker(scheme, decoder)
+}
+
+func apply(o ObjectTracker, resource, name, manager string, force bool, patch string) (runtime.Object, error) {
+	opts := metav1.PatchOptions{FieldManager: manager, Force: &force}
+	action := NewPatchActionWithOptions(corev1.SchemeGroupVersion.WithResource(resource), "default", name, types.ApplyPatchType, []byte(patch), opts)
+	_, obj, err := ObjectReaction(o)(action)
+	return obj, err
+}
+
+func managers(obj runtime.Object) map[string]metav1.ManagedFieldsOperationType {
+	result := map[string]metav1.ManagedFieldsOperationType{}
+	for _, entry := range obj.(metav1.Object).GetManagedFields() {
+		result[entry.Manager] = entry.Operation
+	}
+	return result
+}
+
+func TestApplyPatch(t *testing.T) {
+	o := newApplyTracker(t, false)
+
+	obj, err := apply(o, "configmaps", "cm", "a", false, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm"},"data":{"x":"1","y":"1"}}`)
+	if err != nil {
+		t.Fatalf("unexpected error creating object: %v", err)
+	}
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		t.Fatalf("expected a ConfigMap, got %T", obj)
+	}
+	if cm.Namespace != "default" || !reflect.DeepEqual(cm.Data, map[string]string{"x": "1", "y": "1"}) {
+		t.Errorf("unexpected object: %#v", cm)
+	}
+	if got, want := managers(cm), map[string]metav1.ManagedFieldsOperationType{"a": metav1.ManagedFieldsOperationApply}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected managers %v, got %v", want, got)
+	}
+
+	// Changing a field owned by another manager conflicts.
+	_, err = apply(o, "configmaps", "cm", "b", false, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm"},"data":{"x":"2","z":"2"}}`)
+	if !errors.IsConflict(err) {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+	causes := err.(errors.APIStatus).Status().Details.Causes
+	if len(causes) != 1 || causes[0].Type != metav1.CauseTypeFieldManagerConflict || causes[0].Field != ".data.x" {
+		t.Errorf("unexpected conflict causes: %#v", causes)
+	}
+
+	// Setting a field owned by another manager to the same value shares it.
+	if _, err := apply(o, "configmaps", "cm", "b", false, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm"},"data":{"y":"1","z":"2"}}`); err != nil {
+		t.Fatalf("unexpected error sharing a field: %v", err)
+	}
+
+	// Forcing takes ownership of conflicting fields.
+	if _, err := apply(o, "configmaps", "cm", "b", true, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm"},"data":{"x":"2","y":"1","z":"2"}}`); err != nil {
+		t.Fatalf("unexpected error forcing the apply: %v", err)
+	}
+
+	// Fields no longer applied by a manager are removed, unless another
+	// manager owns them.
+	obj, err = apply(o, "configmaps", "cm", "a", false, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm"}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := obj.(*corev1.ConfigMap).Data, map[string]string{"x": "2", "y": "1", "z": "2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected data %v, got %v", want, got)
+	}
+	obj, err = apply(o, "configmaps", "cm", "b", false, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm"},"data":{"z":"2"}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := obj.(*corev1.ConfigMap).Data, map[string]string{"z": "2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected data %v, got %v", want, got)
+	}
+	// Appliers keep owning the identity of the object, like its name.
+	if got, want := managers(obj), map[string]metav1.ManagedFieldsOperationType{"a": metav1.ManagedFieldsOperationApply, "b": metav1.ManagedFieldsOperationApply}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected managers %v, got %v", want, got)
+	}
+}
+
+func TestApplyPatchWithParser(t *testing.T) {
+	o := newApplyTracker(t, true)
+
+	if _, err := apply(o, "pods", "pod", "a", false, `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"pod"},"spec":{"containers":[{"name":"a","image":"a"}]}}`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Containers are a list map keyed by name, so each manager owns its own.
+	obj, err := apply(o, "pods", "pod", "b", false, `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"pod"},"spec":{"containers":[{"name":"b","image":"b"}]}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, container := range obj.(*corev1.Pod).Spec.Containers {
+		names = append(names, container.Name)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("expected containers %v, got %v", want, names)
+	}
+
+	// Without the schema, lists are atomic and the second apply conflicts.
+	o = newApplyTracker(t, false)
+	if _, err := apply(o, "pods", "pod", "a", false, `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"pod"},"spec":{"containers":[{"name":"a","image":"a"}]}}`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := apply(o, "pods", "pod", "b", false, `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"pod"},"spec":{"containers":[{"name":"b","image":"b"}]}}`); !errors.IsConflict(err) {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+}
+
+func TestApplyPatchInvalid(t *testing.T) {
+	o := newApplyTracker(t, false)
+	if _, err := apply(o, "configmaps", "cm", "", false, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm"}}`); !errors.IsBadRequest(err) {
+		t.Errorf("expected a BadRequest error without field manager, got %v", err)
+	}
+	if _, err := apply(o, "configmaps", "cm", "a", false, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"other"}}`); !errors.IsBadRequest(err) {
+		t.Errorf("expected a BadRequest error for a mismatched name, got %v", err)
+	}
+	if _, err := o.Get(configMapsResource, "default", "cm"); !errors.IsNotFound(err) {
+		t.Errorf("expected no object to be created, got %v", err)
+	}
+}
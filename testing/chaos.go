@@ -0,0 +1,252 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/utils/clock"
+)
+
+// chaosReactor is the name of the chaos in reaction traces.
+const chaosReactor = "chaos"
+
+// Chaos configures the faults injected into the calls of a fake client, to
+// test how a controller copes with an unreliable apiserver. The faults are
+// random but reproducible: the same seed and calls inject the same faults.
+type Chaos struct {
+	// Seed seeds the random source deciding which calls fail.
+	Seed int64
+
+	// Verbs limits the faults to the calls with these verbs, like "get" or
+	// "watch". All calls may fail if empty.
+	Verbs []string
+	// Resources limits the faults to the calls of these resources, like
+	// "pods" or "pods/status". All calls may fail if empty.
+	Resources []string
+
+	// TooManyRequests is the probability of a call to fail with a 429 Too
+	// Many Requests error.
+	TooManyRequests float64
+	// InternalError is the probability of a call to fail with a 500 Internal
+	// Server Error.
+	InternalError float64
+	// Timeout is the probability of a call to fail with a 504 Timeout error.
+	Timeout float64
+	// WatchDisconnect is the probability of a watch to be disconnected after
+	// each of its events.
+	WatchDisconnect float64
+
+	// Delay delays each call, as timed by Clock.
+	Delay time.Duration
+	// Clock times the delays. Defaults to the real clock, tests can step a
+	// fake clock to let delayed calls proceed.
+	Clock clock.Clock
+}
+
+// chaos injects the faults configured by a Chaos.
+type chaos struct {
+	Chaos
+
+	lock    sync.Mutex
+	rand    *rand.Rand
+	watches map[*chaosWatch]struct{}
+}
+
+// SetChaos injects faults into the calls of the fake client, or stops
+// injecting them if c is nil. Faulty calls are recorded as actions, and
+// their reaction traces show a step named "chaos". For example:
+//
+//	client := fake.NewSimpleClientset()
+//	client.SetChaos(&testing.Chaos{Seed: 1, Verbs: []string{"update"}, TooManyRequests: 0.3})
+func (c *Fake) SetChaos(config *Chaos) {
+	var ch *chaos
+	if config != nil {
+		ch = &chaos{
+			Chaos:   *config,
+			rand:    rand.New(rand.NewSource(config.Seed)),
+			watches: map[*chaosWatch]struct{}{},
+		}
+		if ch.Clock == nil {
+			ch.Clock = clock.RealClock{}
+		}
+	}
+	c.Lock()
+	previous := c.chaos
+	c.chaos = ch
+	c.Unlock()
+	if previous != nil {
+		previous.disconnectWatches()
+	}
+}
+
+// DisconnectWatches disconnects the watches opened while chaos was injected,
+// closing their result channels as if the connection to the apiserver was
+// lost.
+func (c *Fake) DisconnectWatches() {
+	c.RLock()
+	ch := c.chaos
+	c.RUnlock()
+	if ch != nil {
+		ch.disconnectWatches()
+	}
+}
+
+// getChaos returns the chaos injected into action, if any, after waiting
+// for its delay. It must be called without the lock held.
+func (c *Fake) getChaos(action Action) *chaos {
+	c.RLock()
+	ch := c.chaos
+	c.RUnlock()
+	if ch == nil || !ch.matches(action) {
+		return nil
+	}
+	if ch.Delay > 0 {
+		<-ch.Clock.After(ch.Delay)
+	}
+	return ch
+}
+
+func (ch *chaos) matches(action Action) bool {
+	if len(ch.Verbs) > 0 && !containsFold(ch.Verbs, action.GetVerb()) {
+		return false
+	}
+	if len(ch.Resources) > 0 {
+		resource := action.GetResource().Resource
+		if action.GetSubresource() != "" {
+			resource += "/" + action.GetSubresource()
+		}
+		if !containsFold(ch.Resources, resource) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// fault returns the error injected into a call, if any.
+func (ch *chaos) fault() error {
+	ch.lock.Lock()
+	p := ch.rand.Float64()
+	ch.lock.Unlock()
+	switch {
+	case p < ch.TooManyRequests:
+		return apierrors.NewTooManyRequests("injected fault: too many requests", 1)
+	case p < ch.TooManyRequests+ch.InternalError:
+		return apierrors.NewInternalError(errors.New("injected fault"))
+	case p < ch.TooManyRequests+ch.InternalError+ch.Timeout:
+		return apierrors.NewTimeoutError("injected fault: request timed out", 1)
+	}
+	return nil
+}
+
+// disconnects returns whether to disconnect a watch after an event.
+func (ch *chaos) disconnects() bool {
+	if ch.WatchDisconnect <= 0 {
+		return false
+	}
+	ch.lock.Lock()
+	defer ch.lock.Unlock()
+	return ch.rand.Float64() < ch.WatchDisconnect
+}
+
+func (ch *chaos) disconnectWatches() {
+	ch.lock.Lock()
+	watches := make([]*chaosWatch, 0, len(ch.watches))
+	for w := range ch.watches {
+		watches = append(watches, w)
+	}
+	ch.lock.Unlock()
+	for _, w := range watches {
+		w.Stop()
+	}
+}
+
+// chaosWatch forwards the events of a watch until it is disconnected.
+type chaosWatch struct {
+	delegate watch.Interface
+	chaos    *chaos
+	result   chan watch.Event
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func newChaosWatch(delegate watch.Interface, ch *chaos) *chaosWatch {
+	w := &chaosWatch{
+		delegate: delegate,
+		chaos:    ch,
+		result:   make(chan watch.Event),
+		done:     make(chan struct{}),
+	}
+	ch.lock.Lock()
+	ch.watches[w] = struct{}{}
+	ch.lock.Unlock()
+	go w.run()
+	return w
+}
+
+func (w *chaosWatch) run() {
+	defer func() {
+		w.delegate.Stop()
+		w.chaos.lock.Lock()
+		delete(w.chaos.watches, w)
+		w.chaos.lock.Unlock()
+		close(w.result)
+	}()
+	for {
+		select {
+		case e, ok := <-w.delegate.ResultChan():
+			if !ok {
+				return
+			}
+			select {
+			case w.result <- e:
+			case <-w.done:
+				return
+			}
+			if w.chaos.disconnects() {
+				return
+			}
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// Stop stops the watch.
+func (w *chaosWatch) Stop() {
+	w.stopOnce.Do(func() { close(w.done) })
+}
+
+// ResultChan returns the events of the watch.
+func (w *chaosWatch) ResultChan() <-chan watch.Event {
+	return w.result
+}
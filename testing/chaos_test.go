@@ -0,0 +1,139 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func newChaosFake(t *testing.T) *Fake {
+	o := newPodTracker(t, newPod("a", "node-1"))
+	f := &Fake{}
+	f.AddReactor("*", "*", ObjectReaction(o))
+	f.AddWatchReactor("*", func(action Action) (bool, watch.Interface, error) {
+		w, err := o.Watch(action.GetResource(), action.GetNamespace())
+		return true, w, err
+	})
+	return f
+}
+
+func TestChaosFaults(t *testing.T) {
+	faults := func() []bool {
+		f := newChaosFake(t)
+		f.SetChaos(&Chaos{Seed: 7, Verbs: []string{"get"}, TooManyRequests: 0.5})
+		var faults []bool
+		for i := 0; i < 100; i++ {
+			_, err := f.Invokes(NewGetAction(podsResource, "default", "a"), nil)
+			if err != nil && !apierrors.IsTooManyRequests(err) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			faults = append(faults, err != nil)
+		}
+		if _, err := f.Invokes(NewListAction(podsResource, podsResource.GroupVersion().WithKind("Pod"), "default", metav1.ListOptions{}), nil); err != nil {
+			t.Errorf("expected lists not to fail, got %v", err)
+		}
+
+		traces := f.ReactionTraces()
+		for i, fault := range faults {
+			if fault && traces[i].Steps[0].Reactor != chaosReactor {
+				t.Errorf("expected the fault to be traced, got %v", traces[i])
+			}
+		}
+
+		f.SetChaos(nil)
+		if _, err := f.Invokes(NewGetAction(podsResource, "default", "a"), nil); err != nil {
+			t.Errorf("expected no fault without chaos, got %v", err)
+		}
+		return faults
+	}
+
+	first := faults()
+	count := 0
+	for _, fault := range first {
+		if fault {
+			count++
+		}
+	}
+	if count < 20 || count > 80 {
+		t.Errorf("expected about half of the gets to fail, got %d", count)
+	}
+	if !reflect.DeepEqual(first, faults()) {
+		t.Error("expected the same seed to inject the same faults")
+	}
+}
+
+func TestChaosDelay(t *testing.T) {
+	f := newChaosFake(t)
+	clock := testingclock.NewFakeClock(time.Now())
+	f.SetChaos(&Chaos{Delay: time.Second, Clock: clock})
+
+	done := make(chan error)
+	go func() {
+		_, err := f.Invokes(NewGetAction(podsResource, "default", "a"), nil)
+		done <- err
+	}()
+	if err := wait.PollImmediate(time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		return clock.HasWaiters(), nil
+	}); err != nil {
+		t.Fatal("expected the call to be delayed")
+	}
+	select {
+	case <-done:
+		t.Fatal("expected the call to wait for the clock")
+	default:
+	}
+	clock.Step(time.Second)
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestChaosWatchDisconnect(t *testing.T) {
+	f := newChaosFake(t)
+	f.SetChaos(&Chaos{WatchDisconnect: 1})
+	w, err := f.InvokesWatch(NewWatchAction(podsResource, "default", metav1.ListOptions{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Invokes(NewCreateAction(podsResource, "default", newPod("b", "node-1")), nil); err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := <-w.ResultChan(); !ok || e.Type != watch.Added {
+		t.Errorf("expected an added event, got %v", e)
+	}
+	if _, ok := <-w.ResultChan(); ok {
+		t.Error("expected the watch to be disconnected after its event")
+	}
+
+	f.SetChaos(&Chaos{})
+	w, err = f.InvokesWatch(NewWatchAction(podsResource, "default", metav1.ListOptions{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.DisconnectWatches()
+	if _, ok := <-w.ResultChan(); ok {
+		t.Error("expected the watch to be disconnected")
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+const (
+	// Like the apiserver, generated names are made of the generateName of
+	// the object, truncated if needed, and a random suffix, and are at most
+	// maxGeneratedNameLength long.
+	maxGeneratedNameLength = 63
+	generatedSuffixLength  = 5
+
+	// maxNameGenerationAttempts is the number of names tried before giving up
+	// on generating a name not taken yet.
+	maxNameGenerationAttempts = 8
+)
+
+var _ DefaultingTracker = &tracker{}
+
+// DefaultingTracker is an ObjectTracker that can run defaulting functions on
+// the objects created through ObjectReaction, like the apiserver does. The
+// trackers returned by NewObjectTracker implement it, for example:
+//
+//	client := fake.NewSimpleClientset(objects...)
+//	client.Tracker().(testing.DefaultingTracker).SetDefaulter(scheme)
+type DefaultingTracker interface {
+	ObjectTracker
+
+	// SetDefaulter sets the defaulter run on created objects, typically a
+	// runtime.Scheme with registered defaulting functions. Defaulting is off
+	// by default and when the defaulter is nil.
+	SetDefaulter(defaulter runtime.ObjectDefaulter)
+}
+
+func (t *tracker) SetDefaulter(defaulter runtime.ObjectDefaulter) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.defaulter = defaulter
+}
+
+func (t *tracker) defaultObject(obj runtime.Object) {
+	t.lock.RLock()
+	defaulter := t.defaulter
+	t.lock.RUnlock()
+	if defaulter != nil {
+		defaulter.Default(obj)
+	}
+}
+
+// prepareForCreate returns a copy of obj completed like the apiserver does
+// before creating it: defaulted if the tracker is set up for it, named from
+// its generateName if it has no name, with a UID and a creation timestamp,
+// and without status if the resource has a status subresource.
+func prepareForCreate(o ObjectTracker, gvr schema.GroupVersionResource, ns string, obj runtime.Object) (runtime.Object, error) {
+	obj, err := resetStatus(o, gvr, obj.DeepCopyObject())
+	if err != nil {
+		return nil, err
+	}
+	if t, ok := o.(*tracker); ok {
+		t.defaultObject(obj)
+	}
+
+	objMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+	if objMeta.GetName() == "" && objMeta.GetGenerateName() != "" {
+		name, err := generateName(o, gvr, ns, objMeta)
+		if err != nil {
+			return nil, err
+		}
+		objMeta.SetName(name)
+	}
+	if objMeta.GetUID() == "" {
+		objMeta.SetUID(uuid.NewUUID())
+	}
+	if creationTimestamp := objMeta.GetCreationTimestamp(); creationTimestamp.IsZero() {
+		objMeta.SetCreationTimestamp(metav1.Now())
+	}
+	return obj, nil
+}
+
+// generateName returns a name generated from the generateName of the object
+// that is not taken yet.
+func generateName(o ObjectTracker, gvr schema.GroupVersionResource, ns string, objMeta metav1.Object) (string, error) {
+	if objMeta.GetNamespace() != "" {
+		ns = objMeta.GetNamespace()
+	}
+	base := objMeta.GetGenerateName()
+	if len(base) > maxGeneratedNameLength-generatedSuffixLength {
+		base = base[:maxGeneratedNameLength-generatedSuffixLength]
+	}
+	for i := 0; i < maxNameGenerationAttempts; i++ {
+		name := base + utilrand.String(generatedSuffixLength)
+		_, err := o.Get(gvr, ns, name)
+		if errors.IsNotFound(err) {
+			return name, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", errors.NewServerTimeout(gvr.GroupResource(), "create", 1)
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	serializer "k8s.io/apimachinery/pkg/runtime/serializer"
+)
+
+func createPod(t *testing.T, o ObjectTracker, pod *corev1.Pod) *corev1.Pod {
+	_, obj, err := ObjectReaction(o)(NewCreateAction(podsResource, "default", pod))
+	if err != nil {
+		t.Fatalf("unexpected error creating pod: %v", err)
+	}
+	return obj.(*corev1.Pod)
+}
+
+func TestCreateGenerateName(t *testing.T) {
+	o := newPodTracker(t)
+
+	names := map[string]bool{}
+	for i := 0; i < 10; i++ {
+		pod := createPod(t, o, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{GenerateName: "pod-"}})
+		if !strings.HasPrefix(pod.Name, "pod-") || len(pod.Name) != len("pod-")+generatedSuffixLength {
+			t.Errorf("unexpected generated name %q", pod.Name)
+		}
+		if names[pod.Name] {
+			t.Errorf("name %q generated twice", pod.Name)
+		}
+		names[pod.Name] = true
+		if pod.UID == "" || pod.CreationTimestamp.IsZero() {
+			t.Errorf("expected a UID and a creation timestamp, got %q and %v", pod.UID, pod.CreationTimestamp)
+		}
+	}
+
+	pod := createPod(t, o, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "named", GenerateName: "pod-", UID: "uid"}})
+	if pod.Name != "named" || pod.UID != "uid" {
+		t.Errorf("expected the name and UID of the object to be kept, got %q and %q", pod.Name, pod.UID)
+	}
+
+	long := createPod(t, o, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{GenerateName: strings.Repeat("a", 100)}})
+	if len(long.Name) != maxGeneratedNameLength {
+		t.Errorf("expected a name of %d characters, got %q", maxGeneratedNameLength, long.Name)
+	}
+}
+
+func TestCreateDefaulting(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	scheme.AddTypeDefaultingFunc(&corev1.Pod{}, func(obj interface{}) {
+		pod := obj.(*corev1.Pod)
+		if pod.Spec.RestartPolicy == "" {
+			pod.Spec.RestartPolicy = corev1.RestartPolicyAlways
+		}
+	})
+	o := NewObjectTracker(scheme, serializer.NewCodecFactory(scheme).UniversalDecoder())
+
+	if pod := createPod(t, o, newPod("a", "")); pod.Spec.RestartPolicy != "" {
+		t.Errorf("expected no defaulting by default, got restart policy %q", pod.Spec.RestartPolicy)
+	}
+
+	o.(DefaultingTracker).SetDefaulter(scheme)
+	original := newPod("b", "")
+	if pod := createPod(t, o, original); pod.Spec.RestartPolicy != corev1.RestartPolicyAlways {
+		t.Errorf("expected the restart policy to be defaulted, got %q", pod.Spec.RestartPolicy)
+	}
+	if original.Spec.RestartPolicy != "" {
+		t.Errorf("expected the object of the action not to be modified")
+	}
+}
@@ -32,6 +32,8 @@ import (
 type Fake struct {
 	sync.RWMutex
 	actions []Action // these may be castable to other types, but "Action" is the minimum
+	// traces holds the reaction trace of each action.
+	traces []ReactionTrace
 
 	// ReactionChain is the list of reactors that will be attempted for every
 	// request in the order they are tried.
@@ -44,6 +46,9 @@ type Fake struct {
 	ProxyReactionChain []ProxyReactor
 
 	Resources []*metav1.APIResourceList
+
+	// chaos injects faults into the calls, see SetChaos.
+	chaos *chaos
 }
 
 // Reactor is an interface to allow the composition of reaction functions.
@@ -94,55 +99,68 @@ type WatchReactionFunc func(action Action) (handled bool, ret watch.Interface, e
 // ignore the results and continue to the next ProxyReactionFunc.
 type ProxyReactionFunc func(action Action) (handled bool, ret restclient.ResponseWrapper, err error)
 
-// AddReactor appends a reactor to the end of the chain.
+// AddReactor appends a reactor to the end of the chain, before the reactors
+// with a negative priority, see AddReactorWithOptions.
 func (c *Fake) AddReactor(verb, resource string, reaction ReactionFunc) {
-	c.ReactionChain = append(c.ReactionChain, &SimpleReactor{verb, resource, reaction})
+	c.insertReactor(&SimpleReactor{verb, resource, reaction}, 0, false)
 }
 
-// PrependReactor adds a reactor to the beginning of the chain.
+// PrependReactor adds a reactor to the beginning of the chain, after the
+// reactors with a positive priority, see PrependReactorWithOptions.
 func (c *Fake) PrependReactor(verb, resource string, reaction ReactionFunc) {
-	c.ReactionChain = append([]Reactor{&SimpleReactor{verb, resource, reaction}}, c.ReactionChain...)
+	c.insertReactor(&SimpleReactor{verb, resource, reaction}, 0, true)
 }
 
 // AddWatchReactor appends a reactor to the end of the chain.
 func (c *Fake) AddWatchReactor(resource string, reaction WatchReactionFunc) {
 	c.Lock()
 	defer c.Unlock()
-	c.WatchReactionChain = append(c.WatchReactionChain, &SimpleWatchReactor{resource, reaction})
+	c.insertWatchReactor(&SimpleWatchReactor{resource, reaction}, 0, false)
 }
 
 // PrependWatchReactor adds a reactor to the beginning of the chain.
 func (c *Fake) PrependWatchReactor(resource string, reaction WatchReactionFunc) {
 	c.Lock()
 	defer c.Unlock()
-	c.WatchReactionChain = append([]WatchReactor{&SimpleWatchReactor{resource, reaction}}, c.WatchReactionChain...)
+	c.insertWatchReactor(&SimpleWatchReactor{resource, reaction}, 0, true)
 }
 
 // AddProxyReactor appends a reactor to the end of the chain.
 func (c *Fake) AddProxyReactor(resource string, reaction ProxyReactionFunc) {
-	c.ProxyReactionChain = append(c.ProxyReactionChain, &SimpleProxyReactor{resource, reaction})
+	c.insertProxyReactor(&SimpleProxyReactor{resource, reaction}, 0, false)
 }
 
 // PrependProxyReactor adds a reactor to the beginning of the chain.
 func (c *Fake) PrependProxyReactor(resource string, reaction ProxyReactionFunc) {
-	c.ProxyReactionChain = append([]ProxyReactor{&SimpleProxyReactor{resource, reaction}}, c.ProxyReactionChain...)
+	c.insertProxyReactor(&SimpleProxyReactor{resource, reaction}, 0, true)
 }
 
 // Invokes records the provided Action and then invokes the ReactionFunc that
 // handles the action if one exists. defaultReturnObj is expected to be of the
 // same type a normal call would return.
 func (c *Fake) Invokes(action Action, defaultReturnObj runtime.Object) (runtime.Object, error) {
+	ch := c.getChaos(action)
+
 	c.Lock()
 	defer c.Unlock()
 
 	actionCopy := action.DeepCopy()
 	c.actions = append(c.actions, action.DeepCopy())
+	trace := c.startTrace(c.actions[len(c.actions)-1])
+	if ch != nil {
+		if err := ch.fault(); err != nil {
+			trace.Steps = append(trace.Steps, ReactionStep{Reactor: chaosReactor, Matched: true, Handled: true, Err: err})
+			return nil, err
+		}
+	}
 	for _, reactor := range c.ReactionChain {
 		if !reactor.Handles(actionCopy) {
+			trace.Steps = append(trace.Steps, ReactionStep{Reactor: reactorName(reactor)})
 			continue
 		}
 
 		handled, ret, err := reactor.React(actionCopy)
+		trace.Steps = append(trace.Steps, ReactionStep{Reactor: reactorName(reactor), Matched: true, Handled: handled, Err: err})
 		if !handled {
 			continue
 		}
@@ -154,23 +172,41 @@ func (c *Fake) Invokes(action Action, defaultReturnObj runtime.Object) (runtime.
 }
 
 // InvokesWatch records the provided Action and then invokes the ReactionFunc
-// that handles the action if one exists.
+// that handles the action if one exists. A watch of a tracker returned by
+// NewObjectTracker is restricted to the action like ObjectWatchReaction does.
 func (c *Fake) InvokesWatch(action Action) (watch.Interface, error) {
+	ch := c.getChaos(action)
+
 	c.Lock()
 	defer c.Unlock()
 
 	actionCopy := action.DeepCopy()
 	c.actions = append(c.actions, action.DeepCopy())
+	trace := c.startTrace(c.actions[len(c.actions)-1])
+	if ch != nil {
+		if err := ch.fault(); err != nil {
+			trace.Steps = append(trace.Steps, ReactionStep{Reactor: chaosReactor, Matched: true, Handled: true, Err: err})
+			return nil, err
+		}
+	}
 	for _, reactor := range c.WatchReactionChain {
 		if !reactor.Handles(actionCopy) {
+			trace.Steps = append(trace.Steps, ReactionStep{Reactor: reactorName(reactor)})
 			continue
 		}
 
 		handled, ret, err := reactor.React(actionCopy)
+		trace.Steps = append(trace.Steps, ReactionStep{Reactor: reactorName(reactor), Matched: true, Handled: handled, Err: err})
 		if !handled {
 			continue
 		}
 
+		if w, ok := ret.(*trackerWatch); ok && err == nil {
+			ret, err = w.restrict(actionCopy)
+		}
+		if ch != nil && err == nil && ret != nil {
+			ret = newChaosWatch(ret, ch)
+		}
 		return ret, err
 	}
 
@@ -185,12 +221,16 @@ func (c *Fake) InvokesProxy(action Action) restclient.ResponseWrapper {
 
 	actionCopy := action.DeepCopy()
 	c.actions = append(c.actions, action.DeepCopy())
+	trace := c.startTrace(c.actions[len(c.actions)-1])
 	for _, reactor := range c.ProxyReactionChain {
 		if !reactor.Handles(actionCopy) {
+			trace.Steps = append(trace.Steps, ReactionStep{Reactor: reactorName(reactor)})
 			continue
 		}
 
 		handled, ret, err := reactor.React(actionCopy)
+		// Proxy reactors returning an error do not end the chain.
+		trace.Steps = append(trace.Steps, ReactionStep{Reactor: reactorName(reactor), Matched: true, Handled: handled && err == nil, Err: err})
 		if !handled || err != nil {
 			continue
 		}
@@ -201,12 +241,21 @@ func (c *Fake) InvokesProxy(action Action) restclient.ResponseWrapper {
 	return nil
 }
 
+// startTrace records a new reaction trace for action and returns it. It must
+// be called with the lock held, and the trace may only be changed until the
+// lock is released.
+func (c *Fake) startTrace(action Action) *ReactionTrace {
+	c.traces = append(c.traces, ReactionTrace{Action: action})
+	return &c.traces[len(c.traces)-1]
+}
+
 // ClearActions clears the history of actions called on the fake client.
 func (c *Fake) ClearActions() {
 	c.Lock()
 	defer c.Unlock()
 
 	c.actions = make([]Action, 0)
+	c.traces = nil
 }
 
 // Actions returns a chronologically ordered slice fake actions called on the
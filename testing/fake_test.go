@@ -164,3 +164,41 @@ func TestReactorChangesPersisted(t *testing.T) {
 		t.Errorf("expected Action recorded to not be modified by ReactionFunc but it was")
 	}
 }
+
+func TestReactorPriorities(t *testing.T) {
+	var called []string
+	reaction := func(name string, handled bool) ReactionFunc {
+		return func(action Action) (bool, runtime.Object, error) {
+			called = append(called, name)
+			return handled, nil, nil
+		}
+	}
+
+	f := &Fake{}
+	f.AddReactorWithOptions("*", "*", reaction("low", true), ReactorOptions{Name: "low", Priority: -1})
+	f.AddReactor("*", "*", reaction("added", false))
+	f.PrependReactor("*", "*", reaction("prepended", false))
+	f.AddReactorWithOptions("*", "*", reaction("high", false), ReactorOptions{Name: "high", Priority: 1})
+	f.PrependReactorWithOptions("get", "pods", reaction("pods", false), ReactorOptions{Name: "pods", Priority: 1})
+
+	resource := schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+	_, err := f.Invokes(NewGetAction(resource, "ns", "name"), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"high", "prepended", "added", "low"}, called)
+
+	traces := f.ReactionTraces()
+	if assert.Len(t, traces, 1) {
+		assert.Equal(t, "get", traces[0].Action.GetVerb())
+		assert.Equal(t, []ReactionStep{
+			{Reactor: "pods"},
+			{Reactor: "high", Matched: true},
+			{Reactor: "* *", Matched: true},
+			{Reactor: "* *", Matched: true},
+			{Reactor: "low", Matched: true, Handled: true},
+		}, traces[0].Steps)
+		assert.Equal(t, "get secrets\n  0. pods: skipped\n  1. high: passed\n  2. * *: passed\n  3. * *: passed\n  4. low: handled", traces[0].String())
+	}
+
+	f.ClearActions()
+	assert.Empty(t, f.ReactionTraces())
+}
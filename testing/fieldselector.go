@@ -0,0 +1,356 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// FieldValueFunc returns the value of a field of obj, as matched by field
+// selectors.
+type FieldValueFunc func(obj runtime.Object) (string, error)
+
+var (
+	fieldSelectorsLock sync.RWMutex
+	// fieldSelectors holds the fields supported in field selectors in
+	// addition to metadata.name and metadata.namespace, like the apiserver.
+	fieldSelectors = map[schema.GroupResource]map[string]FieldValueFunc{
+		{Resource: "pods"}: pathFields(
+			"spec.nodeName", "spec.restartPolicy", "spec.schedulerName", "spec.serviceAccountName",
+			"status.phase", "status.podIP", "status.nominatedNodeName"),
+		{Resource: "events"}: mergeFields(pathFields(
+			"involvedObject.kind", "involvedObject.namespace", "involvedObject.name", "involvedObject.uid",
+			"involvedObject.apiVersion", "involvedObject.resourceVersion", "involvedObject.fieldPath",
+			"reason", "reportingComponent", "type"),
+			map[string]FieldValueFunc{"source": pathValue("source.component")}),
+		{Resource: "secrets"}:                    pathFields("type"),
+		{Resource: "nodes"}:                      pathFields("spec.unschedulable"),
+		{Resource: "namespaces"}:                 pathFields("status.phase"),
+		{Resource: "replicationcontrollers"}:     pathFields("status.replicas"),
+		{Group: "apps", Resource: "replicasets"}: pathFields("status.replicas"),
+		{Group: "batch", Resource: "jobs"}:       pathFields("status.successful"),
+		{Group: "certificates.k8s.io", Resource: "certificatesigningrequests"}: pathFields("spec.signerName"),
+		{Group: "events.k8s.io", Resource: "events"}:                           pathFields("reason", "reportingController", "type"),
+	}
+)
+
+// RegisterFieldSelector makes field usable in the field selectors of lists
+// and watches of resources of gr served by the fake clients, for example for
+// the selectable fields of a custom resource. If fn is nil, the value is read
+// from the field path in the JSON representation of the object.
+func RegisterFieldSelector(gr schema.GroupResource, field string, fn FieldValueFunc) {
+	if fn == nil {
+		fn = pathValue(field)
+	}
+	fieldSelectorsLock.Lock()
+	defer fieldSelectorsLock.Unlock()
+	if fieldSelectors[gr] == nil {
+		fieldSelectors[gr] = map[string]FieldValueFunc{}
+	}
+	fieldSelectors[gr][field] = fn
+}
+
+func pathFields(paths ...string) map[string]FieldValueFunc {
+	result := map[string]FieldValueFunc{}
+	for _, path := range paths {
+		result[path] = pathValue(path)
+	}
+	return result
+}
+
+func mergeFields(a, b map[string]FieldValueFunc) map[string]FieldValueFunc {
+	for field, fn := range b {
+		a[field] = fn
+	}
+	return a
+}
+
+// pathValue returns a FieldValueFunc reading the value at a dotted path in
+// the JSON representation of an object. Missing values are empty.
+func pathValue(path string) FieldValueFunc {
+	return func(obj runtime.Object) (string, error) {
+		var content map[string]interface{}
+		if u, ok := obj.(runtime.Unstructured); ok {
+			content = u.UnstructuredContent()
+		} else {
+			var err error
+			content, err = runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+			if err != nil {
+				return "", err
+			}
+		}
+		value, found, err := unstructured.NestedFieldNoCopy(content, strings.Split(path, ".")...)
+		if err != nil || !found || value == nil {
+			return "", err
+		}
+		return fmt.Sprintf("%v", value), nil
+	}
+}
+
+// validateFieldSelector returns a BadRequest error, like the apiserver, if
+// selector uses a field that is not supported for gr.
+func validateFieldSelector(gr schema.GroupResource, selector fields.Selector) error {
+	fieldSelectorsLock.RLock()
+	defer fieldSelectorsLock.RUnlock()
+	for _, requirement := range selector.Requirements() {
+		switch requirement.Field {
+		case "metadata.name", "metadata.namespace":
+			continue
+		}
+		if _, ok := fieldSelectors[gr][requirement.Field]; !ok {
+			return errors.NewBadRequest(fmt.Sprintf("field label not supported: %s", requirement.Field))
+		}
+	}
+	return nil
+}
+
+// matchesFieldSelector reports whether obj of resource gr matches selector.
+// It returns a BadRequest error, like the apiserver, if the selector uses a
+// field that is not supported for gr.
+func matchesFieldSelector(gr schema.GroupResource, obj runtime.Object, selector fields.Selector) (bool, error) {
+	if selector == nil || selector.Empty() {
+		return true, nil
+	}
+	objMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return false, err
+	}
+	set := fields.Set{
+		"metadata.name":      objMeta.GetName(),
+		"metadata.namespace": objMeta.GetNamespace(),
+	}
+
+	fieldSelectorsLock.RLock()
+	supported := fieldSelectors[gr]
+	fieldSelectorsLock.RUnlock()
+	for _, requirement := range selector.Requirements() {
+		if _, ok := set[requirement.Field]; ok {
+			continue
+		}
+		fn, ok := supported[requirement.Field]
+		if !ok {
+			return false, errors.NewBadRequest(fmt.Sprintf("field label not supported: %s", requirement.Field))
+		}
+		value, err := fn(obj)
+		if err != nil {
+			return false, err
+		}
+		set[requirement.Field] = value
+	}
+	return selector.Matches(set), nil
+}
+
+// filterListByFieldSelector removes the items of list that do not match selector.
+func filterListByFieldSelector(gr schema.GroupResource, list runtime.Object, selector fields.Selector) error {
+	if selector == nil || selector.Empty() {
+		return nil
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return err
+	}
+	var matching []runtime.Object
+	for _, item := range items {
+		ok, err := matchesFieldSelector(gr, item, selector)
+		if err != nil {
+			return err
+		}
+		if ok {
+			matching = append(matching, item)
+		}
+	}
+	return meta.SetList(list, matching)
+}
+
+// ObjectWatchReaction returns a WatchReactionFunc that watches the tracker,
+// only delivering events for objects matching the field selector of the
+// action. Like the apiserver, a modification that makes an object match the
+// selector is delivered as an Added event, and one that makes it stop
+// matching as a Deleted event. For trackers returned by NewObjectTracker, the
+// watch starts from the resource version of the action, see
+// ResourceVersionTracker, and starts with a Bookmark event if bookmarks are
+// allowed.
+func ObjectWatchReaction(o ObjectTracker) WatchReactionFunc {
+	return func(action Action) (bool, watch.Interface, error) {
+		gvr := action.GetResource()
+		ns := action.GetNamespace()
+		var restrictions WatchRestrictions
+		if watchAction, ok := action.(WatchAction); ok {
+			restrictions = watchAction.GetWatchRestrictions()
+		}
+		selector := restrictions.Fields
+		filtered := selector != nil && !selector.Empty()
+		if filtered {
+			if err := validateFieldSelector(gvr.GroupResource(), selector); err != nil {
+				return true, nil, err
+			}
+		}
+
+		t, ok := o.(*tracker)
+		if !ok {
+			w, err := o.Watch(gvr, ns)
+			if err != nil {
+				return false, nil, err
+			}
+			if filtered {
+				w = newFieldFilteredWatcher(w, gvr.GroupResource(), selector, map[types.NamespacedName]bool{})
+			}
+			return true, w, nil
+		}
+
+		fakewatcher, initial, start, err := t.watchFrom(gvr, ns, restrictions.ResourceVersion)
+		if err != nil {
+			return true, nil, err
+		}
+		if fakewatcher == nil {
+			return true, newInitialEventsWatcher(initial, nil), nil
+		}
+		if restrictions.AllowWatchBookmarks {
+			if bookmark, ok := t.bookmark(gvr); ok {
+				initial = append(initial, bookmark)
+			}
+		}
+		var w watch.Interface = fakewatcher
+		if len(initial) > 0 {
+			w = newInitialEventsWatcher(initial, fakewatcher)
+		}
+		if !filtered {
+			return true, w, nil
+		}
+
+		matching := map[types.NamespacedName]bool{}
+		for key, obj := range start {
+			ok, err := matchesFieldSelector(gvr.GroupResource(), obj, selector)
+			if err != nil {
+				w.Stop()
+				return true, nil, err
+			}
+			if ok {
+				matching[key] = true
+			}
+		}
+		return true, newFieldFilteredWatcher(w, gvr.GroupResource(), selector, matching), nil
+	}
+}
+
+// fieldFilteredWatcher filters the events of a watch by field selector.
+type fieldFilteredWatcher struct {
+	source   watch.Interface
+	gr       schema.GroupResource
+	selector fields.Selector
+	// matching holds the objects currently matching the selector.
+	matching map[types.NamespacedName]bool
+	result   chan watch.Event
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+var _ watch.Interface = &fieldFilteredWatcher{}
+
+func newFieldFilteredWatcher(source watch.Interface, gr schema.GroupResource, selector fields.Selector, matching map[types.NamespacedName]bool) *fieldFilteredWatcher {
+	w := &fieldFilteredWatcher{
+		source:   source,
+		gr:       gr,
+		selector: selector,
+		matching: matching,
+		result:   make(chan watch.Event),
+		done:     make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *fieldFilteredWatcher) ResultChan() <-chan watch.Event {
+	return w.result
+}
+
+func (w *fieldFilteredWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.done)
+		w.source.Stop()
+	})
+}
+
+func (w *fieldFilteredWatcher) run() {
+	defer close(w.result)
+	for event := range w.source.ResultChan() {
+		event, ok := w.filter(event)
+		if !ok {
+			continue
+		}
+		select {
+		case w.result <- event:
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// filter returns the event to deliver for event, if any.
+func (w *fieldFilteredWatcher) filter(event watch.Event) (watch.Event, bool) {
+	switch event.Type {
+	case watch.Added, watch.Modified, watch.Deleted:
+	default:
+		return event, true
+	}
+	objMeta, err := meta.Accessor(event.Object)
+	if err != nil {
+		return event, true
+	}
+	key := types.NamespacedName{Namespace: objMeta.GetNamespace(), Name: objMeta.GetName()}
+	matches, err := matchesFieldSelector(w.gr, event.Object, w.selector)
+	if err != nil {
+		return event, false
+	}
+	wasMatching := w.matching[key]
+
+	switch event.Type {
+	case watch.Added:
+		if !matches {
+			return event, false
+		}
+		w.matching[key] = true
+	case watch.Modified:
+		switch {
+		case matches && !wasMatching:
+			w.matching[key] = true
+			event.Type = watch.Added
+		case !matches && wasMatching:
+			delete(w.matching, key)
+			event.Type = watch.Deleted
+		case !matches:
+			return event, false
+		}
+	case watch.Deleted:
+		if !wasMatching {
+			return event, false
+		}
+		delete(w.matching, key)
+	}
+	return event, true
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	serializer "k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+var podsResource = corev1.SchemeGroupVersion.WithResource("pods")
+
+func newPodTracker(t *testing.T, pods ...*corev1.Pod) ObjectTracker {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	o := NewObjectTracker(scheme, serializer.NewCodecFactory(scheme).UniversalDecoder())
+	for _, pod := range pods {
+		if err := o.Add(pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return o
+}
+
+func newPod(name, nodeName string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec:       corev1.PodSpec{NodeName: nodeName},
+	}
+}
+
+func listPodNames(t *testing.T, o ObjectTracker, fieldSelector string) ([]string, error) {
+	action := NewListAction(podsResource, corev1.SchemeGroupVersion.WithKind("Pod"), "default", metav1.ListOptions{FieldSelector: fieldSelector})
+	_, obj, err := ObjectReaction(o)(action)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, pod := range obj.(*corev1.PodList).Items {
+		names = append(names, pod.Name)
+	}
+	return names, nil
+}
+
+func TestListFieldSelector(t *testing.T) {
+	o := newPodTracker(t, newPod("a", "node1"), newPod("b", "node2"), newPod("c", "node1"))
+
+	names, err := listPodNames(t, o, "spec.nodeName=node1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"a", "c"}; !reflect.DeepEqual(expected, names) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+
+	names, err = listPodNames(t, o, "metadata.name!=a,spec.nodeName=node1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"c"}; !reflect.DeepEqual(expected, names) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+
+	if _, err := listPodNames(t, o, "spec.hostname=a"); !errors.IsBadRequest(err) {
+		t.Errorf("expected a bad request error for an unsupported field, got %v", err)
+	}
+
+	RegisterFieldSelector(schema.GroupResource{Resource: "pods"}, "spec.hostname", nil)
+	defer func() {
+		fieldSelectorsLock.Lock()
+		defer fieldSelectorsLock.Unlock()
+		delete(fieldSelectors[schema.GroupResource{Resource: "pods"}], "spec.hostname")
+	}()
+	names, err = listPodNames(t, o, "spec.hostname=")
+	if err != nil {
+		t.Fatalf("unexpected error for a registered field: %v", err)
+	}
+	if len(names) != 3 {
+		t.Errorf("expected all pods, got %v", names)
+	}
+}
+
+func TestWatchFieldSelector(t *testing.T) {
+	o := newPodTracker(t, newPod("a", "node1"), newPod("b", "node2"))
+
+	action := NewWatchAction(podsResource, "default", metav1.ListOptions{FieldSelector: "spec.nodeName=node1"})
+	_, w, err := ObjectWatchReaction(o)(action)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer w.Stop()
+
+	// a leaves the selection, b enters it, c is created outside of it and d inside of it.
+	for _, pod := range []*corev1.Pod{newPod("a", "node2"), newPod("b", "node1")} {
+		if err := o.Update(podsResource, pod, "default"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, pod := range []*corev1.Pod{newPod("c", "node2"), newPod("d", "node1")} {
+		if err := o.Create(podsResource, pod, "default"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := o.Delete(podsResource, "default", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Delete(podsResource, "default", "d"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"DELETED a", "ADDED b", "ADDED d", "DELETED d"}
+	var got []string
+	for len(got) < len(expected) {
+		select {
+		case event := <-w.ResultChan():
+			got = append(got, string(event.Type)+" "+event.Object.(*corev1.Pod).Name)
+		case <-time.After(wait.ForeverTestTimeout):
+			t.Fatalf("timed out waiting for events, got %v", got)
+		}
+	}
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	action = NewWatchAction(podsResource, "default", metav1.ListOptions{FieldSelector: "spec.hostname=a"})
+	if _, _, err := ObjectWatchReaction(o)(action); !errors.IsBadRequest(err) {
+		t.Errorf("expected a bad request error for an unsupported field, got %v", err)
+	}
+}
+
+func TestFakeWatchRestrictsTrackerWatch(t *testing.T) {
+	o := newPodTracker(t, newPod("a", "node1"))
+	fake := &Fake{}
+	// The watch reactor of the generated fake clientsets.
+	fake.AddWatchReactor("*", func(action Action) (handled bool, ret watch.Interface, err error) {
+		gvr := action.GetResource()
+		ns := action.GetNamespace()
+		watch, err := o.Watch(gvr, ns)
+		if err != nil {
+			return false, nil, err
+		}
+		return true, watch, nil
+	})
+
+	w, err := fake.InvokesWatch(NewWatchAction(podsResource, "default", metav1.ListOptions{FieldSelector: "spec.nodeName=node1"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer w.Stop()
+	for _, pod := range []*corev1.Pod{newPod("b", "node2"), newPod("c", "node1")} {
+		if err := o.Create(podsResource, pod, "default"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case event := <-w.ResultChan():
+		if name := event.Object.(*corev1.Pod).Name; event.Type != watch.Added || name != "c" {
+			t.Errorf("expected c to be added, got %s %s", event.Type, name)
+		}
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("timed out waiting for event")
+	}
+
+	_, err = fake.InvokesWatch(NewWatchAction(podsResource, "default", metav1.ListOptions{FieldSelector: "spec.hostname=a"}))
+	if !errors.IsBadRequest(err) {
+		t.Errorf("expected a bad request error for an unsupported field, got %v", err)
+	}
+}
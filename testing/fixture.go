@@ -17,9 +17,11 @@ limitations under the License.
 package testing
 
 import (
+	goerrors "errors"
 	"fmt"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -35,8 +37,13 @@ import (
 	"k8s.io/apimachinery/pkg/util/strategicpatch"
 	"k8s.io/apimachinery/pkg/watch"
 	restclient "k8s.io/client-go/rest"
+	"sigs.k8s.io/structured-merge-diff/v4/typed"
 )
 
+// optimisticLockErrorMsg is the message of the Conflict errors returned for
+// updates of objects with a stale resource version, like in the apiserver.
+const optimisticLockErrorMsg = "the object has been modified; please apply your changes to the latest version and try again"
+
 // ObjectTracker keeps track of objects. It is intended to be used to
 // fake calls to a server by returning objects based on their kind,
 // namespace and name.
@@ -87,20 +94,31 @@ func ObjectReaction(tracker ObjectTracker) ReactionFunc {
 		switch action := action.(type) {
 
 		case ListActionImpl:
-			obj, err := tracker.List(gvr, action.GetKind(), ns)
+			obj, err := listForAction(tracker, gvr, ns, action)
 			return true, obj, err
 
 		case GetActionImpl:
+			if action.GetSubresource() == "scale" {
+				obj, err := getScale(tracker, gvr, ns, action.GetName())
+				return true, obj, err
+			}
 			obj, err := tracker.Get(gvr, ns, action.GetName())
 			return true, obj, err
 
 		case CreateActionImpl:
-			objMeta, err := meta.Accessor(action.GetObject())
+			obj := action.GetObject()
+			if action.GetSubresource() == "" {
+				var err error
+				if obj, err = prepareForCreate(tracker, gvr, ns, obj); err != nil {
+					return true, nil, err
+				}
+			}
+			objMeta, err := meta.Accessor(obj)
 			if err != nil {
 				return true, nil, err
 			}
 			if action.GetSubresource() == "" {
-				err = tracker.Create(gvr, action.GetObject(), ns)
+				err = tracker.Create(gvr, obj, ns)
 			} else {
 				// TODO: Currently we're handling subresource creation as an update
 				// on the enclosing resource. This works for some subresources but
@@ -110,29 +128,47 @@ func ObjectReaction(tracker ObjectTracker) ReactionFunc {
 			if err != nil {
 				return true, nil, err
 			}
-			obj, err := tracker.Get(gvr, ns, objMeta.GetName())
+			obj, err = tracker.Get(gvr, ns, objMeta.GetName())
 			return true, obj, err
 
 		case UpdateActionImpl:
+			if action.GetSubresource() == "scale" {
+				obj, err := updateScale(tracker, gvr, ns, action.GetObject())
+				return true, obj, err
+			}
 			objMeta, err := meta.Accessor(action.GetObject())
 			if err != nil {
 				return true, nil, err
 			}
-			err = tracker.Update(gvr, action.GetObject(), ns)
+			obj, err := withSubresourceSemantics(tracker, gvr, ns, action.GetSubresource(), action.GetObject())
 			if err != nil {
 				return true, nil, err
 			}
-			obj, err := tracker.Get(gvr, ns, objMeta.GetName())
+			err = tracker.Update(gvr, obj, ns)
+			if err != nil {
+				return true, nil, err
+			}
+			obj, err = tracker.Get(gvr, ns, objMeta.GetName())
 			return true, obj, err
 
 		case DeleteActionImpl:
-			err := tracker.Delete(gvr, ns, action.GetName())
+			var err error
+			if t, ok := tracker.(preconditionDeleter); ok {
+				err = t.delete(gvr, ns, action.GetName(), action.GetDeleteOptions().Preconditions)
+			} else {
+				err = tracker.Delete(gvr, ns, action.GetName())
+			}
 			if err != nil {
 				return true, nil, err
 			}
 			return true, nil, nil
 
 		case PatchActionImpl:
+			if action.GetPatchType() == types.ApplyPatchType {
+				obj, err := applyPatch(tracker, action)
+				return true, obj, err
+			}
+
 			obj, err := tracker.Get(gvr, ns, action.GetName())
 			if err != nil {
 				return true, nil, err
@@ -142,6 +178,12 @@ func ObjectReaction(tracker ObjectTracker) ReactionFunc {
 			if err != nil {
 				return true, nil, err
 			}
+			var patchMeta strategicpatch.LookupPatchMeta
+			if action.GetPatchType() == types.StrategicMergePatchType {
+				if patchMeta, err = strategicPatchMeta(tracker, obj); err != nil {
+					return true, nil, err
+				}
+			}
 
 			// reset the object in preparation to unmarshal, since unmarshal does not guarantee that fields
 			// in obj that are removed by patch are cleared
@@ -172,7 +214,7 @@ func ObjectReaction(tracker ObjectTracker) ReactionFunc {
 					return true, nil, err
 				}
 			case types.StrategicMergePatchType:
-				mergedByte, err := strategicpatch.StrategicMergePatch(old, action.GetPatch(), obj)
+				mergedByte, err := strategicpatch.StrategicMergePatchUsingLookupPatchMeta(old, action.GetPatch(), patchMeta)
 				if err != nil {
 					return true, nil, err
 				}
@@ -183,11 +225,15 @@ func ObjectReaction(tracker ObjectTracker) ReactionFunc {
 				return true, nil, fmt.Errorf("PatchType is not supported")
 			}
 
+			if obj, err = withSubresourceSemantics(tracker, gvr, ns, action.GetSubresource(), obj); err != nil {
+				return true, nil, err
+			}
 			if err = tracker.Update(gvr, obj, ns); err != nil {
 				return true, nil, err
 			}
 
-			return true, obj, nil
+			obj, err = tracker.Get(gvr, ns, action.GetName())
+			return true, obj, err
 
 		default:
 			return false, nil, fmt.Errorf("no reaction implemented for %s", action)
@@ -206,45 +252,130 @@ type tracker struct {
 	// watchers' channel. Note that too many unhandled events (currently 100,
 	// see apimachinery/pkg/watch.DefaultChanSize) will cause a panic.
 	watchers map[schema.GroupVersionResource]map[string][]*watch.RaceFreeFakeWatcher
+	// parser holds the schemas used to merge apply patches, if any.
+	parser *typed.Parser
+	// resourceVersions is true if the tracker assigns resource versions and
+	// enforces them, see SetResourceVersionSemantics.
+	resourceVersions bool
+	// resourceVersion is the last resource version assigned.
+	resourceVersion uint64
+	// events holds the last events of each resource when resource version
+	// semantics are on, to serve watches starting from a resource version.
+	events map[schema.GroupVersionResource][]trackedEvent
+	// compacted holds the resource version of the last event dropped from
+	// events, for each resource.
+	compacted map[schema.GroupVersionResource]uint64
+	// defaulter, if set, defaults objects created through ObjectReaction.
+	defaulter runtime.ObjectDefaulter
+	// subresources are the subresources of resources set with
+	// SetSubresources.
+	subresources map[schema.GroupVersionResource]Subresources
+	// pristine holds copies of the objects to detect their mutations, if
+	// turned on with SetMutationDetection.
+	pristine map[schema.GroupVersionResource]map[types.NamespacedName]runtime.Object
 }
 
-var _ ObjectTracker = &tracker{}
+var _ ResourceVersionTracker = &tracker{}
+
+// ResourceVersionTracker is an ObjectTracker that can simulate the
+// optimistic concurrency of the apiserver. The trackers returned by
+// NewObjectTracker implement it, for example:
+//
+//	client := fake.NewSimpleClientset(objects...)
+//	client.Tracker().(testing.ResourceVersionTracker).SetResourceVersionSemantics(true)
+type ResourceVersionTracker interface {
+	ObjectTracker
+
+	// SetResourceVersionSemantics turns resource version semantics on or
+	// off; they are off by default. When on, like the apiserver, the tracker
+	// assigns an increasing resource version to objects each time they are
+	// created, updated or deleted, rejects the creation of objects with a
+	// resource version, and rejects with a Conflict error the updates of
+	// objects whose resource version is not the current one or whose UID
+	// does not match, as well as deletions whose preconditions do not
+	// match. Lists carry the last resource version assigned. Objects
+	// without resource version when this is turned on get one.
+	SetResourceVersionSemantics(enabled bool)
+}
 
 // NewObjectTracker returns an ObjectTracker that can be used to keep track
 // of objects for the fake clientset. Mostly useful for unit tests.
 func NewObjectTracker(scheme ObjectScheme, decoder runtime.Decoder) ObjectTracker {
 	return &tracker{
-		scheme:   scheme,
-		decoder:  decoder,
-		objects:  make(map[schema.GroupVersionResource]map[types.NamespacedName]runtime.Object),
-		watchers: make(map[schema.GroupVersionResource]map[string][]*watch.RaceFreeFakeWatcher),
+		scheme:    scheme,
+		decoder:   decoder,
+		objects:   make(map[schema.GroupVersionResource]map[types.NamespacedName]runtime.Object),
+		watchers:  make(map[schema.GroupVersionResource]map[string][]*watch.RaceFreeFakeWatcher),
+		events:    make(map[schema.GroupVersionResource][]trackedEvent),
+		compacted: make(map[schema.GroupVersionResource]uint64),
+
+		subresources: make(map[schema.GroupVersionResource]Subresources),
 	}
 }
 
-func (t *tracker) List(gvr schema.GroupVersionResource, gvk schema.GroupVersionKind, ns string) (runtime.Object, error) {
-	// Heuristic for list kind: original kind + List suffix. Might
-	// not always be true but this tracker has a pretty limited
-	// understanding of the actual API model.
-	listGVK := gvk
-	listGVK.Kind = listGVK.Kind + "List"
-	// GVK does have the concept of "internal version". The scheme recognizes
-	// the runtime.APIVersionInternal, but not the empty string.
-	if listGVK.Version == "" {
-		listGVK.Version = runtime.APIVersionInternal
+func (t *tracker) SetResourceVersionSemantics(enabled bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.resourceVersions = enabled
+	if !enabled {
+		return
+	}
+	gvrs := make([]schema.GroupVersionResource, 0, len(t.objects))
+	for gvr := range t.objects {
+		gvrs = append(gvrs, gvr)
 	}
+	sort.Slice(gvrs, func(i, j int) bool {
+		return gvrs[i].String() < gvrs[j].String()
+	})
+	for _, gvr := range gvrs {
+		objs, _ := filterByNamespace(t.objects[gvr], metav1.NamespaceAll)
+		for _, obj := range objs {
+			objMeta, err := meta.Accessor(obj)
+			if err != nil || objMeta.GetResourceVersion() != "" {
+				continue
+			}
+			objMeta.SetResourceVersion(t.nextResourceVersionLocked())
+			t.storedLocked(gvr, objectKey(obj), obj)
+		}
+	}
+}
 
-	list, err := t.scheme.New(listGVK)
-	if err != nil {
-		return nil, err
+// nextResourceVersionLocked returns a new resource version, greater than all
+// the ones assigned before.
+func (t *tracker) nextResourceVersionLocked() string {
+	t.resourceVersion++
+	return strconv.FormatUint(t.resourceVersion, 10)
+}
+
+// observeResourceVersionLocked makes sure the resource versions assigned
+// next are greater than resourceVersion, if it is numeric.
+func (t *tracker) observeResourceVersionLocked(resourceVersion string) {
+	if rv, err := strconv.ParseUint(resourceVersion, 10, 64); err == nil && rv > t.resourceVersion {
+		t.resourceVersion = rv
 	}
+}
 
-	if !meta.IsListType(list) {
-		return nil, fmt.Errorf("%q is not a list type", listGVK.Kind)
+func (t *tracker) List(gvr schema.GroupVersionResource, gvk schema.GroupVersionKind, ns string) (runtime.Object, error) {
+	list, err := t.newList(gvk)
+	if err != nil {
+		return nil, err
 	}
 
 	t.lock.RLock()
 	defer t.lock.RUnlock()
 
+	if err := t.checkMutationsLocked(); err != nil {
+		return nil, err
+	}
+	if t.resourceVersions {
+		listMeta, err := meta.ListAccessor(list)
+		if err != nil {
+			return nil, err
+		}
+		listMeta.SetResourceVersion(strconv.FormatUint(t.resourceVersion, 10))
+	}
+
 	objs, ok := t.objects[gvr]
 	if !ok {
 		return list, nil
@@ -260,6 +391,30 @@ func (t *tracker) List(gvr schema.GroupVersionResource, gvk schema.GroupVersionK
 	return list.DeepCopyObject(), nil
 }
 
+// newList returns an empty list of objects of kind gvk.
+func (t *tracker) newList(gvk schema.GroupVersionKind) (runtime.Object, error) {
+	// Heuristic for list kind: original kind + List suffix. Might
+	// not always be true but this tracker has a pretty limited
+	// understanding of the actual API model.
+	listGVK := gvk
+	listGVK.Kind = listGVK.Kind + "List"
+	// GVK does have the concept of "internal version". The scheme recognizes
+	// the runtime.APIVersionInternal, but not the empty string.
+	if listGVK.Version == "" {
+		listGVK.Version = runtime.APIVersionInternal
+	}
+
+	list, err := t.scheme.New(listGVK)
+	if err != nil {
+		return nil, err
+	}
+
+	if !meta.IsListType(list) {
+		return nil, fmt.Errorf("%q is not a list type", listGVK.Kind)
+	}
+	return list, nil
+}
+
 func (t *tracker) Watch(gvr schema.GroupVersionResource, ns string) (watch.Interface, error) {
 	t.lock.Lock()
 	defer t.lock.Unlock()
@@ -270,7 +425,7 @@ func (t *tracker) Watch(gvr schema.GroupVersionResource, ns string) (watch.Inter
 		t.watchers[gvr] = make(map[string][]*watch.RaceFreeFakeWatcher)
 	}
 	t.watchers[gvr][ns] = append(t.watchers[gvr][ns], fakewatcher)
-	return fakewatcher, nil
+	return &trackerWatch{RaceFreeFakeWatcher: fakewatcher, tracker: t}, nil
 }
 
 func (t *tracker) Get(gvr schema.GroupVersionResource, ns, name string) (runtime.Object, error) {
@@ -279,6 +434,9 @@ func (t *tracker) Get(gvr schema.GroupVersionResource, ns, name string) (runtime
 	t.lock.RLock()
 	defer t.lock.RUnlock()
 
+	if err := t.checkMutationsLocked(); err != nil {
+		return nil, err
+	}
 	objs, ok := t.objects[gvr]
 	if !ok {
 		return nil, errNotFound
@@ -343,6 +501,18 @@ func (t *tracker) Add(obj runtime.Object) error {
 }
 
 func (t *tracker) Create(gvr schema.GroupVersionResource, obj runtime.Object, ns string) error {
+	t.lock.RLock()
+	resourceVersions := t.resourceVersions
+	t.lock.RUnlock()
+	if resourceVersions {
+		objMeta, err := meta.Accessor(obj)
+		if err != nil {
+			return err
+		}
+		if objMeta.GetResourceVersion() != "" {
+			return errors.NewBadRequest("resourceVersion should not be set on objects to be created")
+		}
+	}
 	return t.add(gvr, obj, ns, false)
 }
 
@@ -369,6 +539,9 @@ func (t *tracker) add(gvr schema.GroupVersionResource, obj runtime.Object, ns st
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
+	if err := t.checkMutationsLocked(); err != nil {
+		return err
+	}
 	gr := gvr.GroupResource()
 
 	// To avoid the object from being accidentally modified by caller
@@ -397,13 +570,28 @@ func (t *tracker) add(gvr schema.GroupVersionResource, obj runtime.Object, ns st
 	}
 
 	namespacedName := types.NamespacedName{Namespace: newMeta.GetNamespace(), Name: newMeta.GetName()}
-	if _, ok = t.objects[gvr][namespacedName]; ok {
+	if existing, ok := t.objects[gvr][namespacedName]; ok {
 		if replaceExisting {
+			if t.resourceVersions {
+				existingMeta, err := meta.Accessor(existing)
+				if err != nil {
+					return err
+				}
+				if uid := newMeta.GetUID(); uid != "" && uid != existingMeta.GetUID() {
+					return errors.NewConflict(gr, newMeta.GetName(), fmt.Errorf("Precondition failed: UID in precondition: %v, UID in object meta: %v", uid, existingMeta.GetUID()))
+				}
+				if rv := newMeta.GetResourceVersion(); rv != "" && rv != existingMeta.GetResourceVersion() {
+					return errors.NewConflict(gr, newMeta.GetName(), goerrors.New(optimisticLockErrorMsg))
+				}
+				newMeta.SetResourceVersion(t.nextResourceVersionLocked())
+				t.recordEventLocked(gvr, watch.Modified, obj, existing)
+			}
 			for _, w := range t.getWatches(gvr, ns) {
 				// To avoid the object from being accidentally modified by watcher
 				w.Modify(obj.DeepCopyObject())
 			}
 			t.objects[gvr][namespacedName] = obj
+			t.storedLocked(gvr, namespacedName, obj)
 			return nil
 		}
 		return errors.NewAlreadyExists(gr, newMeta.GetName())
@@ -414,7 +602,17 @@ func (t *tracker) add(gvr schema.GroupVersionResource, obj runtime.Object, ns st
 		return errors.NewNotFound(gr, newMeta.GetName())
 	}
 
+	if t.resourceVersions {
+		if rv := newMeta.GetResourceVersion(); rv != "" {
+			// Objects added to seed the tracker keep their resource version.
+			t.observeResourceVersionLocked(rv)
+		} else {
+			newMeta.SetResourceVersion(t.nextResourceVersionLocked())
+		}
+		t.recordEventLocked(gvr, watch.Added, obj, nil)
+	}
 	t.objects[gvr][namespacedName] = obj
+	t.storedLocked(gvr, namespacedName, obj)
 
 	for _, w := range t.getWatches(gvr, ns) {
 		// To avoid the object from being accidentally modified by watcher
@@ -425,7 +623,8 @@ func (t *tracker) add(gvr schema.GroupVersionResource, obj runtime.Object, ns st
 }
 
 func (t *tracker) addList(obj runtime.Object, replaceExisting bool) error {
-	list, err := meta.ExtractList(obj)
+	// Decoding the items changes the list, which belongs to the caller.
+	list, err := meta.ExtractList(obj.DeepCopyObject())
 	if err != nil {
 		return err
 	}
@@ -442,9 +641,24 @@ func (t *tracker) addList(obj runtime.Object, replaceExisting bool) error {
 }
 
 func (t *tracker) Delete(gvr schema.GroupVersionResource, ns, name string) error {
+	return t.delete(gvr, ns, name, nil)
+}
+
+// preconditionDeleter is implemented by trackers that check the
+// preconditions of deletions.
+type preconditionDeleter interface {
+	delete(gvr schema.GroupVersionResource, ns, name string, preconditions *metav1.Preconditions) error
+}
+
+// delete deletes an object, checking preconditions when resource version
+// semantics are on.
+func (t *tracker) delete(gvr schema.GroupVersionResource, ns, name string, preconditions *metav1.Preconditions) error {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
+	if err := t.checkMutationsLocked(); err != nil {
+		return err
+	}
 	objs, ok := t.objects[gvr]
 	if !ok {
 		return errors.NewNotFound(gvr.GroupResource(), name)
@@ -456,13 +670,46 @@ func (t *tracker) Delete(gvr schema.GroupVersionResource, ns, name string) error
 		return errors.NewNotFound(gvr.GroupResource(), name)
 	}
 
+	if t.resourceVersions {
+		objMeta, err := meta.Accessor(obj)
+		if err != nil {
+			return err
+		}
+		if err := checkPreconditions(gvr.GroupResource(), objMeta, preconditions); err != nil {
+			return err
+		}
+		// Like in the apiserver, the object of the deletion event carries the
+		// resource version of the deletion.
+		existing := obj
+		obj = obj.DeepCopyObject()
+		objMeta, _ = meta.Accessor(obj)
+		objMeta.SetResourceVersion(t.nextResourceVersionLocked())
+		t.recordEventLocked(gvr, watch.Deleted, obj, existing)
+	}
+
 	delete(objs, namespacedName)
+	t.removedLocked(gvr, namespacedName)
 	for _, w := range t.getWatches(gvr, ns) {
 		w.Delete(obj.DeepCopyObject())
 	}
 	return nil
 }
 
+// checkPreconditions returns a Conflict error, like the apiserver, if the
+// object does not match the preconditions of a deletion.
+func checkPreconditions(gr schema.GroupResource, objMeta metav1.Object, preconditions *metav1.Preconditions) error {
+	if preconditions == nil {
+		return nil
+	}
+	if preconditions.UID != nil && *preconditions.UID != objMeta.GetUID() {
+		return errors.NewConflict(gr, objMeta.GetName(), fmt.Errorf("Precondition failed: UID in precondition: %v, UID in object meta: %v", *preconditions.UID, objMeta.GetUID()))
+	}
+	if preconditions.ResourceVersion != nil && *preconditions.ResourceVersion != objMeta.GetResourceVersion() {
+		return errors.NewConflict(gr, objMeta.GetName(), fmt.Errorf("Precondition failed: ResourceVersion in precondition: %v, ResourceVersion in object meta: %v", *preconditions.ResourceVersion, objMeta.GetResourceVersion()))
+	}
+	return nil
+}
+
 // filterByNamespace returns all objects in the collection that
 // match provided namespace. Empty namespace matches
 // non-namespaced objects.
@@ -492,6 +739,20 @@ func filterByNamespace(objs map[types.NamespacedName]runtime.Object, ns string)
 	return res, nil
 }
 
+// strategicPatchMeta returns the patch strategies of obj, those of its Go
+// type. Unstructured objects get those of the Go type of their kind in the
+// scheme of the tracker, if any.
+func strategicPatchMeta(o ObjectTracker, obj runtime.Object) (strategicpatch.LookupPatchMeta, error) {
+	if _, ok := obj.(runtime.Unstructured); ok {
+		if t, ok := o.(*tracker); ok {
+			if typed, err := t.scheme.New(obj.GetObjectKind().GroupVersionKind()); err == nil {
+				obj = typed
+			}
+		}
+	}
+	return strategicpatch.NewPatchMetaFromStruct(obj)
+}
+
 func DefaultWatchReactor(watchInterface watch.Interface, err error) WatchReactionFunc {
 	return func(action Action) (bool, watch.Interface, error) {
 		return true, watchInterface, err
@@ -25,8 +25,10 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -409,3 +411,89 @@ func Test_resourceCovers(t *testing.T) {
 		})
 	}
 }
+
+func TestResourceVersionSemantics(t *testing.T) {
+	o := newPodTracker(t, newPod("seeded", ""))
+	o.(ResourceVersionTracker).SetResourceVersionSemantics(true)
+	reaction := ObjectReaction(o)
+
+	resourceVersion := func(obj runtime.Object) string {
+		objMeta, err := meta.Accessor(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return objMeta.GetResourceVersion()
+	}
+
+	seeded, err := o.Get(podsResource, "default", "seeded")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", resourceVersion(seeded), "seeded objects get a resource version")
+
+	_, created, err := reaction(NewCreateAction(podsResource, "default", newPod("pod", "")))
+	assert.NoError(t, err)
+	assert.Equal(t, "2", resourceVersion(created))
+
+	withResourceVersion := newPod("other", "")
+	withResourceVersion.ResourceVersion = "5"
+	_, _, err = reaction(NewCreateAction(podsResource, "default", withResourceVersion))
+	assert.True(t, errors.IsBadRequest(err), "creating an object with a resource version must fail, got %v", err)
+
+	pod := created.(*corev1.Pod).DeepCopy()
+	pod.Spec.NodeName = "node-1"
+	_, updated, err := reaction(NewUpdateAction(podsResource, "default", pod))
+	assert.NoError(t, err)
+	assert.Equal(t, "3", resourceVersion(updated))
+
+	// The update above made pod stale.
+	pod.Spec.NodeName = "node-2"
+	_, _, err = reaction(NewUpdateAction(podsResource, "default", pod))
+	assert.True(t, errors.IsConflict(err), "updating a stale object must fail, got %v", err)
+
+	// Updates without resource version are unconditional.
+	pod.ResourceVersion = ""
+	_, updated, err = reaction(NewUpdateAction(podsResource, "default", pod))
+	assert.NoError(t, err)
+	assert.Equal(t, "4", resourceVersion(updated))
+
+	_, patched, err := reaction(NewPatchAction(podsResource, "default", "pod", types.MergePatchType, []byte(`{"spec":{"nodeName":"node-3"}}`)))
+	assert.NoError(t, err)
+	assert.Equal(t, "5", resourceVersion(patched))
+	_, _, err = reaction(NewPatchAction(podsResource, "default", "pod", types.MergePatchType, []byte(`{"metadata":{"resourceVersion":"4"}}`)))
+	assert.True(t, errors.IsConflict(err), "patching with a stale resource version must fail, got %v", err)
+
+	list, err := o.List(podsResource, corev1.SchemeGroupVersion.WithKind("Pod"), "default")
+	assert.NoError(t, err)
+	listMeta, err := meta.ListAccessor(list)
+	assert.NoError(t, err)
+	assert.Equal(t, "5", listMeta.GetResourceVersion())
+
+	stale := "4"
+	deleteAction := NewDeleteActionWithOptions(podsResource, "default", "pod", metav1.DeleteOptions{Preconditions: &metav1.Preconditions{ResourceVersion: &stale}})
+	_, _, err = reaction(deleteAction)
+	assert.True(t, errors.IsConflict(err), "deleting with a stale precondition must fail, got %v", err)
+
+	w, err := o.Watch(podsResource, "default")
+	assert.NoError(t, err)
+	defer w.Stop()
+	current := "5"
+	deleteAction = NewDeleteActionWithOptions(podsResource, "default", "pod", metav1.DeleteOptions{Preconditions: &metav1.Preconditions{ResourceVersion: &current}})
+	_, _, err = reaction(deleteAction)
+	assert.NoError(t, err)
+	event := <-w.ResultChan()
+	assert.Equal(t, watch.Deleted, event.Type)
+	assert.Equal(t, "6", resourceVersion(event.Object), "deletion events carry the resource version of the deletion")
+}
+
+func TestResourceVersionSemanticsDisabled(t *testing.T) {
+	o := newPodTracker(t)
+	reaction := ObjectReaction(o)
+
+	_, created, err := reaction(NewCreateAction(podsResource, "default", newPod("pod", "")))
+	assert.NoError(t, err)
+	assert.Empty(t, created.(*corev1.Pod).ResourceVersion)
+
+	pod := created.(*corev1.Pod).DeepCopy()
+	pod.ResourceVersion = "stale"
+	_, _, err = reaction(NewUpdateAction(podsResource, "default", pod))
+	assert.NoError(t, err)
+}
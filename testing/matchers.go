@@ -0,0 +1,316 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+// ActionMatcher matches the actions of fake clients, to check them without
+// comparing them field by field:
+//
+//	err := testing.CheckActions(client.Actions(),
+//		testing.ExpectGet(podsResource, "default", "pod"),
+//		testing.ExpectPatch(podsResource, "default", "pod", types.MergePatchType, []byte(`{"spec":{"nodeName":"node"}}`)),
+//	)
+type ActionMatcher interface {
+	// Match returns why action does not match, or "" if it matches.
+	Match(action Action) string
+	// String describes the matched actions.
+	String() string
+}
+
+// ExpectedAction matches the actions with its verb, resource, subresource
+// and namespace, and with its name, object and patch if they are set.
+type ExpectedAction struct {
+	Verb        string
+	Resource    schema.GroupVersionResource
+	Subresource string
+	Namespace   string
+	Name        string
+	// Object is compared with the objects of create and update actions,
+	// semantically.
+	Object runtime.Object
+	// PatchType and Patch are compared with those of patch actions. Patches
+	// are compared as JSON values, so that their formatting and the order
+	// of their keys do not matter.
+	PatchType types.PatchType
+	Patch     []byte
+}
+
+var _ ActionMatcher = ExpectedAction{}
+
+// ExpectGet matches the gets of an object.
+func ExpectGet(resource schema.GroupVersionResource, namespace, name string) ExpectedAction {
+	return ExpectedAction{Verb: "get", Resource: resource, Namespace: namespace, Name: name}
+}
+
+// ExpectList matches the lists of the objects of a namespace.
+func ExpectList(resource schema.GroupVersionResource, namespace string) ExpectedAction {
+	return ExpectedAction{Verb: "list", Resource: resource, Namespace: namespace}
+}
+
+// ExpectWatch matches the watches of the objects of a namespace.
+func ExpectWatch(resource schema.GroupVersionResource, namespace string) ExpectedAction {
+	return ExpectedAction{Verb: "watch", Resource: resource, Namespace: namespace}
+}
+
+// ExpectCreate matches the creations of object.
+func ExpectCreate(resource schema.GroupVersionResource, namespace string, object runtime.Object) ExpectedAction {
+	return ExpectedAction{Verb: "create", Resource: resource, Namespace: namespace, Object: object}
+}
+
+// ExpectUpdate matches the updates to object.
+func ExpectUpdate(resource schema.GroupVersionResource, namespace string, object runtime.Object) ExpectedAction {
+	return ExpectedAction{Verb: "update", Resource: resource, Namespace: namespace, Object: object}
+}
+
+// ExpectPatch matches the patches of an object with patch.
+func ExpectPatch(resource schema.GroupVersionResource, namespace, name string, patchType types.PatchType, patch []byte) ExpectedAction {
+	return ExpectedAction{Verb: "patch", Resource: resource, Namespace: namespace, Name: name, PatchType: patchType, Patch: patch}
+}
+
+// ExpectDelete matches the deletions of an object.
+func ExpectDelete(resource schema.GroupVersionResource, namespace, name string) ExpectedAction {
+	return ExpectedAction{Verb: "delete", Resource: resource, Namespace: namespace, Name: name}
+}
+
+// ExpectDeleteCollection matches the deletions of the objects of a
+// namespace.
+func ExpectDeleteCollection(resource schema.GroupVersionResource, namespace string) ExpectedAction {
+	return ExpectedAction{Verb: "delete-collection", Resource: resource, Namespace: namespace}
+}
+
+// WithSubresource returns a copy of e matching the actions on a subresource.
+func (e ExpectedAction) WithSubresource(subresource string) ExpectedAction {
+	e.Subresource = subresource
+	return e
+}
+
+// Match implements ActionMatcher.
+func (e ExpectedAction) Match(action Action) string {
+	var reasons []string
+	if action.GetVerb() != e.Verb {
+		reasons = append(reasons, fmt.Sprintf("verb is %q", action.GetVerb()))
+	}
+	if action.GetResource() != e.Resource {
+		reasons = append(reasons, fmt.Sprintf("resource is %v", action.GetResource()))
+	}
+	if action.GetSubresource() != e.Subresource {
+		reasons = append(reasons, fmt.Sprintf("subresource is %q", action.GetSubresource()))
+	}
+	if action.GetNamespace() != e.Namespace {
+		reasons = append(reasons, fmt.Sprintf("namespace is %q", action.GetNamespace()))
+	}
+	if name := actionName(action); e.Name != "" && name != e.Name {
+		reasons = append(reasons, fmt.Sprintf("name is %q", name))
+	}
+	if e.Object != nil {
+		var object runtime.Object
+		switch action := action.(type) {
+		case CreateAction:
+			object = action.GetObject()
+		case UpdateAction:
+			object = action.GetObject()
+		}
+		if !equality.Semantic.DeepEqual(e.Object, object) {
+			reasons = append(reasons, fmt.Sprintf("object differs (-expected +got):\n%s", cmp.Diff(e.Object, object)))
+		}
+	}
+	if e.PatchType != "" || e.Patch != nil {
+		if patchAction, ok := action.(PatchAction); !ok {
+			reasons = append(reasons, "action is not a patch")
+		} else {
+			if e.PatchType != "" && patchAction.GetPatchType() != e.PatchType {
+				reasons = append(reasons, fmt.Sprintf("patch type is %q", patchAction.GetPatchType()))
+			}
+			if e.Patch != nil && !equalPatches(e.Patch, patchAction.GetPatch()) {
+				reasons = append(reasons, fmt.Sprintf("patch is %s, expected %s", patchAction.GetPatch(), e.Patch))
+			}
+		}
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// String implements ActionMatcher.
+func (e ExpectedAction) String() string {
+	s := describeAction(e.Verb, e.Resource, e.Subresource, e.Namespace, e.Name)
+	if e.Patch != nil {
+		s += fmt.Sprintf(" with %s", e.Patch)
+	}
+	return s
+}
+
+// equalPatches returns whether two patches are equal JSON, or YAML for apply
+// patches, values.
+func equalPatches(expected, actual []byte) bool {
+	var expectedValue, actualValue interface{}
+	if yaml.Unmarshal(expected, &expectedValue) != nil || yaml.Unmarshal(actual, &actualValue) != nil {
+		return bytes.Equal(expected, actual)
+	}
+	return reflect.DeepEqual(expectedValue, actualValue)
+}
+
+// ExpectVerb matches the actions with the given verb on the given resource,
+// or on any resource if it is "*", see Action.Matches.
+func ExpectVerb(verb, resource string) ActionMatcher {
+	return verbMatcher{verb: verb, resource: resource}
+}
+
+type verbMatcher struct {
+	verb     string
+	resource string
+}
+
+func (m verbMatcher) Match(action Action) string {
+	if action.GetVerb() != m.verb || (m.resource != "*" && action.GetResource().Resource != m.resource) {
+		return fmt.Sprintf("action is %s", describe(action))
+	}
+	return ""
+}
+
+func (m verbMatcher) String() string {
+	return m.verb + " " + m.resource
+}
+
+// CheckActions returns an error describing the differences between actions
+// and the expected ones, in order.
+func CheckActions(actions []Action, expected ...ActionMatcher) error {
+	var differences []string
+	for i := 0; i < len(actions) || i < len(expected); i++ {
+		switch {
+		case i >= len(expected):
+			differences = append(differences, fmt.Sprintf("action %d: unexpected %s", i, describe(actions[i])))
+		case i >= len(actions):
+			differences = append(differences, fmt.Sprintf("action %d: missing %s", i, expected[i]))
+		default:
+			if reason := expected[i].Match(actions[i]); reason != "" {
+				differences = append(differences, fmt.Sprintf("action %d: expected %s, got %s: %s", i, expected[i], describe(actions[i]), reason))
+			}
+		}
+	}
+	if len(differences) > 0 {
+		return fmt.Errorf("unexpected actions:\n%s", strings.Join(differences, "\n"))
+	}
+	return nil
+}
+
+// CheckActionSubsequence returns an error unless the expected actions are
+// among actions in order, possibly with other actions between them.
+func CheckActionSubsequence(actions []Action, expected ...ActionMatcher) error {
+	next := 0
+	for _, matcher := range expected {
+		found := false
+		for ; next < len(actions) && !found; next++ {
+			found = matcher.Match(actions[next]) == ""
+		}
+		if !found {
+			return fmt.Errorf("expected %s after the actions matched so far, got:\n%s", matcher, describeMismatches(actions, matcher))
+		}
+	}
+	return nil
+}
+
+// FilterActions returns the actions matched by matcher.
+func FilterActions(actions []Action, matcher ActionMatcher) []Action {
+	var filtered []Action
+	for _, action := range actions {
+		if matcher.Match(action) == "" {
+			filtered = append(filtered, action)
+		}
+	}
+	return filtered
+}
+
+func describeMismatches(actions []Action, matcher ActionMatcher) string {
+	lines := make([]string, 0, len(actions))
+	for i, action := range actions {
+		line := fmt.Sprintf("action %d: %s", i, describe(action))
+		if reason := matcher.Match(action); reason == "" {
+			line += ": matches"
+		} else {
+			line += ": " + reason
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func describe(action Action) string {
+	return describeAction(action.GetVerb(), action.GetResource(), action.GetSubresource(), action.GetNamespace(), actionName(action))
+}
+
+func describeAction(verb string, resource schema.GroupVersionResource, subresource, namespace, name string) string {
+	s := verb + " " + resource.GroupResource().String()
+	if subresource != "" {
+		s += "/" + subresource
+	}
+	switch {
+	case namespace != "" && name != "":
+		s += " " + namespace + "/" + name
+	case name != "":
+		s += " " + name
+	case namespace != "":
+		s += " in " + namespace
+	}
+	return s
+}
+
+// actionName returns the name of the object of an action, if any.
+func actionName(action Action) string {
+	switch action := action.(type) {
+	case GetAction:
+		return action.GetName()
+	case PatchAction:
+		return action.GetName()
+	case DeleteAction:
+		return action.GetName()
+	case CreateActionImpl:
+		if action.Name != "" {
+			return action.Name
+		}
+		return objectName(action.Object)
+	case CreateAction:
+		return objectName(action.GetObject())
+	case UpdateAction:
+		return objectName(action.GetObject())
+	}
+	return ""
+}
+
+func objectName(object runtime.Object) string {
+	if object == nil {
+		return ""
+	}
+	accessor, err := meta.Accessor(object)
+	if err != nil {
+		return ""
+	}
+	return accessor.GetName()
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestExpectedActionMatch(t *testing.T) {
+	pod := newPod("a", "node1")
+	testCases := []struct {
+		name     string
+		expected ExpectedAction
+		action   Action
+		reason   string
+	}{
+		{
+			name:     "get",
+			expected: ExpectGet(podsResource, "default", "a"),
+			action:   NewGetAction(podsResource, "default", "a"),
+		},
+		{
+			name:     "get of another object",
+			expected: ExpectGet(podsResource, "default", "a"),
+			action:   NewGetAction(podsResource, "other", "b"),
+			reason:   `namespace is "other"; name is "b"`,
+		},
+		{
+			name:     "list",
+			expected: ExpectList(podsResource, "default"),
+			action:   NewListAction(podsResource, corev1.SchemeGroupVersion.WithKind("Pod"), "default", metav1.ListOptions{}),
+		},
+		{
+			name:     "create",
+			expected: ExpectCreate(podsResource, "default", pod),
+			action:   NewCreateAction(podsResource, "default", pod.DeepCopy()),
+		},
+		{
+			name:     "update of another object",
+			expected: ExpectUpdate(podsResource, "default", pod),
+			action:   NewUpdateAction(podsResource, "default", newPod("a", "node2")),
+			reason:   "object differs",
+		},
+		{
+			name:     "status update",
+			expected: ExpectUpdate(podsResource, "default", pod).WithSubresource("status"),
+			action:   NewUpdateSubresourceAction(podsResource, "status", "default", pod.DeepCopy()),
+		},
+		{
+			name:     "update of the main resource",
+			expected: ExpectUpdate(podsResource, "default", pod).WithSubresource("status"),
+			action:   NewUpdateAction(podsResource, "default", pod.DeepCopy()),
+			reason:   `subresource is ""`,
+		},
+		{
+			name:     "equivalent patch",
+			expected: ExpectPatch(podsResource, "default", "a", types.MergePatchType, []byte(`{"metadata":{"labels":{"a":"1","b":"2"}}}`)),
+			action:   NewPatchAction(podsResource, "default", "a", types.MergePatchType, []byte(`{"metadata": {"labels": {"b": "2", "a": "1"}}}`)),
+		},
+		{
+			name:     "apply patch",
+			expected: ExpectPatch(podsResource, "default", "a", types.ApplyPatchType, []byte(`{"metadata":{"name":"a"}}`)),
+			action:   NewPatchAction(podsResource, "default", "a", types.ApplyPatchType, []byte("metadata:\n  name: a\n")),
+		},
+		{
+			name:     "other patch",
+			expected: ExpectPatch(podsResource, "default", "a", types.MergePatchType, []byte(`{"spec":{"nodeName":"node1"}}`)),
+			action:   NewPatchAction(podsResource, "default", "a", types.StrategicMergePatchType, []byte(`{"spec":{"nodeName":"node2"}}`)),
+			reason:   `patch type is "application/strategic-merge-patch+json"; patch is {"spec":{"nodeName":"node2"}}, expected {"spec":{"nodeName":"node1"}}`,
+		},
+		{
+			name:     "delete",
+			expected: ExpectDelete(podsResource, "default", "a"),
+			action:   NewDeleteAction(podsResource, "default", "a"),
+		},
+		{
+			name:     "delete instead of a patch",
+			expected: ExpectPatch(podsResource, "default", "a", types.MergePatchType, []byte(`{}`)),
+			action:   NewDeleteAction(podsResource, "default", "a"),
+			reason:   `verb is "delete"; action is not a patch`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reason := tc.expected.Match(tc.action)
+			if tc.reason == "" && reason != "" {
+				t.Errorf("expected a match, got %q", reason)
+			}
+			if !strings.HasPrefix(reason, tc.reason) {
+				t.Errorf("expected reason %q, got %q", tc.reason, reason)
+			}
+		})
+	}
+}
+
+func TestCheckActions(t *testing.T) {
+	actions := []Action{
+		NewGetAction(podsResource, "default", "a"),
+		NewPatchAction(podsResource, "default", "a", types.MergePatchType, []byte(`{"spec":{"nodeName":"node1"}}`)),
+		NewDeleteAction(podsResource, "default", "b"),
+	}
+
+	if err := CheckActions(actions,
+		ExpectGet(podsResource, "default", "a"),
+		ExpectPatch(podsResource, "default", "a", types.MergePatchType, []byte(`{"spec":{"nodeName":"node1"}}`)),
+		ExpectVerb("delete", "pods"),
+	); err != nil {
+		t.Error(err)
+	}
+
+	err := CheckActions(actions,
+		ExpectGet(podsResource, "default", "a"),
+		ExpectPatch(podsResource, "default", "a", types.MergePatchType, []byte(`{"spec":{"nodeName":"node2"}}`)),
+	)
+	expected := `unexpected actions:
+action 1: expected patch pods default/a with {"spec":{"nodeName":"node2"}}, got patch pods default/a: patch is {"spec":{"nodeName":"node1"}}, expected {"spec":{"nodeName":"node2"}}
+action 2: unexpected delete pods default/b`
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error:\n%s\ngot:\n%v", expected, err)
+	}
+
+	err = CheckActions(actions[:1], ExpectGet(podsResource, "default", "a"), ExpectList(podsResource, "default"))
+	if err == nil || !strings.Contains(err.Error(), "action 1: missing list pods in default") {
+		t.Errorf("expected a missing action, got %v", err)
+	}
+}
+
+func TestCheckActionSubsequence(t *testing.T) {
+	actions := []Action{
+		NewListAction(podsResource, corev1.SchemeGroupVersion.WithKind("Pod"), "default", metav1.ListOptions{}),
+		NewGetAction(podsResource, "default", "a"),
+		NewDeleteAction(podsResource, "default", "a"),
+		NewGetAction(podsResource, "default", "b"),
+		NewDeleteAction(podsResource, "default", "b"),
+	}
+
+	if err := CheckActionSubsequence(actions, ExpectDelete(podsResource, "default", "a"), ExpectDelete(podsResource, "default", "b")); err != nil {
+		t.Error(err)
+	}
+	if err := CheckActionSubsequence(actions, ExpectDelete(podsResource, "default", "b"), ExpectDelete(podsResource, "default", "a")); err == nil || !strings.Contains(err.Error(), "expected delete pods default/a after") {
+		t.Errorf("expected an ordering error, got %v", err)
+	}
+	if deletes := FilterActions(actions, ExpectVerb("delete", "*")); len(deletes) != 2 {
+		t.Errorf("expected 2 deletes, got %d", len(deletes))
+	}
+}
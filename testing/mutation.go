@@ -0,0 +1,117 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/diff"
+)
+
+var _ MutationDetectingTracker = &tracker{}
+
+// MutationDetectingTracker is an ObjectTracker that can detect the mutations
+// of the objects it holds. The trackers returned by NewObjectTracker store
+// deep copies of the objects they are given and return deep copies of the
+// objects they hold, so mutating these objects in place is a bug, for
+// example of a reactor or of a tracker wrapping them, which makes tests
+// depend on their order. The detection of such mutations is turned on with:
+//
+//	client := fake.NewSimpleClientset(objects...)
+//	client.Tracker().(testing.MutationDetectingTracker).SetMutationDetection(true)
+type MutationDetectingTracker interface {
+	ObjectTracker
+
+	// SetMutationDetection turns the detection of mutations on or off; it
+	// is off by default. When on, the tracker keeps a copy of each object it
+	// holds, and its calls fail with an InternalError when an object was
+	// mutated since it was stored.
+	SetMutationDetection(enabled bool)
+
+	// CheckMutations returns an error describing the objects mutated since
+	// they were stored, or nil if there are none or the detection is off.
+	CheckMutations() error
+}
+
+func (t *tracker) SetMutationDetection(enabled bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if !enabled {
+		t.pristine = nil
+		return
+	}
+	t.pristine = make(map[schema.GroupVersionResource]map[types.NamespacedName]runtime.Object)
+	for gvr, objs := range t.objects {
+		for key, obj := range objs {
+			t.storedLocked(gvr, key, obj)
+		}
+	}
+}
+
+func (t *tracker) CheckMutations() error {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return t.checkMutationsLocked()
+}
+
+// storedLocked records a copy of an object stored by the tracker, if the
+// detection of mutations is on.
+func (t *tracker) storedLocked(gvr schema.GroupVersionResource, key types.NamespacedName, obj runtime.Object) {
+	if t.pristine == nil {
+		return
+	}
+	if _, ok := t.pristine[gvr]; !ok {
+		t.pristine[gvr] = make(map[types.NamespacedName]runtime.Object)
+	}
+	t.pristine[gvr][key] = obj.DeepCopyObject()
+}
+
+// removedLocked forgets the copy of an object removed from the tracker.
+func (t *tracker) removedLocked(gvr schema.GroupVersionResource, key types.NamespacedName) {
+	if t.pristine != nil {
+		delete(t.pristine[gvr], key)
+	}
+}
+
+// checkMutationsLocked returns an InternalError describing the objects
+// mutated since they were stored, if any.
+func (t *tracker) checkMutationsLocked() error {
+	if t.pristine == nil {
+		return nil
+	}
+	var mutations []string
+	for gvr, objs := range t.objects {
+		for key, obj := range objs {
+			if pristine := t.pristine[gvr][key]; !equality.Semantic.DeepEqual(pristine, obj) {
+				mutations = append(mutations, fmt.Sprintf("%s %s was mutated:\n%s", gvr.GroupResource(), key, diff.ObjectGoPrintSideBySide(pristine, obj)))
+			}
+		}
+	}
+	if len(mutations) == 0 {
+		return nil
+	}
+	sort.Strings(mutations)
+	return errors.NewInternalError(fmt.Errorf("objects held by the tracker were mutated outside of it: %s", strings.Join(mutations, "\n")))
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func getNodeName(t *testing.T, o ObjectTracker, name string) string {
+	obj, err := o.Get(podsResource, "default", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return obj.(*corev1.Pod).Spec.NodeName
+}
+
+func TestObjectIsolation(t *testing.T) {
+	pod := newPod("a", "node-1")
+	list := &corev1.PodList{Items: []corev1.Pod{*newPod("b", "node-1")}}
+	o := newPodTracker(t, pod)
+	if err := o.Add(list); err != nil {
+		t.Fatal(err)
+	}
+
+	pod.Spec.NodeName = "node-2"
+	list.Items[0].Spec.NodeName = "node-2"
+	obj, err := o.Get(podsResource, "default", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj.(*corev1.Pod).Spec.NodeName = "node-3"
+	listObj, err := o.List(podsResource, corev1.SchemeGroupVersion.WithKind("Pod"), "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range listObj.(*corev1.PodList).Items {
+		listObj.(*corev1.PodList).Items[i].Spec.NodeName = "node-4"
+	}
+
+	for _, name := range []string{"a", "b"} {
+		if nodeName := getNodeName(t, o, name); nodeName != "node-1" {
+			t.Errorf("expected %s to stay on node-1, got %q", name, nodeName)
+		}
+	}
+}
+
+func TestMutationDetection(t *testing.T) {
+	o := newPodTracker(t, newPod("a", "node-1"))
+	o.(MutationDetectingTracker).SetMutationDetection(true)
+	if err := o.(MutationDetectingTracker).CheckMutations(); err != nil {
+		t.Fatal(err)
+	}
+	pod := newPod("a", "node-2")
+	if err := o.Update(podsResource, pod, "default"); err != nil {
+		t.Fatal(err)
+	}
+	pod.Spec.NodeName = "node-3"
+	if nodeName := getNodeName(t, o, "a"); nodeName != "node-2" {
+		t.Errorf("expected a to be on node-2, got %q", nodeName)
+	}
+
+	// Simulate a leaked pointer to an object of the tracker.
+	leaked := o.(*tracker).objects[podsResource][types.NamespacedName{Namespace: "default", Name: "a"}]
+	leaked.(*corev1.Pod).Spec.NodeName = "node-4"
+	if err := o.(MutationDetectingTracker).CheckMutations(); !errors.IsInternalError(err) {
+		t.Errorf("expected the mutation to be detected, got %v", err)
+	}
+	if _, err := o.Get(podsResource, "default", "a"); !errors.IsInternalError(err) {
+		t.Errorf("expected the tracker to fail after the mutation, got %v", err)
+	}
+
+	o.(MutationDetectingTracker).SetMutationDetection(false)
+	if nodeName := getNodeName(t, o, "a"); nodeName != "node-4" {
+		t.Errorf("expected a to be on node-4 without detection, got %q", nodeName)
+	}
+}
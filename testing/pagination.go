@@ -0,0 +1,257 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// continueTokenVersion is the version of the continue tokens returned by the
+// tracker, the same as the apiserver's.
+const continueTokenVersion = "meta.k8s.io/v1"
+
+// continueToken is the decoded continue parameter of a list.
+type continueToken struct {
+	APIVersion string `json:"v"`
+	// ResourceVersion is the resource version of the first page of the list,
+	// 0 if resource version semantics are off.
+	ResourceVersion uint64 `json:"rv"`
+	// Start is the namespace and name of the last object of the previous
+	// page, joined by a slash.
+	Start string `json:"start"`
+}
+
+func encodeContinue(resourceVersion uint64, last types.NamespacedName) (string, error) {
+	out, err := json.Marshal(&continueToken{
+		APIVersion:      continueTokenVersion,
+		ResourceVersion: resourceVersion,
+		Start:           last.Namespace + "/" + last.Name,
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(out), nil
+}
+
+func decodeContinue(continueValue string) (*continueToken, types.NamespacedName, error) {
+	data, err := base64.RawURLEncoding.DecodeString(continueValue)
+	if err != nil {
+		return nil, types.NamespacedName{}, fmt.Errorf("continue key is not valid: %v", err)
+	}
+	token := &continueToken{}
+	if err := json.Unmarshal(data, token); err != nil {
+		return nil, types.NamespacedName{}, fmt.Errorf("continue key is not valid: %v", err)
+	}
+	if token.APIVersion != continueTokenVersion {
+		return nil, types.NamespacedName{}, fmt.Errorf("continue key is not valid: server does not recognize this encoding")
+	}
+	parts := strings.SplitN(token.Start, "/", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, types.NamespacedName{}, fmt.Errorf("continue key is not valid: invalid start %q", token.Start)
+	}
+	return token, types.NamespacedName{Namespace: parts[0], Name: parts[1]}, nil
+}
+
+// snapshotLister is implemented by trackers able to list the objects as they
+// were at a resource version, to serve the pages of a list consistently.
+type snapshotLister interface {
+	listAt(gvr schema.GroupVersionResource, gvk schema.GroupVersionKind, ns string, resourceVersion uint64) (runtime.Object, error)
+}
+
+var _ snapshotLister = &tracker{}
+
+// listAt lists the objects of gvr in ns as they were at resourceVersion. It
+// lists the current objects if resource version semantics are off.
+func (t *tracker) listAt(gvr schema.GroupVersionResource, gvk schema.GroupVersionKind, ns string, resourceVersion uint64) (runtime.Object, error) {
+	list, err := t.newList(gvk)
+	if err != nil {
+		return nil, err
+	}
+
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if !t.resourceVersions {
+		objs, err := filterByNamespace(t.objects[gvr], ns)
+		if err != nil {
+			return nil, err
+		}
+		return setListItems(list, objs)
+	}
+
+	snapshot, _, err := t.objectsAtLocked(gvr, ns, resourceVersion)
+	if errors.IsResourceExpired(err) {
+		return nil, errors.NewResourceExpired("The provided continue parameter is too old to display a consistent list result. You can start a new list without the continue parameter.")
+	}
+	if err != nil {
+		return nil, err
+	}
+	objs, err := filterByNamespace(snapshot, ns)
+	if err != nil {
+		return nil, err
+	}
+	list, err = setListItems(list, objs)
+	if err != nil {
+		return nil, err
+	}
+	listMeta, err := meta.ListAccessor(list)
+	if err != nil {
+		return nil, err
+	}
+	listMeta.SetResourceVersion(strconv.FormatUint(resourceVersion, 10))
+	return list, nil
+}
+
+func setListItems(list runtime.Object, objs []runtime.Object) (runtime.Object, error) {
+	items := make([]runtime.Object, 0, len(objs))
+	for _, obj := range objs {
+		items = append(items, obj.DeepCopyObject())
+	}
+	if err := meta.SetList(list, items); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// listForAction lists the objects for a list action, honoring its label and
+// field selectors and its limit and continue parameters. Like the apiserver,
+// the pages following the first one are served from the objects as they
+// were when the first page was listed if the tracker supports it and
+// resource version semantics are on.
+func listForAction(o ObjectTracker, gvr schema.GroupVersionResource, ns string, action ListActionImpl) (runtime.Object, error) {
+	opts := action.GetListOptions()
+
+	var token *continueToken
+	var start types.NamespacedName
+	if opts.Continue != "" {
+		var err error
+		token, start, err = decodeContinue(opts.Continue)
+		if err != nil {
+			return nil, errors.NewBadRequest(err.Error())
+		}
+	}
+
+	var list runtime.Object
+	var err error
+	if lister, ok := o.(snapshotLister); ok && token != nil && token.ResourceVersion > 0 {
+		list, err = lister.listAt(gvr, action.GetKind(), ns, token.ResourceVersion)
+	} else {
+		list, err = o.List(gvr, action.GetKind(), ns)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	restrictions := action.GetListRestrictions()
+	if err := filterListByFieldSelector(gvr.GroupResource(), list, restrictions.Fields); err != nil {
+		return nil, err
+	}
+	if err := filterListByLabelSelector(list, restrictions.Labels); err != nil {
+		return nil, err
+	}
+	if token == nil && opts.Limit <= 0 {
+		return list, nil
+	}
+	return list, paginateList(list, opts.Limit, token, start)
+}
+
+func filterListByLabelSelector(list runtime.Object, selector labels.Selector) error {
+	if selector == nil || selector.Empty() {
+		return nil
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return err
+	}
+	var matching []runtime.Object
+	for _, item := range items {
+		objMeta, err := meta.Accessor(item)
+		if err != nil {
+			return err
+		}
+		if selector.Matches(labels.Set(objMeta.GetLabels())) {
+			matching = append(matching, item)
+		}
+	}
+	return meta.SetList(list, matching)
+}
+
+// paginateList keeps the objects of a list following start, up to limit
+// objects, and sets the continue parameter of the list if more remain.
+func paginateList(list runtime.Object, limit int64, token *continueToken, start types.NamespacedName) error {
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return err
+	}
+	listMeta, err := meta.ListAccessor(list)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]types.NamespacedName, len(items))
+	for i, item := range items {
+		keys[i] = objectKey(item)
+	}
+	first := 0
+	if token != nil {
+		first = sort.Search(len(keys), func(i int) bool {
+			return keyLess(start, keys[i])
+		})
+	}
+	items, keys = items[first:], keys[first:]
+
+	if limit > 0 && int64(len(items)) > limit {
+		var resourceVersion uint64
+		if token != nil {
+			resourceVersion = token.ResourceVersion
+		} else if rv := listMeta.GetResourceVersion(); rv != "" {
+			if resourceVersion, err = strconv.ParseUint(rv, 10, 64); err != nil {
+				return err
+			}
+		}
+		continueValue, err := encodeContinue(resourceVersion, keys[limit-1])
+		if err != nil {
+			return err
+		}
+		remaining := int64(len(items)) - limit
+		items = items[:limit]
+		listMeta.SetContinue(continueValue)
+		listMeta.SetRemainingItemCount(&remaining)
+	}
+	return meta.SetList(list, items)
+}
+
+// keyLess orders objects like the lists of the tracker, by namespace then
+// name.
+func keyLess(a, b types.NamespacedName) bool {
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	return a.Name < b.Name
+}
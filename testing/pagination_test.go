@@ -0,0 +1,130 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func listPodsPage(o ObjectTracker, opts metav1.ListOptions) (*corev1.PodList, error) {
+	action := NewListAction(podsResource, corev1.SchemeGroupVersion.WithKind("Pod"), "default", opts)
+	_, obj, err := ObjectReaction(o)(action)
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*corev1.PodList), nil
+}
+
+func TestListPagination(t *testing.T) {
+	o := newPodTracker(t, newPod("a", "node-1"), newPod("b", "node-2"), newPod("c", "node-1"), newPod("d", "node-1"), newPod("e", "node-1"))
+
+	var pages [][]string
+	var remaining []int64
+	opts := metav1.ListOptions{Limit: 2, FieldSelector: "spec.nodeName=node-1"}
+	for {
+		list, err := listPodsPage(o, opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var names []string
+		for _, pod := range list.Items {
+			names = append(names, pod.Name)
+		}
+		pages = append(pages, names)
+		if list.Continue == "" {
+			if list.RemainingItemCount != nil {
+				t.Errorf("expected no remaining item count on the last page, got %d", *list.RemainingItemCount)
+			}
+			break
+		}
+		remaining = append(remaining, *list.RemainingItemCount)
+		opts.Continue = list.Continue
+	}
+	if want := [][]string{{"a", "c"}, {"d", "e"}}; !reflect.DeepEqual(pages, want) {
+		t.Errorf("expected pages %v, got %v", want, pages)
+	}
+	if want := []int64{2}; !reflect.DeepEqual(remaining, want) {
+		t.Errorf("expected remaining item counts %v, got %v", want, remaining)
+	}
+
+	list, err := listPodsPage(o, metav1.ListOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list.Items) != 5 || list.Continue != "" {
+		t.Errorf("expected all pods in a single page, got %d pods and continue %q", len(list.Items), list.Continue)
+	}
+}
+
+func TestListPaginationSnapshot(t *testing.T) {
+	o := newPodTracker(t, newPod("a", ""), newPod("b", ""), newPod("c", ""))
+	o.(ResourceVersionTracker).SetResourceVersionSemantics(true)
+
+	first, err := listPodsPage(o, metav1.ListOptions{Limit: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.Delete(podsResource, "default", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Create(podsResource, newPod("bb", ""), "default"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The following pages are served from the objects as they were when the
+	// first page was listed.
+	rest, err := listPodsPage(o, metav1.ListOptions{Limit: 10, Continue: first.Continue})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, pod := range rest.Items {
+		names = append(names, pod.Name)
+	}
+	if want := []string{"b", "c"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("expected pods %v, got %v", want, names)
+	}
+	if rest.ResourceVersion != first.ResourceVersion {
+		t.Errorf("expected resource version %q, got %q", first.ResourceVersion, rest.ResourceVersion)
+	}
+
+	// Once the changes since the first page are no longer known, the list
+	// must be restarted.
+	for i := 0; i <= maxTrackedEvents; i++ {
+		if err := o.Update(podsResource, newPod("a", fmt.Sprintf("node-%d", i)), "default"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := listPodsPage(o, metav1.ListOptions{Limit: 10, Continue: first.Continue}); !errors.IsResourceExpired(err) {
+		t.Errorf("expected an Expired error, got %v", err)
+	}
+}
+
+func TestListInvalidContinue(t *testing.T) {
+	o := newPodTracker(t, newPod("a", ""))
+	for _, continueValue := range []string{"%%%", "e30", "eyJ2IjoibWV0YS5rOHMuaW8vdjEiLCJydiI6MCwic3RhcnQiOiIifQ"} {
+		if _, err := listPodsPage(o, metav1.ListOptions{Continue: continueValue}); !errors.IsBadRequest(err) {
+			t.Errorf("expected a BadRequest error for continue %q, got %v", continueValue, err)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func newPodWithContainers(name string, images map[string]string, finalizers ...string) *corev1.Pod {
+	pod := newPod(name, "node1")
+	pod.Finalizers = finalizers
+	for _, container := range []string{"a", "b", "c"} {
+		if image, ok := images[container]; ok {
+			pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: container, Image: image})
+		}
+	}
+	pod.Spec.Tolerations = []corev1.Toleration{{Key: "a"}, {Key: "b"}}
+	return pod
+}
+
+func TestStrategicMergePatch(t *testing.T) {
+	testCases := []struct {
+		name  string
+		patch string
+
+		expectedImages      map[string]string
+		expectedFinalizers  []string
+		expectedTolerations []string
+	}{
+		{
+			name:                "merge list by key",
+			patch:               `{"spec":{"containers":[{"name":"a","image":"a:2"}]}}`,
+			expectedImages:      map[string]string{"a": "a:2", "b": "b:1"},
+			expectedFinalizers:  []string{"x", "y"},
+			expectedTolerations: []string{"a", "b"},
+		},
+		{
+			name:                "add to list by key",
+			patch:               `{"spec":{"containers":[{"name":"c","image":"c:1"}]}}`,
+			expectedImages:      map[string]string{"a": "a:1", "b": "b:1", "c": "c:1"},
+			expectedFinalizers:  []string{"x", "y"},
+			expectedTolerations: []string{"a", "b"},
+		},
+		{
+			name:                "delete from list by key",
+			patch:               `{"spec":{"containers":[{"name":"a","$patch":"delete"}]}}`,
+			expectedImages:      map[string]string{"b": "b:1"},
+			expectedFinalizers:  []string{"x", "y"},
+			expectedTolerations: []string{"a", "b"},
+		},
+		{
+			name:                "delete from primitive list",
+			patch:               `{"metadata":{"$deleteFromPrimitiveList/finalizers":["x"]}}`,
+			expectedImages:      map[string]string{"a": "a:1", "b": "b:1"},
+			expectedFinalizers:  []string{"y"},
+			expectedTolerations: []string{"a", "b"},
+		},
+		{
+			name:                "replace list without patch strategy",
+			patch:               `{"spec":{"tolerations":[{"key":"c"}]}}`,
+			expectedImages:      map[string]string{"a": "a:1", "b": "b:1"},
+			expectedFinalizers:  []string{"x", "y"},
+			expectedTolerations: []string{"c"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, unstructuredPod := range []bool{false, true} {
+				var pod runtime.Object = newPodWithContainers("pod", map[string]string{"a": "a:1", "b": "b:1"}, "x", "y")
+				if unstructuredPod {
+					content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pod)
+					if err != nil {
+						t.Fatal(err)
+					}
+					u := &unstructured.Unstructured{Object: content}
+					u.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Pod"))
+					pod = u
+				}
+				o := newPodTracker(t)
+				if err := o.Add(pod); err != nil {
+					t.Fatal(err)
+				}
+
+				action := NewPatchAction(podsResource, "default", "pod", types.StrategicMergePatchType, []byte(tc.patch))
+				_, obj, err := ObjectReaction(o)(action)
+				if err != nil {
+					t.Fatalf("unstructured %v: %v", unstructuredPod, err)
+				}
+				patched := &corev1.Pod{}
+				if u, ok := obj.(*unstructured.Unstructured); ok {
+					if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, patched); err != nil {
+						t.Fatal(err)
+					}
+				} else {
+					patched = obj.(*corev1.Pod)
+				}
+
+				images := map[string]string{}
+				for _, container := range patched.Spec.Containers {
+					images[container.Name] = container.Image
+				}
+				var tolerations []string
+				for _, toleration := range patched.Spec.Tolerations {
+					tolerations = append(tolerations, toleration.Key)
+				}
+				if !reflect.DeepEqual(images, tc.expectedImages) {
+					t.Errorf("unstructured %v: expected images %v, got %v", unstructuredPod, tc.expectedImages, images)
+				}
+				if !reflect.DeepEqual(patched.Finalizers, tc.expectedFinalizers) {
+					t.Errorf("unstructured %v: expected finalizers %v, got %v", unstructuredPod, tc.expectedFinalizers, patched.Finalizers)
+				}
+				if !reflect.DeepEqual(tolerations, tc.expectedTolerations) {
+					t.Errorf("unstructured %v: expected tolerations %v, got %v", unstructuredPod, tc.expectedTolerations, tolerations)
+				}
+			}
+		})
+	}
+}
+
+func TestStrategicMergePatchUnstructured(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"})
+	obj.SetNamespace("default")
+	obj.SetName("widget")
+	o := newPodTracker(t)
+	if err := o.Create(gvr, obj, "default"); err != nil {
+		t.Fatal(err)
+	}
+
+	patch := []byte(`{"spec":{"size":2}}`)
+	_, patched, err := ObjectReaction(o)(NewPatchAction(gvr, "default", "widget", types.StrategicMergePatchType, patch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size, _, _ := unstructured.NestedInt64(patched.(*unstructured.Unstructured).Object, "spec", "size"); size != 2 {
+		t.Errorf("expected size 2, got %d", size)
+	}
+}
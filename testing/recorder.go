@@ -0,0 +1,439 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// redacted replaces the values of sensitive headers in recordings.
+const redacted = "REDACTED"
+
+// sensitiveFields are the fields of objects, by kind, redacted by
+// DefaultSanitizer.
+var sensitiveFields = map[string][][]string{
+	"Secret":         {{"data"}, {"stringData"}},
+	"TokenRequest":   {{"status", "token"}},
+	"TokenReview":    {{"spec", "token"}},
+	"ExecCredential": {{"status", "token"}, {"status", "clientKeyData"}},
+}
+
+// sensitivePaths are the path fragments of requests whose bodies are fully
+// redacted by DefaultSanitizer when they cannot be parsed as JSON, like
+// protobuf ones.
+var sensitivePaths = []string{"/secrets", "/token"}
+
+// sensitiveHeaders are the headers redacted by DefaultSanitizer.
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Remote-User",
+	"X-Remote-Group",
+}
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a recorded HTTP request. The URL only holds the path
+// and query of the request, so that recordings can be replayed against any
+// server.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	RecordedBody
+}
+
+// RecordedResponse is a recorded HTTP response.
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	RecordedBody
+}
+
+// RecordedBody is the body of a recorded request or response. Bodies that
+// are not valid UTF-8, like protobuf ones, are kept base64-encoded.
+type RecordedBody struct {
+	Body       string `json:"body,omitempty"`
+	BodyBase64 []byte `json:"bodyBase64,omitempty"`
+}
+
+func newRecordedBody(data []byte) RecordedBody {
+	if utf8.Valid(data) {
+		return RecordedBody{Body: string(data)}
+	}
+	return RecordedBody{BodyBase64: data}
+}
+
+// Bytes returns the content of the body.
+func (b RecordedBody) Bytes() []byte {
+	if b.BodyBase64 != nil {
+		return b.BodyBase64
+	}
+	return []byte(b.Body)
+}
+
+// Sanitizer removes sensitive data from an interaction before it is saved.
+type Sanitizer func(*Interaction)
+
+// DefaultSanitizer redacts the credentials of requests and responses, like
+// their Authorization and Cookie headers, the data of Secrets and the tokens
+// of TokenRequests, TokenReviews and ExecCredentials. The bodies of requests
+// to secrets and tokens that are not JSON are redacted as a whole.
+func DefaultSanitizer(interaction *Interaction) {
+	path := interaction.Request.URL
+	if i := strings.Index(path, "?"); i >= 0 {
+		path = path[:i]
+	}
+	interaction.Request.RecordedBody = redactBody(path, interaction.Request.RecordedBody)
+	interaction.Response.RecordedBody = redactBody(path, interaction.Response.RecordedBody)
+
+	for _, header := range []http.Header{interaction.Request.Header, interaction.Response.Header} {
+		for _, key := range sensitiveHeaders {
+			if _, ok := header[key]; ok {
+				header.Set(key, redacted)
+			}
+		}
+		for key := range header {
+			if strings.HasPrefix(key, "Impersonate-") {
+				header.Set(key, redacted)
+			}
+		}
+	}
+}
+
+// redactBody returns body with the sensitive fields of the objects it holds
+// redacted. Bodies are left untouched when nothing is redacted, so that they
+// keep their formatting.
+func redactBody(path string, body RecordedBody) RecordedBody {
+	if body.BodyBase64 == nil && body.Body == "" {
+		return body
+	}
+	if body.BodyBase64 == nil {
+		// Watch responses hold a stream of JSON objects.
+		var values []interface{}
+		decoder := json.NewDecoder(strings.NewReader(body.Body))
+		decoder.UseNumber()
+		var err error
+		for {
+			var value interface{}
+			if err = decoder.Decode(&value); err != nil {
+				break
+			}
+			values = append(values, value)
+		}
+		if err == io.EOF {
+			changed := false
+			for _, value := range values {
+				if redactValue(value) {
+					changed = true
+				}
+			}
+			if !changed {
+				return body
+			}
+			var buf bytes.Buffer
+			encoder := json.NewEncoder(&buf)
+			for _, value := range values {
+				if err := encoder.Encode(value); err != nil {
+					return RecordedBody{Body: redacted}
+				}
+			}
+			return RecordedBody{Body: strings.TrimSuffix(buf.String(), "\n")}
+		}
+	}
+	for _, fragment := range sensitivePaths {
+		if strings.Contains(path, fragment) {
+			return RecordedBody{Body: redacted}
+		}
+	}
+	return body
+}
+
+// redactValue redacts the sensitive fields of a decoded JSON object, and of
+// the objects of lists and watch events it holds. It returns whether
+// anything was redacted.
+func redactValue(value interface{}) bool {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	changed := false
+	if kind, ok := object["kind"].(string); ok {
+		for _, field := range sensitiveFields[kind] {
+			if redactField(object, field) {
+				changed = true
+			}
+		}
+	}
+	if items, ok := object["items"].([]interface{}); ok {
+		for _, item := range items {
+			if redactValue(item) {
+				changed = true
+			}
+		}
+	}
+	if redactValue(object["object"]) {
+		changed = true
+	}
+	return changed
+}
+
+func redactField(object map[string]interface{}, field []string) bool {
+	for _, name := range field[:len(field)-1] {
+		next, ok := object[name].(map[string]interface{})
+		if !ok {
+			return false
+		}
+		object = next
+	}
+	name := field[len(field)-1]
+	value, ok := object[name]
+	if !ok || value == nil {
+		return false
+	}
+	if values, ok := value.(map[string]interface{}); ok {
+		for key := range values {
+			values[key] = redacted
+		}
+		return len(values) > 0
+	}
+	object[name] = redacted
+	return true
+}
+
+// RecordingRoundTripper records the requests made through it and their
+// responses, so that they can be replayed by a ReplayingRoundTripper. It can
+// wrap the transport of a rest.Config:
+//
+//	recorder := testing.NewRecordingRoundTripper(nil)
+//	config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+//		recorder.Delegate = rt
+//		return recorder
+//	})
+//	...
+//	err := recorder.Save("testdata/interactions.json")
+type RecordingRoundTripper struct {
+	// Delegate makes the requests, defaults to http.DefaultTransport.
+	Delegate http.RoundTripper
+	// Sanitizers are applied to interactions when they are saved. Defaults
+	// to DefaultSanitizer.
+	Sanitizers []Sanitizer
+
+	lock         sync.Mutex
+	interactions []*Interaction
+}
+
+var _ http.RoundTripper = &RecordingRoundTripper{}
+
+// NewRecordingRoundTripper returns a RecordingRoundTripper making requests
+// with delegate.
+func NewRecordingRoundTripper(delegate http.RoundTripper) *RecordingRoundTripper {
+	return &RecordingRoundTripper{
+		Delegate:   delegate,
+		Sanitizers: []Sanitizer{DefaultSanitizer},
+	}
+}
+
+// RoundTrip makes the request and records it with its response. The body of
+// the response is recorded as it is read, so the interactions of streamed
+// responses, like watches, hold what was read when they are saved.
+func (r *RecordingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	interaction := &Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.RequestURI(),
+			Header: req.Header.Clone(),
+		},
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		interaction.Request.RecordedBody = newRecordedBody(body)
+		req = req.Clone(req.Context())
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	delegate := r.Delegate
+	if delegate == nil {
+		delegate = http.DefaultTransport
+	}
+	resp, err := delegate.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	r.lock.Lock()
+	interaction.Response = RecordedResponse{StatusCode: resp.StatusCode, Header: resp.Header.Clone()}
+	r.interactions = append(r.interactions, interaction)
+	r.lock.Unlock()
+	if resp.Body != nil {
+		resp.Body = &recordingBody{ReadCloser: resp.Body, recorder: r, response: &interaction.Response}
+	}
+	return resp, nil
+}
+
+// Interactions returns sanitized copies of the recorded interactions.
+func (r *RecordingRoundTripper) Interactions() []Interaction {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	interactions := make([]Interaction, 0, len(r.interactions))
+	for _, recorded := range r.interactions {
+		interaction := *recorded
+		interaction.Request.Header = recorded.Request.Header.Clone()
+		interaction.Response.Header = recorded.Response.Header.Clone()
+		for _, sanitize := range r.Sanitizers {
+			sanitize(&interaction)
+		}
+		interactions = append(interactions, interaction)
+	}
+	return interactions
+}
+
+// Save writes the sanitized interactions to a file, which can be loaded by
+// NewReplayingRoundTripper.
+func (r *RecordingRoundTripper) Save(path string) error {
+	data, err := json.MarshalIndent(r.Interactions(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// recordingBody records the body of a response as it is read.
+type recordingBody struct {
+	io.ReadCloser
+	recorder *RecordingRoundTripper
+	response *RecordedResponse
+	data     []byte
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.data = append(b.data, p[:n]...)
+		b.recorder.lock.Lock()
+		b.response.RecordedBody = newRecordedBody(b.data)
+		b.recorder.lock.Unlock()
+	}
+	return n, err
+}
+
+// ReplayingRoundTripper serves recorded responses instead of making requests.
+// A request is served the response of the first interaction not served yet
+// with the same method, URL and body. Bodies are also compared once redacted
+// like DefaultSanitizer does, so that requests holding credentials match
+// their sanitized recordings. Requests without matching interaction fail.
+type ReplayingRoundTripper struct {
+	lock         sync.Mutex
+	interactions []Interaction
+	served       []bool
+}
+
+var _ http.RoundTripper = &ReplayingRoundTripper{}
+
+// NewReplayingRoundTripper returns a ReplayingRoundTripper serving the
+// interactions saved to a file by RecordingRoundTripper.Save.
+func NewReplayingRoundTripper(path string) (*ReplayingRoundTripper, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var interactions []Interaction
+	if err := json.Unmarshal(data, &interactions); err != nil {
+		return nil, fmt.Errorf("failed to load interactions from %s: %v", path, err)
+	}
+	return NewReplayingRoundTripperFromInteractions(interactions), nil
+}
+
+// NewReplayingRoundTripperFromInteractions returns a ReplayingRoundTripper
+// serving the given interactions.
+func NewReplayingRoundTripperFromInteractions(interactions []Interaction) *ReplayingRoundTripper {
+	return &ReplayingRoundTripper{
+		interactions: interactions,
+		served:       make([]bool, len(interactions)),
+	}
+}
+
+// RoundTrip serves the recorded response of the request.
+func (r *ReplayingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	redactedBody := redactBody(req.URL.Path, newRecordedBody(body)).Bytes()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for i, interaction := range r.interactions {
+		if r.served[i] || interaction.Request.Method != req.Method || interaction.Request.URL != req.URL.RequestURI() {
+			continue
+		}
+		if recorded := interaction.Request.Bytes(); !bytes.Equal(recorded, body) && !bytes.Equal(recorded, redactedBody) {
+			continue
+		}
+		r.served[i] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        interaction.Response.Header.Clone(),
+			Body:          ioutil.NopCloser(bytes.NewReader(interaction.Response.Bytes())),
+			ContentLength: int64(len(interaction.Response.Bytes())),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("no recorded interaction for %s %s", req.Method, req.URL.RequestURI())
+}
+
+// Unserved returns the interactions that were not served yet, which is
+// useful to check that a test made all the recorded requests.
+func (r *ReplayingRoundTripper) Unserved() []Interaction {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	var unserved []Interaction
+	for i, interaction := range r.interactions {
+		if !r.served[i] {
+			unserved = append(unserved, interaction)
+		}
+	}
+	return unserved
+}
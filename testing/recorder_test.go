@@ -0,0 +1,200 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func doRequest(t *testing.T, client *http.Client, method, url, body string) (int, string) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(data)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if req.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+			w.Write(body)
+			return
+		}
+		w.Write([]byte(`{"kind":"Pod","metadata":{"name":"a"}}`))
+	}))
+	defer server.Close()
+
+	recorder := NewRecordingRoundTripper(nil)
+	client := &http.Client{Transport: recorder}
+	doRequest(t, client, http.MethodGet, server.URL+"/api/v1/namespaces/default/pods/a", "")
+	doRequest(t, client, http.MethodPost, server.URL+"/api/v1/namespaces/default/pods", `{"metadata":{"name":"b"}}`)
+
+	path := filepath.Join(t.TempDir(), "interactions.json")
+	if err := recorder.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(saved), "secret") || !strings.Contains(string(saved), redacted) {
+		t.Errorf("expected the credentials to be redacted, got %s", saved)
+	}
+	if strings.Contains(string(saved), server.URL) {
+		t.Errorf("expected the server address not to be recorded, got %s", saved)
+	}
+
+	replayer, err := NewReplayingRoundTripper(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = &http.Client{Transport: replayer}
+	const host = "https://replay.invalid"
+	if code, body := doRequest(t, client, http.MethodPost, host+"/api/v1/namespaces/default/pods", `{"metadata":{"name":"b"}}`); code != http.StatusCreated || body != `{"metadata":{"name":"b"}}` {
+		t.Errorf("unexpected replayed response %d %s", code, body)
+	}
+	if unserved := replayer.Unserved(); len(unserved) != 1 || unserved[0].Request.Method != http.MethodGet {
+		t.Errorf("expected the GET request to be left, got %v", unserved)
+	}
+	if code, body := doRequest(t, client, http.MethodGet, host+"/api/v1/namespaces/default/pods/a", ""); code != http.StatusOK || body != `{"kind":"Pod","metadata":{"name":"a"}}` {
+		t.Errorf("unexpected replayed response %d %s", code, body)
+	}
+
+	// Each interaction is served once.
+	req, _ := http.NewRequest(http.MethodGet, host+"/api/v1/namespaces/default/pods/a", nil)
+	if _, err := client.Do(req); err == nil {
+		t.Error("expected an error for a request without interaction left")
+	}
+}
+
+func TestReplayMissingFile(t *testing.T) {
+	if _, err := NewReplayingRoundTripper(filepath.Join(t.TempDir(), "missing.json")); !os.IsNotExist(err) {
+		t.Errorf("expected a not exist error, got %v", err)
+	}
+}
+
+func TestDefaultSanitizerRedactsBodies(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		body     RecordedBody
+		expected RecordedBody
+	}{
+		{
+			name:     "secret",
+			url:      "/api/v1/namespaces/default/secrets/a",
+			body:     RecordedBody{Body: `{"kind":"Secret","data":{"password":"c2VjcmV0"},"stringData":{"user":"secret"}}`},
+			expected: RecordedBody{Body: `{"data":{"password":"REDACTED"},"kind":"Secret","stringData":{"user":"REDACTED"}}`},
+		},
+		{
+			name:     "secret list",
+			url:      "/api/v1/namespaces/default/secrets",
+			body:     RecordedBody{Body: `{"kind":"SecretList","items":[{"kind":"Secret","data":{"password":"c2VjcmV0"}}]}`},
+			expected: RecordedBody{Body: `{"items":[{"data":{"password":"REDACTED"},"kind":"Secret"}],"kind":"SecretList"}`},
+		},
+		{
+			name: "secret watch",
+			url:  "/api/v1/namespaces/default/secrets",
+			body: RecordedBody{Body: `{"type":"ADDED","object":{"kind":"Secret","data":{"password":"c2VjcmV0"}}}
+{"type":"DELETED","object":{"kind":"Secret","data":{"password":"c2VjcmV0"}}}`},
+			expected: RecordedBody{Body: `{"object":{"data":{"password":"REDACTED"},"kind":"Secret"},"type":"ADDED"}
+{"object":{"data":{"password":"REDACTED"},"kind":"Secret"},"type":"DELETED"}`},
+		},
+		{
+			name:     "token request",
+			url:      "/api/v1/namespaces/default/serviceaccounts/a/token",
+			body:     RecordedBody{Body: `{"kind":"TokenRequest","status":{"token":"secret","expirationTimestamp":"2021-01-01T00:00:00Z"}}`},
+			expected: RecordedBody{Body: `{"kind":"TokenRequest","status":{"expirationTimestamp":"2021-01-01T00:00:00Z","token":"REDACTED"}}`},
+		},
+		{
+			name:     "token review",
+			url:      "/apis/authentication.k8s.io/v1/tokenreviews",
+			body:     RecordedBody{Body: `{"kind":"TokenReview","spec":{"token":"secret"}}`},
+			expected: RecordedBody{Body: `{"kind":"TokenReview","spec":{"token":"REDACTED"}}`},
+		},
+		{
+			name:     "exec credential",
+			url:      "/credentials",
+			body:     RecordedBody{Body: `{"kind":"ExecCredential","status":{"token":"secret","clientKeyData":"secret"}}`},
+			expected: RecordedBody{Body: `{"kind":"ExecCredential","status":{"clientKeyData":"REDACTED","token":"REDACTED"}}`},
+		},
+		{
+			name:     "protobuf secret",
+			url:      "/api/v1/namespaces/default/secrets/a",
+			body:     RecordedBody{BodyBase64: []byte{0x6b, 0x38, 0x73, 0x00, 0xff}},
+			expected: RecordedBody{Body: redacted},
+		},
+		{
+			name:     "pod",
+			url:      "/api/v1/namespaces/default/pods/a",
+			body:     RecordedBody{Body: `{"kind":"Pod", "metadata":{"name":"a"}}`},
+			expected: RecordedBody{Body: `{"kind":"Pod", "metadata":{"name":"a"}}`},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			interaction := &Interaction{
+				Request:  RecordedRequest{Method: http.MethodGet, URL: test.url + "?watch=true"},
+				Response: RecordedResponse{StatusCode: http.StatusOK, RecordedBody: test.body},
+			}
+			DefaultSanitizer(interaction)
+			if !reflect.DeepEqual(interaction.Response.RecordedBody, test.expected) {
+				t.Errorf("expected body %#v, got %#v", test.expected, interaction.Response.RecordedBody)
+			}
+		})
+	}
+}
+
+func TestReplayRedactedRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	const secret = `{"kind":"Secret","metadata":{"name":"a"},"data":{"password":"c2VjcmV0"}}`
+	recorder := NewRecordingRoundTripper(nil)
+	doRequest(t, &http.Client{Transport: recorder}, http.MethodPost, server.URL+"/api/v1/namespaces/default/secrets", secret)
+	interactions := recorder.Interactions()
+	if strings.Contains(interactions[0].Request.Body, "c2VjcmV0") {
+		t.Fatalf("expected the secret data to be redacted, got %s", interactions[0].Request.Body)
+	}
+
+	replayer := NewReplayingRoundTripperFromInteractions(interactions)
+	if code, _ := doRequest(t, &http.Client{Transport: replayer}, http.MethodPost, "https://replay.invalid/api/v1/namespaces/default/secrets", secret); code != http.StatusCreated {
+		t.Errorf("expected the redacted request to be replayed, got %d", code)
+	}
+}
@@ -0,0 +1,354 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Subresources describes the subresources of a resource, like the
+// subresources of a custom resource definition.
+type Subresources struct {
+	// Status is true if the resource has a status subresource. Then, like
+	// the apiserver, the tracker resets the status of created objects,
+	// keeps the status of objects when they are updated, and only updates
+	// their status when the status subresource is updated.
+	Status bool
+	// Scale describes the scale subresource of the resource. If nil, the
+	// paths of the built-in resources are used.
+	Scale *ScaleSubresource
+}
+
+// ScaleSubresource describes how the scale subresource of a resource maps
+// to the fields of its objects. Paths are JSON paths like ".spec.replicas".
+type ScaleSubresource struct {
+	SpecReplicasPath   string
+	StatusReplicasPath string
+	// LabelSelectorPath is the path of the label selector of the objects,
+	// either a metav1.LabelSelector, a map of labels or a serialized
+	// selector. Optional.
+	LabelSelectorPath string
+}
+
+// defaultScaleSubresource is the scale subresource of the built-in
+// resources.
+var defaultScaleSubresource = ScaleSubresource{
+	SpecReplicasPath:   ".spec.replicas",
+	StatusReplicasPath: ".status.replicas",
+	LabelSelectorPath:  ".spec.selector",
+}
+
+var _ SubresourceTracker = &tracker{}
+
+// SubresourceTracker is an ObjectTracker that can simulate the status and
+// scale subresources of resources. The trackers returned by
+// NewObjectTracker implement it, for example:
+//
+//	client := fake.NewSimpleClientset(objects...)
+//	client.Tracker().(testing.SubresourceTracker).SetSubresources(deploymentsResource, testing.Subresources{Status: true})
+type SubresourceTracker interface {
+	ObjectTracker
+
+	// SetSubresources sets the subresources of a resource. By default,
+	// resources have no status subresource, so updates change whole objects,
+	// and their scale subresource maps to the fields of the built-in
+	// resources.
+	SetSubresources(gvr schema.GroupVersionResource, subresources Subresources)
+}
+
+func (t *tracker) SetSubresources(gvr schema.GroupVersionResource, subresources Subresources) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.subresources[gvr] = subresources
+}
+
+func subresourcesFor(o ObjectTracker, gvr schema.GroupVersionResource) Subresources {
+	t, ok := o.(*tracker)
+	if !ok {
+		return Subresources{}
+	}
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return t.subresources[gvr]
+}
+
+// resetStatus returns obj without status if gvr has a status subresource.
+func resetStatus(o ObjectTracker, gvr schema.GroupVersionResource, obj runtime.Object) (runtime.Object, error) {
+	if !subresourcesFor(o, gvr).Status {
+		return obj, nil
+	}
+	content, err := toUnstructuredContent(obj)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := content["status"]; !ok {
+		return obj, nil
+	}
+	delete(content, "status")
+	return fromUnstructuredContent(content, obj)
+}
+
+// withSubresourceSemantics returns the object to store when updating obj
+// through subresource, or the main resource if subresource is empty. If gvr
+// has a status subresource, it is the existing object with the status of obj
+// for status updates, and obj with the status of the existing object for
+// main resource updates.
+func withSubresourceSemantics(o ObjectTracker, gvr schema.GroupVersionResource, ns, subresource string, obj runtime.Object) (runtime.Object, error) {
+	if (subresource != "" && subresource != "status") || !subresourcesFor(o, gvr).Status {
+		return obj, nil
+	}
+	objMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+	if objMeta.GetNamespace() != "" {
+		ns = objMeta.GetNamespace()
+	}
+	existing, err := o.Get(gvr, ns, objMeta.GetName())
+	if errors.IsNotFound(err) {
+		// Let the update fail.
+		return obj, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := toUnstructuredContent(obj)
+	if err != nil {
+		return nil, err
+	}
+	current, err := toUnstructuredContent(existing)
+	if err != nil {
+		return nil, err
+	}
+	result, from := updated, current
+	if subresource == "status" {
+		result, from = current, updated
+		// The resource version of the update is checked against the one of
+		// the existing object.
+		if err := unstructured.SetNestedField(result, objMeta.GetResourceVersion(), "metadata", "resourceVersion"); err != nil {
+			return nil, err
+		}
+	}
+	if status, ok := from["status"]; ok {
+		result["status"] = status
+	} else {
+		delete(result, "status")
+	}
+	return fromUnstructuredContent(result, obj)
+}
+
+// getScale returns the scale subresource of an object.
+func getScale(o ObjectTracker, gvr schema.GroupVersionResource, ns, name string) (runtime.Object, error) {
+	obj, err := o.Get(gvr, ns, name)
+	if err != nil {
+		return nil, err
+	}
+	content, err := toUnstructuredContent(obj)
+	if err != nil {
+		return nil, err
+	}
+	scale := scaleSubresourceFor(o, gvr)
+
+	specReplicas, _, err := unstructured.NestedInt64(content, jsonPathFields(scale.SpecReplicasPath)...)
+	if err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+	statusReplicas, _, err := unstructured.NestedInt64(content, jsonPathFields(scale.StatusReplicasPath)...)
+	if err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+	var selector string
+	var matchLabels map[string]interface{}
+	if scale.LabelSelectorPath != "" {
+		if selector, matchLabels, err = scaleSelector(content, jsonPathFields(scale.LabelSelectorPath)); err != nil {
+			return nil, errors.NewInternalError(err)
+		}
+	}
+
+	scaleGVK := scaleKind(gvr)
+	objMetadata, _, _ := unstructured.NestedMap(content, "metadata")
+	metadata := map[string]interface{}{}
+	for _, key := range []string{"name", "namespace", "uid", "resourceVersion", "creationTimestamp"} {
+		if value, ok := objMetadata[key]; ok && value != nil {
+			metadata[key] = value
+		}
+	}
+	status := map[string]interface{}{"replicas": statusReplicas}
+	if scaleGVK.Group == "autoscaling" {
+		if selector != "" {
+			status["selector"] = selector
+		}
+	} else {
+		if matchLabels != nil {
+			status["selector"] = matchLabels
+		}
+		if selector != "" {
+			status["targetSelector"] = selector
+		}
+	}
+	result := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": metadata,
+		"spec":     map[string]interface{}{"replicas": specReplicas},
+		"status":   status,
+	}}
+	result.SetGroupVersionKind(scaleGVK)
+
+	t, ok := o.(*tracker)
+	if !ok {
+		return result, nil
+	}
+	typed, err := t.scheme.New(scaleGVK)
+	if err != nil {
+		// The scheme does not know the scale type, like the scheme of the
+		// dynamic fake client.
+		return result, nil
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(result.Object, typed); err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+	return typed, nil
+}
+
+// updateScale updates the replicas of an object from its scale subresource
+// and returns the updated scale subresource.
+func updateScale(o ObjectTracker, gvr schema.GroupVersionResource, ns string, scaleObj runtime.Object) (runtime.Object, error) {
+	scaleContent, err := toUnstructuredContent(scaleObj)
+	if err != nil {
+		return nil, err
+	}
+	name, _, _ := unstructured.NestedString(scaleContent, "metadata", "name")
+	if scaleNamespace, _, _ := unstructured.NestedString(scaleContent, "metadata", "namespace"); scaleNamespace != "" {
+		ns = scaleNamespace
+	}
+	replicas, _, err := unstructured.NestedInt64(scaleContent, "spec", "replicas")
+	if err != nil {
+		return nil, errors.NewBadRequest(err.Error())
+	}
+	resourceVersion, _, _ := unstructured.NestedString(scaleContent, "metadata", "resourceVersion")
+
+	obj, err := o.Get(gvr, ns, name)
+	if err != nil {
+		return nil, err
+	}
+	content, err := toUnstructuredContent(obj)
+	if err != nil {
+		return nil, err
+	}
+	if err := unstructured.SetNestedField(content, replicas, jsonPathFields(scaleSubresourceFor(o, gvr).SpecReplicasPath)...); err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+	if resourceVersion != "" {
+		// The resource version of the scale is checked against the one of
+		// the object.
+		if err := unstructured.SetNestedField(content, resourceVersion, "metadata", "resourceVersion"); err != nil {
+			return nil, errors.NewInternalError(err)
+		}
+	}
+	if obj, err = fromUnstructuredContent(content, obj); err != nil {
+		return nil, err
+	}
+	if err := o.Update(gvr, obj, ns); err != nil {
+		return nil, err
+	}
+	return getScale(o, gvr, ns, name)
+}
+
+func scaleSubresourceFor(o ObjectTracker, gvr schema.GroupVersionResource) ScaleSubresource {
+	if scale := subresourcesFor(o, gvr).Scale; scale != nil {
+		return *scale
+	}
+	return defaultScaleSubresource
+}
+
+// scaleKind returns the kind of the scale subresource of the built-in
+// resources, autoscaling/v1 Scale unless the resource is from an old
+// version of the apps or extensions API groups.
+func scaleKind(gvr schema.GroupVersionResource) schema.GroupVersionKind {
+	switch {
+	case gvr.Group == "apps" && (gvr.Version == "v1beta1" || gvr.Version == "v1beta2"):
+		return gvr.GroupVersion().WithKind("Scale")
+	case gvr.Group == "extensions":
+		return gvr.GroupVersion().WithKind("Scale")
+	}
+	return schema.GroupVersionKind{Group: "autoscaling", Version: "v1", Kind: "Scale"}
+}
+
+// scaleSelector returns the serialized label selector at fields of content,
+// and its labels if it only matches labels.
+func scaleSelector(content map[string]interface{}, fields []string) (string, map[string]interface{}, error) {
+	value, found, err := unstructured.NestedFieldNoCopy(content, fields...)
+	if !found || err != nil || value == nil {
+		return "", nil, err
+	}
+	switch value := value.(type) {
+	case string:
+		return value, nil, nil
+	case map[string]interface{}:
+		_, hasMatchLabels := value["matchLabels"]
+		_, hasMatchExpressions := value["matchExpressions"]
+		if !hasMatchLabels && !hasMatchExpressions {
+			set := labels.Set{}
+			for key, label := range value {
+				set[key] = fmt.Sprint(label)
+			}
+			return labels.SelectorFromSet(set).String(), value, nil
+		}
+		labelSelector := &metav1.LabelSelector{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(value, labelSelector); err != nil {
+			return "", nil, err
+		}
+		selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+		if err != nil {
+			return "", nil, err
+		}
+		var matchLabels map[string]interface{}
+		if !hasMatchExpressions {
+			matchLabels, _, _ = unstructured.NestedMap(value, "matchLabels")
+		}
+		return selector.String(), matchLabels, nil
+	}
+	return "", nil, fmt.Errorf("unsupported label selector %v", value)
+}
+
+func jsonPathFields(path string) []string {
+	return strings.Split(strings.TrimPrefix(path, "."), ".")
+}
+
+// fromUnstructuredContent returns an object of the same type as like with the
+// given content.
+func fromUnstructuredContent(content map[string]interface{}, like runtime.Object) (runtime.Object, error) {
+	if _, ok := like.(runtime.Unstructured); ok {
+		return &unstructured.Unstructured{Object: content}, nil
+	}
+	obj := reflect.New(reflect.TypeOf(like).Elem()).Interface().(runtime.Object)
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, obj); err != nil {
+		return nil, err
+	}
+	obj.GetObjectKind().SetGroupVersionKind(like.GetObjectKind().GroupVersionKind())
+	return obj, nil
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	serializer "k8s.io/apimachinery/pkg/runtime/serializer"
+)
+
+var deploymentsResource = appsv1.SchemeGroupVersion.WithResource("deployments")
+
+func TestStatusSubresource(t *testing.T) {
+	o := newPodTracker(t)
+	o.(SubresourceTracker).SetSubresources(podsResource, Subresources{Status: true})
+	react := ObjectReaction(o)
+
+	pod := newPod("a", "node-1")
+	pod.Status.Phase = corev1.PodRunning
+	_, obj, err := react(NewCreateAction(podsResource, "default", pod))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if phase := obj.(*corev1.Pod).Status.Phase; phase != "" {
+		t.Errorf("expected the status to be reset on create, got phase %q", phase)
+	}
+
+	pod = obj.(*corev1.Pod).DeepCopy()
+	pod.Spec.NodeName = "node-2"
+	pod.Status.Phase = corev1.PodRunning
+	if _, obj, err = react(NewUpdateAction(podsResource, "default", pod)); err != nil {
+		t.Fatal(err)
+	}
+	if updated := obj.(*corev1.Pod); updated.Spec.NodeName != "node-2" || updated.Status.Phase != "" {
+		t.Errorf("expected an update to change the spec but not the status, got %#v", updated)
+	}
+
+	pod = obj.(*corev1.Pod).DeepCopy()
+	pod.Spec.NodeName = "node-3"
+	pod.Status.Phase = corev1.PodSucceeded
+	if _, obj, err = react(NewUpdateSubresourceAction(podsResource, "status", "default", pod)); err != nil {
+		t.Fatal(err)
+	}
+	if updated := obj.(*corev1.Pod); updated.Spec.NodeName != "node-2" || updated.Status.Phase != corev1.PodSucceeded {
+		t.Errorf("expected a status update to change the status but not the spec, got %#v", updated)
+	}
+
+	// Without status subresource, updates change whole objects.
+	o.(SubresourceTracker).SetSubresources(podsResource, Subresources{})
+	pod = obj.(*corev1.Pod).DeepCopy()
+	pod.Status.Phase = corev1.PodFailed
+	if _, obj, err = react(NewUpdateAction(podsResource, "default", pod)); err != nil {
+		t.Fatal(err)
+	}
+	if phase := obj.(*corev1.Pod).Status.Phase; phase != corev1.PodFailed {
+		t.Errorf("expected the status to be updated, got phase %q", phase)
+	}
+}
+
+func TestScaleSubresource(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := appsv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := autoscalingv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	o := NewObjectTracker(scheme, serializer.NewCodecFactory(scheme).UniversalDecoder())
+	replicas := int32(2)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "d"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "d"}},
+		},
+		Status: appsv1.DeploymentStatus{Replicas: 1},
+	}
+	if err := o.Add(deployment); err != nil {
+		t.Fatal(err)
+	}
+	react := ObjectReaction(o)
+
+	_, obj, err := react(NewGetSubresourceAction(deploymentsResource, "default", "scale", "d"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scale, ok := obj.(*autoscalingv1.Scale)
+	if !ok {
+		t.Fatalf("expected a Scale, got %T", obj)
+	}
+	if scale.Name != "d" || scale.Spec.Replicas != 2 || scale.Status.Replicas != 1 || scale.Status.Selector != "app=d" {
+		t.Errorf("unexpected scale: %#v", scale)
+	}
+
+	scale.Spec.Replicas = 5
+	if _, obj, err = react(NewUpdateSubresourceAction(deploymentsResource, "scale", "default", scale)); err != nil {
+		t.Fatal(err)
+	}
+	if replicas := obj.(*autoscalingv1.Scale).Spec.Replicas; replicas != 5 {
+		t.Errorf("expected the updated scale to have 5 replicas, got %d", replicas)
+	}
+	obj, err = o.Get(deploymentsResource, "default", "d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replicas := *obj.(*appsv1.Deployment).Spec.Replicas; replicas != 5 {
+		t.Errorf("expected the deployment to have 5 replicas, got %d", replicas)
+	}
+}
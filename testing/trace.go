@@ -0,0 +1,247 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ReactorOptions holds optional settings of a reactor.
+type ReactorOptions struct {
+	// Name identifies the reactor in reaction traces. Defaults to the verb
+	// and resource of the reactor.
+	Name string
+	// Priority orders the reactors of a chain: reactors with a higher
+	// priority are tried first. Among reactors with the same priority, added
+	// reactors are tried after and prepended reactors before the others.
+	// Reactors added without options have priority 0.
+	Priority int
+}
+
+// ReactionStep records how a reactor of a chain dealt with an action.
+type ReactionStep struct {
+	// Reactor is the name of the reactor.
+	Reactor string
+	// Matched is true if the reactor handles the action's verb and resource.
+	Matched bool
+	// Handled is true if the reactor handled the action, ending the chain.
+	Handled bool
+	// Err is the error returned by the reactor, if any.
+	Err error
+}
+
+// ReactionTrace records the reactors tried for an action.
+type ReactionTrace struct {
+	Action Action
+	Steps  []ReactionStep
+}
+
+// String describes the trace, one reactor per line.
+func (t ReactionTrace) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s", t.Action.GetVerb(), t.Action.GetResource().Resource)
+	if subresource := t.Action.GetSubresource(); subresource != "" {
+		fmt.Fprintf(&b, "/%s", subresource)
+	}
+	for i, step := range t.Steps {
+		outcome := "skipped"
+		switch {
+		case step.Handled:
+			outcome = "handled"
+		case step.Matched:
+			outcome = "passed"
+		}
+		fmt.Fprintf(&b, "\n  %d. %s: %s", i, step.Reactor, outcome)
+		if step.Err != nil {
+			fmt.Fprintf(&b, " (error: %v)", step.Err)
+		}
+	}
+	if len(t.Steps) == 0 || !t.Steps[len(t.Steps)-1].Handled {
+		b.WriteString("\n  not handled")
+	}
+	return b.String()
+}
+
+// reactorInfo is implemented by reactors added with options.
+type reactorInfo interface {
+	reactorOptions() ReactorOptions
+}
+
+var (
+	_ Reactor      = &namedReactor{}
+	_ WatchReactor = &namedWatchReactor{}
+	_ ProxyReactor = &namedProxyReactor{}
+)
+
+type namedReactor struct {
+	Reactor
+	opts ReactorOptions
+}
+
+func (r *namedReactor) reactorOptions() ReactorOptions {
+	return r.opts
+}
+
+type namedWatchReactor struct {
+	WatchReactor
+	opts ReactorOptions
+}
+
+func (r *namedWatchReactor) reactorOptions() ReactorOptions {
+	return r.opts
+}
+
+type namedProxyReactor struct {
+	ProxyReactor
+	opts ReactorOptions
+}
+
+func (r *namedProxyReactor) reactorOptions() ReactorOptions {
+	return r.opts
+}
+
+// reactorName returns the name of a reactor in traces.
+func reactorName(reactor interface{}) string {
+	if info, ok := reactor.(reactorInfo); ok {
+		if name := info.reactorOptions().Name; name != "" {
+			return name
+		}
+		switch r := reactor.(type) {
+		case *namedReactor:
+			reactor = r.Reactor
+		case *namedWatchReactor:
+			reactor = r.WatchReactor
+		case *namedProxyReactor:
+			reactor = r.ProxyReactor
+		}
+	}
+	switch r := reactor.(type) {
+	case *SimpleReactor:
+		return fmt.Sprintf("%s %s", r.Verb, r.Resource)
+	case *SimpleWatchReactor:
+		return fmt.Sprintf("watch %s", r.Resource)
+	case *SimpleProxyReactor:
+		return fmt.Sprintf("proxy %s", r.Resource)
+	}
+	return fmt.Sprintf("%T", reactor)
+}
+
+func reactorPriority(reactor interface{}) int {
+	if info, ok := reactor.(reactorInfo); ok {
+		return info.reactorOptions().Priority
+	}
+	return 0
+}
+
+// reactorIndex returns where to insert a reactor with the given priority in
+// a chain of n reactors, whose priorities are returned by priorityAt.
+func reactorIndex(n int, priorityAt func(i int) int, priority int, prepend bool) int {
+	if prepend {
+		for i := 0; i < n; i++ {
+			if priorityAt(i) <= priority {
+				return i
+			}
+		}
+		return n
+	}
+	for i := n; i > 0; i-- {
+		if priorityAt(i-1) >= priority {
+			return i
+		}
+	}
+	return 0
+}
+
+func (c *Fake) insertReactor(reactor Reactor, priority int, prepend bool) {
+	i := reactorIndex(len(c.ReactionChain), func(i int) int { return reactorPriority(c.ReactionChain[i]) }, priority, prepend)
+	c.ReactionChain = append(c.ReactionChain, nil)
+	copy(c.ReactionChain[i+1:], c.ReactionChain[i:])
+	c.ReactionChain[i] = reactor
+}
+
+func (c *Fake) insertWatchReactor(reactor WatchReactor, priority int, prepend bool) {
+	i := reactorIndex(len(c.WatchReactionChain), func(i int) int { return reactorPriority(c.WatchReactionChain[i]) }, priority, prepend)
+	c.WatchReactionChain = append(c.WatchReactionChain, nil)
+	copy(c.WatchReactionChain[i+1:], c.WatchReactionChain[i:])
+	c.WatchReactionChain[i] = reactor
+}
+
+func (c *Fake) insertProxyReactor(reactor ProxyReactor, priority int, prepend bool) {
+	i := reactorIndex(len(c.ProxyReactionChain), func(i int) int { return reactorPriority(c.ProxyReactionChain[i]) }, priority, prepend)
+	c.ProxyReactionChain = append(c.ProxyReactionChain, nil)
+	copy(c.ProxyReactionChain[i+1:], c.ProxyReactionChain[i:])
+	c.ProxyReactionChain[i] = reactor
+}
+
+// AddReactorWithOptions adds a reactor after the reactors of the chain with
+// the same or a higher priority.
+func (c *Fake) AddReactorWithOptions(verb, resource string, reaction ReactionFunc, opts ReactorOptions) {
+	c.Lock()
+	defer c.Unlock()
+	c.insertReactor(&namedReactor{&SimpleReactor{verb, resource, reaction}, opts}, opts.Priority, false)
+}
+
+// PrependReactorWithOptions adds a reactor before the reactors of the chain
+// with the same or a lower priority.
+func (c *Fake) PrependReactorWithOptions(verb, resource string, reaction ReactionFunc, opts ReactorOptions) {
+	c.Lock()
+	defer c.Unlock()
+	c.insertReactor(&namedReactor{&SimpleReactor{verb, resource, reaction}, opts}, opts.Priority, true)
+}
+
+// AddWatchReactorWithOptions adds a watch reactor after the watch reactors of
+// the chain with the same or a higher priority.
+func (c *Fake) AddWatchReactorWithOptions(resource string, reaction WatchReactionFunc, opts ReactorOptions) {
+	c.Lock()
+	defer c.Unlock()
+	c.insertWatchReactor(&namedWatchReactor{&SimpleWatchReactor{resource, reaction}, opts}, opts.Priority, false)
+}
+
+// PrependWatchReactorWithOptions adds a watch reactor before the watch
+// reactors of the chain with the same or a lower priority.
+func (c *Fake) PrependWatchReactorWithOptions(resource string, reaction WatchReactionFunc, opts ReactorOptions) {
+	c.Lock()
+	defer c.Unlock()
+	c.insertWatchReactor(&namedWatchReactor{&SimpleWatchReactor{resource, reaction}, opts}, opts.Priority, true)
+}
+
+// AddProxyReactorWithOptions adds a proxy reactor after the proxy reactors of
+// the chain with the same or a higher priority.
+func (c *Fake) AddProxyReactorWithOptions(resource string, reaction ProxyReactionFunc, opts ReactorOptions) {
+	c.Lock()
+	defer c.Unlock()
+	c.insertProxyReactor(&namedProxyReactor{&SimpleProxyReactor{resource, reaction}, opts}, opts.Priority, false)
+}
+
+// PrependProxyReactorWithOptions adds a proxy reactor before the proxy
+// reactors of the chain with the same or a lower priority.
+func (c *Fake) PrependProxyReactorWithOptions(resource string, reaction ProxyReactionFunc, opts ReactorOptions) {
+	c.Lock()
+	defer c.Unlock()
+	c.insertProxyReactor(&namedProxyReactor{&SimpleProxyReactor{resource, reaction}, opts}, opts.Priority, true)
+}
+
+// ReactionTraces returns the traces of the reactors tried for the actions
+// called on the fake client, in the same order as Actions.
+func (c *Fake) ReactionTraces() []ReactionTrace {
+	c.RLock()
+	defer c.RUnlock()
+	traces := make([]ReactionTrace, len(c.traces))
+	copy(traces, c.traces)
+	return traces
+}
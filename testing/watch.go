@@ -0,0 +1,269 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// maxTrackedEvents is the number of events kept for each resource to serve
+// watches starting from a resource version. Watches starting from an older
+// resource version fail with an Expired error, like when the watch cache of
+// the apiserver does not reach back far enough.
+const maxTrackedEvents = 1000
+
+// trackedEvent is an event kept by the tracker.
+type trackedEvent struct {
+	resourceVersion uint64
+	event           watch.Event
+	// previous is the object before a Modified or Deleted event.
+	previous runtime.Object
+}
+
+// recordEventLocked keeps an event of the tracker, dropping the oldest one
+// if too many are kept.
+func (t *tracker) recordEventLocked(gvr schema.GroupVersionResource, eventType watch.EventType, obj, previous runtime.Object) {
+	events := append(t.events[gvr], trackedEvent{
+		resourceVersion: t.resourceVersion,
+		event:           watch.Event{Type: eventType, Object: obj},
+		previous:        previous,
+	})
+	if len(events) > maxTrackedEvents {
+		t.compacted[gvr] = events[0].resourceVersion
+		events = events[1:]
+	}
+	t.events[gvr] = events
+}
+
+// trackerWatch is a watch returned by the Watch of a tracker. When a watch
+// reactor answers a watch action with it, like the one of the generated fake
+// clientsets does, Fake restricts it to the action, so that the watch follows
+// the semantics of ObjectWatchReaction.
+type trackerWatch struct {
+	*watch.RaceFreeFakeWatcher
+	tracker *tracker
+}
+
+// restrict replaces the watch with the one ObjectWatchReaction starts for
+// action, which follows its resource version and field selector.
+func (w *trackerWatch) restrict(action Action) (watch.Interface, error) {
+	w.Stop()
+	_, ret, err := ObjectWatchReaction(w.tracker)(action)
+	return ret, err
+}
+
+// watchFrom starts a watch following the resource version semantics of the
+// apiserver. Besides the watch, it returns the events to deliver before the
+// ones of the watch, and the objects that exist when the watch starts, before
+// those events.
+//
+// A watch from resource version "0", or from "" when resource version
+// semantics are on, starts with Added events for the existing objects. When
+// resource version semantics are on, a watch from any other resource version
+// starts with the events that happened after it, or with an Expired error if
+// they are no longer known. Otherwise the watch starts from now.
+func (t *tracker) watchFrom(gvr schema.GroupVersionResource, ns string, resourceVersion string) (*watch.RaceFreeFakeWatcher, []watch.Event, map[types.NamespacedName]runtime.Object, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	objs, err := filterByNamespace(t.objects[gvr], ns)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	start := map[types.NamespacedName]runtime.Object{}
+	for _, obj := range objs {
+		start[objectKey(obj)] = obj
+	}
+
+	var initial []watch.Event
+	switch {
+	case resourceVersion == "0" || (resourceVersion == "" && t.resourceVersions):
+		for _, obj := range objs {
+			initial = append(initial, watch.Event{Type: watch.Added, Object: obj.DeepCopyObject()})
+		}
+		start = map[types.NamespacedName]runtime.Object{}
+
+	case resourceVersion != "" && t.resourceVersions:
+		rv, err := strconv.ParseUint(resourceVersion, 10, 64)
+		if err != nil {
+			return nil, nil, nil, errors.NewBadRequest(fmt.Sprintf("invalid resource version %q: %v", resourceVersion, err))
+		}
+		var events []trackedEvent
+		start, events, err = t.objectsAtLocked(gvr, ns, rv)
+		if errors.IsResourceExpired(err) {
+			return nil, []watch.Event{{Type: watch.Error, Object: &err.(*errors.StatusError).ErrStatus}}, nil, nil
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		for _, event := range events {
+			initial = append(initial, watch.Event{Type: event.event.Type, Object: event.event.Object.DeepCopyObject()})
+		}
+	}
+
+	fakewatcher := watch.NewRaceFreeFake()
+	if _, exists := t.watchers[gvr]; !exists {
+		t.watchers[gvr] = make(map[string][]*watch.RaceFreeFakeWatcher)
+	}
+	t.watchers[gvr][ns] = append(t.watchers[gvr][ns], fakewatcher)
+	return fakewatcher, initial, start, nil
+}
+
+// objectsAtLocked returns the objects of gvr in ns as they were at
+// resourceVersion, and the events of these objects that happened after it.
+// It returns an Expired error if these events are no longer known.
+func (t *tracker) objectsAtLocked(gvr schema.GroupVersionResource, ns string, resourceVersion uint64) (map[types.NamespacedName]runtime.Object, []trackedEvent, error) {
+	if resourceVersion < t.compacted[gvr] {
+		return nil, nil, errors.NewResourceExpired(fmt.Sprintf("too old resource version: %d (%d)", resourceVersion, t.compacted[gvr]+1))
+	}
+	objs, err := filterByNamespace(t.objects[gvr], ns)
+	if err != nil {
+		return nil, nil, err
+	}
+	result := map[types.NamespacedName]runtime.Object{}
+	for _, obj := range objs {
+		result[objectKey(obj)] = obj
+	}
+
+	// Undo the events after resourceVersion, from the last one.
+	var after []trackedEvent
+	events := t.events[gvr]
+	for i := len(events) - 1; i >= 0 && events[i].resourceVersion > resourceVersion; i-- {
+		event := events[i]
+		key := objectKey(event.event.Object)
+		if ns != "" && key.Namespace != ns {
+			continue
+		}
+		if event.event.Type == watch.Added {
+			delete(result, key)
+		} else {
+			result[key] = event.previous
+		}
+		after = append(after, event)
+	}
+	for i, j := 0, len(after)-1; i < j; i, j = i+1, j-1 {
+		after[i], after[j] = after[j], after[i]
+	}
+	return result, after, nil
+}
+
+// bookmark returns a Bookmark event carrying the current resource version,
+// if resource version semantics are on and the type of the objects of gvr
+// is known.
+func (t *tracker) bookmark(gvr schema.GroupVersionResource) (watch.Event, bool) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if !t.resourceVersions {
+		return watch.Event{}, false
+	}
+	var sample runtime.Object
+	for _, obj := range t.objects[gvr] {
+		sample = obj
+		break
+	}
+	if events := t.events[gvr]; sample == nil && len(events) > 0 {
+		sample = events[len(events)-1].event.Object
+	}
+	if sample == nil {
+		return watch.Event{}, false
+	}
+
+	obj := reflect.New(reflect.TypeOf(sample).Elem()).Interface().(runtime.Object)
+	obj.GetObjectKind().SetGroupVersionKind(sample.GetObjectKind().GroupVersionKind())
+	objMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return watch.Event{}, false
+	}
+	objMeta.SetResourceVersion(strconv.FormatUint(t.resourceVersion, 10))
+	return watch.Event{Type: watch.Bookmark, Object: obj}, true
+}
+
+func objectKey(obj runtime.Object) types.NamespacedName {
+	objMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return types.NamespacedName{}
+	}
+	return types.NamespacedName{Namespace: objMeta.GetNamespace(), Name: objMeta.GetName()}
+}
+
+// initialEventsWatcher delivers initial events before the events of a watch.
+// Without source watch, it stops after the initial events.
+type initialEventsWatcher struct {
+	initial  []watch.Event
+	source   watch.Interface
+	result   chan watch.Event
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+var _ watch.Interface = &initialEventsWatcher{}
+
+func newInitialEventsWatcher(initial []watch.Event, source watch.Interface) *initialEventsWatcher {
+	w := &initialEventsWatcher{
+		initial: initial,
+		source:  source,
+		result:  make(chan watch.Event),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *initialEventsWatcher) ResultChan() <-chan watch.Event {
+	return w.result
+}
+
+func (w *initialEventsWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.done)
+		if w.source != nil {
+			w.source.Stop()
+		}
+	})
+}
+
+func (w *initialEventsWatcher) run() {
+	defer close(w.result)
+	for _, event := range w.initial {
+		select {
+		case w.result <- event:
+		case <-w.done:
+			return
+		}
+	}
+	if w.source == nil {
+		return
+	}
+	for event := range w.source.ResultChan() {
+		select {
+		case w.result <- event:
+		case <-w.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+type testEvent struct {
+	eventType       watch.EventType
+	name            string
+	resourceVersion string
+}
+
+func startWatch(t *testing.T, o ObjectTracker, opts metav1.ListOptions) watch.Interface {
+	_, w, err := ObjectWatchReaction(o)(NewWatchAction(podsResource, "default", opts))
+	if err != nil {
+		t.Fatalf("unexpected error starting watch: %v", err)
+	}
+	return w
+}
+
+func receiveEvents(t *testing.T, w watch.Interface, count int) []testEvent {
+	var events []testEvent
+	for len(events) < count {
+		select {
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				t.Fatalf("watch closed after events %v", events)
+			}
+			objMeta, err := meta.Accessor(event.Object)
+			if err != nil {
+				t.Fatal(err)
+			}
+			events = append(events, testEvent{event.Type, objMeta.GetName(), objMeta.GetResourceVersion()})
+		case <-time.After(wait.ForeverTestTimeout):
+			t.Fatalf("timed out waiting for events, got %v", events)
+		}
+	}
+	return events
+}
+
+func currentResourceVersion(t *testing.T, o ObjectTracker) string {
+	list, err := o.List(podsResource, corev1.SchemeGroupVersion.WithKind("Pod"), "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listMeta, err := meta.ListAccessor(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return listMeta.GetResourceVersion()
+}
+
+func TestWatchFromResourceVersion(t *testing.T) {
+	o := newPodTracker(t, newPod("a", "node-1"), newPod("b", "node-1"))
+	o.(ResourceVersionTracker).SetResourceVersionSemantics(true)
+	listed := currentResourceVersion(t, o)
+
+	if err := o.Create(podsResource, newPod("c", ""), "default"); err != nil {
+		t.Fatal(err)
+	}
+	moved := newPod("a", "node-2")
+	if err := o.Update(podsResource, moved, "default"); err != nil {
+		t.Fatal(err)
+	}
+
+	w := startWatch(t, o, metav1.ListOptions{ResourceVersion: listed})
+	defer w.Stop()
+	if err := o.Delete(podsResource, "default", "b"); err != nil {
+		t.Fatal(err)
+	}
+	expected := []testEvent{
+		{watch.Added, "c", "3"},
+		{watch.Modified, "a", "4"},
+		{watch.Deleted, "b", "5"},
+	}
+	if events := receiveEvents(t, w, 3); !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected events %v, got %v", expected, events)
+	}
+
+	// The field selector is evaluated against the objects as they were at
+	// the resource version of the watch.
+	filtered := startWatch(t, o, metav1.ListOptions{ResourceVersion: listed, FieldSelector: "spec.nodeName=node-1"})
+	defer filtered.Stop()
+	expected = []testEvent{
+		{watch.Deleted, "a", "4"},
+		{watch.Deleted, "b", "5"},
+	}
+	if events := receiveEvents(t, filtered, 2); !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected events %v, got %v", expected, events)
+	}
+}
+
+func TestWatchInitialEvents(t *testing.T) {
+	for _, resourceVersions := range []bool{false, true} {
+		t.Run(fmt.Sprintf("resourceVersions=%v", resourceVersions), func(t *testing.T) {
+			o := newPodTracker(t, newPod("a", ""), newPod("b", ""))
+			o.(ResourceVersionTracker).SetResourceVersionSemantics(resourceVersions)
+
+			w := startWatch(t, o, metav1.ListOptions{ResourceVersion: "0"})
+			defer w.Stop()
+			events := receiveEvents(t, w, 2)
+			if events[0].eventType != watch.Added || events[0].name != "a" || events[1].eventType != watch.Added || events[1].name != "b" {
+				t.Errorf("expected Added events for the existing objects, got %v", events)
+			}
+
+			w = startWatch(t, o, metav1.ListOptions{})
+			defer w.Stop()
+			if err := o.Create(podsResource, newPod("c", ""), "default"); err != nil {
+				t.Fatal(err)
+			}
+			events = receiveEvents(t, w, 1)
+			if resourceVersions {
+				events = append(events, receiveEvents(t, w, 2)...)
+			}
+			if names := eventNames(events); resourceVersions && !reflect.DeepEqual(names, []string{"a", "b", "c"}) {
+				t.Errorf("expected events for the existing objects before the new one, got %v", events)
+			} else if !resourceVersions && !reflect.DeepEqual(names, []string{"c"}) {
+				t.Errorf("expected only an event for the new object, got %v", events)
+			}
+		})
+	}
+}
+
+func eventNames(events []testEvent) []string {
+	var names []string
+	for _, event := range events {
+		names = append(names, event.name)
+	}
+	return names
+}
+
+func TestWatchBookmark(t *testing.T) {
+	o := newPodTracker(t, newPod("a", ""))
+	o.(ResourceVersionTracker).SetResourceVersionSemantics(true)
+
+	w := startWatch(t, o, metav1.ListOptions{ResourceVersion: "1", AllowWatchBookmarks: true})
+	defer w.Stop()
+	select {
+	case event := <-w.ResultChan():
+		if event.Type != watch.Bookmark {
+			t.Fatalf("expected a Bookmark event, got %v", event.Type)
+		}
+		if _, ok := event.Object.(*corev1.Pod); !ok {
+			t.Errorf("expected the bookmark to carry a Pod, got %T", event.Object)
+		}
+		if rv := event.Object.(*corev1.Pod).ResourceVersion; rv != "1" {
+			t.Errorf("expected the bookmark to carry resource version 1, got %q", rv)
+		}
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("timed out waiting for the bookmark")
+	}
+}
+
+func TestWatchExpiredResourceVersion(t *testing.T) {
+	o := newPodTracker(t, newPod("a", ""))
+	o.(ResourceVersionTracker).SetResourceVersionSemantics(true)
+	// The first update is dropped from the events kept.
+	for i := 0; i <= maxTrackedEvents; i++ {
+		pod := newPod("a", fmt.Sprintf("node-%d", i))
+		if err := o.Update(podsResource, pod, "default"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := startWatch(t, o, metav1.ListOptions{ResourceVersion: "1"})
+	defer w.Stop()
+	event, ok := <-w.ResultChan()
+	if !ok || event.Type != watch.Error {
+		t.Fatalf("expected an Error event, got %v", event)
+	}
+	if err := errors.FromObject(event.Object); !errors.IsResourceExpired(err) {
+		t.Errorf("expected an Expired error, got %v", err)
+	}
+	if _, ok := <-w.ResultChan(); ok {
+		t.Error("expected the watch to be closed after the error")
+	}
+
+	w = startWatch(t, o, metav1.ListOptions{ResourceVersion: "2"})
+	defer w.Stop()
+	if events := receiveEvents(t, w, maxTrackedEvents); events[0].resourceVersion != "3" {
+		t.Errorf("expected the events after resource version 2, got %v", events[0])
+	}
+}
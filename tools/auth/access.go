@@ -0,0 +1,181 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	authorizationv1client "k8s.io/client-go/kubernetes/typed/authorization/v1"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/clock"
+)
+
+// DefaultAccessCacheTTL is the default duration for which an AccessChecker
+// caches the results of reviews.
+const DefaultAccessCacheTTL = 10 * time.Second
+
+// accessReviewWorkers is the number of reviews CanIAll sends concurrently.
+const accessReviewWorkers = 8
+
+// CanI returns whether the user of client may perform verb on resource in
+// namespace, with an empty namespace standing for all namespaces. It sends a
+// SelfSubjectAccessReview on every call; use an AccessChecker to cache the
+// results.
+func CanI(ctx context.Context, client authorizationv1client.SelfSubjectAccessReviewsGetter, verb string, resource schema.GroupVersionResource, namespace string) (bool, error) {
+	return review(ctx, client, resourceAttributes(verb, resource, namespace))
+}
+
+// AccessChecker checks the access of the user of a client with
+// SelfSubjectAccessReviews and caches the results, allowed or not, for a
+// TTL. Failed reviews are not cached. It is safe for concurrent use.
+type AccessChecker struct {
+	client authorizationv1client.SelfSubjectAccessReviewsGetter
+	ttl    time.Duration
+	clock  clock.Clock
+
+	lock  sync.Mutex
+	cache map[authorizationv1.ResourceAttributes]accessResult
+}
+
+type accessResult struct {
+	allowed bool
+	expires time.Time
+}
+
+// NewAccessChecker returns an AccessChecker caching the results of reviews
+// of client for ttl, or DefaultAccessCacheTTL if ttl is 0.
+func NewAccessChecker(client authorizationv1client.SelfSubjectAccessReviewsGetter, ttl time.Duration) *AccessChecker {
+	return newAccessChecker(client, ttl, clock.RealClock{})
+}
+
+func newAccessChecker(client authorizationv1client.SelfSubjectAccessReviewsGetter, ttl time.Duration, clock clock.Clock) *AccessChecker {
+	if ttl == 0 {
+		ttl = DefaultAccessCacheTTL
+	}
+	return &AccessChecker{
+		client: client,
+		ttl:    ttl,
+		clock:  clock,
+		cache:  map[authorizationv1.ResourceAttributes]accessResult{},
+	}
+}
+
+// CanI returns whether the user may perform verb on resource in namespace,
+// like CanI, from the cache if possible.
+func (c *AccessChecker) CanI(ctx context.Context, verb string, resource schema.GroupVersionResource, namespace string) (bool, error) {
+	return c.Check(ctx, resourceAttributes(verb, resource, namespace))
+}
+
+// Check returns whether the user may perform the action described by
+// attributes, from the cache if possible.
+func (c *AccessChecker) Check(ctx context.Context, attributes authorizationv1.ResourceAttributes) (bool, error) {
+	if allowed, ok := c.cached(attributes); ok {
+		return allowed, nil
+	}
+	allowed, err := review(ctx, c.client, attributes)
+	if err != nil {
+		return false, err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.cache[attributes] = accessResult{allowed: allowed, expires: c.clock.Now().Add(c.ttl)}
+	return allowed, nil
+}
+
+// CheckAll returns whether the user may perform each of the actions described
+// by attributes. Actions missing from the cache are reviewed concurrently,
+// and reviewed once if they are listed more than once. The returned error
+// aggregates the errors of failed reviews, whose actions are not allowed.
+func (c *AccessChecker) CheckAll(ctx context.Context, attributes []authorizationv1.ResourceAttributes) ([]bool, error) {
+	allowed := make([]bool, len(attributes))
+	pending := map[authorizationv1.ResourceAttributes][]int{}
+	var reviews []authorizationv1.ResourceAttributes
+	for i, a := range attributes {
+		if cached, ok := c.cached(a); ok {
+			allowed[i] = cached
+			continue
+		}
+		if _, ok := pending[a]; !ok {
+			reviews = append(reviews, a)
+		}
+		pending[a] = append(pending[a], i)
+	}
+
+	results := make([]bool, len(reviews))
+	errs := make([]error, len(reviews))
+	workqueue.ParallelizeUntil(ctx, accessReviewWorkers, len(reviews), func(i int) {
+		results[i], errs[i] = c.Check(ctx, reviews[i])
+	})
+	for i, a := range reviews {
+		for _, index := range pending[a] {
+			allowed[index] = results[i]
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return allowed, err
+	}
+	return allowed, utilerrors.NewAggregate(errs)
+}
+
+// cached returns the cached result of a review of attributes, if any.
+func (c *AccessChecker) cached(attributes authorizationv1.ResourceAttributes) (bool, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	result, ok := c.cache[attributes]
+	if !ok {
+		return false, false
+	}
+	if !c.clock.Now().Before(result.expires) {
+		delete(c.cache, attributes)
+		return false, false
+	}
+	return result.allowed, true
+}
+
+// Reset drops all cached results, for example after the permissions of the
+// user changed.
+func (c *AccessChecker) Reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.cache = map[authorizationv1.ResourceAttributes]accessResult{}
+}
+
+func resourceAttributes(verb string, resource schema.GroupVersionResource, namespace string) authorizationv1.ResourceAttributes {
+	return authorizationv1.ResourceAttributes{
+		Namespace: namespace,
+		Verb:      verb,
+		Group:     resource.Group,
+		Version:   resource.Version,
+		Resource:  resource.Resource,
+	}
+}
+
+func review(ctx context.Context, client authorizationv1client.SelfSubjectAccessReviewsGetter, attributes authorizationv1.ResourceAttributes) (bool, error) {
+	result, err := client.SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &attributes},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+	return result.Status.Allowed, nil
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+var pods = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+
+// newReviewClient returns a client allowing get and list, failing reviews of
+// delete and counting the reviews.
+func newReviewClient(reviews *int32) *fake.Clientset {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "selfsubjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		atomic.AddInt32(reviews, 1)
+		review := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		switch review.Spec.ResourceAttributes.Verb {
+		case "get", "list":
+			review.Status.Allowed = true
+		case "delete":
+			return true, nil, errors.New("review failed")
+		}
+		return true, review, nil
+	})
+	return client
+}
+
+func TestCanI(t *testing.T) {
+	var reviews int32
+	client := newReviewClient(&reviews)
+	for verb, expected := range map[string]bool{"get": true, "create": false} {
+		allowed, err := CanI(context.Background(), client.AuthorizationV1(), verb, pods, "ns")
+		if err != nil || allowed != expected {
+			t.Errorf("%s: expected %t, got %t (%v)", verb, expected, allowed, err)
+		}
+	}
+	if _, err := CanI(context.Background(), client.AuthorizationV1(), "delete", pods, "ns"); err == nil {
+		t.Error("expected the review to fail")
+	}
+}
+
+func TestAccessCheckerCaches(t *testing.T) {
+	var reviews int32
+	client := newReviewClient(&reviews)
+	clock := testingclock.NewFakeClock(time.Now())
+	checker := newAccessChecker(client.AuthorizationV1(), time.Minute, clock)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if allowed, err := checker.CanI(ctx, "get", pods, "ns"); err != nil || !allowed {
+			t.Errorf("expected get to be allowed, got %t (%v)", allowed, err)
+		}
+		if allowed, err := checker.CanI(ctx, "create", pods, "ns"); err != nil || allowed {
+			t.Errorf("expected create to be denied, got %t (%v)", allowed, err)
+		}
+	}
+	if reviews != 2 {
+		t.Errorf("expected 2 reviews, got %d", reviews)
+	}
+	// failures are not cached
+	checker.CanI(ctx, "delete", pods, "ns")
+	checker.CanI(ctx, "delete", pods, "ns")
+	if reviews != 4 {
+		t.Errorf("expected 4 reviews, got %d", reviews)
+	}
+
+	clock.Step(time.Minute)
+	checker.CanI(ctx, "get", pods, "ns")
+	if reviews != 5 {
+		t.Errorf("expected the expired result to be reviewed again, got %d reviews", reviews)
+	}
+	checker.Reset()
+	checker.CanI(ctx, "get", pods, "ns")
+	if reviews != 6 {
+		t.Errorf("expected the reset result to be reviewed again, got %d reviews", reviews)
+	}
+}
+
+func TestAccessCheckerCheckAll(t *testing.T) {
+	var reviews int32
+	client := newReviewClient(&reviews)
+	checker := NewAccessChecker(client.AuthorizationV1(), 0)
+	ctx := context.Background()
+	if _, err := checker.CanI(ctx, "get", pods, "ns"); err != nil {
+		t.Fatal(err)
+	}
+
+	attributes := []authorizationv1.ResourceAttributes{
+		resourceAttributes("get", pods, "ns"),
+		resourceAttributes("list", pods, "ns"),
+		resourceAttributes("create", pods, "ns"),
+		resourceAttributes("list", pods, "ns"),
+		resourceAttributes("list", pods, ""),
+	}
+	allowed, err := checker.CheckAll(ctx, attributes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []bool{true, true, false, true, true}; !reflect.DeepEqual(allowed, expected) {
+		t.Errorf("expected %v, got %v", expected, allowed)
+	}
+	// the cached and the duplicate actions are not reviewed
+	if reviews != 4 {
+		t.Errorf("expected 4 reviews, got %d", reviews)
+	}
+
+	allowed, err = checker.CheckAll(ctx, append(attributes, resourceAttributes("delete", pods, "ns")))
+	if err == nil {
+		t.Error("expected an error for the failed review")
+	}
+	if expected := []bool{true, true, false, true, true, false}; !reflect.DeepEqual(allowed, expected) {
+		t.Errorf("expected %v, got %v", expected, allowed)
+	}
+}
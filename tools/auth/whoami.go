@@ -0,0 +1,223 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// selfSubjectReviewVersions are the versions of the SelfSubjectReview API
+// WhoAmI tries, in turn.
+var selfSubjectReviewVersions = []string{"v1", "v1beta1", "v1alpha1"}
+
+// serviceAccountUsernamePrefix prefixes the usernames of service accounts.
+const serviceAccountUsernamePrefix = "system:serviceaccount:"
+
+// selfSubjectReview is the part of a SelfSubjectReview read by WhoAmI.
+type selfSubjectReview struct {
+	Status struct {
+		UserInfo authenticationv1.UserInfo `json:"userInfo"`
+	} `json:"status"`
+}
+
+// WhoAmI returns the user the server authenticates the requests of client as,
+// like a clientset's AuthenticationV1().RESTClient(), with a
+// SelfSubjectReview. It tries the versions v1, v1beta1 and v1alpha1 of the API
+// in turn, and returns a NotFound error if the server serves none of them;
+// WhoAmIForConfig falls back to the credentials of the config in that case.
+func WhoAmI(ctx context.Context, client rest.Interface) (*authenticationv1.UserInfo, error) {
+	var err error
+	for _, version := range selfSubjectReviewVersions {
+		var body []byte
+		body, err = json.Marshal(map[string]string{
+			"apiVersion": "authentication.k8s.io/" + version,
+			"kind":       "SelfSubjectReview",
+		})
+		if err != nil {
+			return nil, err
+		}
+		body, err = client.Post().
+			AbsPath("/apis/authentication.k8s.io", version, "selfsubjectreviews").
+			SetHeader("Content-Type", "application/json").
+			SetHeader("Accept", "application/json").
+			Body(body).
+			DoRaw(ctx)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var review selfSubjectReview
+		if err := json.Unmarshal(body, &review); err != nil {
+			return nil, fmt.Errorf("unable to decode the SelfSubjectReview: %v", err)
+		}
+		return &review.Status.UserInfo, nil
+	}
+	return nil, err
+}
+
+// WhoAmIForConfig returns the user the server authenticates the requests made
+// with config as, with WhoAmI. If the server does not serve SelfSubjectReviews,
+// it falls back to the identity of the credentials of config, in order: the
+// impersonated user, the service account of a service account token, the
+// subject of a client certificate, and the username of basic authentication.
+// The fallbacks cannot know the identity mappings of the server, like the
+// prefixes of OIDC usernames, and are thus limited to these credentials.
+func WhoAmIForConfig(ctx context.Context, config *rest.Config) (*authenticationv1.UserInfo, error) {
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	user, err := WhoAmI(ctx, client.AuthenticationV1().RESTClient())
+	if !apierrors.IsNotFound(err) {
+		return user, err
+	}
+	user, fallbackErr := identityFromConfig(config)
+	if fallbackErr != nil {
+		return nil, fmt.Errorf("the server does not serve SelfSubjectReviews (%v) and %v", err, fallbackErr)
+	}
+	return user, nil
+}
+
+// identityFromConfig returns the identity of the credentials of config.
+func identityFromConfig(config *rest.Config) (*authenticationv1.UserInfo, error) {
+	if len(config.Impersonate.UserName) > 0 {
+		return &authenticationv1.UserInfo{
+			Username: config.Impersonate.UserName,
+			UID:      config.Impersonate.UID,
+			Groups:   config.Impersonate.Groups,
+			Extra:    extraValues(config.Impersonate.Extra),
+		}, nil
+	}
+
+	token := config.BearerToken
+	if len(token) == 0 && len(config.BearerTokenFile) > 0 {
+		data, err := ioutil.ReadFile(config.BearerTokenFile)
+		if err != nil {
+			return nil, err
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if len(token) > 0 {
+		if user, ok := serviceAccountFromToken(token); ok {
+			return user, nil
+		}
+		return nil, errors.New("the identity of bearer tokens which are not service account tokens is unknown")
+	}
+
+	certData := config.CertData
+	if len(certData) == 0 && len(config.CertFile) > 0 {
+		data, err := ioutil.ReadFile(config.CertFile)
+		if err != nil {
+			return nil, err
+		}
+		certData = data
+	}
+	if len(certData) > 0 {
+		return userFromCertificate(certData)
+	}
+
+	if len(config.Username) > 0 {
+		return &authenticationv1.UserInfo{Username: config.Username, Groups: []string{"system:authenticated"}}, nil
+	}
+	return nil, errors.New("the identity of the credentials of the config is unknown")
+}
+
+// serviceAccountFromToken returns the service account of a service account
+// token, a JWT whose claims are read without verifying its signature, the
+// server having authenticated it.
+func serviceAccountFromToken(token string) (*authenticationv1.UserInfo, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, false
+	}
+	var claims struct {
+		Subject    string `json:"sub"`
+		Kubernetes struct {
+			ServiceAccount struct {
+				UID string `json:"uid"`
+			} `json:"serviceaccount"`
+		} `json:"kubernetes.io"`
+		LegacyUID string `json:"kubernetes.io/serviceaccount/service-account.uid"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, false
+	}
+	if !strings.HasPrefix(claims.Subject, serviceAccountUsernamePrefix) {
+		return nil, false
+	}
+	namespaceAndName := strings.SplitN(strings.TrimPrefix(claims.Subject, serviceAccountUsernamePrefix), ":", 2)
+	if len(namespaceAndName) != 2 {
+		return nil, false
+	}
+	uid := claims.Kubernetes.ServiceAccount.UID
+	if len(uid) == 0 {
+		uid = claims.LegacyUID
+	}
+	return &authenticationv1.UserInfo{
+		Username: claims.Subject,
+		UID:      uid,
+		Groups:   []string{"system:serviceaccounts", "system:serviceaccounts:" + namespaceAndName[0], "system:authenticated"},
+	}, true
+}
+
+// userFromCertificate returns the user of a client certificate, whose common
+// name is the username and whose organizations are the groups.
+func userFromCertificate(certData []byte) (*authenticationv1.UserInfo, error) {
+	block, _ := pem.Decode(certData)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("unable to decode the client certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the client certificate: %v", err)
+	}
+	if len(cert.Subject.CommonName) == 0 {
+		return nil, errors.New("the client certificate has no common name")
+	}
+	groups := append(append([]string{}, cert.Subject.Organization...), "system:authenticated")
+	return &authenticationv1.UserInfo{Username: cert.Subject.CommonName, Groups: groups}, nil
+}
+
+func extraValues(extra map[string][]string) map[string]authenticationv1.ExtraValue {
+	if len(extra) == 0 {
+		return nil
+	}
+	values := make(map[string]authenticationv1.ExtraValue, len(extra))
+	for key, value := range extra {
+		values[key] = value
+	}
+	return values
+}
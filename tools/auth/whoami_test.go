@@ -0,0 +1,179 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// newReviewServer returns a server serving SelfSubjectReviews of the given
+// versions for the user alice.
+func newReviewServer(versions ...string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, version := range versions {
+			if r.Method == "POST" && r.URL.Path == "/apis/authentication.k8s.io/"+version+"/selfsubjectreviews" {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"kind":"SelfSubjectReview","apiVersion":"authentication.k8s.io/` + version + `","status":{"userInfo":{"username":"alice","groups":["admins","system:authenticated"]}}}`))
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}))
+}
+
+func TestWhoAmI(t *testing.T) {
+	server := newReviewServer("v1beta1")
+	defer server.Close()
+
+	user, err := WhoAmIForConfig(context.Background(), &rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &authenticationv1.UserInfo{Username: "alice", Groups: []string{"admins", "system:authenticated"}}
+	if !reflect.DeepEqual(user, expected) {
+		t.Errorf("expected %#v, got %#v", expected, user)
+	}
+}
+
+func TestWhoAmINotServed(t *testing.T) {
+	server := newReviewServer()
+	defer server.Close()
+
+	config := &rest.Config{Host: server.URL}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := WhoAmI(context.Background(), client.AuthenticationV1().RESTClient()); !apierrors.IsNotFound(err) {
+		t.Errorf("expected a NotFound error, got %v", err)
+	}
+	if _, err := WhoAmIForConfig(context.Background(), config); err == nil {
+		t.Errorf("expected an error for a config without credentials")
+	}
+}
+
+func TestWhoAmIFallbacks(t *testing.T) {
+	server := newReviewServer()
+	defer server.Close()
+
+	claims := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"system:serviceaccount:default:builder","kubernetes.io":{"serviceaccount":{"uid":"1234"}}}`))
+	tests := []struct {
+		name     string
+		config   rest.Config
+		expected *authenticationv1.UserInfo
+	}{
+		{
+			name: "impersonation",
+			config: rest.Config{
+				BearerToken: "token",
+				Impersonate: rest.ImpersonationConfig{UserName: "bob", Groups: []string{"developers"}, Extra: map[string][]string{"scope": {"view"}}},
+			},
+			expected: &authenticationv1.UserInfo{
+				Username: "bob",
+				Groups:   []string{"developers"},
+				Extra:    map[string]authenticationv1.ExtraValue{"scope": {"view"}},
+			},
+		},
+		{
+			name:   "service account token",
+			config: rest.Config{BearerToken: "header." + claims + ".signature"},
+			expected: &authenticationv1.UserInfo{
+				Username: "system:serviceaccount:default:builder",
+				UID:      "1234",
+				Groups:   []string{"system:serviceaccounts", "system:serviceaccounts:default", "system:authenticated"},
+			},
+		},
+		{
+			name:   "client certificate",
+			config: rest.Config{TLSClientConfig: rest.TLSClientConfig{CertData: newCertificate(t, "carol", "operators")}},
+			expected: &authenticationv1.UserInfo{
+				Username: "carol",
+				Groups:   []string{"operators", "system:authenticated"},
+			},
+		},
+		{
+			name:   "basic authentication",
+			config: rest.Config{Username: "dave", Password: "secret"},
+			expected: &authenticationv1.UserInfo{
+				Username: "dave",
+				Groups:   []string{"system:authenticated"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			user, err := identityFromConfig(&test.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(user, test.expected) {
+				t.Errorf("expected %#v, got %#v", test.expected, user)
+			}
+		})
+	}
+
+	config := &rest.Config{Host: server.URL, BearerToken: "header." + claims + ".signature"}
+	user, err := WhoAmIForConfig(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Username != "system:serviceaccount:default:builder" {
+		t.Errorf("expected the service account, got %#v", user)
+	}
+
+	config.BearerToken = "opaque"
+	if _, err := WhoAmIForConfig(context.Background(), config); err == nil {
+		t.Errorf("expected an error for an opaque token")
+	}
+}
+
+// newCertificate returns a self-signed PEM certificate for the user.
+func newCertificate(t *testing.T, user string, groups ...string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: user, Organization: groups},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
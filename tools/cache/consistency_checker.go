@@ -0,0 +1,249 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+// LiveGetFunc gets the live object of the given namespace and name, returning
+// a NotFound error if it does not exist.
+type LiveGetFunc func(ctx context.Context, namespace, name string) (metav1.Object, error)
+
+// ConsistencyMetricsProvider generates the metrics of a ConsistencyChecker.
+type ConsistencyMetricsProvider interface {
+	NewCheckedMetric(name string) CounterMetric
+	NewMissingMetric(name string) CounterMetric
+	NewOutdatedMetric(name string) CounterMetric
+	NewErrorsMetric(name string) CounterMetric
+}
+
+// ConsistencyCheckerConfig configures a ConsistencyChecker.
+type ConsistencyCheckerConfig struct {
+	// Name identifies the checker in logs and metrics.
+	Name string
+	// Store is the cache to check, for example the indexer of an informer.
+	Store Store
+	// Get gets the live objects.
+	Get LiveGetFunc
+
+	// SampleSize is the number of keys checked per round, 10 by default.
+	SampleSize int
+	// Interval is the period of the rounds of Run, 1 minute by default.
+	Interval time.Duration
+	// QPS limits the rate of live gets, 1 by default.
+	QPS float32
+	// Grace is how long the cache is given to catch up with the live
+	// objects before reporting a divergence, 10 seconds by default.
+	Grace time.Duration
+
+	// MetricsProvider optionally counts the checks and divergences.
+	MetricsProvider ConsistencyMetricsProvider
+	// Clock defaults to the real clock.
+	Clock clock.WithTicker
+}
+
+// ConsistencyResult reports a round of a ConsistencyChecker.
+type ConsistencyResult struct {
+	// Checked is the number of keys checked.
+	Checked int
+	// Missing holds the keys of cached objects which do not exist anymore,
+	// for example because their deletion was missed.
+	Missing []string
+	// Outdated holds the keys of cached objects with another UID or
+	// resource version than the live ones.
+	Outdated []string
+	// Errors is the number of keys which could not be checked.
+	Errors int
+}
+
+// Consistent returns true if no divergence was found.
+func (r ConsistencyResult) Consistent() bool {
+	return len(r.Missing) == 0 && len(r.Outdated) == 0
+}
+
+// ConsistencyChecker samples keys from a cache and checks them against the
+// live objects, to detect stale caches without relisting. Divergences are
+// only reported once the cache did not catch up within the grace period.
+type ConsistencyChecker struct {
+	config      ConsistencyCheckerConfig
+	rateLimiter flowcontrol.RateLimiter
+	rand        *rand.Rand
+
+	checked  CounterMetric
+	missing  CounterMetric
+	outdated CounterMetric
+	errors   CounterMetric
+}
+
+// NewConsistencyChecker returns a ConsistencyChecker for config.
+func NewConsistencyChecker(config ConsistencyCheckerConfig) *ConsistencyChecker {
+	if config.SampleSize <= 0 {
+		config.SampleSize = 10
+	}
+	if config.Interval <= 0 {
+		config.Interval = time.Minute
+	}
+	if config.QPS <= 0 {
+		config.QPS = 1
+	}
+	if config.Grace <= 0 {
+		config.Grace = 10 * time.Second
+	}
+	if config.Clock == nil {
+		config.Clock = clock.RealClock{}
+	}
+	c := &ConsistencyChecker{
+		config:      config,
+		rateLimiter: flowcontrol.NewTokenBucketRateLimiterWithClock(config.QPS, 1, config.Clock),
+		rand:        rand.New(rand.NewSource(config.Clock.Now().UnixNano())),
+		checked:     noopMetric{},
+		missing:     noopMetric{},
+		outdated:    noopMetric{},
+		errors:      noopMetric{},
+	}
+	if provider := config.MetricsProvider; provider != nil {
+		c.checked = provider.NewCheckedMetric(config.Name)
+		c.missing = provider.NewMissingMetric(config.Name)
+		c.outdated = provider.NewOutdatedMetric(config.Name)
+		c.errors = provider.NewErrorsMetric(config.Name)
+	}
+	return c
+}
+
+// Run checks the cache every interval until ctx is done, logging the
+// divergences.
+func (c *ConsistencyChecker) Run(ctx context.Context) {
+	ticker := c.config.Clock.NewTicker(c.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+		result, err := c.Check(ctx)
+		if err != nil {
+			return
+		}
+		if !result.Consistent() {
+			klog.Warningf("%s: cache diverges from the live objects, missing: %v, outdated: %v", c.config.Name, result.Missing, result.Outdated)
+		}
+	}
+}
+
+// candidate is a key whose cached object diverged from the live one.
+type candidate struct {
+	key     string
+	cached  metav1.Object
+	missing bool
+}
+
+// Check checks a sample of the keys of the cache, returning an error only if
+// ctx is done.
+func (c *ConsistencyChecker) Check(ctx context.Context) (ConsistencyResult, error) {
+	var result ConsistencyResult
+	var candidates []candidate
+	for _, key := range c.sample() {
+		if err := c.rateLimiter.Wait(ctx); err != nil {
+			return result, err
+		}
+		cached, ok := c.cached(key)
+		if !ok {
+			// deleted meanwhile
+			continue
+		}
+		result.Checked++
+		c.checked.Inc()
+		namespace, name, err := SplitMetaNamespaceKey(key)
+		if err != nil {
+			result.Errors++
+			c.errors.Inc()
+			continue
+		}
+		live, err := c.config.Get(ctx, namespace, name)
+		switch {
+		case apierrors.IsNotFound(err):
+			candidates = append(candidates, candidate{key: key, cached: cached, missing: true})
+		case err != nil:
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			klog.V(4).Infof("%s: failed to get %s: %v", c.config.Name, key, err)
+			result.Errors++
+			c.errors.Inc()
+		case live.GetUID() != cached.GetUID() || live.GetResourceVersion() != cached.GetResourceVersion():
+			candidates = append(candidates, candidate{key: key, cached: cached})
+		}
+	}
+	if len(candidates) == 0 {
+		return result, nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return result, ctx.Err()
+	case <-c.config.Clock.After(c.config.Grace):
+	}
+	for _, candidate := range candidates {
+		cached, ok := c.cached(candidate.key)
+		if !ok || cached.GetUID() != candidate.cached.GetUID() || cached.GetResourceVersion() != candidate.cached.GetResourceVersion() {
+			// the cache caught up
+			continue
+		}
+		if candidate.missing {
+			result.Missing = append(result.Missing, candidate.key)
+			c.missing.Inc()
+		} else {
+			result.Outdated = append(result.Outdated, candidate.key)
+			c.outdated.Inc()
+		}
+	}
+	return result, nil
+}
+
+// sample returns up to SampleSize random keys of the cache.
+func (c *ConsistencyChecker) sample() []string {
+	keys := c.config.Store.ListKeys()
+	c.rand.Shuffle(len(keys), func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+	if len(keys) > c.config.SampleSize {
+		keys = keys[:c.config.SampleSize]
+	}
+	return keys
+}
+
+// cached returns the cached object of key.
+func (c *ConsistencyChecker) cached(key string) (metav1.Object, bool) {
+	obj, exists, err := c.config.Store.GetByKey(key)
+	if err != nil || !exists {
+		return nil, false
+	}
+	cached, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, false
+	}
+	return cached, true
+}
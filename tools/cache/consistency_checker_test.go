@@ -0,0 +1,153 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type countingMetric struct {
+	lock  sync.Mutex
+	count int
+}
+
+func (m *countingMetric) Inc() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.count++
+}
+
+type countingConsistencyMetrics struct {
+	checked, missing, outdated, errors countingMetric
+}
+
+func (m *countingConsistencyMetrics) NewCheckedMetric(string) CounterMetric  { return &m.checked }
+func (m *countingConsistencyMetrics) NewMissingMetric(string) CounterMetric  { return &m.missing }
+func (m *countingConsistencyMetrics) NewOutdatedMetric(string) CounterMetric { return &m.outdated }
+func (m *countingConsistencyMetrics) NewErrorsMetric(string) CounterMetric   { return &m.errors }
+
+func consistencyTestPod(name, uid, resourceVersion string) *v1.Pod {
+	return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name, UID: types.UID(uid), ResourceVersion: resourceVersion}}
+}
+
+func TestConsistencyChecker(t *testing.T) {
+	store := NewStore(MetaNamespaceKeyFunc)
+	for _, pod := range []*v1.Pod{
+		consistencyTestPod("consistent", "1", "10"),
+		consistencyTestPod("deleted", "2", "10"),
+		consistencyTestPod("updated", "3", "10"),
+		consistencyTestPod("recreated", "4", "10"),
+		consistencyTestPod("lagging", "5", "10"),
+		consistencyTestPod("failing", "6", "10"),
+	} {
+		store.Add(pod)
+	}
+	live := map[string]*v1.Pod{
+		"consistent": consistencyTestPod("consistent", "1", "10"),
+		"updated":    consistencyTestPod("updated", "3", "11"),
+		"recreated":  consistencyTestPod("recreated", "7", "10"),
+		"lagging":    consistencyTestPod("lagging", "5", "11"),
+	}
+	metrics := &countingConsistencyMetrics{}
+	checker := NewConsistencyChecker(ConsistencyCheckerConfig{
+		Name:  "pods",
+		Store: store,
+		Get: func(ctx context.Context, namespace, name string) (metav1.Object, error) {
+			switch name {
+			case "failing":
+				return nil, errors.New("unavailable")
+			case "lagging":
+				// the cache catches up within the grace period
+				store.Update(live[name])
+			}
+			if pod, ok := live[name]; ok {
+				return pod, nil
+			}
+			return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "pods"}, name)
+		},
+		SampleSize:      100,
+		QPS:             1000,
+		Grace:           time.Millisecond,
+		MetricsProvider: metrics,
+	})
+
+	result, err := checker.Check(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(result.Outdated)
+	expected := ConsistencyResult{
+		Checked:  6,
+		Missing:  []string{"ns/deleted"},
+		Outdated: []string{"ns/recreated", "ns/updated"},
+		Errors:   1,
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+	if result.Consistent() {
+		t.Errorf("expected divergences")
+	}
+	if metrics.checked.count != 6 || metrics.missing.count != 1 || metrics.outdated.count != 2 || metrics.errors.count != 1 {
+		t.Errorf("unexpected metrics %+v", metrics)
+	}
+}
+
+func TestConsistencyCheckerSampleSize(t *testing.T) {
+	store := NewStore(MetaNamespaceKeyFunc)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		store.Add(consistencyTestPod(name, name, "1"))
+	}
+	var lock sync.Mutex
+	gets := map[string]int{}
+	checker := NewConsistencyChecker(ConsistencyCheckerConfig{
+		Store: store,
+		Get: func(ctx context.Context, namespace, name string) (metav1.Object, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			gets[name]++
+			return consistencyTestPod(name, name, "1"), nil
+		},
+		SampleSize: 2,
+		QPS:        1000,
+	})
+	result, err := checker.Check(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Consistent() || result.Checked != 2 || len(gets) != 2 {
+		t.Errorf("expected 2 consistent keys to be checked, got %+v with gets %v", result, gets)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := checker.Check(ctx); err == nil {
+		t.Errorf("expected an error once the context is done")
+	}
+}
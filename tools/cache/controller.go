@@ -91,6 +91,9 @@ type controller struct {
 	reflector      *Reflector
 	reflectorMutex sync.RWMutex
 	clock          clock.Clock
+	// backoffClock times the backoff of failed list and watch calls, the
+	// real clock is used if nil.
+	backoffClock clock.Clock
 }
 
 // Controller is a low-level controller that is parameterized by a
@@ -139,6 +142,10 @@ func (c *controller) Run(stopCh <-chan struct{}) {
 	r.ShouldResync = c.config.ShouldResync
 	r.WatchListPageSize = c.config.WatchListPageSize
 	r.clock = c.clock
+	if c.backoffClock != nil {
+		r.backoffManager = newReflectorBackoffManager(c.backoffClock)
+		r.initConnBackoffManager = newReflectorBackoffManager(c.backoffClock)
+	}
 	if c.config.WatchErrorHandler != nil {
 		r.watchErrorHandler = c.config.WatchErrorHandler
 	}
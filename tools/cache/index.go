@@ -55,6 +55,33 @@ type Indexer interface {
 	AddIndexers(newIndexers Indexers) error
 }
 
+// MemoryAccountingIndexer is an Indexer which can report its approximate
+// memory usage and compact its internal maps, like the ones returned by
+// NewIndexer and NewStore, and by the GetIndexer of shared informers.
+type MemoryAccountingIndexer interface {
+	Indexer
+	// MemoryUsage returns the approximate memory used by the indexer,
+	// sizing the objects with sizeFunc, or DefaultObjectSize if nil.
+	MemoryUsage(sizeFunc ObjectSizeFunc) MemoryUsage
+	// Compact reallocates the maps of the indexer, which otherwise retain
+	// the memory of their largest size, for example after large deletions.
+	Compact()
+}
+
+// BulkIndexer is an Indexer with allocation conscious accessors, for hot
+// paths reading many items, like the indexers returned by NewIndexer and
+// NewStore, and by the GetIndexer of shared informers.
+type BulkIndexer interface {
+	Indexer
+	// GetMany returns the items of keys, and whether they exist.
+	GetMany(keys []string) (items []interface{}, exists []bool)
+	// ListInto is like List, but reuses the memory of list for the result.
+	ListInto(list []interface{}) []interface{}
+	// ByIndexInto is like ByIndex, but reuses the memory of list for the
+	// result.
+	ByIndexInto(indexName, indexedValue string, list []interface{}) ([]interface{}, error)
+}
+
 // IndexFunc knows how to compute the set of indexed values for an object.
 type IndexFunc func(obj interface{}) ([]string, error)
 
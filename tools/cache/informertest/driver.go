@@ -0,0 +1,334 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package informertest drives a SharedIndexInformer from an in-memory
+// ListerWatcher and a fake clock, to unit test event handlers without a
+// cluster:
+//
+//	d := informertest.NewDriver(&v1.Pod{}, 0, nil)
+//	recorder := d.AddEventHandler(controller, 0)
+//	d.Start()
+//	defer d.Stop()
+//
+//	d.Source.Add(pod)
+//	if err := d.Wait(); err != nil {
+//		t.Fatal(err)
+//	}
+package informertest
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+// backoffStep is larger than the first backoff of the failed list and watch
+// calls of the informer.
+const backoffStep = 2 * time.Second
+
+// Driver runs a SharedIndexInformer fed by Source, whose resyncs and
+// backoffs are timed by Clock.
+type Driver struct {
+	Source   *ListerWatcher
+	Clock    *testingclock.FakeClock
+	Informer cache.SharedIndexInformer
+
+	lock      sync.Mutex
+	recorders []*Recorder
+	stopCh    chan struct{}
+	stopped   chan struct{}
+}
+
+// NewDriver returns a Driver of an informer of objects of the type of
+// exampleObject, see cache.NewSharedIndexInformer.
+func NewDriver(exampleObject runtime.Object, resyncPeriod time.Duration, indexers cache.Indexers) *Driver {
+	source := NewListerWatcher(exampleObject)
+	clock := testingclock.NewFakeClock(time.Now())
+	return &Driver{
+		Source: source,
+		Clock:  clock,
+		Informer: cache.NewSharedIndexInformerWithOptions(source, exampleObject, cache.SharedIndexInformerOptions{
+			ResyncPeriod: resyncPeriod,
+			Indexers:     indexers,
+			Clock:        clock,
+		}),
+	}
+}
+
+// AddEventHandler adds an event handler to the informer, with the given
+// resync period. The returned Recorder records the notifications of the
+// handler, which can be nil.
+func (d *Driver) AddEventHandler(handler cache.ResourceEventHandler, resyncPeriod time.Duration) *Recorder {
+	r := &Recorder{handler: handler, resyncPeriod: resyncPeriod, objects: map[string]string{}}
+	d.lock.Lock()
+	d.recorders = append(d.recorders, r)
+	d.lock.Unlock()
+	d.Informer.AddEventHandlerWithResyncPeriod(r, resyncPeriod)
+	return r
+}
+
+// Start runs the informer until Stop is called.
+func (d *Driver) Start() {
+	d.stopCh = make(chan struct{})
+	d.stopped = make(chan struct{})
+	go func() {
+		defer close(d.stopped)
+		d.Informer.Run(d.stopCh)
+	}()
+}
+
+// Stop stops the informer and waits for it to return.
+func (d *Driver) Stop() {
+	close(d.stopCh)
+	<-d.stopped
+}
+
+// Step advances the clock.
+func (d *Driver) Step(duration time.Duration) {
+	d.Clock.Step(duration)
+}
+
+// Wait waits for the informer to be quiescent: synced, with the objects of
+// Source in its store, and with all the event handlers done handling their
+// notifications of them. Resync notifications are not waited for, see
+// Resync.
+func (d *Driver) Wait() error {
+	var diff string
+	err := wait.PollImmediate(time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		diff = d.quiescent()
+		return diff == "", nil
+	})
+	if err != nil {
+		return fmt.Errorf("informer is not quiescent: %s", diff)
+	}
+	return nil
+}
+
+// WaitForRecovery steps the clock past the backoff of failed list and watch
+// calls until the informer is quiescent, for example after the watches were
+// ended by Source.WatchError or after clearing a list error.
+func (d *Driver) WaitForRecovery() error {
+	var diff string
+	err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		if diff = d.quiescent(); diff == "" {
+			return true, nil
+		}
+		d.Clock.Step(backoffStep)
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("informer did not recover: %s", diff)
+	}
+	return nil
+}
+
+// Resync steps the clock until all the event handlers added with a resync
+// period were notified of a resync of every object.
+func (d *Driver) Resync() error {
+	d.lock.Lock()
+	var recorders []*Recorder
+	var step time.Duration
+	for _, r := range d.recorders {
+		if r.resyncPeriod == 0 {
+			continue
+		}
+		recorders = append(recorders, r)
+		if step == 0 || r.resyncPeriod < step {
+			step = r.resyncPeriod
+		}
+	}
+	d.lock.Unlock()
+	if len(recorders) == 0 {
+		return fmt.Errorf("no event handler was added with a resync period")
+	}
+
+	keys := d.Source.resourceVersions()
+	since := make([]int, len(recorders))
+	for i, r := range recorders {
+		since[i] = len(r.Notifications())
+	}
+	err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		done := true
+		for i, r := range recorders {
+			resynced := map[string]bool{}
+			for _, n := range r.Notifications()[since[i]:] {
+				if n.Type == Resynced {
+					resynced[n.Key] = true
+				}
+			}
+			for key := range keys {
+				done = done && resynced[key]
+			}
+		}
+		if !done {
+			d.Clock.Step(step)
+		}
+		return done, nil
+	})
+	if err != nil {
+		return fmt.Errorf("event handlers were not resynced")
+	}
+	return nil
+}
+
+// quiescent returns why the informer is not quiescent, or "" if it is.
+func (d *Driver) quiescent() string {
+	if !d.Informer.HasSynced() {
+		return "informer has not synced"
+	}
+	expected := d.Source.resourceVersions()
+	stored := map[string]string{}
+	for _, obj := range d.Informer.GetStore().List() {
+		key, _ := cache.MetaNamespaceKeyFunc(obj)
+		accessor, _ := meta.Accessor(obj)
+		stored[key] = accessor.GetResourceVersion()
+	}
+	if !reflect.DeepEqual(expected, stored) {
+		return fmt.Sprintf("store has %v, expected %v", stored, expected)
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for i, r := range d.recorders {
+		if handled := r.handledObjects(); !reflect.DeepEqual(expected, handled) {
+			return fmt.Sprintf("event handler %d handled %v, expected %v", i, handled, expected)
+		}
+	}
+	return ""
+}
+
+// NotificationType is the type of a notification of an event handler.
+type NotificationType string
+
+// The types of notifications.
+const (
+	Added    NotificationType = "Added"
+	Updated  NotificationType = "Updated"
+	Deleted  NotificationType = "Deleted"
+	Resynced NotificationType = "Resynced"
+)
+
+// Notification is a notification of an event handler about an object.
+// Updates of objects which did not change their resource version, like
+// resyncs, are Resynced notifications.
+type Notification struct {
+	Type            NotificationType
+	Key             string
+	ResourceVersion string
+}
+
+func (n Notification) String() string {
+	return fmt.Sprintf("%s %s@%s", n.Type, n.Key, n.ResourceVersion)
+}
+
+// Recorder records the notifications of an event handler, once the handler
+// is done handling them.
+type Recorder struct {
+	handler      cache.ResourceEventHandler
+	resyncPeriod time.Duration
+
+	lock          sync.Mutex
+	notifications []Notification
+	// objects are the resource versions of the objects handled so far.
+	objects map[string]string
+}
+
+var _ cache.ResourceEventHandler = &Recorder{}
+
+// OnAdd implements cache.ResourceEventHandler.
+func (r *Recorder) OnAdd(obj interface{}) {
+	if r.handler != nil {
+		r.handler.OnAdd(obj)
+	}
+	r.record(Added, obj)
+}
+
+// OnUpdate implements cache.ResourceEventHandler.
+func (r *Recorder) OnUpdate(oldObj, newObj interface{}) {
+	if r.handler != nil {
+		r.handler.OnUpdate(oldObj, newObj)
+	}
+	notificationType := Updated
+	if resourceVersion(oldObj) == resourceVersion(newObj) {
+		notificationType = Resynced
+	}
+	r.record(notificationType, newObj)
+}
+
+// OnDelete implements cache.ResourceEventHandler.
+func (r *Recorder) OnDelete(obj interface{}) {
+	if r.handler != nil {
+		r.handler.OnDelete(obj)
+	}
+	r.record(Deleted, obj)
+}
+
+func (r *Recorder) record(notificationType NotificationType, obj interface{}) {
+	key, _ := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	n := Notification{Type: notificationType, Key: key, ResourceVersion: resourceVersion(obj)}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.notifications = append(r.notifications, n)
+	if notificationType == Deleted {
+		delete(r.objects, key)
+	} else {
+		r.objects[key] = n.ResourceVersion
+	}
+}
+
+// Notifications returns the notifications of the event handler, in order.
+func (r *Recorder) Notifications() []Notification {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	notifications := make([]Notification, len(r.notifications))
+	copy(notifications, r.notifications)
+	return notifications
+}
+
+// Reset forgets the notifications recorded so far.
+func (r *Recorder) Reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.notifications = nil
+}
+
+func (r *Recorder) handledObjects() map[string]string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	objects := make(map[string]string, len(r.objects))
+	for key, rv := range r.objects {
+		objects[key] = rv
+	}
+	return objects
+}
+
+func resourceVersion(obj interface{}) string {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return ""
+	}
+	return accessor.GetResourceVersion()
+}
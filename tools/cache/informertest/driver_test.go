@@ -0,0 +1,118 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informertest
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func newPod(name string) *v1.Pod {
+	return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name}}
+}
+
+func startDriver(t *testing.T, resyncPeriod time.Duration) (*Driver, *Recorder) {
+	d := NewDriver(&v1.Pod{}, resyncPeriod, nil)
+	d.Source.Add(newPod("a"))
+	recorder := d.AddEventHandler(nil, resyncPeriod)
+	d.Start()
+	t.Cleanup(d.Stop)
+	if err := d.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	return d, recorder
+}
+
+func checkNotifications(t *testing.T, recorder *Recorder, expected ...Notification) {
+	t.Helper()
+	if notifications := recorder.Notifications(); len(notifications)+len(expected) > 0 && !reflect.DeepEqual(notifications, expected) {
+		t.Errorf("expected notifications %v, got %v", expected, notifications)
+	}
+	recorder.Reset()
+}
+
+func TestEvents(t *testing.T) {
+	d, recorder := startDriver(t, 0)
+	checkNotifications(t, recorder, Notification{Added, "default/a", "2"})
+
+	b := newPod("b")
+	d.Source.Add(b)
+	d.Source.Update(b)
+	d.Source.Delete(newPod("a"))
+	if err := d.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	checkNotifications(t, recorder,
+		Notification{Added, "default/b", "3"},
+		Notification{Updated, "default/b", "4"},
+		Notification{Deleted, "default/a", "5"},
+	)
+
+	d.Source.Bookmark()
+	if err := wait.PollImmediate(time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		return d.Informer.LastSyncResourceVersion() == "5", nil
+	}); err != nil {
+		t.Errorf("expected the bookmark to be observed, got resource version %q", d.Informer.LastSyncResourceVersion())
+	}
+	checkNotifications(t, recorder)
+}
+
+func TestWatchErrors(t *testing.T) {
+	d, recorder := startDriver(t, 0)
+	recorder.Reset()
+
+	// The informer relists the changes made while it was not watching.
+	d.Source.SetWatchError(errors.New("connection reset"))
+	d.Source.WatchError(apierrors.NewResourceExpired("too old resource version"))
+	d.Source.Compact()
+	d.Source.Add(newPod("b"))
+	d.Source.Delete(newPod("a"))
+	d.Source.SetWatchError(nil)
+	if err := d.WaitForRecovery(); err != nil {
+		t.Fatal(err)
+	}
+	checkNotifications(t, recorder,
+		Notification{Added, "default/b", "3"},
+		Notification{Deleted, "default/a", "2"},
+	)
+
+	// The informer rewatches from the last resource version it observed.
+	d.Source.CloseWatches()
+	d.Source.Update(newPod("b"))
+	if err := d.WaitForRecovery(); err != nil {
+		t.Fatal(err)
+	}
+	checkNotifications(t, recorder, Notification{Updated, "default/b", "5"})
+}
+
+func TestResync(t *testing.T) {
+	d, recorder := startDriver(t, time.Minute)
+	recorder.Reset()
+	if err := d.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	if notifications := recorder.Notifications(); len(notifications) == 0 || notifications[0] != (Notification{Resynced, "default/a", "2"}) {
+		t.Errorf("expected a resync of default/a, got %v", notifications)
+	}
+}
@@ -0,0 +1,225 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informertest
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ListerWatcher is an in-memory cache.ListerWatcher whose objects are
+// changed by tests. Every change gets the next resource version and is sent
+// to the open watches, which can also be sent bookmarks and errors.
+type ListerWatcher struct {
+	exampleObject runtime.Object
+
+	lock        sync.Mutex
+	objects     map[string]runtime.Object
+	rv          int
+	changes     []watch.Event
+	broadcaster *watch.Broadcaster
+	listError   error
+	watchError  error
+}
+
+var _ cache.ListerWatcher = &ListerWatcher{}
+
+// NewListerWatcher returns an empty ListerWatcher of objects of the type of
+// exampleObject.
+func NewListerWatcher(exampleObject runtime.Object) *ListerWatcher {
+	return &ListerWatcher{
+		exampleObject: exampleObject,
+		objects:       map[string]runtime.Object{},
+		rv:            1,
+		broadcaster:   watch.NewBroadcaster(100, watch.WaitIfChannelFull),
+	}
+}
+
+// Add adds an object and sends an added event to the open watches. The
+// resource version of obj is set.
+func (lw *ListerWatcher) Add(obj runtime.Object) {
+	lw.change(watch.Added, obj)
+}
+
+// Update updates an object and sends a modified event to the open watches.
+// The resource version of obj is set.
+func (lw *ListerWatcher) Update(obj runtime.Object) {
+	lw.change(watch.Modified, obj)
+}
+
+// Delete deletes an object and sends a deleted event to the open watches.
+// The resource version of obj is set.
+func (lw *ListerWatcher) Delete(obj runtime.Object) {
+	lw.change(watch.Deleted, obj)
+}
+
+func (lw *ListerWatcher) change(eventType watch.EventType, obj runtime.Object) {
+	lw.lock.Lock()
+	defer lw.lock.Unlock()
+
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		panic(err) // this is test code only
+	}
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		panic(err)
+	}
+	lw.rv++
+	accessor.SetResourceVersion(strconv.Itoa(lw.rv))
+	obj = obj.DeepCopyObject()
+	if eventType == watch.Deleted {
+		delete(lw.objects, key)
+	} else {
+		lw.objects[key] = obj
+	}
+	lw.changes = append(lw.changes, watch.Event{Type: eventType, Object: obj})
+	lw.broadcaster.Action(eventType, obj.DeepCopyObject())
+}
+
+// Bookmark sends a bookmark of the current resource version to the open
+// watches.
+func (lw *ListerWatcher) Bookmark() {
+	lw.lock.Lock()
+	defer lw.lock.Unlock()
+
+	obj := reflect.New(reflect.TypeOf(lw.exampleObject).Elem()).Interface().(runtime.Object)
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		panic(err)
+	}
+	accessor.SetResourceVersion(strconv.Itoa(lw.rv))
+	lw.broadcaster.Action(watch.Bookmark, obj)
+}
+
+// WatchError sends an error event to the open watches, which ends them. A
+// ResourceExpired error makes the informer relist.
+func (lw *ListerWatcher) WatchError(err error) {
+	lw.lock.Lock()
+	defer lw.lock.Unlock()
+
+	status, ok := err.(apierrors.APIStatus)
+	if !ok {
+		status = apierrors.NewInternalError(err)
+	}
+	s := status.Status()
+	lw.broadcaster.Action(watch.Error, &s)
+}
+
+// CloseWatches closes the open watches, as if the connection to the server
+// was lost.
+func (lw *ListerWatcher) CloseWatches() {
+	lw.lock.Lock()
+	defer lw.lock.Unlock()
+	lw.broadcaster.Shutdown()
+	lw.broadcaster = watch.NewBroadcaster(100, watch.WaitIfChannelFull)
+}
+
+// Compact forgets the changes made so far, so that watches from their
+// resource versions fail as expired.
+func (lw *ListerWatcher) Compact() {
+	lw.lock.Lock()
+	defer lw.lock.Unlock()
+	lw.changes = nil
+}
+
+// SetListError makes the list calls fail with err, or succeed again if err
+// is nil.
+func (lw *ListerWatcher) SetListError(err error) {
+	lw.lock.Lock()
+	defer lw.lock.Unlock()
+	lw.listError = err
+}
+
+// SetWatchError makes the watch calls fail with err, or succeed again if err
+// is nil.
+func (lw *ListerWatcher) SetWatchError(err error) {
+	lw.lock.Lock()
+	defer lw.lock.Unlock()
+	lw.watchError = err
+}
+
+// List returns the objects, with the current resource version.
+func (lw *ListerWatcher) List(options metav1.ListOptions) (runtime.Object, error) {
+	lw.lock.Lock()
+	defer lw.lock.Unlock()
+
+	if lw.listError != nil {
+		return nil, lw.listError
+	}
+	items := make([]runtime.Object, 0, len(lw.objects))
+	for _, obj := range lw.objects {
+		items = append(items, obj.DeepCopyObject())
+	}
+	list := &metav1.List{ListMeta: metav1.ListMeta{ResourceVersion: strconv.Itoa(lw.rv)}}
+	if err := meta.SetList(list, items); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Watch returns a watch of the changes after the resource version of
+// options.
+func (lw *ListerWatcher) Watch(options metav1.ListOptions) (watch.Interface, error) {
+	lw.lock.Lock()
+	defer lw.lock.Unlock()
+
+	if lw.watchError != nil {
+		return nil, lw.watchError
+	}
+	rv := lw.rv
+	if options.ResourceVersion != "" && options.ResourceVersion != "0" {
+		var err error
+		if rv, err = strconv.Atoi(options.ResourceVersion); err != nil {
+			return nil, apierrors.NewBadRequest(fmt.Sprintf("invalid resource version %q", options.ResourceVersion))
+		}
+	}
+	if rv > lw.rv {
+		return nil, apierrors.NewTimeoutError(fmt.Sprintf("too large resource version: %d, current: %d", rv, lw.rv), 1)
+	}
+	// The changes are those from the resource version lw.rv-len(changes)+1.
+	oldest := lw.rv - len(lw.changes)
+	if rv < oldest {
+		return nil, apierrors.NewResourceExpired(fmt.Sprintf("too old resource version: %d (%d)", rv, oldest))
+	}
+	prefix := make([]watch.Event, 0, lw.rv-rv)
+	for _, e := range lw.changes[rv-oldest:] {
+		prefix = append(prefix, watch.Event{Type: e.Type, Object: e.Object.DeepCopyObject()})
+	}
+	return lw.broadcaster.WatchWithPrefix(prefix), nil
+}
+
+// resourceVersions returns the resource versions of the objects by key.
+func (lw *ListerWatcher) resourceVersions() map[string]string {
+	lw.lock.Lock()
+	defer lw.lock.Unlock()
+	rvs := make(map[string]string, len(lw.objects))
+	for key, obj := range lw.objects {
+		accessor, _ := meta.Accessor(obj)
+		rvs[key] = accessor.GetResourceVersion()
+	}
+	return rvs
+}
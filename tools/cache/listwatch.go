@@ -18,12 +18,15 @@ package cache
 
 import (
 	"context"
+	"strings"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
 	restclient "k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
 )
 
 // Lister is any object that knows how to perform an initial list.
@@ -110,3 +113,78 @@ func (lw *ListWatch) List(options metav1.ListOptions) (runtime.Object, error) {
 func (lw *ListWatch) Watch(options metav1.ListOptions) (watch.Interface, error) {
 	return lw.WatchFunc(options)
 }
+
+// ListConsistency describes the consistency required from lists that do not
+// request a specific resource version.
+type ListConsistency string
+
+const (
+	// ListConsistencyUnspecified leaves the resource version of lists unchanged.
+	ListConsistencyUnspecified ListConsistency = ""
+	// ListConsistencyCached serves lists from the watch cache of the apiserver
+	// (resource version "0"). It is cheaper but the lists may be stale.
+	ListConsistencyCached ListConsistency = "Cached"
+	// ListConsistencyQuorum serves consistent lists read from etcd (resource
+	// version "").
+	ListConsistencyQuorum ListConsistency = "Quorum"
+)
+
+// ResourceVersionFallbackListWatch wraps a ListerWatcher to apply a list
+// consistency and to handle lists that cannot be served by the watch cache of
+// the apiserver. Watches are passed through unchanged.
+type ResourceVersionFallbackListWatch struct {
+	ListerWatcher
+	// Consistency is applied to the lists that do not request a specific
+	// resource version, i.e. whose resource version is "" or "0", and that do
+	// not continue a paginated list.
+	Consistency ListConsistency
+}
+
+// NewResourceVersionFallbackListWatch returns a ListerWatcher listing with the
+// given consistency. When a list served from the watch cache fails because
+// the cache is unavailable, for example while it is (re)initializing, it is
+// retried once with a consistent read (resource version ""), which returns
+// data at least as fresh as the cache would have. Lists at an exact resource
+// version and continued lists are not retried, and neither are lists whose
+// resource version expired or is too large: reflectors and pagers handle
+// these themselves.
+func NewResourceVersionFallbackListWatch(lw ListerWatcher, consistency ListConsistency) *ResourceVersionFallbackListWatch {
+	return &ResourceVersionFallbackListWatch{ListerWatcher: lw, Consistency: consistency}
+}
+
+// List lists with the configured consistency, falling back to a consistent
+// read if the watch cache is unavailable.
+func (lw *ResourceVersionFallbackListWatch) List(options metav1.ListOptions) (runtime.Object, error) {
+	// The resource version of a continued list is set by its continue token.
+	if options.Continue != "" {
+		return lw.ListerWatcher.List(options)
+	}
+	if options.ResourceVersion == "" || options.ResourceVersion == "0" {
+		switch lw.Consistency {
+		case ListConsistencyCached:
+			options.ResourceVersion = "0"
+		case ListConsistencyQuorum:
+			options.ResourceVersion = ""
+			options.ResourceVersionMatch = ""
+		}
+	}
+
+	list, err := lw.ListerWatcher.List(options)
+	if err == nil || options.ResourceVersion == "" || options.ResourceVersionMatch == metav1.ResourceVersionMatchExact || !isWatchCacheUnavailableError(err) {
+		return list, err
+	}
+	klog.V(4).Infof("List at resource version %q failed with %v, falling back to a consistent read", options.ResourceVersion, err)
+	options.ResourceVersion = ""
+	options.ResourceVersionMatch = ""
+	return lw.ListerWatcher.List(options)
+}
+
+// isWatchCacheUnavailableError returns whether err reports that the watch
+// cache of the apiserver cannot serve requests yet. The apiserver does not set
+// a status cause for it, so the message is matched.
+func isWatchCacheUnavailableError(err error) bool {
+	if !apierrors.IsTooManyRequests(err) && !apierrors.IsServiceUnavailable(err) {
+		return false
+	}
+	return strings.Contains(err.Error(), "storage is (re)initializing")
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestResourceVersionFallbackListWatch(t *testing.T) {
+	tooLargeErr := apierrors.NewTimeoutError("too large resource version", 1)
+	tooLargeErr.ErrStatus.Details.Causes = []metav1.StatusCause{{Type: metav1.CauseTypeResourceVersionTooLarge}}
+	initializingErr := apierrors.NewTooManyRequests("storage is (re)initializing", 1)
+
+	tests := []struct {
+		name        string
+		consistency ListConsistency
+		options     metav1.ListOptions
+		listErr     error
+		expectedRVs []string
+		expectErr   bool
+	}{
+		{
+			name:        "unspecified consistency",
+			options:     metav1.ListOptions{ResourceVersion: "0"},
+			expectedRVs: []string{"0"},
+		},
+		{
+			name:        "cached",
+			consistency: ListConsistencyCached,
+			expectedRVs: []string{"0"},
+		},
+		{
+			name:        "quorum",
+			consistency: ListConsistencyQuorum,
+			options:     metav1.ListOptions{ResourceVersion: "0"},
+			expectedRVs: []string{""},
+		},
+		{
+			name:        "specific resource version",
+			consistency: ListConsistencyCached,
+			options:     metav1.ListOptions{ResourceVersion: "10"},
+			expectedRVs: []string{"10"},
+		},
+		{
+			name:        "watch cache unavailable",
+			consistency: ListConsistencyCached,
+			listErr:     initializingErr,
+			expectedRVs: []string{"0", ""},
+		},
+		{
+			name:        "watch cache unavailable at a resource version",
+			options:     metav1.ListOptions{ResourceVersion: "10", ResourceVersionMatch: metav1.ResourceVersionMatchNotOlderThan},
+			listErr:     initializingErr,
+			expectedRVs: []string{"10", ""},
+		},
+		{
+			name:        "watch cache unavailable at an exact resource version",
+			options:     metav1.ListOptions{ResourceVersion: "10", ResourceVersionMatch: metav1.ResourceVersionMatchExact},
+			listErr:     initializingErr,
+			expectedRVs: []string{"10"},
+			expectErr:   true,
+		},
+		{
+			name:        "throttled",
+			consistency: ListConsistencyCached,
+			listErr:     apierrors.NewTooManyRequests("too many requests", 1),
+			expectedRVs: []string{"0"},
+			expectErr:   true,
+		},
+		{
+			name:        "expired resource version",
+			consistency: ListConsistencyCached,
+			options:     metav1.ListOptions{ResourceVersion: "10", ResourceVersionMatch: metav1.ResourceVersionMatchExact},
+			listErr:     apierrors.NewResourceExpired("too old"),
+			expectedRVs: []string{"10"},
+			expectErr:   true,
+		},
+		{
+			name:        "too large resource version",
+			options:     metav1.ListOptions{ResourceVersion: "10"},
+			listErr:     tooLargeErr,
+			expectedRVs: []string{"10"},
+			expectErr:   true,
+		},
+		{
+			name:        "continued list",
+			consistency: ListConsistencyQuorum,
+			options:     metav1.ListOptions{Continue: "token"},
+			expectedRVs: []string{""},
+		},
+		{
+			name:        "continued list with watch cache unavailable",
+			consistency: ListConsistencyCached,
+			options:     metav1.ListOptions{Continue: "token"},
+			listErr:     initializingErr,
+			expectedRVs: []string{""},
+			expectErr:   true,
+		},
+		{
+			name:        "other error",
+			options:     metav1.ListOptions{ResourceVersion: "10"},
+			listErr:     apierrors.NewInternalError(errors.New("boom")),
+			expectedRVs: []string{"10"},
+			expectErr:   true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var rvs []string
+			lw := NewResourceVersionFallbackListWatch(&ListWatch{
+				ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+					rvs = append(rvs, options.ResourceVersion)
+					if (options.ResourceVersion != "" || options.Continue != "") && test.listErr != nil {
+						return nil, test.listErr
+					}
+					if options.ResourceVersion == "" && options.ResourceVersionMatch != "" {
+						t.Errorf("unexpected resourceVersionMatch %q for a consistent read", options.ResourceVersionMatch)
+					}
+					return &v1.PodList{}, nil
+				},
+			}, test.consistency)
+
+			_, err := lw.List(test.options)
+			if (err != nil) != test.expectErr {
+				t.Errorf("expected error %v, got %v", test.expectErr, err)
+			}
+			if !reflect.DeepEqual(test.expectedRVs, rvs) {
+				t.Errorf("expected lists at %q, got %q", test.expectedRVs, rvs)
+			}
+		})
+	}
+}
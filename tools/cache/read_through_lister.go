@@ -0,0 +1,144 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilcache "k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/utils/clock"
+)
+
+// ReadThroughGetFunc gets the live object of the given namespace, empty for
+// cluster scoped objects, and name, returning a NotFound error if it does not
+// exist.
+type ReadThroughGetFunc func(ctx context.Context, namespace, name string) (runtime.Object, error)
+
+// ReadThroughListerOptions configures a read-through lister.
+type ReadThroughListerOptions struct {
+	// NotFoundTTL is how long the objects found not to exist are not read
+	// again, 5 seconds by default.
+	NotFoundTTL time.Duration
+	// MaxNotFound bounds the number of objects remembered as not existing,
+	// 1000 by default.
+	MaxNotFound int
+	// Clock defaults to the real clock.
+	Clock clock.PassiveClock
+}
+
+// NewReadThroughLister returns a GenericLister getting the objects missing
+// from lister with get, for example while the informer of lister catches up.
+// The objects which do not exist are remembered for a short time, so that
+// reconciling references to optional objects does not get them repeatedly.
+// Lists are served by lister.
+func NewReadThroughLister(lister GenericLister, get ReadThroughGetFunc, options ReadThroughListerOptions) GenericLister {
+	if options.NotFoundTTL <= 0 {
+		options.NotFoundTTL = 5 * time.Second
+	}
+	if options.MaxNotFound <= 0 {
+		options.MaxNotFound = 1000
+	}
+	if options.Clock == nil {
+		options.Clock = clock.RealClock{}
+	}
+	return &readThroughLister{
+		lister: lister,
+		readThrough: &readThrough{
+			get:      get,
+			ttl:      options.NotFoundTTL,
+			notFound: utilcache.NewLRUExpireCacheWithClock(options.MaxNotFound, options.Clock),
+		},
+	}
+}
+
+// readThrough gets objects and remembers those which do not exist.
+type readThrough struct {
+	get      ReadThroughGetFunc
+	ttl      time.Duration
+	notFound *utilcache.LRUExpireCache
+}
+
+// read returns the object of namespace and name, for a lister which did not
+// find it with err.
+func (r *readThrough) read(namespace, name string, err error) (runtime.Object, error) {
+	if !errors.IsNotFound(err) {
+		return nil, err
+	}
+	key := name
+	if namespace != "" {
+		key = namespace + "/" + name
+	}
+	if notFound, exists := r.notFound.Get(key); exists {
+		return nil, notFound.(error)
+	}
+	obj, err := r.get(context.TODO(), namespace, name)
+	if errors.IsNotFound(err) {
+		r.notFound.Add(key, err, r.ttl)
+	}
+	return obj, err
+}
+
+type readThroughLister struct {
+	lister      GenericLister
+	readThrough *readThrough
+}
+
+func (l *readThroughLister) List(selector labels.Selector) ([]runtime.Object, error) {
+	return l.lister.List(selector)
+}
+
+func (l *readThroughLister) Get(key string) (runtime.Object, error) {
+	obj, err := l.lister.Get(key)
+	if err == nil {
+		return obj, nil
+	}
+	namespace, name, splitErr := SplitMetaNamespaceKey(key)
+	if splitErr != nil {
+		return nil, err
+	}
+	return l.readThrough.read(namespace, name, err)
+}
+
+func (l *readThroughLister) ByNamespace(namespace string) GenericNamespaceLister {
+	return &readThroughNamespaceLister{
+		lister:      l.lister.ByNamespace(namespace),
+		namespace:   namespace,
+		readThrough: l.readThrough,
+	}
+}
+
+type readThroughNamespaceLister struct {
+	lister      GenericNamespaceLister
+	namespace   string
+	readThrough *readThrough
+}
+
+func (l *readThroughNamespaceLister) List(selector labels.Selector) ([]runtime.Object, error) {
+	return l.lister.List(selector)
+}
+
+func (l *readThroughNamespaceLister) Get(name string) (runtime.Object, error) {
+	obj, err := l.lister.Get(name)
+	if err == nil {
+		return obj, nil
+	}
+	return l.readThrough.read(l.namespace, name, err)
+}
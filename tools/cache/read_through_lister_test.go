@@ -0,0 +1,95 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestReadThroughLister(t *testing.T) {
+	resource := schema.GroupResource{Resource: "configmaps"}
+	indexer := NewIndexer(MetaNamespaceKeyFunc, Indexers{NamespaceIndex: MetaNamespaceIndexFunc})
+	indexer.Add(&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "cached"}})
+	live := map[string]runtime.Object{
+		"ns/live": &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "live"}},
+	}
+	gets := map[string]int{}
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	lister := NewReadThroughLister(NewGenericLister(indexer, resource), func(ctx context.Context, namespace, name string) (runtime.Object, error) {
+		key := namespace + "/" + name
+		gets[key]++
+		if name == "failing" {
+			return nil, errors.New("unavailable")
+		}
+		if obj, ok := live[key]; ok {
+			return obj, nil
+		}
+		return nil, apierrors.NewNotFound(resource, name)
+	}, ReadThroughListerOptions{NotFoundTTL: time.Second, Clock: fakeClock})
+
+	if _, err := lister.ByNamespace("ns").Get("cached"); err != nil || gets["ns/cached"] != 0 {
+		t.Errorf("expected cached objects to be served by the lister, got %v after %d gets", err, gets["ns/cached"])
+	}
+	if obj, err := lister.Get("ns/live"); err != nil || obj.(*v1.ConfigMap).Name != "live" {
+		t.Errorf("expected live objects to be read through, got %v, %v", obj, err)
+	}
+	lister.ByNamespace("ns").Get("live")
+	if gets["ns/live"] != 2 {
+		t.Errorf("expected existing objects to be read every time, got %d gets", gets["ns/live"])
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := lister.ByNamespace("ns").Get("missing"); !apierrors.IsNotFound(err) {
+			t.Errorf("expected a NotFound error, got %v", err)
+		}
+		if _, err := lister.Get("ns/missing"); !apierrors.IsNotFound(err) {
+			t.Errorf("expected a NotFound error, got %v", err)
+		}
+	}
+	if gets["ns/missing"] != 1 {
+		t.Errorf("expected missing objects to be remembered, got %d gets", gets["ns/missing"])
+	}
+	fakeClock.Step(2 * time.Second)
+	lister.ByNamespace("ns").Get("missing")
+	if gets["ns/missing"] != 2 {
+		t.Errorf("expected missing objects to be read again after the ttl, got %d gets", gets["ns/missing"])
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := lister.ByNamespace("ns").Get("failing"); err == nil || apierrors.IsNotFound(err) {
+			t.Errorf("expected the get error, got %v", err)
+		}
+	}
+	if gets["ns/failing"] != 2 {
+		t.Errorf("expected errors not to be remembered, got %d gets", gets["ns/failing"])
+	}
+
+	if objs, err := lister.List(labels.Everything()); err != nil || len(objs) != 1 {
+		t.Errorf("expected lists to be served by the lister, got %v, %v", objs, err)
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/go-logr/logr"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -37,8 +38,10 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/metrics"
 	"k8s.io/client-go/tools/pager"
-	"k8s.io/klog/v2"
+	"k8s.io/client-go/util/backoff"
+	"k8s.io/client-go/util/logging"
 	"k8s.io/utils/clock"
 	"k8s.io/utils/trace"
 )
@@ -89,6 +92,10 @@ type Reflector struct {
 	isLastSyncResourceVersionUnavailable bool
 	// lastSyncResourceVersionMutex guards read/write access to lastSyncResourceVersion
 	lastSyncResourceVersionMutex sync.RWMutex
+	// lastEventTime is the time the last watch event was received.
+	lastEventTime time.Time
+	// lastEventTimeMutex guards read/write access to lastEventTime
+	lastEventTimeMutex sync.RWMutex
 	// WatchListPageSize is the requested chunk size of initial and resync watch lists.
 	// If unset, for consistent reads (RV="") or reads that opt-into arbitrarily old data
 	// (RV="0") it will default to pager.PageSize, for the rest (RV != "" && RV != "0")
@@ -97,8 +104,20 @@ type Reflector struct {
 	// etcd, which is significantly less efficient and may lead to serious performance and
 	// scalability problems.
 	WatchListPageSize int64
+	// MaxWatchDuration, if positive, is the maximum time a single watch is kept open.
+	// The watch is then stopped and re-established from the last observed resource version,
+	// so that long-lived watches get redistributed across apiservers and are not cut by
+	// proxies enforcing connection limits. It also caps the server-side watch timeout.
+	MaxWatchDuration time.Duration
+	// ResourceVersionComparator, if set, compares the resource versions of lists and watch
+	// events, which are otherwise opaque, with the last synced one. Watch events the reflector
+	// already observed are then ignored, and a list older than the last synced resource
+	// version fails, the reflector relisting with a consistent read.
+	ResourceVersionComparator ResourceVersionStringComparator
 	// Called whenever the ListAndWatch drops the connection with an error.
 	watchErrorHandler WatchErrorHandler
+	// logger carries the name and expected type of the reflector.
+	logger logr.Logger
 }
 
 // ResourceVersionUpdater is an interface that allows store implementation to
@@ -129,11 +148,11 @@ func DefaultWatchErrorHandler(r *Reflector, err error) {
 		// Don't set LastSyncResourceVersionUnavailable - LIST call with ResourceVersion=RV already
 		// has a semantic that it returns data at least as fresh as provided RV.
 		// So first try to LIST with setting RV to resource version of last observed object.
-		klog.V(4).Infof("%s: watch of %v closed with: %v", r.name, r.expectedTypeName, err)
+		r.logger.V(4).Info("Watch closed", "err", err)
 	case err == io.EOF:
 		// watch closed normally
 	case err == io.ErrUnexpectedEOF:
-		klog.V(1).Infof("%s: Watch for %v closed with unexpected EOF: %v", r.name, r.expectedTypeName, err)
+		r.logger.V(1).Info("Watch closed with unexpected EOF", "err", err)
 	default:
 		utilruntime.HandleError(fmt.Errorf("%s: Failed to watch %v: %v", r.name, r.expectedTypeName, err))
 	}
@@ -167,6 +186,12 @@ func NewReflector(lw ListerWatcher, expectedType interface{}, store Store, resyn
 	return NewNamedReflector(naming.GetNameFromCallsite(internalPackages...), lw, expectedType, store, resyncPeriod)
 }
 
+// newReflectorBackoffManager returns the backoff used between failed list and watch calls.
+func newReflectorBackoffManager(c clock.Clock) *backoff.Manager {
+	return backoff.NewManager(backoff.WithJitter(backoff.Exponential(800*time.Millisecond, 30*time.Second, 2.0), 1.0), c).
+		ResetOnDuration(2 * time.Minute)
+}
+
 // NewNamedReflector same as NewReflector, but with a specified name for logging
 func NewNamedReflector(name string, lw ListerWatcher, expectedType interface{}, store Store, resyncPeriod time.Duration) *Reflector {
 	realClock := &clock.RealClock{}
@@ -177,8 +202,8 @@ func NewNamedReflector(name string, lw ListerWatcher, expectedType interface{},
 		// We used to make the call every 1sec (1 QPS), the goal here is to achieve ~98% traffic reduction when
 		// API server is not healthy. With these parameters, backoff will stop at [30,60) sec interval which is
 		// 0.22 QPS. If we don't backoff for 2min, assume API server is healthy and we reset the backoff.
-		backoffManager:         wait.NewExponentialBackoffManager(800*time.Millisecond, 30*time.Second, 2*time.Minute, 2.0, 1.0, realClock),
-		initConnBackoffManager: wait.NewExponentialBackoffManager(800*time.Millisecond, 30*time.Second, 2*time.Minute, 2.0, 1.0, realClock),
+		backoffManager:         newReflectorBackoffManager(realClock),
+		initConnBackoffManager: newReflectorBackoffManager(realClock),
 		resyncPeriod:           resyncPeriod,
 		clock:                  realClock,
 		watchErrorHandler:      WatchErrorHandler(DefaultWatchErrorHandler),
@@ -191,23 +216,32 @@ func (r *Reflector) setExpectedType(expectedType interface{}) {
 	r.expectedType = reflect.TypeOf(expectedType)
 	if r.expectedType == nil {
 		r.expectedTypeName = defaultExpectedTypeName
+		r.logger = reflectorLogger(r.name, r.expectedTypeName)
 		return
 	}
 
 	r.expectedTypeName = r.expectedType.String()
+	r.logger = reflectorLogger(r.name, r.expectedTypeName)
 
 	if obj, ok := expectedType.(*unstructured.Unstructured); ok {
 		// Use gvk to check that watch event objects are of the desired type.
 		gvk := obj.GroupVersionKind()
 		if gvk.Empty() {
-			klog.V(4).Infof("Reflector from %s configured with expectedType of *unstructured.Unstructured with empty GroupVersionKind.", r.name)
+			r.logger.V(4).Info("Reflector configured with expectedType of *unstructured.Unstructured with empty GroupVersionKind")
 			return
 		}
 		r.expectedGVK = &gvk
 		r.expectedTypeName = gvk.String()
+		r.logger = reflectorLogger(r.name, r.expectedTypeName)
 	}
 }
 
+// reflectorLogger returns the logger of the reflector named name, built once
+// rather than for every message.
+func reflectorLogger(name, expectedTypeName string) logr.Logger {
+	return logging.Logger().WithValues("reflector", name, "type", expectedTypeName)
+}
+
 // internalPackages are packages that ignored when creating a default reflector name. These packages are in the common
 // call chains to NewReflector, so they'd be low entropy names for reflectors
 var internalPackages = []string{"client-go/tools/cache/"}
@@ -216,13 +250,13 @@ var internalPackages = []string{"client-go/tools/cache/"}
 // objects and subsequent deltas.
 // Run will exit when stopCh is closed.
 func (r *Reflector) Run(stopCh <-chan struct{}) {
-	klog.V(3).Infof("Starting reflector %s (%s) from %s", r.expectedTypeName, r.resyncPeriod, r.name)
+	r.logger.V(3).Info("Starting reflector", "resyncPeriod", r.resyncPeriod)
 	wait.BackoffUntil(func() {
 		if err := r.ListAndWatch(stopCh); err != nil {
 			r.watchErrorHandler(r, err)
 		}
 	}, r.backoffManager, true, stopCh)
-	klog.V(3).Infof("Stopping reflector %s (%s) from %s", r.expectedTypeName, r.resyncPeriod, r.name)
+	r.logger.V(3).Info("Stopping reflector", "resyncPeriod", r.resyncPeriod)
 }
 
 var (
@@ -252,7 +286,7 @@ func (r *Reflector) resyncChan() (<-chan time.Time, func() bool) {
 // and then use the resource version to watch.
 // It returns error if ListAndWatch didn't even try to initialize watch.
 func (r *Reflector) ListAndWatch(stopCh <-chan struct{}) error {
-	klog.V(3).Infof("Listing and watching %v from %s", r.expectedTypeName, r.name)
+	r.logger.V(3).Info("Listing and watching")
 	var resourceVersion string
 
 	options := metav1.ListOptions{ResourceVersion: r.relistResourceVersion()}
@@ -321,7 +355,7 @@ func (r *Reflector) ListAndWatch(stopCh <-chan struct{}) error {
 		}
 		initTrace.Step("Objects listed", trace.Field{"error", err})
 		if err != nil {
-			klog.Warningf("%s: failed to list %v: %v", r.name, r.expectedTypeName, err)
+			logging.Warning(r.logger, "Failed to list", "err", err)
 			return fmt.Errorf("failed to list %v: %v", r.expectedTypeName, err)
 		}
 
@@ -345,6 +379,10 @@ func (r *Reflector) ListAndWatch(stopCh <-chan struct{}) error {
 			return fmt.Errorf("unable to understand list result %#v: %v", list, err)
 		}
 		resourceVersion = listMetaInterface.GetResourceVersion()
+		if r.isStaleList(resourceVersion) {
+			r.setIsLastSyncResourceVersionUnavailable(true)
+			return fmt.Errorf("list of %v at resource version %q is older than the last synced resource version %q", r.expectedTypeName, resourceVersion, r.LastSyncResourceVersion())
+		}
 		initTrace.Step("Resource version extracted")
 		items, err := meta.ExtractList(list)
 		if err != nil {
@@ -379,7 +417,7 @@ func (r *Reflector) ListAndWatch(stopCh <-chan struct{}) error {
 				return
 			}
 			if r.ShouldResync == nil || r.ShouldResync() {
-				klog.V(4).Infof("%s: forcing resync", r.name)
+				r.logger.V(4).Info("Forcing resync")
 				if err := r.store.Resync(); err != nil {
 					resyncerrc <- err
 					return
@@ -399,6 +437,9 @@ func (r *Reflector) ListAndWatch(stopCh <-chan struct{}) error {
 		}
 
 		timeoutSeconds := int64(minWatchTimeout.Seconds() * (rand.Float64() + 1.0))
+		if maxSeconds := int64(r.MaxWatchDuration.Seconds()); maxSeconds > 0 && maxSeconds < timeoutSeconds {
+			timeoutSeconds = maxSeconds
+		}
 		options = metav1.ListOptions{
 			ResourceVersion: resourceVersion,
 			// We want to avoid situations of hanging watchers. Stop any wachers that do not
@@ -420,11 +461,14 @@ func (r *Reflector) ListAndWatch(stopCh <-chan struct{}) error {
 			// If that's the case begin exponentially backing off and resend watch request.
 			// Do the same for "429" errors.
 			if utilnet.IsConnectionRefused(err) || apierrors.IsTooManyRequests(err) {
+				r.watchRestarted(metrics.WatchRestartReasonThrottled)
 				<-r.initConnBackoffManager.Backoff().C()
 				continue
 			}
+			r.watchRestarted(metrics.WatchRestartReasonError)
 			return err
 		}
+		metrics.WatchDisconnected.Set(r.name, r.expectedTypeName, nil)
 
 		if err := r.watchHandler(start, w, &resourceVersion, resyncerrc, stopCh); err != nil {
 			if err != errorStopRequested {
@@ -433,20 +477,34 @@ func (r *Reflector) ListAndWatch(stopCh <-chan struct{}) error {
 					// Don't set LastSyncResourceVersionUnavailable - LIST call with ResourceVersion=RV already
 					// has a semantic that it returns data at least as fresh as provided RV.
 					// So first try to LIST with setting RV to resource version of last observed object.
-					klog.V(4).Infof("%s: watch of %v closed with: %v", r.name, r.expectedTypeName, err)
+					r.logger.V(4).Info("Watch closed", "err", err)
+					r.watchRestarted(metrics.WatchRestartReasonExpired)
 				case apierrors.IsTooManyRequests(err):
-					klog.V(2).Infof("%s: watch of %v returned 429 - backing off", r.name, r.expectedTypeName)
+					r.logger.V(2).Info("Watch returned 429 - backing off")
+					r.watchRestarted(metrics.WatchRestartReasonThrottled)
 					<-r.initConnBackoffManager.Backoff().C()
 					continue
 				default:
-					klog.Warningf("%s: watch of %v ended with: %v", r.name, r.expectedTypeName, err)
+					logging.Warning(r.logger, "Watch ended", "err", err)
+					r.watchRestarted(metrics.WatchRestartReasonError)
 				}
+			} else {
+				metrics.WatchDisconnected.Set(r.name, r.expectedTypeName, nil)
 			}
 			return nil
 		}
+		r.watchRestarted(metrics.WatchRestartReasonClosed)
 	}
 }
 
+// watchRestarted records that the watch ended for the given reason and that
+// the reflector has no established watch until the next one starts.
+func (r *Reflector) watchRestarted(reason string) {
+	metrics.WatchRestarts.Increment(r.name, r.expectedTypeName, reason)
+	since := r.clock.Now()
+	metrics.WatchDisconnected.Set(r.name, r.expectedTypeName, &since)
+}
+
 // syncWith replaces the store's items with the given list.
 func (r *Reflector) syncWith(items []runtime.Object, resourceVersion string) error {
 	found := make([]interface{}, 0, len(items))
@@ -464,6 +522,13 @@ func (r *Reflector) watchHandler(start time.Time, w watch.Interface, resourceVer
 	// we're coming back in with the same watch interface.
 	defer w.Stop()
 
+	var maxDurationCh <-chan time.Time
+	if r.MaxWatchDuration > 0 {
+		timer := r.clock.NewTimer(r.MaxWatchDuration)
+		defer timer.Stop()
+		maxDurationCh = timer.C()
+	}
+
 loop:
 	for {
 		select {
@@ -471,10 +536,14 @@ loop:
 			return errorStopRequested
 		case err := <-errc:
 			return err
+		case <-maxDurationCh:
+			r.logger.V(4).Info("Watch reached the maximum duration, restarting", "maxWatchDuration", r.MaxWatchDuration)
+			break loop
 		case event, ok := <-w.ResultChan():
 			if !ok {
 				break loop
 			}
+			r.setLastEventTime(r.clock.Now())
 			if event.Type == watch.Error {
 				return apierrors.FromObject(event.Object)
 			}
@@ -496,6 +565,10 @@ loop:
 				continue
 			}
 			newResourceVersion := meta.GetResourceVersion()
+			if IsStaleEvent(r.ResourceVersionComparator, event.Type, newResourceVersion, *resourceVersion) {
+				r.logger.V(4).Info("Ignoring stale watch event", "eventType", event.Type, "resourceVersion", newResourceVersion, "lastResourceVersion", *resourceVersion)
+				continue
+			}
 			switch event.Type {
 			case watch.Added:
 				err := r.store.Add(event.Object)
@@ -533,7 +606,7 @@ loop:
 	if watchDuration < 1*time.Second && eventCount == 0 {
 		return fmt.Errorf("very short watch: %s: Unexpected watch close - watch lasted less than a second and no items received", r.name)
 	}
-	klog.V(4).Infof("%s: Watch close - %v total %v items received", r.name, r.expectedTypeName, eventCount)
+	r.logger.V(4).Info("Watch closed", "items", eventCount)
 	return nil
 }
 
@@ -551,6 +624,21 @@ func (r *Reflector) setLastSyncResourceVersion(v string) {
 	r.lastSyncResourceVersion = v
 }
 
+// LastEventTime returns the time the last watch event was received, or the
+// zero time if none was received yet. A watch that has been established for a
+// long time without events may be silently stale.
+func (r *Reflector) LastEventTime() time.Time {
+	r.lastEventTimeMutex.RLock()
+	defer r.lastEventTimeMutex.RUnlock()
+	return r.lastEventTime
+}
+
+func (r *Reflector) setLastEventTime(t time.Time) {
+	r.lastEventTimeMutex.Lock()
+	defer r.lastEventTimeMutex.Unlock()
+	r.lastEventTime = t
+}
+
 // relistResourceVersion determines the resource version the reflector should list or relist from.
 // Returns either the lastSyncResourceVersion so that this reflector will relist with a resource
 // versions no older than has already been observed in relist results or watch events, or, if the last relist resulted
@@ -574,6 +662,17 @@ func (r *Reflector) relistResourceVersion() string {
 	return r.lastSyncResourceVersion
 }
 
+// isStaleList returns true if the resource version comparator determines that a list at
+// resourceVersion is older than the last synced resource version.
+func (r *Reflector) isStaleList(resourceVersion string) bool {
+	lastSyncResourceVersion := r.LastSyncResourceVersion()
+	if r.ResourceVersionComparator == nil || lastSyncResourceVersion == "" {
+		return false
+	}
+	cmp, err := r.ResourceVersionComparator.CompareResourceVersions(resourceVersion, lastSyncResourceVersion)
+	return err == nil && cmp < 0
+}
+
 // setIsLastSyncResourceVersionUnavailable sets if the last list or watch request with lastSyncResourceVersion returned
 // "expired" or "too large resource version" error.
 func (r *Reflector) setIsLastSyncResourceVersionUnavailable(isUnavailable bool) {
@@ -22,6 +22,7 @@ import (
 	"math/rand"
 	"reflect"
 	"strconv"
+	"strings"
 	"syscall"
 	"testing"
 	"time"
@@ -34,6 +35,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/metrics"
 	"k8s.io/utils/clock"
 	testingclock "k8s.io/utils/clock/testing"
 )
@@ -199,6 +201,108 @@ func TestReflectorWatchHandler(t *testing.T) {
 	if e, a := "32", g.LastSyncResourceVersion(); e != a {
 		t.Errorf("expected %v, got %v", e, a)
 	}
+
+	if g.LastEventTime().IsZero() {
+		t.Errorf("expected last event time to be set")
+	}
+}
+
+func TestReflectorWatchHandlerMaxWatchDuration(t *testing.T) {
+	s := NewStore(MetaNamespaceKeyFunc)
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	g := NewReflector(&testLW{}, &v1.Pod{}, s, 0)
+	g.clock = fakeClock
+	g.MaxWatchDuration = time.Minute
+	fw := watch.NewFake()
+
+	errCh := make(chan error)
+	var resumeRV string
+	go func() {
+		errCh <- g.watchHandler(fakeClock.Now(), fw, &resumeRV, nevererrc, wait.NeverStop)
+	}()
+	fw.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo", ResourceVersion: "7"}})
+	if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		return fakeClock.HasWaiters(), nil
+	}); err != nil {
+		t.Fatalf("expected the watch handler to wait for the maximum duration: %v", err)
+	}
+	fakeClock.Step(time.Minute)
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("expected the watch to be stopped after the maximum duration")
+	}
+	if !fw.IsStopped() {
+		t.Errorf("expected the watch to be stopped")
+	}
+	if e, a := "7", resumeRV; e != a {
+		t.Errorf("expected resume resource version %v, got %v", e, a)
+	}
+}
+
+func TestReflectorWatchHandlerStaleEvents(t *testing.T) {
+	s := NewStore(MetaNamespaceKeyFunc)
+	g := NewReflector(&testLW{}, &v1.Pod{}, s, 0)
+	g.ResourceVersionComparator = IntegerResourceVersions
+	fw := watch.NewFake()
+	go func() {
+		fw.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo", ResourceVersion: "10"}})
+		// replayed by a caching proxy
+		fw.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "stale", ResourceVersion: "9"}})
+		fw.Modify(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo", ResourceVersion: "10", Labels: map[string]string{"stale": "true"}}})
+		fw.Action(watch.Bookmark, &v1.Pod{ObjectMeta: metav1.ObjectMeta{ResourceVersion: "8"}})
+		fw.Modify(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo", ResourceVersion: "11"}})
+		fw.Stop()
+	}()
+	resumeRV := "5"
+	if err := g.watchHandler(time.Now(), fw, &resumeRV, nevererrc, wait.NeverStop); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	if _, exists, _ := s.GetByKey("stale"); exists {
+		t.Errorf("expected the stale event to be ignored")
+	}
+	obj, exists, _ := s.GetByKey("foo")
+	if !exists || obj.(*v1.Pod).ResourceVersion != "11" || len(obj.(*v1.Pod).Labels) > 0 {
+		t.Errorf("unexpected object %#v", obj)
+	}
+	if e, a := "11", resumeRV; e != a {
+		t.Errorf("expected resume resource version %v, got %v", e, a)
+	}
+}
+
+func TestReflectorStaleList(t *testing.T) {
+	s := NewStore(MetaNamespaceKeyFunc)
+	var listOptions []metav1.ListOptions
+	lw := &testLW{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			listOptions = append(listOptions, options)
+			return &v1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "5"}}, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return nil, errors.New("unexpected watch")
+		},
+	}
+	g := NewReflector(lw, &v1.Pod{}, s, 0)
+	g.ResourceVersionComparator = IntegerResourceVersions
+	g.setLastSyncResourceVersion("10")
+
+	if err := g.ListAndWatch(wait.NeverStop); err == nil || !strings.Contains(err.Error(), "older than the last synced") {
+		t.Errorf("expected a stale list error, got %v", err)
+	}
+	if e, a := "10", g.LastSyncResourceVersion(); e != a {
+		t.Errorf("expected last sync resource version %v, got %v", e, a)
+	}
+	if e, a := "", g.relistResourceVersion(); e != a {
+		t.Errorf("expected to relist with a consistent read, got resource version %q", a)
+	}
+	if len(listOptions) != 1 || listOptions[0].ResourceVersion != "10" {
+		t.Errorf("unexpected lists %v", listOptions)
+	}
 }
 
 func TestReflectorStopWatch(t *testing.T) {
@@ -420,6 +524,7 @@ func TestReflectorListAndWatchInitConnBackoff(t *testing.T) {
 					initConnBackoffManager: bm,
 					clock:                  fakeClock,
 					watchErrorHandler:      WatchErrorHandler(DefaultWatchErrorHandler),
+					logger:                 reflectorLogger("test-reflector", defaultExpectedTypeName),
 				}
 				start := fakeClock.Now()
 				err := r.ListAndWatch(stopCh)
@@ -480,6 +585,7 @@ func TestBackoffOnTooManyRequests(t *testing.T) {
 		initConnBackoffManager: bm,
 		clock:                  clock,
 		watchErrorHandler:      WatchErrorHandler(DefaultWatchErrorHandler),
+		logger:                 reflectorLogger("test-reflector", defaultExpectedTypeName),
 	}
 
 	stopCh := make(chan struct{})
@@ -490,6 +596,130 @@ func TestBackoffOnTooManyRequests(t *testing.T) {
 	}
 }
 
+type fakeWatchMetrics struct {
+	restarts     []string
+	disconnected map[string]*time.Time
+}
+
+func (m *fakeWatchMetrics) Increment(component, resource, reason string) {
+	m.restarts = append(m.restarts, component+"/"+resource+"/"+reason)
+}
+
+func (m *fakeWatchMetrics) Set(component, resource string, since *time.Time) {
+	m.disconnected[component+"/"+resource] = since
+}
+
+func TestReflectorWatchMetrics(t *testing.T) {
+	fakeMetrics := &fakeWatchMetrics{disconnected: map[string]*time.Time{}}
+	originalRestarts, originalDisconnected := metrics.WatchRestarts, metrics.WatchDisconnected
+	t.Cleanup(func() { metrics.WatchRestarts, metrics.WatchDisconnected = originalRestarts, originalDisconnected })
+	metrics.WatchRestarts, metrics.WatchDisconnected = fakeMetrics, fakeMetrics
+
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	bm := &fakeBackoff{clock: clock.RealClock{}}
+	watches := 0
+	lw := &testLW{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return &v1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "1"}}, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			watches++
+			switch watches {
+			case 1:
+				return nil, apierrors.NewTooManyRequests("too many requests", 1)
+			case 2:
+				w := watch.NewFakeWithChanSize(1, false)
+				w.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo", ResourceVersion: "2"}})
+				w.Stop()
+				return w, nil
+			default:
+				w := watch.NewFake()
+				w.Stop()
+				return w, nil
+			}
+		},
+	}
+
+	r := &Reflector{
+		name:                   "test-reflector",
+		expectedTypeName:       defaultExpectedTypeName,
+		listerWatcher:          lw,
+		store:                  NewStore(MetaNamespaceKeyFunc),
+		initConnBackoffManager: bm,
+		clock:                  fakeClock,
+		watchErrorHandler:      WatchErrorHandler(DefaultWatchErrorHandler),
+		logger:                 reflectorLogger("test-reflector", defaultExpectedTypeName),
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	if err := r.ListAndWatch(stopCh); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"test-reflector/" + defaultExpectedTypeName + "/throttled",
+		"test-reflector/" + defaultExpectedTypeName + "/closed",
+		"test-reflector/" + defaultExpectedTypeName + "/error",
+	}
+	if !reflect.DeepEqual(expected, fakeMetrics.restarts) {
+		t.Errorf("expected restarts %v, got %v", expected, fakeMetrics.restarts)
+	}
+	if since := fakeMetrics.disconnected["test-reflector/"+defaultExpectedTypeName]; since == nil || !since.Equal(fakeClock.Now()) {
+		t.Errorf("expected reflector to be reported disconnected since %v, got %v", fakeClock.Now(), since)
+	}
+	if !r.LastEventTime().Equal(fakeClock.Now()) {
+		t.Errorf("expected last event time %v, got %v", fakeClock.Now(), r.LastEventTime())
+	}
+}
+
+func TestReflectorWatchMetricsSharedName(t *testing.T) {
+	fakeMetrics := &fakeWatchMetrics{disconnected: map[string]*time.Time{}}
+	originalRestarts, originalDisconnected := metrics.WatchRestarts, metrics.WatchDisconnected
+	t.Cleanup(func() { metrics.WatchRestarts, metrics.WatchDisconnected = originalRestarts, originalDisconnected })
+	metrics.WatchRestarts, metrics.WatchDisconnected = fakeMetrics, fakeMetrics
+
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	disconnected := NewNamedReflector("shared", &testLW{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return &v1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "1"}}, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return nil, errors.New("watch failed")
+		},
+	}, &v1.Pod{}, NewStore(MetaNamespaceKeyFunc), 0)
+	disconnected.clock = fakeClock
+
+	stopCh := make(chan struct{})
+	connected := NewNamedReflector("shared", &testLW{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return &v1.ServiceList{ListMeta: metav1.ListMeta{ResourceVersion: "1"}}, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			close(stopCh)
+			return watch.NewFake(), nil
+		},
+	}, &v1.Service{}, NewStore(MetaNamespaceKeyFunc), 0)
+	connected.clock = fakeClock
+
+	if err := disconnected.ListAndWatch(wait.NeverStop); err == nil {
+		t.Fatalf("expected the watch to fail")
+	}
+	if err := connected.ListAndWatch(stopCh); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if since := fakeMetrics.disconnected["shared/*v1.Pod"]; since == nil || !since.Equal(fakeClock.Now()) {
+		t.Errorf("expected pod reflector to be reported disconnected since %v, got %v", fakeClock.Now(), since)
+	}
+	if since, ok := fakeMetrics.disconnected["shared/*v1.Service"]; !ok || since != nil {
+		t.Errorf("expected service reflector to be reported connected, got %v", since)
+	}
+	if expected := []string{"shared/*v1.Pod/error"}; !reflect.DeepEqual(expected, fakeMetrics.restarts) {
+		t.Errorf("expected restarts %v, got %v", expected, fakeMetrics.restarts)
+	}
+}
+
 func TestReflectorResync(t *testing.T) {
 	iteration := 0
 	stopCh := make(chan struct{})
@@ -0,0 +1,81 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// ResourceVersionStringComparator compares resource versions, which are
+// otherwise opaque to clients, for servers and proxies whose resource versions
+// are ordered. Reflector and RetryWatcher use it to detect the stale lists and
+// events served by caching proxies or multi-cluster servers. Unlike
+// ResourceVersionComparator, it compares the resource versions themselves
+// rather than objects.
+type ResourceVersionStringComparator interface {
+	// CompareResourceVersions returns a negative number if lhs is older than
+	// rhs, zero if they are equal and a positive number if lhs is newer. It
+	// returns an error if either cannot be parsed, in which case the resource
+	// versions are treated as opaque.
+	CompareResourceVersions(lhs, rhs string) (int, error)
+}
+
+// IntegerResourceVersions compares resource versions as unsigned integers,
+// like the resource versions of the servers backed by etcd.
+var IntegerResourceVersions ResourceVersionStringComparator = integerResourceVersions{}
+
+type integerResourceVersions struct{}
+
+func (integerResourceVersions) CompareResourceVersions(lhs, rhs string) (int, error) {
+	l, err := strconv.ParseUint(lhs, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid resource version %q: %v", lhs, err)
+	}
+	r, err := strconv.ParseUint(rhs, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid resource version %q: %v", rhs, err)
+	}
+	switch {
+	case l < r:
+		return -1, nil
+	case l > r:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// IsStaleEvent returns true if comparator determines that an event of type
+// eventType at resourceVersion was already observed by a watch at
+// lastResourceVersion: object events at or before it, bookmarks before it.
+// Events are never stale if comparator is nil, lastResourceVersion is empty
+// or the resource versions cannot be compared.
+func IsStaleEvent(comparator ResourceVersionStringComparator, eventType watch.EventType, resourceVersion, lastResourceVersion string) bool {
+	if comparator == nil || len(lastResourceVersion) == 0 {
+		return false
+	}
+	cmp, err := comparator.CompareResourceVersions(resourceVersion, lastResourceVersion)
+	if err != nil {
+		return false
+	}
+	if eventType == watch.Bookmark {
+		return cmp < 0
+	}
+	return cmp <= 0
+}
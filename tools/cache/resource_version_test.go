@@ -0,0 +1,71 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestIntegerResourceVersions(t *testing.T) {
+	testCases := []struct {
+		lhs, rhs string
+		expected int
+		err      bool
+	}{
+		{lhs: "1", rhs: "2", expected: -1},
+		{lhs: "10", rhs: "9", expected: 1},
+		{lhs: "7", rhs: "7", expected: 0},
+		{lhs: "", rhs: "7", err: true},
+		{lhs: "7", rhs: "opaque", err: true},
+	}
+	for _, tc := range testCases {
+		cmp, err := IntegerResourceVersions.CompareResourceVersions(tc.lhs, tc.rhs)
+		if (err != nil) != tc.err {
+			t.Errorf("%q, %q: unexpected error %v", tc.lhs, tc.rhs, err)
+		}
+		if cmp != tc.expected {
+			t.Errorf("%q, %q: expected %d, got %d", tc.lhs, tc.rhs, tc.expected, cmp)
+		}
+	}
+}
+
+func TestIsStaleEvent(t *testing.T) {
+	testCases := []struct {
+		name            string
+		comparator      ResourceVersionStringComparator
+		eventType       watch.EventType
+		resourceVersion string
+		lastVersion     string
+		expected        bool
+	}{
+		{name: "no comparator", eventType: watch.Added, resourceVersion: "1", lastVersion: "2"},
+		{name: "no last version", comparator: IntegerResourceVersions, eventType: watch.Added, resourceVersion: "1"},
+		{name: "newer", comparator: IntegerResourceVersions, eventType: watch.Modified, resourceVersion: "3", lastVersion: "2"},
+		{name: "older", comparator: IntegerResourceVersions, eventType: watch.Deleted, resourceVersion: "1", lastVersion: "2", expected: true},
+		{name: "same", comparator: IntegerResourceVersions, eventType: watch.Added, resourceVersion: "2", lastVersion: "2", expected: true},
+		{name: "same bookmark", comparator: IntegerResourceVersions, eventType: watch.Bookmark, resourceVersion: "2", lastVersion: "2"},
+		{name: "older bookmark", comparator: IntegerResourceVersions, eventType: watch.Bookmark, resourceVersion: "1", lastVersion: "2", expected: true},
+		{name: "opaque", comparator: IntegerResourceVersions, eventType: watch.Added, resourceVersion: "a", lastVersion: "2"},
+	}
+	for _, tc := range testCases {
+		if got := IsStaleEvent(tc.comparator, tc.eventType, tc.resourceVersion, tc.lastVersion); got != tc.expected {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, got)
+		}
+	}
+}
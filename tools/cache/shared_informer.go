@@ -208,16 +208,47 @@ func NewSharedInformer(lw ListerWatcher, exampleObject runtime.Object, defaultEv
 // defaultEventHandlerResyncPeriod given here and (b) the constant
 // `minimumResyncPeriod` defined in this file.
 func NewSharedIndexInformer(lw ListerWatcher, exampleObject runtime.Object, defaultEventHandlerResyncPeriod time.Duration, indexers Indexers) SharedIndexInformer {
-	realClock := &clock.RealClock{}
+	return NewSharedIndexInformerWithOptions(lw, exampleObject, SharedIndexInformerOptions{
+		ResyncPeriod: defaultEventHandlerResyncPeriod,
+		Indexers:     indexers,
+	})
+}
+
+// SharedIndexInformerOptions configures a sharedIndexInformer. All are
+// optional.
+type SharedIndexInformerOptions struct {
+	// ResyncPeriod is the default resync period of the event handlers, see
+	// NewSharedIndexInformer.
+	ResyncPeriod time.Duration
+
+	// Indexers are the indexers of the informer's store.
+	Indexers Indexers
+
+	// Clock times the resyncs of the informer and the backoff of its failed
+	// list and watch calls. Defaults to the real clock, tests can use a fake
+	// one to control them.
+	Clock clock.Clock
+}
+
+// NewSharedIndexInformerWithOptions creates a new instance for the
+// listwatcher, configured by opts.
+func NewSharedIndexInformerWithOptions(lw ListerWatcher, exampleObject runtime.Object, opts SharedIndexInformerOptions) SharedIndexInformer {
+	if opts.Indexers == nil {
+		opts.Indexers = Indexers{}
+	}
+	if opts.Clock == nil {
+		opts.Clock = &clock.RealClock{}
+	}
 	sharedIndexInformer := &sharedIndexInformer{
-		processor:                       &sharedProcessor{clock: realClock},
-		indexer:                         NewIndexer(DeletionHandlingMetaNamespaceKeyFunc, indexers),
+		processor:                       &sharedProcessor{clock: opts.Clock},
+		indexer:                         NewIndexer(DeletionHandlingMetaNamespaceKeyFunc, opts.Indexers),
 		listerWatcher:                   lw,
 		objectType:                      exampleObject,
-		resyncCheckPeriod:               defaultEventHandlerResyncPeriod,
-		defaultEventHandlerResyncPeriod: defaultEventHandlerResyncPeriod,
+		resyncCheckPeriod:               opts.ResyncPeriod,
+		defaultEventHandlerResyncPeriod: opts.ResyncPeriod,
 		cacheMutationDetector:           NewCacheMutationDetector(fmt.Sprintf("%T", exampleObject)),
-		clock:                           realClock,
+		clock:                           opts.Clock,
+		backoffClock:                    opts.Clock,
 	}
 	return sharedIndexInformer
 }
@@ -308,6 +339,10 @@ type sharedIndexInformer struct {
 	defaultEventHandlerResyncPeriod time.Duration
 	// clock allows for testability
 	clock clock.Clock
+	// backoffClock times the backoff of failed list and watch calls. It is
+	// only set by SharedIndexInformerOptions, to keep the backoff of tests
+	// replacing clock on the real clock.
+	backoffClock clock.Clock
 
 	started, stopped bool
 	startedLock      sync.Mutex
@@ -395,6 +430,7 @@ func (s *sharedIndexInformer) Run(stopCh <-chan struct{}) {
 
 		s.controller = New(cfg)
 		s.controller.(*controller).clock = s.clock
+		s.controller.(*controller).backoffClock = s.backoffClock
 		s.started = true
 	}()
 
@@ -254,6 +254,31 @@ func (c *cache) Resync() error {
 	return nil
 }
 
+// MemoryUsage returns the approximate memory used by the cache.
+func (c *cache) MemoryUsage(sizeFunc ObjectSizeFunc) MemoryUsage {
+	return c.cacheStorage.MemoryUsage(sizeFunc)
+}
+
+// Compact reallocates the maps of the cache.
+func (c *cache) Compact() {
+	c.cacheStorage.Compact()
+}
+
+// GetMany returns the items of keys, and whether they exist.
+func (c *cache) GetMany(keys []string) (items []interface{}, exists []bool) {
+	return c.cacheStorage.GetMany(keys)
+}
+
+// ListInto is like List, but reuses the memory of list for the result.
+func (c *cache) ListInto(list []interface{}) []interface{} {
+	return c.cacheStorage.ListInto(list)
+}
+
+// ByIndexInto is like ByIndex, but reuses the memory of list for the result.
+func (c *cache) ByIndexInto(indexName, indexedValue string, list []interface{}) ([]interface{}, error) {
+	return c.cacheStorage.ByIndexInto(indexName, indexedValue, list)
+}
+
 // NewStore returns a Store implemented simply with a map and a lock.
 func NewStore(keyFunc KeyFunc) Store {
 	return &cache{
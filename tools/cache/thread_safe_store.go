@@ -57,6 +57,21 @@ type ThreadSafeStore interface {
 	AddIndexers(newIndexers Indexers) error
 	// Resync is a no-op and is deprecated
 	Resync() error
+
+	// MemoryUsage returns the approximate memory used by the store, sizing
+	// the objects with sizeFunc, or DefaultObjectSize if nil.
+	MemoryUsage(sizeFunc ObjectSizeFunc) MemoryUsage
+	// Compact reallocates the maps of the store, which otherwise retain the
+	// memory of their largest size, for example after large deletions.
+	Compact()
+
+	// GetMany returns the items of keys, and whether they exist.
+	GetMany(keys []string) (items []interface{}, exists []bool)
+	// ListInto is like List, but reuses the memory of list for the result.
+	ListInto(list []interface{}) []interface{}
+	// ByIndexInto is like ByIndex, but reuses the memory of list for the
+	// result.
+	ByIndexInto(indexName, indexedValue string, list []interface{}) ([]interface{}, error)
 }
 
 // threadSafeMap implements ThreadSafeStore
@@ -108,6 +123,27 @@ func (c *threadSafeMap) List() []interface{} {
 	return list
 }
 
+func (c *threadSafeMap) GetMany(keys []string) (items []interface{}, exists []bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	items = make([]interface{}, len(keys))
+	exists = make([]bool, len(keys))
+	for i, key := range keys {
+		items[i], exists[i] = c.items[key]
+	}
+	return items, exists
+}
+
+func (c *threadSafeMap) ListInto(list []interface{}) []interface{} {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	list = resetList(list, len(c.items))
+	for _, item := range c.items {
+		list = append(list, item)
+	}
+	return list
+}
+
 // ListKeys returns a list of all the keys of the objects currently
 // in the threadSafeMap.
 func (c *threadSafeMap) ListKeys() []string {
@@ -193,6 +229,37 @@ func (c *threadSafeMap) ByIndex(indexName, indexedValue string) ([]interface{},
 	return list, nil
 }
 
+// ByIndexInto is like ByIndex, but reuses the memory of list for the result.
+func (c *threadSafeMap) ByIndexInto(indexName, indexedValue string, list []interface{}) ([]interface{}, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	indexFunc := c.indexers[indexName]
+	if indexFunc == nil {
+		return nil, fmt.Errorf("Index with name %s does not exist", indexName)
+	}
+
+	set := c.indices[indexName][indexedValue]
+	list = resetList(list, set.Len())
+	for key := range set {
+		list = append(list, c.items[key])
+	}
+	return list, nil
+}
+
+// resetList returns list emptied, with the capacity for size items, clearing
+// its former items so they can be garbage collected.
+func resetList(list []interface{}, size int) []interface{} {
+	if cap(list) < size {
+		return make([]interface{}, 0, size)
+	}
+	list = list[:cap(list)]
+	for i := range list {
+		list[i] = nil
+	}
+	return list[:0]
+}
+
 // IndexKeys returns a list of the Store keys of the objects whose indexed values in the given index include the given indexed value.
 // IndexKeys is thread-safe so long as you treat all items as immutable.
 func (c *threadSafeMap) IndexKeys(indexName, indexedValue string) ([]string, error) {
@@ -325,6 +392,107 @@ func (c *threadSafeMap) Resync() error {
 	return nil
 }
 
+// ObjectSizeFunc returns the approximate memory used by an object, in bytes.
+type ObjectSizeFunc func(obj interface{}) int64
+
+// DefaultObjectSize returns the size of the protobuf serialization of obj,
+// as an approximation of the memory it uses, or 0 if obj cannot be
+// serialized to protobuf.
+func DefaultObjectSize(obj interface{}) int64 {
+	if sized, ok := obj.(interface{ Size() int }); ok {
+		return int64(sized.Size())
+	}
+	return 0
+}
+
+// Approximate sizes of the structures of a threadSafeMap, in bytes.
+const (
+	// mapEntryOverhead approximates the memory used by a map entry beside
+	// its key and value, accounting for buckets and load factor.
+	mapEntryOverhead = 16
+	// stringHeaderSize and interfaceSize are the sizes of a string and an
+	// interface{} on 64 bit platforms.
+	stringHeaderSize = 16
+	interfaceSize    = 16
+)
+
+// MemoryUsage approximates the memory used by a store, in bytes.
+type MemoryUsage struct {
+	// Items is the number of items of the store.
+	Items int
+	// ItemsBytes is the memory used by the items and their keys.
+	ItemsBytes int64
+	// Namespaces maps namespaces to the memory used by their items and
+	// keys, cluster scoped items being under "".
+	Namespaces map[string]int64
+	// Indices maps index names to the memory used by their indexed values
+	// and keys.
+	Indices map[string]int64
+}
+
+// Total returns the memory used by the items and the indices.
+func (u MemoryUsage) Total() int64 {
+	total := u.ItemsBytes
+	for _, bytes := range u.Indices {
+		total += bytes
+	}
+	return total
+}
+
+func (c *threadSafeMap) MemoryUsage(sizeFunc ObjectSizeFunc) MemoryUsage {
+	if sizeFunc == nil {
+		sizeFunc = DefaultObjectSize
+	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	usage := MemoryUsage{
+		Items:      len(c.items),
+		Namespaces: map[string]int64{},
+		Indices:    map[string]int64{},
+	}
+	for key, obj := range c.items {
+		bytes := mapEntryOverhead + stringHeaderSize + int64(len(key)) + interfaceSize + sizeFunc(obj)
+		usage.ItemsBytes += bytes
+		namespace, _, err := SplitMetaNamespaceKey(key)
+		if err != nil {
+			namespace = ""
+		}
+		usage.Namespaces[namespace] += bytes
+	}
+	for name, index := range c.indices {
+		var bytes int64
+		for indexedValue, set := range index {
+			bytes += mapEntryOverhead + stringHeaderSize + int64(len(indexedValue))
+			// the keys share their bytes with the keys of the items
+			bytes += int64(len(set)) * (mapEntryOverhead + stringHeaderSize)
+		}
+		usage.Indices[name] = bytes
+	}
+	return usage
+}
+
+func (c *threadSafeMap) Compact() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	items := make(map[string]interface{}, len(c.items))
+	for key, obj := range c.items {
+		items[key] = obj
+	}
+	c.items = items
+
+	indices := make(Indices, len(c.indices))
+	for name, index := range c.indices {
+		compacted := make(Index, len(index))
+		for indexedValue, set := range index {
+			compacted[indexedValue] = sets.NewString(set.UnsortedList()...)
+		}
+		indices[name] = compacted
+	}
+	c.indices = indices
+}
+
 // NewThreadSafeStore creates a new instance of ThreadSafeStore.
 func NewThreadSafeStore(indexers Indexers, indices Indices) ThreadSafeStore {
 	return &threadSafeMap{
@@ -18,7 +18,12 @@ package cache
 
 import (
 	"fmt"
+	"reflect"
+	"sort"
 	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestThreadSafeStoreDeleteRemovesEmptySetsFromIndex(t *testing.T) {
@@ -120,3 +125,108 @@ func BenchmarkIndexer(b *testing.B) {
 		store.Update(objects[i%objectCount], objects[i%objectCount])
 	}
 }
+
+func TestThreadSafeStoreMemoryUsage(t *testing.T) {
+	store := NewThreadSafeStore(Indexers{NamespaceIndex: MetaNamespaceIndexFunc}, Indices{})
+	for _, pod := range []*v1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "a"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "b"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "ns2", Name: "c", Labels: map[string]string{"app": "large"}}},
+	} {
+		key, _ := MetaNamespaceKeyFunc(pod)
+		store.Add(key, pod)
+	}
+	store.Add("node", &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}})
+
+	usage := store.MemoryUsage(nil)
+	if usage.Items != 4 {
+		t.Errorf("expected 4 items, got %d", usage.Items)
+	}
+	if usage.Namespaces["ns1"] <= 0 || usage.Namespaces["ns2"] <= usage.Namespaces["ns1"]/2 || usage.Namespaces[""] <= 0 {
+		t.Errorf("unexpected namespaces usage %v", usage.Namespaces)
+	}
+	var namespaces int64
+	for _, bytes := range usage.Namespaces {
+		namespaces += bytes
+	}
+	if namespaces != usage.ItemsBytes {
+		t.Errorf("expected namespaces to add up to %d bytes, got %d", usage.ItemsBytes, namespaces)
+	}
+	if usage.Indices[NamespaceIndex] <= 0 || usage.Total() != usage.ItemsBytes+usage.Indices[NamespaceIndex] {
+		t.Errorf("unexpected indices usage %v", usage.Indices)
+	}
+
+	constant := store.MemoryUsage(func(interface{}) int64 { return 1000 })
+	if constant.ItemsBytes <= 4000 || constant.ItemsBytes >= usage.ItemsBytes+4000 {
+		t.Errorf("unexpected usage with sizes of 1000 bytes %d", constant.ItemsBytes)
+	}
+}
+
+func TestThreadSafeStoreCompact(t *testing.T) {
+	store := NewThreadSafeStore(Indexers{"first": func(obj interface{}) ([]string, error) {
+		return []string{obj.(string)[:1]}, nil
+	}}, Indices{})
+	for i := 0; i < 1000; i++ {
+		store.Add(fmt.Sprint(i), fmt.Sprint(i))
+	}
+	for i := 10; i < 1000; i++ {
+		store.Delete(fmt.Sprint(i))
+	}
+	before := store.MemoryUsage(nil)
+	store.Compact()
+	if after := store.MemoryUsage(nil); !reflect.DeepEqual(before, after) {
+		t.Errorf("expected compaction to keep usage %v, got %v", before, after)
+	}
+	if keys, _ := store.IndexKeys("first", "1"); !reflect.DeepEqual(keys, []string{"1"}) {
+		t.Errorf("unexpected index keys %v", keys)
+	}
+	store.Add("12", "12")
+	if keys, _ := store.IndexKeys("first", "1"); !reflect.DeepEqual(keys, []string{"1", "12"}) {
+		t.Errorf("unexpected index keys %v", keys)
+	}
+}
+
+func TestThreadSafeStoreBulkAccessors(t *testing.T) {
+	store := NewThreadSafeStore(Indexers{"first": func(obj interface{}) ([]string, error) {
+		return []string{obj.(string)[:1]}, nil
+	}}, Indices{})
+	for _, item := range []string{"a1", "a2", "b1"} {
+		store.Add(item, item)
+	}
+
+	items, exists := store.GetMany([]string{"a1", "c1", "b1"})
+	if !reflect.DeepEqual(items, []interface{}{"a1", nil, "b1"}) || !reflect.DeepEqual(exists, []bool{true, false, true}) {
+		t.Errorf("unexpected items %v and exists %v", items, exists)
+	}
+
+	list := make([]interface{}, 5)
+	list = store.ListInto(list)
+	if len(list) != 3 || cap(list) != 5 {
+		t.Errorf("expected 3 items in the list of capacity 5, got %v with capacity %d", list, cap(list))
+	}
+	if allocs := testing.AllocsPerRun(10, func() { list = store.ListInto(list) }); allocs != 0 {
+		t.Errorf("expected no allocations listing into a large enough list, got %v", allocs)
+	}
+
+	list, err := store.ByIndexInto("first", "a", list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sorted := []string{}
+	for _, item := range list {
+		sorted = append(sorted, item.(string))
+	}
+	sort.Strings(sorted)
+	if !reflect.DeepEqual(sorted, []string{"a1", "a2"}) {
+		t.Errorf("unexpected items by index %v", sorted)
+	}
+	if full := list[:cap(list)]; full[2] != nil {
+		t.Errorf("expected former items to be cleared, got %v", full)
+	}
+	if _, err := store.ByIndexInto("missing", "a", list); err == nil {
+		t.Errorf("expected an error for a missing index")
+	}
+	if list = store.ListInto(nil); len(list) != 3 {
+		t.Errorf("expected 3 items, got %v", list)
+	}
+}
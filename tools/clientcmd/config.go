@@ -144,7 +144,7 @@ func (o *PathOptions) GetExplicitFile() string {
 
 func NewDefaultPathOptions() *PathOptions {
 	ret := &PathOptions{
-		GlobalFile:       RecommendedHomeFile,
+		GlobalFile:       recommendedHomeFile(),
 		EnvVar:           RecommendedConfigPathEnvVar,
 		ExplicitFileFlag: RecommendedConfigPathFlag,
 
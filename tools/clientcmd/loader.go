@@ -43,14 +43,105 @@ const (
 	RecommendedHomeDir          = ".kube"
 	RecommendedFileName         = "config"
 	RecommendedSchemaName       = "schema"
+	RecommendedCacheName        = "cache"
+
+	// RecommendedConfigDirEnvVar overrides the recommended directory of the
+	// kubeconfig and schema files.
+	RecommendedConfigDirEnvVar = "KUBE_CONFIG_DIR"
+	// RecommendedCacheDirEnvVar overrides the recommended cache directory.
+	RecommendedCacheDirEnvVar = "KUBE_CACHE_DIR"
+	// RecommendedXDGDirsEnvVar, set to "true", opts in to the XDG base
+	// directories when the .kube directory does not exist. Tools built with
+	// other versions of client-go do not look for files there.
+	RecommendedXDGDirsEnvVar = "KUBE_XDG_DIRS"
+	// xdgDirName is the name of the directories of kube in XDG base directories.
+	xdgDirName = "kube"
 )
 
+// The recommended paths are resolved when the package is initialized. The
+// loading rules resolve RecommendedHomeFile again unless it was assigned, so
+// that overrides made later, like homedir.SetHomeDirFunc, are honored; use
+// ResolveRecommendedConfigDir, ResolveRecommendedSchemaFile and
+// ResolveRecommendedCacheDir for the other paths.
 var (
-	RecommendedConfigDir  = filepath.Join(homedir.HomeDir(), RecommendedHomeDir)
+	RecommendedConfigDir  = ResolveRecommendedConfigDir()
 	RecommendedHomeFile   = filepath.Join(RecommendedConfigDir, RecommendedFileName)
-	RecommendedSchemaFile = filepath.Join(RecommendedConfigDir, RecommendedSchemaName)
+	RecommendedSchemaFile = ResolveRecommendedSchemaFile()
+	RecommendedCacheDir   = ResolveRecommendedCacheDir()
+
+	// initialRecommendedHomeFile tells whether RecommendedHomeFile was assigned.
+	initialRecommendedHomeFile = RecommendedHomeFile
 )
 
+// ResolveRecommendedConfigDir returns the recommended directory of the
+// kubeconfig and schema files, which is the first of
+// 1. the directory in $KUBE_CONFIG_DIR, if set.
+// 2. the .kube directory in the home directory, if it exists.
+// 3. the kube directory in $XDG_CONFIG_HOME, if set and $KUBE_XDG_DIRS is
+// "true".
+// 4. the .kube directory in the home directory.
+// The home directory can be overridden with homedir.SetHomeDirFunc.
+func ResolveRecommendedConfigDir() string {
+	if dir := os.Getenv(RecommendedConfigDirEnvVar); len(dir) > 0 {
+		return dir
+	}
+	home := homedir.HomeDir()
+	legacyDir := filepath.Join(home, RecommendedHomeDir)
+	if len(home) > 0 {
+		if _, err := os.Stat(legacyDir); err == nil {
+			return legacyDir
+		}
+	}
+	if useXDGDirs() && filepath.IsAbs(os.Getenv("XDG_CONFIG_HOME")) {
+		return filepath.Join(homedir.ConfigDir(), xdgDirName)
+	}
+	return legacyDir
+}
+
+// ResolveRecommendedSchemaFile returns the recommended schema file, in the
+// recommended config directory.
+func ResolveRecommendedSchemaFile() string {
+	return filepath.Join(ResolveRecommendedConfigDir(), RecommendedSchemaName)
+}
+
+// ResolveRecommendedCacheDir returns the recommended cache directory, which
+// is the first of
+//  1. the directory in $KUBE_CACHE_DIR, if set.
+//  2. the cache directory in the directory in $KUBE_CONFIG_DIR, if set.
+//  3. the cache directory in the .kube directory in the home directory, if
+//     that is the recommended config directory.
+//  4. the kube directory in $XDG_CACHE_HOME, if set and $KUBE_XDG_DIRS is
+//     "true".
+//  5. the cache directory in the recommended config directory.
+func ResolveRecommendedCacheDir() string {
+	if dir := os.Getenv(RecommendedCacheDirEnvVar); len(dir) > 0 {
+		return dir
+	}
+	configDir := ResolveRecommendedConfigDir()
+	legacyDir := filepath.Join(homedir.HomeDir(), RecommendedHomeDir)
+	if len(os.Getenv(RecommendedConfigDirEnvVar)) == 0 && configDir != legacyDir {
+		if useXDGDirs() && filepath.IsAbs(os.Getenv("XDG_CACHE_HOME")) {
+			return filepath.Join(homedir.CacheDir(), xdgDirName)
+		}
+	}
+	return filepath.Join(configDir, RecommendedCacheName)
+}
+
+// useXDGDirs returns whether the XDG base directories were opted in to.
+func useXDGDirs() bool {
+	return os.Getenv(RecommendedXDGDirsEnvVar) == "true"
+}
+
+// recommendedHomeFile returns RecommendedHomeFile if it was assigned, and
+// otherwise the recommended kubeconfig file resolved now, so overrides made
+// after initialization are honored.
+func recommendedHomeFile() string {
+	if RecommendedHomeFile != initialRecommendedHomeFile {
+		return RecommendedHomeFile
+	}
+	return filepath.Join(ResolveRecommendedConfigDir(), RecommendedFileName)
+}
+
 // currentMigrationRules returns a map that holds the history of recommended home directories used in previous versions.
 // Any future changes to RecommendedHomeFile and related are expected to add a migration rule here, in order to make
 // sure existing config files are migrated to their new locations properly.
@@ -62,7 +153,7 @@ func currentMigrationRules() map[string]string {
 		oldRecommendedHomeFileName = ".kubeconfig"
 	}
 	return map[string]string{
-		RecommendedHomeFile: filepath.Join(os.Getenv("HOME"), RecommendedHomeDir, oldRecommendedHomeFileName),
+		recommendedHomeFile(): filepath.Join(os.Getenv("HOME"), RecommendedHomeDir, oldRecommendedHomeFileName),
 	}
 }
 
@@ -148,7 +239,7 @@ func NewDefaultClientConfigLoadingRules() *ClientConfigLoadingRules {
 		warnIfAllMissing = true
 
 	} else {
-		chain = append(chain, RecommendedHomeFile)
+		chain = append(chain, recommendedHomeFile())
 	}
 
 	return &ClientConfigLoadingRules{
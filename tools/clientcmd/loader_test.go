@@ -33,6 +33,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/diff"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	clientcmdlatest "k8s.io/client-go/tools/clientcmd/api/latest"
+	"k8s.io/client-go/util/homedir"
 )
 
 var (
@@ -914,3 +915,95 @@ func TestLoadingGetLoadingPrecedence(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveRecommendedDirs(t *testing.T) {
+	home, err := ioutil.TempDir("", "home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	homedir.SetHomeDirFunc(func() string { return home })
+	defer homedir.SetHomeDirFunc(nil)
+
+	legacyDir := filepath.Join(home, ".kube")
+	testCases := map[string]struct {
+		env           map[string]string
+		legacyExists  bool
+		expectedDir   string
+		expectedCache string
+	}{
+		"default": {
+			expectedDir:   legacyDir,
+			expectedCache: filepath.Join(legacyDir, "cache"),
+		},
+		"xdg not opted in": {
+			env:           map[string]string{"XDG_CONFIG_HOME": "/xdg/config", "XDG_CACHE_HOME": "/xdg/cache"},
+			expectedDir:   legacyDir,
+			expectedCache: filepath.Join(legacyDir, "cache"),
+		},
+		"xdg": {
+			env:           map[string]string{RecommendedXDGDirsEnvVar: "true", "XDG_CONFIG_HOME": "/xdg/config", "XDG_CACHE_HOME": "/xdg/cache"},
+			expectedDir:   "/xdg/config/kube",
+			expectedCache: "/xdg/cache/kube",
+		},
+		"xdg config only": {
+			env:           map[string]string{RecommendedXDGDirsEnvVar: "true", "XDG_CONFIG_HOME": "/xdg/config"},
+			expectedDir:   "/xdg/config/kube",
+			expectedCache: "/xdg/config/kube/cache",
+		},
+		"relative xdg": {
+			env:           map[string]string{RecommendedXDGDirsEnvVar: "true", "XDG_CONFIG_HOME": "config", "XDG_CACHE_HOME": "cache"},
+			expectedDir:   legacyDir,
+			expectedCache: filepath.Join(legacyDir, "cache"),
+		},
+		"existing legacy dir": {
+			env:           map[string]string{RecommendedXDGDirsEnvVar: "true", "XDG_CONFIG_HOME": "/xdg/config", "XDG_CACHE_HOME": "/xdg/cache"},
+			legacyExists:  true,
+			expectedDir:   legacyDir,
+			expectedCache: filepath.Join(legacyDir, "cache"),
+		},
+		"config dir": {
+			env:           map[string]string{RecommendedConfigDirEnvVar: "/config", RecommendedXDGDirsEnvVar: "true", "XDG_CACHE_HOME": "/xdg/cache"},
+			legacyExists:  true,
+			expectedDir:   "/config",
+			expectedCache: "/config/cache",
+		},
+		"cache dir": {
+			env:           map[string]string{RecommendedCacheDirEnvVar: "/cache"},
+			expectedDir:   legacyDir,
+			expectedCache: "/cache",
+		},
+	}
+
+	envVars := []string{RecommendedConfigDirEnvVar, RecommendedCacheDirEnvVar, RecommendedXDGDirsEnvVar, "XDG_CONFIG_HOME", "XDG_CACHE_HOME", "KUBECONFIG"}
+	for _, envVar := range envVars {
+		defer os.Setenv(envVar, os.Getenv(envVar))
+	}
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			for _, envVar := range envVars {
+				os.Setenv(envVar, test.env[envVar])
+			}
+			os.RemoveAll(legacyDir)
+			if test.legacyExists {
+				if err := os.Mkdir(legacyDir, 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if dir := ResolveRecommendedConfigDir(); dir != test.expectedDir {
+				t.Errorf("expected config dir %q, got %q", test.expectedDir, dir)
+			}
+			if file := ResolveRecommendedSchemaFile(); file != filepath.Join(test.expectedDir, RecommendedSchemaName) {
+				t.Errorf("expected schema file in %q, got %q", test.expectedDir, file)
+			}
+			if dir := ResolveRecommendedCacheDir(); dir != test.expectedCache {
+				t.Errorf("expected cache dir %q, got %q", test.expectedCache, dir)
+			}
+			expectedPrecedence := []string{filepath.Join(test.expectedDir, RecommendedFileName)}
+			if precedence := NewDefaultClientConfigLoadingRules().GetLoadingPrecedence(); !reflect.DeepEqual(precedence, expectedPrecedence) {
+				t.Errorf("expected precedence %v, got %v", expectedPrecedence, precedence)
+			}
+		})
+	}
+}
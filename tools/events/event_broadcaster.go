@@ -351,6 +351,8 @@ type eventBroadcasterAdapterImpl struct {
 	eventsv1Broadcaster EventBroadcaster
 }
 
+var _ LegacyRecorderAdapter = &eventBroadcasterAdapterImpl{}
+
 // NewEventBroadcasterAdapter creates a wrapper around new and legacy broadcasters to simplify
 // migration of individual components to the new Event API.
 func NewEventBroadcasterAdapter(client clientset.Interface) EventBroadcasterAdapter {
@@ -388,6 +390,13 @@ func (e *eventBroadcasterAdapterImpl) DeprecatedNewLegacyRecorder(name string) r
 	return e.coreBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: name})
 }
 
+func (e *eventBroadcasterAdapterImpl) NewLegacyRecorder(name string) record.EventRecorder {
+	if e.eventsv1Broadcaster != nil && e.eventsv1Client != nil {
+		return NewLegacyEventRecorderAdapter(e.eventsv1Broadcaster.NewRecorder(scheme.Scheme, name))
+	}
+	return e.DeprecatedNewLegacyRecorder(name)
+}
+
 func (e *eventBroadcasterAdapterImpl) Shutdown() {
 	if e.coreBroadcaster != nil {
 		e.coreBroadcaster.Shutdown()
@@ -26,6 +26,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/tools/record/util"
 	"k8s.io/client-go/tools/reference"
 	"k8s.io/klog/v2"
@@ -90,3 +91,37 @@ func (recorder *recorderImpl) makeEvent(refRegarding *v1.ObjectReference, refRel
 		Type:                eventtype,
 	}
 }
+
+// LegacyEventRecorderAdapter is a wrapper around a "k8s.io/client-go/tools/events".EventRecorder
+// implementing the legacy "k8s.io/client-go/tools/record".EventRecorder interface, so components
+// written against the legacy interface can record events with the new Event API.
+type LegacyEventRecorderAdapter struct {
+	recorder EventRecorder
+}
+
+var _ record.EventRecorder = &LegacyEventRecorderAdapter{}
+
+// NewLegacyEventRecorderAdapter returns an adapter implementing the legacy
+// "k8s.io/client-go/tools/record".EventRecorder interface. The reason of
+// events is also used as their action.
+func NewLegacyEventRecorderAdapter(recorder EventRecorder) *LegacyEventRecorderAdapter {
+	return &LegacyEventRecorderAdapter{
+		recorder: recorder,
+	}
+}
+
+// Event is a wrapper around Eventf
+func (a *LegacyEventRecorderAdapter) Event(object runtime.Object, eventtype, reason, message string) {
+	a.recorder.Eventf(object, nil, eventtype, reason, reason, "%s", message)
+}
+
+// Eventf is a wrapper around Eventf
+func (a *LegacyEventRecorderAdapter) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	a.recorder.Eventf(object, nil, eventtype, reason, reason, messageFmt, args...)
+}
+
+// AnnotatedEventf is a wrapper around Eventf. The new Event API does not
+// support annotations, so they are dropped.
+func (a *LegacyEventRecorderAdapter) AnnotatedEventf(object runtime.Object, _ map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	a.recorder.Eventf(object, nil, eventtype, reason, reason, messageFmt, args...)
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2014 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+func TestLegacyEventRecorderAdapter(t *testing.T) {
+	broadcaster := NewBroadcaster(&testEventSeriesSink{})
+	defer broadcaster.Shutdown()
+	events := make(chan *eventsv1.Event, 3)
+	stop := broadcaster.StartEventWatcher(func(obj runtime.Object) {
+		events <- obj.(*eventsv1.Event)
+	})
+	defer stop()
+
+	recorder := NewLegacyEventRecorderAdapter(broadcaster.NewRecorder(scheme.Scheme, "test-controller"))
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod", UID: "uid"}}
+	recorder.Event(pod, v1.EventTypeNormal, "Started", "started 100%")
+	recorder.Eventf(pod, v1.EventTypeWarning, "Failed", "failed %d times", 3)
+	recorder.AnnotatedEventf(pod, map[string]string{"key": "value"}, v1.EventTypeNormal, "Stopped", "stopped")
+
+	expected := map[string]string{"Started": "started 100%", "Failed": "failed 3 times", "Stopped": "stopped"}
+	for range expected {
+		select {
+		case event := <-events:
+			if event.Note != expected[event.Reason] || event.Action != event.Reason {
+				t.Errorf("unexpected event %#v", event)
+			}
+			if event.Regarding.Name != "pod" || event.ReportingController != "test-controller" {
+				t.Errorf("unexpected event %#v", event)
+			}
+		case <-time.After(wait.ForeverTestTimeout):
+			t.Fatal("timed out waiting for events")
+		}
+	}
+}
+
+func TestEventBroadcasterAdapterNewLegacyRecorder(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	adapter := NewEventBroadcasterAdapter(client)
+	defer adapter.Shutdown()
+	if _, ok := adapter.(LegacyRecorderAdapter).NewLegacyRecorder("test").(*LegacyEventRecorderAdapter); ok {
+		t.Error("expected a legacy recorder without the new Event API")
+	}
+
+	client.Resources = []*metav1.APIResourceList{{GroupVersion: eventsv1.SchemeGroupVersion.String()}}
+	adapter = NewEventBroadcasterAdapter(client)
+	defer adapter.Shutdown()
+	if _, ok := adapter.(LegacyRecorderAdapter).NewLegacyRecorder("test").(*LegacyEventRecorderAdapter); !ok {
+		t.Error("expected a recorder of the new Event API")
+	}
+}
@@ -93,3 +93,12 @@ type EventBroadcasterAdapter interface {
 	// Shutdown shuts down the broadcaster.
 	Shutdown()
 }
+
+// LegacyRecorderAdapter is implemented by the EventBroadcasterAdapters which
+// can record the events of the legacy recorder interface with the new Event
+// API, like the ones returned by NewEventBroadcasterAdapter.
+type LegacyRecorderAdapter interface {
+	// NewLegacyRecorder creates an Event Recorder with specified name implementing the
+	// legacy interface, which records events with the new Event API if the server supports it.
+	NewLegacyRecorder(name string) record.EventRecorder
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2014 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package finalizer adds and removes finalizers of objects with minimal
+// patches that are safe against concurrent changes.
+package finalizer // import "k8s.io/client-go/tools/finalizer"
+
+import (
+	"context"
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/util/retry"
+)
+
+// Has returns true if obj has the finalizer name.
+func Has(obj metav1.Object, name string) bool {
+	for _, finalizer := range obj.GetFinalizers() {
+		if finalizer == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Ensure adds the finalizer name to obj through client, which must be bound to
+// the resource and namespace of obj, for example
+// metadataClient.Resource(gvr).Namespace(obj.GetNamespace()). It returns false
+// without calling the server if obj already has the finalizer.
+//
+// The patch replaces the finalizers of obj on the condition that the object
+// was not changed since obj was read, so finalizers added or removed
+// concurrently by others are never lost. On a conflict the object is read
+// again and the patch is retried, unless the finalizer was added meanwhile.
+// Servers refuse to add finalizers to objects that are being deleted.
+func Ensure(ctx context.Context, client metadata.ResourceInterface, obj metav1.Object, name string) (bool, error) {
+	return update(ctx, client, obj, func(obj metav1.Object) ([]string, error) {
+		if Has(obj, name) {
+			return nil, nil
+		}
+		return append(append([]string{}, obj.GetFinalizers()...), name), nil
+	})
+}
+
+// Remove removes the finalizer name from obj through client, which must be
+// bound to the resource and namespace of obj like for Ensure. It returns false
+// without calling the server if obj does not have the finalizer, and treats
+// objects that no longer exist as not having it.
+func Remove(ctx context.Context, client metadata.ResourceInterface, obj metav1.Object, name string) (bool, error) {
+	changed, err := update(ctx, client, obj, func(obj metav1.Object) ([]string, error) {
+		if !Has(obj, name) {
+			return nil, nil
+		}
+		finalizers := []string{}
+		for _, finalizer := range obj.GetFinalizers() {
+			if finalizer != name {
+				finalizers = append(finalizers, finalizer)
+			}
+		}
+		return finalizers, nil
+	})
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	return changed, err
+}
+
+// update patches the finalizers of obj to those returned by finalizers, which
+// returns nil if no change is needed. obj is read from the server again after
+// a conflict.
+func update(ctx context.Context, client metadata.ResourceInterface, obj metav1.Object, finalizers func(metav1.Object) ([]string, error)) (bool, error) {
+	var changed bool
+	current := obj
+	err := retry.OnErrorWithContext(ctx, retry.DefaultRetry, errors.IsConflict, func(ctx context.Context) error {
+		// without a resource version the patch would not be conditional
+		if current == nil || len(current.GetResourceVersion()) == 0 {
+			latest, err := client.Get(ctx, obj.GetName(), metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			current = latest
+		}
+		updated, err := finalizers(current)
+		if err != nil || updated == nil {
+			return err
+		}
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"finalizers":      updated,
+				"resourceVersion": current.GetResourceVersion(),
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := client.Patch(ctx, obj.GetName(), types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			// read the object again before the next attempt
+			current = nil
+			return err
+		}
+		changed = true
+		return nil
+	})
+	return changed, err
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2014 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package finalizer
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/metadata"
+)
+
+// fakeResource stores a single object and rejects patches of its finalizers
+// with a stale resource version like a server.
+type fakeResource struct {
+	metadata.ResourceInterface
+
+	obj     *metav1.PartialObjectMetadata
+	gets    int
+	patches int
+	// beforePatch is called before a patch is applied.
+	beforePatch func(obj *metav1.PartialObjectMetadata)
+}
+
+func (f *fakeResource) Get(ctx context.Context, name string, options metav1.GetOptions, subresources ...string) (*metav1.PartialObjectMetadata, error) {
+	f.gets++
+	if f.obj == nil || f.obj.Name != name {
+		return nil, errors.NewNotFound(schema.GroupResource{Resource: "tests"}, name)
+	}
+	return f.obj.DeepCopy(), nil
+}
+
+func (f *fakeResource) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, options metav1.PatchOptions, subresources ...string) (*metav1.PartialObjectMetadata, error) {
+	f.patches++
+	if f.beforePatch != nil {
+		f.beforePatch(f.obj)
+	}
+	if f.obj == nil || f.obj.Name != name {
+		return nil, errors.NewNotFound(schema.GroupResource{Resource: "tests"}, name)
+	}
+	if pt != types.MergePatchType {
+		return nil, errors.NewBadRequest("unexpected patch type " + string(pt))
+	}
+	var patch metav1.PartialObjectMetadata
+	if err := json.Unmarshal(data, &patch); err != nil {
+		return nil, err
+	}
+	if rv := patch.ResourceVersion; rv != "" && rv != f.obj.ResourceVersion {
+		return nil, errors.NewConflict(schema.GroupResource{Resource: "tests"}, name, nil)
+	}
+	f.bump(patch.Finalizers...)
+	return f.obj.DeepCopy(), nil
+}
+
+// bump replaces the finalizers of the stored object and increments its
+// resource version.
+func (f *fakeResource) bump(finalizers ...string) {
+	rv, _ := strconv.Atoi(f.obj.ResourceVersion)
+	f.obj.ResourceVersion = strconv.Itoa(rv + 1)
+	f.obj.Finalizers = finalizers
+}
+
+func newObject(resourceVersion string, finalizers ...string) *metav1.PartialObjectMetadata {
+	return &metav1.PartialObjectMetadata{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", ResourceVersion: resourceVersion, Finalizers: finalizers},
+	}
+}
+
+func TestEnsure(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeResource{obj: newObject("1", "a")}
+
+	changed, err := Ensure(ctx, client, newObject("1", "a"), "b")
+	if err != nil || !changed {
+		t.Fatalf("expected the finalizer to be added, got %t (%v)", changed, err)
+	}
+	if !reflect.DeepEqual(client.obj.Finalizers, []string{"a", "b"}) {
+		t.Errorf("unexpected finalizers %v", client.obj.Finalizers)
+	}
+
+	// present finalizers are not added again, without calling the server
+	changed, err = Ensure(ctx, client, client.obj.DeepCopy(), "b")
+	if err != nil || changed {
+		t.Errorf("expected no change, got %t (%v)", changed, err)
+	}
+	if client.gets != 0 || client.patches != 1 {
+		t.Errorf("expected a single patch, got %d gets and %d patches", client.gets, client.patches)
+	}
+}
+
+func TestEnsureConflict(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeResource{obj: newObject("1")}
+	stale := client.obj.DeepCopy()
+	// another client adds its finalizer first
+	client.bump("other")
+
+	changed, err := Ensure(ctx, client, stale, "mine")
+	if err != nil || !changed {
+		t.Fatalf("expected the finalizer to be added, got %t (%v)", changed, err)
+	}
+	if !reflect.DeepEqual(client.obj.Finalizers, []string{"other", "mine"}) {
+		t.Errorf("expected the concurrently added finalizer to be kept, got %v", client.obj.Finalizers)
+	}
+	if client.gets != 1 || client.patches != 2 {
+		t.Errorf("expected a retry after the conflict, got %d gets and %d patches", client.gets, client.patches)
+	}
+
+	// the finalizer was added concurrently with the same name
+	client = &fakeResource{obj: newObject("1")}
+	client.beforePatch = func(obj *metav1.PartialObjectMetadata) {
+		if client.patches == 1 {
+			client.bump("mine")
+		}
+	}
+	changed, err = Ensure(ctx, client, client.obj.DeepCopy(), "mine")
+	if err != nil || changed {
+		t.Errorf("expected no change, got %t (%v)", changed, err)
+	}
+	if client.patches != 1 {
+		t.Errorf("expected no patch after the conflict, got %d patches", client.patches)
+	}
+}
+
+func TestEnsureWithoutResourceVersion(t *testing.T) {
+	client := &fakeResource{obj: newObject("3", "a")}
+	changed, err := Ensure(context.Background(), client, newObject(""), "b")
+	if err != nil || !changed {
+		t.Fatalf("expected the finalizer to be added, got %t (%v)", changed, err)
+	}
+	if !reflect.DeepEqual(client.obj.Finalizers, []string{"a", "b"}) {
+		t.Errorf("unexpected finalizers %v", client.obj.Finalizers)
+	}
+	if client.gets != 1 {
+		t.Errorf("expected the object to be read, got %d gets", client.gets)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeResource{obj: newObject("1", "a", "b")}
+	stale := client.obj.DeepCopy()
+	client.bump("a", "b", "c")
+
+	changed, err := Remove(ctx, client, stale, "a")
+	if err != nil || !changed {
+		t.Fatalf("expected the finalizer to be removed, got %t (%v)", changed, err)
+	}
+	if !reflect.DeepEqual(client.obj.Finalizers, []string{"b", "c"}) {
+		t.Errorf("unexpected finalizers %v", client.obj.Finalizers)
+	}
+
+	changed, err = Remove(ctx, client, client.obj.DeepCopy(), "a")
+	if err != nil || changed {
+		t.Errorf("expected no change, got %t (%v)", changed, err)
+	}
+
+	// the last finalizer is removed after a conflict
+	obj := client.obj.DeepCopy()
+	client.bump("c")
+	if changed, err := Remove(ctx, client, obj, "c"); err != nil || !changed {
+		t.Errorf("expected the finalizer to be removed, got %t (%v)", changed, err)
+	}
+	if len(client.obj.Finalizers) != 0 {
+		t.Errorf("unexpected finalizers %v", client.obj.Finalizers)
+	}
+	// the object was deleted by the server
+	client.obj = nil
+	if changed, err := Remove(ctx, client, obj, "b"); err != nil || changed {
+		t.Errorf("expected no change for missing objects, got %t (%v)", changed, err)
+	}
+}
+
+func TestEnsureNotFound(t *testing.T) {
+	client := &fakeResource{}
+	if _, err := Ensure(context.Background(), client, newObject("1"), "a"); !errors.IsNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
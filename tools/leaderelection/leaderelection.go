@@ -64,9 +64,9 @@ import (
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	rl "k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/util/backoff"
+	"k8s.io/client-go/util/logging"
 	"k8s.io/utils/clock"
-
-	"k8s.io/klog/v2"
 )
 
 const (
@@ -100,9 +100,13 @@ func NewLeaderElector(lec LeaderElectionConfig) (*LeaderElector, error) {
 	if lec.Lock == nil {
 		return nil, fmt.Errorf("Lock must not be nil.")
 	}
+	c := lec.Clock
+	if c == nil {
+		c = clock.RealClock{}
+	}
 	le := LeaderElector{
 		config:  lec,
-		clock:   clock.RealClock{},
+		clock:   c,
 		metrics: globalMetricsFactory.newLeaderMetrics(),
 	}
 	le.metrics.leaderOff(le.config.Name)
@@ -155,6 +159,11 @@ type LeaderElectionConfig struct {
 
 	// Name is the name of the resource lock for debugging
 	Name string
+
+	// Clock is used to measure time and wait between tries. Optional,
+	// defaults to the real clock. Tests can use a fake clock to step through
+	// leader election deterministically.
+	Clock clock.Clock
 }
 
 // LeaderCallbacks are callbacks that are triggered during certain
@@ -244,20 +253,20 @@ func (le *LeaderElector) acquire(ctx context.Context) bool {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 	succeeded := false
-	desc := le.config.Lock.Describe()
-	klog.Infof("attempting to acquire leader lease %v...", desc)
-	wait.JitterUntil(func() {
+	logger := logging.FromContext(ctx).WithValues("lock", le.config.Lock.Describe())
+	logger.Info("Attempting to acquire leader lease")
+	wait.BackoffUntil(func() {
 		succeeded = le.tryAcquireOrRenew(ctx)
 		le.maybeReportTransition()
 		if !succeeded {
-			klog.V(4).Infof("failed to acquire lease %v", desc)
+			logger.V(4).Info("Failed to acquire lease")
 			return
 		}
 		le.config.Lock.RecordEvent("became leader")
 		le.metrics.leaderOn(le.config.Name)
-		klog.Infof("successfully acquired lease %v", desc)
+		logger.Info("Successfully acquired lease")
 		cancel()
-	}, le.config.RetryPeriod, JitterFactor, true, ctx.Done())
+	}, backoff.NewManager(backoff.WithJitter(backoff.Steps(le.config.RetryPeriod), JitterFactor), le.clock), true, ctx.Done())
 	return succeeded
 }
 
@@ -265,24 +274,24 @@ func (le *LeaderElector) acquire(ctx context.Context) bool {
 func (le *LeaderElector) renew(ctx context.Context) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
-	wait.Until(func() {
-		timeoutCtx, timeoutCancel := context.WithTimeout(ctx, le.config.RenewDeadline)
+	logger := logging.FromContext(ctx).WithValues("lock", le.config.Lock.Describe())
+	wait.BackoffUntil(func() {
+		timeoutCtx, timeoutCancel := le.withTimeout(ctx, le.config.RenewDeadline)
 		defer timeoutCancel()
-		err := wait.PollImmediateUntil(le.config.RetryPeriod, func() (bool, error) {
-			return le.tryAcquireOrRenew(timeoutCtx), nil
+		err := le.pollImmediateUntil(le.config.RetryPeriod, func() bool {
+			return le.tryAcquireOrRenew(timeoutCtx)
 		}, timeoutCtx.Done())
 
 		le.maybeReportTransition()
-		desc := le.config.Lock.Describe()
 		if err == nil {
-			klog.V(5).Infof("successfully renewed lease %v", desc)
+			logger.V(5).Info("Successfully renewed lease")
 			return
 		}
 		le.config.Lock.RecordEvent("stopped leading")
 		le.metrics.leaderOff(le.config.Name)
-		klog.Infof("failed to renew lease %v: %v", desc, err)
+		logger.Info("Failed to renew lease", "err", err)
 		cancel()
-	}, le.config.RetryPeriod, ctx.Done())
+	}, backoff.NewManager(backoff.Steps(le.config.RetryPeriod), le.clock), true, ctx.Done())
 
 	// if we hold the lease, give it up
 	if le.config.ReleaseOnCancel {
@@ -295,7 +304,7 @@ func (le *LeaderElector) release() bool {
 	if !le.IsLeader() {
 		return true
 	}
-	now := metav1.Now()
+	now := metav1.NewTime(le.clock.Now())
 	leaderElectionRecord := rl.LeaderElectionRecord{
 		LeaderTransitions:    le.observedRecord.LeaderTransitions,
 		LeaseDurationSeconds: 1,
@@ -303,7 +312,7 @@ func (le *LeaderElector) release() bool {
 		AcquireTime:          now,
 	}
 	if err := le.config.Lock.Update(context.TODO(), leaderElectionRecord); err != nil {
-		klog.Errorf("Failed to release lock: %v", err)
+		logging.Logger().Error(err, "Failed to release lock", "lock", le.config.Lock.Describe())
 		return false
 	}
 
@@ -315,7 +324,7 @@ func (le *LeaderElector) release() bool {
 // else it tries to renew the lease if it has already been acquired. Returns true
 // on success else returns false.
 func (le *LeaderElector) tryAcquireOrRenew(ctx context.Context) bool {
-	now := metav1.Now()
+	now := metav1.NewTime(le.clock.Now())
 	leaderElectionRecord := rl.LeaderElectionRecord{
 		HolderIdentity:       le.config.Lock.Identity(),
 		LeaseDurationSeconds: int(le.config.LeaseDuration / time.Second),
@@ -327,11 +336,11 @@ func (le *LeaderElector) tryAcquireOrRenew(ctx context.Context) bool {
 	oldLeaderElectionRecord, oldLeaderElectionRawRecord, err := le.config.Lock.Get(ctx)
 	if err != nil {
 		if !errors.IsNotFound(err) {
-			klog.Errorf("error retrieving resource lock %v: %v", le.config.Lock.Describe(), err)
+			logging.FromContext(ctx).Error(err, "Error retrieving resource lock", "lock", le.config.Lock.Describe())
 			return false
 		}
 		if err = le.config.Lock.Create(ctx, leaderElectionRecord); err != nil {
-			klog.Errorf("error initially creating leader election record: %v", err)
+			logging.FromContext(ctx).Error(err, "Error initially creating leader election record", "lock", le.config.Lock.Describe())
 			return false
 		}
 
@@ -349,7 +358,7 @@ func (le *LeaderElector) tryAcquireOrRenew(ctx context.Context) bool {
 	if len(oldLeaderElectionRecord.HolderIdentity) > 0 &&
 		le.observedTime.Add(le.config.LeaseDuration).After(now.Time) &&
 		!le.IsLeader() {
-		klog.V(4).Infof("lock is held by %v and has not yet expired", oldLeaderElectionRecord.HolderIdentity)
+		logging.FromContext(ctx).V(4).Info("Lock is held and has not yet expired", "lock", le.config.Lock.Describe(), "holder", oldLeaderElectionRecord.HolderIdentity)
 		return false
 	}
 
@@ -364,7 +373,7 @@ func (le *LeaderElector) tryAcquireOrRenew(ctx context.Context) bool {
 
 	// update the lock itself
 	if err = le.config.Lock.Update(ctx, leaderElectionRecord); err != nil {
-		klog.Errorf("Failed to update lock: %v", err)
+		logging.FromContext(ctx).Error(err, "Failed to update lock", "lock", le.config.Lock.Describe())
 		return false
 	}
 
@@ -382,6 +391,73 @@ func (le *LeaderElector) maybeReportTransition() {
 	}
 }
 
+// withTimeout is like context.WithTimeout, measuring the timeout with the
+// clock of the elector.
+func (le *LeaderElector) withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	switch le.clock.(type) {
+	case clock.RealClock, *clock.RealClock:
+		return context.WithTimeout(ctx, timeout)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	c := &clockDeadlineContext{Context: ctx, deadline: le.clock.Now().Add(timeout)}
+	timer := le.clock.NewTimer(timeout)
+	go func() {
+		defer timer.Stop()
+		select {
+		case <-timer.C():
+			c.lock.Lock()
+			c.err = context.DeadlineExceeded
+			c.lock.Unlock()
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return c, cancel
+}
+
+// clockDeadlineContext is a context whose deadline is measured with a clock
+// other than the real one. Like the contexts of context.WithDeadline, it
+// reports its deadline and fails with context.DeadlineExceeded once it is
+// reached.
+type clockDeadlineContext struct {
+	context.Context
+	deadline time.Time
+
+	lock sync.Mutex
+	err  error
+}
+
+func (c *clockDeadlineContext) Deadline() (time.Time, bool) {
+	return c.deadline, true
+}
+
+func (c *clockDeadlineContext) Err() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	return c.Context.Err()
+}
+
+// pollImmediateUntil is like wait.PollImmediateUntil, waiting with the clock
+// of the elector.
+func (le *LeaderElector) pollImmediateUntil(interval time.Duration, condition func() bool, stopCh <-chan struct{}) error {
+	for {
+		if condition() {
+			return nil
+		}
+		timer := le.clock.NewTimer(interval)
+		select {
+		case <-stopCh:
+			timer.Stop()
+			return wait.ErrWaitTimeout
+		case <-timer.C():
+		}
+	}
+}
+
 // Check will determine if the current lease is expired by more than timeout.
 func (le *LeaderElector) Check(maxTolerableExpiredLease time.Duration) error {
 	if !le.IsLeader() {
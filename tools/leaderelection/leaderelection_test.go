@@ -31,11 +31,13 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/diff"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes/fake"
 	fakeclient "k8s.io/client-go/testing"
 	rl "k8s.io/client-go/tools/leaderelection/resourcelock"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/clock"
+	testingclock "k8s.io/utils/clock/testing"
 )
 
 func createLockObject(t *testing.T, objectType, namespace, name string, record *rl.LeaderElectionRecord) (obj runtime.Object) {
@@ -1198,3 +1200,39 @@ func testReleaseOnCancellation(t *testing.T, objectType string) {
 		t.Fatal("the lock was not released")
 	}
 }
+
+func TestWithTimeout(t *testing.T) {
+	now := time.Now()
+	fakeClock := testingclock.NewFakeClock(now)
+	for _, c := range []clock.Clock{clock.RealClock{}, fakeClock} {
+		le := &LeaderElector{clock: c}
+
+		ctx, cancel := le.withTimeout(context.Background(), time.Minute)
+		if deadline, ok := ctx.Deadline(); !ok || deadline.Before(c.Now()) {
+			t.Errorf("%T: expected a deadline after now, got %v, %v", c, deadline, ok)
+		}
+		cancel()
+		if err := ctx.Err(); err != context.Canceled {
+			t.Errorf("%T: expected a canceled context, got %v", c, err)
+		}
+	}
+
+	le := &LeaderElector{clock: fakeClock}
+	ctx, cancel := le.withTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if deadline, _ := ctx.Deadline(); !deadline.Equal(now.Add(time.Minute)) {
+		t.Errorf("expected deadline %v, got %v", now.Add(time.Minute), deadline)
+	}
+	for !fakeClock.HasWaiters() {
+		time.Sleep(time.Millisecond)
+	}
+	fakeClock.Step(time.Minute)
+	select {
+	case <-ctx.Done():
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("timed out waiting for the deadline")
+	}
+	if err := ctx.Err(); err != context.DeadlineExceeded {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
@@ -0,0 +1,266 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fake provides an in-memory resourcelock.Interface to unit test
+// leader-elected components without a cluster.
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	rl "k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/utils/clock"
+)
+
+// TransitionType is the type of a change of the holder of a lock.
+type TransitionType string
+
+const (
+	// Acquired means a candidate became the holder of the lock.
+	Acquired TransitionType = "Acquired"
+	// Released means the holder of the lock gave it up.
+	Released TransitionType = "Released"
+	// Stolen means the lock was given to another candidate with Steal.
+	Stolen TransitionType = "Stolen"
+)
+
+// Transition is a change of the holder of a lock.
+type Transition struct {
+	Type TransitionType
+	// Identity is the candidate that acquired or released the lock, or the
+	// one the lock was given to.
+	Identity string
+}
+
+func (t Transition) String() string {
+	return fmt.Sprintf("%s by %s", t.Type, t.Identity)
+}
+
+var leasesResource = schema.GroupResource{Group: "coordination.k8s.io", Resource: "leases"}
+
+// Lock is an in-memory resourcelock.Interface. The locks of the candidates
+// of an election, created with Candidate, share the leader election record.
+type Lock struct {
+	identity string
+	state    *lockState
+}
+
+var _ rl.Interface = &Lock{}
+
+type lockState struct {
+	name  string
+	clock clock.PassiveClock
+
+	lock        sync.Mutex
+	record      *rl.LeaderElectionRecord
+	raw         []byte
+	transitions []Transition
+	events      []string
+	errors      map[string]map[string]error
+}
+
+// NewLock returns a lock named name for the candidate identity. The clock is
+// used to time the records of Steal, and defaults to the real clock.
+func NewLock(name, identity string, c clock.PassiveClock) *Lock {
+	if c == nil {
+		c = clock.RealClock{}
+	}
+	return &Lock{
+		identity: identity,
+		state: &lockState{
+			name:   name,
+			clock:  c,
+			errors: map[string]map[string]error{},
+		},
+	}
+}
+
+// Candidate returns the lock of another candidate of the same election.
+func (l *Lock) Candidate(identity string) *Lock {
+	return &Lock{identity: identity, state: l.state}
+}
+
+// Get returns the leader election record, or a NotFound error if it was not
+// created yet.
+func (l *Lock) Get(ctx context.Context) (*rl.LeaderElectionRecord, []byte, error) {
+	s := l.state
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.errors[l.identity]["get"]; err != nil {
+		return nil, nil, err
+	}
+	if s.record == nil {
+		return nil, nil, errors.NewNotFound(leasesResource, s.name)
+	}
+	record := *s.record
+	return &record, s.raw, nil
+}
+
+// Create creates the leader election record.
+func (l *Lock) Create(ctx context.Context, ler rl.LeaderElectionRecord) error {
+	s := l.state
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.errors[l.identity]["create"]; err != nil {
+		return err
+	}
+	if s.record != nil {
+		return errors.NewAlreadyExists(leasesResource, s.name)
+	}
+	return s.setLocked(ler, "")
+}
+
+// Update updates the leader election record.
+func (l *Lock) Update(ctx context.Context, ler rl.LeaderElectionRecord) error {
+	s := l.state
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.errors[l.identity]["update"]; err != nil {
+		return err
+	}
+	if s.record == nil {
+		return errors.NewNotFound(leasesResource, s.name)
+	}
+	return s.setLocked(ler, s.record.HolderIdentity)
+}
+
+// RecordEvent records an event of the candidate.
+func (l *Lock) RecordEvent(s string) {
+	l.state.lock.Lock()
+	defer l.state.lock.Unlock()
+	l.state.events = append(l.state.events, fmt.Sprintf("%v %v", l.identity, s))
+}
+
+// Identity returns the identity of the candidate.
+func (l *Lock) Identity() string {
+	return l.identity
+}
+
+// Describe returns the name of the lock.
+func (l *Lock) Describe() string {
+	return l.state.name
+}
+
+// SetError makes the calls of the candidate with verb, one of "get",
+// "create" and "update", fail with err, or succeed again if err is nil. For
+// example, failing updates makes the leader fail to renew the lock.
+func (l *Lock) SetError(verb string, err error) {
+	s := l.state
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err == nil {
+		delete(s.errors[l.identity], verb)
+		return
+	}
+	if s.errors[l.identity] == nil {
+		s.errors[l.identity] = map[string]error{}
+	}
+	s.errors[l.identity][verb] = err
+}
+
+// Steal gives the lock to identity, as if another candidate had acquired it
+// while the holder failed to renew it.
+func (l *Lock) Steal(identity string, leaseDuration int) {
+	s := l.state
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	now := metav1.NewTime(s.clock.Now())
+	record := rl.LeaderElectionRecord{
+		HolderIdentity:       identity,
+		LeaseDurationSeconds: leaseDuration,
+		AcquireTime:          now,
+		RenewTime:            now,
+	}
+	holder := ""
+	if s.record != nil {
+		record.LeaderTransitions = s.record.LeaderTransitions + 1
+		holder = s.record.HolderIdentity
+	}
+	s.record = &record
+	s.raw, _ = json.Marshal(record)
+	if holder != identity {
+		s.transitions = append(s.transitions, Transition{Type: Stolen, Identity: identity})
+	}
+}
+
+// Holder returns the identity of the holder of the lock, if any.
+func (l *Lock) Holder() string {
+	s := l.state
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.record == nil {
+		return ""
+	}
+	return s.record.HolderIdentity
+}
+
+// Transitions returns the changes of the holder of the lock, in order.
+func (l *Lock) Transitions() []Transition {
+	s := l.state
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	transitions := make([]Transition, len(s.transitions))
+	copy(transitions, s.transitions)
+	return transitions
+}
+
+// Events returns the events recorded by the candidates, prefixed with their
+// identity, in order.
+func (l *Lock) Events() []string {
+	s := l.state
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	events := make([]string, len(s.events))
+	copy(events, s.events)
+	return events
+}
+
+// CheckTransitions returns an error describing the differences between the
+// changes of the holder of the lock and the expected ones.
+func (l *Lock) CheckTransitions(expected ...Transition) error {
+	transitions := l.Transitions()
+	if len(transitions) == 0 && len(expected) == 0 {
+		return nil
+	}
+	if !reflect.DeepEqual(transitions, expected) {
+		return fmt.Errorf("expected transitions %v, got %v", expected, transitions)
+	}
+	return nil
+}
+
+func (s *lockState) setLocked(ler rl.LeaderElectionRecord, holder string) error {
+	raw, err := json.Marshal(ler)
+	if err != nil {
+		return err
+	}
+	switch {
+	case ler.HolderIdentity == holder:
+	case ler.HolderIdentity == "":
+		s.transitions = append(s.transitions, Transition{Type: Released, Identity: holder})
+	default:
+		s.transitions = append(s.transitions, Transition{Type: Acquired, Identity: ler.HolderIdentity})
+	}
+	s.record = &ler
+	s.raw = raw
+	return nil
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock/fake"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+const (
+	leaseDuration = 15 * time.Second
+	renewDeadline = 10 * time.Second
+	retryPeriod   = 2 * time.Second
+)
+
+type elector struct {
+	started chan struct{}
+	stopped chan struct{}
+	cancel  context.CancelFunc
+}
+
+func startElector(t *testing.T, lock *fake.Lock, clock *testingclock.FakeClock, releaseOnCancel bool) *elector {
+	e := &elector{started: make(chan struct{}), stopped: make(chan struct{})}
+	le, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   renewDeadline,
+		RetryPeriod:     retryPeriod,
+		ReleaseOnCancel: releaseOnCancel,
+		Clock:           clock,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) { close(e.started) },
+			OnStoppedLeading: func() { close(e.stopped) },
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ctx context.Context
+	ctx, e.cancel = context.WithCancel(context.Background())
+	go le.Run(ctx)
+	return e
+}
+
+func waitFor(t *testing.T, ch <-chan struct{}, what string) {
+	select {
+	case <-ch:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatalf("timed out waiting for the elector to %s", what)
+	}
+}
+
+// stepUntil steps the clock by the retry period until ch is closed.
+func stepUntil(t *testing.T, clock *testingclock.FakeClock, ch <-chan struct{}, what string) {
+	timeout := time.After(wait.ForeverTestTimeout)
+	for {
+		select {
+		case <-ch:
+			return
+		case <-timeout:
+			t.Fatalf("timed out waiting for the elector to %s", what)
+		case <-time.After(time.Millisecond):
+			clock.Step(retryPeriod)
+		}
+	}
+}
+
+func TestRenewFailure(t *testing.T) {
+	clock := testingclock.NewFakeClock(time.Now())
+	lock := fake.NewLock("lease", "a", clock)
+	e := startElector(t, lock, clock, false)
+	defer e.cancel()
+
+	waitFor(t, e.started, "start leading")
+	if holder := lock.Holder(); holder != "a" {
+		t.Errorf("expected a to hold the lock, got %q", holder)
+	}
+
+	lock.SetError("update", errors.New("injected failure"))
+	stepUntil(t, clock, e.stopped, "stop leading")
+	if err := lock.CheckTransitions(fake.Transition{Type: fake.Acquired, Identity: "a"}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStolenLock(t *testing.T) {
+	clock := testingclock.NewFakeClock(time.Now())
+	lock := fake.NewLock("lease", "a", clock)
+	e := startElector(t, lock, clock, false)
+	defer e.cancel()
+
+	waitFor(t, e.started, "start leading")
+	lock.Steal("b", int(leaseDuration/time.Second))
+	stepUntil(t, clock, e.stopped, "stop leading")
+	if err := lock.CheckTransitions(
+		fake.Transition{Type: fake.Acquired, Identity: "a"},
+		fake.Transition{Type: fake.Stolen, Identity: "b"},
+	); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReleaseOnCancel(t *testing.T) {
+	clock := testingclock.NewFakeClock(time.Now())
+	lock := fake.NewLock("lease", "a", clock)
+	a := startElector(t, lock, clock, true)
+	waitFor(t, a.started, "start leading")
+
+	b := startElector(t, lock.Candidate("b"), clock, true)
+	defer b.cancel()
+	a.cancel()
+	waitFor(t, a.stopped, "stop leading")
+
+	// b acquires the released lock without waiting for it to expire.
+	stepUntil(t, clock, b.started, "start leading")
+	if err := lock.CheckTransitions(
+		fake.Transition{Type: fake.Acquired, Identity: "a"},
+		fake.Transition{Type: fake.Released, Identity: "a"},
+		fake.Transition{Type: fake.Acquired, Identity: "b"},
+	); err != nil {
+		t.Error(err)
+	}
+}
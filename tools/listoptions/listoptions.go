@@ -0,0 +1,209 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package listoptions builds list and watch options and validates them
+// against the rules the server enforces, so that malformed options fail
+// before a request is sent rather than in response to it.
+package listoptions // import "k8s.io/client-go/tools/listoptions"
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Builder builds metav1.ListOptions. Its methods return the builder so that
+// calls can be chained; errors, like invalid label requirements, are
+// collected and returned by Build along with the violations of Validate.
+//
+//	options, err := listoptions.New().
+//		LabelEquals("app", "web").
+//		FieldEquals("status.phase", "Running").
+//		Limit(500).
+//		Build()
+type Builder struct {
+	options metav1.ListOptions
+	labels  labels.Selector
+	fields  []fields.Selector
+	errs    field.ErrorList
+}
+
+// New returns an empty Builder.
+func New() *Builder {
+	return &Builder{labels: labels.NewSelector()}
+}
+
+// Label adds a requirement on the label key to the label selector.
+func (b *Builder) Label(key string, op selection.Operator, values ...string) *Builder {
+	requirement, err := labels.NewRequirement(key, op, values)
+	if err != nil {
+		b.errs = append(b.errs, field.Invalid(field.NewPath("labelSelector"), key, err.Error()))
+		return b
+	}
+	b.labels = b.labels.Add(*requirement)
+	return b
+}
+
+// LabelEquals requires the label key to equal value.
+func (b *Builder) LabelEquals(key, value string) *Builder {
+	return b.Label(key, selection.Equals, value)
+}
+
+// LabelIn requires the label key to equal one of values.
+func (b *Builder) LabelIn(key string, values ...string) *Builder {
+	return b.Label(key, selection.In, values...)
+}
+
+// LabelExists requires the label key to be set.
+func (b *Builder) LabelExists(key string) *Builder {
+	return b.Label(key, selection.Exists)
+}
+
+// LabelSelector adds the requirements of selector to the label selector.
+// Selectors matching nothing, like labels.Nothing(), have no requirements to
+// send to the server and are an error.
+func (b *Builder) LabelSelector(selector labels.Selector) *Builder {
+	requirements, selectable := selector.Requirements()
+	if !selectable {
+		b.errs = append(b.errs, field.Invalid(field.NewPath("labelSelector"), selector.String(), "selector matches nothing and cannot be sent to the server"))
+		return b
+	}
+	b.labels = b.labels.Add(requirements...)
+	return b
+}
+
+// FieldEquals requires the field path to equal value.
+func (b *Builder) FieldEquals(path, value string) *Builder {
+	b.fields = append(b.fields, fields.OneTermEqualSelector(path, value))
+	return b
+}
+
+// FieldNotEquals requires the field path not to equal value.
+func (b *Builder) FieldNotEquals(path, value string) *Builder {
+	b.fields = append(b.fields, fields.OneTermNotEqualSelector(path, value))
+	return b
+}
+
+// Limit limits lists to n items per page.
+func (b *Builder) Limit(n int64) *Builder {
+	b.options.Limit = n
+	return b
+}
+
+// Continue continues a paginated list from token.
+func (b *Builder) Continue(token string) *Builder {
+	b.options.Continue = token
+	return b
+}
+
+// ResourceVersion sets the resource version of lists and watches, and how
+// lists match it; match may be empty for the legacy semantics.
+func (b *Builder) ResourceVersion(resourceVersion string, match metav1.ResourceVersionMatch) *Builder {
+	b.options.ResourceVersion = resourceVersion
+	b.options.ResourceVersionMatch = match
+	return b
+}
+
+// Watch makes the options watch options.
+func (b *Builder) Watch() *Builder {
+	b.options.Watch = true
+	return b
+}
+
+// AllowWatchBookmarks requests bookmark events from watches.
+func (b *Builder) AllowWatchBookmarks() *Builder {
+	b.options.AllowWatchBookmarks = true
+	return b
+}
+
+// Timeout bounds the duration of lists and watches, rounded down to seconds.
+func (b *Builder) Timeout(timeout time.Duration) *Builder {
+	seconds := int64(timeout / time.Second)
+	b.options.TimeoutSeconds = &seconds
+	return b
+}
+
+// Build returns the options, or the errors of the builder and the violations
+// of Validate.
+func (b *Builder) Build() (metav1.ListOptions, error) {
+	options := b.options
+	if !b.labels.Empty() {
+		options.LabelSelector = b.labels.String()
+	}
+	if len(b.fields) > 0 {
+		options.FieldSelector = fields.AndSelectors(b.fields...).String()
+	}
+	errs := append(field.ErrorList{}, b.errs...)
+	errs = append(errs, validate(options)...)
+	if len(errs) > 0 {
+		return metav1.ListOptions{}, errs.ToAggregate()
+	}
+	return options, nil
+}
+
+// Validate returns the violations of options of the rules the server
+// enforces, or nil.
+func Validate(options metav1.ListOptions) error {
+	return validate(options).ToAggregate()
+}
+
+func validate(options metav1.ListOptions) field.ErrorList {
+	errs := field.ErrorList{}
+	if _, err := labels.Parse(options.LabelSelector); err != nil {
+		errs = append(errs, field.Invalid(field.NewPath("labelSelector"), options.LabelSelector, err.Error()))
+	}
+	if _, err := fields.ParseSelector(options.FieldSelector); err != nil {
+		errs = append(errs, field.Invalid(field.NewPath("fieldSelector"), options.FieldSelector, err.Error()))
+	}
+	if options.Limit < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("limit"), options.Limit, "must be greater than or equal to 0"))
+	}
+	if options.TimeoutSeconds != nil && *options.TimeoutSeconds < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("timeoutSeconds"), *options.TimeoutSeconds, "must be greater than or equal to 0"))
+	}
+	if len(options.Continue) > 0 {
+		if options.Watch {
+			errs = append(errs, field.Forbidden(field.NewPath("continue"), "continue is forbidden for watch"))
+		}
+		if len(options.ResourceVersion) > 0 && options.ResourceVersion != "0" {
+			errs = append(errs, field.Forbidden(field.NewPath("resourceVersion"), "resourceVersion is forbidden when continue is provided"))
+		}
+	}
+	// These mirror the validation of the server.
+	if match := options.ResourceVersionMatch; len(match) > 0 {
+		path := field.NewPath("resourceVersionMatch")
+		if options.Watch {
+			errs = append(errs, field.Forbidden(path, "resourceVersionMatch is forbidden for watch"))
+		}
+		if len(options.ResourceVersion) == 0 {
+			errs = append(errs, field.Forbidden(path, "resourceVersionMatch is forbidden unless resourceVersion is provided"))
+		}
+		if len(options.Continue) > 0 {
+			errs = append(errs, field.Forbidden(path, "resourceVersionMatch is forbidden when continue is provided"))
+		}
+		if match != metav1.ResourceVersionMatchExact && match != metav1.ResourceVersionMatchNotOlderThan {
+			errs = append(errs, field.NotSupported(path, match, []string{string(metav1.ResourceVersionMatchExact), string(metav1.ResourceVersionMatchNotOlderThan), ""}))
+		}
+		if match == metav1.ResourceVersionMatchExact && options.ResourceVersion == "0" {
+			errs = append(errs, field.Forbidden(path, "resourceVersionMatch \"exact\" is forbidden for resourceVersion \"0\""))
+		}
+	}
+	return errs
+}
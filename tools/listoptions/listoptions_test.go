@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package listoptions
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestBuild(t *testing.T) {
+	options, err := New().
+		LabelEquals("app", "web").
+		LabelIn("tier", "frontend", "backend").
+		LabelSelector(labels.SelectorFromSet(labels.Set{"team": "payments"})).
+		FieldEquals("status.phase", "Running").
+		FieldNotEquals("spec.nodeName", "").
+		Limit(500).
+		ResourceVersion("42", metav1.ResourceVersionMatchNotOlderThan).
+		Timeout(90 * time.Second).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeoutSeconds := int64(90)
+	expected := metav1.ListOptions{
+		LabelSelector:        "app=web,team=payments,tier in (backend,frontend)",
+		FieldSelector:        "status.phase=Running,spec.nodeName!=",
+		Limit:                500,
+		ResourceVersion:      "42",
+		ResourceVersionMatch: metav1.ResourceVersionMatchNotOlderThan,
+		TimeoutSeconds:       &timeoutSeconds,
+	}
+	if !reflect.DeepEqual(options, expected) {
+		t.Errorf("expected %#v, got %#v", expected, options)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		builder  *Builder
+		expected []string
+	}{
+		{
+			name:     "invalid label",
+			builder:  New().LabelEquals("not a key", "value"),
+			expected: []string{"labelSelector"},
+		},
+		{
+			name:     "continue with watch",
+			builder:  New().Continue("token").Watch(),
+			expected: []string{"continue is forbidden for watch"},
+		},
+		{
+			name:     "continue with resource version",
+			builder:  New().Continue("token").ResourceVersion("42", ""),
+			expected: []string{"resourceVersion is forbidden when continue is provided"},
+		},
+		{
+			name:     "resource version match without resource version",
+			builder:  New().ResourceVersion("", metav1.ResourceVersionMatchExact),
+			expected: []string{"resourceVersionMatch is forbidden unless resourceVersion is provided"},
+		},
+		{
+			name:     "exact match of resource version 0",
+			builder:  New().ResourceVersion("0", metav1.ResourceVersionMatchExact),
+			expected: []string{`resourceVersionMatch "exact" is forbidden for resourceVersion "0"`},
+		},
+		{
+			name:     "resource version match for watch",
+			builder:  New().ResourceVersion("42", metav1.ResourceVersionMatchNotOlderThan).Watch(),
+			expected: []string{"resourceVersionMatch is forbidden for watch"},
+		},
+		{
+			name:     "selector matching nothing",
+			builder:  New().LabelSelector(labels.Nothing()),
+			expected: []string{"selector matches nothing"},
+		},
+		{
+			name:     "negative limit",
+			builder:  New().Limit(-1),
+			expected: []string{"limit"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.builder.Build()
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, expected := range test.expected {
+				if !strings.Contains(err.Error(), expected) {
+					t.Errorf("expected error to contain %q, got %v", expected, err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := []metav1.ListOptions{
+		{},
+		{Limit: 500, Continue: "token"},
+		{Continue: "token", ResourceVersion: "0"},
+		{Watch: true, AllowWatchBookmarks: true, ResourceVersion: "42"},
+		// the server ignores bookmarks for lists
+		{AllowWatchBookmarks: true},
+		{ResourceVersion: "42", ResourceVersionMatch: metav1.ResourceVersionMatchExact},
+	}
+	for _, options := range valid {
+		if err := Validate(options); err != nil {
+			t.Errorf("expected %#v to be valid, got %v", options, err)
+		}
+	}
+	if err := Validate(metav1.ListOptions{FieldSelector: "a=b=c=d"}); err == nil {
+		t.Errorf("expected an invalid field selector to fail validation")
+	}
+}
@@ -53,6 +53,36 @@ type CallsMetric interface {
 	Increment(exitCode int, callStatus string)
 }
 
+// WatchRestartsMetric counts restarts of long-running watches partitioned by
+// the component maintaining the watch, the watched resource and the reason of
+// the restart.
+type WatchRestartsMetric interface {
+	Increment(component string, resource string, reason string)
+}
+
+// WatchDisconnectedMetric records since when a component has had no
+// established watch of a resource. since is nil while a watch is established.
+// Components are only told apart by their name and resource, so the
+// components sharing both share the recorded time.
+type WatchDisconnectedMetric interface {
+	Set(component string, resource string, since *time.Time)
+}
+
+// Reasons reported by WatchRestarts.
+const (
+	// WatchRestartReasonClosed is reported when the watch was closed, for
+	// example because its timeout expired.
+	WatchRestartReasonClosed = "closed"
+	// WatchRestartReasonExpired is reported when the resource version of the
+	// watch expired.
+	WatchRestartReasonExpired = "expired"
+	// WatchRestartReasonThrottled is reported when the server responded with
+	// 429 Too Many Requests or refused the connection.
+	WatchRestartReasonThrottled = "throttled"
+	// WatchRestartReasonError is reported for any other error.
+	WatchRestartReasonError = "error"
+)
+
 var (
 	// ClientCertExpiry is the expiry time of a client certificate
 	ClientCertExpiry ExpiryMetric = noopExpiry{}
@@ -67,6 +97,12 @@ var (
 	// ExecPluginCalls is the number of calls made to an exec plugin, partitioned by
 	// exit code and call status.
 	ExecPluginCalls CallsMetric = noopCalls{}
+	// WatchRestarts is the number of restarts of watches maintained by
+	// reflectors and retry watchers.
+	WatchRestarts WatchRestartsMetric = noopWatchRestarts{}
+	// WatchDisconnected is the time since which reflectors and retry watchers
+	// have had no established watch.
+	WatchDisconnected WatchDisconnectedMetric = noopWatchDisconnected{}
 )
 
 // RegisterOpts contains all the metrics to register. Metrics may be nil.
@@ -77,6 +113,8 @@ type RegisterOpts struct {
 	RateLimiterLatency    LatencyMetric
 	RequestResult         ResultMetric
 	ExecPluginCalls       CallsMetric
+	WatchRestarts         WatchRestartsMetric
+	WatchDisconnected     WatchDisconnectedMetric
 }
 
 // Register registers metrics for the rest client to use. This can
@@ -101,6 +139,12 @@ func Register(opts RegisterOpts) {
 		if opts.ExecPluginCalls != nil {
 			ExecPluginCalls = opts.ExecPluginCalls
 		}
+		if opts.WatchRestarts != nil {
+			WatchRestarts = opts.WatchRestarts
+		}
+		if opts.WatchDisconnected != nil {
+			WatchDisconnected = opts.WatchDisconnected
+		}
 	})
 }
 
@@ -123,3 +167,11 @@ func (noopResult) Increment(context.Context, string, string, string) {}
 type noopCalls struct{}
 
 func (noopCalls) Increment(int, string) {}
+
+type noopWatchRestarts struct{}
+
+func (noopWatchRestarts) Increment(string, string, string) {}
+
+type noopWatchDisconnected struct{}
+
+func (noopWatchDisconnected) Set(string, string, *time.Time) {}
@@ -0,0 +1,98 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi
+
+import (
+	"fmt"
+
+	"k8s.io/kube-openapi/pkg/util/proto"
+)
+
+// applyDefaults sets the defaults of the fields of s to the fields value does
+// not set, and defaults the fields value sets, recursively.
+func applyDefaults(value interface{}, s proto.Schema) {
+	switch s := s.(type) {
+	case proto.Reference:
+		applyDefaults(value, s.SubSchema())
+	case *proto.Kind:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for name, fieldSchema := range s.Fields {
+			if _, ok := obj[name]; !ok {
+				if def := fieldSchema.GetDefault(); def != nil {
+					obj[name] = jsonValue(def)
+				}
+			}
+			if fieldValue, ok := obj[name]; ok {
+				applyDefaults(fieldValue, fieldSchema)
+			}
+		}
+	case *proto.Map:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for _, fieldValue := range obj {
+			applyDefaults(fieldValue, s.SubType)
+		}
+	case *proto.Array:
+		items, ok := value.([]interface{})
+		if !ok {
+			return
+		}
+		for _, item := range items {
+			applyDefaults(item, s.SubType)
+		}
+	}
+}
+
+// jsonValue returns a copy of the default def, parsed from YAML, holding the
+// types of unstructured objects.
+func jsonValue(def interface{}) interface{} {
+	switch def := def.(type) {
+	case map[interface{}]interface{}:
+		obj := make(map[string]interface{}, len(def))
+		for key, value := range def {
+			obj[fmt.Sprint(key)] = jsonValue(value)
+		}
+		return obj
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(def))
+		for key, value := range def {
+			obj[key] = jsonValue(value)
+		}
+		return obj
+	case []interface{}:
+		items := make([]interface{}, len(def))
+		for i, item := range def {
+			items[i] = jsonValue(item)
+		}
+		return items
+	case int:
+		return int64(def)
+	case int32:
+		return int64(def)
+	case uint64:
+		return int64(def)
+	case float32:
+		return float64(def)
+	default:
+		return def
+	}
+}
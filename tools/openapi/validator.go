@@ -0,0 +1,197 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	openapi_v2 "github.com/googleapis/gnostic/openapiv2"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/discovery"
+	"k8s.io/kube-openapi/pkg/util/proto"
+	"k8s.io/kube-openapi/pkg/util/proto/validation"
+)
+
+// groupVersionKindExtensionKey is the vendor extension of the OpenAPI models
+// listing the kinds they describe.
+const groupVersionKindExtensionKey = "x-kubernetes-group-version-kind"
+
+// ErrNoSchema is wrapped by the errors returned for objects whose kinds have
+// no schema.
+var ErrNoSchema = errors.New("no schema")
+
+// Validator defaults and validates objects against the OpenAPI models of
+// their kinds.
+type Validator struct {
+	schemas map[schema.GroupVersionKind]proto.Schema
+}
+
+// NewValidator returns a Validator of the kinds of models.
+func NewValidator(models proto.Models) *Validator {
+	schemas := map[schema.GroupVersionKind]proto.Schema{}
+	for _, name := range models.ListModels() {
+		model := models.LookupModel(name)
+		if model == nil {
+			continue
+		}
+		for _, gvk := range modelGroupVersionKinds(model) {
+			schemas[gvk] = model
+		}
+	}
+	return &Validator{schemas: schemas}
+}
+
+// NewValidatorForDocument returns a Validator of the kinds of an OpenAPI
+// document, which may be read from a file with openapi_v2.ParseDocument.
+func NewValidatorForDocument(doc *openapi_v2.Document) (*Validator, error) {
+	models, err := proto.NewOpenAPIData(doc)
+	if err != nil {
+		return nil, err
+	}
+	return NewValidator(models), nil
+}
+
+// NewValidatorFromDiscovery returns a Validator of the kinds of the OpenAPI
+// document of a server.
+func NewValidatorFromDiscovery(client discovery.OpenAPISchemaInterface) (*Validator, error) {
+	doc, err := client.OpenAPISchema()
+	if err != nil {
+		return nil, err
+	}
+	return NewValidatorForDocument(doc)
+}
+
+// SchemaFor returns the schema of the kind gvk, if it has one.
+func (v *Validator) SchemaFor(gvk schema.GroupVersionKind) (proto.Schema, bool) {
+	s, ok := v.schemas[gvk]
+	return s, ok
+}
+
+// schemaForObject returns the schema of the kind of obj.
+func (v *Validator) schemaForObject(obj runtime.Unstructured) (schema.GroupVersionKind, proto.Schema, error) {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	s, ok := v.schemas[gvk]
+	if !ok {
+		return gvk, nil, fmt.Errorf("%w for %v", ErrNoSchema, gvk)
+	}
+	return gvk, s, nil
+}
+
+// Default sets the defaults of the schema of the kind of obj to the fields
+// obj does not set, recursively, like servers default the custom resources
+// whose schemas declare defaults.
+func (v *Validator) Default(obj runtime.Unstructured) error {
+	_, s, err := v.schemaForObject(obj)
+	if err != nil {
+		return err
+	}
+	applyDefaults(obj.UnstructuredContent(), s)
+	return nil
+}
+
+// Validate validates obj against the schema of its kind, and returns an
+// Invalid error listing the invalid fields, like servers do.
+func (v *Validator) Validate(obj runtime.Unstructured) error {
+	gvk, s, err := v.schemaForObject(obj)
+	if err != nil {
+		return err
+	}
+	var allErrs field.ErrorList
+	for _, err := range validation.ValidateModel(obj.UnstructuredContent(), s, "") {
+		allErrs = append(allErrs, fieldError(err))
+	}
+	if len(allErrs) == 0 {
+		return nil
+	}
+	var name string
+	if objMeta, err := meta.Accessor(obj); err == nil {
+		name = objMeta.GetName()
+	}
+	return apierrors.NewInvalid(gvk.GroupKind(), name, allErrs)
+}
+
+// DefaultAndValidate defaults obj, and validates the defaulted object.
+func (v *Validator) DefaultAndValidate(obj runtime.Unstructured) error {
+	if err := v.Default(obj); err != nil {
+		return err
+	}
+	return v.Validate(obj)
+}
+
+// fieldError converts an error returned by validation.ValidateModel.
+func fieldError(err error) *field.Error {
+	validationErr, ok := err.(validation.ValidationError)
+	if !ok {
+		return field.InternalError(nil, err)
+	}
+	path := fieldPath(validationErr.Path)
+	switch err := validationErr.Err.(type) {
+	case validation.MissingRequiredFieldError:
+		return field.Required(path.Child(err.Field), "")
+	case validation.UnknownFieldError:
+		return field.Forbidden(path.Child(err.Field), "field not declared in schema")
+	case validation.InvalidTypeError:
+		return field.Invalid(path, err.Actual, fmt.Sprintf("must be of type %s", err.Expected))
+	default:
+		return field.Invalid(path, nil, validationErr.Err.Error())
+	}
+}
+
+// fieldPath converts a path of validation.ValidateModel, like
+// ".spec.containers[0]", to a field path.
+func fieldPath(path string) *field.Path {
+	path = strings.TrimPrefix(path, ".")
+	if path == "" {
+		return nil
+	}
+	return field.NewPath(path)
+}
+
+// modelGroupVersionKinds returns the kinds an OpenAPI model describes.
+func modelGroupVersionKinds(model proto.Schema) []schema.GroupVersionKind {
+	values, ok := model.GetExtensions()[groupVersionKindExtensionKey].([]interface{})
+	if !ok {
+		return nil
+	}
+	var gvks []schema.GroupVersionKind
+	for _, value := range values {
+		var group, version, kind interface{}
+		switch value := value.(type) {
+		case map[interface{}]interface{}:
+			group, version, kind = value["group"], value["version"], value["kind"]
+		case map[string]interface{}:
+			group, version, kind = value["group"], value["version"], value["kind"]
+		default:
+			continue
+		}
+		gvk := schema.GroupVersionKind{}
+		gvk.Group, _ = group.(string)
+		gvk.Version, _ = version.(string)
+		gvk.Kind, _ = kind.(string)
+		if gvk.Version != "" && gvk.Kind != "" {
+			gvks = append(gvks, gvk)
+		}
+	}
+	return gvks
+}
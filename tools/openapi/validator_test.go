@@ -0,0 +1,219 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	openapi_v2 "github.com/googleapis/gnostic/openapiv2"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const widgetSchema = `
+swagger: "2.0"
+info:
+  title: widgets
+  version: v1
+paths: {}
+definitions:
+  com.example.v1.Widget:
+    type: object
+    x-kubernetes-group-version-kind:
+    - group: example.com
+      version: v1
+      kind: Widget
+    properties:
+      apiVersion:
+        type: string
+      kind:
+        type: string
+      metadata:
+        description: arbitrary metadata
+      spec:
+        type: object
+        required:
+        - size
+        properties:
+          size:
+            type: integer
+          replicas:
+            type: integer
+            default: 1
+          strategy:
+            type: object
+            default:
+              type: Recreate
+            properties:
+              type:
+                type: string
+              maxSurge:
+                type: integer
+                default: 2
+          ports:
+            type: array
+            items:
+              type: object
+              properties:
+                port:
+                  type: integer
+                protocol:
+                  type: string
+                  default: TCP
+          limits:
+            type: object
+            additionalProperties:
+              type: object
+              properties:
+                value:
+                  type: string
+                unit:
+                  type: string
+                  default: bytes
+`
+
+type fakeOpenAPISchema struct {
+	doc *openapi_v2.Document
+	err error
+}
+
+func (f fakeOpenAPISchema) OpenAPISchema() (*openapi_v2.Document, error) {
+	return f.doc, f.err
+}
+
+func newTestValidator(t *testing.T) *Validator {
+	doc, err := openapi_v2.ParseDocument([]byte(widgetSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator, err := NewValidatorFromDiscovery(fakeOpenAPISchema{doc: doc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return validator
+}
+
+func TestDefault(t *testing.T) {
+	validator := newTestValidator(t)
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Widget",
+		"metadata":   map[string]interface{}{"name": "foo"},
+		"spec": map[string]interface{}{
+			"size": int64(3),
+			"ports": []interface{}{
+				map[string]interface{}{"port": int64(80)},
+				map[string]interface{}{"port": int64(53), "protocol": "UDP"},
+			},
+			"limits": map[string]interface{}{
+				"memory": map[string]interface{}{"value": "1Gi"},
+			},
+		},
+	}}
+	if err := validator.DefaultAndValidate(obj); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Widget",
+		"metadata":   map[string]interface{}{"name": "foo"},
+		"spec": map[string]interface{}{
+			"size":     int64(3),
+			"replicas": int64(1),
+			// the fields of defaults are defaulted too
+			"strategy": map[string]interface{}{"type": "Recreate", "maxSurge": int64(2)},
+			"ports": []interface{}{
+				map[string]interface{}{"port": int64(80), "protocol": "TCP"},
+				map[string]interface{}{"port": int64(53), "protocol": "UDP"},
+			},
+			"limits": map[string]interface{}{
+				"memory": map[string]interface{}{"value": "1Gi", "unit": "bytes"},
+			},
+		},
+	}
+	if diff := cmp.Diff(expected, obj.Object); diff != "" {
+		t.Errorf("unexpected defaulted object (-want +got):\n%s", diff)
+	}
+
+	// the defaults are copied
+	obj.Object["spec"].(map[string]interface{})["strategy"].(map[string]interface{})["type"] = "RollingUpdate"
+	other := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Widget",
+		"spec":       map[string]interface{}{"size": int64(1)},
+	}}
+	if err := validator.Default(other); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strategy, _, _ := unstructured.NestedString(other.Object, "spec", "strategy", "type"); strategy != "Recreate" {
+		t.Errorf("expected the default strategy Recreate, got %s", strategy)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	validator := newTestValidator(t)
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Widget",
+		"metadata":   map[string]interface{}{"name": "foo"},
+		"spec": map[string]interface{}{
+			"replicas": "three",
+			"color":    "blue",
+		},
+	}}
+	err := validator.Validate(obj)
+	if !apierrors.IsInvalid(err) {
+		t.Fatalf("expected an invalid error, got %v", err)
+	}
+	causes := map[string]bool{}
+	for _, cause := range err.(apierrors.APIStatus).Status().Details.Causes {
+		causes[cause.Field] = true
+	}
+	for _, field := range []string{"spec.size", "spec.replicas", "spec.color"} {
+		if !causes[field] {
+			t.Errorf("expected an error for %s, got %v", field, err)
+		}
+	}
+}
+
+func TestNoSchema(t *testing.T) {
+	validator := newTestValidator(t)
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v2",
+		"kind":       "Widget",
+	}}
+	if err := validator.Validate(obj); !errors.Is(err, ErrNoSchema) {
+		t.Errorf("expected a no schema error, got %v", err)
+	}
+	if err := validator.Default(obj); !errors.Is(err, ErrNoSchema) {
+		t.Errorf("expected a no schema error, got %v", err)
+	}
+	if _, ok := validator.SchemaFor(schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}); !ok {
+		t.Error("expected a schema for widgets")
+	}
+}
+
+func TestNewValidatorFromDiscoveryError(t *testing.T) {
+	if _, err := NewValidatorFromDiscovery(fakeOpenAPISchema{err: errors.New("unreachable")}); err == nil {
+		t.Error("expected the error of the discovery")
+	}
+}
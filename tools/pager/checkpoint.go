@@ -0,0 +1,147 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pager
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Checkpoint records the progress of a paginated list, and of the watch
+// following it, so that an interrupted export can be resumed.
+type Checkpoint struct {
+	// Continue resumes the list after the last page that was completely
+	// processed. It is empty before the first page and once the list completed.
+	Continue string `json:"continue,omitempty"`
+	// ResourceVersion is the resource version of the list, and once the list
+	// completed, the last resource version observed by the watch following it.
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+	// ListComplete is true once every page of the list has been processed.
+	ListComplete bool `json:"listComplete,omitempty"`
+}
+
+// CheckpointStore persists a Checkpoint.
+type CheckpointStore interface {
+	// Load returns the stored checkpoint, or an empty one if there is none.
+	Load() (Checkpoint, error)
+	// Save stores checkpoint, replacing any previously stored one.
+	Save(checkpoint Checkpoint) error
+}
+
+// EachListItemWithCheckpoint is EachListItemWithLimit resuming from and
+// recording its progress in store. The checkpoint is saved after every page
+// whose items were all processed, so after an interruption the items of the
+// page being processed are passed to fn again. Once the list completed, the
+// checkpoint holds the resource version to start a watch from, and further
+// calls return it without listing.
+//
+// Continue tokens expire, in which case resuming fails with an "Expired" error
+// (metav1.StatusReasonExpired) and the checkpoint must be discarded.
+func (p *ListPager) EachListItemWithCheckpoint(ctx context.Context, options metav1.ListOptions, store CheckpointStore, fn func(obj runtime.Object) error) (Checkpoint, error) {
+	checkpoint, err := store.Load()
+	if err != nil {
+		return checkpoint, err
+	}
+	if checkpoint.ListComplete {
+		return checkpoint, nil
+	}
+	if checkpoint.Continue != "" {
+		options.Continue = checkpoint.Continue
+	}
+
+	var saveErr error
+	_, err = p.EachListItemWithLimit(ctx, options, func(obj runtime.Object) error {
+		// Stop processing as soon as the progress cannot be recorded.
+		if saveErr != nil {
+			return saveErr
+		}
+		return fn(obj)
+	}, func(progress ListProgress) {
+		if saveErr != nil {
+			return
+		}
+		next := Checkpoint{
+			Continue:        progress.Continue,
+			ResourceVersion: progress.ResourceVersion,
+			ListComplete:    progress.Continue == "",
+		}
+		if saveErr = store.Save(next); saveErr == nil {
+			checkpoint = next
+		}
+	})
+	if saveErr != nil {
+		return checkpoint, saveErr
+	}
+	return checkpoint, err
+}
+
+// NewFileCheckpointStore returns a CheckpointStore that keeps the checkpoint
+// as JSON in the file at path. The file is replaced atomically on every save.
+func NewFileCheckpointStore(path string) CheckpointStore {
+	return &fileCheckpointStore{path: path}
+}
+
+type fileCheckpointStore struct {
+	lock sync.Mutex
+	path string
+}
+
+func (s *fileCheckpointStore) Load() (Checkpoint, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var checkpoint Checkpoint
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return checkpoint, nil
+	}
+	if err != nil {
+		return checkpoint, err
+	}
+	err = json.Unmarshal(data, &checkpoint)
+	return checkpoint, err
+}
+
+func (s *fileCheckpointStore) Save(checkpoint Checkpoint) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path)
+}
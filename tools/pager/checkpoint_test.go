@@ -0,0 +1,102 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pager
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1beta1 "k8s.io/apimachinery/pkg/apis/meta/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestListPager_EachListItemWithCheckpoint(t *testing.T) {
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint"))
+	errProcessing := fmt.Errorf("processing failed")
+
+	p := &ListPager{PageSize: 10, PageFn: (&testPager{t: t, expectPage: 10, remaining: 25, rv: "rv:20"}).PagedList}
+	checkpoint, err := p.EachListItemWithCheckpoint(context.Background(), metav1.ListOptions{}, store, func(obj runtime.Object) error {
+		if obj.(*metav1beta1.PartialObjectMetadata).Name == "15" {
+			return errProcessing
+		}
+		return nil
+	})
+	if err != errProcessing {
+		t.Fatalf("expected processing error, got %v", err)
+	}
+	want := Checkpoint{Continue: "rv:20:10", ResourceVersion: "rv:20"}
+	if checkpoint != want {
+		t.Errorf("expected checkpoint %#v, got %#v", want, checkpoint)
+	}
+	if stored, err := store.Load(); err != nil || stored != want {
+		t.Errorf("expected stored checkpoint %#v, got %#v, %v", want, stored, err)
+	}
+
+	// Resume from the stored checkpoint; the partially processed page is fetched again.
+	var names []string
+	p = &ListPager{PageSize: 10, PageFn: (&testPager{t: t, expectPage: 10, index: 10, remaining: 15, last: 10, continuing: true, rv: "rv:20"}).PagedList}
+	checkpoint, err = p.EachListItemWithCheckpoint(context.Background(), metav1.ListOptions{}, store, func(obj runtime.Object) error {
+		names = append(names, obj.(*metav1beta1.PartialObjectMetadata).Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want = Checkpoint{ResourceVersion: "rv:20", ListComplete: true}
+	if checkpoint != want {
+		t.Errorf("expected checkpoint %#v, got %#v", want, checkpoint)
+	}
+	if len(names) != 15 || names[0] != "10" || names[14] != "24" {
+		t.Errorf("unexpected items after resuming: %v", names)
+	}
+
+	// A completed list is not listed again.
+	p = &ListPager{PageSize: 10, PageFn: (&testPager{t: t, done: true}).PagedList}
+	checkpoint, err = p.EachListItemWithCheckpoint(context.Background(), metav1.ListOptions{}, store, func(obj runtime.Object) error {
+		t.Errorf("unexpected item %v", obj)
+		return nil
+	})
+	if err != nil || checkpoint != want {
+		t.Errorf("expected checkpoint %#v, got %#v, %v", want, checkpoint, err)
+	}
+}
+
+type failingCheckpointStore struct {
+	err error
+}
+
+func (s failingCheckpointStore) Load() (Checkpoint, error) { return Checkpoint{}, nil }
+func (s failingCheckpointStore) Save(Checkpoint) error     { return s.err }
+
+func TestListPager_EachListItemWithCheckpointSaveError(t *testing.T) {
+	errSave := fmt.Errorf("disk full")
+	var items int
+	p := &ListPager{PageSize: 10, PageFn: (&testPager{t: t, expectPage: 10, remaining: 25, rv: "rv:20"}).PagedList}
+	_, err := p.EachListItemWithCheckpoint(context.Background(), metav1.ListOptions{}, failingCheckpointStore{err: errSave}, func(obj runtime.Object) error {
+		items++
+		return nil
+	})
+	if err != errSave {
+		t.Errorf("expected save error, got %v", err)
+	}
+	if items != 10 {
+		t.Errorf("expected processing to stop after the first page, processed %d items", items)
+	}
+}
@@ -53,6 +53,11 @@ type ListPager struct {
 
 	// Number of pages to buffer
 	PageBufferSize int32
+
+	// PrefetchPages is the number of pages EachListItemWithLimit requests ahead of the page
+	// being processed, overlapping the round trips with processing. Pages are still processed
+	// in order. Zero disables prefetching so only one page is held in memory at a time.
+	PrefetchPages int32
 }
 
 // New creates a new pager from the provided pager function using the default
@@ -78,6 +83,7 @@ func (p *ListPager) List(ctx context.Context, options metav1.ListOptions) (runti
 		options.Limit = p.PageSize
 	}
 	requestedResourceVersion := options.ResourceVersion
+	requestedResourceVersionMatch := options.ResourceVersionMatch
 	var list *metainternalversion.List
 	paginatedResult := false
 
@@ -102,6 +108,7 @@ func (p *ListPager) List(ctx context.Context, options metav1.ListOptions) (runti
 			options.Limit = 0
 			options.Continue = ""
 			options.ResourceVersion = requestedResourceVersion
+			options.ResourceVersionMatch = requestedResourceVersionMatch
 			result, err := p.PageFn(ctx, options)
 			return result, paginatedResult, err
 		}
@@ -139,6 +146,7 @@ func (p *ListPager) List(ctx context.Context, options metav1.ListOptions) (runti
 		// `specifying resource version is not allowed when using continue` error.
 		// See https://github.com/kubernetes/kubernetes/issues/85221#issuecomment-553748143.
 		options.ResourceVersion = ""
+		options.ResourceVersionMatch = ""
 		// At this point, result is already paginated.
 		paginatedResult = true
 	}
@@ -159,6 +167,71 @@ func (p *ListPager) EachListItem(ctx context.Context, options metav1.ListOptions
 	})
 }
 
+// ListProgress describes how far EachListItemWithLimit has progressed through a paginated list.
+type ListProgress struct {
+	// Pages is the number of pages whose processing has started.
+	Pages int
+	// Items is the number of items passed to the item function without error.
+	Items int
+	// Continue is the continue token that resumes the list after the last page whose items were
+	// all processed. It is empty once the last page has been processed.
+	Continue string
+	// ResourceVersion is the resource version of the list.
+	ResourceVersion string
+}
+
+// EachListItemWithLimit fetches runtime.Object items using this ListPager and invokes fn on each item,
+// like EachListItem, but never holds more than the page currently being processed in memory: the next
+// page is only requested once fn has been called for every item of the current one. If
+// ListPager.PrefetchPages is set, up to that many additional pages are requested in the background.
+//
+// If progress is not nil it is called after every page whose items were all processed. The returned
+// ListProgress reflects the state when processing stopped; if an error is returned, setting
+// options.Continue to its Continue token resumes the list with the first page that was not completely
+// processed. Items of that page that were already processed are passed to fn again. Continue tokens
+// expire, in which case resuming fails with an "Expired" error (metav1.StatusReasonExpired).
+func (p *ListPager) EachListItemWithLimit(ctx context.Context, options metav1.ListOptions, fn func(obj runtime.Object) error, progress func(ListProgress)) (ListProgress, error) {
+	state := ListProgress{Continue: options.Continue}
+	if options.Continue != "" {
+		// Specifying a resource version or a resource version match is not
+		// allowed when using continue.
+		options.ResourceVersion = ""
+		options.ResourceVersionMatch = ""
+	}
+	if p.PrefetchPages < 0 {
+		return state, fmt.Errorf("ListPager.PrefetchPages must be >= 0, got %d", p.PrefetchPages)
+	}
+	eachChunk := p.eachListChunk
+	if p.PrefetchPages > 0 {
+		eachChunk = func(ctx context.Context, options metav1.ListOptions, fn func(obj runtime.Object) error) error {
+			return p.eachListChunkBufferedWithSize(ctx, options, p.PrefetchPages, fn)
+		}
+	}
+	err := eachChunk(ctx, options, func(obj runtime.Object) error {
+		state.Pages++
+		m, err := meta.ListAccessor(obj)
+		if err != nil {
+			return fmt.Errorf("returned object must be a list: %v", err)
+		}
+		if err := meta.EachListItem(obj, func(item runtime.Object) error {
+			if err := fn(item); err != nil {
+				return err
+			}
+			state.Items++
+			return nil
+		}); err != nil {
+			return err
+		}
+		state.Continue = m.GetContinue()
+		state.ResourceVersion = m.GetResourceVersion()
+		if progress != nil {
+			progress(state)
+		}
+		return nil
+	})
+	return state, err
+}
+
 // eachListChunkBuffered fetches runtimeObject list chunks using this ListPager and invokes fn on
 // each list chunk.  If fn returns an error, processing stops and that error is returned. If fn does
 // not return an error, any error encountered while retrieving the list from the server is
@@ -171,6 +244,11 @@ func (p *ListPager) eachListChunkBuffered(ctx context.Context, options metav1.Li
 	if p.PageBufferSize < 0 {
 		return fmt.Errorf("ListPager.PageBufferSize must be >= 0, got %d", p.PageBufferSize)
 	}
+	return p.eachListChunkBufferedWithSize(ctx, options, p.PageBufferSize, fn)
+}
+
+// eachListChunkBufferedWithSize is eachListChunkBuffered with up to bufferSize chunks buffered.
+func (p *ListPager) eachListChunkBufferedWithSize(ctx context.Context, options metav1.ListOptions, bufferSize int32, fn func(obj runtime.Object) error) error {
 
 	// Ensure background goroutine is stopped if this call exits before all list items are
 	// processed. Cancelation error from this deferred cancel call is never returned to caller;
@@ -179,7 +257,7 @@ func (p *ListPager) eachListChunkBuffered(ctx context.Context, options metav1.Li
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	chunkC := make(chan runtime.Object, p.PageBufferSize)
+	chunkC := make(chan runtime.Object, bufferSize)
 	bgResultC := make(chan error, 1)
 	go func() {
 		defer utilruntime.HandleCrash()
@@ -243,5 +321,10 @@ func (p *ListPager) eachListChunk(ctx context.Context, options metav1.ListOption
 		}
 		// set the next loop up
 		options.Continue = m.GetContinue()
+		// Clear the ResourceVersion and ResourceVersionMatch on the subsequent List
+		// calls to avoid the `specifying resource version is not allowed when using
+		// continue` error.
+		options.ResourceVersion = ""
+		options.ResourceVersionMatch = ""
 	}
 }
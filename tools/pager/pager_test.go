@@ -76,6 +76,10 @@ func (p *testPager) PagedList(ctx context.Context, options metav1.ListOptions) (
 		p.t.Errorf("invariant violated, specifying resource version (%s) is not allowed when using continue (%s).", options.ResourceVersion, options.Continue)
 		return nil, fmt.Errorf("invariant violated")
 	}
+	if options.Continue != "" && options.ResourceVersionMatch != "" {
+		p.t.Errorf("invariant violated, specifying resource version match (%s) is not allowed when using continue (%s).", options.ResourceVersionMatch, options.Continue)
+		return nil, fmt.Errorf("invariant violated")
+	}
 	var list metainternalversion.List
 	total := options.Limit
 	if total == 0 {
@@ -465,3 +469,117 @@ func TestListPager_eachListPageBuffered(t *testing.T) {
 		})
 	}
 }
+
+func TestListPager_EachListItemWithLimit(t *testing.T) {
+	errProcessing := fmt.Errorf("processing failed")
+	var reported []ListProgress
+	p := &ListPager{PageSize: 10, PageFn: (&testPager{t: t, expectPage: 10, remaining: 25, rv: "rv:20"}).PagedList}
+	state, err := p.EachListItemWithLimit(context.Background(), metav1.ListOptions{}, func(obj runtime.Object) error {
+		if obj.(*metav1beta1.PartialObjectMetadata).Name == "15" {
+			return errProcessing
+		}
+		return nil
+	}, func(progress ListProgress) {
+		reported = append(reported, progress)
+	})
+	if err != errProcessing {
+		t.Fatalf("expected processing error, got %v", err)
+	}
+	if want := (ListProgress{Pages: 2, Items: 15, Continue: "rv:20:10", ResourceVersion: "rv:20"}); state != want {
+		t.Errorf("expected progress %#v, got %#v", want, state)
+	}
+	if want := []ListProgress{{Pages: 1, Items: 10, Continue: "rv:20:10", ResourceVersion: "rv:20"}}; !reflect.DeepEqual(reported, want) {
+		t.Errorf("expected reported progress %#v, got %#v", want, reported)
+	}
+
+	// Resume from the saved continue token; the partially processed page is fetched again.
+	var names []string
+	p = &ListPager{PageSize: 10, PageFn: (&testPager{t: t, expectPage: 10, index: 10, remaining: 15, last: 10, continuing: true, rv: "rv:20"}).PagedList}
+	state, err = p.EachListItemWithLimit(context.Background(), metav1.ListOptions{ResourceVersion: "rv:20", Continue: state.Continue}, func(obj runtime.Object) error {
+		names = append(names, obj.(*metav1beta1.PartialObjectMetadata).Name)
+		return nil
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (ListProgress{Pages: 2, Items: 15, ResourceVersion: "rv:20"}); state != want {
+		t.Errorf("expected progress %#v, got %#v", want, state)
+	}
+	if len(names) != 15 || names[0] != "10" || names[14] != "24" {
+		t.Errorf("unexpected items after resuming: %v", names)
+	}
+}
+
+func TestListPager_EachListItemWithLimitResourceVersionMatch(t *testing.T) {
+	options := metav1.ListOptions{ResourceVersion: "rv:20", ResourceVersionMatch: metav1.ResourceVersionMatchNotOlderThan}
+	for _, prefetch := range []int32{0, 1} {
+		p := &ListPager{PageSize: 10, PrefetchPages: prefetch, PageFn: (&testPager{t: t, expectPage: 10, remaining: 25, rv: "rv:20"}).PagedList}
+		state, err := p.EachListItemWithLimit(context.Background(), options, func(runtime.Object) error { return nil }, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if state.Items != 25 {
+			t.Errorf("expected 25 items, got %#v", state)
+		}
+	}
+
+	// Resuming from a continue token drops the resource version match.
+	p := &ListPager{PageSize: 10, PageFn: (&testPager{t: t, expectPage: 10, index: 10, remaining: 15, last: 10, continuing: true, rv: "rv:20"}).PagedList}
+	resumed := options
+	resumed.Continue = "rv:20:10"
+	state, err := p.EachListItemWithLimit(context.Background(), resumed, func(runtime.Object) error { return nil }, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state.Items != 15 {
+		t.Errorf("expected 15 items, got %#v", state)
+	}
+}
+
+func TestListPager_EachListItemWithLimitPrefetch(t *testing.T) {
+	fetched := make(chan struct{}, 10)
+	tp := &testPager{t: t, expectPage: 10, remaining: 30, rv: "rv:20"}
+	p := &ListPager{
+		PageSize: 10,
+		PageFn: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			defer func() { fetched <- struct{}{} }()
+			return tp.PagedList(ctx, options)
+		},
+		PrefetchPages: 1,
+	}
+
+	var names []string
+	state, err := p.EachListItemWithLimit(context.Background(), metav1.ListOptions{}, func(obj runtime.Object) error {
+		name := obj.(*metav1beta1.PartialObjectMetadata).Name
+		if name == "0" {
+			// The second page is requested while the first one is being processed.
+			select {
+			case <-fetched:
+			case <-time.After(time.Second):
+			}
+			select {
+			case <-fetched:
+			case <-time.After(time.Second):
+				t.Errorf("expected the next page to be prefetched")
+			}
+		}
+		names = append(names, name)
+		return nil
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (ListProgress{Pages: 3, Items: 30, ResourceVersion: "rv:20"}); state != want {
+		t.Errorf("expected progress %#v, got %#v", want, state)
+	}
+	for i, name := range names {
+		if name != fmt.Sprintf("%d", i) {
+			t.Fatalf("expected items in order, got %v", names)
+		}
+	}
+
+	p.PrefetchPages = -1
+	if _, err := p.EachListItemWithLimit(context.Background(), metav1.ListOptions{}, func(runtime.Object) error { return nil }, nil); err == nil {
+		t.Errorf("expected error for negative PrefetchPages")
+	}
+}
@@ -27,10 +27,13 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/httpstream"
 	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
 	netutils "k8s.io/utils/net"
 )
 
@@ -42,12 +45,20 @@ const PortForwardProtocolV1Name = "portforward.k8s.io"
 // a remote pod via an upgraded HTTP request.
 type PortForwarder struct {
 	addresses []listenAddress
-	ports     []ForwardedPort
 	stopChan  <-chan struct{}
 
-	dialer        httpstream.Dialer
+	// portsLock guards ports, listening and closed
+	portsLock sync.Mutex
+	ports     []*Port
+	// listening is whether the ports are listened on as soon as they are added
+	listening bool
+	closed    bool
+
+	dialer  httpstream.Dialer
+	options Options
+	// connLock guards streamConn, which is replaced when dialed again
+	connLock      sync.RWMutex
 	streamConn    httpstream.Connection
-	listeners     []io.Closer
 	Ready         chan struct{}
 	requestIDLock sync.Mutex
 	requestID     int
@@ -55,12 +66,144 @@ type PortForwarder struct {
 	errOut        io.Writer
 }
 
+// Options configures the optional behaviors of a PortForwarder.
+type Options struct {
+	// Reconnect is the backoff between consecutive attempts to dial the
+	// connection to the pod again once it is lost, while the listeners keep
+	// listening. Its Steps is the maximum number of consecutive attempts,
+	// which restarts once dialing succeeds. The connection is not dialed
+	// again if nil, and the PortForwarder then stops forwarding.
+	Reconnect *wait.Backoff
+	// OnReconnect is an optional function called with the error which ended
+	// the connection, or the error of the previous attempt, before each
+	// attempt to dial the connection again.
+	OnReconnect func(err error)
+	// OnError is an optional function called with the errors of forwarding
+	// the connections to a port, in addition to runtime.HandleError.
+	OnError func(port ForwardedPort, err error)
+	// OnConnectionClosed is an optional function called once a connection
+	// to a port was forwarded, with the number of bytes sent to the pod and
+	// received from the pod through the connection.
+	OnConnectionClosed func(port ForwardedPort, sent, received int64)
+}
+
 // ForwardedPort contains a Local:Remote port pairing.
 type ForwardedPort struct {
 	Local  uint16
 	Remote uint16
 }
 
+// Port is a port forwarded by a PortForwarder, which may be added and removed
+// while the PortForwarder forwards its ports.
+type Port struct {
+	// the connection counters are accessed atomically, and first in the
+	// struct to be 64-bit aligned
+	activeConnections int64
+	totalConnections  int64
+	bytesSent         int64
+	bytesReceived     int64
+
+	lock      sync.Mutex
+	port      ForwardedPort
+	listeners []io.Closer
+	removed   bool
+	ready     chan struct{}
+}
+
+func newPort(port ForwardedPort) *Port {
+	return &Port{port: port, ready: make(chan struct{})}
+}
+
+// Ready returns a channel closed once the port is listened on. It is never
+// closed if listening fails.
+func (p *Port) Ready() <-chan struct{} {
+	return p.ready
+}
+
+// ForwardedPort returns the ports of the port. Its local port is the port
+// listened on once the port is ready, if the port was requested with the
+// local port 0.
+func (p *Port) ForwardedPort() ForwardedPort {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.port
+}
+
+// ActiveConnections returns the number of connections being forwarded.
+func (p *Port) ActiveConnections() int64 {
+	return atomic.LoadInt64(&p.activeConnections)
+}
+
+// TotalConnections returns the number of connections accepted on the port.
+func (p *Port) TotalConnections() int64 {
+	return atomic.LoadInt64(&p.totalConnections)
+}
+
+// BytesSent returns the number of bytes sent to the pod through the
+// connections to the port.
+func (p *Port) BytesSent() int64 {
+	return atomic.LoadInt64(&p.bytesSent)
+}
+
+// BytesReceived returns the number of bytes received from the pod through
+// the connections to the port.
+func (p *Port) BytesReceived() int64 {
+	return atomic.LoadInt64(&p.bytesReceived)
+}
+
+// countingConn counts the bytes forwarded through a connection to a port.
+type countingConn struct {
+	net.Conn
+	port           *Port
+	sent, received int64
+}
+
+// Read reads the data sent to the pod.
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	atomic.AddInt64(&c.sent, int64(n))
+	atomic.AddInt64(&c.port.bytesSent, int64(n))
+	return n, err
+}
+
+// Write writes the data received from the pod.
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	atomic.AddInt64(&c.received, int64(n))
+	atomic.AddInt64(&c.port.bytesReceived, int64(n))
+	return n, err
+}
+
+// setListeners sets the listeners of the port listened on port, or closes
+// them if the port was removed meanwhile.
+func (p *Port) setListeners(port ForwardedPort, listeners []io.Closer) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.port = port
+	p.listeners = listeners
+	if p.removed {
+		p.closeListenersLocked()
+	}
+}
+
+// close closes the listeners of the port, and prevents it from being
+// listened on again.
+func (p *Port) close() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.removed = true
+	p.closeListenersLocked()
+}
+
+func (p *Port) closeListenersLocked() {
+	for _, l := range p.listeners {
+		if err := l.Close(); err != nil {
+			runtime.HandleError(fmt.Errorf("error closing listener: %v", err))
+		}
+	}
+	p.listeners = nil
+}
+
 /*
 	valid port specifications:
 
@@ -159,6 +302,12 @@ func New(dialer httpstream.Dialer, ports []string, stopChan <-chan struct{}, rea
 
 // NewOnAddresses creates a new PortForwarder with custom listen addresses.
 func NewOnAddresses(dialer httpstream.Dialer, addresses []string, ports []string, stopChan <-chan struct{}, readyChan chan struct{}, out, errOut io.Writer) (*PortForwarder, error) {
+	return NewOnAddressesWithOptions(dialer, addresses, ports, stopChan, readyChan, out, errOut, Options{})
+}
+
+// NewOnAddressesWithOptions creates a new PortForwarder with custom listen
+// addresses and options.
+func NewOnAddressesWithOptions(dialer httpstream.Dialer, addresses []string, ports []string, stopChan <-chan struct{}, readyChan chan struct{}, out, errOut io.Writer, options Options) (*PortForwarder, error) {
 	if len(addresses) == 0 {
 		return nil, errors.New("you must specify at least 1 address")
 	}
@@ -173,10 +322,15 @@ func NewOnAddresses(dialer httpstream.Dialer, addresses []string, ports []string
 	if err != nil {
 		return nil, err
 	}
+	forwardedPorts := make([]*Port, 0, len(parsedPorts))
+	for _, port := range parsedPorts {
+		forwardedPorts = append(forwardedPorts, newPort(port))
+	}
 	return &PortForwarder{
 		dialer:    dialer,
+		options:   options,
 		addresses: parsedAddresses,
-		ports:     parsedPorts,
+		ports:     forwardedPorts,
 		stopChan:  stopChan,
 		Ready:     readyChan,
 		out:       out,
@@ -189,38 +343,83 @@ func NewOnAddresses(dialer httpstream.Dialer, addresses []string, ports []string
 func (pf *PortForwarder) ForwardPorts() error {
 	defer pf.Close()
 
-	var err error
-	pf.streamConn, _, err = pf.dialer.Dial(PortForwardProtocolV1Name)
+	streamConn, _, err := pf.dialer.Dial(PortForwardProtocolV1Name)
 	if err != nil {
 		return fmt.Errorf("error upgrading connection: %s", err)
 	}
-	defer pf.streamConn.Close()
+	pf.setConnection(streamConn)
+	defer func() { pf.connection().Close() }()
 
 	return pf.forward()
 }
 
+// connection returns the current connection to the pod.
+func (pf *PortForwarder) connection() httpstream.Connection {
+	pf.connLock.RLock()
+	defer pf.connLock.RUnlock()
+	return pf.streamConn
+}
+
+func (pf *PortForwarder) setConnection(conn httpstream.Connection) {
+	pf.connLock.Lock()
+	defer pf.connLock.Unlock()
+	pf.streamConn = conn
+}
+
+// reconnect dials the connection to the pod again, until dialing succeeds,
+// or the attempts are exhausted, or the PortForwarder is stopped.
+func (pf *PortForwarder) reconnect(lost error) (stopped bool, err error) {
+	backoff := *pf.options.Reconnect
+	err = lost
+	for backoff.Steps > 0 {
+		if pf.options.OnReconnect != nil {
+			pf.options.OnReconnect(err)
+		}
+		t := time.NewTimer(backoff.Step())
+		select {
+		case <-pf.stopChan:
+			t.Stop()
+			return true, nil
+		case <-t.C:
+		}
+
+		streamConn, _, dialErr := pf.dialer.Dial(PortForwardProtocolV1Name)
+		if dialErr == nil {
+			pf.setConnection(streamConn)
+			return false, nil
+		}
+		err = fmt.Errorf("error upgrading connection: %s", dialErr)
+	}
+	return false, err
+}
+
 // forward dials the remote host specific in req, upgrades the request, starts
 // listeners for each port specified in ports, and forwards local connections
 // to the remote host via streams.
 func (pf *PortForwarder) forward() error {
 	var err error
 
+	// the ports added from now on are listened on by AddPort
+	pf.portsLock.Lock()
+	pf.listening = true
+	ports := append([]*Port(nil), pf.ports...)
+	pf.portsLock.Unlock()
+
 	listenSuccess := false
-	for i := range pf.ports {
-		port := &pf.ports[i]
+	for _, port := range ports {
 		err = pf.listenOnPort(port)
 		switch {
 		case err == nil:
 			listenSuccess = true
 		default:
 			if pf.errOut != nil {
-				fmt.Fprintf(pf.errOut, "Unable to listen on port %d: %v\n", port.Local, err)
+				fmt.Fprintf(pf.errOut, "Unable to listen on port %d: %v\n", port.ForwardedPort().Local, err)
 			}
 		}
 	}
 
 	if !listenSuccess {
-		return fmt.Errorf("unable to listen on any of the requested ports: %v", pf.ports)
+		return fmt.Errorf("unable to listen on any of the requested ports: %v", forwardedPorts(ports))
 	}
 
 	if pf.Ready != nil {
@@ -228,49 +427,65 @@ func (pf *PortForwarder) forward() error {
 	}
 
 	// wait for interrupt or conn closure
-	select {
-	case <-pf.stopChan:
-	case <-pf.streamConn.CloseChan():
-		runtime.HandleError(errors.New("lost connection to pod"))
+	for {
+		select {
+		case <-pf.stopChan:
+			return nil
+		case <-pf.connection().CloseChan():
+			lost := errors.New("lost connection to pod")
+			runtime.HandleError(lost)
+			if pf.options.Reconnect == nil {
+				return nil
+			}
+			stopped, err := pf.reconnect(lost)
+			if stopped {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("unable to reconnect to pod: %v", err)
+			}
+		}
 	}
-
-	return nil
 }
 
 // listenOnPort delegates listener creation and waits for connections on requested bind addresses.
 // An error is raised based on address groups (default and localhost) and their failure modes
-func (pf *PortForwarder) listenOnPort(port *ForwardedPort) error {
+func (pf *PortForwarder) listenOnPort(port *Port) error {
 	var errors []error
+	var listeners []io.Closer
+	forwardedPort := port.ForwardedPort()
 	failCounters := make(map[string]int, 2)
 	successCounters := make(map[string]int, 2)
 	for _, addr := range pf.addresses {
-		err := pf.listenOnPortAndAddress(port, addr.protocol, addr.address)
+		listener, err := pf.listenOnPortAndAddress(port, &forwardedPort, addr.protocol, addr.address)
 		if err != nil {
 			errors = append(errors, err)
 			failCounters[addr.failureMode]++
 		} else {
+			listeners = append(listeners, listener)
 			successCounters[addr.failureMode]++
 		}
 	}
+	port.setListeners(forwardedPort, listeners)
 	if successCounters["all"] == 0 && failCounters["all"] > 0 {
 		return fmt.Errorf("%s: %v", "Listeners failed to create with the following errors", errors)
 	}
 	if failCounters["any"] > 0 {
 		return fmt.Errorf("%s: %v", "Listeners failed to create with the following errors", errors)
 	}
+	close(port.ready)
 	return nil
 }
 
 // listenOnPortAndAddress delegates listener creation and waits for new connections
 // in the background f
-func (pf *PortForwarder) listenOnPortAndAddress(port *ForwardedPort, protocol string, address string) error {
-	listener, err := pf.getListener(protocol, address, port)
+func (pf *PortForwarder) listenOnPortAndAddress(port *Port, forwardedPort *ForwardedPort, protocol string, address string) (io.Closer, error) {
+	listener, err := pf.getListener(protocol, address, forwardedPort)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	pf.listeners = append(pf.listeners, listener)
-	go pf.waitForConnection(listener, *port)
-	return nil
+	go pf.waitForConnection(listener, port)
+	return listener, nil
 }
 
 // getListener creates a listener on the interface targeted by the given hostname on the given port with
@@ -298,22 +513,43 @@ func (pf *PortForwarder) getListener(protocol string, hostname string, port *For
 
 // waitForConnection waits for new connections to listener and handles them in
 // the background.
-func (pf *PortForwarder) waitForConnection(listener net.Listener, port ForwardedPort) {
+func (pf *PortForwarder) waitForConnection(listener net.Listener, port *Port) {
 	for {
-		select {
-		case <-pf.streamConn.CloseChan():
-			return
-		default:
-			conn, err := listener.Accept()
-			if err != nil {
-				// TODO consider using something like https://github.com/hydrogen18/stoppableListener?
-				if !strings.Contains(strings.ToLower(err.Error()), "use of closed network connection") {
-					runtime.HandleError(fmt.Errorf("error accepting connection on port %d: %v", port.Local, err))
-				}
+		// the listeners outlive the connections to the pod dialed again
+		if pf.options.Reconnect == nil {
+			select {
+			case <-pf.connection().CloseChan():
 				return
+			default:
+			}
+		}
+		conn, err := listener.Accept()
+		if err != nil {
+			// TODO consider using something like https://github.com/hydrogen18/stoppableListener?
+			if !strings.Contains(strings.ToLower(err.Error()), "use of closed network connection") {
+				pf.handleError(port.ForwardedPort(), fmt.Errorf("error accepting connection on port %d: %v", port.ForwardedPort().Local, err))
 			}
-			go pf.handleConnection(conn, port)
+			return
 		}
+		atomic.AddInt64(&port.totalConnections, 1)
+		atomic.AddInt64(&port.activeConnections, 1)
+		go func() {
+			defer atomic.AddInt64(&port.activeConnections, -1)
+			counted := &countingConn{Conn: conn, port: port}
+			forwardedPort := port.ForwardedPort()
+			pf.handleConnection(counted, forwardedPort)
+			if pf.options.OnConnectionClosed != nil {
+				pf.options.OnConnectionClosed(forwardedPort, atomic.LoadInt64(&counted.sent), atomic.LoadInt64(&counted.received))
+			}
+		}()
+	}
+}
+
+// handleError handles an error of forwarding the connections to port.
+func (pf *PortForwarder) handleError(port ForwardedPort, err error) {
+	runtime.HandleError(err)
+	if pf.options.OnError != nil {
+		pf.options.OnError(port, err)
 	}
 }
 
@@ -335,15 +571,16 @@ func (pf *PortForwarder) handleConnection(conn net.Conn, port ForwardedPort) {
 	}
 
 	requestID := pf.nextRequestID()
+	streamConn := pf.connection()
 
 	// create error stream
 	headers := http.Header{}
 	headers.Set(v1.StreamType, v1.StreamTypeError)
 	headers.Set(v1.PortHeader, fmt.Sprintf("%d", port.Remote))
 	headers.Set(v1.PortForwardRequestIDHeader, strconv.Itoa(requestID))
-	errorStream, err := pf.streamConn.CreateStream(headers)
+	errorStream, err := streamConn.CreateStream(headers)
 	if err != nil {
-		runtime.HandleError(fmt.Errorf("error creating error stream for port %d -> %d: %v", port.Local, port.Remote, err))
+		pf.handleError(port, fmt.Errorf("error creating error stream for port %d -> %d: %v", port.Local, port.Remote, err))
 		return
 	}
 	// we're not writing to this stream
@@ -363,9 +600,9 @@ func (pf *PortForwarder) handleConnection(conn net.Conn, port ForwardedPort) {
 
 	// create data stream
 	headers.Set(v1.StreamType, v1.StreamTypeData)
-	dataStream, err := pf.streamConn.CreateStream(headers)
+	dataStream, err := streamConn.CreateStream(headers)
 	if err != nil {
-		runtime.HandleError(fmt.Errorf("error creating forwarding stream for port %d -> %d: %v", port.Local, port.Remote, err))
+		pf.handleError(port, fmt.Errorf("error creating forwarding stream for port %d -> %d: %v", port.Local, port.Remote, err))
 		return
 	}
 
@@ -375,7 +612,7 @@ func (pf *PortForwarder) handleConnection(conn net.Conn, port ForwardedPort) {
 	go func() {
 		// Copy from the remote side to the local port.
 		if _, err := io.Copy(conn, dataStream); err != nil && !strings.Contains(err.Error(), "use of closed network connection") {
-			runtime.HandleError(fmt.Errorf("error copying from remote stream to local connection: %v", err))
+			pf.handleError(port, fmt.Errorf("error copying from remote stream to local connection: %v", err))
 		}
 
 		// inform the select below that the remote copy is done
@@ -388,7 +625,7 @@ func (pf *PortForwarder) handleConnection(conn net.Conn, port ForwardedPort) {
 
 		// Copy from the local port to the remote side.
 		if _, err := io.Copy(dataStream, conn); err != nil && !strings.Contains(err.Error(), "use of closed network connection") {
-			runtime.HandleError(fmt.Errorf("error copying from local connection to remote stream: %v", err))
+			pf.handleError(port, fmt.Errorf("error copying from local connection to remote stream: %v", err))
 			// break out of the select below without waiting for the other copy to finish
 			close(localError)
 		}
@@ -403,19 +640,82 @@ func (pf *PortForwarder) handleConnection(conn net.Conn, port ForwardedPort) {
 	// always expect something on errorChan (it may be nil)
 	err = <-errorChan
 	if err != nil {
-		runtime.HandleError(err)
-		pf.streamConn.Close()
+		pf.handleError(port, err)
+		streamConn.Close()
 	}
 }
 
 // Close stops all listeners of PortForwarder.
 func (pf *PortForwarder) Close() {
+	pf.portsLock.Lock()
+	defer pf.portsLock.Unlock()
+	pf.closed = true
 	// stop all listeners
-	for _, l := range pf.listeners {
-		if err := l.Close(); err != nil {
-			runtime.HandleError(fmt.Errorf("error closing listener: %v", err))
+	for _, port := range pf.ports {
+		port.close()
+	}
+}
+
+// AddPort adds a port to forward, in the format of the ports given to New.
+// If the PortForwarder already forwards its ports, the port is listened on
+// before AddPort returns, and an error is returned if listening fails.
+// Otherwise, it is listened on with the other ports.
+func (pf *PortForwarder) AddPort(port string) (*Port, error) {
+	parsedPorts, err := parsePorts([]string{port})
+	if err != nil {
+		return nil, err
+	}
+	added := newPort(parsedPorts[0])
+
+	pf.portsLock.Lock()
+	if pf.closed {
+		pf.portsLock.Unlock()
+		return nil, errors.New("the port forwarder is closed")
+	}
+	pf.ports = append(pf.ports, added)
+	listening := pf.listening
+	pf.portsLock.Unlock()
+
+	if listening {
+		if err := pf.listenOnPort(added); err != nil {
+			pf.RemovePort(added)
+			return nil, err
+		}
+	}
+	return added, nil
+}
+
+// RemovePort stops forwarding port: its listeners are closed, while the
+// connections being forwarded are not interrupted. It returns an error if
+// the port is not forwarded by the PortForwarder.
+func (pf *PortForwarder) RemovePort(port *Port) error {
+	pf.portsLock.Lock()
+	defer pf.portsLock.Unlock()
+	for i := range pf.ports {
+		if pf.ports[i] == port {
+			pf.ports = append(pf.ports[:i], pf.ports[i+1:]...)
+			port.close()
+			return nil
 		}
 	}
+	return fmt.Errorf("port %d is not forwarded", port.ForwardedPort().Local)
+}
+
+// Ports returns the ports forwarded by the PortForwarder, including the
+// ports added with AddPort.
+func (pf *PortForwarder) Ports() []*Port {
+	pf.portsLock.Lock()
+	defer pf.portsLock.Unlock()
+	return append([]*Port(nil), pf.ports...)
+}
+
+// forwardedPorts returns the ports of ports.
+func forwardedPorts(ports []*Port) []ForwardedPort {
+	forwarded := make([]ForwardedPort, 0, len(ports))
+	for _, port := range ports {
+		forwarded = append(forwarded, port.ForwardedPort())
+	}
+	return forwarded
 }
 
 // GetPorts will return the ports that were forwarded; this can be used to
@@ -429,7 +729,7 @@ func (pf *PortForwarder) GetPorts() ([]ForwardedPort, error) {
 	}
 	select {
 	case <-pf.Ready:
-		return pf.ports, nil
+		return forwardedPorts(pf.Ports()), nil
 	default:
 		return nil, fmt.Errorf("listeners not ready")
 	}
@@ -18,13 +18,18 @@ package portforward
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -32,6 +37,7 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/apimachinery/pkg/util/wait"
 )
 
 type fakeDialer struct {
@@ -557,6 +563,250 @@ func TestWaitForConnectionExitsOnStreamConnClosed(t *testing.T) {
 	pf.streamConn = newFakeConnection()
 	pf.streamConn.Close()
 
-	port := ForwardedPort{}
+	port := newPort(ForwardedPort{})
 	pf.waitForConnection(&listener, port)
 }
+
+func TestAddAndRemovePorts(t *testing.T) {
+	conn := newFakeConnection()
+	conn.errorStream.readFunc = func(p []byte) (int, error) { return 0, io.EOF }
+	conn.dataStream.readFunc = func(p []byte) (int, error) { return 0, io.EOF }
+	conn.dataStream.writeFunc = func(p []byte) (int, error) { return len(p), nil }
+	stopChan := make(chan struct{})
+	readyChan := make(chan struct{})
+	pf, err := NewOnAddresses(&fakeDialer{conn: conn}, []string{"127.0.0.1"}, []string{":80"}, stopChan, readyChan, nil, nil)
+	if err != nil {
+		t.Fatalf("error while calling New: %s", err)
+	}
+
+	// the ports added before forwarding are listened on with the others
+	queued, err := pf.AddPort(":81")
+	if err != nil {
+		t.Fatalf("unexpected error adding a port: %v", err)
+	}
+	select {
+	case <-queued.Ready():
+		t.Fatal("the port is ready before forwarding")
+	default:
+	}
+
+	errChan := make(chan error)
+	go func() {
+		errChan <- pf.ForwardPorts()
+	}()
+	<-pf.Ready
+	<-queued.Ready()
+
+	added, err := pf.AddPort(":82")
+	if err != nil {
+		t.Fatalf("unexpected error adding a port: %v", err)
+	}
+	select {
+	case <-added.Ready():
+	default:
+		t.Fatal("the port added while forwarding is not ready")
+	}
+	port := added.ForwardedPort()
+	if port.Local == 0 || port.Remote != 82 {
+		t.Fatalf("unexpected port %#v", port)
+	}
+	if ports, err := pf.GetPorts(); err != nil || len(ports) != 3 {
+		t.Fatalf("expected 3 ports, got %v (%v)", ports, err)
+	}
+
+	local, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(int(port.Local))))
+	if err != nil {
+		t.Fatalf("unable to connect to the added port: %v", err)
+	}
+	local.Close()
+	err = wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		return added.TotalConnections() == 1 && added.ActiveConnections() == 0, nil
+	})
+	if err != nil {
+		t.Fatalf("expected 1 handled connection, got %d total and %d active", added.TotalConnections(), added.ActiveConnections())
+	}
+
+	if err := pf.RemovePort(added); err != nil {
+		t.Fatalf("unexpected error removing a port: %v", err)
+	}
+	if _, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(int(port.Local)))); err == nil {
+		t.Error("expected the removed port not to be listened on")
+	}
+	if err := pf.RemovePort(added); err == nil {
+		t.Error("expected an error removing a port twice")
+	}
+	if ports := pf.Ports(); len(ports) != 2 {
+		t.Errorf("expected 2 ports, got %d", len(ports))
+	}
+
+	close(stopChan)
+	if err := <-errChan; err != nil {
+		t.Fatalf("ForwardPorts returned error: %s", err)
+	}
+	if _, err := pf.AddPort(":83"); err == nil {
+		t.Error("expected an error adding a port once closed")
+	}
+}
+
+// sequenceDialer dials its connections, or fails with its errors, in order.
+type sequenceDialer struct {
+	lock  sync.Mutex
+	conns []httpstream.Connection
+	errs  []error
+	dials int
+}
+
+func (d *sequenceDialer) Dial(protocols ...string) (httpstream.Connection, string, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	i := d.dials
+	if i >= len(d.conns) {
+		i = len(d.conns) - 1
+	}
+	d.dials++
+	return d.conns[i], "", d.errs[i]
+}
+
+func (d *sequenceDialer) dialCount() int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.dials
+}
+
+func TestForwardPortsReconnects(t *testing.T) {
+	first, second := newFakeConnection(), newFakeConnection()
+	// the second connection replies "pong" to "ping"
+	received := make(chan struct{})
+	var data bytes.Buffer
+	replied := false
+	second.errorStream.readFunc = func(p []byte) (int, error) { return 0, io.EOF }
+	second.dataStream.writeFunc = func(p []byte) (int, error) {
+		n, _ := data.Write(p)
+		if data.String() == "ping" {
+			close(received)
+		}
+		return n, nil
+	}
+	second.dataStream.readFunc = func(p []byte) (int, error) {
+		<-received
+		if replied {
+			return 0, io.EOF
+		}
+		replied = true
+		return copy(p, "pong"), nil
+	}
+	dialer := &sequenceDialer{
+		conns: []httpstream.Connection{first, nil, second},
+		errs:  []error{nil, errors.New("connection refused"), nil},
+	}
+
+	var lock sync.Mutex
+	var reconnectErrors, forwardErrors []error
+	var sent, receivedBytes int64
+	closedConnections := make(chan struct{})
+	stopChan := make(chan struct{})
+	readyChan := make(chan struct{})
+	pf, err := NewOnAddressesWithOptions(dialer, []string{"127.0.0.1"}, []string{":80"}, stopChan, readyChan, nil, nil, Options{
+		Reconnect: &wait.Backoff{Duration: time.Millisecond, Steps: 3},
+		OnReconnect: func(err error) {
+			lock.Lock()
+			defer lock.Unlock()
+			reconnectErrors = append(reconnectErrors, err)
+		},
+		OnError: func(port ForwardedPort, err error) {
+			lock.Lock()
+			defer lock.Unlock()
+			forwardErrors = append(forwardErrors, err)
+		},
+		OnConnectionClosed: func(port ForwardedPort, s, r int64) {
+			sent, receivedBytes = s, r
+			close(closedConnections)
+		},
+	})
+	if err != nil {
+		t.Fatalf("error while calling New: %s", err)
+	}
+	errChan := make(chan error)
+	go func() {
+		errChan <- pf.ForwardPorts()
+	}()
+	<-pf.Ready
+
+	first.Close()
+	err = wait.PollImmediate(time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		return dialer.dialCount() == 3, nil
+	})
+	if err != nil {
+		t.Fatalf("expected 3 dials, got %d", dialer.dialCount())
+	}
+	lock.Lock()
+	if len(reconnectErrors) != 2 || !strings.Contains(reconnectErrors[1].Error(), "connection refused") {
+		t.Errorf("unexpected reconnection errors %v", reconnectErrors)
+	}
+	lock.Unlock()
+
+	// the listeners forward to the new connection
+	port := pf.Ports()[0]
+	local, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(int(port.ForwardedPort().Local))))
+	if err != nil {
+		t.Fatalf("unable to connect to the port: %v", err)
+	}
+	fmt.Fprint(local, "ping")
+	local.(*net.TCPConn).CloseWrite()
+	reply, err := ioutil.ReadAll(local)
+	local.Close()
+	if err != nil || string(reply) != "pong" {
+		t.Fatalf("expected pong, got %q (%v)", reply, err)
+	}
+	select {
+	case <-closedConnections:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("the connection was not reported closed")
+	}
+	if sent != 4 || receivedBytes != 4 || port.BytesSent() != 4 || port.BytesReceived() != 4 {
+		t.Errorf("expected 4 bytes sent and received, got %d and %d, and %d and %d on the port", sent, receivedBytes, port.BytesSent(), port.BytesReceived())
+	}
+	lock.Lock()
+	if len(forwardErrors) != 0 {
+		t.Errorf("unexpected errors %v", forwardErrors)
+	}
+	lock.Unlock()
+
+	close(stopChan)
+	if err := <-errChan; err != nil {
+		t.Fatalf("ForwardPorts returned error: %s", err)
+	}
+}
+
+func TestForwardPortsReconnectionsExhausted(t *testing.T) {
+	first := newFakeConnection()
+	dialer := &sequenceDialer{
+		conns: []httpstream.Connection{first, nil},
+		errs:  []error{nil, errors.New("connection refused")},
+	}
+	readyChan := make(chan struct{})
+	pf, err := NewOnAddressesWithOptions(dialer, []string{"127.0.0.1"}, []string{":80"}, nil, readyChan, nil, nil, Options{
+		Reconnect: &wait.Backoff{Duration: time.Millisecond, Steps: 2},
+	})
+	if err != nil {
+		t.Fatalf("error while calling New: %s", err)
+	}
+	errChan := make(chan error)
+	go func() {
+		errChan <- pf.ForwardPorts()
+	}()
+	<-pf.Ready
+
+	first.Close()
+	select {
+	case err := <-errChan:
+		if err == nil || !strings.Contains(err.Error(), "connection refused") {
+			t.Errorf("expected the error of the last attempt, got %v", err)
+		}
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("ForwardPorts did not return once the attempts were exhausted")
+	}
+	if dials := dialer.dialCount(); dials != 3 {
+		t.Errorf("expected 3 dials, got %d", dials)
+	}
+}
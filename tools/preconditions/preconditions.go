@@ -0,0 +1,99 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package preconditions sets the UID and resource version preconditions of
+// deletions and updates, so that controllers do not delete or overwrite an
+// object recreated with the same name, and classifies their failures. The
+// helpers work with the typed, dynamic and metadata clients alike.
+package preconditions // import "k8s.io/client-go/tools/preconditions"
+
+import (
+	"errors"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// uidFailedMsg and resourceVersionFailedMsg prefix the messages of the
+	// conflicts returned by the server when a precondition fails.
+	uidFailedMsg             = "Precondition failed: UID in precondition"
+	resourceVersionFailedMsg = "Precondition failed: ResourceVersion in precondition"
+	// optimisticLockErrorMsg is the message of the conflicts returned by the
+	// server when an update carries a stale resource version.
+	optimisticLockErrorMsg = "the object has been modified; please apply your changes to the latest version and try again"
+)
+
+// UID returns preconditions on the UID of obj.
+func UID(obj metav1.Object) *metav1.Preconditions {
+	uid := obj.GetUID()
+	return &metav1.Preconditions{UID: &uid}
+}
+
+// UIDAndResourceVersion returns preconditions on the UID and resource version
+// of obj, failing if obj was recreated or modified since it was read.
+func UIDAndResourceVersion(obj metav1.Object) *metav1.Preconditions {
+	uid := obj.GetUID()
+	resourceVersion := obj.GetResourceVersion()
+	return &metav1.Preconditions{UID: &uid, ResourceVersion: &resourceVersion}
+}
+
+// DeleteOptions returns delete options with a precondition on the UID of
+// obj, so that the deletion fails instead of deleting an object recreated
+// with the same name.
+func DeleteOptions(obj metav1.Object) metav1.DeleteOptions {
+	return metav1.DeleteOptions{Preconditions: UID(obj)}
+}
+
+// ForUpdate sets the UID and resource version of obj, the object about to be
+// updated, to the ones of current, the object it was computed from, so that
+// the update fails if current was recreated or modified since it was read.
+func ForUpdate(obj, current metav1.Object) {
+	obj.SetUID(current.GetUID())
+	obj.SetResourceVersion(current.GetResourceVersion())
+}
+
+// IsFailed returns true if err is a conflict caused by a failed UID or
+// resource version precondition, including a stale resource version in the
+// object of an update.
+func IsFailed(err error) bool {
+	return IsUIDFailed(err) || IsResourceVersionFailed(err)
+}
+
+// IsUIDFailed returns true if err is a conflict caused by a failed UID
+// precondition, meaning that the object was recreated with the same name.
+func IsUIDFailed(err error) bool {
+	return conflictMessageContains(err, uidFailedMsg)
+}
+
+// IsResourceVersionFailed returns true if err is a conflict caused by a failed
+// resource version precondition, or by a stale resource version in the object
+// of an update, meaning that the object was modified.
+func IsResourceVersionFailed(err error) bool {
+	return conflictMessageContains(err, resourceVersionFailedMsg) || conflictMessageContains(err, optimisticLockErrorMsg)
+}
+
+func conflictMessageContains(err error, msg string) bool {
+	if !apierrors.IsConflict(err) {
+		return false
+	}
+	var status apierrors.APIStatus
+	if !errors.As(err, &status) {
+		return false
+	}
+	return strings.Contains(status.Status().Message, msg)
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preconditions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func newPod(uid string) *v1.Pod {
+	return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns", UID: types.UID(uid)}}
+}
+
+func TestDeleteRecreated(t *testing.T) {
+	client := fake.NewSimpleClientset(newPod("old"))
+	client.Tracker().(clienttesting.ResourceVersionTracker).SetResourceVersionSemantics(true)
+	pods := client.CoreV1().Pods("ns")
+	ctx := context.Background()
+
+	old, err := pods.Get(ctx, "pod", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pods.Delete(ctx, "pod", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pods.Create(ctx, newPod("new"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	err = pods.Delete(ctx, "pod", DeleteOptions(old))
+	if !IsUIDFailed(err) || !IsFailed(err) || IsResourceVersionFailed(err) {
+		t.Errorf("expected a failed UID precondition, got %v", err)
+	}
+	if _, err := pods.Get(ctx, "pod", metav1.GetOptions{}); err != nil {
+		t.Errorf("expected the recreated pod to remain, got %v", err)
+	}
+}
+
+func TestUpdateModified(t *testing.T) {
+	client := fake.NewSimpleClientset(newPod("uid"))
+	client.Tracker().(clienttesting.ResourceVersionTracker).SetResourceVersionSemantics(true)
+	pods := client.CoreV1().Pods("ns")
+	ctx := context.Background()
+
+	current, err := pods.Get(ctx, "pod", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	modified := current.DeepCopy()
+	modified.Labels = map[string]string{"modified": "true"}
+	if _, err := pods.Update(ctx, modified, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	desired := newPod("")
+	desired.Labels = map[string]string{"desired": "true"}
+	ForUpdate(desired, current)
+	_, err = pods.Update(ctx, desired, metav1.UpdateOptions{})
+	if !IsResourceVersionFailed(err) || IsUIDFailed(err) {
+		t.Errorf("expected a failed resource version precondition, got %v", err)
+	}
+
+	err = pods.Delete(ctx, "pod", metav1.DeleteOptions{Preconditions: UIDAndResourceVersion(current)})
+	if !IsResourceVersionFailed(err) {
+		t.Errorf("expected a failed resource version precondition, got %v", err)
+	}
+}
+
+func TestUpdateRecreated(t *testing.T) {
+	client := fake.NewSimpleClientset(newPod("new"))
+	client.Tracker().(clienttesting.ResourceVersionTracker).SetResourceVersionSemantics(true)
+	pods := client.CoreV1().Pods("ns")
+
+	desired := newPod("")
+	ForUpdate(desired, newPod("old"))
+	_, err := pods.Update(context.Background(), desired, metav1.UpdateOptions{})
+	if !IsUIDFailed(err) {
+		t.Errorf("expected a failed UID precondition, got %v", err)
+	}
+}
+
+func TestIsFailed(t *testing.T) {
+	gr := schema.GroupResource{Resource: "pods"}
+	uidFailed := apierrors.NewConflict(gr, "pod", errors.New("Precondition failed: UID in precondition: a, UID in object meta: b"))
+	testCases := []struct {
+		name                string
+		err                 error
+		uidFailed, rvFailed bool
+	}{
+		{name: "nil"},
+		{name: "other conflict", err: apierrors.NewConflict(gr, "pod", errors.New("other"))},
+		{name: "not a conflict", err: apierrors.NewBadRequest("Precondition failed: UID in precondition")},
+		{name: "uid", err: uidFailed, uidFailed: true},
+		{name: "wrapped uid", err: fmt.Errorf("deleting: %w", uidFailed), uidFailed: true},
+		{
+			name:     "resource version",
+			err:      apierrors.NewConflict(gr, "pod", errors.New("Precondition failed: ResourceVersion in precondition: 1, ResourceVersion in object meta: 2")),
+			rvFailed: true,
+		},
+		{
+			name:     "optimistic lock",
+			err:      apierrors.NewConflict(gr, "pod", errors.New(optimisticLockErrorMsg)),
+			rvFailed: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsUIDFailed(tc.err); got != tc.uidFailed {
+				t.Errorf("expected IsUIDFailed %v, got %v", tc.uidFailed, got)
+			}
+			if got := IsResourceVersionFailed(tc.err); got != tc.rvFailed {
+				t.Errorf("expected IsResourceVersionFailed %v, got %v", tc.rvFailed, got)
+			}
+			if got := IsFailed(tc.err); got != (tc.uidFailed || tc.rvFailed) {
+				t.Errorf("unexpected IsFailed %v", got)
+			}
+		})
+	}
+}
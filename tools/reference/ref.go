@@ -33,7 +33,8 @@ var (
 
 // GetReference returns an ObjectReference which refers to the given
 // object, or an error if the object doesn't follow the conventions
-// that would allow this.
+// that would allow this. The kinds of unstructured and metadata-only
+// objects are read from the objects themselves, and never from scheme.
 // TODO: should take a meta.Interface see http://issue.k8s.io/7127
 func GetReference(scheme *runtime.Scheme, obj runtime.Object) (*v1.ObjectReference, error) {
 	if obj == nil {
@@ -56,29 +57,11 @@ func GetReference(scheme *runtime.Scheme, obj runtime.Object) (*v1.ObjectReferen
 		listMeta = objectMeta
 	}
 
-	gvk := obj.GetObjectKind().GroupVersionKind()
-
-	// If object meta doesn't contain data about kind and/or version,
-	// we are falling back to scheme.
-	//
-	// TODO: This doesn't work for CRDs, which are not registered in scheme.
-	if gvk.Empty() {
-		gvks, _, err := scheme.ObjectKinds(obj)
-		if err != nil {
-			return nil, err
-		}
-		if len(gvks) == 0 || gvks[0].Empty() {
-			return nil, fmt.Errorf("unexpected gvks registered for object %T: %v", obj, gvks)
-		}
-		// TODO: The same object can be registered for multiple group versions
-		// (although in practise this doesn't seem to be used).
-		// In such case, the version set may not be correct.
-		gvk = gvks[0]
+	kind, version, err := objectKind(scheme, obj)
+	if err != nil {
+		return nil, err
 	}
 
-	kind := gvk.Kind
-	version := gvk.GroupVersion().String()
-
 	// only has list metadata
 	if objectMeta == nil {
 		return &v1.ObjectReference{
@@ -98,6 +81,53 @@ func GetReference(scheme *runtime.Scheme, obj runtime.Object) (*v1.ObjectReferen
 	}, nil
 }
 
+// objectKind returns the kind and the API version of obj. Unstructured and
+// metadata-only objects, such as the custom resources which are not
+// registered in scheme, are never looked up in scheme, which registers at
+// best their Go types and not the kinds they hold: their kinds are read from
+// the objects themselves.
+func objectKind(scheme *runtime.Scheme, obj runtime.Object) (string, string, error) {
+	switch obj := obj.(type) {
+	case runtime.Unstructured:
+		// the API version is read as is, even if it is not a valid group version
+		content := obj.UnstructuredContent()
+		kind, _ := content["kind"].(string)
+		version, _ := content["apiVersion"].(string)
+		if len(kind) == 0 {
+			return "", "", fmt.Errorf("unstructured object %T has no kind", obj)
+		}
+		return kind, version, nil
+	case *metav1.PartialObjectMetadata, *metav1.PartialObjectMetadataList:
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		if len(gvk.Kind) == 0 {
+			return "", "", fmt.Errorf("metadata-only object %T has no kind set in its TypeMeta", obj)
+		}
+		return gvk.Kind, gvk.GroupVersion().String(), nil
+	}
+
+	gvk := obj.GetObjectKind().GroupVersionKind()
+
+	// If object meta doesn't contain data about kind and/or version,
+	// we are falling back to scheme.
+	if gvk.Empty() {
+		if scheme == nil {
+			return "", "", fmt.Errorf("object %T has no kind set, and no scheme to look it up", obj)
+		}
+		gvks, _, err := scheme.ObjectKinds(obj)
+		if err != nil {
+			return "", "", err
+		}
+		if len(gvks) == 0 || gvks[0].Empty() {
+			return "", "", fmt.Errorf("unexpected gvks registered for object %T: %v", obj, gvks)
+		}
+		// TODO: The same object can be registered for multiple group versions
+		// (although in practise this doesn't seem to be used).
+		// In such case, the version set may not be correct.
+		gvk = gvks[0]
+	}
+	return gvk.Kind, gvk.GroupVersion().String(), nil
+}
+
 // GetPartialReference is exactly like GetReference, but allows you to set the FieldPath.
 func GetPartialReference(scheme *runtime.Scheme, obj runtime.Object, fieldPath string) (*v1.ObjectReference, error) {
 	ref, err := GetReference(scheme, obj)
@@ -17,9 +17,12 @@ limitations under the License.
 package reference
 
 import (
+	"reflect"
 	"testing"
 
+	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
@@ -72,3 +75,79 @@ func TestGetReferenceRefVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestGetReferenceUnregisteredKinds(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       runtime.Object
+		expected    *v1.ObjectReference
+		expectedErr string
+	}{
+		{
+			name: "unstructured object",
+			input: &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "example.com/v1",
+				"kind":       "Widget",
+				"metadata": map[string]interface{}{
+					"name":            "foo",
+					"namespace":       "bar",
+					"uid":             "1234",
+					"resourceVersion": "42",
+				},
+			}},
+			expected: &v1.ObjectReference{APIVersion: "example.com/v1", Kind: "Widget", Name: "foo", Namespace: "bar", UID: "1234", ResourceVersion: "42"},
+		},
+		{
+			name: "unstructured list",
+			input: &unstructured.UnstructuredList{Object: map[string]interface{}{
+				"apiVersion": "example.com/v1",
+				"kind":       "WidgetList",
+				"metadata":   map[string]interface{}{"resourceVersion": "42"},
+			}},
+			expected: &v1.ObjectReference{APIVersion: "example.com/v1", Kind: "WidgetList", ResourceVersion: "42"},
+		},
+		{
+			name: "unstructured object without kind",
+			input: &unstructured.Unstructured{Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "foo"},
+			}},
+			expectedErr: "unstructured object *unstructured.Unstructured has no kind",
+		},
+		{
+			name: "metadata-only object",
+			input: &metav1.PartialObjectMetadata{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "example.com/v1", Kind: "Widget"},
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "bar", UID: "1234", ResourceVersion: "42"},
+			},
+			expected: &v1.ObjectReference{APIVersion: "example.com/v1", Kind: "Widget", Name: "foo", Namespace: "bar", UID: "1234", ResourceVersion: "42"},
+		},
+		{
+			name:        "metadata-only object without kind",
+			input:       &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
+			expectedErr: "metadata-only object *v1.PartialObjectMetadata has no kind set in its TypeMeta",
+		},
+		{
+			name:        "typed object without kind nor scheme",
+			input:       &TestRuntimeObj{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
+			expectedErr: "object *reference.TestRuntimeObj has no kind set, and no scheme to look it up",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ref, err := GetReference(nil, test.input)
+			if len(test.expectedErr) > 0 {
+				if err == nil || err.Error() != test.expectedErr {
+					t.Fatalf("expected error %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(test.expected, ref) {
+				t.Errorf("expected %#v, got %#v", test.expected, ref)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remotecommand
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/httpstream"
+)
+
+// StreamControl closes the streams of sessions individually, while the
+// session goes on. It is set in the StreamOptions of a session, and may be
+// used before the session starts: the streams closed beforehand are closed
+// as soon as they are created, in every session using the StreamControl.
+type StreamControl struct {
+	lock sync.Mutex
+
+	stdin, stdout, stderr                   httpstream.Stream
+	stdinClosed, stdoutClosed, stderrClosed bool
+}
+
+// NewStreamControl returns a StreamControl without closed streams.
+func NewStreamControl() *StreamControl {
+	return &StreamControl{}
+}
+
+// CloseStdin closes the stdin stream, so that the remote command reads the
+// end of its input. The Stdin of the session is no longer sent.
+func (c *StreamControl) CloseStdin() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.stdinClosed = true
+	if c.stdin == nil {
+		return nil
+	}
+	return c.stdin.Close()
+}
+
+// CloseStdout resets the stdout stream, so that the output of the remote
+// command is no longer written to the Stdout of the session.
+func (c *StreamControl) CloseStdout() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.stdoutClosed = true
+	if c.stdout == nil {
+		return nil
+	}
+	return c.stdout.Reset()
+}
+
+// CloseStderr resets the stderr stream, so that the error output of the
+// remote command is no longer written to the Stderr of the session.
+func (c *StreamControl) CloseStderr() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.stderrClosed = true
+	if c.stderr == nil {
+		return nil
+	}
+	return c.stderr.Reset()
+}
+
+// stdinIsClosed returns whether CloseStdin was called.
+func (c *StreamControl) stdinIsClosed() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.stdinClosed
+}
+
+// setStreams sets the streams of a new session, any of which may be nil,
+// and closes the streams which were closed beforehand.
+func (c *StreamControl) setStreams(stdin, stdout, stderr httpstream.Stream) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.stdin, c.stdout, c.stderr = stdin, stdout, stderr
+	if c.stdinClosed && stdin != nil {
+		if err := stdin.Close(); err != nil {
+			return err
+		}
+	}
+	if c.stdoutClosed && stdout != nil {
+		if err := stdout.Reset(); err != nil {
+			return err
+		}
+	}
+	if c.stderrClosed && stderr != nil {
+		if err := stderr.Reset(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,345 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remotecommand
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// errConnectionLost is returned by sessions whose connection ended before
+// the remote command terminated.
+var errConnectionLost = errors.New("connection lost before the remote command terminated")
+
+// ReconnectOptions configures the reconnections of an executor created by
+// NewReconnectingExecutor.
+type ReconnectOptions struct {
+	// Backoff is the backoff between consecutive reconnection attempts. Its
+	// Steps is the maximum number of consecutive attempts, which restarts
+	// once a session outputs data. Defaults to DefaultReconnectBackoff.
+	Backoff *wait.Backoff
+	// IsTransient returns whether a session failed with err because of a
+	// transient failure, after which it is reconnected. Defaults to
+	// IsTransientError.
+	IsTransient func(err error) bool
+	// OnReconnect is an optional function called with the error of the
+	// previous session before each reconnection attempt.
+	OnReconnect func(err error)
+}
+
+// DefaultReconnectBackoff is the default backoff of reconnection attempts.
+var DefaultReconnectBackoff = wait.Backoff{
+	Duration: time.Second,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    5,
+	Cap:      30 * time.Second,
+}
+
+// IsTransientError returns whether err is the error of a session which
+// failed because of a network failure, or because the server was temporarily
+// unable to serve it.
+func IsTransientError(err error) bool {
+	if errors.Is(err, errConnectionLost) {
+		return true
+	}
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		return apierrors.IsInternalError(err) || apierrors.IsServiceUnavailable(err) ||
+			apierrors.IsServerTimeout(err) || apierrors.IsTimeout(err) || apierrors.IsTooManyRequests(err)
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		utilnet.IsConnectionReset(err) || utilnet.IsConnectionRefused(err) || utilnet.IsProbableEOF(err)
+}
+
+// reconnectingExecutor reconnects the sessions of an executor which fail
+// transiently.
+type reconnectingExecutor struct {
+	executor    Executor
+	backoff     wait.Backoff
+	isTransient func(err error) bool
+	onReconnect func(err error)
+}
+
+var _ ContextExecutor = &reconnectingExecutor{}
+
+// NewReconnectingExecutor returns an executor which streams with executor,
+// and opens a new session whenever a session fails transiently, until it
+// terminates or fails otherwise. Input which is not consumed by a failed
+// session is sent to the next one, and the last terminal size is sent to
+// the new sessions.
+//
+// Sessions of executors created by NewSPDYExecutor using the latest version
+// of the protocol are known to fail when their connection is lost. With
+// previous versions, or other executors, a lost connection may look like a
+// successful session.
+//
+// Reconnecting an exec session executes the command again, so reconnections
+// are meant for attach sessions and for idempotent commands.
+func NewReconnectingExecutor(executor Executor, options ReconnectOptions) Executor {
+	e := &reconnectingExecutor{
+		executor:    executor,
+		backoff:     DefaultReconnectBackoff,
+		isTransient: options.IsTransient,
+		onReconnect: options.OnReconnect,
+	}
+	if options.Backoff != nil {
+		e.backoff = *options.Backoff
+	}
+	if e.isTransient == nil {
+		e.isTransient = IsTransientError
+	}
+	return e
+}
+
+// Stream streams until a session terminates, or fails with an error which
+// is not transient, or the reconnection attempts are exhausted.
+func (e *reconnectingExecutor) Stream(options StreamOptions) error {
+	return e.StreamWithContext(context.Background(), options)
+}
+
+// StreamWithContext is Stream, which also ends when ctx is done. Sessions of
+// executors which do not implement ContextExecutor are not ended by ctx, but
+// no new session is opened once ctx is done.
+func (e *reconnectingExecutor) StreamWithContext(ctx context.Context, options StreamOptions) error {
+	var stdin *stdinPump
+	if options.Stdin != nil {
+		stdin = newStdinPump(options.Stdin)
+	}
+	var sizes *terminalSizePump
+	if options.TerminalSizeQueue != nil {
+		sizes = newTerminalSizePump(options.TerminalSizeQueue)
+	}
+
+	backoff := e.backoff
+	for {
+		done := make(chan struct{})
+		sessionOptions := StreamOptions{
+			Tty:                  options.Tty,
+			Control:              options.Control,
+			reportLostConnection: true,
+		}
+		if stdin != nil {
+			sessionOptions.Stdin = &sessionStdin{pump: stdin, done: done}
+		}
+		var output outputWatcher
+		if options.Stdout != nil {
+			sessionOptions.Stdout = &progressWriter{Writer: options.Stdout, output: &output}
+		}
+		if options.Stderr != nil {
+			sessionOptions.Stderr = &progressWriter{Writer: options.Stderr, output: &output}
+		}
+		if sizes != nil {
+			sessionOptions.TerminalSizeQueue = sizes.session(done)
+		}
+
+		var err error
+		if executor, ok := e.executor.(ContextExecutor); ok {
+			err = executor.StreamWithContext(ctx, sessionOptions)
+		} else {
+			err = e.executor.Stream(sessionOptions)
+		}
+		close(done)
+		if err == nil || ctx.Err() != nil || !e.isTransient(err) {
+			return err
+		}
+
+		if output.seen() {
+			backoff = e.backoff
+		}
+		if backoff.Steps <= 0 {
+			return err
+		}
+		if e.onReconnect != nil {
+			e.onReconnect(err)
+		} else {
+			klog.V(2).Infof("Reconnecting the remote command session after a transient failure: %v", err)
+		}
+		t := time.NewTimer(backoff.Step())
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// stdinPump reads the input of the sessions of a reconnecting executor, so
+// that the input of a failed session is not lost in its copying goroutine.
+type stdinPump struct {
+	chunks chan []byte
+	// err is the error which ended reading, written before chunks is closed
+	err error
+
+	// lock serializes the reads of the sessions
+	lock    sync.Mutex
+	pending []byte
+}
+
+func newStdinPump(r io.Reader) *stdinPump {
+	p := &stdinPump{chunks: make(chan []byte)}
+	go func() {
+		defer runtime.HandleCrash()
+		defer close(p.chunks)
+		for {
+			buf := make([]byte, 32*1024)
+			n, err := r.Read(buf)
+			if n > 0 {
+				p.chunks <- buf[:n]
+			}
+			if err != nil {
+				p.err = err
+				return
+			}
+		}
+	}()
+	return p
+}
+
+// sessionStdin is the input of a session, which ends when the session does.
+type sessionStdin struct {
+	pump *stdinPump
+	done <-chan struct{}
+}
+
+func (s *sessionStdin) Read(p []byte) (int, error) {
+	s.pump.lock.Lock()
+	defer s.pump.lock.Unlock()
+	if len(s.pump.pending) == 0 {
+		select {
+		case chunk, ok := <-s.pump.chunks:
+			if !ok {
+				return 0, s.pump.err
+			}
+			s.pump.pending = chunk
+		case <-s.done:
+			return 0, io.EOF
+		}
+	}
+	n := copy(p, s.pump.pending)
+	s.pump.pending = s.pump.pending[n:]
+	return n, nil
+}
+
+// terminalSizePump reads the terminal sizes of the sessions of a
+// reconnecting executor, and remembers the last one for new sessions.
+type terminalSizePump struct {
+	sizes chan *TerminalSize
+
+	lock sync.Mutex
+	last *TerminalSize
+}
+
+func newTerminalSizePump(queue TerminalSizeQueue) *terminalSizePump {
+	p := &terminalSizePump{sizes: make(chan *TerminalSize)}
+	go func() {
+		defer runtime.HandleCrash()
+		defer close(p.sizes)
+		for {
+			size := queue.Next()
+			if size == nil {
+				return
+			}
+			p.sizes <- size
+		}
+	}()
+	return p
+}
+
+func (p *terminalSizePump) session(done <-chan struct{}) TerminalSizeQueue {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	s := &sessionTerminalSizes{pump: p, done: done}
+	if p.last != nil {
+		last := *p.last
+		s.replay = &last
+	}
+	return s
+}
+
+// sessionTerminalSizes is the terminal size queue of a session, which starts
+// with the last size of the previous sessions.
+type sessionTerminalSizes struct {
+	pump   *terminalSizePump
+	done   <-chan struct{}
+	replay *TerminalSize
+}
+
+func (s *sessionTerminalSizes) Next() *TerminalSize {
+	if s.replay != nil {
+		size := s.replay
+		s.replay = nil
+		return size
+	}
+	select {
+	case size, ok := <-s.pump.sizes:
+		if !ok {
+			return nil
+		}
+		s.pump.lock.Lock()
+		defer s.pump.lock.Unlock()
+		last := *size
+		s.pump.last = &last
+		return size
+	case <-s.done:
+		return nil
+	}
+}
+
+// outputWatcher records whether a session wrote output.
+type outputWatcher struct {
+	lock    sync.Mutex
+	written bool
+}
+
+func (w *outputWatcher) wrote() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.written = true
+}
+
+func (w *outputWatcher) seen() bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.written
+}
+
+// progressWriter records the output of a session.
+type progressWriter struct {
+	io.Writer
+	output *outputWatcher
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		w.output.wrote()
+	}
+	return w.Writer.Write(p)
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remotecommand
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/exec"
+)
+
+// fakeExecutor runs a function per session. It does not implement
+// ContextExecutor.
+type fakeExecutor struct {
+	sessions []func(options StreamOptions) error
+	calls    int
+}
+
+func (e *fakeExecutor) Stream(options StreamOptions) error {
+	session := e.sessions[e.calls]
+	if e.calls < len(e.sessions)-1 {
+		e.calls++
+	}
+	return session(options)
+}
+
+type fakeTerminalSizeQueue chan *TerminalSize
+
+func (q fakeTerminalSizeQueue) Next() *TerminalSize {
+	return <-q
+}
+
+var fastReconnectBackoff = &wait.Backoff{Duration: time.Millisecond, Steps: 2}
+
+func TestReconnectingExecutor(t *testing.T) {
+	sizes := make(fakeTerminalSizeQueue, 1)
+	sizes <- &TerminalSize{Width: 80, Height: 24}
+	var reconnectErrors []error
+
+	executor := NewReconnectingExecutor(&fakeExecutor{sessions: []func(StreamOptions) error{
+		func(options StreamOptions) error {
+			buf := make([]byte, 3)
+			if _, err := io.ReadFull(options.Stdin, buf); err != nil || string(buf) != "abc" {
+				t.Errorf("expected to read %q, got %q, %v", "abc", buf, err)
+			}
+			if size := options.TerminalSizeQueue.Next(); size == nil || *size != (TerminalSize{Width: 80, Height: 24}) {
+				t.Errorf("unexpected terminal size %v", size)
+			}
+			fmt.Fprint(options.Stdout, "out1")
+			return errConnectionLost
+		},
+		func(options StreamOptions) error {
+			if size := options.TerminalSizeQueue.Next(); size == nil || *size != (TerminalSize{Width: 80, Height: 24}) {
+				t.Errorf("expected the last terminal size to be replayed, got %v", size)
+			}
+			data, err := ioutil.ReadAll(options.Stdin)
+			if err != nil || string(data) != "def" {
+				t.Errorf("expected to read the rest of the input %q, got %q, %v", "def", data, err)
+			}
+			fmt.Fprint(options.Stdout, "out2")
+			return nil
+		},
+	}}, ReconnectOptions{
+		Backoff:     fastReconnectBackoff,
+		OnReconnect: func(err error) { reconnectErrors = append(reconnectErrors, err) },
+	})
+
+	stdout := &bytes.Buffer{}
+	err := executor.Stream(StreamOptions{
+		Stdin:             strings.NewReader("abcdef"),
+		Stdout:            stdout,
+		Tty:               true,
+		TerminalSizeQueue: sizes,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stdout.String() != "out1out2" {
+		t.Errorf("expected the output of both sessions, got %q", stdout.String())
+	}
+	if len(reconnectErrors) != 1 || reconnectErrors[0] != errConnectionLost {
+		t.Errorf("expected to reconnect once after the lost connection, got %v", reconnectErrors)
+	}
+}
+
+func TestReconnectingExecutorFailures(t *testing.T) {
+	exitError := exec.CodeExitError{Err: errors.New("command terminated with exit code 1"), Code: 1}
+	tests := []struct {
+		name          string
+		err           error
+		output        bool
+		expectedCalls int
+	}{
+		{
+			name:          "not transient",
+			err:           exitError,
+			expectedCalls: 1,
+		},
+		{
+			name:          "attempts exhausted",
+			err:           errConnectionLost,
+			expectedCalls: 3,
+		},
+		{
+			name:          "attempts restarted by output",
+			err:           errConnectionLost,
+			output:        true,
+			expectedCalls: 10,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calls := 0
+			executor := NewReconnectingExecutor(&fakeExecutor{sessions: []func(StreamOptions) error{
+				func(options StreamOptions) error {
+					calls++
+					if calls == 10 {
+						return exitError
+					}
+					if test.output {
+						fmt.Fprint(options.Stdout, "out")
+					}
+					return test.err
+				},
+			}}, ReconnectOptions{Backoff: fastReconnectBackoff})
+
+			err := executor.Stream(StreamOptions{Stdout: ioutil.Discard})
+			if calls != test.expectedCalls {
+				t.Errorf("expected %d sessions, got %d", test.expectedCalls, calls)
+			}
+			if err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		err       error
+		transient bool
+	}{
+		{errConnectionLost, true},
+		{fmt.Errorf("error reading from error stream: %w", io.ErrUnexpectedEOF), true},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, true},
+		{apierrors.NewServiceUnavailable("unavailable"), true},
+		{apierrors.NewInternalError(errors.New("oops")), true},
+		{apierrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "p", errors.New("no")), false},
+		{apierrors.NewNotFound(schema.GroupResource{Resource: "pods"}, "p"), false},
+		{exec.CodeExitError{Err: errors.New("exit"), Code: 2}, false},
+		{errors.New("error executing remote command: oops"), false},
+	}
+	for _, test := range tests {
+		if transient := IsTransientError(test.err); transient != test.transient {
+			t.Errorf("expected IsTransientError(%v) to be %v", test.err, test.transient)
+		}
+	}
+}
+
+func TestReconnectingSPDYExecutor(t *testing.T) {
+	var lock sync.Mutex
+	connections := 0
+	options := &StreamOptions{Stdout: &bytes.Buffer{}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx, err := createHTTPStreams(w, req, options)
+		if err != nil {
+			return
+		}
+		defer ctx.conn.Close()
+
+		lock.Lock()
+		connections++
+		first := connections == 1
+		lock.Unlock()
+		if first {
+			// the connection is lost before the status is sent
+			ctx.stdoutStream.Write([]byte("out1"))
+			return
+		}
+		ctx.stdoutStream.Write([]byte("out2"))
+		ctx.writeStatus(&apierrors.StatusError{ErrStatus: metav1.Status{Status: metav1.StatusSuccess}})
+	}))
+	defer server.Close()
+
+	uri, _ := url.Parse(server.URL)
+	spdyExecutor, err := NewSPDYExecutor(&rest.Config{Host: uri.Host}, "POST", uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := &bytes.Buffer{}
+	if err := spdyExecutor.Stream(StreamOptions{Stdout: stdout}); err != nil {
+		t.Fatalf("expected a lost connection to look like a successful session without reconnections, got %v", err)
+	}
+
+	stdout.Reset()
+	lock.Lock()
+	connections = 0
+	lock.Unlock()
+	executor := NewReconnectingExecutor(spdyExecutor, ReconnectOptions{Backoff: fastReconnectBackoff})
+	if err := executor.Stream(StreamOptions{Stdout: stdout}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stdout.String() != "out1out2" {
+		t.Errorf("expected the output of both sessions, got %q", stdout.String())
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if connections != 2 {
+		t.Errorf("expected 2 connections, got %d", connections)
+	}
+}
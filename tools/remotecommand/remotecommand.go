@@ -17,6 +17,8 @@ limitations under the License.
 package remotecommand
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -28,6 +30,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/remotecommand"
 	restclient "k8s.io/client-go/rest"
 	spdy "k8s.io/client-go/transport/spdy"
+	"k8s.io/client-go/util/exec"
 )
 
 // StreamOptions holds information pertaining to the current streaming session:
@@ -39,6 +42,12 @@ type StreamOptions struct {
 	Stderr            io.Writer
 	Tty               bool
 	TerminalSizeQueue TerminalSizeQueue
+	// Control optionally closes the streams of the session individually.
+	Control *StreamControl
+
+	// reportLostConnection makes the version 4 of the protocol return
+	// errConnectionLost when the connection ends without a status.
+	reportLostConnection bool
 }
 
 // Executor is an interface for transporting shell-style streams.
@@ -50,6 +59,30 @@ type Executor interface {
 	Stream(options StreamOptions) error
 }
 
+// ContextExecutor is implemented by the executors whose sessions can be
+// ended by a context, like the ones returned by NewSPDYExecutor.
+type ContextExecutor interface {
+	Executor
+	// StreamWithContext is the same as Stream, except that the session is
+	// closed, and the context error returned, once ctx is done.
+	StreamWithContext(ctx context.Context, options StreamOptions) error
+}
+
+// ExitCode returns the exit code of the remote command of a session which
+// returned err, and whether the command terminated. It is 0 if err is nil.
+// Only the latest version of the protocol returns the exit codes of
+// commands which failed, as exec.ExitError.
+func ExitCode(err error) (int, bool) {
+	if err == nil {
+		return 0, true
+	}
+	var exitErr exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.Exited() {
+		return exitErr.ExitStatus(), true
+	}
+	return 0, false
+}
+
 type streamCreator interface {
 	CreateStream(headers http.Header) (httpstream.Stream, error)
 }
@@ -68,6 +101,8 @@ type streamExecutor struct {
 	protocols []string
 }
 
+var _ ContextExecutor = &streamExecutor{}
+
 // NewSPDYExecutor connects to the provided server and upgrades the connection to
 // multiplexed bidirectional streams.
 func NewSPDYExecutor(config *restclient.Config, method string, url *url.URL) (Executor, error) {
@@ -106,7 +141,13 @@ func NewSPDYExecutorForProtocols(transport http.RoundTripper, upgrader spdy.Upgr
 // Stream opens a protocol streamer to the server and streams until a client closes
 // the connection or the server disconnects.
 func (e *streamExecutor) Stream(options StreamOptions) error {
-	req, err := http.NewRequest(e.method, e.url.String(), nil)
+	return e.StreamWithContext(context.Background(), options)
+}
+
+// StreamWithContext opens a protocol streamer to the server and streams until
+// a client closes the connection, the server disconnects or ctx is done.
+func (e *streamExecutor) StreamWithContext(ctx context.Context, options StreamOptions) error {
+	req, err := http.NewRequestWithContext(ctx, e.method, e.url.String(), nil)
 	if err != nil {
 		return fmt.Errorf("error creating request: %v", err)
 	}
@@ -138,5 +179,24 @@ func (e *streamExecutor) Stream(options StreamOptions) error {
 		streamer = newStreamProtocolV1(options)
 	}
 
-	return streamer.stream(conn)
+	panicChan := make(chan interface{}, 1)
+	errorChan := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				panicChan <- p
+			}
+		}()
+		errorChan <- streamer.stream(conn)
+	}()
+
+	select {
+	case p := <-panicChan:
+		panic(p)
+	case err := <-errorChan:
+		return err
+	case <-ctx.Done():
+		// closing the connection ends the streams
+		return ctx.Err()
+	}
 }
@@ -17,8 +17,10 @@ limitations under the License.
 package remotecommand
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	v1 "k8s.io/api/core/v1"
@@ -29,6 +31,7 @@ import (
 	remotecommandconsts "k8s.io/apimachinery/pkg/util/remotecommand"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/exec"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -264,3 +267,116 @@ func v4WriteStatusFunc(stream io.Writer) func(status *apierrors.StatusError) err
 		return err
 	}
 }
+
+func TestSPDYExecutorStreamWithContextCancel(t *testing.T) {
+	stdin, stdinWriter := io.Pipe()
+	defer stdinWriter.Close()
+	options := StreamOptions{Stdin: stdin}
+	server := newTestHTTPServer(func(in io.Reader, out, err io.WriteCloser, tty bool, resize <-chan TerminalSize) error {
+		// the client never closes its input, so this returns once the
+		// connection is closed
+		_, copyErr := io.Copy(ioutil.Discard, in)
+		return copyErr
+	}, &options)
+	defer server.Close()
+
+	uri, _ := url.Parse(server.URL)
+	exec, err := NewSPDYExecutor(&rest.Config{Host: uri.Host}, "POST", uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		result <- exec.(ContextExecutor).StreamWithContext(ctx, options)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-result:
+		if err != context.Canceled {
+			t.Errorf("expected %v, got %v", context.Canceled, err)
+		}
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("the stream did not end once its context was canceled")
+	}
+}
+
+// writerFunc is a writer calling a function with each write.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+func TestStreamControlCloseStdin(t *testing.T) {
+	stdin, stdinWriter := io.Pipe()
+	defer stdinWriter.Close()
+	control := NewStreamControl()
+	var closeErr error
+	options := StreamOptions{
+		Stdin: stdin,
+		// close the input of the command once it asks for it to be closed
+		Stdout: writerFunc(func(p []byte) (int, error) {
+			closeErr = control.CloseStdin()
+			return len(p), nil
+		}),
+		Control: control,
+	}
+	server := newTestHTTPServer(func(in io.Reader, out, err io.WriteCloser, tty bool, resize <-chan TerminalSize) error {
+		if _, err := out.Write([]byte("close")); err != nil {
+			return err
+		}
+		_, copyErr := io.Copy(ioutil.Discard, in)
+		return copyErr
+	}, &options)
+	defer server.Close()
+
+	if err := attach2Server(server.URL, options); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if closeErr != nil {
+		t.Errorf("unexpected error closing stdin: %v", closeErr)
+	}
+}
+
+func TestStreamControlClosedBeforehand(t *testing.T) {
+	stdin, stdinWriter := io.Pipe()
+	defer stdinWriter.Close()
+	control := NewStreamControl()
+	if err := control.CloseStdin(); err != nil {
+		t.Fatal(err)
+	}
+	options := StreamOptions{Stdin: stdin, Control: control}
+	server := newTestHTTPServer(func(in io.Reader, out, err io.WriteCloser, tty bool, resize <-chan TerminalSize) error {
+		_, copyErr := io.Copy(ioutil.Discard, in)
+		return copyErr
+	}, &options)
+	defer server.Close()
+
+	if err := attach2Server(server.URL, options); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	exitErr := exec.CodeExitError{Err: errors.New("command terminated with exit code 3"), Code: 3}
+	tests := []struct {
+		name       string
+		err        error
+		expectCode int
+		expectOK   bool
+	}{
+		{name: "success", expectCode: 0, expectOK: true},
+		{name: "exit error", err: exitErr, expectCode: 3, expectOK: true},
+		{name: "wrapped exit error", err: fmt.Errorf("streaming: %w", exitErr), expectCode: 3, expectOK: true},
+		{name: "other error", err: errors.New("connection refused"), expectCode: 0, expectOK: false},
+	}
+	for _, test := range tests {
+		code, ok := ExitCode(test.err)
+		if code != test.expectCode || ok != test.expectOK {
+			t.Errorf("%s: expected (%d, %t), got (%d, %t)", test.name, test.expectCode, test.expectOK, code, ok)
+		}
+	}
+}
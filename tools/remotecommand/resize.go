@@ -16,6 +16,8 @@ limitations under the License.
 
 package remotecommand
 
+import "sync"
+
 // TerminalSize and TerminalSizeQueue was a part of k8s.io/kubernetes/pkg/util/term
 // and were moved in order to decouple client from other term dependencies
 
@@ -31,3 +33,60 @@ type TerminalSizeQueue interface {
 	// monitoring has been stopped.
 	Next() *TerminalSize
 }
+
+// ResizeQueue is a TerminalSizeQueue fed programmatically with Resize. Only
+// the latest size not yet returned by Next is kept.
+type ResizeQueue struct {
+	lock  sync.Mutex
+	sizes chan TerminalSize
+	done  chan struct{}
+	once  sync.Once
+}
+
+var _ TerminalSizeQueue = &ResizeQueue{}
+
+// NewResizeQueue returns an empty ResizeQueue.
+func NewResizeQueue() *ResizeQueue {
+	return &ResizeQueue{
+		sizes: make(chan TerminalSize, 1),
+		done:  make(chan struct{}),
+	}
+}
+
+// Resize queues size, replacing the size queued before if Next did not
+// return it yet. It does nothing once the queue is closed.
+func (q *ResizeQueue) Resize(size TerminalSize) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	select {
+	case <-q.done:
+		return
+	default:
+	}
+	select {
+	case <-q.sizes:
+	default:
+	}
+	q.sizes <- size
+}
+
+// Close stops the queue: Next returns nil from then on.
+func (q *ResizeQueue) Close() {
+	q.once.Do(func() { close(q.done) })
+}
+
+// Next waits for a size to be queued and returns it, or returns nil once the
+// queue is closed.
+func (q *ResizeQueue) Next() *TerminalSize {
+	select {
+	case <-q.done:
+		return nil
+	default:
+	}
+	select {
+	case size := <-q.sizes:
+		return &size
+	case <-q.done:
+		return nil
+	}
+}
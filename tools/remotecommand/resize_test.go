@@ -0,0 +1,67 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remotecommand
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestResizeQueue(t *testing.T) {
+	queue := NewResizeQueue()
+
+	// only the latest size is kept
+	queue.Resize(TerminalSize{Width: 80, Height: 24})
+	queue.Resize(TerminalSize{Width: 120, Height: 40})
+	if size := queue.Next(); size == nil || *size != (TerminalSize{Width: 120, Height: 40}) {
+		t.Fatalf("expected the latest size, got %v", size)
+	}
+
+	sizes := make(chan *TerminalSize)
+	go func() {
+		sizes <- queue.Next()
+	}()
+	queue.Resize(TerminalSize{Width: 100, Height: 30})
+	select {
+	case size := <-sizes:
+		if size == nil || *size != (TerminalSize{Width: 100, Height: 30}) {
+			t.Fatalf("expected the queued size, got %v", size)
+		}
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("Next did not return the queued size")
+	}
+
+	go func() {
+		sizes <- queue.Next()
+	}()
+	queue.Close()
+	select {
+	case size := <-sizes:
+		if size != nil {
+			t.Fatalf("expected nil once closed, got %v", size)
+		}
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("Next did not return once the queue was closed")
+	}
+
+	queue.Resize(TerminalSize{Width: 80, Height: 24})
+	if size := queue.Next(); size != nil {
+		t.Errorf("expected nil once closed, got %v", size)
+	}
+}
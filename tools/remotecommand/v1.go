@@ -107,6 +107,12 @@ func (p *streamProtocolV1) stream(conn streamCreator) error {
 		defer p.remoteStderr.Reset()
 	}
 
+	if p.Control != nil {
+		if err := p.Control.setStreams(p.remoteStdin, p.remoteStdout, p.remoteStderr); err != nil {
+			return err
+		}
+	}
+
 	// now that all the streams have been created, proceed with reading & copying
 
 	// always read from errorStream
@@ -24,6 +24,7 @@ import (
 	"sync"
 
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
 	"k8s.io/apimachinery/pkg/util/runtime"
 )
 
@@ -50,6 +51,7 @@ func newStreamProtocolV2(options StreamOptions) streamProtocolHandler {
 
 func (p *streamProtocolV2) createStreams(conn streamCreator) error {
 	var err error
+	var stdin, stdout, stderr httpstream.Stream
 	headers := http.Header{}
 
 	// set up error stream
@@ -62,28 +64,35 @@ func (p *streamProtocolV2) createStreams(conn streamCreator) error {
 	// set up stdin stream
 	if p.Stdin != nil {
 		headers.Set(v1.StreamType, v1.StreamTypeStdin)
-		p.remoteStdin, err = conn.CreateStream(headers)
+		stdin, err = conn.CreateStream(headers)
 		if err != nil {
 			return err
 		}
+		p.remoteStdin = stdin
 	}
 
 	// set up stdout stream
 	if p.Stdout != nil {
 		headers.Set(v1.StreamType, v1.StreamTypeStdout)
-		p.remoteStdout, err = conn.CreateStream(headers)
+		stdout, err = conn.CreateStream(headers)
 		if err != nil {
 			return err
 		}
+		p.remoteStdout = stdout
 	}
 
 	// set up stderr stream
 	if p.Stderr != nil && !p.Tty {
 		headers.Set(v1.StreamType, v1.StreamTypeStderr)
-		p.remoteStderr, err = conn.CreateStream(headers)
+		stderr, err = conn.CreateStream(headers)
 		if err != nil {
 			return err
 		}
+		p.remoteStderr = stderr
+	}
+
+	if p.Control != nil {
+		return p.Control.setStreams(stdin, stdout, stderr)
 	}
 	return nil
 }
@@ -101,7 +110,8 @@ func (p *streamProtocolV2) copyStdin() {
 			// the executed command will remain running.
 			defer once.Do(func() { p.remoteStdin.Close() })
 
-			if _, err := io.Copy(p.remoteStdin, readerWrapper{p.Stdin}); err != nil {
+			// writing fails once the StreamControl closed remoteStdin
+			if _, err := io.Copy(p.remoteStdin, readerWrapper{p.Stdin}); err != nil && (p.Control == nil || !p.Control.stdinIsClosed()) {
 				runtime.HandleError(err)
 			}
 		}()
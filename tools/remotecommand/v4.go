@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"strconv"
 	"sync"
 
@@ -58,7 +59,8 @@ func (p *streamProtocolV4) stream(conn streamCreator) error {
 
 	// now that all the streams have been created, proceed with reading & copying
 
-	errorChan := watchErrorStream(p.errorStream, &errorDecoderV4{})
+	errorStream := &countingReader{Reader: p.errorStream}
+	errorChan := watchErrorStream(errorStream, &errorDecoderV4{})
 
 	p.handleResizes()
 
@@ -72,7 +74,25 @@ func (p *streamProtocolV4) stream(conn streamCreator) error {
 	wg.Wait()
 
 	// waits for errorStream to finish reading with an error or nil
-	return <-errorChan
+	err := <-errorChan
+	if err == nil && errorStream.n == 0 && p.reportLostConnection {
+		// the server always sends a status in this version, even on
+		// success, so the connection was lost
+		return errConnectionLost
+	}
+	return err
+}
+
+// countingReader counts the bytes read from Reader.
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
 }
 
 // errorDecoderV4 interprets the json-marshaled metav1.Status on the error channel
@@ -0,0 +1,208 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package shutdown sequences the graceful shutdown of controllers: informers
+// are stopped first so that no new work is queued, then the work queues are
+// drained while their workers finish the work already queued, and only then
+// is the shutdown reported complete.
+//
+//	s := shutdown.NewSequence()
+//	s.StartInformers(factory)
+//	s.AddQueues(queue)
+//	s.RunWorkers(workers, c.runWorker)
+//	<-ctx.Done()
+//	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+//	defer cancel()
+//	if err := s.Shutdown(ctx); err != nil { ... }
+package shutdown // import "k8s.io/client-go/tools/shutdown"
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+)
+
+// Starter starts informers until stopCh is closed, like the shared informer
+// factories.
+type Starter interface {
+	Start(stopCh <-chan struct{})
+}
+
+// Runner runs until stopCh is closed, like informers.
+type Runner interface {
+	Run(stopCh <-chan struct{})
+}
+
+// Sequence ties informers, work queues and their workers together and shuts
+// them down in order. It is safe for concurrent use.
+type Sequence struct {
+	informersStopCh chan struct{}
+	workersStopCh   chan struct{}
+	done            chan struct{}
+
+	informers sync.WaitGroup
+	workers   sync.WaitGroup
+
+	lock   sync.Mutex
+	queues []workqueue.Interface
+
+	once sync.Once
+	err  error
+}
+
+// NewSequence returns a Sequence.
+func NewSequence() *Sequence {
+	return &Sequence{
+		informersStopCh: make(chan struct{}),
+		workersStopCh:   make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+}
+
+// StopCh returns a channel closed when the informers are stopped, for
+// informers started otherwise than by StartInformers and RunInformers.
+func (s *Sequence) StopCh() <-chan struct{} {
+	return s.informersStopCh
+}
+
+// StartInformers starts the informers of factories until the shutdown. The
+// factories do not report when their informers have stopped, so Shutdown
+// does not wait for them; use RunInformers for that.
+func (s *Sequence) StartInformers(factories ...Starter) {
+	for _, factory := range factories {
+		factory.Start(s.informersStopCh)
+	}
+}
+
+// RunInformers runs informers until the shutdown, which waits for them to
+// return before draining the queues.
+func (s *Sequence) RunInformers(informers ...Runner) {
+	for _, informer := range informers {
+		informer := informer
+		s.informers.Add(1)
+		go func() {
+			defer s.informers.Done()
+			informer.Run(s.informersStopCh)
+		}()
+	}
+}
+
+// AddQueues adds queues to drain on shutdown.
+func (s *Sequence) AddQueues(queues ...workqueue.Interface) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.queues = append(s.queues, queues...)
+}
+
+// RunWorkers runs n workers, restarting each a second after it returns, like
+// wait.Until, until its queue is drained. Workers should return once the Get
+// of their queue reports a shutdown. Unlike with wait.Until, every worker runs
+// at least once, so that items queued before the shutdown are processed even
+// if the workers started late.
+func (s *Sequence) RunWorkers(n int, worker func()) {
+	for i := 0; i < n; i++ {
+		s.workers.Add(1)
+		go func() {
+			defer s.workers.Done()
+			for {
+				worker()
+				restart := time.NewTimer(time.Second)
+				select {
+				case <-s.workersStopCh:
+					restart.Stop()
+					return
+				case <-restart.C:
+				}
+			}
+		}()
+	}
+}
+
+// Shutdown stops the informers and waits for the ones run by RunInformers to
+// return, then drains the queues and waits for the workers to return, and
+// finally closes Done. If ctx is done first, the queues are shut down without
+// draining, Done is closed and the error of ctx is returned; workers still
+// processing items are left running. Later calls return the result of the
+// first one.
+func (s *Sequence) Shutdown(ctx context.Context) error {
+	s.once.Do(func() {
+		defer close(s.done)
+		s.err = s.shutdown(ctx)
+	})
+	return s.err
+}
+
+func (s *Sequence) shutdown(ctx context.Context) error {
+	close(s.informersStopCh)
+	if err := waitFor(ctx, s.informers.Wait); err != nil {
+		return fmt.Errorf("timed out waiting for informers to stop: %w", err)
+	}
+
+	s.lock.Lock()
+	queues := append([]workqueue.Interface{}, s.queues...)
+	s.lock.Unlock()
+	// ShutDownWithDrain only waits for the items being processed. The items
+	// still queued are left to the workers, which keep getting them until the
+	// queue is empty and run at least once even if started after this.
+	err := waitFor(ctx, func() {
+		var drained sync.WaitGroup
+		for _, queue := range queues {
+			queue := queue
+			drained.Add(1)
+			go func() {
+				defer drained.Done()
+				queue.ShutDownWithDrain()
+			}()
+		}
+		drained.Wait()
+	})
+	close(s.workersStopCh)
+	if err != nil {
+		for _, queue := range queues {
+			queue.ShutDown()
+		}
+		return fmt.Errorf("timed out draining %d queues: %w", len(queues), err)
+	}
+
+	if err := waitFor(ctx, s.workers.Wait); err != nil {
+		return fmt.Errorf("timed out waiting for workers to return: %w", err)
+	}
+	return nil
+}
+
+// Done returns a channel closed once Shutdown has completed.
+func (s *Sequence) Done() <-chan struct{} {
+	return s.done
+}
+
+// waitFor runs wait and returns nil once it returns, or the error of ctx if
+// ctx is done first.
+func waitFor(ctx context.Context, wait func()) error {
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		wait()
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// fakeInformer records whether the queue was shutting down when it stopped.
+type fakeInformer struct {
+	queue           workqueue.Interface
+	queueShutDown   bool
+	stopped, called chan struct{}
+}
+
+func (i *fakeInformer) Run(stopCh <-chan struct{}) {
+	<-stopCh
+	i.queueShutDown = i.queue.ShuttingDown()
+	close(i.stopped)
+}
+
+func (i *fakeInformer) Start(stopCh <-chan struct{}) {
+	close(i.called)
+}
+
+func TestShutdownOrder(t *testing.T) {
+	queue := workqueue.New()
+	informer := &fakeInformer{queue: queue, stopped: make(chan struct{}), called: make(chan struct{})}
+
+	s := NewSequence()
+	s.StartInformers(informer)
+	s.RunInformers(informer)
+	s.AddQueues(queue)
+
+	var lock sync.Mutex
+	processed := 0
+	s.RunWorkers(2, func() {
+		for {
+			item, shutdown := queue.Get()
+			if shutdown {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+			lock.Lock()
+			processed++
+			lock.Unlock()
+			queue.Done(item)
+		}
+	})
+	for i := 0; i < 10; i++ {
+		queue.Add(i)
+	}
+
+	select {
+	case <-informer.called:
+	default:
+		t.Errorf("expected the factory to be started")
+	}
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-s.Done():
+	default:
+		t.Errorf("expected Done to be closed")
+	}
+	if informer.queueShutDown {
+		t.Errorf("expected the informer to stop before the queue was shut down")
+	}
+	if processed != 10 {
+		t.Errorf("expected the 10 queued items to be processed, got %d", processed)
+	}
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Errorf("expected later calls to succeed, got %v", err)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	queue := workqueue.New()
+	s := NewSequence()
+	s.AddQueues(queue)
+
+	block := make(chan struct{})
+	defer close(block)
+	s.RunWorkers(1, func() {
+		item, shutdown := queue.Get()
+		if shutdown {
+			return
+		}
+		<-block
+		queue.Done(item)
+	})
+	queue.Add("stuck")
+
+	// Let the worker take the item.
+	for queue.Len() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the drain to time out, got %v", err)
+	}
+	select {
+	case <-s.Done():
+	default:
+		t.Errorf("expected Done to be closed")
+	}
+	if !queue.ShuttingDown() {
+		t.Errorf("expected the queue to be shut down")
+	}
+}
+
+func TestShutdownLateWorkers(t *testing.T) {
+	queue := workqueue.New()
+	s := NewSequence()
+	s.AddQueues(queue)
+	for i := 0; i < 3; i++ {
+		queue.Add(i)
+	}
+
+	// Nothing is processing, so the queue is shut down right away with its
+	// items still queued, and the workers start once it is.
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !queue.ShuttingDown() {
+		t.Fatalf("expected the queue to be shut down")
+	}
+	processed := make(chan interface{}, 3)
+	returned := make(chan struct{})
+	s.RunWorkers(1, func() {
+		defer close(returned)
+		for {
+			item, shutdown := queue.Get()
+			if shutdown {
+				return
+			}
+			processed <- item
+			queue.Done(item)
+		}
+	})
+
+	select {
+	case <-returned:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatalf("expected the worker to run and return")
+	}
+	if len(processed) != 3 {
+		t.Errorf("expected the 3 queued items to be drained, got %d", len(processed))
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package token requests bound service account tokens with the TokenRequest
+// API. Request mints a token on every call; a Manager caches the tokens it
+// mints and renews them ahead of their expiry, for controllers calling
+// webhooks or other services as service accounts.
+package token // import "k8s.io/client-go/tools/token"
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/util/tokencache"
+)
+
+// Request requests a token of the service account sa for audiences, expiring
+// after ttl or the default lifetime of the server if ttl is 0. The server may
+// issue a token expiring sooner or later than requested; the returned status
+// carries its actual expiry.
+func Request(ctx context.Context, client corev1client.ServiceAccountsGetter, sa types.NamespacedName, audiences []string, ttl time.Duration) (*authenticationv1.TokenRequestStatus, error) {
+	tokenRequest := &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{Audiences: audiences},
+	}
+	if ttl > 0 {
+		expirationSeconds := int64(ttl / time.Second)
+		tokenRequest.Spec.ExpirationSeconds = &expirationSeconds
+	}
+	tokenRequest, err := client.ServiceAccounts(sa.Namespace).CreateToken(ctx, sa.Name, tokenRequest, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if len(tokenRequest.Status.Token) == 0 {
+		return nil, fmt.Errorf("the server returned no token for service account %s", sa)
+	}
+	return &tokenRequest.Status, nil
+}
+
+// Manager requests tokens of service accounts and caches them until they are
+// due for renewal, by default after 80% of their lifetime like the kubelet
+// does for projected tokens. It is safe for concurrent use.
+type Manager struct {
+	client corev1client.ServiceAccountsGetter
+	cache  *tokencache.Cache
+}
+
+// tokenKey identifies the tokens cached by a Manager.
+type tokenKey struct {
+	Namespace string        `json:"namespace"`
+	Name      string        `json:"name"`
+	Audiences []string      `json:"audiences"`
+	TTL       time.Duration `json:"ttl"`
+}
+
+// NewManager returns a Manager requesting tokens with client and caching them
+// as configured by options.
+func NewManager(client corev1client.ServiceAccountsGetter, options tokencache.Options) *Manager {
+	m := &Manager{client: client}
+	m.cache = tokencache.New(m.fetch, options)
+	return m
+}
+
+// Token returns a token of the service account sa for audiences, requested
+// with Request or cached.
+func (m *Manager) Token(ctx context.Context, sa types.NamespacedName, audiences []string, ttl time.Duration) (string, error) {
+	key, err := cacheKey(sa, audiences, ttl)
+	if err != nil {
+		return "", err
+	}
+	token, err := m.cache.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	return token.(string), nil
+}
+
+// Invalidate drops the cached token of the service account sa for audiences,
+// like one rejected by its audience, so that the next call to Token requests
+// a new one.
+func (m *Manager) Invalidate(sa types.NamespacedName, audiences []string, ttl time.Duration) {
+	if key, err := cacheKey(sa, audiences, ttl); err == nil {
+		m.cache.Invalidate(key)
+	}
+}
+
+// Run removes expired tokens periodically until stopCh is closed.
+func (m *Manager) Run(stopCh <-chan struct{}) {
+	m.cache.Run(stopCh)
+}
+
+func (m *Manager) fetch(ctx context.Context, key string) (interface{}, time.Time, error) {
+	var k tokenKey
+	if err := json.Unmarshal([]byte(key), &k); err != nil {
+		return nil, time.Time{}, err
+	}
+	status, err := Request(ctx, m.client, types.NamespacedName{Namespace: k.Namespace, Name: k.Name}, k.Audiences, k.TTL)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return status.Token, status.ExpirationTimestamp.Time, nil
+}
+
+func cacheKey(sa types.NamespacedName, audiences []string, ttl time.Duration) (string, error) {
+	key, err := json.Marshal(tokenKey{Namespace: sa.Namespace, Name: sa.Name, Audiences: audiences, TTL: ttl})
+	if err != nil {
+		return "", err
+	}
+	return string(key), nil
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package token
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/util/tokencache"
+)
+
+var builder = types.NamespacedName{Namespace: "default", Name: "builder"}
+
+// newTokenClient returns a client issuing numbered tokens and recording the
+// token requests.
+func newTokenClient(requests *[]*authenticationv1.TokenRequest) *fake.Clientset {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "serviceaccounts", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "token" {
+			return false, nil, nil
+		}
+		tokenRequest := action.(clienttesting.CreateAction).GetObject().(*authenticationv1.TokenRequest).DeepCopy()
+		*requests = append(*requests, tokenRequest)
+		tokenRequest.Status = authenticationv1.TokenRequestStatus{
+			Token:               fmt.Sprintf("token-%d", len(*requests)),
+			ExpirationTimestamp: metav1.NewTime(time.Now().Add(time.Hour)),
+		}
+		return true, tokenRequest, nil
+	})
+	return client
+}
+
+func TestRequest(t *testing.T) {
+	var requests []*authenticationv1.TokenRequest
+	client := newTokenClient(&requests)
+
+	status, err := Request(context.Background(), client.CoreV1(), builder, []string{"webhook"}, 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Token != "token-1" {
+		t.Errorf("expected token-1, got %q", status.Token)
+	}
+	expirationSeconds := int64(600)
+	expected := authenticationv1.TokenRequestSpec{Audiences: []string{"webhook"}, ExpirationSeconds: &expirationSeconds}
+	if !reflect.DeepEqual(requests[0].Spec, expected) {
+		t.Errorf("expected spec %#v, got %#v", expected, requests[0].Spec)
+	}
+}
+
+func TestManagerCachesTokens(t *testing.T) {
+	var requests []*authenticationv1.TokenRequest
+	client := newTokenClient(&requests)
+	manager := NewManager(client.CoreV1(), tokencache.Options{})
+	ctx := context.Background()
+
+	token := func(audiences ...string) string {
+		t.Helper()
+		token, err := manager.Token(ctx, builder, audiences, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	if first, second := token("webhook"), token("webhook"); first != "token-1" || second != "token-1" {
+		t.Errorf("expected the token to be cached, got %q and %q", first, second)
+	}
+	if other := token("registry"); other != "token-2" {
+		t.Errorf("expected a token per audience, got %q", other)
+	}
+	manager.Invalidate(builder, []string{"webhook"}, 0)
+	if renewed := token("webhook"); renewed != "token-3" {
+		t.Errorf("expected a new token once invalidated, got %q", renewed)
+	}
+	if len(requests) != 3 {
+		t.Errorf("expected 3 token requests, got %d", len(requests))
+	}
+	if requests[0].Spec.ExpirationSeconds != nil {
+		t.Errorf("expected the default lifetime to be requested, got %d seconds", *requests[0].Spec.ExpirationSeconds)
+	}
+}
@@ -0,0 +1,281 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// ObjectConditionFunc returns true if the condition has been reached for obj, false if it has not been
+// reached yet, or an error if the wait should terminate. exists is false and obj is nil if the object
+// is not present in the informer cache.
+type ObjectConditionFunc func(obj interface{}, exists bool) (bool, error)
+
+// ObjectWaiter waits for conditions on the objects in the cache of an
+// existing, running informer, instead of starting a new watch per wait.
+// Informers cannot remove event handlers, so every ObjectWaiter adds one to
+// its informer for the lifetime of the informer: create one per informer and
+// share it between any number of waits. It is safe for concurrent use.
+type ObjectWaiter struct {
+	informer cache.SharedInformer
+	notifier *objectNotifier
+}
+
+// NewObjectWaiter returns an ObjectWaiter for the objects of informer.
+func NewObjectWaiter(informer cache.SharedInformer) *ObjectWaiter {
+	n := &objectNotifier{waiters: map[string]map[chan struct{}]struct{}{}}
+	informer.AddEventHandler(n)
+	return &ObjectWaiter{informer: informer, notifier: n}
+}
+
+// WaitForObjectCondition waits until the object identified by key satisfies condition, using the informer
+// cache of waiter. key has the namespace/name format produced by cache.MetaNamespaceKeyFunc. It returns the
+// object that satisfied the condition, or nil if the condition was satisfied by the object not existing. If
+// ctx is done before the condition is reached, wait.ErrWaitTimeout is returned.
+func WaitForObjectCondition(ctx context.Context, waiter *ObjectWaiter, key string, condition ObjectConditionFunc) (interface{}, error) {
+	informer := waiter.informer
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		return nil, wait.ErrWaitTimeout
+	}
+
+	notify := make(chan struct{}, 1)
+	n := waiter.notifier
+	n.add(key, notify)
+	defer n.remove(key, notify)
+
+	for {
+		obj, exists, err := informer.GetStore().GetByKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %q from the informer cache: %v", key, err)
+		}
+		done, err := condition(obj, exists)
+		if err != nil {
+			return obj, err
+		}
+		if done {
+			return obj, nil
+		}
+
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			return obj, wait.ErrWaitTimeout
+		}
+	}
+}
+
+// GetObjectFunc gets the current state of an object. It returns a NotFound
+// error if the object does not exist.
+type GetObjectFunc func(ctx context.Context) (runtime.Object, error)
+
+// ObjectWaitOptions configures WaitForCondition.
+type ObjectWaitOptions struct {
+	// Waiter, if set, waits using a running informer whose cache holds the
+	// object. The condition is then evaluated against the cache, see
+	// WaitForObjectCondition, and Get and Watcher are not used.
+	Waiter *ObjectWaiter
+	// Key is the key of the object in the informer cache, in the format
+	// produced by cache.MetaNamespaceKeyFunc. It is required with Waiter.
+	Key string
+	// Watcher, if set, watches the object, for example a cache.ListWatch
+	// restricted to it with a metadata.name field selector. The condition is
+	// then re-evaluated on every event instead of every PollInterval.
+	Watcher cache.Watcher
+	// PollInterval is the interval between gets while no watch is
+	// established. Defaults to one second.
+	PollInterval time.Duration
+}
+
+// WaitForCondition waits until the object returned by get satisfies
+// condition. It gets the object, then watches it if opts.Watcher is set, or
+// polls it every opts.PollInterval otherwise. When the watch ends or cannot be
+// started, it falls back to polling until a new watch is established. Errors
+// returned by get other than NotFound are retried. If opts.Waiter is set,
+// the wait is delegated to WaitForObjectCondition.
+//
+// It returns the object that satisfied the condition, or nil if the condition
+// was satisfied by the object not existing. If ctx is done before the
+// condition is reached, wait.ErrWaitTimeout is returned.
+func WaitForCondition(ctx context.Context, get GetObjectFunc, opts ObjectWaitOptions, condition ObjectConditionFunc) (runtime.Object, error) {
+	if opts.Waiter != nil {
+		if opts.Key == "" {
+			return nil, fmt.Errorf("a key is required to wait using an informer")
+		}
+		obj, err := WaitForObjectCondition(ctx, opts.Waiter, opts.Key, condition)
+		if obj == nil {
+			return nil, err
+		}
+		runtimeObj, ok := obj.(runtime.Object)
+		if !ok {
+			return nil, fmt.Errorf("informer returned %T which is not a runtime.Object", obj)
+		}
+		return runtimeObj, err
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+
+	for {
+		obj, err := get(ctx)
+		exists := true
+		switch {
+		case apierrors.IsNotFound(err):
+			obj, exists = nil, false
+		case err != nil:
+			if ctx.Err() != nil {
+				return nil, wait.ErrWaitTimeout
+			}
+			klog.V(4).InfoS("Failed to get object, retrying", "err", err)
+		}
+
+		if err == nil || !exists {
+			done, err := evaluateCondition(condition, obj, exists)
+			if err != nil || done {
+				return obj, err
+			}
+			if opts.Watcher != nil {
+				obj, done, err := watchForCondition(ctx, opts.Watcher, obj, condition)
+				if err != nil || done {
+					return obj, err
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, wait.ErrWaitTimeout
+		case <-time.After(opts.PollInterval):
+		}
+	}
+}
+
+func evaluateCondition(condition ObjectConditionFunc, obj runtime.Object, exists bool) (bool, error) {
+	if !exists {
+		return condition(nil, false)
+	}
+	return condition(obj, true)
+}
+
+// watchForCondition watches the object starting after the state of obj and
+// evaluates condition on every event. It returns done false when the watch
+// ended or could not be started, so the caller falls back to polling.
+func watchForCondition(ctx context.Context, watcher cache.Watcher, obj runtime.Object, condition ObjectConditionFunc) (runtime.Object, bool, error) {
+	// Without a resourceVersion the watch starts with a synthetic event for
+	// the current state of the object, if it exists.
+	resourceVersion := ""
+	if obj != nil {
+		if accessor, err := meta.Accessor(obj); err == nil {
+			resourceVersion = accessor.GetResourceVersion()
+		}
+	}
+	w, err := watcher.Watch(metav1.ListOptions{ResourceVersion: resourceVersion, AllowWatchBookmarks: true})
+	if err != nil {
+		klog.V(4).InfoS("Failed to watch object, polling", "err", err)
+		return nil, false, nil
+	}
+	defer w.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, false, wait.ErrWaitTimeout
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return nil, false, nil
+			}
+			var done bool
+			var err error
+			switch event.Type {
+			case watch.Added, watch.Modified:
+				obj = event.Object
+				done, err = condition(obj, true)
+			case watch.Deleted:
+				obj = nil
+				done, err = condition(nil, false)
+			case watch.Bookmark:
+				continue
+			default:
+				klog.V(4).InfoS("Watch of object failed, polling", "err", apierrors.FromObject(event.Object))
+				return nil, false, nil
+			}
+			if err != nil || done {
+				return obj, true, err
+			}
+		}
+	}
+}
+
+// objectNotifier wakes up the waiters of an object whenever the informer observes a change to it.
+type objectNotifier struct {
+	lock    sync.Mutex
+	waiters map[string]map[chan struct{}]struct{}
+}
+
+var _ cache.ResourceEventHandler = &objectNotifier{}
+
+func (n *objectNotifier) add(key string, ch chan struct{}) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.waiters[key] == nil {
+		n.waiters[key] = map[chan struct{}]struct{}{}
+	}
+	n.waiters[key][ch] = struct{}{}
+}
+
+func (n *objectNotifier) remove(key string, ch chan struct{}) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	delete(n.waiters[key], ch)
+	if len(n.waiters[key]) == 0 {
+		delete(n.waiters, key)
+	}
+}
+
+func (n *objectNotifier) notify(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		klog.V(4).InfoS("Unable to compute key of object", "err", err)
+		return
+	}
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	for ch := range n.waiters[key] {
+		// The channel has a buffer of one, so a pending notification already
+		// guarantees the waiter re-evaluates its condition.
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (n *objectNotifier) OnAdd(obj interface{})               { n.notify(obj) }
+func (n *objectNotifier) OnUpdate(oldObj, newObj interface{}) { n.notify(newObj) }
+func (n *objectNotifier) OnDelete(obj interface{})            { n.notify(obj) }
@@ -0,0 +1,194 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestWaitForObjectCondition(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "a"}}
+	client := fake.NewSimpleClientset(pod)
+	informer := cache.NewSharedInformer(&cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return client.CoreV1().Pods("default").List(context.TODO(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return client.CoreV1().Pods("default").Watch(context.TODO(), options)
+		},
+	}, &corev1.Pod{}, 0)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go informer.Run(stopCh)
+	waiter := NewObjectWaiter(informer)
+
+	ctx, cancel := context.WithTimeout(context.Background(), wait.ForeverTestTimeout)
+	defer cancel()
+
+	running := make(chan interface{}, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			obj, err := WaitForObjectCondition(ctx, waiter, "default/a", func(obj interface{}, exists bool) (bool, error) {
+				return exists && obj.(*corev1.Pod).Status.Phase == corev1.PodRunning, nil
+			})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			running <- obj
+		}()
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	updated := pod.DeepCopy()
+	updated.Status.Phase = corev1.PodRunning
+	if _, err := client.CoreV1().Pods("default").UpdateStatus(context.TODO(), updated, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if obj := <-running; obj == nil || obj.(*corev1.Pod).Status.Phase != corev1.PodRunning {
+			t.Errorf("expected running pod, got %#v", obj)
+		}
+	}
+
+	deleted := make(chan error, 1)
+	go func() {
+		_, err := WaitForObjectCondition(ctx, waiter, "default/a", func(obj interface{}, exists bool) (bool, error) {
+			return !exists, nil
+		})
+		deleted <- err
+	}()
+	if err := client.CoreV1().Pods("default").Delete(context.TODO(), "a", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-deleted; err != nil {
+		t.Errorf("unexpected error waiting for deletion: %v", err)
+	}
+
+	shortCtx, shortCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer shortCancel()
+	_, err := WaitForObjectCondition(shortCtx, waiter, "default/b", func(obj interface{}, exists bool) (bool, error) {
+		return exists, nil
+	})
+	if err != wait.ErrWaitTimeout {
+		t.Errorf("expected wait.ErrWaitTimeout, got %v", err)
+	}
+	if n := waiter.notifier; len(n.waiters) != 0 {
+		t.Errorf("expected all waiters to be removed, got %v", n.waiters)
+	}
+}
+
+func TestWaitForCondition(t *testing.T) {
+	podRunning := func(obj interface{}, exists bool) (bool, error) {
+		return exists && obj.(*corev1.Pod).Status.Phase == corev1.PodRunning, nil
+	}
+
+	t.Run("poll", func(t *testing.T) {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "a"}}
+		client := fake.NewSimpleClientset(pod)
+		gets := 0
+		get := func(ctx context.Context) (runtime.Object, error) {
+			gets++
+			if gets == 3 {
+				updated := pod.DeepCopy()
+				updated.Status.Phase = corev1.PodRunning
+				if _, err := client.CoreV1().Pods("default").UpdateStatus(ctx, updated, metav1.UpdateOptions{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			return client.CoreV1().Pods("default").Get(ctx, "a", metav1.GetOptions{})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), wait.ForeverTestTimeout)
+		defer cancel()
+		obj, err := WaitForCondition(ctx, get, ObjectWaitOptions{PollInterval: time.Millisecond}, podRunning)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if obj.(*corev1.Pod).Status.Phase != corev1.PodRunning || gets != 3 {
+			t.Errorf("expected running pod after 3 gets, got %#v after %d gets", obj, gets)
+		}
+	})
+
+	t.Run("watch", func(t *testing.T) {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "a"}}
+		client := fake.NewSimpleClientset(pod)
+		watching := make(chan struct{})
+		lw := &cache.ListWatch{
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				defer close(watching)
+				return client.CoreV1().Pods("default").Watch(context.TODO(), options)
+			},
+		}
+		get := func(ctx context.Context) (runtime.Object, error) {
+			return client.CoreV1().Pods("default").Get(ctx, "a", metav1.GetOptions{})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), wait.ForeverTestTimeout)
+		defer cancel()
+		result := make(chan error, 1)
+		go func() {
+			// A poll interval longer than the test ensures the condition is observed through the watch.
+			_, err := WaitForCondition(ctx, get, ObjectWaitOptions{Watcher: lw, PollInterval: time.Hour}, podRunning)
+			result <- err
+		}()
+
+		<-watching
+		updated := pod.DeepCopy()
+		updated.Status.Phase = corev1.PodRunning
+		if _, err := client.CoreV1().Pods("default").UpdateStatus(context.TODO(), updated, metav1.UpdateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := <-result; err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		client := fake.NewSimpleClientset()
+		get := func(ctx context.Context) (runtime.Object, error) {
+			return client.CoreV1().Pods("default").Get(ctx, "a", metav1.GetOptions{})
+		}
+		obj, err := WaitForCondition(context.Background(), get, ObjectWaitOptions{}, func(obj interface{}, exists bool) (bool, error) {
+			return !exists, nil
+		})
+		if err != nil || obj != nil {
+			t.Errorf("expected no object and no error, got %#v, %v", obj, err)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		client := fake.NewSimpleClientset(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "a"}})
+		get := func(ctx context.Context) (runtime.Object, error) {
+			return client.CoreV1().Pods("default").Get(ctx, "a", metav1.GetOptions{})
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if _, err := WaitForCondition(ctx, get, ObjectWaitOptions{PollInterval: time.Millisecond}, podRunning); err != wait.ErrWaitTimeout {
+			t.Errorf("expected wait.ErrWaitTimeout, got %v", err)
+		}
+	})
+}
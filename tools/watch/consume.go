@@ -0,0 +1,123 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// ErrResultChanClosed is returned by ConsumeUntil when the result channel of
+// the watch is closed before the handler is done, for example because the
+// server ended the watch.
+var ErrResultChanClosed = errors.New("watch result channel closed")
+
+// consumeDrainTimeout bounds how long ConsumeUntil waits for the result
+// channel of a stopped watch to be closed before draining it in the
+// background.
+const consumeDrainTimeout = 5 * time.Second
+
+// ConsumeResult reports the events consumed by ConsumeUntil.
+type ConsumeResult struct {
+	// Events is the number of events passed to the handler.
+	Events int
+	// EventsByType is the number of events passed to the handler by type.
+	EventsByType map[watch.EventType]int
+	// LastEvent is the last event passed to the handler, nil if none was.
+	LastEvent *watch.Event
+	// Drained is the number of events discarded after the watch was
+	// stopped.
+	Drained int
+}
+
+// ConsumeUntil passes the events of w to handler until handler returns true or
+// an error, ctx is done or the result channel of w is closed. It returns nil,
+// the error of handler, the error of ctx or ErrResultChanClosed respectively,
+// so that callers can tell a deadline of their own from the end of the watch.
+//
+// Whatever the outcome, ConsumeUntil stops w and drains its result channel
+// until it is closed, so that the goroutines sending to it do not leak. If the
+// channel is not closed within a few seconds, or once ctx is done, it is
+// drained in the background, so that ConsumeUntil does not outlive ctx.
+func ConsumeUntil(ctx context.Context, w watch.Interface, handler ConditionFunc) (ConsumeResult, error) {
+	result := ConsumeResult{EventsByType: map[watch.EventType]int{}}
+	ch := w.ResultChan()
+	err := consume(ctx, ch, handler, &result)
+	w.Stop()
+	if !errors.Is(err, ErrResultChanClosed) {
+		result.Drained = drain(ctx, ch, consumeDrainTimeout)
+	}
+	return result, err
+}
+
+func consume(ctx context.Context, ch <-chan watch.Event, handler ConditionFunc, result *ConsumeResult) error {
+	for {
+		// A done context wins over pending events.
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		select {
+		case event, ok := <-ch:
+			if !ok {
+				return ErrResultChanClosed
+			}
+			result.Events++
+			result.EventsByType[event.Type]++
+			result.LastEvent = &event
+			done, err := handler(event)
+			if err != nil {
+				return err
+			}
+			if done {
+				return nil
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// drain discards the events of ch until it is closed and returns how many it
+// discarded. If ch is not closed within timeout, or once ctx is done, it keeps
+// draining ch in the background.
+func drain(ctx context.Context, ch <-chan watch.Event, timeout time.Duration) int {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	drained := 0
+loop:
+	for ctx.Err() == nil {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return drained
+			}
+			drained++
+		case <-timer.C:
+			break loop
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	go func() {
+		for range ch {
+		}
+	}()
+	return drained
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestConsumeUntilDone(t *testing.T) {
+	w := watch.NewFakeWithChanSize(5, false)
+	w.Add(&corev1.Pod{})
+	w.Modify(&corev1.Pod{})
+	w.Modify(&corev1.Pod{})
+	w.Delete(&corev1.Pod{})
+
+	result, err := ConsumeUntil(context.Background(), w, func(event watch.Event) (bool, error) {
+		return event.Type == watch.Modified, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !w.IsStopped() {
+		t.Errorf("expected the watch to be stopped")
+	}
+	if result.Events != 2 || result.EventsByType[watch.Added] != 1 || result.EventsByType[watch.Modified] != 1 {
+		t.Errorf("unexpected counts %+v", result)
+	}
+	if result.LastEvent == nil || result.LastEvent.Type != watch.Modified {
+		t.Errorf("expected the last event to be the modification, got %v", result.LastEvent)
+	}
+	if result.Drained != 2 {
+		t.Errorf("expected the 2 remaining events to be drained, got %d", result.Drained)
+	}
+}
+
+func TestConsumeUntilErrors(t *testing.T) {
+	handlerErr := errors.New("handler failed")
+	tests := []struct {
+		name     string
+		prepare  func(w *watch.FakeWatcher)
+		ctx      func() (context.Context, context.CancelFunc)
+		handler  ConditionFunc
+		expected error
+	}{
+		{
+			name: "context done",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 10*time.Millisecond)
+			},
+			expected: context.DeadlineExceeded,
+		},
+		{
+			name:     "result channel closed",
+			prepare:  func(w *watch.FakeWatcher) { w.Stop() },
+			expected: ErrResultChanClosed,
+		},
+		{
+			name:     "handler error",
+			prepare:  func(w *watch.FakeWatcher) { w.Add(&corev1.Pod{}) },
+			handler:  func(watch.Event) (bool, error) { return false, handlerErr },
+			expected: handlerErr,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := watch.NewFakeWithChanSize(1, false)
+			if test.prepare != nil {
+				test.prepare(w)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			if test.ctx != nil {
+				ctx, cancel = test.ctx()
+			}
+			defer cancel()
+			handler := test.handler
+			if handler == nil {
+				handler = func(watch.Event) (bool, error) { return false, nil }
+			}
+			if _, err := ConsumeUntil(ctx, w, handler); !errors.Is(err, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, err)
+			}
+			if !w.IsStopped() {
+				t.Errorf("expected the watch to be stopped")
+			}
+		})
+	}
+}
+
+func TestDrainInBackground(t *testing.T) {
+	ch := make(chan watch.Event)
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		for i := 0; i < 3; i++ {
+			ch <- watch.Event{Type: watch.Added}
+		}
+	}()
+	// the channel is never closed, the sender must not block anyway
+	drain(context.Background(), ch, 10*time.Millisecond)
+	select {
+	case <-sent:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("expected the events to be drained in the background")
+	}
+}
+
+func TestConsumeUntilReturnsOnceContextDone(t *testing.T) {
+	// The watch is never closed, so only the context bounds the drain.
+	w := &unstoppableWatcher{ch: make(chan watch.Event)}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := ConsumeUntil(ctx, w, func(watch.Event) (bool, error) { return false, nil }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed >= consumeDrainTimeout {
+		t.Errorf("expected ConsumeUntil to return once the context was done, took %v", elapsed)
+	}
+}
+
+// unstoppableWatcher is a watch whose result channel is not closed when it
+// is stopped.
+type unstoppableWatcher struct {
+	ch chan watch.Event
+}
+
+func (w *unstoppableWatcher) Stop()                          {}
+func (w *unstoppableWatcher) ResultChan() <-chan watch.Event { return w.ch }
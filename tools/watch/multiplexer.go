@@ -0,0 +1,259 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/klog/v2"
+)
+
+// OverflowPolicy determines what a Multiplexer does when a subscriber's buffer is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits until the subscriber has room for the event. A slow
+	// subscriber with this policy delays delivery to all other subscribers.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest discards the event for the subscriber whose buffer is full.
+	OverflowDropNewest
+	// OverflowDisconnect stops the subscriber whose buffer is full, closing its
+	// result channel.
+	OverflowDisconnect
+)
+
+// SubscribeOptions configures which events a subscriber of a Multiplexer receives
+// and how they are buffered.
+type SubscribeOptions struct {
+	// LabelSelector, if set, restricts Added, Modified and Deleted events to
+	// objects whose labels match.
+	LabelSelector labels.Selector
+	// FieldSelector, if set, restricts Added, Modified and Deleted events to
+	// objects whose metadata.name and metadata.namespace match.
+	FieldSelector fields.Selector
+	// Predicate, if set, is called for every event that passed the selectors,
+	// including Bookmark and Error events, and the event is delivered only if it
+	// returns true.
+	Predicate func(watch.Event) bool
+	// BufferSize is the number of events buffered for the subscriber.
+	BufferSize int
+	// OverflowPolicy determines what happens when the buffer is full.
+	OverflowPolicy OverflowPolicy
+}
+
+// Multiplexer fans the events of a single upstream watch out to many
+// subscribers, each with its own filter and buffer. Subscribers only receive
+// events that arrive after they subscribed. When the upstream watch is closed,
+// the result channels of all subscribers are closed.
+type Multiplexer struct {
+	upstream watch.Interface
+
+	lock        sync.Mutex
+	subscribers map[*multiplexedWatcher]struct{}
+	stopped     bool
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+}
+
+// NewMultiplexer creates a Multiplexer that distributes the events of upstream.
+func NewMultiplexer(upstream watch.Interface) *Multiplexer {
+	m := &Multiplexer{
+		upstream:    upstream,
+		subscribers: map[*multiplexedWatcher]struct{}{},
+		stopCh:      make(chan struct{}),
+	}
+	go m.run()
+	return m
+}
+
+// Subscribe returns a watch.Interface receiving the upstream events selected by opts.
+func (m *Multiplexer) Subscribe(opts SubscribeOptions) watch.Interface {
+	w := &multiplexedWatcher{
+		m:      m,
+		opts:   opts,
+		result: make(chan watch.Event, opts.BufferSize),
+		stopCh: make(chan struct{}),
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.stopped {
+		w.closed = true
+		close(w.result)
+		return w
+	}
+	m.subscribers[w] = struct{}{}
+	return w
+}
+
+// Stop stops the upstream watch and all subscribers.
+func (m *Multiplexer) Stop() {
+	m.stopOnce.Do(func() {
+		close(m.stopCh)
+		m.upstream.Stop()
+	})
+}
+
+func (m *Multiplexer) run() {
+	defer m.closeAll()
+
+	ch := m.upstream.ResultChan()
+	for {
+		select {
+		case <-m.stopCh:
+			return
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			m.distribute(event)
+		}
+	}
+}
+
+func (m *Multiplexer) distribute(event watch.Event) {
+	m.lock.Lock()
+	subscribers := make([]*multiplexedWatcher, 0, len(m.subscribers))
+	for w := range m.subscribers {
+		subscribers = append(subscribers, w)
+	}
+	m.lock.Unlock()
+
+	for _, w := range subscribers {
+		if !w.matches(event) {
+			continue
+		}
+		if !w.send(event) {
+			klog.V(4).InfoS("Disconnecting watch multiplexer subscriber with full buffer", "bufferSize", w.opts.BufferSize)
+			w.Stop()
+		}
+	}
+}
+
+func (m *Multiplexer) remove(w *multiplexedWatcher) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.subscribers, w)
+}
+
+func (m *Multiplexer) closeAll() {
+	m.lock.Lock()
+	m.stopped = true
+	subscribers := m.subscribers
+	m.subscribers = map[*multiplexedWatcher]struct{}{}
+	m.lock.Unlock()
+
+	for w := range subscribers {
+		w.close()
+	}
+}
+
+// multiplexedWatcher is a single subscriber of a Multiplexer.
+type multiplexedWatcher struct {
+	m    *Multiplexer
+	opts SubscribeOptions
+
+	// lock serializes sending with closing the result channel.
+	lock   sync.Mutex
+	result chan watch.Event
+	closed bool
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+}
+
+var _ watch.Interface = &multiplexedWatcher{}
+
+// ResultChan implements watch.Interface.
+func (w *multiplexedWatcher) ResultChan() <-chan watch.Event {
+	return w.result
+}
+
+// Stop implements watch.Interface.
+func (w *multiplexedWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stopCh)
+		w.m.remove(w)
+		w.close()
+	})
+}
+
+func (w *multiplexedWatcher) close() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if !w.closed {
+		w.closed = true
+		close(w.result)
+	}
+}
+
+// send delivers event according to the overflow policy. It returns false if
+// the subscriber must be disconnected.
+func (w *multiplexedWatcher) send(event watch.Event) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return true
+	}
+
+	select {
+	case w.result <- event:
+		return true
+	default:
+	}
+
+	switch w.opts.OverflowPolicy {
+	case OverflowDropNewest:
+		return true
+	case OverflowDisconnect:
+		return false
+	default:
+		select {
+		case w.result <- event:
+		case <-w.stopCh:
+		case <-w.m.stopCh:
+		}
+		return true
+	}
+}
+
+func (w *multiplexedWatcher) matches(event watch.Event) bool {
+	switch event.Type {
+	case watch.Added, watch.Modified, watch.Deleted:
+		if w.opts.LabelSelector != nil || w.opts.FieldSelector != nil {
+			accessor, err := meta.Accessor(event.Object)
+			if err != nil {
+				return false
+			}
+			if w.opts.LabelSelector != nil && !w.opts.LabelSelector.Matches(labels.Set(accessor.GetLabels())) {
+				return false
+			}
+			if w.opts.FieldSelector != nil && !w.opts.FieldSelector.Matches(fields.Set{
+				"metadata.name":      accessor.GetName(),
+				"metadata.namespace": accessor.GetNamespace(),
+			}) {
+				return false
+			}
+		}
+	}
+	return w.opts.Predicate == nil || w.opts.Predicate(event)
+}
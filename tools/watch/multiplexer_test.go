@@ -0,0 +1,127 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func makeLabeledPod(namespace, name string, podLabels map[string]string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: podLabels}}
+}
+
+func collectNames(t *testing.T, w watch.Interface) []string {
+	t.Helper()
+	var names []string
+	for {
+		select {
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return names
+			}
+			names = append(names, event.Object.(*corev1.Pod).Name)
+		case <-time.After(wait.ForeverTestTimeout):
+			t.Fatalf("timed out waiting for result channel to be closed, got %v", names)
+		}
+	}
+}
+
+func TestMultiplexerFilters(t *testing.T) {
+	upstream := watch.NewFake()
+	m := NewMultiplexer(upstream)
+
+	all := m.Subscribe(SubscribeOptions{BufferSize: 10})
+	byLabel := m.Subscribe(SubscribeOptions{
+		BufferSize:    10,
+		LabelSelector: labels.SelectorFromSet(labels.Set{"app": "web"}),
+	})
+	byField := m.Subscribe(SubscribeOptions{
+		BufferSize:    10,
+		FieldSelector: fields.OneTermEqualSelector("metadata.namespace", "kube-system"),
+	})
+	byPredicate := m.Subscribe(SubscribeOptions{
+		BufferSize: 10,
+		Predicate:  func(event watch.Event) bool { return event.Type == watch.Deleted },
+	})
+
+	upstream.Add(makeLabeledPod("default", "a", map[string]string{"app": "web"}))
+	upstream.Add(makeLabeledPod("kube-system", "b", nil))
+	upstream.Delete(makeLabeledPod("default", "c", map[string]string{"app": "db"}))
+	upstream.Stop()
+
+	if got, want := collectNames(t, all), []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("all: expected %v, got %v", want, got)
+	}
+	if got, want := collectNames(t, byLabel), []string{"a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("label selector: expected %v, got %v", want, got)
+	}
+	if got, want := collectNames(t, byField), []string{"b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("field selector: expected %v, got %v", want, got)
+	}
+	if got, want := collectNames(t, byPredicate), []string{"c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("predicate: expected %v, got %v", want, got)
+	}
+
+	if _, ok := <-m.Subscribe(SubscribeOptions{}).ResultChan(); ok {
+		t.Errorf("expected subscription after upstream closed to be closed")
+	}
+}
+
+func TestMultiplexerOverflow(t *testing.T) {
+	upstream := watch.NewFake()
+	m := NewMultiplexer(upstream)
+	defer m.Stop()
+
+	drop := m.Subscribe(SubscribeOptions{BufferSize: 1, OverflowPolicy: OverflowDropNewest})
+	disconnect := m.Subscribe(SubscribeOptions{BufferSize: 1, OverflowPolicy: OverflowDisconnect})
+	block := m.Subscribe(SubscribeOptions{BufferSize: 1})
+
+	upstream.Add(makeLabeledPod("default", "a", nil))
+	go func() {
+		<-block.ResultChan()
+		<-block.ResultChan()
+	}()
+	// The fake watcher is unbuffered, so once the third event is accepted the
+	// second one has been distributed to every subscriber.
+	upstream.Add(makeLabeledPod("default", "b", nil))
+	upstream.Add(makeLabeledPod("default", "c", nil))
+
+	if got, want := collectNames(t, disconnect), []string{"a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("disconnect: expected %v, got %v", want, got)
+	}
+
+	event := <-drop.ResultChan()
+	if name := event.Object.(*corev1.Pod).Name; name != "a" {
+		t.Errorf("drop: expected first event to be kept, got %q", name)
+	}
+	drop.Stop()
+	// c may or may not have been distributed before the first event was read.
+	for _, name := range collectNames(t, drop) {
+		if name == "b" {
+			t.Errorf("drop: expected b to be dropped")
+		}
+	}
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// DefaultProcessorMaxRetries is the number of times a Processor retries a
+// failed event unless ProcessorOptions.MaxRetries is set.
+const DefaultProcessorMaxRetries = 5
+
+// ProcessFunc handles a single event. Returning an error makes the Processor
+// retry the event.
+type ProcessFunc func(ctx context.Context, event watch.Event) error
+
+// ProcessorOptions holds optional settings for a Processor.
+type ProcessorOptions struct {
+	// KeyFunc computes the key of the object of an event. Events with the same
+	// key are processed one at a time, in the order they were received.
+	// Defaults to cache.DeletionHandlingMetaNamespaceKeyFunc.
+	KeyFunc cache.KeyFunc
+	// Workers is the number of events processed concurrently. Defaults to 1.
+	Workers int
+	// MaxRetries is the number of times a failed event is retried before it is
+	// dropped. Defaults to DefaultProcessorMaxRetries; a negative value
+	// disables retries.
+	MaxRetries int
+	// RateLimiter computes the delay before retrying the events of a key.
+	// Defaults to workqueue.DefaultControllerRateLimiter().
+	RateLimiter workqueue.RateLimiter
+}
+
+// Processor consumes a watch and dispatches its events to a pool of workers,
+// retrying failed events. Events are keyed by object; events with the same key
+// are processed sequentially and in order, while events with different keys
+// are processed concurrently. It is meant for consumers that need to act on
+// a watch but do not need the cache maintained by an informer.
+type Processor struct {
+	watcher    watch.Interface
+	process    ProcessFunc
+	keyFunc    cache.KeyFunc
+	workers    int
+	maxRetries int
+	queue      workqueue.RateLimitingInterface
+
+	lock    sync.Mutex
+	pending map[string][]watch.Event
+	// inFlight is the number of events that were received but not processed or dropped yet.
+	inFlight    int
+	watchClosed bool
+	// drained is closed once the watch is closed and no event is in flight.
+	drained chan struct{}
+}
+
+// NewProcessor returns a Processor dispatching the events of w to process.
+// Call Run to start processing.
+func NewProcessor(w watch.Interface, process ProcessFunc, opts ProcessorOptions) *Processor {
+	if opts.KeyFunc == nil {
+		opts.KeyFunc = cache.DeletionHandlingMetaNamespaceKeyFunc
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	switch {
+	case opts.MaxRetries == 0:
+		opts.MaxRetries = DefaultProcessorMaxRetries
+	case opts.MaxRetries < 0:
+		opts.MaxRetries = 0
+	}
+	if opts.RateLimiter == nil {
+		opts.RateLimiter = workqueue.DefaultControllerRateLimiter()
+	}
+	return &Processor{
+		watcher:    w,
+		process:    process,
+		keyFunc:    opts.KeyFunc,
+		workers:    opts.Workers,
+		maxRetries: opts.MaxRetries,
+		queue:      workqueue.NewRateLimitingQueue(opts.RateLimiter),
+		pending:    map[string][]watch.Event{},
+		drained:    make(chan struct{}),
+	}
+}
+
+// Run processes the events of the watch until it is closed and all the
+// received events have been processed, or until ctx is done. It stops the
+// watch before returning. Run returns ctx.Err() if ctx was done, the error
+// carried by a watch.Error event if one was received, and nil otherwise.
+// Run must be called only once.
+func (p *Processor) Run(ctx context.Context) error {
+	defer p.watcher.Stop()
+
+	var workers sync.WaitGroup
+	for i := 0; i < p.workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for p.processNextKey(ctx) {
+			}
+		}()
+	}
+	defer func() {
+		p.queue.ShutDown()
+		workers.Wait()
+	}()
+
+	if err := p.dispatch(ctx); err != nil {
+		return err
+	}
+
+	p.lock.Lock()
+	p.watchClosed = true
+	p.checkDrainedLocked()
+	p.lock.Unlock()
+
+	select {
+	case <-p.drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// dispatch queues the events of the watch until it is closed.
+func (p *Processor) dispatch(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-p.watcher.ResultChan():
+			if !ok {
+				return nil
+			}
+			if event.Type == watch.Error {
+				return apierrors.FromObject(event.Object)
+			}
+			key, err := p.keyFunc(event.Object)
+			if err != nil {
+				utilruntime.HandleError(fmt.Errorf("unable to compute key of %v event: %v", event.Type, err))
+				continue
+			}
+			p.lock.Lock()
+			p.inFlight++
+			p.pending[key] = append(p.pending[key], event)
+			p.lock.Unlock()
+			p.queue.Add(key)
+		}
+	}
+}
+
+// processNextKey processes the pending events of the next key in the queue.
+// It returns false when the queue was shut down.
+func (p *Processor) processNextKey(ctx context.Context) bool {
+	item, shutdown := p.queue.Get()
+	if shutdown {
+		return false
+	}
+	key := item.(string)
+	defer p.queue.Done(key)
+
+	for {
+		p.lock.Lock()
+		events := p.pending[key]
+		if len(events) == 0 {
+			delete(p.pending, key)
+			p.lock.Unlock()
+			p.queue.Forget(key)
+			return true
+		}
+		event := events[0]
+		p.lock.Unlock()
+
+		if err := p.process(ctx, event); err != nil {
+			if ctx.Err() != nil {
+				return true
+			}
+			if p.queue.NumRequeues(key) < p.maxRetries {
+				// Keep the event at the head of the key's events so ordering is preserved.
+				p.queue.AddRateLimited(key)
+				return true
+			}
+			utilruntime.HandleError(fmt.Errorf("dropping %v event for %q after %d retries: %v", event.Type, key, p.maxRetries, err))
+		}
+		p.queue.Forget(key)
+
+		p.lock.Lock()
+		p.pending[key] = p.pending[key][1:]
+		p.inFlight--
+		p.checkDrainedLocked()
+		p.lock.Unlock()
+	}
+}
+
+func (p *Processor) checkDrainedLocked() {
+	if p.watchClosed && p.inFlight == 0 {
+		close(p.drained)
+	}
+}
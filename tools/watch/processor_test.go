@@ -0,0 +1,134 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestProcessorOrderingAndRetries(t *testing.T) {
+	fw := watch.NewFakeWithChanSize(6, false)
+	fw.Add(makeTestPod("a", "1"))
+	fw.Add(makeTestPod("b", "2"))
+	fw.Modify(makeTestPod("a", "3"))
+	fw.Modify(makeTestPod("b", "4"))
+	fw.Delete(makeTestPod("a", "5"))
+	fw.Stop()
+
+	var lock sync.Mutex
+	processed := map[string][]string{}
+	failures := map[string]int{}
+	process := func(ctx context.Context, event watch.Event) error {
+		pod := event.Object.(interface {
+			GetName() string
+			GetResourceVersion() string
+		})
+		lock.Lock()
+		defer lock.Unlock()
+		// Fail the first two attempts of resourceVersion 3.
+		if pod.GetResourceVersion() == "3" && failures["3"] < 2 {
+			failures["3"]++
+			return errors.New("transient")
+		}
+		processed[pod.GetName()] = append(processed[pod.GetName()], pod.GetResourceVersion())
+		return nil
+	}
+
+	p := NewProcessor(fw, process, ProcessorOptions{
+		Workers:     3,
+		RateLimiter: workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, 10*time.Millisecond),
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := p.Run(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string][]string{"a": {"1", "3", "5"}, "b": {"2", "4"}}
+	if !reflect.DeepEqual(expected, processed) {
+		t.Errorf("expected %v, got %v", expected, processed)
+	}
+	if failures["3"] != 2 {
+		t.Errorf("expected 2 failures, got %d", failures["3"])
+	}
+}
+
+func TestProcessorDropsAfterMaxRetries(t *testing.T) {
+	fw := watch.NewFakeWithChanSize(2, false)
+	fw.Add(makeTestPod("a", "1"))
+	fw.Modify(makeTestPod("a", "2"))
+	fw.Stop()
+
+	var attempts []string
+	process := func(ctx context.Context, event watch.Event) error {
+		rv := event.Object.(interface{ GetResourceVersion() string }).GetResourceVersion()
+		attempts = append(attempts, rv)
+		if rv == "1" {
+			return errors.New("permanent")
+		}
+		return nil
+	}
+
+	p := NewProcessor(fw, process, ProcessorOptions{
+		MaxRetries:  2,
+		RateLimiter: workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, time.Millisecond),
+	})
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"1", "1", "1", "2"}; !reflect.DeepEqual(expected, attempts) {
+		t.Errorf("expected attempts %v, got %v", expected, attempts)
+	}
+}
+
+func TestProcessorStops(t *testing.T) {
+	t.Run("error event", func(t *testing.T) {
+		fw := watch.NewFakeWithChanSize(1, false)
+		fw.Error(&apierrors.NewResourceExpired("too old").ErrStatus)
+
+		p := NewProcessor(fw, func(context.Context, watch.Event) error { return nil }, ProcessorOptions{})
+		if err := p.Run(context.Background()); !apierrors.IsResourceExpired(err) {
+			t.Errorf("expected expired error, got %v", err)
+		}
+		if !fw.IsStopped() {
+			t.Errorf("expected watch to be stopped")
+		}
+	})
+
+	t.Run("context done", func(t *testing.T) {
+		fw := watch.NewFakeWithChanSize(1, false)
+		fw.Add(makeTestPod("a", "1"))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		p := NewProcessor(fw, func(context.Context, watch.Event) error {
+			cancel()
+			return nil
+		}, ProcessorOptions{})
+		if err := p.Run(ctx); err != context.Canceled {
+			t.Errorf("expected %v, got %v", context.Canceled, err)
+		}
+	})
+}
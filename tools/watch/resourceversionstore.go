@@ -0,0 +1,116 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"k8s.io/client-go/tools/pager"
+)
+
+// ResourceVersionStore persists the last resourceVersion observed by a RetryWatcher
+// so that a watch can be resumed after the process restarts.
+type ResourceVersionStore interface {
+	// Get returns the stored resourceVersion, or an empty string if there is none.
+	Get() (string, error)
+	// Set stores resourceVersion, replacing any previously stored value.
+	Set(resourceVersion string) error
+}
+
+// NewFileResourceVersionStore returns a ResourceVersionStore that keeps the
+// resourceVersion in the file at path. The file is replaced atomically on
+// every update.
+func NewFileResourceVersionStore(path string) ResourceVersionStore {
+	return &fileResourceVersionStore{path: path}
+}
+
+type fileResourceVersionStore struct {
+	lock sync.Mutex
+	path string
+}
+
+func (s *fileResourceVersionStore) Get() (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (s *fileResourceVersionStore) Set(resourceVersion string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	f, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(resourceVersion); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path)
+}
+
+// NewCheckpointResourceVersionStore returns a ResourceVersionStore keeping the
+// resourceVersion in the checkpoint of a list made with
+// pager.ListPager.EachListItemWithCheckpoint, so that an export can resume
+// watching from where it stopped. Get fails until the list has completed.
+func NewCheckpointResourceVersionStore(store pager.CheckpointStore) ResourceVersionStore {
+	return &checkpointResourceVersionStore{store: store}
+}
+
+type checkpointResourceVersionStore struct {
+	lock  sync.Mutex
+	store pager.CheckpointStore
+}
+
+func (s *checkpointResourceVersionStore) Get() (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	checkpoint, err := s.store.Load()
+	if err != nil {
+		return "", err
+	}
+	if !checkpoint.ListComplete {
+		return "", fmt.Errorf("the list of the checkpoint has not completed")
+	}
+	return checkpoint.ResourceVersion, nil
+}
+
+func (s *checkpointResourceVersionStore) Set(resourceVersion string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.store.Save(pager.Checkpoint{ResourceVersion: resourceVersion, ListComplete: true})
+}
@@ -22,17 +22,22 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/net"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/metrics"
+	"k8s.io/client-go/util/backoff"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
 )
 
 // resourceVersionGetter is an interface used to get resource version from events.
@@ -45,30 +50,101 @@ type resourceVersionGetter interface {
 // it will get restarted from the last point without the consumer even knowing about it.
 // RetryWatcher does that by inspecting events and keeping track of resourceVersion.
 // Especially useful when using watch.UntilWithoutRetry where premature termination is causing issues and flakes.
-// Please note that this is not resilient to etcd cache not having the resource version anymore - you would need to
-// use Informers for that.
+// Please note that unless a Lister is provided through RetryWatcherOptions this is not resilient to etcd cache
+// not having the resource version anymore - you would need to use Informers for that.
 type RetryWatcher struct {
 	lastResourceVersion string
 	watcherClient       cache.Watcher
+	lister              cache.Lister
+	rvStore             ResourceVersionStore
+	needsRelist         bool
 	resultChan          chan watch.Event
 	stopChan            chan struct{}
 	doneChan            chan struct{}
 	minRestartDelay     time.Duration
+	maxWatchDuration    time.Duration
+	rvComparator        cache.ResourceVersionStringComparator
+	name                string
+	resource            string
+	// restartReason is the reason of the last watch restart, reported to metrics.
+	restartReason string
+
+	lastEventTimeLock sync.RWMutex
+	lastEventTime     time.Time
+}
+
+// RetryWatcherOptions holds optional settings for a RetryWatcher.
+type RetryWatcherOptions struct {
+	// ResourceVersionStore, if set, is used to persist the last resourceVersion
+	// seen by the watcher, including the ones carried by bookmarks. If no
+	// initial resourceVersion is given, the watch is resumed from the stored one.
+	ResourceVersionStore ResourceVersionStore
+
+	// Lister, if set, is used to list the current state when the watch cannot be
+	// started from a resourceVersion, either because none is known or because
+	// it has expired (410 Gone). All listed objects are delivered as Added events
+	// and the watch continues from the resourceVersion of the list. Objects that
+	// were deleted while the resourceVersion was expired are not reported.
+	// A cache.ListerWatcher can be passed as both the Lister and the Watcher.
+	Lister cache.Lister
+
+	// Name identifies the watcher in metrics, together with Resource. Retry
+	// watchers of the same resource sharing a name share their metrics, so it
+	// should be unique among them. Metrics are only recorded for named
+	// watchers.
+	Name string
+
+	// Resource identifies the watched resource in metrics, for example its
+	// GroupVersionResource.
+	Resource string
+
+	// MaxWatchDuration, if positive, is the maximum time a single watch is kept
+	// open. The watch is then stopped and transparently re-established from the
+	// last seen resourceVersion, so that long-lived watches get redistributed
+	// across apiservers and are not cut by proxies enforcing connection limits.
+	MaxWatchDuration time.Duration
+
+	// ResourceVersionComparator, if set, compares the resourceVersions of the
+	// events, which are otherwise opaque, with the last seen one, so that the
+	// events already seen, like the ones replayed by caching proxies after a
+	// restart, are dropped instead of being delivered twice.
+	ResourceVersionComparator cache.ResourceVersionStringComparator
 }
 
 // NewRetryWatcher creates a new RetryWatcher.
 // It will make sure that watches gets restarted in case of recoverable errors.
 // The initialResourceVersion will be given to watch method when first called.
 func NewRetryWatcher(initialResourceVersion string, watcherClient cache.Watcher) (*RetryWatcher, error) {
-	return newRetryWatcher(initialResourceVersion, watcherClient, 1*time.Second)
+	return newRetryWatcher(initialResourceVersion, watcherClient, RetryWatcherOptions{}, 1*time.Second)
+}
+
+// NewRetryWatcherWithOptions creates a new RetryWatcher with the given options.
+// The initialResourceVersion may be empty if opts provides a ResourceVersionStore
+// holding a resourceVersion or a Lister to obtain the initial state.
+func NewRetryWatcherWithOptions(initialResourceVersion string, watcherClient cache.Watcher, opts RetryWatcherOptions) (*RetryWatcher, error) {
+	return newRetryWatcher(initialResourceVersion, watcherClient, opts, 1*time.Second)
 }
 
-func newRetryWatcher(initialResourceVersion string, watcherClient cache.Watcher, minRestartDelay time.Duration) (*RetryWatcher, error) {
+func newRetryWatcher(initialResourceVersion string, watcherClient cache.Watcher, opts RetryWatcherOptions, minRestartDelay time.Duration) (*RetryWatcher, error) {
+	if (initialResourceVersion == "" || initialResourceVersion == "0") && opts.ResourceVersionStore != nil {
+		storedResourceVersion, err := opts.ResourceVersionStore.Get()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load resourceVersion: %v", err)
+		}
+		if storedResourceVersion != "" {
+			initialResourceVersion = storedResourceVersion
+		}
+	}
+
+	needsRelist := false
 	switch initialResourceVersion {
 	case "", "0":
-		// TODO: revisit this if we ever get WATCH v2 where it means start "now"
-		//       without doing the synthetic list of objects at the beginning (see #74022)
-		return nil, fmt.Errorf("initial RV %q is not supported due to issues with underlying WATCH", initialResourceVersion)
+		if opts.Lister == nil {
+			// TODO: revisit this if we ever get WATCH v2 where it means start "now"
+			//       without doing the synthetic list of objects at the beginning (see #74022)
+			return nil, fmt.Errorf("initial RV %q is not supported due to issues with underlying WATCH", initialResourceVersion)
+		}
+		needsRelist = true
 	default:
 		break
 	}
@@ -76,10 +152,17 @@ func newRetryWatcher(initialResourceVersion string, watcherClient cache.Watcher,
 	rw := &RetryWatcher{
 		lastResourceVersion: initialResourceVersion,
 		watcherClient:       watcherClient,
+		lister:              opts.Lister,
+		rvStore:             opts.ResourceVersionStore,
+		needsRelist:         needsRelist,
 		stopChan:            make(chan struct{}),
 		doneChan:            make(chan struct{}),
 		resultChan:          make(chan watch.Event, 0),
 		minRestartDelay:     minRestartDelay,
+		maxWatchDuration:    opts.MaxWatchDuration,
+		rvComparator:        opts.ResourceVersionComparator,
+		name:                opts.Name,
+		resource:            opts.Resource,
 	}
 
 	go rw.receive()
@@ -97,13 +180,92 @@ func (rw *RetryWatcher) send(event watch.Event) bool {
 	}
 }
 
+// setResourceVersion records the last seen resourceVersion and persists it if a store is configured.
+func (rw *RetryWatcher) setResourceVersion(resourceVersion string) {
+	rw.lastResourceVersion = resourceVersion
+	if rw.rvStore == nil {
+		return
+	}
+	if err := rw.rvStore.Set(resourceVersion); err != nil {
+		klog.ErrorS(err, "Failed to persist resourceVersion", "resourceVersion", resourceVersion)
+	}
+}
+
+// relist lists the current state using the lister and delivers every object as an Added event.
+// It returns true when it is done, false otherwise, like doReceive.
+func (rw *RetryWatcher) relist() (bool, time.Duration) {
+	list, err := rw.lister.List(metav1.ListOptions{})
+	if err != nil {
+		klog.ErrorS(err, "Failed to list after resourceVersion expired")
+		// Retry
+		return rw.restart(metrics.WatchRestartReasonError, 0)
+	}
+	listMeta, err := meta.ListAccessor(list)
+	if err != nil {
+		_ = rw.send(watch.Event{
+			Type:   watch.Error,
+			Object: &apierrors.NewInternalError(fmt.Errorf("retryWatcher: unable to understand list result %#v: %v", list, err)).ErrStatus,
+		})
+		return true, 0
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		_ = rw.send(watch.Event{
+			Type:   watch.Error,
+			Object: &apierrors.NewInternalError(fmt.Errorf("retryWatcher: unable to extract list items from %#v: %v", list, err)).ErrStatus,
+		})
+		return true, 0
+	}
+	for _, item := range items {
+		if !rw.send(watch.Event{Type: watch.Added, Object: item}) {
+			return true, 0
+		}
+	}
+	rw.needsRelist = false
+	rw.setResourceVersion(listMeta.GetResourceVersion())
+	return false, 0
+}
+
+// restart records the reason the watch needs to be restarted and returns the
+// values doReceive returns in that case.
+func (rw *RetryWatcher) restart(reason string, retryAfter time.Duration) (bool, time.Duration) {
+	rw.restartReason = reason
+	return false, retryAfter
+}
+
+func (rw *RetryWatcher) setLastEventTime(t time.Time) {
+	rw.lastEventTimeLock.Lock()
+	defer rw.lastEventTimeLock.Unlock()
+	rw.lastEventTime = t
+}
+
+// LastEventTime returns the time the last event, including bookmarks, was
+// received from the underlying watch, or the zero time if none was received
+// yet. A watch that has not received events for a long time, while the server
+// is expected to send bookmarks, may be silently stale.
+func (rw *RetryWatcher) LastEventTime() time.Time {
+	rw.lastEventTimeLock.RLock()
+	defer rw.lastEventTimeLock.RUnlock()
+	return rw.lastEventTime
+}
+
 // doReceive returns true when it is done, false otherwise.
 // If it is not done the second return value holds the time to wait before calling it again.
 func (rw *RetryWatcher) doReceive() (bool, time.Duration) {
-	watcher, err := rw.watcherClient.Watch(metav1.ListOptions{
+	if rw.needsRelist {
+		if done, retryAfter := rw.relist(); done || rw.needsRelist {
+			return done, retryAfter
+		}
+	}
+
+	options := metav1.ListOptions{
 		ResourceVersion:     rw.lastResourceVersion,
 		AllowWatchBookmarks: true,
-	})
+	}
+	if timeoutSeconds := int64(rw.maxWatchDuration.Seconds()); timeoutSeconds > 0 {
+		options.TimeoutSeconds = &timeoutSeconds
+	}
+	watcher, err := rw.watcherClient.Watch(options)
 	// We are very unlikely to hit EOF here since we are just establishing the call,
 	// but it may happen that the apiserver is just shutting down (e.g. being restarted)
 	// This is consistent with how it is handled for informers
@@ -113,44 +275,66 @@ func (rw *RetryWatcher) doReceive() (bool, time.Duration) {
 
 	case io.EOF:
 		// watch closed normally
-		return false, 0
+		return rw.restart(metrics.WatchRestartReasonClosed, 0)
 
 	case io.ErrUnexpectedEOF:
 		klog.V(1).InfoS("Watch closed with unexpected EOF", "err", err)
-		return false, 0
+		return rw.restart(metrics.WatchRestartReasonError, 0)
 
 	default:
 		msg := "Watch failed"
+		if rw.lister != nil && (apierrors.IsResourceExpired(err) || apierrors.IsGone(err)) {
+			klog.V(4).InfoS("ResourceVersion expired, relisting", "resourceVersion", rw.lastResourceVersion)
+			rw.needsRelist = true
+			return rw.restart(metrics.WatchRestartReasonExpired, 0)
+		}
 		if net.IsProbableEOF(err) || net.IsTimeout(err) {
 			klog.V(5).InfoS(msg, "err", err)
 			// Retry
-			return false, 0
+			return rw.restart(metrics.WatchRestartReasonClosed, 0)
+		}
+		if net.IsConnectionRefused(err) || apierrors.IsTooManyRequests(err) {
+			klog.V(4).InfoS(msg, "err", err)
+			// Retry
+			return rw.restart(metrics.WatchRestartReasonThrottled, 0)
 		}
 
 		klog.ErrorS(err, msg)
 		// Retry
-		return false, 0
+		return rw.restart(metrics.WatchRestartReasonError, 0)
 	}
 
 	if watcher == nil {
 		klog.ErrorS(nil, "Watch returned nil watcher")
 		// Retry
-		return false, 0
+		return rw.restart(metrics.WatchRestartReasonError, 0)
 	}
+	rw.setDisconnected(nil)
 
 	ch := watcher.ResultChan()
 	defer watcher.Stop()
 
+	var maxDurationCh <-chan time.Time
+	if rw.maxWatchDuration > 0 {
+		timer := time.NewTimer(rw.maxWatchDuration)
+		defer timer.Stop()
+		maxDurationCh = timer.C
+	}
+
 	for {
 		select {
 		case <-rw.stopChan:
 			klog.V(4).InfoS("Stopping RetryWatcher.")
 			return true, 0
+		case <-maxDurationCh:
+			klog.V(4).InfoS("Watch reached the maximum duration, re-creating the watcher.", "resourceVersion", rw.lastResourceVersion)
+			return rw.restart(metrics.WatchRestartReasonClosed, 0)
 		case event, ok := <-ch:
 			if !ok {
 				klog.V(4).InfoS("Failed to get event! Re-creating the watcher.", "resourceVersion", rw.lastResourceVersion)
-				return false, 0
+				return rw.restart(metrics.WatchRestartReasonClosed, 0)
 			}
+			rw.setLastEventTime(time.Now())
 
 			// We need to inspect the event and get ResourceVersion out of it
 			switch event.Type {
@@ -175,6 +359,11 @@ func (rw *RetryWatcher) doReceive() (bool, time.Duration) {
 					return true, 0
 				}
 
+				if cache.IsStaleEvent(rw.rvComparator, event.Type, resourceVersion, rw.lastResourceVersion) {
+					klog.V(4).InfoS("Dropping stale event", "eventType", event.Type, "resourceVersion", resourceVersion, "lastResourceVersion", rw.lastResourceVersion)
+					continue
+				}
+
 				// All is fine; send the non-bookmark events and update resource version.
 				if event.Type != watch.Bookmark {
 					ok = rw.send(event)
@@ -182,7 +371,7 @@ func (rw *RetryWatcher) doReceive() (bool, time.Duration) {
 						return true, 0
 					}
 				}
-				rw.lastResourceVersion = resourceVersion
+				rw.setResourceVersion(resourceVersion)
 
 				continue
 
@@ -193,7 +382,7 @@ func (rw *RetryWatcher) doReceive() (bool, time.Duration) {
 				if !ok {
 					klog.Error(spew.Sprintf("Received an error which is not *metav1.Status but %#+v", event.Object))
 					// Retry unknown errors
-					return false, 0
+					return rw.restart(metrics.WatchRestartReasonError, 0)
 				}
 
 				status := statusErr.ErrStatus
@@ -205,13 +394,22 @@ func (rw *RetryWatcher) doReceive() (bool, time.Duration) {
 
 				switch status.Code {
 				case http.StatusGone:
+					if rw.lister != nil {
+						klog.V(4).InfoS("ResourceVersion expired, relisting", "resourceVersion", rw.lastResourceVersion)
+						rw.needsRelist = true
+						return rw.restart(metrics.WatchRestartReasonExpired, 0)
+					}
 					// Never retry RV too old errors
 					_ = rw.send(event)
 					return true, 0
 
 				case http.StatusGatewayTimeout, http.StatusInternalServerError:
 					// Retry
-					return false, statusDelay
+					return rw.restart(metrics.WatchRestartReasonError, statusDelay)
+
+				case http.StatusTooManyRequests:
+					// Retry
+					return rw.restart(metrics.WatchRestartReasonThrottled, statusDelay)
 
 				default:
 					// We retry by default. RetryWatcher is meant to proceed unless it is certain
@@ -223,7 +421,7 @@ func (rw *RetryWatcher) doReceive() (bool, time.Duration) {
 					klog.V(5).Info(spew.Sprintf("Retrying after unexpected error: %#+v", event.Object))
 
 					// Retry
-					return false, statusDelay
+					return rw.restart(metrics.WatchRestartReasonError, statusDelay)
 				}
 
 			default:
@@ -239,6 +437,14 @@ func (rw *RetryWatcher) doReceive() (bool, time.Duration) {
 	}
 }
 
+// setDisconnected records in metrics since when the watcher has had no
+// established watch, nil meaning that it has one.
+func (rw *RetryWatcher) setDisconnected(since *time.Time) {
+	if rw.name != "" {
+		metrics.WatchDisconnected.Set(rw.name, rw.resource, since)
+	}
+}
+
 // receive reads the result from a watcher, restarting it if necessary.
 func (rw *RetryWatcher) receive() {
 	defer close(rw.doneChan)
@@ -261,17 +467,24 @@ func (rw *RetryWatcher) receive() {
 
 	// We use non sliding until so we don't introduce delays on happy path when WATCH call
 	// timeouts or gets closed and we need to reestablish it while also avoiding hot loops.
-	wait.NonSlidingUntilWithContext(ctx, func(ctx context.Context) {
+	wait.BackoffUntil(func() {
+		rw.restartReason = ""
 		done, retryAfter := rw.doReceive()
 		if done {
+			rw.setDisconnected(nil)
 			cancel()
 			return
 		}
+		if rw.restartReason != "" && rw.name != "" {
+			metrics.WatchRestarts.Increment(rw.name, rw.resource, rw.restartReason)
+		}
+		since := time.Now()
+		rw.setDisconnected(&since)
 
 		time.Sleep(retryAfter)
 
 		klog.V(4).Infof("Restarting RetryWatcher at RV=%q", rw.lastResourceVersion)
-	}, rw.minRestartDelay)
+	}, backoff.NewManager(backoff.Steps(rw.minRestartDelay), clock.RealClock{}), false, ctx.Done())
 }
 
 // ResultChan implements Interface.
@@ -20,14 +20,17 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"path/filepath"
 	"reflect"
 	"strconv"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
 
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -36,6 +39,8 @@ import (
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/metrics"
+	"k8s.io/client-go/tools/pager"
 	"k8s.io/klog/v2"
 )
 
@@ -516,7 +521,7 @@ func TestRetryWatcher(t *testing.T) {
 			t.Parallel()
 
 			atomicCounter, watchFunc := withCounter(tc.watchClient)
-			watcher, err := newRetryWatcher(tc.initialRV, watchFunc, time.Duration(0))
+			watcher, err := newRetryWatcher(tc.initialRV, watchFunc, RetryWatcherOptions{}, time.Duration(0))
 			if err != nil {
 				t.Fatalf("failed to create a RetryWatcher: %v", err)
 			}
@@ -601,3 +606,309 @@ func TestRetryWatcherToFinishWithUnreadEvents(t *testing.T) {
 		t.Error("ResultChan is not closed")
 	}
 }
+
+type memoryResourceVersionStore struct {
+	lock            sync.Mutex
+	resourceVersion string
+}
+
+func (s *memoryResourceVersionStore) Get() (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.resourceVersion, nil
+}
+
+func (s *memoryResourceVersionStore) Set(resourceVersion string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.resourceVersion = resourceVersion
+	return nil
+}
+
+func makeTestPod(name, rv string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, ResourceVersion: rv}}
+}
+
+func TestRetryWatcherRelistsOnExpiredResourceVersion(t *testing.T) {
+	var lock sync.Mutex
+	var watchRVs []string
+	lists := []*corev1.PodList{
+		{ListMeta: metav1.ListMeta{ResourceVersion: "10"}, Items: []corev1.Pod{*makeTestPod("a", "5")}},
+		{ListMeta: metav1.ListMeta{ResourceVersion: "20"}, Items: []corev1.Pod{*makeTestPod("a", "15")}},
+	}
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			list := lists[0]
+			lists = lists[1:]
+			return list, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			watchRVs = append(watchRVs, options.ResourceVersion)
+			if options.ResourceVersion == "10" {
+				return watch.NewProxyWatcher(arrayToChannel([]watch.Event{
+					{Type: watch.Modified, Object: makeTestPod("a", "11")},
+					{Type: watch.Bookmark, Object: makeTestPod("", "12")},
+					{Type: watch.Error, Object: &apierrors.NewResourceExpired("too old").ErrStatus},
+				})), nil
+			}
+			return watch.NewProxyWatcher(make(chan watch.Event)), nil
+		},
+	}
+	store := &memoryResourceVersionStore{}
+
+	watcher, err := newRetryWatcher("", lw, RetryWatcherOptions{ResourceVersionStore: store, Lister: lw}, time.Duration(0))
+	if err != nil {
+		t.Fatalf("failed to create a RetryWatcher: %v", err)
+	}
+	defer watcher.Stop()
+
+	expected := []watch.Event{
+		{Type: watch.Added, Object: makeTestPod("a", "5")},
+		{Type: watch.Modified, Object: makeTestPod("a", "11")},
+		{Type: watch.Added, Object: makeTestPod("a", "15")},
+	}
+	var got []watch.Event
+	for len(got) < len(expected) {
+		select {
+		case event := <-watcher.ResultChan():
+			got = append(got, event)
+		case <-time.After(wait.ForeverTestTimeout):
+			t.Fatalf("timed out waiting for events, got %#v", got)
+		}
+	}
+	if !reflect.DeepEqual(expected, got) {
+		t.Fatal(spew.Errorf("expected %#+v, got %#+v;\ndiff: %s", expected, got, diff.ObjectReflectDiff(expected, got)))
+	}
+
+	err = wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(watchRVs) == 2, nil
+	})
+	if err != nil {
+		t.Fatalf("expected watch to be restarted after relisting: %v", err)
+	}
+	if !reflect.DeepEqual(watchRVs, []string{"10", "20"}) {
+		t.Errorf("unexpected watch resourceVersions: %v", watchRVs)
+	}
+	if rv, _ := store.Get(); rv != "20" {
+		t.Errorf("expected stored resourceVersion %q, got %q", "20", rv)
+	}
+}
+
+func TestRetryWatcherResumesFromStore(t *testing.T) {
+	store := NewFileResourceVersionStore(filepath.Join(t.TempDir(), "rv"))
+	if rv, err := store.Get(); err != nil || rv != "" {
+		t.Fatalf("expected empty store, got %q, %v", rv, err)
+	}
+	if err := store.Set("42"); err != nil {
+		t.Fatal(err)
+	}
+
+	watchRV := make(chan string, 1)
+	watcher, err := NewRetryWatcherWithOptions("", &cache.ListWatch{
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			select {
+			case watchRV <- options.ResourceVersion:
+			default:
+			}
+			return watch.NewProxyWatcher(make(chan watch.Event)), nil
+		},
+	}, RetryWatcherOptions{ResourceVersionStore: store})
+	if err != nil {
+		t.Fatalf("failed to create a RetryWatcher: %v", err)
+	}
+	defer watcher.Stop()
+
+	select {
+	case rv := <-watchRV:
+		if rv != "42" {
+			t.Errorf("expected watch to resume from %q, got %q", "42", rv)
+		}
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("timed out waiting for watch")
+	}
+}
+
+type fakeWatchMetrics struct {
+	lock         sync.Mutex
+	restarts     []string
+	disconnected map[string]*time.Time
+}
+
+func (m *fakeWatchMetrics) Increment(component, resource, reason string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.restarts = append(m.restarts, component+"/"+resource+"/"+reason)
+}
+
+func (m *fakeWatchMetrics) Set(component, resource string, since *time.Time) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.disconnected[component+"/"+resource] = since
+}
+
+func TestRetryWatcherMetrics(t *testing.T) {
+	fakeMetrics := &fakeWatchMetrics{disconnected: map[string]*time.Time{}}
+	originalRestarts, originalDisconnected := metrics.WatchRestarts, metrics.WatchDisconnected
+	t.Cleanup(func() { metrics.WatchRestarts, metrics.WatchDisconnected = originalRestarts, originalDisconnected })
+	metrics.WatchRestarts, metrics.WatchDisconnected = fakeMetrics, fakeMetrics
+
+	var watches int32
+	watcher, err := newRetryWatcher("1", &cache.ListWatch{
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			switch atomic.AddInt32(&watches, 1) {
+			case 1:
+				ch := arrayToChannel([]watch.Event{
+					{Type: watch.Added, Object: makeTestPod("a", "2")},
+				})
+				close(ch)
+				return watch.NewProxyWatcher(ch), nil
+			case 2:
+				return nil, apierrors.NewTooManyRequests("slow down", 0)
+			default:
+				return watch.NewProxyWatcher(make(chan watch.Event)), nil
+			}
+		},
+	}, RetryWatcherOptions{Name: "test", Resource: "pods"}, time.Duration(0))
+	if err != nil {
+		t.Fatalf("failed to create a RetryWatcher: %v", err)
+	}
+	defer func() {
+		// Wait for the watcher to finish before the metrics are restored.
+		watcher.Stop()
+		<-watcher.Done()
+	}()
+
+	if !watcher.LastEventTime().IsZero() {
+		t.Errorf("expected no event time before receiving events")
+	}
+	select {
+	case <-watcher.ResultChan():
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("timed out waiting for event")
+	}
+	if watcher.LastEventTime().IsZero() {
+		t.Errorf("expected event time to be recorded")
+	}
+
+	err = wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		if atomic.LoadInt32(&watches) < 3 {
+			return false, nil
+		}
+		fakeMetrics.lock.Lock()
+		defer fakeMetrics.lock.Unlock()
+		since, ok := fakeMetrics.disconnected["test/pods"]
+		return ok && since == nil, nil
+	})
+	if err != nil {
+		t.Fatalf("expected watch to be re-established: %v", err)
+	}
+	fakeMetrics.lock.Lock()
+	defer fakeMetrics.lock.Unlock()
+	if expected := []string{"test/pods/closed", "test/pods/throttled"}; !reflect.DeepEqual(expected, fakeMetrics.restarts) {
+		t.Errorf("expected restarts %v, got %v", expected, fakeMetrics.restarts)
+	}
+}
+
+func TestRetryWatcherMaxWatchDuration(t *testing.T) {
+	watchRVs := make(chan string, 2)
+	var watches int32
+	watcher, err := newRetryWatcher("1", &cache.ListWatch{
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			if atomic.AddInt32(&watches, 1) > 2 {
+				return watch.NewProxyWatcher(make(chan watch.Event)), nil
+			}
+			watchRVs <- options.ResourceVersion
+			if options.ResourceVersion == "1" {
+				return watch.NewProxyWatcher(arrayToChannel([]watch.Event{
+					{Type: watch.Added, Object: makeTestPod("a", "2")},
+				})), nil
+			}
+			return watch.NewProxyWatcher(make(chan watch.Event)), nil
+		},
+	}, RetryWatcherOptions{MaxWatchDuration: 50 * time.Millisecond}, time.Duration(0))
+	if err != nil {
+		t.Fatalf("failed to create a RetryWatcher: %v", err)
+	}
+	defer watcher.Stop()
+
+	select {
+	case <-watcher.ResultChan():
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("timed out waiting for event")
+	}
+	var got []string
+	for len(got) < 2 {
+		select {
+		case rv := <-watchRVs:
+			got = append(got, rv)
+		case <-time.After(wait.ForeverTestTimeout):
+			t.Fatalf("timed out waiting for the watch to be re-established, got %v", got)
+		}
+	}
+	if expected := []string{"1", "2"}; !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected watches from %v, got %v", expected, got)
+	}
+}
+
+func TestRetryWatcherDropsStaleEvents(t *testing.T) {
+	var watches int32
+	watcher, err := newRetryWatcher("1", &cache.ListWatch{
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			if atomic.AddInt32(&watches, 1) > 1 {
+				return watch.NewProxyWatcher(make(chan watch.Event)), nil
+			}
+			return watch.NewProxyWatcher(arrayToChannel([]watch.Event{
+				{Type: watch.Added, Object: makeTestPod("a", "2")},
+				{Type: watch.Modified, Object: makeTestPod("a", "2")},
+				{Type: watch.Added, Object: makeTestPod("stale", "1")},
+				{Type: watch.Modified, Object: makeTestPod("a", "3")},
+			})), nil
+		},
+	}, RetryWatcherOptions{ResourceVersionComparator: cache.IntegerResourceVersions}, time.Duration(0))
+	if err != nil {
+		t.Fatalf("failed to create a RetryWatcher: %v", err)
+	}
+	defer watcher.Stop()
+
+	var got []string
+	for len(got) < 2 {
+		select {
+		case event := <-watcher.ResultChan():
+			pod := event.Object.(*corev1.Pod)
+			got = append(got, fmt.Sprintf("%s %s@%s", event.Type, pod.Name, pod.ResourceVersion))
+		case <-time.After(wait.ForeverTestTimeout):
+			t.Fatalf("timed out waiting for events, got %v", got)
+		}
+	}
+	if expected := []string{"ADDED a@2", "MODIFIED a@3"}; !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected events %v, got %v", expected, got)
+	}
+}
+
+func TestCheckpointResourceVersionStore(t *testing.T) {
+	checkpoints := pager.NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint"))
+	store := NewCheckpointResourceVersionStore(checkpoints)
+
+	if err := checkpoints.Save(pager.Checkpoint{Continue: "token", ResourceVersion: "10"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(); err == nil {
+		t.Errorf("expected an error while the list has not completed")
+	}
+
+	if err := store.Set("42"); err != nil {
+		t.Fatal(err)
+	}
+	if rv, err := store.Get(); err != nil || rv != "42" {
+		t.Errorf("expected resourceVersion %q, got %q, %v", "42", rv, err)
+	}
+	if checkpoint, _ := checkpoints.Load(); checkpoint != (pager.Checkpoint{ResourceVersion: "42", ListComplete: true}) {
+		t.Errorf("unexpected checkpoint %#v", checkpoint)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+)
+
+// WatchSingleObject watches the object of resource gvr with the given
+// namespace, empty for cluster scoped resources, and name, until ctx is done
+// or the watch is stopped.
+//
+// The current state of the object is first delivered as an Added event if
+// it exists, then the watch is restricted to the object with a metadata.name
+// field selector. Both the list and the watch use the field selector, and the
+// watch starts from the resourceVersion of the list, which is the current one
+// of the collection rather than the older one of the object. Like a
+// RetryWatcher, the watch is restarted from the last seen resourceVersion when
+// it ends, and the current state is listed again when the resourceVersion
+// expired, which is cheaper than watching the whole collection. A deletion of
+// the object while the resourceVersion was expired is not reported.
+func WatchSingleObject(ctx context.Context, client dynamic.Interface, gvr schema.GroupVersionResource, namespace, name string) (watch.Interface, error) {
+	resource := client.Resource(gvr).Namespace(namespace)
+	selector := fields.OneTermEqualSelector("metadata.name", name).String()
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = selector
+			return resource.List(ctx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = selector
+			return resource.Watch(ctx, options)
+		},
+	}
+	rw, err := NewRetryWatcherWithOptions("", lw, RetryWatcherOptions{
+		Lister: lw,
+		Name:   "WatchSingleObject",
+	})
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			rw.Stop()
+		case <-rw.Done():
+		}
+	}()
+	return rw, nil
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func singleObjectTestConfigMap(name, resourceVersion string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("ConfigMap")
+	obj.SetNamespace("ns")
+	obj.SetName(name)
+	obj.SetResourceVersion(resourceVersion)
+	return obj
+}
+
+func nextSingleObjectEvent(t *testing.T, w watch.Interface) watch.Event {
+	t.Helper()
+	select {
+	case event, ok := <-w.ResultChan():
+		if !ok {
+			t.Fatalf("unexpected end of the watch")
+		}
+		return event
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatalf("timed out waiting for an event")
+	}
+	return watch.Event{}
+}
+
+func TestWatchSingleObject(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	listKinds := map[schema.GroupVersionResource]string{gvr: "ConfigMapList"}
+
+	for _, tc := range []struct {
+		name                    string
+		existing                []runtime.Object
+		expectedResourceVersion string
+	}{
+		{
+			name:                    "existing object",
+			existing:                []runtime.Object{singleObjectTestConfigMap("foo", "1")},
+			expectedResourceVersion: "5",
+		},
+		{
+			name:                    "missing object",
+			expectedResourceVersion: "5",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, tc.existing...)
+			var listSelector string
+			client.PrependReactor("list", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				listSelector = action.(clienttesting.ListAction).GetListRestrictions().Fields.String()
+				list := &unstructured.UnstructuredList{}
+				for _, obj := range tc.existing {
+					list.Items = append(list.Items, *obj.(*unstructured.Unstructured))
+				}
+				list.SetResourceVersion("5")
+				return true, list, nil
+			})
+			fakeWatcher := watch.NewFake()
+			watches := make(chan clienttesting.WatchActionImpl, 1)
+			client.PrependWatchReactor("configmaps", func(action clienttesting.Action) (bool, watch.Interface, error) {
+				watches <- action.(clienttesting.WatchActionImpl)
+				return true, fakeWatcher, nil
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			w, err := WatchSingleObject(ctx, client, gvr, "ns", "foo")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(tc.existing) > 0 {
+				if event := nextSingleObjectEvent(t, w); event.Type != watch.Added || event.Object.(*unstructured.Unstructured).GetName() != "foo" {
+					t.Fatalf("expected the object to be added, got %#v", event)
+				}
+			}
+			select {
+			case action := <-watches:
+				restrictions := action.GetWatchRestrictions()
+				if restrictions.Fields.String() != "metadata.name=foo" || restrictions.ResourceVersion != tc.expectedResourceVersion {
+					t.Errorf("unexpected watch restrictions %#v", restrictions)
+				}
+			case <-time.After(wait.ForeverTestTimeout):
+				t.Fatalf("timed out waiting for the watch")
+			}
+			if listSelector != "metadata.name=foo" {
+				t.Errorf("expected a list of the object, got selector %q", listSelector)
+			}
+
+			fakeWatcher.Modify(singleObjectTestConfigMap("foo", "6"))
+			if event := nextSingleObjectEvent(t, w); event.Type != watch.Modified || event.Object.(*unstructured.Unstructured).GetResourceVersion() != "6" {
+				t.Fatalf("expected the object to be modified, got %#v", event)
+			}
+
+			cancel()
+			select {
+			case _, ok := <-w.ResultChan():
+				if ok {
+					t.Errorf("expected the watch to end")
+				}
+			case <-time.After(wait.ForeverTestTimeout):
+				t.Fatalf("timed out waiting for the watch to end")
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// TypedWatcher wraps a watch.Interface and only delivers on ResultChan the
+// events whose object has the type of the expected object, so that consumers
+// can type-assert them without checking. Error events and objects of any other
+// type are delivered on Errors instead. Both channels must be consumed until
+// they are closed or Stop is called.
+type TypedWatcher struct {
+	watcher      watch.Interface
+	expectedType reflect.Type
+	result       chan watch.Event
+	errors       chan error
+	stopOnce     sync.Once
+	stopCh       chan struct{}
+}
+
+// Typed returns a TypedWatcher delivering the events of w whose object has the
+// type of expectedType, for example &corev1.Pod{}.
+func Typed(w watch.Interface, expectedType runtime.Object) *TypedWatcher {
+	tw := &TypedWatcher{
+		watcher:      w,
+		expectedType: reflect.TypeOf(expectedType),
+		result:       make(chan watch.Event),
+		errors:       make(chan error),
+		stopCh:       make(chan struct{}),
+	}
+	go tw.receive()
+	return tw
+}
+
+func (tw *TypedWatcher) receive() {
+	defer close(tw.errors)
+	defer close(tw.result)
+	defer tw.watcher.Stop()
+
+	ch := tw.watcher.ResultChan()
+	for {
+		select {
+		case <-tw.stopCh:
+			return
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := checkEventType(event, tw.expectedType); err != nil {
+				select {
+				case tw.errors <- err:
+				case <-tw.stopCh:
+					return
+				}
+				continue
+			}
+			select {
+			case tw.result <- event:
+			case <-tw.stopCh:
+				return
+			}
+		}
+	}
+}
+
+// ResultChan returns the channel of the events of the expected type.
+func (tw *TypedWatcher) ResultChan() <-chan watch.Event {
+	return tw.result
+}
+
+// Errors returns the channel of errors received from the watch.
+func (tw *TypedWatcher) Errors() <-chan error {
+	return tw.errors
+}
+
+// Stop stops the underlying watch. It is safe to call Stop more than once.
+func (tw *TypedWatcher) Stop() {
+	tw.stopOnce.Do(func() { close(tw.stopCh) })
+}
+
+// ForEach calls fn for every event of w until fn reports that it is done,
+// fn or the watch returns an error, the watch is closed or ctx is done. The
+// objects passed to fn have the type of expectedType, events of any other type
+// ending ForEach with an error. Bookmark events are passed to fn like any
+// other event. ForEach stops w before returning and returns ErrWatchClosed if
+// the watch was closed.
+func ForEach(ctx context.Context, w watch.Interface, expectedType runtime.Object, fn func(watch.EventType, runtime.Object) (done bool, err error)) error {
+	defer w.Stop()
+
+	t := reflect.TypeOf(expectedType)
+	ch := w.ResultChan()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-ch:
+			if !ok {
+				return ErrWatchClosed
+			}
+			if err := checkEventType(event, t); err != nil {
+				return err
+			}
+			done, err := fn(event.Type, event.Object)
+			if err != nil {
+				return err
+			}
+			if done {
+				return nil
+			}
+		}
+	}
+}
+
+// checkEventType returns an error for error events and events whose object is
+// not of type t.
+func checkEventType(event watch.Event, t reflect.Type) error {
+	if event.Type == watch.Error {
+		return apierrors.FromObject(event.Object)
+	}
+	if reflect.TypeOf(event.Object) != t {
+		return fmt.Errorf("unexpected object type in %s event: expected %v, got %T", event.Type, t, event.Object)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestTyped(t *testing.T) {
+	fw := watch.NewFake()
+	tw := Typed(fw, &corev1.Pod{})
+	defer tw.Stop()
+
+	go func() {
+		fw.Add(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "a"}})
+		fw.Add(&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "b"}})
+		fw.Modify(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "c"}})
+		fw.Stop()
+	}()
+
+	var names []string
+	var errs []error
+loop:
+	for {
+		select {
+		case event, ok := <-tw.ResultChan():
+			if !ok {
+				break loop
+			}
+			names = append(names, string(event.Type)+"/"+event.Object.(*corev1.Pod).Name)
+		case err, ok := <-tw.Errors():
+			if ok {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if len(names) != 2 || names[0] != "ADDED/a" || names[1] != "MODIFIED/c" {
+		t.Errorf("unexpected events: %v", names)
+	}
+	if len(errs) != 1 {
+		t.Errorf("expected one error for the unexpected object type, got %v", errs)
+	}
+}
+
+func TestForEach(t *testing.T) {
+	fw := watch.NewFakeWithChanSize(3, false)
+	fw.Add(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "a"}})
+	fw.Add(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "b"}})
+	fw.Add(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "c"}})
+
+	var names []string
+	err := ForEach(context.Background(), fw, &corev1.Pod{}, func(eventType watch.EventType, obj runtime.Object) (bool, error) {
+		pod := obj.(*corev1.Pod)
+		names = append(names, pod.Name)
+		return pod.Name == "b", nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(names) != 2 {
+		t.Errorf("expected ForEach to stop after b, got %v", names)
+	}
+	if !fw.IsStopped() {
+		t.Errorf("expected watch to be stopped")
+	}
+
+	fw = watch.NewFakeWithChanSize(1, false)
+	fw.Error(&apierrors.NewResourceExpired("too old").ErrStatus)
+	err = ForEach(context.Background(), fw, &corev1.Pod{}, func(watch.EventType, runtime.Object) (bool, error) {
+		t.Errorf("unexpected call for error event")
+		return false, nil
+	})
+	if !apierrors.IsResourceExpired(err) {
+		t.Errorf("expected resource expired error, got %v", err)
+	}
+
+	fw = watch.NewFake()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := ForEach(ctx, fw, &corev1.Pod{}, func(watch.EventType, runtime.Object) (bool, error) { return false, nil }); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
@@ -26,6 +26,7 @@ import (
 
 	utilnet "k8s.io/apimachinery/pkg/util/net"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/clock"
 )
 
 // TlsTransportCache caches TLS http.RoundTrippers different configurations. The
@@ -33,23 +34,24 @@ import (
 // the config has no custom TLS options, http.DefaultTransport is returned.
 type tlsTransportCache struct {
 	mu         sync.Mutex
-	transports map[tlsCacheKey]*http.Transport
+	transports map[tlsCacheKey]http.RoundTripper
 }
 
 const idleConnsPerHost = 25
 
-var tlsCache = &tlsTransportCache{transports: make(map[tlsCacheKey]*http.Transport)}
+var tlsCache = &tlsTransportCache{transports: make(map[tlsCacheKey]http.RoundTripper)}
 
 type tlsCacheKey struct {
-	insecure           bool
-	caData             string
-	certData           string
-	keyData            string `datapolicy:"security-key"`
-	certFile           string
-	keyFile            string
-	serverName         string
-	nextProtos         string
-	disableCompression bool
+	insecure              bool
+	caData                string
+	certData              string
+	keyData               string `datapolicy:"security-key"`
+	certFile              string
+	keyFile               string
+	serverName            string
+	nextProtos            string
+	disableCompression    bool
+	maxConnectionLifetime time.Duration
 }
 
 func (t tlsCacheKey) String() string {
@@ -57,7 +59,7 @@ func (t tlsCacheKey) String() string {
 	if len(t.keyData) > 0 {
 		keyText = "<redacted>"
 	}
-	return fmt.Sprintf("insecure:%v, caData:%#v, certData:%#v, keyData:%s, serverName:%s, disableCompression:%t", t.insecure, t.caData, t.certData, keyText, t.serverName, t.disableCompression)
+	return fmt.Sprintf("insecure:%v, caData:%#v, certData:%#v, keyData:%s, serverName:%s, disableCompression:%t, maxConnectionLifetime:%s", t.insecure, t.caData, t.certData, keyText, t.serverName, t.disableCompression, t.maxConnectionLifetime)
 }
 
 func (c *tlsTransportCache) get(config *Config) (http.RoundTripper, error) {
@@ -83,7 +85,7 @@ func (c *tlsTransportCache) get(config *Config) (http.RoundTripper, error) {
 		return nil, err
 	}
 	// The options didn't require a custom TLS config
-	if tlsConfig == nil && config.Dial == nil && config.Proxy == nil {
+	if tlsConfig == nil && config.Dial == nil && config.Proxy == nil && config.MaxConnectionLifetime == 0 {
 		return http.DefaultTransport, nil
 	}
 
@@ -109,14 +111,23 @@ func (c *tlsTransportCache) get(config *Config) (http.RoundTripper, error) {
 		proxy = config.Proxy
 	}
 
-	transport := utilnet.SetTransportDefaults(&http.Transport{
-		Proxy:               proxy,
-		TLSHandshakeTimeout: 10 * time.Second,
-		TLSClientConfig:     tlsConfig,
-		MaxIdleConnsPerHost: idleConnsPerHost,
-		DialContext:         dial,
-		DisableCompression:  config.DisableCompression,
-	})
+	newTransport := func() *http.Transport {
+		return utilnet.SetTransportDefaults(&http.Transport{
+			Proxy:               proxy,
+			TLSHandshakeTimeout: 10 * time.Second,
+			TLSClientConfig:     tlsConfig.Clone(),
+			MaxIdleConnsPerHost: idleConnsPerHost,
+			DialContext:         dial,
+			DisableCompression:  config.DisableCompression,
+		})
+	}
+
+	var transport http.RoundTripper
+	if config.MaxConnectionLifetime > 0 {
+		transport = newConnectionRecycler(newTransport, config.MaxConnectionLifetime, clock.RealClock{})
+	} else {
+		transport = newTransport()
+	}
 
 	if canCache {
 		// Cache a single transport for these options
@@ -139,11 +150,12 @@ func tlsConfigKey(c *Config) (tlsCacheKey, bool, error) {
 	}
 
 	k := tlsCacheKey{
-		insecure:           c.TLS.Insecure,
-		caData:             string(c.TLS.CAData),
-		serverName:         c.TLS.ServerName,
-		nextProtos:         strings.Join(c.TLS.NextProtos, ","),
-		disableCompression: c.DisableCompression,
+		insecure:              c.TLS.Insecure,
+		caData:                string(c.TLS.CAData),
+		serverName:            c.TLS.ServerName,
+		nextProtos:            strings.Join(c.TLS.NextProtos, ","),
+		disableCompression:    c.DisableCompression,
+		maxConnectionLifetime: c.MaxConnectionLifetime,
 	}
 
 	if c.TLS.ReloadTLSFiles {
@@ -156,3 +168,74 @@ func tlsConfigKey(c *Config) (tlsCacheKey, bool, error) {
 
 	return k, true, nil
 }
+
+// connectionRecycler is an http.RoundTripper that periodically replaces its
+// underlying http.Transport so that no new request is sent over a connection
+// older than maxLifetime. Requests that are in flight when the transport is
+// replaced complete on their existing connection, which is then closed once
+// it becomes idle. This rebalances long-lived HTTP/2 connections across
+// apiservers behind a load balancer.
+type connectionRecycler struct {
+	newTransport func() *http.Transport
+	maxLifetime  time.Duration
+	clock        clock.PassiveClock
+
+	mu       sync.Mutex
+	current  *http.Transport
+	previous *http.Transport
+	created  time.Time
+}
+
+var _ utilnet.RoundTripperWrapper = &connectionRecycler{}
+
+func newConnectionRecycler(newTransport func() *http.Transport, maxLifetime time.Duration, clock clock.PassiveClock) *connectionRecycler {
+	return &connectionRecycler{
+		newTransport: newTransport,
+		maxLifetime:  maxLifetime,
+		clock:        clock,
+		current:      newTransport(),
+		created:      clock.Now(),
+	}
+}
+
+// transport returns the http.Transport to use for a new request, replacing
+// the current one if it has exceeded its lifetime.
+func (r *connectionRecycler) transport() *http.Transport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.clock.Since(r.created) < r.maxLifetime {
+		return r.current
+	}
+	if r.previous != nil {
+		r.previous.CloseIdleConnections()
+	}
+	r.previous = r.current
+	r.current = r.newTransport()
+	r.created = r.clock.Now()
+	r.previous.CloseIdleConnections()
+	return r.current
+}
+
+func (r *connectionRecycler) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r.transport().RoundTrip(req)
+}
+
+// CloseIdleConnections closes idle connections of the current and the
+// previous transport.
+func (r *connectionRecycler) CloseIdleConnections() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.current.CloseIdleConnections()
+	if r.previous != nil {
+		r.previous.CloseIdleConnections()
+	}
+}
+
+// WrappedRoundTripper returns the current transport. Unlike RoundTrip, it
+// never replaces it, so that inspecting the chain of round trippers has no
+// side effect.
+func (r *connectionRecycler) WrappedRoundTripper() http.RoundTripper {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current
+}
@@ -22,6 +22,9 @@ import (
 	"net"
 	"net/http"
 	"testing"
+	"time"
+
+	testingclock "k8s.io/utils/clock/testing"
 )
 
 func TestTLSConfigKey(t *testing.T) {
@@ -136,6 +139,8 @@ func TestTLSConfigKey(t *testing.T) {
 		},
 		"http2, http1.1": {TLS: TLSConfig{NextProtos: []string{"h2", "http/1.1"}}},
 		"http1.1-only":   {TLS: TLSConfig{NextProtos: []string{"http/1.1"}}},
+		"max lifetime":   {MaxConnectionLifetime: time.Minute},
+		"max lifetime 2": {MaxConnectionLifetime: time.Hour},
 	}
 	for nameA, valueA := range uniqueConfigurations {
 		for nameB, valueB := range uniqueConfigurations {
@@ -170,3 +175,47 @@ func TestTLSConfigKey(t *testing.T) {
 		}
 	}
 }
+
+func TestConnectionRecycler(t *testing.T) {
+	fakeClock := testingclock.NewFakePassiveClock(time.Now())
+	created := 0
+	newTransport := func() *http.Transport {
+		created++
+		return &http.Transport{}
+	}
+	r := newConnectionRecycler(newTransport, time.Minute, fakeClock)
+
+	first := r.transport()
+	fakeClock.SetTime(fakeClock.Now().Add(30 * time.Second))
+	if r.transport() != first {
+		t.Errorf("expected transport to be reused before the max lifetime")
+	}
+
+	fakeClock.SetTime(fakeClock.Now().Add(30 * time.Second))
+	if r.WrappedRoundTripper() != first {
+		t.Errorf("expected inspecting the wrapped transport not to replace it")
+	}
+	second := r.transport()
+	if second == first {
+		t.Errorf("expected transport to be replaced after the max lifetime")
+	}
+	if r.previous != first {
+		t.Errorf("expected previous transport to be retained until the next rotation")
+	}
+	if r.transport() != second {
+		t.Errorf("expected new transport to be reused")
+	}
+	if created != 2 {
+		t.Errorf("expected 2 transports to be created, got %d", created)
+	}
+}
+
+func TestMaxConnectionLifetimeTransport(t *testing.T) {
+	rt, err := tlsCache.get(&Config{MaxConnectionLifetime: time.Minute})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := rt.(*connectionRecycler); !ok {
+		t.Errorf("expected *connectionRecycler, got %T", rt)
+	}
+}
@@ -22,6 +22,9 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"time"
+
+	"golang.org/x/oauth2"
 )
 
 // Config holds various options for establishing a transport.
@@ -45,6 +48,14 @@ type Config struct {
 	// The last successfully read value takes precedence over BearerToken.
 	BearerTokenFile string
 
+	// TokenSource supplies bearer tokens for authentication. Tokens are cached
+	// until shortly before they expire and are discarded when the server
+	// responds with 401 Unauthorized. If TokenSource also implements
+	// ContextTokenSource, the request context is used when fetching a token so
+	// that cancelled requests do not wait for a token to be issued.
+	// TokenSource may not be combined with BearerToken or BearerTokenFile.
+	TokenSource oauth2.TokenSource
+
 	// Impersonate is the config that this Config will impersonate using
 	Impersonate ImpersonationConfig
 
@@ -76,6 +87,44 @@ type Config struct {
 	//
 	// socks5 proxying does not currently support spdy streaming endpoints.
 	Proxy func(*http.Request) (*url.URL, error)
+
+	// MaxConnectionLifetime, if non-zero, is the maximum amount of time a
+	// connection is used for new requests. Once exceeded, new requests are
+	// sent over fresh connections while requests in flight, such as watches,
+	// finish on the old ones. This allows clients behind an L4 load balancer
+	// to be spread across apiservers over time.
+	MaxConnectionLifetime time.Duration
+
+	// HTTP3Transport is an EXPERIMENTAL option to send requests over HTTP/3
+	// (QUIC). client-go does not include a QUIC implementation; callers that
+	// opt in supply a function building a round tripper that speaks HTTP/3,
+	// such as one built on quic-go, from the TLS settings of this config.
+	// It cannot be combined with HostOverrides. Requests that fail over
+	// HTTP/3 without a response are retried on the regular transport, and
+	// the host is then contacted over the regular transport only for a
+	// minute. Requests whose body cannot be replayed always use the regular
+	// transport.
+	HTTP3Transport HTTP3TransportFunc
+
+	// HostOverrides holds transport settings that apply only to requests
+	// sent to a particular host. Keys are matched against the request URL
+	// host, including the port if the URL has one (for example
+	// "metrics.example.com:8443"). Requests to any other host use the
+	// settings above.
+	//
+	// Overrides are only honored by transports built with New; they are not
+	// applied to hijacked connections such as spdy streams.
+	HostOverrides map[string]HostOverride
+}
+
+// HostOverride holds the transport settings used for a single host in
+// Config.HostOverrides. Unset fields are inherited from the enclosing Config.
+type HostOverride struct {
+	// TLS, if non-nil, replaces the base TLS configuration for this host.
+	TLS *TLSConfig
+
+	// Proxy, if non-nil, replaces the base proxy func for this host.
+	Proxy func(*http.Request) (*url.URL, error)
 }
 
 // ImpersonationConfig has all the available impersonation options
@@ -102,7 +151,7 @@ func (c *Config) HasBasicAuth() bool {
 
 // HasTokenAuth returns whether the configuration has token authentication or not.
 func (c *Config) HasTokenAuth() bool {
-	return len(c.BearerToken) != 0 || len(c.BearerTokenFile) != 0
+	return len(c.BearerToken) != 0 || len(c.BearerTokenFile) != 0 || c.TokenSource != nil
 }
 
 // HasCertAuth returns whether the configuration has certificate authentication or not.
@@ -0,0 +1,131 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transport
+
+import (
+	"crypto/tls"
+	"net/http"
+	"sync"
+	"time"
+
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/client-go/util/logging"
+	"k8s.io/utils/clock"
+)
+
+// http3FallbackPeriod is how long a host that failed over HTTP/3 is contacted
+// over the fallback transport only.
+const http3FallbackPeriod = time.Minute
+
+// HTTP3TransportFunc builds a round tripper that speaks HTTP/3. tlsConfig
+// carries the CA, client certificate, server name and insecure settings of
+// the transport config; its NextProtos are left empty so the HTTP/3
+// implementation can negotiate its own protocol.
+type HTTP3TransportFunc func(tlsConfig *tls.Config) (http.RoundTripper, error)
+
+// newHTTP3RoundTripper builds the HTTP/3 round tripper of config using the
+// TLS settings of config.
+func newHTTP3RoundTripper(config *Config) (http.RoundTripper, error) {
+	tlsConfig, err := TLSConfigFor(config)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	tlsConfig.NextProtos = nil
+	return config.HTTP3Transport(tlsConfig)
+}
+
+// http3FallbackRoundTripper sends requests over an HTTP/3 round tripper and
+// falls back to the regular (HTTP/2 or HTTP/1.1) transport when the HTTP/3
+// request fails without a response.
+type http3FallbackRoundTripper struct {
+	http3    http.RoundTripper
+	fallback http.RoundTripper
+	clock    clock.PassiveClock
+
+	mu sync.Mutex
+	// failed holds the time HTTP/3 last failed for a host.
+	failed map[string]time.Time
+}
+
+var _ utilnet.RoundTripperWrapper = &http3FallbackRoundTripper{}
+
+func newHTTP3FallbackRoundTripper(http3, fallback http.RoundTripper, clock clock.PassiveClock) *http3FallbackRoundTripper {
+	return &http3FallbackRoundTripper{
+		http3:    http3,
+		fallback: fallback,
+		clock:    clock,
+		failed:   make(map[string]time.Time),
+	}
+}
+
+func (rt *http3FallbackRoundTripper) useHTTP3(host string) bool {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	failed, ok := rt.failed[host]
+	if !ok {
+		return true
+	}
+	if rt.clock.Since(failed) < http3FallbackPeriod {
+		return false
+	}
+	delete(rt.failed, host)
+	return true
+}
+
+func (rt *http3FallbackRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Requests whose body cannot be replayed are never sent over HTTP/3, since
+	// they could not be retried on the fallback transport.
+	if req.URL == nil || req.URL.Scheme != "https" || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+		return rt.fallback.RoundTrip(req)
+	}
+	host := req.URL.Host
+	if !rt.useHTTP3(host) {
+		return rt.fallback.RoundTrip(req)
+	}
+
+	resp, err := rt.http3.RoundTrip(req)
+	if err == nil {
+		return resp, nil
+	}
+	if req.Context().Err() != nil {
+		return nil, err
+	}
+
+	logging.FromContext(req.Context()).V(4).Info("HTTP/3 request failed, falling back", "host", host, "period", http3FallbackPeriod, "err", err)
+	rt.mu.Lock()
+	rt.failed[host] = rt.clock.Now()
+	rt.mu.Unlock()
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = body
+	}
+	return rt.fallback.RoundTrip(req)
+}
+
+func (rt *http3FallbackRoundTripper) CancelRequest(req *http.Request) {
+	tryCancelRequest(rt.fallback, req)
+}
+
+func (rt *http3FallbackRoundTripper) WrappedRoundTripper() http.RoundTripper { return rt.fallback }
@@ -0,0 +1,160 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transport
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+type countingRoundTripper struct {
+	calls int
+	err   error
+	body  string
+}
+
+func (rt *countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.calls++
+	if req.Body != nil {
+		b, _ := ioutil.ReadAll(req.Body)
+		rt.body = string(b)
+	}
+	if rt.err != nil {
+		return nil, rt.err
+	}
+	return &http.Response{StatusCode: http.StatusOK}, nil
+}
+
+func TestHTTP3FallbackRoundTripper(t *testing.T) {
+	fakeClock := testingclock.NewFakePassiveClock(time.Now())
+	h3 := &countingRoundTripper{}
+	h2 := &countingRoundTripper{}
+	rt := newHTTP3FallbackRoundTripper(h3, h2, fakeClock)
+
+	newRequest := func() *http.Request {
+		req, _ := http.NewRequest("POST", "https://example.com/api", bytes.NewBufferString("body"))
+		return req
+	}
+
+	if _, err := rt.RoundTrip(newRequest()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if h3.calls != 1 || h2.calls != 0 {
+		t.Fatalf("expected request over HTTP/3, got h3=%d h2=%d", h3.calls, h2.calls)
+	}
+
+	h3.err = errors.New("no recent network activity")
+	if _, err := rt.RoundTrip(newRequest()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if h3.calls != 2 || h2.calls != 1 {
+		t.Fatalf("expected fallback after HTTP/3 failure, got h3=%d h2=%d", h3.calls, h2.calls)
+	}
+	if h2.body != "body" {
+		t.Errorf("expected request body to be replayed on fallback, got %q", h2.body)
+	}
+
+	if _, err := rt.RoundTrip(newRequest()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if h3.calls != 2 || h2.calls != 2 {
+		t.Fatalf("expected HTTP/3 to be skipped after a failure, got h3=%d h2=%d", h3.calls, h2.calls)
+	}
+
+	h3.err = nil
+	fakeClock.SetTime(fakeClock.Now().Add(http3FallbackPeriod))
+	if _, err := rt.RoundTrip(newRequest()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if h3.calls != 3 || h2.calls != 2 {
+		t.Fatalf("expected HTTP/3 to be retried after the fallback period, got h3=%d h2=%d", h3.calls, h2.calls)
+	}
+
+	plain, _ := http.NewRequest("GET", "http://example.com/api", nil)
+	if _, err := rt.RoundTrip(plain); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if h3.calls != 3 || h2.calls != 3 {
+		t.Fatalf("expected plain HTTP request to skip HTTP/3, got h3=%d h2=%d", h3.calls, h2.calls)
+	}
+}
+
+func TestHTTP3TransportUsesConfigTLS(t *testing.T) {
+	var tlsConfig *tls.Config
+	config := &Config{
+		TLS: TLSConfig{
+			CAData:     []byte(rootCACert),
+			CertData:   []byte(certData),
+			KeyData:    []byte(keyData),
+			ServerName: "apiserver.example.com",
+			NextProtos: []string{"h2", "http/1.1"},
+		},
+		HTTP3Transport: func(c *tls.Config) (http.RoundTripper, error) {
+			tlsConfig = c
+			return &countingRoundTripper{}, nil
+		},
+	}
+	if _, err := New(config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tlsConfig == nil {
+		t.Fatal("expected the HTTP/3 transport to be built")
+	}
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM([]byte(rootCACert))
+	if tlsConfig.RootCAs == nil || !tlsConfig.RootCAs.Equal(roots) {
+		t.Errorf("expected the HTTP/3 transport to trust the configured CA")
+	}
+	if tlsConfig.GetClientCertificate == nil {
+		t.Fatal("expected the HTTP/3 transport to present the client certificate")
+	}
+	cert, err := tlsConfig.GetClientCertificate(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected, _ := tls.X509KeyPair([]byte(certData), []byte(keyData))
+	if len(cert.Certificate) == 0 || !bytes.Equal(cert.Certificate[0], expected.Certificate[0]) {
+		t.Errorf("expected the HTTP/3 transport to present the configured client certificate")
+	}
+	if tlsConfig.ServerName != "apiserver.example.com" {
+		t.Errorf("expected server name to be used, got %q", tlsConfig.ServerName)
+	}
+	if len(tlsConfig.NextProtos) != 0 {
+		t.Errorf("expected NextProtos to be left to the HTTP/3 transport, got %v", tlsConfig.NextProtos)
+	}
+}
+
+func TestHTTP3TransportWithHostOverrides(t *testing.T) {
+	config := &Config{
+		HTTP3Transport: func(*tls.Config) (http.RoundTripper, error) {
+			return &countingRoundTripper{}, nil
+		},
+		HostOverrides: map[string]HostOverride{"metrics.example.com": {}},
+	}
+	if _, err := New(config); err == nil {
+		t.Fatal("expected an error combining HTTP/3 with host overrides")
+	}
+}
@@ -18,17 +18,21 @@ package transport
 
 import (
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptrace"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/go-logr/logr"
 	"golang.org/x/oauth2"
 
 	utilnet "k8s.io/apimachinery/pkg/util/net"
-	"k8s.io/klog/v2"
+	"k8s.io/client-go/util/logging"
 )
 
 // HTTPWrappersForConfig wraps a round tripper with any relevant layered
@@ -47,6 +51,10 @@ func HTTPWrappersForConfig(config *Config, rt http.RoundTripper) (http.RoundTrip
 	switch {
 	case config.HasBasicAuth() && config.HasTokenAuth():
 		return nil, fmt.Errorf("username/password or bearer token may be set, but not both")
+	case config.TokenSource != nil && (len(config.BearerToken) != 0 || len(config.BearerTokenFile) != 0):
+		return nil, fmt.Errorf("bearer token or token source may be set, but not both")
+	case config.TokenSource != nil:
+		rt = NewTokenSourceRoundTripper(config.TokenSource, rt)
 	case config.HasTokenAuth():
 		var err error
 		rt, err = NewBearerAuthWithRefreshRoundTripper(config.BearerToken, config.BearerTokenFile, rt)
@@ -70,14 +78,15 @@ func HTTPWrappersForConfig(config *Config, rt http.RoundTripper) (http.RoundTrip
 
 // DebugWrappers wraps a round tripper and logs based on the current log level.
 func DebugWrappers(rt http.RoundTripper) http.RoundTripper {
+	logger := logging.Logger()
 	switch {
-	case bool(klog.V(9).Enabled()):
+	case logger.V(9).Enabled():
 		rt = NewDebuggingRoundTripper(rt, DebugCurlCommand, DebugDetailedTiming, DebugResponseHeaders)
-	case bool(klog.V(8).Enabled()):
+	case logger.V(8).Enabled():
 		rt = NewDebuggingRoundTripper(rt, DebugJustURL, DebugRequestHeaders, DebugResponseStatus, DebugResponseHeaders)
-	case bool(klog.V(7).Enabled()):
+	case logger.V(7).Enabled():
 		rt = NewDebuggingRoundTripper(rt, DebugJustURL, DebugRequestHeaders, DebugResponseStatus)
-	case bool(klog.V(6).Enabled()):
+	case logger.V(6).Enabled():
 		rt = NewDebuggingRoundTripper(rt, DebugURLTiming)
 	}
 
@@ -430,10 +439,31 @@ var knownAuthTypes = map[string]bool{
 	"negotiate": true,
 }
 
-// maskValue masks credential content from authorization headers
+// bearerProtocolPrefix is the prefix of the websocket subprotocol used to pass
+// a bearer token to the apiserver.
+const bearerProtocolPrefix = "base64url.bearer.authorization.k8s.io."
+
+// maskValue masks credential content from authorization, cookie and websocket
+// protocol headers.
 // See https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Authorization
 func maskValue(key string, value string) string {
-	if !strings.EqualFold(key, "Authorization") {
+	switch {
+	case strings.EqualFold(key, "Authorization"), strings.EqualFold(key, "Proxy-Authorization"):
+	case strings.EqualFold(key, "Cookie"), strings.EqualFold(key, "Set-Cookie"):
+		if len(value) == 0 {
+			return ""
+		}
+		return "<masked>"
+	case strings.EqualFold(key, "Sec-WebSocket-Protocol"):
+		protocols := strings.Split(value, ",")
+		for i, protocol := range protocols {
+			trimmed := strings.TrimSpace(protocol)
+			if strings.HasPrefix(trimmed, bearerProtocolPrefix) {
+				protocols[i] = strings.Replace(protocol, trimmed, bearerProtocolPrefix+"<masked>", 1)
+			}
+		}
+		return strings.Join(protocols, ",")
+	default:
 		return value
 	}
 	if len(value) == 0 {
@@ -458,21 +488,16 @@ func maskValue(key string, value string) string {
 
 func (rt *debuggingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 	reqInfo := newRequestInfo(req)
+	logger := logging.FromContext(req.Context())
 
 	if rt.levels[DebugJustURL] {
-		klog.Infof("%s %s", reqInfo.RequestVerb, reqInfo.RequestURL)
+		logger.Info("Request", "verb", reqInfo.RequestVerb, "url", reqInfo.RequestURL)
 	}
 	if rt.levels[DebugCurlCommand] {
-		klog.Infof("%s", reqInfo.toCurl())
+		logger.Info("Request", "curlCommand", reqInfo.toCurl())
 	}
 	if rt.levels[DebugRequestHeaders] {
-		klog.Info("Request Headers:")
-		for key, values := range reqInfo.RequestHeaders {
-			for _, value := range values {
-				value = maskValue(key, value)
-				klog.Infof("    %s: %s", key, value)
-			}
-		}
+		logger.Info("Request", "headers", maskHeaders(reqInfo.RequestHeaders))
 	}
 
 	startTime := time.Now()
@@ -492,7 +517,7 @@ func (rt *debuggingRoundTripper) RoundTrip(req *http.Request) (*http.Response, e
 				reqInfo.muTrace.Lock()
 				defer reqInfo.muTrace.Unlock()
 				reqInfo.DNSLookup = time.Now().Sub(dnsStart)
-				klog.Infof("HTTP Trace: DNS Lookup for %s resolved to %v", host, info.Addrs)
+				logger.Info("HTTP Trace: DNS Lookup", "host", host, "resolved", info.Addrs)
 			},
 			// Dial
 			ConnectStart: func(network, addr string) {
@@ -505,9 +530,9 @@ func (rt *debuggingRoundTripper) RoundTrip(req *http.Request) (*http.Response, e
 				defer reqInfo.muTrace.Unlock()
 				reqInfo.Dialing = time.Now().Sub(dialStart)
 				if err != nil {
-					klog.Infof("HTTP Trace: Dial to %s:%s failed: %v", network, addr, err)
+					logger.Info("HTTP Trace: Dial failed", "network", network, "address", addr, "err", err)
 				} else {
-					klog.Infof("HTTP Trace: Dial to %s:%s succeed", network, addr)
+					logger.Info("HTTP Trace: Dial succeed", "network", network, "address", addr)
 				}
 			},
 			// TLS
@@ -550,36 +575,31 @@ func (rt *debuggingRoundTripper) RoundTrip(req *http.Request) (*http.Response, e
 	reqInfo.complete(response, err)
 
 	if rt.levels[DebugURLTiming] {
-		klog.Infof("%s %s %s in %d milliseconds", reqInfo.RequestVerb, reqInfo.RequestURL, reqInfo.ResponseStatus, reqInfo.Duration.Nanoseconds()/int64(time.Millisecond))
+		logger.Info("Response", "verb", reqInfo.RequestVerb, "url", reqInfo.RequestURL, "status", reqInfo.ResponseStatus, "milliseconds", reqInfo.Duration.Nanoseconds()/int64(time.Millisecond))
 	}
 	if rt.levels[DebugDetailedTiming] {
-		stats := ""
+		var stats []interface{}
 		if !reqInfo.ConnectionReused {
-			stats += fmt.Sprintf(`DNSLookup %d ms Dial %d ms TLSHandshake %d ms`,
-				reqInfo.DNSLookup.Nanoseconds()/int64(time.Millisecond),
-				reqInfo.Dialing.Nanoseconds()/int64(time.Millisecond),
-				reqInfo.TLSHandshake.Nanoseconds()/int64(time.Millisecond),
+			stats = append(stats,
+				"dnsLookupMilliseconds", reqInfo.DNSLookup.Nanoseconds()/int64(time.Millisecond),
+				"dialMilliseconds", reqInfo.Dialing.Nanoseconds()/int64(time.Millisecond),
+				"tlsHandshakeMilliseconds", reqInfo.TLSHandshake.Nanoseconds()/int64(time.Millisecond),
 			)
 		} else {
-			stats += fmt.Sprintf(`GetConnection %d ms`, reqInfo.GetConnection.Nanoseconds()/int64(time.Millisecond))
+			stats = append(stats, "getConnectionMilliseconds", reqInfo.GetConnection.Nanoseconds()/int64(time.Millisecond))
 		}
 		if reqInfo.ServerProcessing != 0 {
-			stats += fmt.Sprintf(` ServerProcessing %d ms`, reqInfo.ServerProcessing.Nanoseconds()/int64(time.Millisecond))
+			stats = append(stats, "serverProcessingMilliseconds", reqInfo.ServerProcessing.Nanoseconds()/int64(time.Millisecond))
 		}
-		stats += fmt.Sprintf(` Duration %d ms`, reqInfo.Duration.Nanoseconds()/int64(time.Millisecond))
-		klog.Infof("HTTP Statistics: %s", stats)
+		stats = append(stats, "durationMilliseconds", reqInfo.Duration.Nanoseconds()/int64(time.Millisecond))
+		logger.Info("HTTP Statistics", stats...)
 	}
 
 	if rt.levels[DebugResponseStatus] {
-		klog.Infof("Response Status: %s in %d milliseconds", reqInfo.ResponseStatus, reqInfo.Duration.Nanoseconds()/int64(time.Millisecond))
+		logger.Info("Response", "status", reqInfo.ResponseStatus, "milliseconds", reqInfo.Duration.Nanoseconds()/int64(time.Millisecond))
 	}
 	if rt.levels[DebugResponseHeaders] {
-		klog.Info("Response Headers:")
-		for key, values := range reqInfo.ResponseHeaders {
-			for _, value := range values {
-				klog.Infof("    %s: %s", key, value)
-			}
-		}
+		logger.Info("Response", "headers", maskHeaders(reqInfo.ResponseHeaders))
 	}
 
 	return response, err
@@ -589,6 +609,150 @@ func (rt *debuggingRoundTripper) WrappedRoundTripper() http.RoundTripper {
 	return rt.delegatedRoundTripper
 }
 
+// DebugRecord is a structured description of a single request, emitted by
+// the round tripper returned from NewStructuredDebuggingRoundTripper.
+// Credentials in headers are masked.
+type DebugRecord struct {
+	Verb            string        `json:"verb"`
+	URL             string        `json:"url"`
+	RequestHeaders  http.Header   `json:"requestHeaders,omitempty"`
+	StatusCode      int           `json:"statusCode,omitempty"`
+	ResponseHeaders http.Header   `json:"responseHeaders,omitempty"`
+	Error           string        `json:"error,omitempty"`
+	Latency         time.Duration `json:"latency"`
+}
+
+// DebugRecordSink receives the records emitted by the round tripper returned
+// from NewStructuredDebuggingRoundTripper. It may be called concurrently.
+type DebugRecordSink func(record *DebugRecord)
+
+// StructuredDebugOptions configures NewStructuredDebuggingRoundTripper.
+type StructuredDebugOptions struct {
+	// Headers adds the masked request and response headers to each record.
+	Headers bool
+	// SampleEvery records one out of every SampleEvery requests. Values less
+	// than or equal to 1 record every request.
+	SampleEvery int
+	// Sink receives the records. If nil, records are logged with the logger
+	// of the context of the requests.
+	Sink DebugRecordSink
+}
+
+// NewStructuredDebuggingRoundTripper returns a round tripper that emits one
+// structured record per request to the configured sink. Unlike
+// NewDebuggingRoundTripper it does not log free-form text, and with sampling
+// enabled it is suitable for use in production.
+func NewStructuredDebuggingRoundTripper(rt http.RoundTripper, opts StructuredDebugOptions) http.RoundTripper {
+	return &structuredDebuggingRoundTripper{
+		delegatedRoundTripper: rt,
+		opts:                  opts,
+	}
+}
+
+// JSONDebugRecordSink returns a DebugRecordSink that writes each record to w as
+// a line of JSON.
+func JSONDebugRecordSink(w io.Writer) DebugRecordSink {
+	var mu sync.Mutex
+	encoder := json.NewEncoder(w)
+	return func(record *DebugRecord) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err := encoder.Encode(record); err != nil {
+			logging.Logger().V(4).Info("Unable to write HTTP debug record", "err", err)
+		}
+	}
+}
+
+// logDebugRecord logs record with logger.
+func logDebugRecord(logger logr.Logger, record *DebugRecord) {
+	keysAndValues := []interface{}{"verb", record.Verb, "url", record.URL, "latency", record.Latency}
+	if record.StatusCode != 0 {
+		keysAndValues = append(keysAndValues, "statusCode", record.StatusCode)
+	}
+	if record.Error != "" {
+		keysAndValues = append(keysAndValues, "err", record.Error)
+	}
+	if record.RequestHeaders != nil {
+		keysAndValues = append(keysAndValues, "requestHeaders", record.RequestHeaders)
+	}
+	if record.ResponseHeaders != nil {
+		keysAndValues = append(keysAndValues, "responseHeaders", record.ResponseHeaders)
+	}
+	logger.Info("HTTP request", keysAndValues...)
+}
+
+type structuredDebuggingRoundTripper struct {
+	// count is accessed atomically and kept first for 64-bit alignment.
+	count uint64
+
+	delegatedRoundTripper http.RoundTripper
+	opts                  StructuredDebugOptions
+}
+
+var _ utilnet.RoundTripperWrapper = &structuredDebuggingRoundTripper{}
+
+func (rt *structuredDebuggingRoundTripper) sampled() bool {
+	if rt.opts.SampleEvery <= 1 {
+		return true
+	}
+	return (atomic.AddUint64(&rt.count, 1)-1)%uint64(rt.opts.SampleEvery) == 0
+}
+
+func (rt *structuredDebuggingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !rt.sampled() {
+		return rt.delegatedRoundTripper.RoundTrip(req)
+	}
+
+	record := &DebugRecord{
+		Verb: req.Method,
+		URL:  req.URL.String(),
+	}
+	if rt.opts.Headers {
+		record.RequestHeaders = maskHeaders(req.Header)
+	}
+
+	start := time.Now()
+	response, err := rt.delegatedRoundTripper.RoundTrip(req)
+	record.Latency = time.Since(start)
+
+	if err != nil {
+		record.Error = err.Error()
+	} else {
+		record.StatusCode = response.StatusCode
+		if rt.opts.Headers {
+			record.ResponseHeaders = maskHeaders(response.Header)
+		}
+	}
+	if rt.opts.Sink != nil {
+		rt.opts.Sink(record)
+	} else {
+		logDebugRecord(logging.FromContext(req.Context()), record)
+	}
+
+	return response, err
+}
+
+func (rt *structuredDebuggingRoundTripper) CancelRequest(req *http.Request) {
+	tryCancelRequest(rt.WrappedRoundTripper(), req)
+}
+
+func (rt *structuredDebuggingRoundTripper) WrappedRoundTripper() http.RoundTripper {
+	return rt.delegatedRoundTripper
+}
+
+// maskHeaders returns a copy of the headers with credentials masked.
+func maskHeaders(headers http.Header) http.Header {
+	masked := make(http.Header, len(headers))
+	for key, values := range headers {
+		maskedValues := make([]string, len(values))
+		for i, value := range values {
+			maskedValues[i] = maskValue(key, value)
+		}
+		masked[key] = maskedValues
+	}
+	return masked
+}
+
 func legalHeaderByte(b byte) bool {
 	return int(b) < len(legalHeaderKeyBytes) && legalHeaderKeyBytes[b]
 }
@@ -18,6 +18,8 @@ package transport
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -25,6 +27,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/go-logr/logr/funcr"
+
+	"k8s.io/client-go/util/logging"
 	"k8s.io/klog/v2"
 )
 
@@ -115,6 +120,26 @@ func TestMaskValue(t *testing.T) {
 			value:    "",
 			expected: "",
 		},
+		{
+			key:      "Proxy-Authorization",
+			value:    "Basic YWxhZGRpbjpvcGVuc2VzYW1l",
+			expected: "Basic <masked>",
+		},
+		{
+			key:      "Cookie",
+			value:    "session=cn389ncoiwuencr",
+			expected: "<masked>",
+		},
+		{
+			key:      "Set-Cookie",
+			value:    "session=cn389ncoiwuencr; Secure",
+			expected: "<masked>",
+		},
+		{
+			key:      "Sec-WebSocket-Protocol",
+			value:    "v4.channel.k8s.io, base64url.bearer.authorization.k8s.io.cn389ncoiwuencr",
+			expected: "v4.channel.k8s.io, base64url.bearer.authorization.k8s.io.<masked>",
+		},
 	}
 	for _, tc := range tcs {
 		maskedValue := maskValue(tc.key, tc.value)
@@ -471,46 +496,27 @@ func TestDebuggingRoundTripper(t *testing.T) {
 	}{
 		{
 			levels:              []DebugLevel{DebugJustURL},
-			expectedOutputLines: []string{fmt.Sprintf("%s %s", req.Method, rawURL)},
-		},
-		{
-			levels: []DebugLevel{DebugRequestHeaders},
-			expectedOutputLines: func() []string {
-				lines := []string{fmt.Sprintf("Request Headers:\n")}
-				for key, values := range req.Header {
-					for _, value := range values {
-						if key == "Authorization" {
-							value = "bearer <masked>"
-						}
-						lines = append(lines, fmt.Sprintf("    %s: %s\n", key, value))
-					}
-				}
-				return lines
-			}(),
-		},
-		{
-			levels: []DebugLevel{DebugResponseHeaders},
-			expectedOutputLines: func() []string {
-				lines := []string{fmt.Sprintf("Response Headers:\n")}
-				for key, values := range res.Header {
-					for _, value := range values {
-						lines = append(lines, fmt.Sprintf("    %s: %s\n", key, value))
-					}
-				}
-				return lines
-			}(),
+			expectedOutputLines: []string{fmt.Sprintf(`"Request" verb=%q url=%q`, req.Method, rawURL)},
+		},
+		{
+			levels:              []DebugLevel{DebugRequestHeaders},
+			expectedOutputLines: []string{`"Request" headers=map[Authorization:[bearer <masked>] X-Test-Request:[test]]`},
+		},
+		{
+			levels:              []DebugLevel{DebugResponseHeaders},
+			expectedOutputLines: []string{`"Response" headers=map[X-Test-Response:[test]]`},
 		},
 		{
 			levels:              []DebugLevel{DebugURLTiming},
-			expectedOutputLines: []string{fmt.Sprintf("%s %s %s", req.Method, rawURL, res.Status)},
+			expectedOutputLines: []string{fmt.Sprintf(`"Response" verb=%q url=%q status=%q`, req.Method, rawURL, res.Status)},
 		},
 		{
 			levels:              []DebugLevel{DebugResponseStatus},
-			expectedOutputLines: []string{fmt.Sprintf("Response Status: %s", res.Status)},
+			expectedOutputLines: []string{fmt.Sprintf(`"Response" status=%q`, res.Status)},
 		},
 		{
 			levels:              []DebugLevel{DebugCurlCommand},
-			expectedOutputLines: []string{fmt.Sprintf("curl -v -X")},
+			expectedOutputLines: []string{`"Request" curlCommand="curl -v -X`},
 		},
 	}
 
@@ -545,3 +551,78 @@ func TestDebuggingRoundTripper(t *testing.T) {
 		}
 	}
 }
+
+func TestDebuggingRoundTripperContextLogger(t *testing.T) {
+	var lines []string
+	logger := funcr.New(func(prefix, args string) {
+		lines = append(lines, args)
+	}, funcr.Options{})
+	req, err := http.NewRequestWithContext(logging.NewContext(context.Background(), logger), http.MethodGet, "https://127.0.0.1:12345/api/v1/pods", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt := &testRoundTripper{Response: &http.Response{Status: "OK", StatusCode: http.StatusOK}}
+
+	NewDebuggingRoundTripper(rt, DebugJustURL).RoundTrip(req)
+	NewStructuredDebuggingRoundTripper(rt, StructuredDebugOptions{}).RoundTrip(req)
+
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines logged with the logger of the request, got %q", lines)
+	}
+	if expected := `"msg"="Request" "verb"="GET" "url"="https://127.0.0.1:12345/api/v1/pods"`; !strings.Contains(lines[0], expected) {
+		t.Errorf("%q does not contain expected output %q", lines[0], expected)
+	}
+	if expected := `"msg"="HTTP request" "verb"="GET"`; !strings.Contains(lines[1], expected) {
+		t.Errorf("%q does not contain expected output %q", lines[1], expected)
+	}
+}
+
+func TestStructuredDebuggingRoundTripper(t *testing.T) {
+	req := &http.Request{
+		Method: http.MethodGet,
+		URL:    &url.URL{Scheme: "https", Host: "127.0.0.1:12345", Path: "/api/v1/pods"},
+		Header: map[string][]string{
+			"Authorization":  {"bearer secretauthtoken"},
+			"X-Test-Request": {"test"},
+		},
+	}
+	res := &http.Response{
+		StatusCode: http.StatusOK,
+		Header: map[string][]string{
+			"Set-Cookie": {"session=secret"},
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	rt := NewStructuredDebuggingRoundTripper(&testRoundTripper{Response: res}, StructuredDebugOptions{
+		Headers:     true,
+		SampleEvery: 2,
+		Sink:        JSONDebugRecordSink(buf),
+	})
+	for i := 0; i < 4; i++ {
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 sampled records, got %d: %q", len(lines), buf.String())
+	}
+	if strings.Contains(buf.String(), "secret") {
+		t.Errorf("expected credentials to be masked, got %q", buf.String())
+	}
+	record := &DebugRecord{}
+	if err := json.Unmarshal([]byte(lines[0]), record); err != nil {
+		t.Fatalf("unexpected error decoding record: %v", err)
+	}
+	if record.Verb != http.MethodGet || record.URL != req.URL.String() || record.StatusCode != http.StatusOK {
+		t.Errorf("unexpected record: %#v", record)
+	}
+	if got := record.RequestHeaders.Get("Authorization"); got != "bearer <masked>" {
+		t.Errorf("expected masked authorization header, got %q", got)
+	}
+	if got := record.RequestHeaders.Get("X-Test-Request"); got != "test" {
+		t.Errorf("expected unmasked header to be preserved, got %q", got)
+	}
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spdytest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/apimachinery/pkg/util/httpstream/spdy"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	spdytransport "k8s.io/client-go/transport/spdy"
+)
+
+// listener accepts the in-memory connections of round trippers.
+type listener struct {
+	conns     chan net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+var _ net.Listener = &listener{}
+
+func newListener() *listener {
+	return &listener{
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+}
+
+// dial returns the client end of a new connection to the listener.
+func (l *listener) dial() (net.Conn, error) {
+	client, server := net.Pipe()
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.done:
+		return nil, fmt.Errorf("server is closed")
+	}
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, fmt.Errorf("listener is closed")
+	}
+}
+
+func (l *listener) Close() error {
+	l.closeOnce.Do(func() { close(l.done) })
+	return nil
+}
+
+func (l *listener) Addr() net.Addr {
+	return addr{}
+}
+
+type addr struct{}
+
+func (addr) Network() string { return "memory" }
+func (addr) String() string  { return "memory" }
+
+// roundTripper sends upgrade requests to a server over in-memory
+// connections, like spdy.SpdyRoundTripper does over the network.
+type roundTripper struct {
+	listener *listener
+	conn     net.Conn
+}
+
+var _ http.RoundTripper = &roundTripper{}
+var _ spdytransport.Upgrader = &roundTripper{}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	conn, err := rt.listener.dial()
+	if err != nil {
+		return nil, err
+	}
+	clone := utilnet.CloneRequest(req)
+	clone.Header.Add(httpstream.HeaderConnection, httpstream.HeaderUpgrade)
+	clone.Header.Add(httpstream.HeaderUpgrade, spdy.HeaderSpdy31)
+	if err := clone.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, clone)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	rt.conn = &bufferedConn{Conn: conn, reader: reader}
+	return resp, nil
+}
+
+// NewConnection validates the upgrade response and returns the client
+// connection.
+func (rt *roundTripper) NewConnection(resp *http.Response) (httpstream.Connection, error) {
+	connectionHeader := strings.ToLower(resp.Header.Get(httpstream.HeaderConnection))
+	upgradeHeader := strings.ToLower(resp.Header.Get(httpstream.HeaderUpgrade))
+	if resp.StatusCode != http.StatusSwitchingProtocols || !strings.Contains(connectionHeader, strings.ToLower(httpstream.HeaderUpgrade)) || !strings.Contains(upgradeHeader, strings.ToLower(spdy.HeaderSpdy31)) {
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("unable to upgrade connection: unable to read error from server response")
+		}
+		status := metav1.Status{}
+		if err := json.Unmarshal(body, &status); err == nil && status.Status == metav1.StatusFailure {
+			return nil, &apierrors.StatusError{ErrStatus: status}
+		}
+		return nil, fmt.Errorf("unable to upgrade connection: %s", strings.TrimSpace(string(body)))
+	}
+	return spdy.NewClientConnection(rt.conn)
+}
+
+// bufferedConn reads what was buffered while reading the upgrade response
+// before reading the connection.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
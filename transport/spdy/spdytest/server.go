@@ -0,0 +1,452 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package spdytest serves the exec, attach and port-forward requests of pods
+// in-memory, like a kubelet behind an apiserver, to unit test the clients of
+// the remotecommand and portforward packages without a cluster:
+//
+//	server := spdytest.NewServer(spdytest.ServerConfig{
+//		Exec: func(cmd *spdytest.Command) error {
+//			_, err := io.Copy(cmd.Stdout, cmd.Stdin)
+//			return err
+//		},
+//	})
+//	defer server.Close()
+//
+//	executor, err := server.Executor("POST", spdytest.ExecURL("default", "pod", &v1.PodExecOptions{...}))
+//	...
+//	err = executor.Stream(remotecommand.StreamOptions{...})
+package spdytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/apimachinery/pkg/util/httpstream/spdy"
+	remotecommandconsts "k8s.io/apimachinery/pkg/util/remotecommand"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/tools/remotecommand"
+	spdytransport "k8s.io/client-go/transport/spdy"
+	"k8s.io/client-go/util/exec"
+)
+
+// Command is a command run by an exec request, or the process of a container
+// an attach request attaches to.
+type Command struct {
+	Namespace string
+	Pod       string
+	Container string
+	// Command is the command of an exec request, empty for attach requests.
+	Command []string
+	// Protocol is the negotiated remote command protocol.
+	Protocol string
+	TTY      bool
+
+	// Stdin is nil unless the client sends its standard input. Reads
+	// return io.EOF once the client closed it.
+	Stdin io.Reader
+	// Stdout and Stderr are nil unless the client reads them. Stderr is nil
+	// with a TTY.
+	Stdout io.Writer
+	Stderr io.Writer
+	// Resize receives the terminal sizes sent by the client, with a TTY and
+	// a protocol supporting them. It is closed when the client stops
+	// sending them.
+	Resize <-chan remotecommand.TerminalSize
+}
+
+// CommandHandler runs a command until it exits. Errors implementing
+// exec.ExitError are reported to clients as exit codes.
+type CommandHandler func(cmd *Command) error
+
+// PortForwardHandler handles a connection forwarded to a port of a pod. It
+// reads what the client sends until io.EOF, which is when the client closed
+// its side of the connection, and writes the replies. The server closes the
+// connection when the handler returns, and reports its error to the client.
+type PortForwardHandler func(namespace, pod string, port int32, conn io.ReadWriter) error
+
+// ServerConfig configures a Server.
+type ServerConfig struct {
+	// Exec handles exec requests, which fail with a 404 if nil.
+	Exec CommandHandler
+	// Attach handles attach requests, which fail with a 404 if nil.
+	Attach CommandHandler
+	// PortForward handles forwarded connections. Port-forward requests
+	// fail with a 404 if nil.
+	PortForward PortForwardHandler
+
+	// Protocols are the remote command protocols supported by the server,
+	// in order of preference. Defaults to all.
+	Protocols []string
+	// StreamCreationTimeout is how long the server waits for the streams
+	// of exec and attach requests. Defaults to 30s.
+	StreamCreationTimeout time.Duration
+}
+
+// Server serves exec, attach and port-forward requests over in-memory
+// connections.
+type Server struct {
+	config   ServerConfig
+	listener *listener
+	server   *http.Server
+
+	lock  sync.Mutex
+	conns map[httpstream.Connection]struct{}
+}
+
+// NewServer returns a Server, which must be closed.
+func NewServer(config ServerConfig) *Server {
+	if len(config.Protocols) == 0 {
+		config.Protocols = remotecommandconsts.SupportedStreamingProtocols
+	}
+	if config.StreamCreationTimeout == 0 {
+		config.StreamCreationTimeout = remotecommandconsts.DefaultStreamCreationTimeout
+	}
+	s := &Server{
+		config:   config,
+		listener: newListener(),
+		conns:    map[httpstream.Connection]struct{}{},
+	}
+	s.server = &http.Server{Handler: s}
+	go s.server.Serve(s.listener)
+	return s
+}
+
+// Close closes the server and the connections of its clients.
+func (s *Server) Close() {
+	s.server.Close()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
+
+// RoundTripper returns a round tripper and an upgrader connecting to the
+// server, like spdy.RoundTripperFor does to a cluster. They can be used for
+// a single connection at a time.
+func (s *Server) RoundTripper() (http.RoundTripper, spdytransport.Upgrader) {
+	rt := &roundTripper{listener: s.listener}
+	return rt, rt
+}
+
+// Executor returns an executor of the exec or attach request of url.
+func (s *Server) Executor(method string, url *url.URL) (remotecommand.Executor, error) {
+	rt, upgrader := s.RoundTripper()
+	return remotecommand.NewSPDYExecutorForTransports(rt, upgrader, method, url)
+}
+
+// Dialer returns a dialer of the port-forward request of url.
+func (s *Server) Dialer(method string, url *url.URL) httpstream.Dialer {
+	rt, upgrader := s.RoundTripper()
+	return spdytransport.NewDialer(upgrader, &http.Client{Transport: rt}, method, url)
+}
+
+// ExecURL returns the URL of an exec request.
+func ExecURL(namespace, pod string, options *v1.PodExecOptions) *url.URL {
+	query := url.Values{}
+	setStreamQuery(query, options.Container, options.Stdin, options.Stdout, options.Stderr, options.TTY)
+	query["command"] = options.Command
+	return podURL(namespace, pod, "exec", query)
+}
+
+// AttachURL returns the URL of an attach request.
+func AttachURL(namespace, pod string, options *v1.PodAttachOptions) *url.URL {
+	query := url.Values{}
+	setStreamQuery(query, options.Container, options.Stdin, options.Stdout, options.Stderr, options.TTY)
+	return podURL(namespace, pod, "attach", query)
+}
+
+// PortForwardURL returns the URL of a port-forward request.
+func PortForwardURL(namespace, pod string) *url.URL {
+	return podURL(namespace, pod, "portforward", nil)
+}
+
+func setStreamQuery(query url.Values, container string, stdin, stdout, stderr, tty bool) {
+	if container != "" {
+		query.Set("container", container)
+	}
+	for name, value := range map[string]bool{"stdin": stdin, "stdout": stdout, "stderr": stderr, "tty": tty} {
+		if value {
+			query.Set(name, "true")
+		}
+	}
+}
+
+func podURL(namespace, pod, subresource string, query url.Values) *url.URL {
+	return &url.URL{
+		Scheme:   "https",
+		Host:     "memory",
+		Path:     fmt.Sprintf("/api/v1/namespaces/%s/pods/%s/%s", namespace, pod, subresource),
+		RawQuery: query.Encode(),
+	}
+}
+
+// ServeHTTP routes the requests by subresource, from paths like those of
+// the apiserver.
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(segments) < 5 || segments[len(segments)-5] != "namespaces" || segments[len(segments)-3] != "pods" {
+		http.NotFound(w, req)
+		return
+	}
+	namespace, pod := segments[len(segments)-4], segments[len(segments)-2]
+	query := req.URL.Query()
+	switch subresource := segments[len(segments)-1]; {
+	case subresource == "exec" && s.config.Exec != nil:
+		s.serveCommand(w, req, s.config.Exec, &Command{Namespace: namespace, Pod: pod, Container: query.Get("container"), Command: query["command"]})
+	case subresource == "attach" && s.config.Attach != nil:
+		s.serveCommand(w, req, s.config.Attach, &Command{Namespace: namespace, Pod: pod, Container: query.Get("container")})
+	case subresource == "portforward" && s.config.PortForward != nil:
+		s.servePortForward(w, req, namespace, pod)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// upgrade upgrades the connection of req, and returns the connection and
+// the streams created by the client once they were replied to.
+func (s *Server) upgrade(w http.ResponseWriter, req *http.Request, protocols []string) (string, httpstream.Connection, <-chan httpstream.Stream) {
+	protocol, err := httpstream.Handshake(req, w, protocols)
+	if err != nil {
+		return "", nil, nil
+	}
+	streams := make(chan httpstream.Stream)
+	done := make(chan struct{})
+	conn := spdy.NewResponseUpgrader().UpgradeResponse(w, req, func(stream httpstream.Stream, replySent <-chan struct{}) error {
+		go func() {
+			<-replySent
+			select {
+			case streams <- stream:
+			case <-done:
+			}
+		}()
+		return nil
+	})
+	if conn == nil {
+		close(done)
+		return "", nil, nil
+	}
+	s.lock.Lock()
+	s.conns[conn] = struct{}{}
+	s.lock.Unlock()
+	go func() {
+		<-conn.CloseChan()
+		close(done)
+		s.lock.Lock()
+		delete(s.conns, conn)
+		s.lock.Unlock()
+	}()
+	return protocol, conn, streams
+}
+
+func (s *Server) serveCommand(w http.ResponseWriter, req *http.Request, handler CommandHandler, cmd *Command) {
+	query := req.URL.Query()
+	stdin, _ := strconv.ParseBool(query.Get("stdin"))
+	stdout, _ := strconv.ParseBool(query.Get("stdout"))
+	stderr, _ := strconv.ParseBool(query.Get("stderr"))
+	cmd.TTY, _ = strconv.ParseBool(query.Get("tty"))
+	if !stdin && !stdout && !stderr {
+		http.Error(w, "you must specify at least 1 of stdin, stdout, stderr", http.StatusBadRequest)
+		return
+	}
+
+	protocol, conn, streams := s.upgrade(w, req, s.config.Protocols)
+	if conn == nil {
+		return
+	}
+	defer conn.Close()
+	cmd.Protocol = protocol
+
+	// The client creates the streams requested by the query, and a resize
+	// stream with a TTY if the protocol supports it.
+	expected := map[string]bool{v1.StreamTypeError: true, v1.StreamTypeStdin: stdin, v1.StreamTypeStdout: stdout, v1.StreamTypeStderr: stderr && !cmd.TTY}
+	expected[v1.StreamTypeResize] = cmd.TTY && protocol != remotecommandconsts.StreamProtocolV1Name && protocol != remotecommandconsts.StreamProtocolV2Name
+	received := map[string]httpstream.Stream{}
+	timeout := time.After(s.config.StreamCreationTimeout)
+	for len(received) < countTrue(expected) {
+		select {
+		case stream := <-streams:
+			streamType := stream.Headers().Get(v1.StreamType)
+			if !expected[streamType] || received[streamType] != nil {
+				return
+			}
+			received[streamType] = stream
+		case <-timeout:
+			return
+		case <-conn.CloseChan():
+			return
+		}
+	}
+
+	if stream := received[v1.StreamTypeStdin]; stream != nil {
+		cmd.Stdin = stream
+	}
+	if stream := received[v1.StreamTypeStdout]; stream != nil {
+		cmd.Stdout = stream
+	}
+	if stream := received[v1.StreamTypeStderr]; stream != nil {
+		cmd.Stderr = stream
+	}
+	if stream := received[v1.StreamTypeResize]; stream != nil {
+		cmd.Resize = decodeResizes(stream, conn.CloseChan())
+	}
+
+	err := handler(cmd)
+	for _, streamType := range []string{v1.StreamTypeStdout, v1.StreamTypeStderr} {
+		if stream := received[streamType]; stream != nil {
+			stream.Close()
+		}
+	}
+	errorStream := received[v1.StreamTypeError]
+	if protocol == remotecommandconsts.StreamProtocolV4Name {
+		json.NewEncoder(errorStream).Encode(commandStatus(err))
+	} else if err != nil {
+		errorStream.Write([]byte(commandError(err).Error()))
+	}
+	errorStream.Close()
+}
+
+func countTrue(m map[string]bool) int {
+	n := 0
+	for _, v := range m {
+		if v {
+			n++
+		}
+	}
+	return n
+}
+
+// decodeResizes returns the terminal sizes sent on stream.
+func decodeResizes(stream io.Reader, done <-chan bool) <-chan remotecommand.TerminalSize {
+	sizes := make(chan remotecommand.TerminalSize)
+	go func() {
+		defer close(sizes)
+		decoder := json.NewDecoder(stream)
+		for {
+			size := remotecommand.TerminalSize{}
+			if err := decoder.Decode(&size); err != nil {
+				return
+			}
+			select {
+			case sizes <- size:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return sizes
+}
+
+// commandError returns the error reported by the kubelet for the error of a
+// command.
+func commandError(err error) error {
+	if exitErr, ok := err.(exec.ExitError); ok && exitErr.Exited() {
+		return fmt.Errorf("command terminated with non-zero exit code: %v", exitErr)
+	}
+	return fmt.Errorf("error executing command in container: %v", err)
+}
+
+// commandStatus returns the status reported by the kubelet for the error
+// of a command.
+func commandStatus(err error) metav1.Status {
+	if err == nil {
+		return metav1.Status{Status: metav1.StatusSuccess}
+	}
+	if exitErr, ok := err.(exec.ExitError); ok && exitErr.Exited() {
+		return metav1.Status{
+			Status: metav1.StatusFailure,
+			Reason: remotecommandconsts.NonZeroExitCodeReason,
+			Details: &metav1.StatusDetails{
+				Causes: []metav1.StatusCause{{
+					Type:    remotecommandconsts.ExitCodeCauseType,
+					Message: strconv.Itoa(exitErr.ExitStatus()),
+				}},
+			},
+			Message: commandError(err).Error(),
+		}
+	}
+	return apierrors.NewInternalError(commandError(err)).Status()
+}
+
+// portForwardStreams are the streams of a forwarded connection.
+type portForwardStreams struct {
+	errorStream httpstream.Stream
+	dataStream  httpstream.Stream
+}
+
+func (s *Server) servePortForward(w http.ResponseWriter, req *http.Request, namespace, pod string) {
+	_, conn, streams := s.upgrade(w, req, []string{portforward.PortForwardProtocolV1Name})
+	if conn == nil {
+		return
+	}
+	defer conn.Close()
+
+	// Each forwarded connection has an error and a data stream, with the
+	// same request ID.
+	requests := map[string]*portForwardStreams{}
+	for {
+		select {
+		case stream := <-streams:
+			requestID := stream.Headers().Get(v1.PortForwardRequestIDHeader)
+			request, ok := requests[requestID]
+			if !ok {
+				request = &portForwardStreams{}
+				requests[requestID] = request
+			}
+			switch stream.Headers().Get(v1.StreamType) {
+			case v1.StreamTypeError:
+				request.errorStream = stream
+			case v1.StreamTypeData:
+				request.dataStream = stream
+			default:
+				stream.Reset()
+				continue
+			}
+			if request.errorStream != nil && request.dataStream != nil {
+				delete(requests, requestID)
+				go s.forward(namespace, pod, request)
+			}
+		case <-conn.CloseChan():
+			return
+		}
+	}
+}
+
+func (s *Server) forward(namespace, pod string, request *portForwardStreams) {
+	defer request.errorStream.Close()
+	port, err := strconv.ParseInt(request.dataStream.Headers().Get(v1.PortHeader), 10, 32)
+	if err == nil {
+		err = s.config.PortForward(namespace, pod, int32(port), request.dataStream)
+	} else {
+		err = fmt.Errorf("invalid port %q", request.dataStream.Headers().Get(v1.PortHeader))
+	}
+	request.dataStream.Close()
+	if err != nil {
+		request.errorStream.Write([]byte(err.Error()))
+	}
+}
@@ -0,0 +1,242 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spdytest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	remotecommandconsts "k8s.io/apimachinery/pkg/util/remotecommand"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/util/exec"
+)
+
+// echo copies stdin to stdout and writes the command to stderr.
+func echo(cmd *Command) error {
+	if cmd.Stderr != nil {
+		fmt.Fprint(cmd.Stderr, strings.Join(cmd.Command, " "))
+	}
+	_, err := io.Copy(cmd.Stdout, cmd.Stdin)
+	return err
+}
+
+func TestExec(t *testing.T) {
+	// The first version of the protocol cannot close stdin.
+	for _, protocol := range []string{remotecommandconsts.StreamProtocolV2Name, remotecommandconsts.StreamProtocolV3Name, remotecommandconsts.StreamProtocolV4Name} {
+		t.Run(protocol, func(t *testing.T) {
+			server := NewServer(ServerConfig{Exec: echo, Protocols: []string{protocol}})
+			defer server.Close()
+
+			executor, err := server.Executor("POST", ExecURL("default", "pod", &v1.PodExecOptions{
+				Command: []string{"cat", "-"},
+				Stdin:   true,
+				Stdout:  true,
+				Stderr:  true,
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			err = executor.Stream(remotecommand.StreamOptions{
+				Stdin:  strings.NewReader("hello"),
+				Stdout: stdout,
+				Stderr: stderr,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stdout.String() != "hello" {
+				t.Errorf("expected stdin on stdout, got %q", stdout.String())
+			}
+			if stderr.String() != "cat -" {
+				t.Errorf("expected the command on stderr, got %q", stderr.String())
+			}
+		})
+	}
+}
+
+func TestExecErrors(t *testing.T) {
+	testCases := []struct {
+		protocol     string
+		err          error
+		expectedErr  string
+		expectedCode int
+	}{
+		{
+			protocol:     remotecommandconsts.StreamProtocolV4Name,
+			err:          exec.CodeExitError{Err: errors.New("exit status 3"), Code: 3},
+			expectedErr:  "command terminated with exit code 3",
+			expectedCode: 3,
+		},
+		{
+			protocol:    remotecommandconsts.StreamProtocolV4Name,
+			err:         errors.New("container not found"),
+			expectedErr: "Internal error occurred: error executing command in container: container not found",
+		},
+		{
+			protocol:    remotecommandconsts.StreamProtocolV3Name,
+			err:         exec.CodeExitError{Err: errors.New("exit status 3"), Code: 3},
+			expectedErr: "error executing remote command: command terminated with non-zero exit code: exit status 3",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.protocol+" "+tc.expectedErr, func(t *testing.T) {
+			server := NewServer(ServerConfig{
+				Exec:      func(*Command) error { return tc.err },
+				Protocols: []string{tc.protocol},
+			})
+			defer server.Close()
+
+			executor, err := server.Executor("POST", ExecURL("default", "pod", &v1.PodExecOptions{Command: []string{"false"}, Stdout: true}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = executor.Stream(remotecommand.StreamOptions{Stdout: ioutil.Discard})
+			if err == nil || err.Error() != tc.expectedErr {
+				t.Fatalf("expected error %q, got %v", tc.expectedErr, err)
+			}
+			if exitErr, ok := err.(exec.ExitError); ok != (tc.expectedCode != 0) || ok && exitErr.ExitStatus() != tc.expectedCode {
+				t.Errorf("expected exit code %d, got %v", tc.expectedCode, err)
+			}
+		})
+	}
+}
+
+type sizeQueue []remotecommand.TerminalSize
+
+func (q *sizeQueue) Next() *remotecommand.TerminalSize {
+	if len(*q) == 0 {
+		return nil
+	}
+	size := (*q)[0]
+	*q = (*q)[1:]
+	return &size
+}
+
+func TestAttachResize(t *testing.T) {
+	sizes := []remotecommand.TerminalSize{{Width: 80, Height: 24}, {Width: 120, Height: 40}}
+	var received []remotecommand.TerminalSize
+	var command *Command
+	server := NewServer(ServerConfig{
+		Attach: func(cmd *Command) error {
+			command = cmd
+			for size := range cmd.Resize {
+				received = append(received, size)
+				if len(received) == len(sizes) {
+					break
+				}
+			}
+			return nil
+		},
+	})
+	defer server.Close()
+
+	executor, err := server.Executor("POST", AttachURL("default", "pod", &v1.PodAttachOptions{Container: "app", Stdout: true, Stderr: true, TTY: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	queue := sizeQueue(append([]remotecommand.TerminalSize(nil), sizes...))
+	err = executor.Stream(remotecommand.StreamOptions{Stdout: ioutil.Discard, Stderr: ioutil.Discard, Tty: true, TerminalSizeQueue: &queue})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(received, sizes) {
+		t.Errorf("expected sizes %v, got %v", sizes, received)
+	}
+	if command.Container != "app" || !command.TTY || command.Stderr != nil || command.Protocol != remotecommandconsts.StreamProtocolV4Name {
+		t.Errorf("unexpected command: %+v", command)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	server := NewServer(ServerConfig{})
+	defer server.Close()
+
+	executor, err := server.Executor("POST", ExecURL("default", "pod", &v1.PodExecOptions{Stdout: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = executor.Stream(remotecommand.StreamOptions{Stdout: ioutil.Discard})
+	if err == nil || !strings.Contains(err.Error(), "404 page not found") {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestPortForward(t *testing.T) {
+	ports := make(chan int32, 1)
+	server := NewServer(ServerConfig{
+		PortForward: func(namespace, pod string, port int32, conn io.ReadWriter) error {
+			ports <- port
+			data, err := ioutil.ReadAll(conn)
+			if err != nil {
+				return err
+			}
+			_, err = conn.Write(bytes.ToUpper(data))
+			return err
+		},
+	})
+	defer server.Close()
+
+	stopCh, readyCh := make(chan struct{}), make(chan struct{})
+	errOut := &bytes.Buffer{}
+	forwarder, err := portforward.NewOnAddresses(server.Dialer("POST", PortForwardURL("default", "pod")), []string{"127.0.0.1"}, []string{":80"}, stopCh, readyCh, ioutil.Discard, errOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forwardErr := make(chan error)
+	go func() {
+		forwardErr <- forwarder.ForwardPorts()
+	}()
+	<-readyCh
+	forwarded, err := forwarder.GetPorts()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", forwarded[0].Local))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprint(conn, "hello")
+	// The handler replies once the client closed its side of the connection.
+	conn.(*net.TCPConn).CloseWrite()
+	reply, err := ioutil.ReadAll(conn)
+	conn.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(reply) != "HELLO" {
+		t.Errorf("expected HELLO, got %q", reply)
+	}
+	if port := <-ports; port != 80 {
+		t.Errorf("expected port 80, got %d", port)
+	}
+
+	close(stopCh)
+	if err := <-forwardErr; err != nil {
+		t.Fatal(err)
+	}
+}
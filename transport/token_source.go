@@ -17,6 +17,7 @@ limitations under the License.
 package transport
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -27,7 +28,7 @@ import (
 	"golang.org/x/oauth2"
 
 	utilnet "k8s.io/apimachinery/pkg/util/net"
-	"k8s.io/klog/v2"
+	"k8s.io/client-go/util/logging"
 )
 
 // TokenSourceWrapTransport returns a WrapTransport that injects bearer tokens
@@ -44,6 +45,31 @@ func TokenSourceWrapTransport(ts oauth2.TokenSource) func(http.RoundTripper) htt
 	}
 }
 
+// ContextTokenSource is an oauth2.TokenSource that can obtain a token on
+// behalf of a request. Implementations should stop waiting for a token and
+// return an error once ctx is done.
+type ContextTokenSource interface {
+	oauth2.TokenSource
+	TokenWithContext(ctx context.Context) (*oauth2.Token, error)
+}
+
+// NewTokenSourceRoundTripper returns a RoundTripper that sets the bearer token
+// obtained from ts on every request that does not already carry an
+// Authorization header. Tokens are cached until 10 seconds before they expire
+// and are discarded when the server responds with 401 Unauthorized. If ts
+// implements ContextTokenSource, the request context is used to obtain the
+// token.
+func NewTokenSourceRoundTripper(ts oauth2.TokenSource, rt http.RoundTripper) http.RoundTripper {
+	return &contextTokenSourceTransport{
+		base: rt,
+		src: &cachingTokenSource{
+			now:    time.Now,
+			leeway: 10 * time.Second,
+			base:   ts,
+		},
+	}
+}
+
 type ResettableTokenSource interface {
 	oauth2.TokenSource
 	ResetTokenOlderThan(time.Time)
@@ -104,14 +130,29 @@ func (tst *tokenSourceTransport) RoundTrip(req *http.Request) (*http.Response, e
 		return tst.base.RoundTrip(req)
 	}
 	// record time before RoundTrip to make sure newly acquired Unauthorized
-	// token would not be reset. Another request from user is required to reset
-	// and proceed.
-	start := time.Now()
-	resp, err := tst.ort.RoundTrip(req)
-	if err == nil && resp != nil && resp.StatusCode == 401 && tst.src != nil {
-		tst.src.ResetTokenOlderThan(start)
+	// token would not be reset. If a cached token was reset, the request is
+	// retried once with a fresh token.
+	var start time.Time
+	return RoundTripWithUnauthorizedRetry(req, func(req *http.Request) (*http.Response, error) {
+		start = time.Now()
+		return tst.ort.RoundTrip(req)
+	}, func(*http.Response) bool {
+		if tst.src == nil {
+			return false
+		}
+		return resetTokenOlderThan(tst.src, start)
+	})
+}
+
+// resetTokenOlderThan resets the token of ts if it was obtained before t and
+// reports whether a new token will be requested. Only token sources created by
+// this package can report a reset.
+func resetTokenOlderThan(ts ResettableTokenSource, t time.Time) bool {
+	if cts, ok := ts.(*cachingTokenSource); ok {
+		return cts.resetTokenOlderThan(t)
 	}
-	return resp, err
+	ts.ResetTokenOlderThan(t)
+	return false
 }
 
 func (tst *tokenSourceTransport) CancelRequest(req *http.Request) {
@@ -124,6 +165,43 @@ func (tst *tokenSourceTransport) CancelRequest(req *http.Request) {
 
 func (tst *tokenSourceTransport) WrappedRoundTripper() http.RoundTripper { return tst.base }
 
+// contextTokenSourceTransport is like tokenSourceTransport but obtains tokens
+// using the request context.
+type contextTokenSourceTransport struct {
+	base http.RoundTripper
+	src  *cachingTokenSource
+}
+
+var _ utilnet.RoundTripperWrapper = &contextTokenSourceTransport{}
+
+func (t *contextTokenSourceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// This is to allow --token to override other bearer token providers.
+	if req.Header.Get("Authorization") != "" {
+		return t.base.RoundTrip(req)
+	}
+	// record time before RoundTrip to make sure newly acquired Unauthorized
+	// token would not be reset.
+	var start time.Time
+	return RoundTripWithUnauthorizedRetry(req, func(req *http.Request) (*http.Response, error) {
+		start = time.Now()
+		tok, err := t.src.tokenWithContext(req.Context())
+		if err != nil {
+			return nil, err
+		}
+		req = utilnet.CloneRequest(req)
+		tok.SetAuthHeader(req)
+		return t.base.RoundTrip(req)
+	}, func(*http.Response) bool {
+		return t.src.resetTokenOlderThan(start)
+	})
+}
+
+func (t *contextTokenSourceTransport) CancelRequest(req *http.Request) {
+	tryCancelRequest(t.base, req)
+}
+
+func (t *contextTokenSourceTransport) WrappedRoundTripper() http.RoundTripper { return t.base }
+
 type fileTokenSource struct {
 	path   string
 	period time.Duration
@@ -160,6 +238,10 @@ type cachingTokenSource struct {
 }
 
 func (ts *cachingTokenSource) Token() (*oauth2.Token, error) {
+	return ts.tokenWithContext(context.Background())
+}
+
+func (ts *cachingTokenSource) tokenWithContext(ctx context.Context) (*oauth2.Token, error) {
 	now := ts.now()
 	// fast path
 	ts.RLock()
@@ -177,12 +259,17 @@ func (ts *cachingTokenSource) Token() (*oauth2.Token, error) {
 		return tok, nil
 	}
 
-	tok, err := ts.base.Token()
+	var err error
+	if cts, ok := ts.base.(ContextTokenSource); ok {
+		tok, err = cts.TokenWithContext(ctx)
+	} else {
+		tok, err = ts.base.Token()
+	}
 	if err != nil {
-		if ts.tok == nil {
+		if ts.tok == nil || ctx.Err() != nil {
 			return nil, err
 		}
-		klog.Errorf("Unable to rotate token: %v", err)
+		logging.FromContext(ctx).Error(err, "Unable to rotate token")
 		return ts.tok, nil
 	}
 
@@ -192,10 +279,18 @@ func (ts *cachingTokenSource) Token() (*oauth2.Token, error) {
 }
 
 func (ts *cachingTokenSource) ResetTokenOlderThan(t time.Time) {
+	ts.resetTokenOlderThan(t)
+}
+
+// resetTokenOlderThan resets the cached token if it was obtained before t and
+// reports whether it did.
+func (ts *cachingTokenSource) resetTokenOlderThan(t time.Time) bool {
 	ts.Lock()
 	defer ts.Unlock()
-	if ts.t.Before(t) {
+	if ts.tok != nil && ts.t.Before(t) {
 		ts.tok = nil
 		ts.t = time.Time{}
+		return true
 	}
+	return false
 }
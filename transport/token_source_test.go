@@ -17,6 +17,7 @@ limitations under the License.
 package transport
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"reflect"
@@ -208,7 +209,8 @@ func TestTokenSourceTransportRoundTrip(t *testing.T) {
 			name:        "unauthorized on cached bad token",
 			token:       badToken,
 			cachedToken: badToken,
-			wantCalls:   0,
+			wantCalls:   1,
+			wantCaching: true,
 		},
 	}
 	for _, test := range tests {
@@ -233,6 +235,84 @@ func TestTokenSourceTransportRoundTrip(t *testing.T) {
 	}
 }
 
+type testContextTokenSource struct {
+	testTokenSource
+	ctxCalls int
+}
+
+func (ts *testContextTokenSource) TokenWithContext(ctx context.Context) (*oauth2.Token, error) {
+	ts.ctxCalls++
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ts.tok, ts.err
+}
+
+func TestTokenSourceRoundTripper(t *testing.T) {
+	goodToken := &oauth2.Token{
+		AccessToken: "good",
+		Expiry:      time.Now().Add(1000 * time.Hour),
+	}
+	badToken := &oauth2.Token{
+		AccessToken: "bad",
+		Expiry:      time.Now().Add(1000 * time.Hour),
+	}
+
+	tts := &testTokenSource{tok: goodToken}
+	rt := NewTokenSourceRoundTripper(tts, &testTransport{})
+	for i := 0; i < 2; i++ {
+		if _, err := rt.RoundTrip(&http.Request{Header: http.Header{}}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if tts.calls != 1 {
+		t.Errorf("expected token to be cached, Token() called %d times", tts.calls)
+	}
+
+	// A token acquired for a request that gets a 401 is kept, a cached token
+	// that gets a 401 is discarded and the request is retried once.
+	tts = &testTokenSource{tok: badToken}
+	rt = NewTokenSourceRoundTripper(tts, &testTransport{})
+	for i := 0; i < 2; i++ {
+		resp, err := rt.RoundTrip(&http.Request{Header: http.Header{}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("expected 401, got %d", resp.StatusCode)
+		}
+	}
+	if tts.calls != 2 {
+		t.Errorf("expected token to be reset after 401, Token() called %d times", tts.calls)
+	}
+
+	cts := &testContextTokenSource{testTokenSource: testTokenSource{tok: goodToken}}
+	rt = NewTokenSourceRoundTripper(cts, &testTransport{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := (&http.Request{Header: http.Header{}}).WithContext(ctx)
+	if _, err := rt.RoundTrip(req); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if cts.ctxCalls != 1 || cts.calls != 0 {
+		t.Errorf("expected TokenWithContext to be used, got %d context calls and %d plain calls", cts.ctxCalls, cts.calls)
+	}
+}
+
+func TestHTTPWrappersForConfigTokenSource(t *testing.T) {
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "good"})
+	if _, err := HTTPWrappersForConfig(&Config{TokenSource: ts, BearerToken: "token"}, &testTransport{}); err == nil {
+		t.Errorf("expected error combining a bearer token and a token source")
+	}
+	rt, err := HTTPWrappersForConfig(&Config{TokenSource: ts}, &testTransport{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := rt.(*contextTokenSourceTransport); !ok {
+		t.Errorf("expected *contextTokenSourceTransport, got %T", rt)
+	}
+}
+
 type uncancellableRT struct {
 	rt http.RoundTripper
 }
@@ -28,7 +28,8 @@ import (
 	"time"
 
 	utilnet "k8s.io/apimachinery/pkg/util/net"
-	"k8s.io/klog/v2"
+	"k8s.io/client-go/util/logging"
+	"k8s.io/utils/clock"
 )
 
 // New returns an http.RoundTripper that will provide the authentication
@@ -44,6 +45,10 @@ func New(config *Config) (http.RoundTripper, error) {
 		err error
 	)
 
+	if config.Transport != nil && len(config.HostOverrides) > 0 {
+		return nil, fmt.Errorf("using a custom transport with host overrides is not allowed")
+	}
+
 	if config.Transport != nil {
 		rt = config.Transport
 	} else {
@@ -51,11 +56,79 @@ func New(config *Config) (http.RoundTripper, error) {
 		if err != nil {
 			return nil, err
 		}
+		if len(config.HostOverrides) > 0 {
+			rt, err = newHostOverrideRoundTripper(config, rt)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if config.HTTP3Transport != nil {
+		if len(config.HostOverrides) > 0 {
+			return nil, fmt.Errorf("using an HTTP/3 transport with host overrides is not allowed")
+		}
+		http3, err := newHTTP3RoundTripper(config)
+		if err != nil {
+			return nil, err
+		}
+		rt = newHTTP3FallbackRoundTripper(http3, rt, clock.RealClock{})
 	}
 
 	return HTTPWrappersForConfig(config, rt)
 }
 
+// hostOverrideRoundTripper dispatches requests to a per-host transport when
+// the request host has an entry in Config.HostOverrides.
+type hostOverrideRoundTripper struct {
+	rt    http.RoundTripper
+	hosts map[string]http.RoundTripper
+}
+
+var _ utilnet.RoundTripperWrapper = &hostOverrideRoundTripper{}
+
+func newHostOverrideRoundTripper(config *Config, rt http.RoundTripper) (http.RoundTripper, error) {
+	hosts := make(map[string]http.RoundTripper, len(config.HostOverrides))
+	for host, override := range config.HostOverrides {
+		hostConfig := *config
+		hostConfig.HostOverrides = nil
+		if override.TLS != nil {
+			hostConfig.TLS = *override.TLS
+		}
+		if override.Proxy != nil {
+			hostConfig.Proxy = override.Proxy
+		}
+		if hostConfig.HasCA() && hostConfig.TLS.Insecure {
+			return nil, fmt.Errorf("host override %q: specifying a root certificates file with the insecure flag is not allowed", host)
+		}
+		hostRT, err := tlsCache.get(&hostConfig)
+		if err != nil {
+			return nil, fmt.Errorf("host override %q: %w", host, err)
+		}
+		hosts[host] = hostRT
+	}
+	return &hostOverrideRoundTripper{rt: rt, hosts: hosts}, nil
+}
+
+func (rt *hostOverrideRoundTripper) roundTripperFor(req *http.Request) http.RoundTripper {
+	if req.URL != nil {
+		if hostRT, ok := rt.hosts[req.URL.Host]; ok {
+			return hostRT
+		}
+	}
+	return rt.rt
+}
+
+func (rt *hostOverrideRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return rt.roundTripperFor(req).RoundTrip(req)
+}
+
+func (rt *hostOverrideRoundTripper) CancelRequest(req *http.Request) {
+	tryCancelRequest(rt.roundTripperFor(req), req)
+}
+
+func (rt *hostOverrideRoundTripper) WrappedRoundTripper() http.RoundTripper { return rt.rt }
+
 // TLSConfigFor returns a tls.Config that will provide the transport level security defined
 // by the provided Config. Will return nil if no transport level security is requested.
 func TLSConfigFor(c *Config) (*tls.Config, error) {
@@ -285,7 +358,7 @@ func tryCancelRequest(rt http.RoundTripper, req *http.Request) {
 	case utilnet.RoundTripperWrapper:
 		tryCancelRequest(rt.WrappedRoundTripper(), req)
 	default:
-		klog.Warningf("Unable to cancel request for %T", rt)
+		logging.Warning(logging.FromContext(req.Context()), "Unable to cancel request", "roundTripperType", fmt.Sprintf("%T", rt))
 	}
 }
 
@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"testing"
 )
 
@@ -321,6 +322,40 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestNewHostOverrides(t *testing.T) {
+	config := &Config{
+		TLS: TLSConfig{Insecure: true},
+		HostOverrides: map[string]HostOverride{
+			"aggregated.example.com:8443": {TLS: &TLSConfig{CAData: []byte(rootCACert)}},
+		},
+	}
+	rt, err := New(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	overrideRT, ok := rt.(*hostOverrideRoundTripper)
+	if !ok {
+		t.Fatalf("expected *hostOverrideRoundTripper, got %T", rt)
+	}
+
+	baseTransport := overrideRT.roundTripperFor(&http.Request{URL: &url.URL{Host: "apiserver.example.com"}}).(*http.Transport)
+	if !baseTransport.TLSClientConfig.InsecureSkipVerify {
+		t.Errorf("expected base transport to be insecure")
+	}
+	hostTransport := overrideRT.roundTripperFor(&http.Request{URL: &url.URL{Host: "aggregated.example.com:8443"}}).(*http.Transport)
+	if hostTransport.TLSClientConfig.InsecureSkipVerify {
+		t.Errorf("expected host override transport to verify certificates")
+	}
+	if hostTransport.TLSClientConfig.RootCAs == nil {
+		t.Errorf("expected host override transport to use the override CA")
+	}
+
+	config.Transport = &fakeRoundTripper{}
+	if _, err := New(config); err == nil {
+		t.Errorf("expected error combining a custom transport with host overrides")
+	}
+}
+
 type fakeRoundTripper struct {
 	Req  *http.Request
 	Resp *http.Response
@@ -0,0 +1,102 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transport
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+)
+
+// maxDrainBytes bounds how much of a rejected response body is read so the
+// connection can be reused for the retry.
+const maxDrainBytes = 4 << 10
+
+// CredentialRefreshFunc is called when the server rejects a request with 401
+// Unauthorized. It refreshes the credentials used by the caller, for example by
+// re-running an exec plugin or reloading a token, and returns true if new
+// credentials are available and the request should be retried.
+type CredentialRefreshFunc func(resp *http.Response) bool
+
+// RoundTripWithUnauthorizedRetry sends req using roundTrip. If the server
+// responds with 401 Unauthorized and refresh reports that new credentials are
+// available, the response is discarded and the request is sent one more time.
+// roundTrip is expected to apply the current credentials on every call.
+// Requests with a body that cannot be replayed are not retried.
+func RoundTripWithUnauthorizedRetry(req *http.Request, roundTrip func(*http.Request) (*http.Response, error), refresh CredentialRefreshFunc) (*http.Response, error) {
+	resp, err := roundTrip(req)
+	if err != nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	if !refresh(resp) {
+		return resp, nil
+	}
+	retryReq, ok := replayableRequest(req)
+	if !ok || req.Context().Err() != nil {
+		return resp, nil
+	}
+	if resp.Body != nil {
+		io.CopyN(ioutil.Discard, resp.Body, maxDrainBytes)
+		resp.Body.Close()
+	}
+	return roundTrip(retryReq)
+}
+
+// replayableRequest returns a copy of req that can be sent again, or false if
+// the request body cannot be replayed.
+func replayableRequest(req *http.Request) (*http.Request, bool) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return utilnet.CloneRequest(req), true
+	}
+	if req.GetBody == nil {
+		return nil, false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	retryReq := utilnet.CloneRequest(req)
+	retryReq.Body = body
+	return retryReq, true
+}
+
+// NewUnauthorizedRetryRoundTripper returns a round tripper that calls refresh
+// when rt receives a 401 Unauthorized response and, if new credentials are
+// available, transparently retries the request once through rt. rt must be
+// the round tripper that applies the credentials refreshed by refresh.
+func NewUnauthorizedRetryRoundTripper(rt http.RoundTripper, refresh CredentialRefreshFunc) http.RoundTripper {
+	return &unauthorizedRetryRoundTripper{rt: rt, refresh: refresh}
+}
+
+type unauthorizedRetryRoundTripper struct {
+	rt      http.RoundTripper
+	refresh CredentialRefreshFunc
+}
+
+var _ utilnet.RoundTripperWrapper = &unauthorizedRetryRoundTripper{}
+
+func (rt *unauthorizedRetryRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return RoundTripWithUnauthorizedRetry(req, rt.rt.RoundTrip, rt.refresh)
+}
+
+func (rt *unauthorizedRetryRoundTripper) CancelRequest(req *http.Request) {
+	tryCancelRequest(rt.WrappedRoundTripper(), req)
+}
+
+func (rt *unauthorizedRetryRoundTripper) WrappedRoundTripper() http.RoundTripper { return rt.rt }
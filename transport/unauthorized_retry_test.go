@@ -0,0 +1,132 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transport
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type unauthorizedRoundTripper struct {
+	tokens []string
+	bodies []string
+}
+
+func (rt *unauthorizedRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.tokens = append(rt.tokens, req.Header.Get("Authorization"))
+	if req.Body != nil {
+		b, _ := ioutil.ReadAll(req.Body)
+		rt.bodies = append(rt.bodies, string(b))
+	}
+	code := http.StatusOK
+	if req.Header.Get("Authorization") != "Bearer good" {
+		code = http.StatusUnauthorized
+	}
+	return &http.Response{StatusCode: code, Body: ioutil.NopCloser(strings.NewReader("body"))}, nil
+}
+
+func TestRoundTripWithUnauthorizedRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		refreshed bool
+		body      bool
+		getBody   bool
+		wantCode  int
+		wantCalls int
+	}{
+		{
+			name:      "refresh and retry",
+			refreshed: true,
+			wantCode:  http.StatusOK,
+			wantCalls: 2,
+		},
+		{
+			name:      "no new credentials",
+			wantCode:  http.StatusUnauthorized,
+			wantCalls: 1,
+		},
+		{
+			name:      "replayable body",
+			refreshed: true,
+			body:      true,
+			getBody:   true,
+			wantCode:  http.StatusOK,
+			wantCalls: 2,
+		},
+		{
+			name:      "non-replayable body",
+			refreshed: true,
+			body:      true,
+			wantCode:  http.StatusUnauthorized,
+			wantCalls: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "https://127.0.0.1", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.body {
+				req.Body = ioutil.NopCloser(bytes.NewBufferString("payload"))
+				if test.getBody {
+					req.GetBody = func() (io.ReadCloser, error) {
+						return ioutil.NopCloser(bytes.NewBufferString("payload")), nil
+					}
+				}
+			}
+
+			token := "bad"
+			base := &unauthorizedRoundTripper{}
+			rt := NewUnauthorizedRetryRoundTripper(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				req.Header.Set("Authorization", "Bearer "+token)
+				return base.RoundTrip(req)
+			}), func(resp *http.Response) bool {
+				if test.refreshed {
+					token = "good"
+				}
+				return test.refreshed
+			})
+
+			resp, err := rt.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.StatusCode != test.wantCode {
+				t.Errorf("expected status %d, got %d", test.wantCode, resp.StatusCode)
+			}
+			if len(base.tokens) != test.wantCalls {
+				t.Errorf("expected %d calls, got %d: %v", test.wantCalls, len(base.tokens), base.tokens)
+			}
+			for i, body := range base.bodies {
+				if body != "payload" {
+					t.Errorf("request %d: expected body %q, got %q", i, "payload", body)
+				}
+			}
+		})
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package util contains utilities shared by transports and applications, like
+// the watching of rotated credential files.
+package util // import "k8s.io/client-go/transport/util"
+
+import (
+	"context"
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// DefaultFileWatchInterval is the default interval of the polling of
+	// watched files.
+	DefaultFileWatchInterval = 10 * time.Second
+	// DefaultFileWatchDebounce is the default duration for which files must be
+	// unchanged before listeners are notified of their changes.
+	DefaultFileWatchDebounce = time.Second
+)
+
+// FileWatcherOptions configures a FileWatcher.
+type FileWatcherOptions struct {
+	// Interval is the interval of the polling of the files. Defaults to
+	// DefaultFileWatchInterval.
+	Interval time.Duration
+	// Debounce is the duration for which the files must be unchanged after a
+	// change before listeners are notified, so files written in several steps,
+	// like a certificate and its key, cause a single notification. Defaults
+	// to DefaultFileWatchDebounce.
+	Debounce time.Duration
+}
+
+// FileWatcher watches files, like CA bundles, client certificates and keys
+// or tokens, for rotations and notifies listeners of their changes.
+//
+// Files are polled and compared by content, so changes are detected however
+// files are replaced, including through the symbolic links of projected
+// volumes. Files that do not exist are watched until they are created.
+type FileWatcher struct {
+	paths    []string
+	interval time.Duration
+	debounce time.Duration
+	clock    clock.WithTicker
+
+	lock      sync.Mutex
+	listeners []func(changed []string)
+	// hashes holds the hash of the content of the files, and no entry for
+	// files that do not exist.
+	hashes map[string][sha256.Size]byte
+}
+
+// NewFileWatcher returns a watcher of the files at paths. Watching starts
+// with Run.
+func NewFileWatcher(paths []string, options FileWatcherOptions) *FileWatcher {
+	if options.Interval <= 0 {
+		options.Interval = DefaultFileWatchInterval
+	}
+	if options.Debounce <= 0 {
+		options.Debounce = DefaultFileWatchDebounce
+	}
+	return &FileWatcher{
+		paths:    append([]string(nil), paths...),
+		interval: options.Interval,
+		debounce: options.Debounce,
+		clock:    clock.RealClock{},
+	}
+}
+
+// AddListener registers fn to be called with the sorted paths of the changed
+// files after changes. Listeners are called one after another and must not
+// block.
+func (w *FileWatcher) AddListener(fn func(changed []string)) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.listeners = append(w.listeners, fn)
+}
+
+// Run watches the files until ctx is done. Changes made before Run are not
+// reported.
+func (w *FileWatcher) Run(ctx context.Context) {
+	defer utilruntime.HandleCrash()
+
+	w.poll()
+	ticker := w.clock.NewTicker(w.interval)
+	defer ticker.Stop()
+	var debounce clock.Timer
+	var debounceC <-chan time.Time
+	pending := map[string]bool{}
+	for {
+		select {
+		case <-ctx.Done():
+			if debounce != nil {
+				debounce.Stop()
+			}
+			return
+		case <-ticker.C():
+			changed := w.poll()
+			if len(changed) == 0 {
+				continue
+			}
+			for _, path := range changed {
+				pending[path] = true
+			}
+			if debounce == nil {
+				debounce = w.clock.NewTimer(w.debounce)
+			} else {
+				debounce.Stop()
+				debounce.Reset(w.debounce)
+			}
+			debounceC = debounce.C()
+		case <-debounceC:
+			// make sure the files did not change since the last poll
+			if changed := w.poll(); len(changed) > 0 {
+				for _, path := range changed {
+					pending[path] = true
+				}
+				debounce.Reset(w.debounce)
+				continue
+			}
+			debounceC = nil
+			w.notify(pending)
+			pending = map[string]bool{}
+		}
+	}
+}
+
+// poll reads the files and returns the paths of those that changed.
+func (w *FileWatcher) poll() []string {
+	hashes := map[string][sha256.Size]byte{}
+	var changed []string
+	for _, path := range w.paths {
+		data, err := ioutil.ReadFile(path)
+		switch {
+		case os.IsNotExist(err):
+		case err != nil:
+			// keep the previous state until the file can be read
+			klog.V(2).Infof("Failed to read watched file %q: %v", path, err)
+			if hash, ok := w.hashes[path]; ok {
+				hashes[path] = hash
+			}
+			continue
+		default:
+			hashes[path] = sha256.Sum256(data)
+		}
+		previous, existed := w.hashes[path]
+		current, exists := hashes[path]
+		if w.hashes != nil && (existed != exists || previous != current) {
+			changed = append(changed, path)
+		}
+	}
+	w.hashes = hashes
+	return changed
+}
+
+func (w *FileWatcher) notify(pending map[string]bool) {
+	changed := make([]string, 0, len(pending))
+	for path := range pending {
+		changed = append(changed, path)
+	}
+	sort.Strings(changed)
+	klog.V(2).Infof("Watched files changed: %v", changed)
+
+	w.lock.Lock()
+	listeners := append([]func([]string){}, w.listeners...)
+	w.lock.Unlock()
+	for _, listener := range listeners {
+		listener(changed)
+	}
+}
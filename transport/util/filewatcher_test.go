@@ -0,0 +1,109 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestFileWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filewatcher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ca := filepath.Join(dir, "ca.crt")
+	token := filepath.Join(dir, "token")
+	missing := filepath.Join(dir, "missing")
+	write := func(path, content string) {
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(ca, "ca")
+	write(token, "token")
+
+	watcher := NewFileWatcher([]string{ca, token, missing}, FileWatcherOptions{
+		Interval: 10 * time.Millisecond,
+		Debounce: 100 * time.Millisecond,
+	})
+	notifications := make(chan []string, 10)
+	watcher.AddListener(func(changed []string) {
+		notifications <- changed
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		watcher.Run(ctx)
+	}()
+	// let the watcher read the initial content
+	time.Sleep(50 * time.Millisecond)
+
+	expectNotification := func(expected ...string) {
+		t.Helper()
+		select {
+		case changed := <-notifications:
+			if !reflect.DeepEqual(changed, expected) {
+				t.Errorf("expected changes of %v, got %v", expected, changed)
+			}
+		case <-time.After(wait.ForeverTestTimeout):
+			t.Fatalf("timed out waiting for changes of %v", expected)
+		}
+	}
+	expectNoNotification := func() {
+		t.Helper()
+		select {
+		case changed := <-notifications:
+			t.Errorf("unexpected changes of %v", changed)
+		case <-time.After(300 * time.Millisecond):
+		}
+	}
+
+	// unchanged content is not a change
+	write(ca, "ca")
+	expectNoNotification()
+
+	// changes close together are notified together
+	write(ca, "new ca")
+	write(token, "new token")
+	expectNotification(ca, token)
+	expectNoNotification()
+
+	write(missing, "created")
+	expectNotification(missing)
+	if err := os.Remove(missing); err != nil {
+		t.Fatal(err)
+	}
+	expectNotification(missing)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("the watcher did not stop")
+	}
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package backoff provides composable backoff policies and a Manager that
+// tracks the state of a retry loop, shared by the reflector, the retry
+// watcher and leader election.
+package backoff
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/clock"
+)
+
+// Policy computes the delays of a retry loop.
+type Policy interface {
+	// Delay returns the delay before retry number attempt, starting at zero.
+	// prev is the delay returned for the previous attempt, or zero.
+	Delay(attempt int, prev time.Duration) time.Duration
+}
+
+// PolicyFunc adapts a function to a Policy.
+type PolicyFunc func(attempt int, prev time.Duration) time.Duration
+
+// Delay implements Policy.
+func (f PolicyFunc) Delay(attempt int, prev time.Duration) time.Duration {
+	return f(attempt, prev)
+}
+
+// Exponential returns a Policy starting at initial and multiplying the delay
+// by factor for every attempt, up to max if max is positive.
+func Exponential(initial, max time.Duration, factor float64) Policy {
+	return PolicyFunc(func(attempt int, _ time.Duration) time.Duration {
+		delay := float64(initial) * math.Pow(factor, float64(attempt))
+		if max > 0 && delay > float64(max) {
+			return max
+		}
+		return time.Duration(delay)
+	})
+}
+
+// DecorrelatedJitter returns a Policy whose delays are random durations
+// between base and three times the previous delay, up to max if max is
+// positive. Unlike an exponential backoff, the delays of clients that failed
+// at the same time quickly diverge.
+func DecorrelatedJitter(base, max time.Duration) Policy {
+	return PolicyFunc(func(_ int, prev time.Duration) time.Duration {
+		if base <= 0 {
+			return 0
+		}
+		if prev < base {
+			prev = base
+		}
+		upper := prev * 3
+		if upper <= base {
+			// overflow
+			upper = base + 1
+		}
+		delay := base + time.Duration(rand.Int63n(int64(upper-base)))
+		if max > 0 && delay > max {
+			delay = max
+		}
+		return delay
+	})
+}
+
+// Steps returns a Policy that uses the given delays in order and repeats the
+// last one once they are exhausted. Steps with a single delay is a constant
+// backoff.
+func Steps(delays ...time.Duration) Policy {
+	return PolicyFunc(func(attempt int, _ time.Duration) time.Duration {
+		if len(delays) == 0 {
+			return 0
+		}
+		if attempt >= len(delays) {
+			attempt = len(delays) - 1
+		}
+		return delays[attempt]
+	})
+}
+
+// WithJitter returns a Policy adding a random duration of up to
+// maxFactor times the delay of policy, like wait.Jitter.
+func WithJitter(policy Policy, maxFactor float64) Policy {
+	return PolicyFunc(func(attempt int, prev time.Duration) time.Duration {
+		return wait.Jitter(policy.Delay(attempt, prev), maxFactor)
+	})
+}
+
+// Manager tracks the attempts of a retry loop and computes its delays using a
+// Policy. It implements wait.BackoffManager so it can drive wait.BackoffUntil.
+// A Manager is safe for concurrent use.
+type Manager struct {
+	policy     Policy
+	clock      clock.Clock
+	resetAfter time.Duration
+
+	lock        sync.Mutex
+	attempt     int
+	prev        time.Duration
+	lastBackoff time.Time
+	timer       clock.Timer
+}
+
+var _ wait.BackoffManager = &Manager{}
+
+// NewManager returns a Manager computing delays with policy.
+func NewManager(policy Policy, c clock.Clock) *Manager {
+	return &Manager{policy: policy, clock: c}
+}
+
+// ResetOnDuration makes the manager start over from the first delay of its
+// policy when no delay was requested for d, assuming the operation recovered.
+// It returns m for chaining.
+func (m *Manager) ResetOnDuration(d time.Duration) *Manager {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.resetAfter = d
+	return m
+}
+
+// ResetOnSuccess makes the manager start over from the first delay of its
+// policy. It is meant to be called when an attempt succeeded.
+func (m *Manager) ResetOnSuccess() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.reset()
+}
+
+func (m *Manager) reset() {
+	m.attempt = 0
+	m.prev = 0
+}
+
+// Next returns the delay before the next attempt.
+func (m *Manager) Next() time.Duration {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	now := m.clock.Now()
+	if m.resetAfter > 0 && !m.lastBackoff.IsZero() && now.Sub(m.lastBackoff) > m.resetAfter {
+		m.reset()
+	}
+	m.lastBackoff = now
+
+	delay := m.policy.Delay(m.attempt, m.prev)
+	m.attempt++
+	m.prev = delay
+	return delay
+}
+
+// Backoff implements wait.BackoffManager. The returned timer is reused by
+// subsequent calls.
+func (m *Manager) Backoff() clock.Timer {
+	delay := m.Next()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.timer == nil {
+		m.timer = m.clock.NewTimer(delay)
+	} else {
+		m.timer.Reset(delay)
+	}
+	return m.timer
+}
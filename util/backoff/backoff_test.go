@@ -0,0 +1,97 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backoff
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func delays(policy Policy, n int) []time.Duration {
+	var result []time.Duration
+	var prev time.Duration
+	for i := 0; i < n; i++ {
+		prev = policy.Delay(i, prev)
+		result = append(result, prev)
+	}
+	return result
+}
+
+func TestPolicies(t *testing.T) {
+	if got, want := delays(Exponential(time.Second, 10*time.Second, 2), 5), []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second}; !reflect.DeepEqual(got, want) {
+		t.Errorf("exponential: expected %v, got %v", want, got)
+	}
+	if got, want := delays(Steps(time.Second, time.Minute), 3), []time.Duration{time.Second, time.Minute, time.Minute}; !reflect.DeepEqual(got, want) {
+		t.Errorf("steps: expected %v, got %v", want, got)
+	}
+	for _, d := range delays(WithJitter(Steps(time.Second), 0.5), 100) {
+		if d < time.Second || d >= 1500*time.Millisecond {
+			t.Fatalf("jitter: delay %v out of range", d)
+		}
+	}
+
+	base, max := 10*time.Millisecond, time.Second
+	var prev time.Duration
+	for i := 0; i < 100; i++ {
+		d := DecorrelatedJitter(base, max).Delay(i, prev)
+		lowerPrev := prev
+		if lowerPrev < base {
+			lowerPrev = base
+		}
+		if d < base || d > max || (d > 3*lowerPrev && d != max) {
+			t.Fatalf("decorrelated jitter: delay %v out of range for previous delay %v", d, prev)
+		}
+		prev = d
+	}
+}
+
+func TestManagerReset(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	m := NewManager(Exponential(time.Second, time.Minute, 2), fakeClock).ResetOnDuration(time.Minute)
+
+	if got, want := []time.Duration{m.Next(), m.Next(), m.Next()}, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	m.ResetOnSuccess()
+	if d := m.Next(); d != time.Second {
+		t.Errorf("expected reset after success, got %v", d)
+	}
+	m.Next()
+	fakeClock.Step(2 * time.Minute)
+	if d := m.Next(); d != time.Second {
+		t.Errorf("expected reset after a minute without backoff, got %v", d)
+	}
+}
+
+func TestManagerBackoff(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	m := NewManager(Steps(time.Second), fakeClock)
+
+	timer := m.Backoff()
+	fakeClock.Step(time.Second)
+	select {
+	case <-timer.C():
+	default:
+		t.Fatalf("expected timer to fire")
+	}
+	if m.Backoff() != timer {
+		t.Errorf("expected timer to be reused")
+	}
+}
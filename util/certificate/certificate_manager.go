@@ -66,6 +66,16 @@ type Manager interface {
 	ServerHealthy() bool
 }
 
+// Rotator is implemented by the managers which can rotate their certificate
+// on demand, like the ones returned by NewManager.
+type Rotator interface {
+	// RotateNow requests a new certificate without waiting for the rotation
+	// deadline of the current one, for example because its key may be
+	// compromised. It does not wait for the rotation, which only happens once
+	// the manager is started.
+	RotateNow()
+}
+
 // Config is the set of configuration parameters available for a new Manager.
 type Config struct {
 	// ClientsetFn will be used to create a clientset for
@@ -137,6 +147,17 @@ type Config struct {
 	// Logf is an optional function that log output will be sent to from the
 	// certificate manager. If not set it will use klog.V(2)
 	Logf func(format string, args ...interface{})
+	// Signer is an optional signer of the certificates, used instead of the
+	// certificate signing request API. If set, ClientsetFn and SignerName are
+	// not used.
+	Signer Signer
+	// OnRotation is an optional function called after each rotation, once
+	// the new certificate is stored and returned by Current. old is nil if
+	// there was no certificate.
+	OnRotation func(old, new *tls.Certificate)
+	// OnRotationFailure is an optional function called with the error of
+	// each failed rotation attempt. Failed attempts are retried with backoff.
+	OnRotationFailure func(err error)
 }
 
 // Store is responsible for getting and updating the current certificate.
@@ -211,6 +232,13 @@ type manager struct {
 	stopCh           chan struct{}
 	stopped          bool
 
+	signer            Signer
+	onRotation        func(old, new *tls.Certificate)
+	onRotationFailure func(err error)
+
+	// rotateNow holds a pending request to rotate before the deadline
+	rotateNow chan struct{}
+
 	// Set to time.Now but can be stubbed out for testing
 	now func() time.Time
 
@@ -248,6 +276,10 @@ func NewManager(config *Config) (Manager, error) {
 		forceRotation:                forceRotation,
 		certificateRotation:          config.CertificateRotation,
 		certificateRenewFailure:      config.CertificateRenewFailure,
+		signer:                       config.Signer,
+		onRotation:                   config.OnRotation,
+		onRotationFailure:            config.OnRotationFailure,
+		rotateNow:                    make(chan struct{}, 1),
 		now:                          time.Now,
 	}
 
@@ -295,6 +327,16 @@ func (m *manager) ServerHealthy() bool {
 	return m.serverHealth
 }
 
+// RotateNow requests a rotation before the deadline of the current
+// certificate.
+func (m *manager) RotateNow() {
+	select {
+	case m.rotateNow <- struct{}{}:
+	default:
+		// a rotation is already requested
+	}
+}
+
 // Stop terminates the manager.
 func (m *manager) Stop() {
 	m.clientAccessLock.Lock()
@@ -310,8 +352,8 @@ func (m *manager) Stop() {
 func (m *manager) Start() {
 	// Certificate rotation depends on access to the API server certificate
 	// signing API, so don't start the certificate manager if we don't have a
-	// client.
-	if m.clientsetFn == nil {
+	// client or another signer.
+	if m.clientsetFn == nil && m.signer == nil {
 		m.logf("%s: Certificate rotation is not enabled, no connection to the apiserver", m.name)
 		return
 	}
@@ -329,6 +371,8 @@ func (m *manager) Start() {
 			select {
 			case <-timer.C:
 				// unblock when deadline expires
+			case <-m.rotateNow:
+				m.logf("%s: Certificate rotation requested, rotating", m.name)
 			case <-templateChanged:
 				_, lastRequestTemplate := m.getLastRequest()
 				if reflect.DeepEqual(lastRequestTemplate, m.getTemplate()) {
@@ -344,6 +388,12 @@ func (m *manager) Start() {
 			return
 		}
 
+		// this rotation satisfies any pending request
+		select {
+		case <-m.rotateNow:
+		default:
+		}
+
 		backoff := wait.Backoff{
 			Duration: 2 * time.Second,
 			Factor:   2,
@@ -447,32 +497,53 @@ func (m *manager) rotateCerts() (bool, error) {
 
 	template, csrPEM, keyPEM, privateKey, err := m.generateCSR()
 	if err != nil {
-		utilruntime.HandleError(fmt.Errorf("%s: Unable to generate a certificate signing request: %v", m.name, err))
-		if m.certificateRenewFailure != nil {
-			m.certificateRenewFailure.Inc()
-		}
+		m.rotationFailed(fmt.Errorf("%s: Unable to generate a certificate signing request: %v", m.name, err))
 		return false, nil
 	}
 
+	var crtPEM []byte
+	if m.signer != nil {
+		crtPEM, err = m.sign(template, csrPEM, privateKey)
+	} else {
+		crtPEM, err = m.requestCertificate(template, csrPEM, privateKey)
+	}
+	if err != nil {
+		m.rotationFailed(err)
+		return false, nil
+	}
+
+	cert, err := m.certStore.Update(crtPEM, keyPEM)
+	if err != nil {
+		m.rotationFailed(fmt.Errorf("%s: Unable to store the new cert/key pair: %v", m.name, err))
+		return false, nil
+	}
+
+	old := m.updateCached(cert)
+	if old != nil && m.certificateRotation != nil {
+		m.certificateRotation.Observe(m.now().Sub(old.Leaf.NotBefore).Seconds())
+	}
+	if m.onRotation != nil {
+		m.onRotation(old, cert)
+	}
+
+	return true, nil
+}
+
+// requestCertificate requests a certificate for the CSR with the certificate
+// signing request API and waits for it to be issued.
+func (m *manager) requestCertificate(template *x509.CertificateRequest, csrPEM []byte, privateKey interface{}) ([]byte, error) {
 	// request the client each time
 	clientSet, err := m.getClientset()
 	if err != nil {
-		utilruntime.HandleError(fmt.Errorf("%s: Unable to load a client to request certificates: %v", m.name, err))
-		if m.certificateRenewFailure != nil {
-			m.certificateRenewFailure.Inc()
-		}
-		return false, nil
+		return nil, fmt.Errorf("%s: Unable to load a client to request certificates: %v", m.name, err)
 	}
 
 	// Call the Certificate Signing Request API to get a certificate for the
 	// new private key.
 	reqName, reqUID, err := csr.RequestCertificate(clientSet, csrPEM, "", m.signerName, m.requestedCertificateLifetime, m.usages, privateKey)
 	if err != nil {
-		utilruntime.HandleError(fmt.Errorf("%s: Failed while requesting a signed certificate from the control plane: %v", m.name, err))
-		if m.certificateRenewFailure != nil {
-			m.certificateRenewFailure.Inc()
-		}
-		return false, m.updateServerError(err)
+		m.updateServerError(err)
+		return nil, fmt.Errorf("%s: Failed while requesting a signed certificate from the control plane: %v", m.name, err)
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), certificateWaitTimeout)
@@ -485,27 +556,39 @@ func (m *manager) rotateCerts() (bool, error) {
 	// is a remainder after the old design using raw watch wrapped with backoff.
 	crtPEM, err := csr.WaitForCertificate(ctx, clientSet, reqName, reqUID)
 	if err != nil {
-		utilruntime.HandleError(fmt.Errorf("%s: certificate request was not signed: %v", m.name, err))
-		if m.certificateRenewFailure != nil {
-			m.certificateRenewFailure.Inc()
-		}
-		return false, nil
+		return nil, fmt.Errorf("%s: certificate request was not signed: %v", m.name, err)
 	}
+	return crtPEM, nil
+}
 
-	cert, err := m.certStore.Update(crtPEM, keyPEM)
+// sign gets a certificate for the CSR from the signer of the manager.
+func (m *manager) sign(template *x509.CertificateRequest, csrPEM []byte, privateKey interface{}) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), certificateWaitTimeout)
+	defer cancel()
+
+	m.setLastRequest(cancel, template)
+
+	crtPEM, err := m.signer.Sign(ctx, &SigningRequest{
+		CSRPEM:     csrPEM,
+		PrivateKey: privateKey,
+		Usages:     m.usages,
+		Lifetime:   m.requestedCertificateLifetime,
+	})
 	if err != nil {
-		utilruntime.HandleError(fmt.Errorf("%s: Unable to store the new cert/key pair: %v", m.name, err))
-		if m.certificateRenewFailure != nil {
-			m.certificateRenewFailure.Inc()
-		}
-		return false, nil
+		return nil, fmt.Errorf("%s: certificate request was not signed: %v", m.name, err)
 	}
+	return crtPEM, nil
+}
 
-	if old := m.updateCached(cert); old != nil && m.certificateRotation != nil {
-		m.certificateRotation.Observe(m.now().Sub(old.Leaf.NotBefore).Seconds())
+// rotationFailed reports the error of a failed rotation attempt.
+func (m *manager) rotationFailed(err error) {
+	utilruntime.HandleError(err)
+	if m.certificateRenewFailure != nil {
+		m.certificateRenewFailure.Inc()
+	}
+	if m.onRotationFailure != nil {
+		m.onRotationFailure(err)
 	}
-
-	return true, nil
 }
 
 // Check that the current certificate on disk satisfies the requests from the
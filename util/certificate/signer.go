@@ -0,0 +1,173 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificate
+
+import (
+	"context"
+	"crypto"
+	cryptorand "crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	certificates "k8s.io/api/certificates/v1"
+	"k8s.io/client-go/util/cert"
+)
+
+// SigningRequest is a request of a Manager for a certificate.
+type SigningRequest struct {
+	// CSRPEM is the PEM encoded certificate signing request.
+	CSRPEM []byte
+	// PrivateKey is the private key of the certificate signing request.
+	PrivateKey interface{} `datapolicy:"security-key"`
+	// Usages is the types of usages of the certificate.
+	Usages []certificates.KeyUsage
+	// Lifetime is the requested lifetime of the certificate, if any.
+	Lifetime *time.Duration
+}
+
+// Signer issues the certificates of a Manager, for example with a local
+// certificate authority or a SPIFFE workload API, instead of the
+// certificate signing request API.
+type Signer interface {
+	// Sign returns the PEM encoded certificate issued for request. It
+	// blocks until the certificate is issued or ctx is done.
+	Sign(ctx context.Context, request *SigningRequest) ([]byte, error)
+}
+
+// SignerFunc is a function implementing Signer.
+type SignerFunc func(ctx context.Context, request *SigningRequest) ([]byte, error)
+
+// Sign calls f.
+func (f SignerFunc) Sign(ctx context.Context, request *SigningRequest) ([]byte, error) {
+	return f(ctx, request)
+}
+
+// localSigner signs certificates with a local certificate authority.
+type localSigner struct {
+	caCert   *x509.Certificate
+	caKey    crypto.Signer
+	lifetime time.Duration
+	now      func() time.Time
+}
+
+// NewLocalSigner returns a Signer issuing certificates signed by the
+// certificate authority caCert, whose private key is caKey. The certificates
+// have the requested lifetime, or the given lifetime if none is requested,
+// and never outlive caCert.
+func NewLocalSigner(caCert *x509.Certificate, caKey crypto.Signer, lifetime time.Duration) Signer {
+	return &localSigner{caCert: caCert, caKey: caKey, lifetime: lifetime, now: time.Now}
+}
+
+func (s *localSigner) Sign(ctx context.Context, request *SigningRequest) ([]byte, error) {
+	block, _ := pem.Decode(request.CSRPEM)
+	if block == nil || block.Type != cert.CertificateRequestBlockType {
+		return nil, fmt.Errorf("PEM block type must be %s", cert.CertificateRequestBlockType)
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the certificate signing request: %v", err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("invalid signature of the certificate signing request: %v", err)
+	}
+
+	keyUsage, extKeyUsage, err := x509KeyUsages(request.Usages)
+	if err != nil {
+		return nil, err
+	}
+	lifetime := s.lifetime
+	if request.Lifetime != nil {
+		lifetime = *request.Lifetime
+	}
+	serialNumber, err := cryptorand.Int(cryptorand.Reader, new(big.Int).SetInt64(math.MaxInt64))
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate a serial number: %v", err)
+	}
+
+	now := s.now()
+	notAfter := now.Add(lifetime)
+	if notAfter.After(s.caCert.NotAfter) {
+		notAfter = s.caCert.NotAfter
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               csr.Subject,
+		DNSNames:              csr.DNSNames,
+		IPAddresses:           csr.IPAddresses,
+		EmailAddresses:        csr.EmailAddresses,
+		URIs:                  csr.URIs,
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              notAfter,
+		KeyUsage:              keyUsage,
+		ExtKeyUsage:           extKeyUsage,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(cryptorand.Reader, template, s.caCert, csr.PublicKey, s.caKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to sign the certificate: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: cert.CertificateBlockType, Bytes: der}), nil
+}
+
+var keyUsages = map[certificates.KeyUsage]x509.KeyUsage{
+	certificates.UsageSigning:           x509.KeyUsageDigitalSignature,
+	certificates.UsageDigitalSignature:  x509.KeyUsageDigitalSignature,
+	certificates.UsageContentCommitment: x509.KeyUsageContentCommitment,
+	certificates.UsageKeyEncipherment:   x509.KeyUsageKeyEncipherment,
+	certificates.UsageKeyAgreement:      x509.KeyUsageKeyAgreement,
+	certificates.UsageDataEncipherment:  x509.KeyUsageDataEncipherment,
+	certificates.UsageCertSign:          x509.KeyUsageCertSign,
+	certificates.UsageCRLSign:           x509.KeyUsageCRLSign,
+	certificates.UsageEncipherOnly:      x509.KeyUsageEncipherOnly,
+	certificates.UsageDecipherOnly:      x509.KeyUsageDecipherOnly,
+}
+
+var extKeyUsages = map[certificates.KeyUsage]x509.ExtKeyUsage{
+	certificates.UsageAny:             x509.ExtKeyUsageAny,
+	certificates.UsageServerAuth:      x509.ExtKeyUsageServerAuth,
+	certificates.UsageClientAuth:      x509.ExtKeyUsageClientAuth,
+	certificates.UsageCodeSigning:     x509.ExtKeyUsageCodeSigning,
+	certificates.UsageEmailProtection: x509.ExtKeyUsageEmailProtection,
+	certificates.UsageSMIME:           x509.ExtKeyUsageEmailProtection,
+	certificates.UsageIPsecEndSystem:  x509.ExtKeyUsageIPSECEndSystem,
+	certificates.UsageIPsecTunnel:     x509.ExtKeyUsageIPSECTunnel,
+	certificates.UsageIPsecUser:       x509.ExtKeyUsageIPSECUser,
+	certificates.UsageTimestamping:    x509.ExtKeyUsageTimeStamping,
+	certificates.UsageOCSPSigning:     x509.ExtKeyUsageOCSPSigning,
+	certificates.UsageMicrosoftSGC:    x509.ExtKeyUsageMicrosoftServerGatedCrypto,
+	certificates.UsageNetscapeSGC:     x509.ExtKeyUsageNetscapeServerGatedCrypto,
+}
+
+// x509KeyUsages converts usages to the key usages of x509 certificates.
+func x509KeyUsages(usages []certificates.KeyUsage) (x509.KeyUsage, []x509.ExtKeyUsage, error) {
+	var keyUsage x509.KeyUsage
+	var extKeyUsage []x509.ExtKeyUsage
+	for _, usage := range usages {
+		if u, ok := keyUsages[usage]; ok {
+			keyUsage |= u
+		} else if u, ok := extKeyUsages[usage]; ok {
+			extKeyUsage = append(extKeyUsage, u)
+		} else {
+			return 0, nil, fmt.Errorf("unknown key usage %q", usage)
+		}
+	}
+	return keyUsage, extKeyUsage, nil
+}
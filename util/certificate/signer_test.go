@@ -0,0 +1,229 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificate
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	cryptorand "crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	"k8s.io/client-go/util/cert"
+)
+
+func newLocalSigner(t *testing.T, lifetime time.Duration) (Signer, *x509.Certificate) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), cryptorand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, err := cert.NewSelfSignedCACert(cert.Config{CommonName: "local-ca"}, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewLocalSigner(caCert, caKey, lifetime), caCert
+}
+
+func newSigningRequest(t *testing.T, template *x509.CertificateRequest, usages []certificatesv1.KeyUsage) *SigningRequest {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), cryptorand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csrPEM, err := cert.MakeCSRFromTemplate(key, template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &SigningRequest{CSRPEM: csrPEM, PrivateKey: key, Usages: usages}
+}
+
+type counterMock struct {
+	calls int
+}
+
+func (c *counterMock) Inc() {
+	c.calls++
+}
+
+func TestLocalSigner(t *testing.T) {
+	signer, caCert := newLocalSigner(t, time.Hour)
+
+	request := newSigningRequest(t, &x509.CertificateRequest{
+		Subject:     pkix.Name{CommonName: "system:node:n", Organization: []string{"system:nodes"}},
+		DNSNames:    []string{"n.example.com"},
+		IPAddresses: []net.IP{net.ParseIP("10.0.0.1")},
+	}, []certificatesv1.KeyUsage{certificatesv1.UsageDigitalSignature, certificatesv1.UsageKeyEncipherment, certificatesv1.UsageServerAuth})
+	certPEM, err := signer.Sign(context.TODO(), request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	certs, err := cert.ParseCertsPEM(certPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issued := certs[0]
+
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+	if _, err := issued.Verify(x509.VerifyOptions{Roots: roots, DNSName: "n.example.com", KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}); err != nil {
+		t.Errorf("unable to verify the certificate: %v", err)
+	}
+	if issued.Subject.CommonName != "system:node:n" || !reflect.DeepEqual(issued.Subject.Organization, []string{"system:nodes"}) {
+		t.Errorf("unexpected subject %v", issued.Subject)
+	}
+	if len(issued.IPAddresses) != 1 || !issued.IPAddresses[0].Equal(net.ParseIP("10.0.0.1")) {
+		t.Errorf("unexpected IP addresses %v", issued.IPAddresses)
+	}
+	if issued.KeyUsage != x509.KeyUsageDigitalSignature|x509.KeyUsageKeyEncipherment {
+		t.Errorf("unexpected key usage %v", issued.KeyUsage)
+	}
+	if lifetime := issued.NotAfter.Sub(issued.NotBefore); lifetime < time.Hour || lifetime > time.Hour+2*time.Minute {
+		t.Errorf("expected a lifetime of an hour, got %v", lifetime)
+	}
+
+	lifetime := 10 * time.Minute
+	request.Lifetime = &lifetime
+	certPEM, err = signer.Sign(context.TODO(), request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if certs, err = cert.ParseCertsPEM(certPEM); err != nil {
+		t.Fatal(err)
+	}
+	if lifetime := certs[0].NotAfter.Sub(certs[0].NotBefore); lifetime > 12*time.Minute {
+		t.Errorf("expected the requested lifetime, got %v", lifetime)
+	}
+}
+
+func TestLocalSignerErrors(t *testing.T) {
+	signer, _ := newLocalSigner(t, time.Hour)
+
+	request := newSigningRequest(t, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "n"}}, []certificatesv1.KeyUsage{"unknown"})
+	if _, err := signer.Sign(context.TODO(), request); err == nil {
+		t.Errorf("expected an error for an unknown usage")
+	}
+
+	request = &SigningRequest{CSRPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("x")})}
+	if _, err := signer.Sign(context.TODO(), request); err == nil {
+		t.Errorf("expected an error for a PEM block which is not a certificate signing request")
+	}
+}
+
+func TestRotateCertsWithSigner(t *testing.T) {
+	signer, caCert := newLocalSigner(t, time.Hour)
+	failure := fmt.Errorf("signer unavailable")
+	fail := true
+
+	var rotated []*tls.Certificate
+	var failures []error
+	renewFailures := &counterMock{}
+	m, err := NewManager(&Config{
+		Template: &x509.CertificateRequest{Subject: pkix.Name{CommonName: "n"}},
+		Usages:   []certificatesv1.KeyUsage{certificatesv1.UsageDigitalSignature, certificatesv1.UsageClientAuth},
+		Signer: SignerFunc(func(ctx context.Context, request *SigningRequest) ([]byte, error) {
+			if fail {
+				return nil, failure
+			}
+			return signer.Sign(ctx, request)
+		}),
+		CertificateStore:        &fakeStore{},
+		CertificateRenewFailure: renewFailures,
+		OnRotation: func(old, new *tls.Certificate) {
+			if old != nil {
+				t.Errorf("expected no previous certificate, got %v", old)
+			}
+			rotated = append(rotated, new)
+		},
+		OnRotationFailure: func(err error) {
+			failures = append(failures, err)
+		},
+		Logf: t.Logf,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if ok, err := m.(*manager).rotateCerts(); ok || err != nil {
+		t.Errorf("expected the rotation to fail without error, got %v, %v", ok, err)
+	}
+	if len(failures) != 1 || renewFailures.calls != 1 || len(rotated) != 0 {
+		t.Fatalf("expected a failure, got failures %v, %d failure metrics and rotations %v", failures, renewFailures.calls, rotated)
+	}
+
+	fail = false
+	if ok, err := m.(*manager).rotateCerts(); !ok || err != nil {
+		t.Fatalf("expected the rotation to succeed, got %v, %v", ok, err)
+	}
+	if len(rotated) != 1 || rotated[0] != m.Current() {
+		t.Fatalf("expected the rotation hook to be called with the current certificate, got %v", rotated)
+	}
+	certs, err := x509.ParseCertificates(m.Current().Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := certs[0].CheckSignatureFrom(caCert); err != nil {
+		t.Errorf("expected a certificate issued by the signer: %v", err)
+	}
+}
+
+func TestRotateNow(t *testing.T) {
+	signer, _ := newLocalSigner(t, time.Hour)
+	rotated := make(chan *tls.Certificate, 1)
+	m, err := NewManager(&Config{
+		Template:         &x509.CertificateRequest{Subject: pkix.Name{CommonName: storeCertData.certificate.Leaf.Subject.CommonName}},
+		Usages:           []certificatesv1.KeyUsage{certificatesv1.UsageDigitalSignature, certificatesv1.UsageClientAuth},
+		Signer:           signer,
+		CertificateStore: &fakeStore{cert: storeCertData.certificate},
+		OnRotation: func(old, new *tls.Certificate) {
+			rotated <- new
+		},
+		Logf: t.Logf,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m.Start()
+	defer m.Stop()
+
+	select {
+	case <-rotated:
+		t.Fatalf("unexpected rotation of a valid certificate")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	rotator, ok := m.(Rotator)
+	if !ok {
+		t.Fatalf("expected the manager to implement Rotator")
+	}
+	rotator.RotateNow()
+	select {
+	case cert := <-rotated:
+		if certificatesEqual(cert, storeCertData.certificate) {
+			t.Errorf("expected a new certificate")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("timed out waiting for the requested rotation")
+	}
+}
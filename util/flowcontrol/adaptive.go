@@ -0,0 +1,186 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flowcontrol
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/utils/clock"
+)
+
+// ResponseObserver is implemented by rate limiters that adapt to the responses
+// of the server. The rest client calls ObserveResponse for every response to a
+// request that was throttled by such a rate limiter, except for watches and
+// streams. retryAfter is zero if the response had no Retry-After header.
+type ResponseObserver interface {
+	ObserveResponse(statusCode int, retryAfter time.Duration)
+}
+
+// AdaptiveRateLimiterConfig configures a rate limiter created by NewAdaptiveRateLimiter.
+type AdaptiveRateLimiterConfig struct {
+	// MinQPS and MaxQPS bound the rate. The limiter starts at MaxQPS.
+	MinQPS float32
+	MaxQPS float32
+	// Burst is the maximum number of requests allowed to exceed the rate.
+	Burst int
+	// IncreaseStep is added to the rate after every AdjustInterval in which the
+	// server accepted requests without signalling overload. Defaults to 1.
+	IncreaseStep float32
+	// DecreaseFactor multiplies the rate when the server responds with 429 Too
+	// Many Requests or 503 Service Unavailable. Defaults to 0.5.
+	DecreaseFactor float32
+	// AdjustInterval is the minimum time between two decreases of the rate,
+	// so a burst of rejected requests decreases the rate only once, and
+	// between a change of the rate and the next increase. Defaults to one
+	// second.
+	AdjustInterval time.Duration
+}
+
+// NewAdaptiveRateLimiter returns a rate limiter that adjusts its rate to the
+// signals of the server using additive increase and multiplicative decrease:
+// the rate is cut by DecreaseFactor when the server responds with 429 or 503,
+// and grows by IncreaseStep while it does not. A Retry-After header on such a
+// response additionally holds all requests until it has passed.
+//
+// The returned limiter can be set as rest.Config.RateLimiter, which shares it
+// between all clients created from the config.
+func NewAdaptiveRateLimiter(config AdaptiveRateLimiterConfig) RateLimiter {
+	return newAdaptiveRateLimiter(config, clock.RealClock{})
+}
+
+func newAdaptiveRateLimiter(config AdaptiveRateLimiterConfig, c clock.Clock) *adaptiveRateLimiter {
+	if config.MinQPS <= 0 || config.MinQPS > config.MaxQPS {
+		config.MinQPS = config.MaxQPS
+	}
+	if config.IncreaseStep <= 0 {
+		config.IncreaseStep = 1
+	}
+	if config.DecreaseFactor <= 0 || config.DecreaseFactor >= 1 {
+		config.DecreaseFactor = 0.5
+	}
+	if config.AdjustInterval <= 0 {
+		config.AdjustInterval = time.Second
+	}
+	return &adaptiveRateLimiter{
+		config:       config,
+		clock:        c,
+		limiter:      rate.NewLimiter(rate.Limit(config.MaxQPS), config.Burst),
+		qps:          config.MaxQPS,
+		lastIncrease: c.Now(),
+	}
+}
+
+type adaptiveRateLimiter struct {
+	config  AdaptiveRateLimiterConfig
+	clock   clock.Clock
+	limiter *rate.Limiter
+
+	lock sync.Mutex
+	qps  float32
+	// lastIncrease is when the rate last changed, increases wait for
+	// AdjustInterval after it.
+	lastIncrease time.Time
+	// lastDecrease is when the rate was last decreased, decreases wait for
+	// AdjustInterval after it.
+	lastDecrease time.Time
+	blockedUntil time.Time
+}
+
+var (
+	_ RateLimiter      = &adaptiveRateLimiter{}
+	_ ResponseObserver = &adaptiveRateLimiter{}
+)
+
+func (a *adaptiveRateLimiter) ObserveResponse(statusCode int, retryAfter time.Duration) {
+	overloaded := statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	now := a.clock.Now()
+	qps := a.qps
+	if overloaded {
+		if retryAfter > 0 {
+			if until := now.Add(retryAfter); until.After(a.blockedUntil) {
+				a.blockedUntil = until
+			}
+		}
+		if !a.lastDecrease.IsZero() && now.Sub(a.lastDecrease) < a.config.AdjustInterval {
+			return
+		}
+		a.lastDecrease = now
+		qps *= a.config.DecreaseFactor
+		if qps < a.config.MinQPS {
+			qps = a.config.MinQPS
+		}
+	} else {
+		if now.Sub(a.lastIncrease) < a.config.AdjustInterval {
+			return
+		}
+		qps += a.config.IncreaseStep
+		if qps > a.config.MaxQPS {
+			qps = a.config.MaxQPS
+		}
+	}
+	a.lastIncrease = now
+	if qps != a.qps {
+		a.qps = qps
+		a.limiter.SetLimitAt(now, rate.Limit(qps))
+	}
+}
+
+func (a *adaptiveRateLimiter) TryAccept() bool {
+	now := a.clock.Now()
+	if now.Before(a.blocked()) {
+		return false
+	}
+	return a.limiter.AllowN(now, 1)
+}
+
+func (a *adaptiveRateLimiter) Stop() {}
+
+func (a *adaptiveRateLimiter) QPS() float32 {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.qps
+}
+
+func (a *adaptiveRateLimiter) Accept() {
+	a.Wait(context.Background())
+}
+
+func (a *adaptiveRateLimiter) Wait(ctx context.Context) error {
+	if d := a.blocked().Sub(a.clock.Now()); d > 0 {
+		t := a.clock.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C():
+		}
+	}
+	return a.limiter.Wait(ctx)
+}
+
+func (a *adaptiveRateLimiter) blocked() time.Time {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.blockedUntil
+}
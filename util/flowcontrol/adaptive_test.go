@@ -0,0 +1,93 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flowcontrol
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestAdaptiveRateLimiterAIMD(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	rl := newAdaptiveRateLimiter(AdaptiveRateLimiterConfig{MinQPS: 2, MaxQPS: 10, Burst: 1, IncreaseStep: 2}, fakeClock)
+
+	steps := []struct {
+		advance time.Duration
+		code    int
+		wantQPS float32
+	}{
+		// decreases are at most once per second
+		{advance: 0, code: http.StatusTooManyRequests, wantQPS: 5},
+		{advance: 0, code: http.StatusServiceUnavailable, wantQPS: 5},
+		{advance: time.Second, code: http.StatusServiceUnavailable, wantQPS: 2.5},
+		{advance: time.Second, code: http.StatusTooManyRequests, wantQPS: 2},
+		// increases wait a second after the last change
+		{advance: 0, code: http.StatusOK, wantQPS: 2},
+		{advance: time.Second, code: http.StatusOK, wantQPS: 4},
+		{advance: 0, code: http.StatusOK, wantQPS: 4},
+		{advance: time.Second, code: http.StatusNotFound, wantQPS: 6},
+		// a decrease right after an increase is applied
+		{advance: 0, code: http.StatusTooManyRequests, wantQPS: 3},
+		{advance: time.Second, code: http.StatusOK, wantQPS: 5},
+		{advance: time.Second, code: http.StatusOK, wantQPS: 7},
+		{advance: time.Second, code: http.StatusOK, wantQPS: 9},
+		{advance: time.Second, code: http.StatusOK, wantQPS: 10},
+	}
+	for i, step := range steps {
+		fakeClock.Step(step.advance)
+		rl.ObserveResponse(step.code, 0)
+		if qps := rl.QPS(); qps != step.wantQPS {
+			t.Errorf("step %d: expected QPS %v, got %v", i, step.wantQPS, qps)
+		}
+	}
+}
+
+func TestAdaptiveRateLimiterRetryAfter(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	rl := newAdaptiveRateLimiter(AdaptiveRateLimiterConfig{MaxQPS: 1000, Burst: 100}, fakeClock)
+
+	rl.ObserveResponse(http.StatusTooManyRequests, 5*time.Second)
+	if rl.TryAccept() {
+		t.Errorf("expected requests to be held while Retry-After has not passed")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- rl.Wait(ctx)
+	}()
+	for !fakeClock.HasWaiters() {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-done:
+		t.Fatalf("expected Wait to block until Retry-After has passed")
+	default:
+	}
+	fakeClock.Step(5 * time.Second)
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !rl.TryAccept() {
+		t.Errorf("expected requests to be accepted after Retry-After has passed")
+	}
+}
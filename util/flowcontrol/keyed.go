@@ -0,0 +1,179 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flowcontrol
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"golang.org/x/time/rate"
+	"k8s.io/utils/clock"
+)
+
+// KeyedTokenBucket is a set of token bucket rate limiters, one per key, under
+// a shared global token bucket. A request for a key is admitted only when both
+// the bucket of the key and the global bucket have a token, so the limits of
+// individual keys, for example target clusters or resources, can be tuned
+// without exceeding the overall budget. A KeyedTokenBucket is safe for
+// concurrent use.
+type KeyedTokenBucket struct {
+	clock  clock.Clock
+	global *rate.Limiter
+
+	lock         sync.Mutex
+	defaultQPS   float32
+	defaultBurst int
+	limiters     map[string]*rate.Limiter
+}
+
+// NewKeyedTokenBucket returns a KeyedTokenBucket with a global limit of
+// globalQPS and globalBurst. Keys without an explicit limit set by SetLimit
+// are limited to defaultQPS and defaultBurst.
+func NewKeyedTokenBucket(globalQPS float32, globalBurst int, defaultQPS float32, defaultBurst int) *KeyedTokenBucket {
+	return newKeyedTokenBucket(globalQPS, globalBurst, defaultQPS, defaultBurst, clock.RealClock{})
+}
+
+func newKeyedTokenBucket(globalQPS float32, globalBurst int, defaultQPS float32, defaultBurst int, c clock.Clock) *KeyedTokenBucket {
+	return &KeyedTokenBucket{
+		clock:        c,
+		global:       rate.NewLimiter(rate.Limit(globalQPS), globalBurst),
+		defaultQPS:   defaultQPS,
+		defaultBurst: defaultBurst,
+		limiters:     map[string]*rate.Limiter{},
+	}
+}
+
+// SetLimit sets the limit of key. It can be called while requests for key are
+// being throttled.
+func (k *KeyedTokenBucket) SetLimit(key string, qps float32, burst int) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	if l, ok := k.limiters[key]; ok {
+		now := k.clock.Now()
+		l.SetLimitAt(now, rate.Limit(qps))
+		l.SetBurstAt(now, burst)
+		return
+	}
+	k.limiters[key] = rate.NewLimiter(rate.Limit(qps), burst)
+}
+
+// Forget drops the state of key. The next request for key starts with a full
+// bucket using the default limit.
+func (k *KeyedTokenBucket) Forget(key string) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	delete(k.limiters, key)
+}
+
+func (k *KeyedTokenBucket) limiterFor(key string) *rate.Limiter {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	l, ok := k.limiters[key]
+	if !ok {
+		l = rate.NewLimiter(rate.Limit(k.defaultQPS), k.defaultBurst)
+		k.limiters[key] = l
+	}
+	return l
+}
+
+// QPS returns the QPS limit of key.
+func (k *KeyedTokenBucket) QPS(key string) float32 {
+	return float32(k.limiterFor(key).Limit())
+}
+
+// TryAccept returns true if a token of key and a global token are taken
+// immediately. Otherwise, it returns false and takes no token.
+func (k *KeyedTokenBucket) TryAccept(key string) bool {
+	now := k.clock.Now()
+	keyed := k.limiterFor(key).ReserveN(now, 1)
+	if !keyed.OK() || keyed.DelayFrom(now) > 0 {
+		keyed.CancelAt(now)
+		return false
+	}
+	global := k.global.ReserveN(now, 1)
+	if !global.OK() || global.DelayFrom(now) > 0 {
+		global.CancelAt(now)
+		keyed.CancelAt(now)
+		return false
+	}
+	return true
+}
+
+// Wait returns nil once a token of key and a global token are taken, or an
+// error if ctx is done first or its deadline does not leave enough time.
+func (k *KeyedTokenBucket) Wait(ctx context.Context, key string) error {
+	now := k.clock.Now()
+	keyed := k.limiterFor(key).ReserveN(now, 1)
+	if !keyed.OK() {
+		return fmt.Errorf("rate limit of key %q does not allow any request", key)
+	}
+	global := k.global.ReserveN(now, 1)
+	if !global.OK() {
+		keyed.CancelAt(now)
+		return fmt.Errorf("global rate limit does not allow any request")
+	}
+	cancel := func() {
+		now := k.clock.Now()
+		keyed.CancelAt(now)
+		global.CancelAt(now)
+	}
+
+	delay := keyed.DelayFrom(now)
+	if d := global.DelayFrom(now); d > delay {
+		delay = d
+	}
+	if delay == 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(now.Add(delay)) {
+		cancel()
+		return fmt.Errorf("rate limiter Wait(%q) would exceed context deadline", key)
+	}
+	t := k.clock.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C():
+		return nil
+	case <-ctx.Done():
+		cancel()
+		return ctx.Err()
+	}
+}
+
+// ForKey returns a RateLimiter that throttles requests as key, for example to
+// be set as rest.Config.RateLimiter of the client of a single cluster.
+func (k *KeyedTokenBucket) ForKey(key string) RateLimiter {
+	return &keyedRateLimiter{bucket: k, key: key}
+}
+
+type keyedRateLimiter struct {
+	bucket *KeyedTokenBucket
+	key    string
+}
+
+var _ RateLimiter = &keyedRateLimiter{}
+
+func (r *keyedRateLimiter) TryAccept() bool { return r.bucket.TryAccept(r.key) }
+
+func (r *keyedRateLimiter) Stop() {}
+
+func (r *keyedRateLimiter) QPS() float32 { return r.bucket.QPS(r.key) }
+
+func (r *keyedRateLimiter) Accept() { r.bucket.Wait(context.Background(), r.key) }
+
+func (r *keyedRateLimiter) Wait(ctx context.Context) error { return r.bucket.Wait(ctx, r.key) }
@@ -0,0 +1,88 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flowcontrol
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestKeyedTokenBucketTryAccept(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	k := newKeyedTokenBucket(1, 5, 1, 2, fakeClock)
+	k.SetLimit("big", 1, 4)
+
+	for i := 0; i < 2; i++ {
+		if !k.TryAccept("small") {
+			t.Errorf("expected request %d of small to be accepted", i)
+		}
+	}
+	if k.TryAccept("small") {
+		t.Errorf("expected small to be limited by its own burst")
+	}
+	for i := 0; i < 3; i++ {
+		if !k.TryAccept("big") {
+			t.Errorf("expected request %d of big to be accepted", i)
+		}
+	}
+	// big has a token left, but the global bucket is empty.
+	if k.TryAccept("big") {
+		t.Errorf("expected big to be limited by the global burst")
+	}
+
+	fakeClock.Step(time.Second)
+	if !k.ForKey("big").TryAccept() {
+		t.Errorf("expected big to be accepted after the global bucket refilled")
+	}
+	if k.ForKey("small").TryAccept() {
+		t.Errorf("expected small to be rejected while the global bucket is empty")
+	}
+	if qps := k.ForKey("big").QPS(); qps != 1 {
+		t.Errorf("expected QPS 1, got %v", qps)
+	}
+}
+
+func TestKeyedTokenBucketWait(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	k := newKeyedTokenBucket(100, 100, 1, 1, fakeClock)
+
+	if err := k.Wait(context.Background(), "a"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- k.Wait(ctx, "a")
+	}()
+	for !fakeClock.HasWaiters() {
+		time.Sleep(time.Millisecond)
+	}
+	fakeClock.Step(time.Second)
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	shortCtx, shortCancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer shortCancel()
+	if err := k.Wait(shortCtx, "a"); err == nil {
+		t.Errorf("expected error when the deadline does not leave enough time")
+	}
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flowcontrol
+
+import (
+	"sync"
+)
+
+// sharedRateLimiters holds the rate limiters registered by Shared by name.
+var sharedRateLimiters = struct {
+	lock     sync.Mutex
+	limiters map[string]RateLimiter
+}{limiters: map[string]RateLimiter{}}
+
+// Shared returns the token bucket rate limiter registered under name in the
+// process, registering one limited to qps and burst on first use. Controllers
+// calling the same external dependency, for example a cloud provider API, can
+// look up the same limiter to throttle their calls together rather than each
+// on its own. The limits of the first call win; later calls return the
+// registered limiter whatever their limits.
+func Shared(name string, qps float32, burst int) RateLimiter {
+	sharedRateLimiters.lock.Lock()
+	defer sharedRateLimiters.lock.Unlock()
+	limiter, ok := sharedRateLimiters.limiters[name]
+	if !ok {
+		limiter = NewTokenBucketRateLimiter(qps, burst)
+		sharedRateLimiters.limiters[name] = limiter
+	}
+	return limiter
+}
+
+// RegisterShared registers limiter under name, replacing the limiter
+// registered so far, so that later calls to Shared return it. It allows
+// processes to configure the limits of shared limiters up front, or to share
+// limiters of other kinds. Limiters returned by earlier calls to Shared keep
+// their limits.
+func RegisterShared(name string, limiter RateLimiter) {
+	sharedRateLimiters.lock.Lock()
+	defer sharedRateLimiters.lock.Unlock()
+	sharedRateLimiters.limiters[name] = limiter
+}
+
+// UnregisterShared removes the limiter registered under name, if any.
+func UnregisterShared(name string) {
+	sharedRateLimiters.lock.Lock()
+	defer sharedRateLimiters.lock.Unlock()
+	delete(sharedRateLimiters.limiters, name)
+}
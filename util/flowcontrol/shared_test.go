@@ -0,0 +1,54 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flowcontrol
+
+import (
+	"testing"
+)
+
+func TestShared(t *testing.T) {
+	defer UnregisterShared("cloud-api")
+
+	limiter := Shared("cloud-api", 1, 2)
+	if other := Shared("cloud-api", 100, 100); other != limiter {
+		t.Fatalf("expected the registered limiter to be shared")
+	}
+	if qps := limiter.QPS(); qps != 1 {
+		t.Errorf("expected the limits of the first call to win, got %v QPS", qps)
+	}
+	// the burst is shared by all the users of the limiter
+	if !limiter.TryAccept() || !Shared("cloud-api", 1, 2).TryAccept() {
+		t.Fatalf("expected the burst to be accepted")
+	}
+	if Shared("cloud-api", 1, 2).TryAccept() {
+		t.Errorf("expected the shared burst to be exhausted")
+	}
+	if Shared("other-api", 1, 2) == limiter {
+		t.Errorf("expected limiters of other names to be distinct")
+	}
+	UnregisterShared("other-api")
+
+	registered := NewFakeAlwaysRateLimiter()
+	RegisterShared("cloud-api", registered)
+	if Shared("cloud-api", 1, 2) != registered {
+		t.Errorf("expected the registered limiter to be returned")
+	}
+	UnregisterShared("cloud-api")
+	if Shared("cloud-api", 5, 5).QPS() != 5 {
+		t.Errorf("expected a new limiter once unregistered")
+	}
+}
@@ -20,15 +20,66 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sync"
 )
 
+var (
+	homeDirFuncLock sync.RWMutex
+	homeDirFunc     func() string
+)
+
+// SetHomeDirFunc overrides the home directory returned by HomeDir with the
+// result of fn, unless fn returns an empty string. This lets programs running
+// without a usable home directory relocate the files of client-go, like
+// kubeconfig files. A nil fn removes the override.
+func SetHomeDirFunc(fn func() string) {
+	homeDirFuncLock.Lock()
+	defer homeDirFuncLock.Unlock()
+	homeDirFunc = fn
+}
+
+// ConfigDir returns the base directory of user configuration files following
+// the XDG base directory specification: $XDG_CONFIG_HOME if set, otherwise
+// the .config directory in HomeDir, or an empty string if neither is set.
+func ConfigDir() string {
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+// CacheDir returns the base directory of user cache files following the XDG
+// base directory specification: $XDG_CACHE_HOME if set, otherwise the .cache
+// directory in HomeDir, or an empty string if neither is set.
+func CacheDir() string {
+	return xdgDir("XDG_CACHE_HOME", ".cache")
+}
+
+func xdgDir(envVar, homeSubdir string) string {
+	// relative paths are invalid and must be ignored
+	if dir := os.Getenv(envVar); filepath.IsAbs(dir) {
+		return dir
+	}
+	if home := HomeDir(); len(home) > 0 {
+		return filepath.Join(home, homeSubdir)
+	}
+	return ""
+}
+
 // HomeDir returns the home directory for the current user.
 // On Windows:
 // 1. the first of %HOME%, %HOMEDRIVE%%HOMEPATH%, %USERPROFILE% containing a `.kube\config` file is returned.
 // 2. if none of those locations contain a `.kube\config` file, the first of %HOME%, %USERPROFILE%, %HOMEDRIVE%%HOMEPATH% that exists and is writeable is returned.
 // 3. if none of those locations are writeable, the first of %HOME%, %USERPROFILE%, %HOMEDRIVE%%HOMEPATH% that exists is returned.
 // 4. if none of those locations exists, the first of %HOME%, %USERPROFILE%, %HOMEDRIVE%%HOMEPATH% that is set is returned.
+// Programs can override the home directory with SetHomeDirFunc.
 func HomeDir() string {
+	homeDirFuncLock.RLock()
+	fn := homeDirFunc
+	homeDirFuncLock.RUnlock()
+	if fn != nil {
+		if home := fn(); len(home) > 0 {
+			return home
+		}
+	}
+
 	if runtime.GOOS == "windows" {
 		home := os.Getenv("HOME")
 		homeDriveHomePath := ""
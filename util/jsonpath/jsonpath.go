@@ -57,13 +57,26 @@ func (j *JSONPath) AllowMissingKeys(allow bool) *JSONPath {
 	return j
 }
 
-// Parse parses the given template and returns an error.
+// Parse parses the given template and returns an error. Once parsed, the
+// template may be executed any number of times.
 func (j *JSONPath) Parse(text string) error {
 	var err error
 	j.parser, err = Parse(j.name, text)
 	return err
 }
 
+// Copy returns a JSONPath sharing the parsed template of j, without parsing
+// it again. JSONPaths are not safe for concurrent use, but their copies may be
+// executed concurrently.
+func (j *JSONPath) Copy() *JSONPath {
+	return &JSONPath{
+		name:             j.name,
+		parser:           j.parser,
+		allowMissingKeys: j.allowMissingKeys,
+		outputJSON:       j.outputJSON,
+	}
+}
+
 // Execute bounds data into template and writes the result.
 func (j *JSONPath) Execute(wr io.Writer, data interface{}) error {
 	fullResults, err := j.FindResults(data)
@@ -83,8 +96,16 @@ func (j *JSONPath) FindResults(data interface{}) ([][]reflect.Value, error) {
 		return nil, fmt.Errorf("%s is an incomplete jsonpath template", j.name)
 	}
 
+	// reset the state left by a previous execution which failed in a range
+	j.beginRange, j.inRange, j.endRange = 0, 0, 0
+	j.lastEndNode = nil
+	return j.findResults(j.parser.Root.Nodes, data)
+}
+
+// findResults evaluates nodes, without modifying the parsed template so that
+// it can be executed again.
+func (j *JSONPath) findResults(nodes []Node, data interface{}) ([][]reflect.Value, error) {
 	cur := []reflect.Value{reflect.ValueOf(data)}
-	nodes := j.parser.Root.Nodes
 	fullResult := [][]reflect.Value{}
 	for i := 0; i < len(nodes); i++ {
 		node := nodes[i]
@@ -105,8 +126,7 @@ func (j *JSONPath) FindResults(data interface{}) ([][]reflect.Value, error) {
 			j.inRange++
 			if len(results) > 0 {
 				for _, value := range results {
-					j.parser.Root.Nodes = nodes[i+1:]
-					nextResults, err := j.FindResults(value.Interface())
+					nextResults, err := j.findResults(nodes[i+1:], value.Interface())
 					if err != nil {
 						return nil, err
 					}
@@ -115,8 +135,7 @@ func (j *JSONPath) FindResults(data interface{}) ([][]reflect.Value, error) {
 			} else {
 				// If the range has no results, we still need to process the nodes within the range
 				// so the position will advance to the end node
-				j.parser.Root.Nodes = nodes[i+1:]
-				_, err := j.FindResults(nil)
+				_, err := j.findResults(nodes[i+1:], nil)
 				if err != nil {
 					return nil, err
 				}
@@ -948,3 +948,38 @@ func TestStep(t *testing.T) {
 		t,
 	)
 }
+
+func TestExecuteTwice(t *testing.T) {
+	var input interface{}
+	if err := json.Unmarshal([]byte(`{"items":[{"name":"a","values":[1,2]},{"name":"b","values":[3]}]}`), &input); err != nil {
+		t.Fatal(err)
+	}
+	j := New("twice")
+	if err := j.Parse(`{range .items[*]}{.name}:{range .values[*]}{@},{end};{end}`); err != nil {
+		t.Fatal(err)
+	}
+	// a copy shares the parsed template and executes concurrently
+	c := j.Copy()
+	copied := make(chan string)
+	go func() {
+		buf := new(bytes.Buffer)
+		if err := c.Execute(buf, input); err != nil {
+			t.Errorf("unexpected error executing the copy: %v", err)
+		}
+		copied <- buf.String()
+	}()
+
+	expected := "a:1,2,;b:3,;"
+	for i := 0; i < 2; i++ {
+		buf := new(bytes.Buffer)
+		if err := j.Execute(buf, input); err != nil {
+			t.Fatalf("unexpected error executing %d times: %v", i+1, err)
+		}
+		if buf.String() != expected {
+			t.Errorf("executing %d times: expected %q, got %q", i+1, expected, buf.String())
+		}
+	}
+	if out := <-copied; out != expected {
+		t.Errorf("copy: expected %q, got %q", expected, out)
+	}
+}
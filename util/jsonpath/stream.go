@@ -0,0 +1,255 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// ExecuteStream evaluates the template against each of the JSON objects read
+// from r, and writes the results. See FindStreamResults for the objects read.
+func (j *JSONPath) ExecuteStream(wr io.Writer, r io.Reader) error {
+	return j.FindStreamResults(r, func(results [][]reflect.Value) error {
+		for ix := range results {
+			if err := j.PrintResults(wr, results[ix]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// FindStreamResults evaluates the template against each of the JSON objects
+// read from r, and calls fn with the results of each object. r holds a
+// sequence of JSON values, each being:
+//   - a list, holding an items array, whose items are evaluated one at a
+//     time, without decoding the list at once;
+//   - a watch event, holding a type and an object, whose object is evaluated;
+//   - any other object, which is evaluated.
+//
+// Only the fields of the objects which the template refers to are decoded,
+// unless the template holds ranges, or refers to indices, filters or
+// wildcards.
+func (j *JSONPath) FindStreamResults(r io.Reader, fn func(results [][]reflect.Value) error) error {
+	if j.parser == nil {
+		return fmt.Errorf("%s is an incomplete jsonpath template", j.name)
+	}
+	fields := projectionOf(j.parser.Root)
+	evaluate := func(raw json.RawMessage) error {
+		data, err := fields.decode(raw)
+		if err != nil {
+			return err
+		}
+		results, err := j.FindResults(data)
+		if err != nil {
+			return err
+		}
+		return fn(results)
+	}
+
+	decoder := json.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if token != json.Delim('{') {
+			return fmt.Errorf("expected a JSON object, got %v", token)
+		}
+		if err := decodeStreamObject(decoder, evaluate); err != nil {
+			return err
+		}
+	}
+}
+
+// decodeStreamObject decodes the fields of the object whose opening brace was
+// read from decoder, and evaluates its items, its object or itself.
+func decodeStreamObject(decoder *json.Decoder, evaluate func(raw json.RawMessage) error) error {
+	fields := map[string]json.RawMessage{}
+	var keys []string
+	isList := false
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return fmt.Errorf("expected an object key, got %v", token)
+		}
+		if key == "items" {
+			isList = true
+			if err := decodeStreamItems(decoder, evaluate); err != nil {
+				return err
+			}
+			continue
+		}
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return err
+		}
+		fields[key] = raw
+		keys = append(keys, key)
+	}
+	// closing brace
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+
+	if isList {
+		return nil
+	}
+	if object, ok := fields["object"]; ok && len(fields) == 2 && len(fields["type"]) > 0 {
+		return evaluate(object)
+	}
+	// the fields were already split, join them back
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+		encodedKey, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+		buffer.Write(encodedKey)
+		buffer.WriteByte(':')
+		buffer.Write(fields[key])
+	}
+	buffer.WriteByte('}')
+	return evaluate(buffer.Bytes())
+}
+
+// decodeStreamItems evaluates the items of the items array whose key was read
+// from decoder, one at a time.
+func decodeStreamItems(decoder *json.Decoder, evaluate func(raw json.RawMessage) error) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		// null items
+		return nil
+	}
+	if token != json.Delim('[') {
+		return fmt.Errorf("expected an items array, got %v", token)
+	}
+	for decoder.More() {
+		var item json.RawMessage
+		if err := decoder.Decode(&item); err != nil {
+			return err
+		}
+		if err := evaluate(item); err != nil {
+			return err
+		}
+	}
+	// closing bracket
+	_, err = decoder.Token()
+	return err
+}
+
+// projection is the set of the fields of an object which a template refers
+// to. A nil projection refers to the whole object.
+type projection map[string]projection
+
+// projectionOf returns the projection of the fields root refers to, or nil if
+// it cannot tell which fields it refers to.
+func projectionOf(root *ListNode) projection {
+	fields := projection{}
+	for _, node := range root.Nodes {
+		list, ok := node.(*ListNode)
+		if !ok {
+			// text
+			continue
+		}
+		if !fields.add(list.Nodes) {
+			return nil
+		}
+	}
+	return fields
+}
+
+// add adds the fields the path of nodes refers to, and returns false if it
+// cannot tell which fields they are.
+func (p projection) add(nodes []Node) bool {
+	for i, node := range nodes {
+		switch node := node.(type) {
+		case *FieldNode:
+			next, ok := p[node.Value]
+			if ok && next == nil {
+				// the whole field is referred to already
+				return true
+			}
+			if !ok {
+				next = projection{}
+				p[node.Value] = next
+			}
+			if i == len(nodes)-1 {
+				// the value of the path is the whole field
+				p[node.Value] = nil
+				return true
+			}
+			p = next
+		case *TextNode, *IntNode, *FloatNode, *BoolNode:
+			// the path refers to a literal, and not to the object
+			return true
+		default:
+			// ranges, and the paths which refer to indices, filters or to
+			// any field
+			return false
+		}
+	}
+	// the path refers to the object itself
+	return false
+}
+
+// decode decodes the fields of the JSON value raw which p refers to. The
+// values other than objects are decoded as a whole.
+func (p projection) decode(raw json.RawMessage) (interface{}, error) {
+	if trimmed := bytes.TrimLeft(raw, " \t\r\n"); p == nil || len(trimmed) == 0 || trimmed[0] != '{' {
+		var data interface{}
+		if err := json.Unmarshal(raw, &data); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	data := make(map[string]interface{}, len(p))
+	for key, fieldProjection := range p {
+		field, ok := fields[key]
+		if !ok {
+			continue
+		}
+		value, err := fieldProjection.decode(field)
+		if err != nil {
+			return nil, err
+		}
+		data[key] = value
+	}
+	return data, nil
+}
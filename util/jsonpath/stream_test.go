@@ -0,0 +1,194 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var streamPods = []string{
+	`{"kind":"Pod","metadata":{"name":"a","labels":{"app":"web"}},"spec":{"containers":[{"name":"nginx"},{"name":"sidecar"}]},"status":{"phase":"Running"}}`,
+	`{"kind":"Pod","metadata":{"name":"b"},"spec":{"containers":[{"name":"redis"}]},"status":{"phase":"Pending"}}`,
+	`{"kind":"Pod","metadata":{"name":"c","labels":{"app":"db"}},"spec":{"containers":[{"name":"postgres"}]}}`,
+}
+
+func TestExecuteStream(t *testing.T) {
+	list := `{"kind":"PodList","apiVersion":"v1","metadata":{"resourceVersion":"10"},"items":[` + strings.Join(streamPods, ",") + `]}`
+	var events []string
+	for _, pod := range streamPods {
+		events = append(events, `{"type":"ADDED","object":`+pod+`}`)
+	}
+	inputs := map[string]string{
+		"list":    list,
+		"watch":   strings.Join(events, "\n"),
+		"objects": strings.Join(streamPods, "\n"),
+	}
+	templates := []string{
+		`{.metadata.name} `,
+		`{.metadata.name}={.status.phase};`,
+		`{.metadata.labels.app},`,
+		`{.metadata} `,
+		`{range .spec.containers[*]}{.name},{end}`,
+		`{.spec.containers[0].name} `,
+		`{.spec.containers[?(@.name=="redis")].name}`,
+		`{..name} `,
+	}
+
+	for name, input := range inputs {
+		for _, template := range templates {
+			j := New(name).AllowMissingKeys(true)
+			if err := j.Parse(template); err != nil {
+				t.Fatalf("%s: unexpected error parsing %s: %v", name, template, err)
+			}
+
+			// the results of the objects decoded at once
+			expected := &bytes.Buffer{}
+			for _, pod := range streamPods {
+				var data interface{}
+				if err := json.Unmarshal([]byte(pod), &data); err != nil {
+					t.Fatal(err)
+				}
+				if err := j.Execute(expected, data); err != nil {
+					t.Fatalf("%s: unexpected error executing %s: %v", name, template, err)
+				}
+			}
+
+			out := &bytes.Buffer{}
+			if err := j.ExecuteStream(out, strings.NewReader(input)); err != nil {
+				t.Fatalf("%s: unexpected error streaming %s: %v", name, template, err)
+			}
+			if template == `{..name} ` {
+				// the maps are visited in random order
+				if e, a := sortedFields(expected.String()), sortedFields(out.String()); !reflect.DeepEqual(e, a) {
+					t.Errorf("%s: %s: expected %v, got %v", name, template, e, a)
+				}
+				continue
+			}
+			if expected.String() != out.String() {
+				t.Errorf("%s: %s: expected %q, got %q", name, template, expected.String(), out.String())
+			}
+		}
+	}
+}
+
+func sortedFields(s string) []string {
+	fields := strings.Fields(s)
+	sort.Strings(fields)
+	return fields
+}
+
+func TestExecuteStreamErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		input    string
+		expected string
+	}{
+		{
+			name:     "missing key",
+			template: `{.metadata.namespace}`,
+			input:    `{"metadata":{"name":"a"}}`,
+			expected: "namespace is not found",
+		},
+		{
+			name:     "not an object",
+			template: `{.metadata.name}`,
+			input:    `["a"]`,
+			expected: "expected a JSON object",
+		},
+		{
+			name:     "invalid items",
+			template: `{.metadata.name}`,
+			input:    `{"items":{"a":"b"}}`,
+			expected: "expected an items array",
+		},
+		{
+			name:     "truncated list",
+			template: `{.metadata.name}`,
+			input:    `{"items":[{"metadata":{"name":"a"}},`,
+			expected: "unexpected",
+		},
+	}
+	for _, test := range tests {
+		j := New(test.name)
+		if err := j.Parse(test.template); err != nil {
+			t.Fatal(err)
+		}
+		err := j.ExecuteStream(&bytes.Buffer{}, strings.NewReader(test.input))
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", test.name, test.expected, err)
+		}
+	}
+}
+
+func TestProjection(t *testing.T) {
+	tests := []struct {
+		template string
+		expected projection
+	}{
+		{
+			template: `{.metadata.name}`,
+			expected: projection{"metadata": projection{"name": nil}},
+		},
+		{
+			template: `name: {.metadata.name} {.metadata} {.status.phase}`,
+			expected: projection{"metadata": nil, "status": projection{"phase": nil}},
+		},
+		{
+			template: `{.metadata} {.metadata.name}`,
+			expected: projection{"metadata": nil},
+		},
+		{
+			template: `{.spec.containers[0].name}`,
+		},
+		{
+			template: `{range .items[*]}{.metadata.name}{end}`,
+		},
+		{
+			template: `{@}`,
+		},
+	}
+	for _, test := range tests {
+		parser, err := Parse("projection", test.template)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p := projectionOf(parser.Root); !reflect.DeepEqual(test.expected, p) {
+			t.Errorf("%s: expected %v, got %v", test.template, test.expected, p)
+		}
+	}
+}
+
+func TestProjectionDecode(t *testing.T) {
+	p := projection{"metadata": projection{"name": nil}, "status": nil}
+	data, err := p.decode(json.RawMessage(`{"metadata":{"name":"a","labels":{"app":"web"}},"spec":{"replicas":1},"status":{"phase":"Running"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "a"},
+		"status":   map[string]interface{}{"phase": "Running"},
+	}
+	if !reflect.DeepEqual(expected, data) {
+		t.Errorf("expected %v, got %v", expected, data)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	"k8s.io/klog/v2"
+)
+
+// klogSink is the default sink of the logger of client-go, logging through
+// klog. Messages without key/value pairs are logged like klog.Info does, so
+// that the output of client-go is unchanged for embedders using klog.
+type klogSink struct {
+	callDepth int
+	prefix    string
+	values    []interface{}
+}
+
+var _ logr.CallDepthLogSink = &klogSink{}
+
+func (s *klogSink) Init(info logr.RuntimeInfo) {
+	s.callDepth += info.CallDepth
+}
+
+func (s *klogSink) Enabled(level int) bool {
+	return bool(klog.V(klog.Level(level)).Enabled())
+}
+
+func (s *klogSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	if len(s.prefix) > 0 {
+		msg = s.prefix + ": " + msg
+	}
+	if len(s.values) == 0 && len(keysAndValues) == 0 {
+		klog.InfoDepth(s.callDepth+1, msg)
+		return
+	}
+	klog.InfoSDepth(s.callDepth+1, msg, s.keysAndValues(keysAndValues)...)
+}
+
+func (s *klogSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	if len(s.prefix) > 0 {
+		msg = s.prefix + ": " + msg
+	}
+	klog.ErrorSDepth(s.callDepth+1, err, msg, s.keysAndValues(keysAndValues)...)
+}
+
+// warning logs msg and keysAndValues like klog.InfoS does, but at the
+// warning severity. It is called by Warning, whose frame replaces the one of
+// logr.Logger accounted for by Init.
+func (s *klogSink) warning(msg string, keysAndValues ...interface{}) {
+	if len(s.prefix) > 0 {
+		msg = s.prefix + ": " + msg
+	}
+	keysAndValues = s.keysAndValues(keysAndValues)
+	if len(keysAndValues) == 0 {
+		klog.WarningDepth(s.callDepth, msg)
+		return
+	}
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "%q", msg)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		var value interface{} = "(MISSING)"
+		if i+1 < len(keysAndValues) {
+			value = keysAndValues[i+1]
+		}
+		switch v := value.(type) {
+		case string:
+			fmt.Fprintf(b, " %s=%q", keysAndValues[i], v)
+		case error:
+			fmt.Fprintf(b, " %s=%q", keysAndValues[i], v.Error())
+		case fmt.Stringer:
+			fmt.Fprintf(b, " %s=%q", keysAndValues[i], v.String())
+		default:
+			fmt.Fprintf(b, " %s=%+v", keysAndValues[i], v)
+		}
+	}
+	klog.WarningDepth(s.callDepth, b.String())
+}
+
+func (s *klogSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	clone := *s
+	clone.values = s.keysAndValues(keysAndValues)
+	return &clone
+}
+
+func (s *klogSink) WithName(name string) logr.LogSink {
+	clone := *s
+	if len(s.prefix) > 0 {
+		clone.prefix = s.prefix + "/" + name
+	} else {
+		clone.prefix = name
+	}
+	return &clone
+}
+
+func (s *klogSink) WithCallDepth(depth int) logr.LogSink {
+	clone := *s
+	clone.callDepth += depth
+	return &clone
+}
+
+// keysAndValues returns the values of the sink followed by keysAndValues.
+func (s *klogSink) keysAndValues(keysAndValues []interface{}) []interface{} {
+	if len(s.values) == 0 {
+		return keysAndValues
+	}
+	all := make([]interface{}, 0, len(s.values)+len(keysAndValues))
+	all = append(all, s.values...)
+	return append(all, keysAndValues...)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package logging is the injection point of the logger of client-go. By
+// default, client-go logs through klog; embedders may route its logs into
+// their own structured logging pipeline with SetLogger, and scope loggers to
+// requests or operations with NewContext.
+package logging
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-logr/logr"
+)
+
+var (
+	lock   sync.RWMutex
+	logger = logr.New(&klogSink{})
+)
+
+// SetLogger sets the logger of client-go. It should be called before
+// client-go is used, components keeping the logger they were started with.
+func SetLogger(l logr.Logger) {
+	lock.Lock()
+	defer lock.Unlock()
+	logger = l
+}
+
+// Logger returns the logger of client-go.
+func Logger() logr.Logger {
+	lock.RLock()
+	defer lock.RUnlock()
+	return logger
+}
+
+// NewContext returns a copy of ctx holding l, which client-go uses instead of
+// its logger for the operations done with the context.
+func NewContext(ctx context.Context, l logr.Logger) context.Context {
+	return logr.NewContext(ctx, l)
+}
+
+// FromContext returns the logger held by ctx, or the logger of client-go if
+// ctx holds none.
+func FromContext(ctx context.Context) logr.Logger {
+	if l, err := logr.FromContext(ctx); err == nil {
+		return l
+	}
+	return Logger()
+}
+
+// Warning logs msg and keysAndValues at the warning severity. logr has no
+// such severity, so loggers other than the default one log them as an
+// informational message of level 0, the logr convention for warnings.
+func Warning(l logr.Logger, msg string, keysAndValues ...interface{}) {
+	if s, ok := l.GetSink().(*klogSink); ok {
+		s.warning(msg, keysAndValues...)
+		return
+	}
+	l.WithCallDepth(1).Info(msg, keysAndValues...)
+}
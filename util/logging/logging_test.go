@@ -0,0 +1,95 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+
+	"k8s.io/klog/v2"
+)
+
+func TestSetLogger(t *testing.T) {
+	defer SetLogger(Logger())
+
+	var lines []string
+	SetLogger(funcr.New(func(prefix, args string) {
+		lines = append(lines, args)
+	}, funcr.Options{}))
+
+	Logger().Info("global", "key", "value")
+	ctx := NewContext(context.Background(), funcr.New(func(prefix, args string) {
+		lines = append(lines, "scoped "+args)
+	}, funcr.Options{}))
+	FromContext(ctx).Info("request")
+	FromContext(context.Background()).Info("fallback")
+	Warning(Logger(), "warning", "key", "value")
+
+	expected := []string{
+		`"level"=0 "msg"="global" "key"="value"`,
+		`scoped "level"=0 "msg"="request"`,
+		`"level"=0 "msg"="fallback"`,
+		`"level"=0 "msg"="warning" "key"="value"`,
+	}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+}
+
+func TestKlogSink(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	klog.SetOutput(buffer)
+	klog.LogToStderr(false)
+	defer klog.LogToStderr(true)
+
+	logger := logr.New(&klogSink{})
+	logger.Info("plain text: unchanged")
+	logger.WithName("reflector").WithValues("type", "v1.Pod").Info("Watch closed", "items", 3)
+	logger.Error(errors.New("failed"), "Failed to list")
+	Warning(logger.WithName("reflector").WithValues("type", "v1.Pod"), "Failed to list", "err", errors.New("failed"), "items", 3)
+	Warning(logger, "plain warning")
+	logger.V(10).Info("too verbose")
+	klog.Flush()
+
+	output := buffer.String()
+	for _, expected := range []string{
+		"] plain text: unchanged\n",
+		`] "reflector: Watch closed" type="v1.Pod" items=3` + "\n",
+		`] "Failed to list" err="failed"` + "\n",
+		`] "reflector: Failed to list" type="v1.Pod" err="failed" items=3` + "\n",
+		"] plain warning\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("%q does not contain expected output %q", output, expected)
+		}
+	}
+	if !strings.Contains(output, "\nW") {
+		t.Errorf("expected a warning in %q", output)
+	}
+	if strings.Contains(output, "too verbose") {
+		t.Errorf("unexpected verbose output in %q", output)
+	}
+	if !strings.Contains(output, "logging_test.go") {
+		t.Errorf("expected the caller to be logged, got %q", output)
+	}
+}
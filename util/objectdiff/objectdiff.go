@@ -0,0 +1,163 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package objectdiff compares the desired state of objects with their current
+// state, so that controllers can skip updates which would not change anything.
+package objectdiff
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// serverFields are the paths of the fields populated by the server, which are
+// always ignored.
+var serverFields = [][]string{
+	{"metadata", "uid"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "generation"},
+	{"metadata", "creationTimestamp"},
+	{"metadata", "deletionTimestamp"},
+	{"metadata", "deletionGracePeriodSeconds"},
+	{"metadata", "managedFields"},
+	{"metadata", "selfLink"},
+}
+
+// Options configures the comparison of objects.
+type Options struct {
+	// CompareStatus compares the status of the objects, which is ignored
+	// by default since updates of the main resource do not write it.
+	CompareStatus bool
+	// IgnoredFields are the paths of additional fields to ignore, like
+	// {"metadata", "annotations", "example.com/last-sync"}.
+	IgnoredFields [][]string
+}
+
+// Diff returns the paths of the fields set in desired whose value differs in
+// current, like "spec.template.spec.containers[0].image", sorted. The fields
+// unset in desired are considered defaulted or populated by the server and
+// are ignored, as are the fields populated by the server in the metadata:
+// resourceVersion, uid, generation, managedFields and the timestamps. Lists
+// are compared element by element, a list of a different length differing
+// as a whole.
+//
+// The objects are compared in their unstructured form, so typed and
+// unstructured objects may be compared with one another.
+func Diff(desired, current runtime.Object, options Options) ([]string, error) {
+	desiredContent, err := toUnstructured(desired)
+	if err != nil {
+		return nil, err
+	}
+	currentContent, err := toUnstructured(current)
+	if err != nil {
+		return nil, err
+	}
+	ignored := append(append([][]string{}, serverFields...), options.IgnoredFields...)
+	if !options.CompareStatus {
+		ignored = append(ignored, []string{"status"})
+	}
+	for _, path := range ignored {
+		removeField(desiredContent, path)
+	}
+
+	var paths []string
+	diffValues("", desiredContent, currentContent, &paths)
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// NeedsUpdate returns whether updating current with desired would change any
+// field, as compared by Diff.
+func NeedsUpdate(desired, current runtime.Object, options Options) (bool, error) {
+	paths, err := Diff(desired, current, options)
+	if err != nil {
+		return false, err
+	}
+	return len(paths) > 0, nil
+}
+
+func toUnstructured(obj runtime.Object) (map[string]interface{}, error) {
+	if u, ok := obj.(runtime.Unstructured); ok {
+		return runtime.DeepCopyJSON(u.UnstructuredContent()), nil
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert %T to unstructured: %v", obj, err)
+	}
+	return content, nil
+}
+
+// removeField removes the field at path from content, if it is set.
+func removeField(content map[string]interface{}, path []string) {
+	for i, field := range path {
+		if i == len(path)-1 {
+			delete(content, field)
+			return
+		}
+		next, ok := content[field].(map[string]interface{})
+		if !ok {
+			return
+		}
+		content = next
+	}
+}
+
+// diffValues appends to paths the paths of the fields set in desired whose
+// value differs in current.
+func diffValues(path string, desired, current interface{}, paths *[]string) {
+	if desired == nil {
+		return
+	}
+	switch desired := desired.(type) {
+	case map[string]interface{}:
+		current, ok := current.(map[string]interface{})
+		if !ok {
+			*paths = append(*paths, path)
+			return
+		}
+		for key, value := range desired {
+			diffValues(fieldPath(path, key), value, current[key], paths)
+		}
+	case []interface{}:
+		current, ok := current.([]interface{})
+		if !ok || len(current) != len(desired) {
+			*paths = append(*paths, path)
+			return
+		}
+		for i := range desired {
+			diffValues(fmt.Sprintf("%s[%d]", path, i), desired[i], current[i], paths)
+		}
+	default:
+		if !reflect.DeepEqual(desired, current) {
+			*paths = append(*paths, path)
+		}
+	}
+}
+
+// fieldPath returns the path of the field key of the object at path.
+func fieldPath(path, key string) string {
+	if strings.ContainsAny(key, ".[]") {
+		return fmt.Sprintf("%s[%q]", path, key)
+	}
+	if len(path) == 0 {
+		return key
+	}
+	return path + "." + key
+}
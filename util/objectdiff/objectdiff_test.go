@@ -0,0 +1,185 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectdiff
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func desiredPod() *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "pod",
+			Namespace:   "ns",
+			Labels:      map[string]string{"app": "test"},
+			Annotations: map[string]string{"example.com/last-sync": "now"},
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{
+				Name:  "main",
+				Image: "image:1",
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1000m")},
+				},
+			}},
+		},
+	}
+}
+
+// currentPod returns the desired pod as returned by the server, defaulted and
+// with the fields populated by the server.
+func currentPod() *v1.Pod {
+	pod := desiredPod()
+	pod.UID = types.UID("uid")
+	pod.ResourceVersion = "42"
+	pod.Generation = 2
+	pod.CreationTimestamp = metav1.NewTime(time.Now())
+	pod.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "test", Operation: metav1.ManagedFieldsOperationUpdate}}
+	pod.Spec.RestartPolicy = v1.RestartPolicyAlways
+	pod.Spec.DNSPolicy = v1.DNSClusterFirst
+	pod.Spec.Containers[0].ImagePullPolicy = v1.PullIfNotPresent
+	pod.Spec.Containers[0].TerminationMessagePath = v1.TerminationMessagePathDefault
+	pod.Spec.Containers[0].Resources.Requests[v1.ResourceCPU] = resource.MustParse("1")
+	pod.Status.Phase = v1.PodRunning
+	return pod
+}
+
+func TestDiff(t *testing.T) {
+	testCases := []struct {
+		name     string
+		desired  func(*v1.Pod)
+		current  func(*v1.Pod)
+		options  Options
+		expected []string
+	}{
+		{
+			name: "no changes",
+		},
+		{
+			name: "fields only set in current",
+			current: func(pod *v1.Pod) {
+				pod.Labels["injected"] = "true"
+				pod.Spec.NodeName = "node"
+			},
+		},
+		{
+			name: "changed fields",
+			desired: func(pod *v1.Pod) {
+				pod.Labels["app"] = "other"
+				pod.Spec.Containers[0].Image = "image:2"
+			},
+			expected: []string{`metadata.labels.app`, `spec.containers[0].image`},
+		},
+		{
+			name: "keys needing quoting",
+			desired: func(pod *v1.Pod) {
+				pod.Annotations["example.com/last-sync"] = "later"
+			},
+			expected: []string{`metadata.annotations["example.com/last-sync"]`},
+		},
+		{
+			name: "ignored fields",
+			desired: func(pod *v1.Pod) {
+				pod.Annotations["example.com/last-sync"] = "later"
+			},
+			options: Options{IgnoredFields: [][]string{{"metadata", "annotations", "example.com/last-sync"}}},
+		},
+		{
+			name: "added container",
+			desired: func(pod *v1.Pod) {
+				pod.Spec.Containers = append(pod.Spec.Containers, v1.Container{Name: "sidecar"})
+			},
+			expected: []string{`spec.containers`},
+		},
+		{
+			name: "explicit value differing from the default",
+			desired: func(pod *v1.Pod) {
+				pod.Spec.RestartPolicy = v1.RestartPolicyNever
+			},
+			expected: []string{`spec.restartPolicy`},
+		},
+		{
+			name: "status ignored",
+			desired: func(pod *v1.Pod) {
+				pod.Status.Phase = v1.PodFailed
+			},
+		},
+		{
+			name: "status compared",
+			desired: func(pod *v1.Pod) {
+				pod.Status.Phase = v1.PodFailed
+			},
+			options:  Options{CompareStatus: true},
+			expected: []string{`status.phase`},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			desired, current := desiredPod(), currentPod()
+			if tc.desired != nil {
+				tc.desired(desired)
+			}
+			if tc.current != nil {
+				tc.current(current)
+			}
+			paths, err := Diff(desired, current, tc.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(paths, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, paths)
+			}
+			needsUpdate, err := NeedsUpdate(desired, current, tc.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if needsUpdate != (len(tc.expected) > 0) {
+				t.Errorf("unexpected NeedsUpdate %v", needsUpdate)
+			}
+		})
+	}
+}
+
+func TestDiffUnstructured(t *testing.T) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(currentPod())
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := &unstructured.Unstructured{Object: content}
+
+	if paths, err := Diff(desiredPod(), current, Options{}); err != nil || len(paths) > 0 {
+		t.Errorf("expected no differences, got %v, %v", paths, err)
+	}
+
+	desired := desiredPod()
+	desired.Spec.Containers[0].Image = "image:2"
+	if paths, err := Diff(desired, current, Options{}); err != nil || !reflect.DeepEqual(paths, []string{"spec.containers[0].image"}) {
+		t.Errorf("expected the image to differ, got %v, %v", paths, err)
+	}
+	if current.GetResourceVersion() != "42" {
+		t.Errorf("Diff modified the current object")
+	}
+}
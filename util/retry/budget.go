@@ -0,0 +1,116 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package retry
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/utils/clock"
+)
+
+// Budget limits the retries of all the retry loops sharing it, so that a
+// failing dependency does not cause every call site to retry up to its own
+// limit. The first attempt of a retry loop is never limited by the budget.
+// A Budget is safe for concurrent use.
+type Budget struct {
+	maxRetries int
+	maxElapsed time.Duration
+	refill     time.Duration
+	clock      clock.PassiveClock
+
+	lock    sync.Mutex
+	start   time.Time
+	retries int
+	// refilled is when the retries were last given back.
+	refilled time.Time
+}
+
+// NewBudget returns a Budget allowing at most maxRetries retries in total and
+// no retries once maxElapsed has passed since the budget was created. A zero
+// value disables the corresponding limit. The retries are never given back,
+// so such a budget is exhausted for good once used up: it bounds a single
+// operation, like the retries of the requests of one reconciliation. Use
+// NewRefillingBudget to share a budget for the lifetime of a process.
+func NewBudget(maxRetries int, maxElapsed time.Duration) *Budget {
+	return newBudget(maxRetries, maxElapsed, 0, clock.RealClock{})
+}
+
+// NewRefillingBudget returns a Budget allowing at most maxRetries retries in a
+// burst, and giving a retry back every refill, like a token bucket. It bounds
+// the rate of the retries of the call sites sharing it rather than their
+// total. A zero maxRetries disables the limit.
+func NewRefillingBudget(maxRetries int, refill time.Duration) *Budget {
+	return newBudget(maxRetries, 0, refill, clock.RealClock{})
+}
+
+func newBudget(maxRetries int, maxElapsed, refill time.Duration, clock clock.PassiveClock) *Budget {
+	now := clock.Now()
+	return &Budget{
+		maxRetries: maxRetries,
+		maxElapsed: maxElapsed,
+		refill:     refill,
+		clock:      clock,
+		start:      now,
+		refilled:   now,
+	}
+}
+
+// take consumes one retry and reports whether the budget allowed it.
+func (b *Budget) take() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.maxElapsed > 0 && b.clock.Since(b.start) >= b.maxElapsed {
+		return false
+	}
+	b.refillLocked()
+	if b.maxRetries > 0 && b.retries >= b.maxRetries {
+		return false
+	}
+	b.retries++
+	return true
+}
+
+// refillLocked gives back the retries regained since the last refill. It must
+// be called while holding the lock.
+func (b *Budget) refillLocked() {
+	if b.refill <= 0 {
+		return
+	}
+	now := b.clock.Now()
+	regained := int(now.Sub(b.refilled) / b.refill)
+	if regained >= b.retries {
+		// a full budget does not save up retries
+		b.retries = 0
+		b.refilled = now
+		return
+	}
+	b.retries -= regained
+	b.refilled = b.refilled.Add(time.Duration(regained) * b.refill)
+}
+
+// Remaining returns the number of retries left in the budget, or -1 if the
+// number of retries is not limited. It does not account for maxElapsed.
+func (b *Budget) Remaining() int {
+	if b.maxRetries <= 0 {
+		return -1
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.refillLocked()
+	return b.maxRetries - b.retries
+}
@@ -17,10 +17,15 @@ limitations under the License.
 package retry
 
 import (
+	"context"
 	"time"
 
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	utilbackoff "k8s.io/client-go/util/backoff"
 )
 
 // DefaultRetry is the recommended retry for a conflict where multiple clients
@@ -65,6 +70,94 @@ func OnError(backoff wait.Backoff, retriable func(error) bool, fn func() error)
 	return err
 }
 
+// Option configures OnErrorWithContext.
+type Option func(*options)
+
+type options struct {
+	budget             *Budget
+	decorrelatedJitter bool
+}
+
+// WithBudget limits the retries of OnErrorWithContext by budget, which may be
+// shared with other call sites.
+func WithBudget(budget *Budget) Option {
+	return func(o *options) {
+		o.budget = budget
+	}
+}
+
+// WithDecorrelatedJitter replaces the exponential backoff of OnErrorWithContext
+// with decorrelated jitter: every delay is a random duration between
+// backoff.Duration and three times the previous delay, capped at backoff.Cap
+// if set. backoff.Factor and backoff.Jitter are ignored. This avoids the
+// synchronized retries of many clients that failed at the same time.
+func WithDecorrelatedJitter() Option {
+	return func(o *options) {
+		o.decorrelatedJitter = true
+	}
+}
+
+// OnErrorWithContext is like OnError but passes ctx to fn and stops waiting
+// between attempts when ctx is done, in which case the context error is
+// returned. fn is called at most backoff.Steps times, and at least once unless
+// ctx is already done.
+func OnErrorWithContext(ctx context.Context, backoff wait.Backoff, retriable func(error) bool, fn func(ctx context.Context) error, opts ...Option) error {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	// backoff.Step decrements backoff.Steps.
+	steps := backoff.Steps
+	var delay time.Duration
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := fn(ctx)
+		switch {
+		case err == nil:
+			return nil
+		case !retriable(err):
+			return err
+		case attempt >= steps:
+			return err
+		case o.budget != nil && !o.budget.take():
+			return err
+		}
+
+		if o.decorrelatedJitter {
+			delay = utilbackoff.DecorrelatedJitter(backoff.Duration, backoff.Cap).Delay(attempt-1, delay)
+		} else {
+			delay = backoff.Step()
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// OnErrorWithTimeout is like OnError but calls fn with a context that expires
+// after perAttemptTimeout, so a single hung attempt cannot block the retry loop
+// indefinitely. An attempt that fails after its context expired is retried
+// regardless of retriable. fn must return when its context is done.
+func OnErrorWithTimeout(backoff wait.Backoff, perAttemptTimeout time.Duration, retriable func(error) bool, fn func(ctx context.Context) error) error {
+	var timedOut bool
+	return OnErrorWithContext(context.Background(), backoff, func(err error) bool {
+		return timedOut || retriable(err)
+	}, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, perAttemptTimeout)
+		defer cancel()
+		err := fn(ctx)
+		timedOut = err != nil && ctx.Err() == context.DeadlineExceeded
+		return err
+	})
+}
+
 // RetryOnConflict is used to make an update to a resource when you have to worry about
 // conflicts caused by other code making unrelated updates to the resource at the same
 // time. fn should fetch the resource to be modified, make appropriate changes to it, try
@@ -103,3 +196,109 @@ func OnError(backoff wait.Backoff, retriable func(error) bool, fn func() error)
 func RetryOnConflict(backoff wait.Backoff, fn func() error) error {
 	return OnError(backoff, errors.IsConflict, fn)
 }
+
+// RetryOnConflictWithApply retries an optimistic-concurrency update like
+// RetryOnConflict and, after conflictsBeforeApply conflicts, falls back to a
+// server-side apply of only the fields changed by mutateFn.
+//
+// Every attempt calls getFn to fetch the current object, mutateFn to modify it
+// in place and updateFn to write it back. Once the update has conflicted
+// conflictsBeforeApply times and applyFn is not nil, the object is fetched and
+// mutated once more and applyFn is called with an object holding the fields
+// mutateFn added or changed, plus apiVersion, kind, name and namespace. Fields
+// removed by mutateFn are not part of the apply configuration. apiVersion and
+// kind are only set if the fetched object has them; typed clients usually
+// return objects without them, so applyFn may have to set them. If mutateFn
+// changed nothing, applyFn is not called.
+func RetryOnConflictWithApply(ctx context.Context, backoff wait.Backoff, conflictsBeforeApply int,
+	getFn func(ctx context.Context) (runtime.Object, error),
+	mutateFn func(obj runtime.Object) error,
+	updateFn func(ctx context.Context, obj runtime.Object) error,
+	applyFn func(ctx context.Context, fields *unstructured.Unstructured) error) error {
+
+	conflicts := 0
+	return OnErrorWithContext(ctx, backoff, errors.IsConflict, func(ctx context.Context) error {
+		obj, err := getFn(ctx)
+		if err != nil {
+			return err
+		}
+		if applyFn == nil || conflicts < conflictsBeforeApply {
+			if err := mutateFn(obj); err != nil {
+				return err
+			}
+			err = updateFn(ctx, obj)
+			if errors.IsConflict(err) {
+				conflicts++
+			}
+			return err
+		}
+
+		mutated := obj.DeepCopyObject()
+		if err := mutateFn(mutated); err != nil {
+			return err
+		}
+		fields, err := changedFields(obj, mutated)
+		if err != nil || fields == nil {
+			return err
+		}
+		return applyFn(ctx, fields)
+	})
+}
+
+// changedFields returns an object holding the fields of modified that differ
+// from original, or nil if there are none.
+func changedFields(original, modified runtime.Object) (*unstructured.Unstructured, error) {
+	originalMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(original)
+	if err != nil {
+		return nil, err
+	}
+	modifiedMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(modified)
+	if err != nil {
+		return nil, err
+	}
+	changed := diffFields(originalMap, modifiedMap)
+	delete(changed, "apiVersion")
+	delete(changed, "kind")
+	if metadata, ok := changed["metadata"].(map[string]interface{}); ok {
+		for _, field := range []string{"resourceVersion", "managedFields", "generation", "uid", "creationTimestamp"} {
+			delete(metadata, field)
+		}
+		if len(metadata) == 0 {
+			delete(changed, "metadata")
+		}
+	}
+	if len(changed) == 0 {
+		return nil, nil
+	}
+
+	fields := &unstructured.Unstructured{Object: changed}
+	u := &unstructured.Unstructured{Object: modifiedMap}
+	fields.SetAPIVersion(u.GetAPIVersion())
+	fields.SetKind(u.GetKind())
+	fields.SetName(u.GetName())
+	fields.SetNamespace(u.GetNamespace())
+	return fields, nil
+}
+
+// diffFields returns the fields of modified that are missing from or differ
+// from original. Maps are compared recursively, all other values including
+// lists are compared as a whole.
+func diffFields(original, modified map[string]interface{}) map[string]interface{} {
+	changed := map[string]interface{}{}
+	for key, value := range modified {
+		originalValue, ok := original[key]
+		if ok && equality.Semantic.DeepEqual(originalValue, value) {
+			continue
+		}
+		originalMap, originalIsMap := originalValue.(map[string]interface{})
+		valueMap, valueIsMap := value.(map[string]interface{})
+		if originalIsMap && valueIsMap {
+			if nested := diffFields(originalMap, valueMap); len(nested) > 0 {
+				changed[key] = nested
+			}
+			continue
+		}
+		changed[key] = value
+	}
+	return changed
+}
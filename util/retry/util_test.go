@@ -17,12 +17,20 @@ limitations under the License.
 package retry
 
 import (
+	"context"
 	"fmt"
+	"reflect"
 	"testing"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/wait"
+	testingclock "k8s.io/utils/clock/testing"
 )
 
 func TestRetryOnConflict(t *testing.T) {
@@ -69,3 +77,184 @@ func TestRetryOnConflict(t *testing.T) {
 		t.Errorf("unexpected error: %v", err)
 	}
 }
+
+func TestOnErrorWithContext(t *testing.T) {
+	errRetriable := fmt.Errorf("retriable")
+	retriable := func(err error) bool { return err == errRetriable }
+	backoff := wait.Backoff{Duration: time.Millisecond, Factor: 1.0, Steps: 3}
+
+	calls := 0
+	err := OnErrorWithContext(context.Background(), backoff, retriable, func(context.Context) error {
+		calls++
+		return errRetriable
+	})
+	if err != errRetriable || calls != 3 {
+		t.Errorf("expected %d calls and retriable error, got %d calls and %v", 3, calls, err)
+	}
+
+	calls = 0
+	err = OnErrorWithContext(context.Background(), backoff, retriable, func(context.Context) error {
+		calls++
+		if calls == 2 {
+			return nil
+		}
+		return errRetriable
+	}, WithDecorrelatedJitter())
+	if err != nil || calls != 2 {
+		t.Errorf("expected success after %d calls, got %d calls and %v", 2, calls, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls = 0
+	err = OnErrorWithContext(ctx, wait.Backoff{Duration: time.Hour, Steps: 3}, retriable, func(context.Context) error {
+		calls++
+		cancel()
+		return errRetriable
+	})
+	if err != context.Canceled || calls != 1 {
+		t.Errorf("expected cancellation after %d call, got %d calls and %v", 1, calls, err)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	errRetriable := fmt.Errorf("retriable")
+	retriable := func(err error) bool { return err == errRetriable }
+	backoff := wait.Backoff{Steps: 10}
+
+	budget := NewBudget(3, 0)
+	calls := 0
+	for i := 0; i < 2; i++ {
+		OnErrorWithContext(context.Background(), backoff, retriable, func(context.Context) error {
+			calls++
+			return errRetriable
+		}, WithBudget(budget))
+	}
+	// The first loop uses the three retries, the second loop only gets its first attempt.
+	if calls != 5 {
+		t.Errorf("expected %d calls, got %d", 5, calls)
+	}
+	if remaining := budget.Remaining(); remaining != 0 {
+		t.Errorf("expected no retries remaining, got %d", remaining)
+	}
+
+	fakeClock := testingclock.NewFakePassiveClock(time.Now())
+	budget = newBudget(0, time.Minute, 0, fakeClock)
+	if !budget.take() {
+		t.Errorf("expected retry to be allowed before the deadline")
+	}
+	fakeClock.SetTime(fakeClock.Now().Add(time.Minute))
+	if budget.take() {
+		t.Errorf("expected retry to be denied after the deadline")
+	}
+}
+
+func TestRefillingBudget(t *testing.T) {
+	fakeClock := testingclock.NewFakePassiveClock(time.Now())
+	budget := newBudget(2, 0, time.Second, fakeClock)
+	if !budget.take() || !budget.take() {
+		t.Fatalf("expected the first retries to be allowed")
+	}
+	if budget.take() {
+		t.Errorf("expected retry to be denied once the budget is used up")
+	}
+	fakeClock.SetTime(fakeClock.Now().Add(1500 * time.Millisecond))
+	if remaining := budget.Remaining(); remaining != 1 {
+		t.Errorf("expected one retry to be given back, got %d", remaining)
+	}
+	if !budget.take() {
+		t.Errorf("expected the retry given back to be allowed")
+	}
+	// The half second left over counts towards the next retry.
+	fakeClock.SetTime(fakeClock.Now().Add(500 * time.Millisecond))
+	if remaining := budget.Remaining(); remaining != 1 {
+		t.Errorf("expected one retry to be given back, got %d", remaining)
+	}
+	fakeClock.SetTime(fakeClock.Now().Add(time.Hour))
+	if remaining := budget.Remaining(); remaining != 2 {
+		t.Errorf("expected the budget not to exceed its maximum, got %d", remaining)
+	}
+}
+
+func TestOnErrorWithTimeout(t *testing.T) {
+	calls := 0
+	err := OnErrorWithTimeout(wait.Backoff{Steps: 3}, 10*time.Millisecond, func(error) bool { return false }, func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			// hang until the attempt times out
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("expected success after %d calls, got %d calls and %v", 3, calls, err)
+	}
+
+	errPermanent := fmt.Errorf("permanent")
+	calls = 0
+	err = OnErrorWithTimeout(wait.Backoff{Steps: 3}, time.Minute, func(error) bool { return false }, func(ctx context.Context) error {
+		calls++
+		return errPermanent
+	})
+	if err != errPermanent || calls != 1 {
+		t.Errorf("expected permanent error after %d call, got %d calls and %v", 1, calls, err)
+	}
+}
+
+func TestRetryOnConflictWithApply(t *testing.T) {
+	conflictErr := errors.NewConflict(schema.GroupResource{Resource: "configmaps"}, "cm", nil)
+	original := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "cm", ResourceVersion: "1"},
+		Data:       map[string]string{"a": "1"},
+	}
+	getFn := func(context.Context) (runtime.Object, error) { return original.DeepCopy(), nil }
+	mutateFn := func(obj runtime.Object) error {
+		cm := obj.(*corev1.ConfigMap)
+		cm.Data["b"] = "2"
+		cm.Labels = map[string]string{"app": "test"}
+		return nil
+	}
+
+	updates := 0
+	var applied *unstructured.Unstructured
+	err := RetryOnConflictWithApply(context.Background(), wait.Backoff{Steps: 5}, 2, getFn, mutateFn,
+		func(ctx context.Context, obj runtime.Object) error {
+			updates++
+			return conflictErr
+		},
+		func(ctx context.Context, fields *unstructured.Unstructured) error {
+			applied = fields
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updates != 2 {
+		t.Errorf("expected %d updates before applying, got %d", 2, updates)
+	}
+	expected := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":      "cm",
+			"namespace": "ns",
+			"labels":    map[string]interface{}{"app": "test"},
+		},
+		"data": map[string]interface{}{"b": "2"},
+	}
+	if applied == nil || !reflect.DeepEqual(applied.Object, expected) {
+		t.Errorf("expected apply of %#v, got %#v", expected, applied)
+	}
+
+	// Without an apply function it behaves like RetryOnConflict.
+	updates = 0
+	err = RetryOnConflictWithApply(context.Background(), wait.Backoff{Steps: 3}, 1, getFn, mutateFn,
+		func(ctx context.Context, obj runtime.Object) error {
+			updates++
+			return conflictErr
+		}, nil)
+	if err != conflictErr || updates != 3 {
+		t.Errorf("expected conflict after %d updates, got %d updates and %v", 3, updates, err)
+	}
+}
@@ -0,0 +1,313 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tokencache provides a concurrency-safe cache of expiring
+// credentials, like the tokens of service accounts requested with the
+// TokenRequest API for many tenants. Credentials are fetched once per key
+// however many callers ask for them concurrently, renewed ahead of their
+// expiry with jitter so that the credentials of many keys are not renewed all
+// at once, and garbage-collected once expired.
+package tokencache
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/logging"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// DefaultRenewAfter is the default fraction of the lifetime of a
+	// credential after which it is renewed.
+	DefaultRenewAfter = 0.8
+	// DefaultGCPeriod is the default period at which Run removes expired
+	// credentials.
+	DefaultGCPeriod = time.Minute
+	// DefaultRenewBackoff is the default delay before renewing a credential
+	// again after its renewal failed.
+	DefaultRenewBackoff = time.Second
+
+	// maxRenewBackoff bounds the delay between the renewals of a credential.
+	maxRenewBackoff = 5 * time.Minute
+)
+
+// errFetchPanicked is returned to the callers waiting for a fetch which
+// panicked.
+var errFetchPanicked = errors.New("fetching the credential panicked")
+
+// FetchFunc fetches the credential of key and returns it with its expiry. A
+// zero expiry means the credential never expires.
+type FetchFunc func(ctx context.Context, key string) (value interface{}, expiry time.Time, err error)
+
+// Options configures a Cache. The zero value is valid.
+type Options struct {
+	// RenewAfter is the fraction of the lifetime of a credential after which
+	// it is renewed, DefaultRenewAfter if 0. A credential is renewed by the
+	// first Get after that point, and is still returned while unexpired if
+	// renewing it fails.
+	RenewAfter float64
+	// JitterFactor renews credentials up to JitterFactor times their
+	// lifetime earlier, at random. Credentials are renewed without jitter if
+	// it is 0.
+	JitterFactor float64
+	// MaxEntries bounds the number of cached credentials. Once reached,
+	// expired credentials are removed and then the credentials expiring the
+	// soonest. The number of credentials is unbounded if it is 0.
+	MaxEntries int
+	// GCPeriod is the period at which Run removes expired credentials,
+	// DefaultGCPeriod if 0.
+	GCPeriod time.Duration
+	// RenewBackoff is the delay before renewing a credential again after
+	// its renewal failed, DefaultRenewBackoff if 0. It doubles with every
+	// consecutive failure, up to five minutes, so that callers are not
+	// blocked on a failing server until the credential expires.
+	RenewBackoff time.Duration
+	// Clock optionally allows injecting a real or fake clock, for example
+	// to test the expiry of credentials.
+	Clock clock.PassiveClock
+}
+
+// Cache caches the credentials returned by a FetchFunc by key until they
+// expire. It is safe for concurrent use.
+type Cache struct {
+	fetch   FetchFunc
+	options Options
+	clock   clock.PassiveClock
+
+	lock     sync.Mutex
+	entries  map[string]*entry
+	inflight map[string]*fetchCall
+}
+
+type entry struct {
+	value   interface{}
+	expiry  time.Time
+	renewAt time.Time
+	// failures counts the consecutive failed renewals.
+	failures int
+}
+
+// expired returns whether the credential of e has expired at now.
+func (e *entry) expired(now time.Time) bool {
+	return !e.expiry.IsZero() && !now.Before(e.expiry)
+}
+
+// fetchCall is a fetch in flight, shared by the callers of Get for its key.
+type fetchCall struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// New returns a Cache of the credentials returned by fetch.
+func New(fetch FetchFunc, options Options) *Cache {
+	if options.Clock != nil {
+		return newCache(fetch, options, options.Clock)
+	}
+	return newCache(fetch, options, clock.RealClock{})
+}
+
+func newCache(fetch FetchFunc, options Options, clock clock.PassiveClock) *Cache {
+	if options.RenewAfter == 0 {
+		options.RenewAfter = DefaultRenewAfter
+	}
+	if options.GCPeriod == 0 {
+		options.GCPeriod = DefaultGCPeriod
+	}
+	if options.RenewBackoff == 0 {
+		options.RenewBackoff = DefaultRenewBackoff
+	}
+	return &Cache{
+		fetch:    fetch,
+		options:  options,
+		clock:    clock,
+		entries:  map[string]*entry{},
+		inflight: map[string]*fetchCall{},
+	}
+}
+
+// Get returns the credential of key, fetching it if it is not cached, has
+// expired or is due for renewal. Concurrent calls for the same key share a
+// single fetch, which runs with the context of the call starting it.
+func (c *Cache) Get(ctx context.Context, key string) (interface{}, error) {
+	c.lock.Lock()
+	now := c.clock.Now()
+	current, ok := c.entries[key]
+	if ok && current.expired(now) {
+		delete(c.entries, key)
+		current, ok = nil, false
+	}
+	if ok && (current.renewAt.IsZero() || now.Before(current.renewAt)) {
+		c.lock.Unlock()
+		return current.value, nil
+	}
+
+	call, inflight := c.inflight[key]
+	if !inflight {
+		call = &fetchCall{done: make(chan struct{})}
+		c.inflight[key] = call
+	}
+	c.lock.Unlock()
+
+	if inflight {
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	} else {
+		c.doFetch(ctx, key, call)
+	}
+
+	if call.err != nil {
+		// Keep serving the current credential until it expires.
+		if ok && !current.expired(c.clock.Now()) {
+			logging.FromContext(ctx).Error(call.err, "Unable to renew credential", "key", key)
+			return current.value, nil
+		}
+		return nil, call.err
+	}
+	return call.value, nil
+}
+
+// doFetch fetches the credential of key for call and caches it.
+func (c *Cache) doFetch(ctx context.Context, key string, call *fetchCall) {
+	var value interface{}
+	var expiry time.Time
+	err := errFetchPanicked
+	// Deferred so that the callers waiting for call are released even if
+	// fetch panics.
+	defer func() {
+		c.finishFetch(key, call, value, expiry, err)
+	}()
+	value, expiry, err = c.fetch(ctx, key)
+}
+
+// finishFetch releases the callers waiting for call and caches the
+// credential it fetched, or delays the next renewal of the current
+// credential if it failed.
+func (c *Cache) finishFetch(key string, call *fetchCall, value interface{}, expiry time.Time, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.inflight, key)
+	call.value, call.err = value, err
+	close(call.done)
+
+	now := c.clock.Now()
+	if err != nil {
+		if current, ok := c.entries[key]; ok && !current.expired(now) {
+			current.failures++
+			current.renewAt = now.Add(c.renewBackoff(current.failures))
+		}
+		return
+	}
+
+	e := &entry{value: value, expiry: expiry}
+	if !expiry.IsZero() {
+		factor := c.options.RenewAfter - rand.Float64()*c.options.JitterFactor
+		if factor < 0 {
+			factor = 0
+		}
+		e.renewAt = now.Add(time.Duration(float64(expiry.Sub(now)) * factor))
+	}
+	if _, cached := c.entries[key]; !cached && c.options.MaxEntries > 0 && len(c.entries) >= c.options.MaxEntries {
+		c.evictLocked(now)
+	}
+	c.entries[key] = e
+}
+
+// renewBackoff returns the delay before renewing a credential again after
+// failures consecutive failed renewals.
+func (c *Cache) renewBackoff(failures int) time.Duration {
+	backoff := c.options.RenewBackoff
+	for i := 1; i < failures && backoff < maxRenewBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRenewBackoff {
+		backoff = maxRenewBackoff
+	}
+	return backoff
+}
+
+// evictLocked makes room for an entry, removing expired entries or else the
+// entry expiring the soonest. It must be called while holding the lock.
+func (c *Cache) evictLocked(now time.Time) {
+	if c.removeExpiredLocked(now) > 0 {
+		return
+	}
+	var soonest string
+	var soonestExpiry time.Time
+	for key, e := range c.entries {
+		if e.expiry.IsZero() {
+			continue
+		}
+		if soonestExpiry.IsZero() || e.expiry.Before(soonestExpiry) {
+			soonest, soonestExpiry = key, e.expiry
+		}
+	}
+	if soonestExpiry.IsZero() {
+		// All credentials never expire; remove any.
+		for key := range c.entries {
+			soonest = key
+			break
+		}
+	}
+	delete(c.entries, soonest)
+}
+
+// Invalidate removes the credential of key, like one the server rejected,
+// so that the next Get fetches it again.
+func (c *Cache) Invalidate(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.entries, key)
+}
+
+// Len returns the number of cached credentials, expired or not.
+func (c *Cache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.entries)
+}
+
+// GarbageCollect removes the expired credentials and returns how many it
+// removed.
+func (c *Cache) GarbageCollect() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.removeExpiredLocked(c.clock.Now())
+}
+
+func (c *Cache) removeExpiredLocked(now time.Time) int {
+	removed := 0
+	for key, e := range c.entries {
+		if e.expired(now) {
+			delete(c.entries, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Run removes the expired credentials every GCPeriod until stopCh is closed.
+func (c *Cache) Run(stopCh <-chan struct{}) {
+	wait.Until(func() { c.GarbageCollect() }, c.options.GCPeriod, stopCh)
+}
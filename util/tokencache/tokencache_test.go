@@ -0,0 +1,254 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tokencache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+// countingFetch returns a FetchFunc returning "<key>-<n>" for the nth fetch,
+// expiring after ttl, and counting the fetches.
+func countingFetch(clock *testingclock.FakeClock, ttl time.Duration, fetches *int32) FetchFunc {
+	return func(ctx context.Context, key string) (interface{}, time.Time, error) {
+		n := atomic.AddInt32(fetches, 1)
+		return fmt.Sprintf("%s-%d", key, n), clock.Now().Add(ttl), nil
+	}
+}
+
+func TestCacheRenewsAndExpires(t *testing.T) {
+	clock := testingclock.NewFakeClock(time.Now())
+	var fetches int32
+	cache := newCache(countingFetch(clock, 10*time.Minute, &fetches), Options{}, clock)
+	ctx := context.Background()
+
+	get := func(expected string) {
+		t.Helper()
+		value, err := cache.Get(ctx, "tenant")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != expected {
+			t.Errorf("expected %q, got %q", expected, value)
+		}
+	}
+
+	get("tenant-1")
+	clock.Step(7 * time.Minute)
+	get("tenant-1")
+	// Renewed after 80% of the lifetime.
+	clock.Step(2 * time.Minute)
+	get("tenant-2")
+	if fetches != 2 {
+		t.Errorf("expected 2 fetches, got %d", fetches)
+	}
+
+	cache.Invalidate("tenant")
+	get("tenant-3")
+
+	clock.Step(11 * time.Minute)
+	if removed := cache.GarbageCollect(); removed != 1 {
+		t.Errorf("expected 1 expired credential to be removed, got %d", removed)
+	}
+	if cache.Len() != 0 {
+		t.Errorf("expected an empty cache, got %d credentials", cache.Len())
+	}
+}
+
+func TestCacheServesCurrentCredentialWhenRenewalFails(t *testing.T) {
+	clock := testingclock.NewFakeClock(time.Now())
+	fail := false
+	fetch := func(ctx context.Context, key string) (interface{}, time.Time, error) {
+		if fail {
+			return nil, time.Time{}, errors.New("fetch failed")
+		}
+		return "token", clock.Now().Add(10 * time.Minute), nil
+	}
+	cache := newCache(fetch, Options{}, clock)
+	ctx := context.Background()
+
+	if _, err := cache.Get(ctx, "tenant"); err != nil {
+		t.Fatal(err)
+	}
+	fail = true
+	clock.Step(9 * time.Minute)
+	if value, err := cache.Get(ctx, "tenant"); err != nil || value != "token" {
+		t.Errorf("expected the current credential, got %v, %v", value, err)
+	}
+	clock.Step(time.Minute)
+	if _, err := cache.Get(ctx, "tenant"); err == nil {
+		t.Errorf("expected an error once the credential expired")
+	}
+}
+
+func TestCacheBacksOffFailedRenewals(t *testing.T) {
+	clock := testingclock.NewFakeClock(time.Now())
+	fetches := 0
+	fetch := func(ctx context.Context, key string) (interface{}, time.Time, error) {
+		fetches++
+		if fetches > 1 {
+			return nil, time.Time{}, errors.New("fetch failed")
+		}
+		return "token", clock.Now().Add(10 * time.Minute), nil
+	}
+	cache := newCache(fetch, Options{RenewBackoff: time.Second}, clock)
+	ctx := context.Background()
+
+	cache.Get(ctx, "tenant")
+	clock.Step(8 * time.Minute)
+	cache.Get(ctx, "tenant")
+	cache.Get(ctx, "tenant")
+	if fetches != 2 {
+		t.Errorf("expected a failed renewal not to be retried right away, got %d fetches", fetches)
+	}
+	clock.Step(time.Second)
+	cache.Get(ctx, "tenant")
+	clock.Step(time.Second)
+	cache.Get(ctx, "tenant")
+	if fetches != 3 {
+		t.Errorf("expected the delay to double after consecutive failures, got %d fetches", fetches)
+	}
+	clock.Step(time.Second)
+	if value, err := cache.Get(ctx, "tenant"); err != nil || value != "token" || fetches != 4 {
+		t.Errorf("expected the current credential after a renewal, got %v, %v after %d fetches", value, err, fetches)
+	}
+}
+
+func TestCacheFetchPanics(t *testing.T) {
+	release := make(chan struct{})
+	var fetches int32
+	fetch := func(ctx context.Context, key string) (interface{}, time.Time, error) {
+		if atomic.AddInt32(&fetches, 1) > 1 {
+			return nil, time.Time{}, errors.New("unexpected fetch")
+		}
+		<-release
+		panic("fetch panicked")
+	}
+	cache := newCache(fetch, Options{}, testingclock.NewFakeClock(time.Now()))
+
+	panicked := make(chan interface{})
+	go func() {
+		defer func() { panicked <- recover() }()
+		cache.Get(context.Background(), "tenant")
+	}()
+	for {
+		cache.lock.Lock()
+		inflight := len(cache.inflight)
+		cache.lock.Unlock()
+		if inflight > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	waited := make(chan error)
+	go func() {
+		_, err := cache.Get(context.Background(), "tenant")
+		waited <- err
+	}()
+	// Let the second caller wait for the fetch.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	if r := <-panicked; r == nil {
+		t.Errorf("expected the panic to be propagated")
+	}
+	if err := <-waited; err != errFetchPanicked {
+		t.Errorf("expected the waiting caller to be released with errFetchPanicked, got %v", err)
+	}
+	if len(cache.inflight) != 0 {
+		t.Errorf("expected the fetch to be removed, got %v", cache.inflight)
+	}
+}
+
+func TestCacheSharesFetches(t *testing.T) {
+	clock := testingclock.NewFakeClock(time.Now())
+	var fetches int32
+	release := make(chan struct{})
+	fetch := func(ctx context.Context, key string) (interface{}, time.Time, error) {
+		atomic.AddInt32(&fetches, 1)
+		<-release
+		return "token", clock.Now().Add(time.Hour), nil
+	}
+	cache := newCache(fetch, Options{}, clock)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if value, err := cache.Get(context.Background(), "tenant"); err != nil || value != "token" {
+				errs <- fmt.Errorf("unexpected result %v, %v", value, err)
+			}
+		}()
+	}
+	// Let the callers queue on the fetch in flight.
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if fetches != 1 {
+		t.Errorf("expected 1 fetch, got %d", fetches)
+	}
+}
+
+func TestCacheJitter(t *testing.T) {
+	clock := testingclock.NewFakeClock(time.Now())
+	var fetches int32
+	cache := newCache(countingFetch(clock, 100*time.Minute, &fetches), Options{JitterFactor: 0.1}, clock)
+	for i := 0; i < 100; i++ {
+		if _, err := cache.Get(context.Background(), fmt.Sprint(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for key, e := range cache.entries {
+		renewAfter := e.renewAt.Sub(clock.Now())
+		if renewAfter < 70*time.Minute || renewAfter > 80*time.Minute {
+			t.Errorf("expected %s to be renewed between 70 and 80 minutes, got %v", key, renewAfter)
+		}
+	}
+}
+
+func TestCacheMaxEntries(t *testing.T) {
+	clock := testingclock.NewFakeClock(time.Now())
+	ttls := map[string]time.Duration{"a": time.Hour, "b": time.Minute, "c": 2 * time.Hour, "d": time.Hour}
+	fetch := func(ctx context.Context, key string) (interface{}, time.Time, error) {
+		return key, clock.Now().Add(ttls[key]), nil
+	}
+	cache := newCache(fetch, Options{MaxEntries: 3}, clock)
+	for _, key := range []string{"a", "b", "c", "d"} {
+		if _, err := cache.Get(context.Background(), key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if cache.Len() != 3 {
+		t.Errorf("expected 3 credentials, got %d", cache.Len())
+	}
+	if _, ok := cache.entries["b"]; ok {
+		t.Errorf("expected the credential expiring the soonest to be evicted")
+	}
+}
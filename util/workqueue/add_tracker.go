@@ -0,0 +1,222 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workqueue
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultAddTrackerSampleRate is the default rate at which an AddTracker
+	// records stack traces of the adds of an item.
+	DefaultAddTrackerSampleRate = 100
+	// DefaultAddTrackerMaxItems is the default number of items an AddTracker
+	// tracks.
+	DefaultAddTrackerMaxItems = 10000
+
+	// addTrackerStackDepth is the maximum number of frames of recorded stacks.
+	addTrackerStackDepth = 32
+	// addTrackerMaxStacks is the maximum number of stacks recorded per item.
+	addTrackerMaxStacks = 16
+)
+
+// AddTrackerOptions configures an AddTracker.
+type AddTrackerOptions struct {
+	// SampleRate is the number of adds of an item per recorded stack trace:
+	// the stack of the first add of an item and of every SampleRate-th add
+	// after it are recorded. Defaults to DefaultAddTrackerSampleRate. Use 1 to
+	// record the stacks of all adds.
+	SampleRate int
+	// MaxItems bounds the number of tracked items. Adds of further items are
+	// only counted in total. Defaults to DefaultAddTrackerMaxItems.
+	MaxItems int
+}
+
+// AddTracker counts the adds of items to queues and samples their stack
+// traces, to debug controllers that add items pathologically often. Add
+// trackers are set in the configs of queues, like QueueConfig, and can be
+// shared by several queues. Adds of items with delays are counted when they
+// are requested, with the stack of the caller of AddAfter.
+type AddTracker struct {
+	sampleRate int
+	maxItems   int
+
+	lock      sync.Mutex
+	items     map[t]*itemAdds
+	untracked int64
+}
+
+type itemAdds struct {
+	adds   int64
+	stacks map[string]int64
+}
+
+// ItemAdds reports the adds of an item.
+type ItemAdds struct {
+	// Item is the added item.
+	Item interface{}
+	// Adds is the number of adds of the item.
+	Adds int64
+	// Stacks are the sampled stack traces of the adds, with the most
+	// frequent first.
+	Stacks []StackAdds
+}
+
+// StackAdds reports the sampled adds of an item from a stack.
+type StackAdds struct {
+	// Stack is the stack trace of the adds, without the frames of the queue.
+	Stack string
+	// Samples is the number of sampled adds with the stack.
+	Samples int64
+}
+
+// NewAddTracker returns an AddTracker.
+func NewAddTracker(options AddTrackerOptions) *AddTracker {
+	if options.SampleRate <= 0 {
+		options.SampleRate = DefaultAddTrackerSampleRate
+	}
+	if options.MaxItems <= 0 {
+		options.MaxItems = DefaultAddTrackerMaxItems
+	}
+	return &AddTracker{
+		sampleRate: options.SampleRate,
+		maxItems:   options.MaxItems,
+		items:      map[t]*itemAdds{},
+	}
+}
+
+// record counts an add of item, recording the stack of the caller if sampled.
+func (a *AddTracker) record(item t) {
+	a.lock.Lock()
+	adds, ok := a.items[item]
+	if !ok {
+		if len(a.items) >= a.maxItems {
+			a.untracked++
+			a.lock.Unlock()
+			return
+		}
+		adds = &itemAdds{stacks: map[string]int64{}}
+		a.items[item] = adds
+	}
+	adds.adds++
+	sampled := (adds.adds-1)%int64(a.sampleRate) == 0
+	a.lock.Unlock()
+	if !sampled {
+		return
+	}
+
+	stack := callerStack()
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	// the item may have been reset meanwhile
+	if adds, ok := a.items[item]; ok {
+		if _, ok := adds.stacks[stack]; ok || len(adds.stacks) < addTrackerMaxStacks {
+			adds.stacks[stack]++
+		}
+	}
+}
+
+// callerStack returns the stack trace of the caller of the queue.
+func callerStack() string {
+	pcs := make([]uintptr, addTrackerStackDepth)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var stack strings.Builder
+	inQueue := true
+	for {
+		frame, more := frames.Next()
+		// skip the frames of the queues on top of the stack
+		if inQueue && isQueueFrame(frame.Function) {
+			if !more {
+				break
+			}
+			continue
+		}
+		inQueue = false
+		fmt.Fprintf(&stack, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return stack.String()
+}
+
+// queueFunctions are the prefixes of the functions of the queues.
+var queueFunctions = []string{
+	"k8s.io/client-go/util/workqueue.(*Type).",
+	"k8s.io/client-go/util/workqueue.(*delayingType).",
+	"k8s.io/client-go/util/workqueue.(*steppableDelayingType).",
+	"k8s.io/client-go/util/workqueue.(*rateLimitingType).",
+	"k8s.io/client-go/util/workqueue.(*AddTracker).",
+	"k8s.io/client-go/util/workqueue.callerStack",
+}
+
+func isQueueFrame(function string) bool {
+	for _, prefix := range queueFunctions {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Top returns the n most added items, with the most added first.
+func (a *AddTracker) Top(n int) []ItemAdds {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	result := make([]ItemAdds, 0, len(a.items))
+	for item, adds := range a.items {
+		report := ItemAdds{Item: item, Adds: adds.adds}
+		for stack, samples := range adds.stacks {
+			report.Stacks = append(report.Stacks, StackAdds{Stack: stack, Samples: samples})
+		}
+		sort.Slice(report.Stacks, func(i, j int) bool {
+			if report.Stacks[i].Samples != report.Stacks[j].Samples {
+				return report.Stacks[i].Samples > report.Stacks[j].Samples
+			}
+			return report.Stacks[i].Stack < report.Stacks[j].Stack
+		})
+		result = append(result, report)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Adds > result[j].Adds
+	})
+	if n < len(result) {
+		result = result[:n]
+	}
+	return result
+}
+
+// Untracked returns the number of adds of items that were not tracked because
+// MaxItems items were tracked already.
+func (a *AddTracker) Untracked() int64 {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.untracked
+}
+
+// Reset forgets all adds, for example to report the adds of every interval.
+func (a *AddTracker) Reset() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.items = map[t]*itemAdds{}
+	a.untracked = 0
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workqueue
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func addFromLoop(q Interface, item interface{}, times int) {
+	for i := 0; i < times; i++ {
+		q.Add(item)
+	}
+}
+
+func addFromHandler(q Interface, item interface{}) {
+	q.Add(item)
+}
+
+func TestAddTracker(t *testing.T) {
+	tracker := NewAddTracker(AddTrackerOptions{SampleRate: 10})
+	q := NewWithConfig(QueueConfig{AddTracker: tracker})
+	defer q.ShutDown()
+
+	addFromLoop(q, "hot", 1000)
+	addFromHandler(q, "hot")
+	addFromHandler(q, "cold")
+
+	top := tracker.Top(1)
+	if len(top) != 1 || top[0].Item != "hot" || top[0].Adds != 1001 {
+		t.Fatalf("unexpected top items %#v", top)
+	}
+	stacks := top[0].Stacks
+	if len(stacks) != 2 || stacks[0].Samples != 100 || stacks[1].Samples != 1 {
+		t.Fatalf("unexpected stacks %#v", stacks)
+	}
+	if !strings.HasPrefix(stacks[0].Stack, "k8s.io/client-go/util/workqueue.addFromLoop\n") {
+		t.Errorf("expected the stack to start at the caller of the queue, got\n%s", stacks[0].Stack)
+	}
+	if !strings.HasPrefix(stacks[1].Stack, "k8s.io/client-go/util/workqueue.addFromHandler\n") {
+		t.Errorf("expected the stack to start at the caller of the queue, got\n%s", stacks[1].Stack)
+	}
+	if all := tracker.Top(10); len(all) != 2 || all[1].Item != "cold" || all[1].Adds != 1 {
+		t.Errorf("unexpected items %#v", all)
+	}
+
+	tracker.Reset()
+	if top := tracker.Top(10); len(top) != 0 {
+		t.Errorf("expected no items after a reset, got %#v", top)
+	}
+}
+
+func TestAddTrackerMaxItems(t *testing.T) {
+	tracker := NewAddTracker(AddTrackerOptions{MaxItems: 2})
+	q := NewWithConfig(QueueConfig{AddTracker: tracker})
+	defer q.ShutDown()
+	for _, item := range []string{"a", "b", "c", "a", "d"} {
+		q.Add(item)
+	}
+	if top := tracker.Top(10); len(top) != 2 || top[0].Item != "a" || top[0].Adds != 2 {
+		t.Errorf("unexpected items %#v", top)
+	}
+	if untracked := tracker.Untracked(); untracked != 2 {
+		t.Errorf("expected 2 untracked adds, got %d", untracked)
+	}
+}
+
+func TestAddTrackerRateLimitingQueue(t *testing.T) {
+	tracker := NewAddTracker(AddTrackerOptions{SampleRate: 1})
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	q := NewRateLimitingQueueWithConfig(NewItemExponentialFailureRateLimiter(time.Second, time.Minute), RateLimitingQueueConfig{
+		DelayingQueue: NewSteppableDelayingQueue(fakeClock, DelayingQueueConfig{AddTracker: tracker}),
+	})
+	defer q.ShutDown()
+
+	q.AddRateLimited("item")
+	q.(*rateLimitingType).DelayingInterface.(SteppableDelayingInterface).Step(time.Minute)
+	if q.Len() != 1 {
+		t.Fatalf("expected the item to be added")
+	}
+	// the add is recorded once, with the caller of AddRateLimited
+	top := tracker.Top(1)
+	if len(top) != 1 || top[0].Adds != 1 || len(top[0].Stacks) != 1 {
+		t.Fatalf("unexpected items %#v", top)
+	}
+	if !strings.HasPrefix(top[0].Stacks[0].Stack, "k8s.io/client-go/util/workqueue.TestAddTrackerRateLimitingQueue\n") {
+		t.Errorf("expected the stack to start at the caller of the queue, got\n%s", top[0].Stacks[0].Stack)
+	}
+}
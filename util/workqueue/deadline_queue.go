@@ -0,0 +1,174 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workqueue
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/utils/clock"
+)
+
+// DeadlineInterface is a delaying queue whose adds can carry a deadline by
+// which the item should be processed, for example the budget of an SLA, so
+// that workers can prioritize or short-circuit work which exceeded its
+// budget. The item is returned with the earliest deadline of its adds.
+type DeadlineInterface interface {
+	DelayingInterface
+	// AddWithDeadline adds item, to be processed by deadline.
+	AddWithDeadline(item interface{}, deadline time.Time)
+	// AddAfterWithDeadline adds item after duration, to be processed by
+	// deadline. The deadline applies once the item is added.
+	AddAfterWithDeadline(item interface{}, duration time.Duration, deadline time.Time)
+	// GetWithDeadline returns an item to process with the earliest deadline
+	// of its adds, or a zero deadline if none of them carried one.
+	GetWithDeadline() (item interface{}, deadline time.Time, shutdown bool)
+	// GetWithContext returns an item to process with a context derived from
+	// parent which expires at its deadline, if any. The context must be
+	// canceled once the item is processed.
+	GetWithContext(parent context.Context) (item interface{}, ctx context.Context, cancel context.CancelFunc, shutdown bool)
+}
+
+// itemDeadline is the deadline of an add, applying from readyAt.
+type itemDeadline struct {
+	deadline time.Time
+	readyAt  time.Time
+}
+
+// deadlineType is a queue carrying deadlines.
+type deadlineType struct {
+	DelayingInterface
+	clock clock.Clock
+
+	lock sync.Mutex
+	// deadlines holds the deadlines of the adds of items since they were
+	// last returned by GetWithDeadline
+	deadlines map[t][]itemDeadline
+}
+
+// NewDeadlineQueue returns a DeadlineInterface adding items to queue. To use
+// it with rate limiting queues, pass it as their DelayingQueue and call
+// AddWithDeadline and GetWithDeadline on it, for example
+//
+//	deadlines := workqueue.NewDeadlineQueue(workqueue.NewDelayingQueue())
+//	queue := workqueue.NewRateLimitingQueueWithConfig(rateLimiter, workqueue.RateLimitingQueueConfig{
+//		DelayingQueue: deadlines,
+//	})
+//
+// Items added through the rate limiting methods carry no deadline.
+func NewDeadlineQueue(queue DelayingInterface) DeadlineInterface {
+	return newDeadlineQueue(queue, clock.RealClock{})
+}
+
+func newDeadlineQueue(queue DelayingInterface, clock clock.Clock) *deadlineType {
+	return &deadlineType{
+		DelayingInterface: queue,
+		clock:             clock,
+		deadlines:         map[t][]itemDeadline{},
+	}
+}
+
+// AddWithDeadline marks item as needing processing by deadline.
+func (q *deadlineType) AddWithDeadline(item interface{}, deadline time.Time) {
+	if q.ShuttingDown() {
+		return
+	}
+	q.record(item, deadline, q.clock.Now())
+	q.DelayingInterface.Add(item)
+}
+
+// AddAfterWithDeadline adds item after duration, to be processed by
+// deadline.
+func (q *deadlineType) AddAfterWithDeadline(item interface{}, duration time.Duration, deadline time.Time) {
+	if q.ShuttingDown() {
+		return
+	}
+	q.record(item, deadline, q.clock.Now().Add(duration))
+	q.DelayingInterface.AddAfter(item, duration)
+}
+
+func (q *deadlineType) record(item interface{}, deadline, readyAt time.Time) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.deadlines[item] = append(q.deadlines[item], itemDeadline{deadline: deadline, readyAt: readyAt})
+}
+
+// drop removes item and its deadlines from the queue.
+func (q *deadlineType) drop(item interface{}) {
+	queue, ok := q.DelayingInterface.(dropper)
+	if !ok {
+		return
+	}
+	q.lock.Lock()
+	delete(q.deadlines, item)
+	q.lock.Unlock()
+	queue.drop(item)
+}
+
+// GetWithDeadline blocks until it can return an item to be processed, with
+// the earliest deadline of its adds.
+func (q *deadlineType) GetWithDeadline() (interface{}, time.Time, bool) {
+	item, shutdown := q.DelayingInterface.Get()
+	if shutdown {
+		return item, time.Time{}, shutdown
+	}
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	// deadlines of delayed adds which are not ready yet apply to the get
+	// following their add
+	now := q.clock.Now()
+	var earliest time.Time
+	var pending []itemDeadline
+	for _, d := range q.deadlines[item] {
+		if d.readyAt.After(now) {
+			pending = append(pending, d)
+			continue
+		}
+		if earliest.IsZero() || d.deadline.Before(earliest) {
+			earliest = d.deadline
+		}
+	}
+	if len(pending) > 0 {
+		q.deadlines[item] = pending
+	} else {
+		delete(q.deadlines, item)
+	}
+	return item, earliest, false
+}
+
+// GetWithContext blocks until it can return an item to be processed, with a
+// context expiring at its deadline.
+func (q *deadlineType) GetWithContext(parent context.Context) (interface{}, context.Context, context.CancelFunc, bool) {
+	item, deadline, shutdown := q.GetWithDeadline()
+	if shutdown {
+		return item, nil, nil, shutdown
+	}
+	if deadline.IsZero() {
+		ctx, cancel := context.WithCancel(parent)
+		return item, ctx, cancel, false
+	}
+	ctx, cancel := context.WithDeadline(parent, deadline)
+	return item, ctx, cancel, false
+}
+
+// Get blocks until it can return an item to be processed, dropping its
+// deadline.
+func (q *deadlineType) Get() (interface{}, bool) {
+	item, _, shutdown := q.GetWithDeadline()
+	return item, shutdown
+}
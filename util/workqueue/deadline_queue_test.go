@@ -0,0 +1,108 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workqueue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestDeadlineQueue(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	q := newDeadlineQueue(NewDelayingQueueWithConfig(DelayingQueueConfig{Clock: fakeClock}), fakeClock)
+	defer q.ShutDown()
+
+	now := fakeClock.Now()
+	q.AddWithDeadline("foo", now.Add(time.Minute))
+	q.AddWithDeadline("foo", now.Add(30*time.Second))
+	q.Add("foo")
+	q.Add("bar")
+	item, deadline, _ := q.GetWithDeadline()
+	if item != "foo" || !deadline.Equal(now.Add(30*time.Second)) {
+		t.Fatalf("expected foo with the earliest deadline, got %v with %v", item, deadline)
+	}
+	q.Done(item)
+	item, deadline, _ = q.GetWithDeadline()
+	if item != "bar" || !deadline.IsZero() {
+		t.Fatalf("expected bar without deadline, got %v with %v", item, deadline)
+	}
+	q.Done(item)
+
+	// the deadline of a delayed add applies once the item is added
+	q.AddAfterWithDeadline("foo", time.Minute, now.Add(2*time.Minute))
+	q.Add("foo")
+	item, deadline, _ = q.GetWithDeadline()
+	if item != "foo" || !deadline.IsZero() {
+		t.Fatalf("expected foo without deadline before its delayed add, got %v with %v", item, deadline)
+	}
+	q.Done(item)
+	if err := waitForWaitingQueueToFill(q.DelayingInterface); err != nil {
+		t.Fatal(err)
+	}
+	fakeClock.Step(time.Minute)
+	if err := waitForAdded(q, 1); err != nil {
+		t.Fatal(err)
+	}
+	item, deadline, _ = q.GetWithDeadline()
+	if item != "foo" || !deadline.Equal(now.Add(2*time.Minute)) {
+		t.Fatalf("expected foo with the deadline of its delayed add, got %v with %v", item, deadline)
+	}
+	q.Done(item)
+}
+
+func TestDeadlineQueueGetWithContext(t *testing.T) {
+	q := NewDeadlineQueue(NewDelayingQueue())
+	defer q.ShutDown()
+
+	deadline := time.Now().Add(time.Hour)
+	q.AddWithDeadline("foo", deadline)
+	q.Add("bar")
+
+	item, ctx, cancel, _ := q.GetWithContext(context.Background())
+	if got, ok := ctx.Deadline(); item != "foo" || !ok || !got.Equal(deadline) {
+		t.Fatalf("expected foo with a context expiring at %v, got %v expiring at %v", deadline, item, got)
+	}
+	cancel()
+	q.Done(item)
+
+	item, ctx, cancel, _ = q.GetWithContext(context.Background())
+	defer cancel()
+	if _, ok := ctx.Deadline(); item != "bar" || ok {
+		t.Fatalf("expected bar with a context without deadline, got %v", item)
+	}
+}
+
+func TestDeadlineQueueWithRateLimitingQueue(t *testing.T) {
+	deadlines := NewDeadlineQueue(NewDelayingQueue())
+	q := NewRateLimitingQueueWithConfig(DefaultControllerRateLimiter(), RateLimitingQueueConfig{DelayingQueue: deadlines})
+	defer q.ShutDown()
+
+	deadline := time.Now().Add(time.Hour)
+	deadlines.AddWithDeadline("foo", deadline)
+	q.Add("foo")
+	q.(DroppingInterface).ForgetAndDrop("foo")
+	if got := q.Len(); got != 0 {
+		t.Fatalf("expected foo to be dropped, got %d items", got)
+	}
+	q.Add("foo")
+	if item, got, _ := deadlines.GetWithDeadline(); item != "foo" || !got.IsZero() {
+		t.Fatalf("expected the deadline of foo to be dropped, got %v", got)
+	}
+}
@@ -34,6 +34,35 @@ type RateLimiter interface {
 	NumRequeues(item interface{}) int
 }
 
+// StatefulRateLimiter is a RateLimiter whose per-item failure counts can be
+// exported and imported, for example to persist them across restarts so that
+// failing items are not retried at once.
+type StatefulRateLimiter interface {
+	RateLimiter
+	// ExportFailures returns the failure counts of the tracked items.
+	ExportFailures() map[interface{}]int
+	// ImportFailures raises the failure counts of items to the given ones.
+	ImportFailures(failures map[interface{}]int)
+}
+
+// exportFailures returns a copy of failures.
+func exportFailures(failures map[interface{}]int) map[interface{}]int {
+	exported := make(map[interface{}]int, len(failures))
+	for item, count := range failures {
+		exported[item] = count
+	}
+	return exported
+}
+
+// importFailures raises the counts of failures to the imported ones.
+func importFailures(failures, imported map[interface{}]int) {
+	for item, count := range imported {
+		if count > failures[item] {
+			failures[item] = count
+		}
+	}
+}
+
 // DefaultControllerRateLimiter is a no-arg constructor for a default rate limiter for a workqueue.  It has
 // both overall and per-item rate limiting.  The overall is a token bucket and the per-item is exponential
 func DefaultControllerRateLimiter() RateLimiter {
@@ -72,7 +101,7 @@ type ItemExponentialFailureRateLimiter struct {
 	maxDelay  time.Duration
 }
 
-var _ RateLimiter = &ItemExponentialFailureRateLimiter{}
+var _ StatefulRateLimiter = &ItemExponentialFailureRateLimiter{}
 
 func NewItemExponentialFailureRateLimiter(baseDelay time.Duration, maxDelay time.Duration) RateLimiter {
 	return &ItemExponentialFailureRateLimiter{
@@ -121,6 +150,20 @@ func (r *ItemExponentialFailureRateLimiter) Forget(item interface{}) {
 	delete(r.failures, item)
 }
 
+func (r *ItemExponentialFailureRateLimiter) ExportFailures() map[interface{}]int {
+	r.failuresLock.Lock()
+	defer r.failuresLock.Unlock()
+
+	return exportFailures(r.failures)
+}
+
+func (r *ItemExponentialFailureRateLimiter) ImportFailures(failures map[interface{}]int) {
+	r.failuresLock.Lock()
+	defer r.failuresLock.Unlock()
+
+	importFailures(r.failures, failures)
+}
+
 // ItemFastSlowRateLimiter does a quick retry for a certain number of attempts, then a slow retry after that
 type ItemFastSlowRateLimiter struct {
 	failuresLock sync.Mutex
@@ -131,7 +174,7 @@ type ItemFastSlowRateLimiter struct {
 	slowDelay       time.Duration
 }
 
-var _ RateLimiter = &ItemFastSlowRateLimiter{}
+var _ StatefulRateLimiter = &ItemFastSlowRateLimiter{}
 
 func NewItemFastSlowRateLimiter(fastDelay, slowDelay time.Duration, maxFastAttempts int) RateLimiter {
 	return &ItemFastSlowRateLimiter{
@@ -169,6 +212,20 @@ func (r *ItemFastSlowRateLimiter) Forget(item interface{}) {
 	delete(r.failures, item)
 }
 
+func (r *ItemFastSlowRateLimiter) ExportFailures() map[interface{}]int {
+	r.failuresLock.Lock()
+	defer r.failuresLock.Unlock()
+
+	return exportFailures(r.failures)
+}
+
+func (r *ItemFastSlowRateLimiter) ImportFailures(failures map[interface{}]int) {
+	r.failuresLock.Lock()
+	defer r.failuresLock.Unlock()
+
+	importFailures(r.failures, failures)
+}
+
 // MaxOfRateLimiter calls every RateLimiter and returns the worst case response
 // When used with a token bucket limiter, the burst could be apparently exceeded in cases where particular items
 // were separately delayed a longer time.
@@ -210,6 +267,26 @@ func (r *MaxOfRateLimiter) Forget(item interface{}) {
 	}
 }
 
+// ExportFailures returns the highest failure counts of the stateful limiters.
+func (r *MaxOfRateLimiter) ExportFailures() map[interface{}]int {
+	exported := map[interface{}]int{}
+	for _, limiter := range r.limiters {
+		if stateful, ok := limiter.(StatefulRateLimiter); ok {
+			importFailures(exported, stateful.ExportFailures())
+		}
+	}
+	return exported
+}
+
+// ImportFailures imports failures into the stateful limiters.
+func (r *MaxOfRateLimiter) ImportFailures(failures map[interface{}]int) {
+	for _, limiter := range r.limiters {
+		if stateful, ok := limiter.(StatefulRateLimiter); ok {
+			stateful.ImportFailures(failures)
+		}
+	}
+}
+
 // WithMaxWaitRateLimiter have maxDelay which avoids waiting too long
 type WithMaxWaitRateLimiter struct {
 	limiter  RateLimiter
@@ -236,3 +313,16 @@ func (w WithMaxWaitRateLimiter) Forget(item interface{}) {
 func (w WithMaxWaitRateLimiter) NumRequeues(item interface{}) int {
 	return w.limiter.NumRequeues(item)
 }
+
+func (w WithMaxWaitRateLimiter) ExportFailures() map[interface{}]int {
+	if stateful, ok := w.limiter.(StatefulRateLimiter); ok {
+		return stateful.ExportFailures()
+	}
+	return map[interface{}]int{}
+}
+
+func (w WithMaxWaitRateLimiter) ImportFailures(failures map[interface{}]int) {
+	if stateful, ok := w.limiter.(StatefulRateLimiter); ok {
+		stateful.ImportFailures(failures)
+	}
+}
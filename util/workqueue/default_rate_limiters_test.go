@@ -17,8 +17,11 @@ limitations under the License.
 package workqueue
 
 import (
+	"reflect"
 	"testing"
 	"time"
+
+	"golang.org/x/time/rate"
 )
 
 func TestItemExponentialFailureRateLimiter(t *testing.T) {
@@ -229,3 +232,57 @@ func (r *StepRateLimiter) NumRequeues(item interface{}) int {
 
 func (r *StepRateLimiter) Forget(item interface{}) {
 }
+
+func TestStatefulRateLimiters(t *testing.T) {
+	limiter := NewItemExponentialFailureRateLimiter(1*time.Millisecond, 1*time.Second).(StatefulRateLimiter)
+	limiter.When("one")
+	limiter.When("one")
+	limiter.When("two")
+	exported := limiter.ExportFailures()
+	if e, a := map[interface{}]int{"one": 2, "two": 1}, exported; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	exported["one"] = 5
+	if e, a := 2, limiter.NumRequeues("one"); e != a {
+		t.Errorf("expected the export to be a copy with %v requeues, got %v", e, a)
+	}
+
+	restarted := NewMaxOfRateLimiter(
+		NewItemExponentialFailureRateLimiter(1*time.Millisecond, 1*time.Second),
+		NewItemFastSlowRateLimiter(5*time.Millisecond, 10*time.Second, 3),
+		&BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(10), 100)},
+	).(StatefulRateLimiter)
+	restarted.When("two")
+	restarted.When("two")
+	restarted.ImportFailures(map[interface{}]int{"one": 2, "two": 1})
+	if e, a := 2, restarted.NumRequeues("two"); e != a {
+		t.Errorf("expected imports not to lower failures from %v, got %v", e, a)
+	}
+	if e, a := 5*time.Millisecond, NewWithMaxWaitRateLimiter(restarted, time.Second).When("one"); e != a {
+		t.Errorf("expected imported failures to back off %v, got %v", e, a)
+	}
+	if e, a := 10*time.Second, restarted.When("one"); e != a {
+		t.Errorf("expected imported failures to reach the slow delay %v, got %v", e, a)
+	}
+	if e, a := (map[interface{}]int{"one": 4, "two": 2}), restarted.ExportFailures(); !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+
+	queue := NewRateLimitingQueue(restarted)
+	defer queue.ShutDown()
+	queue.Forget("two")
+	if e, a := (map[interface{}]int{"one": 4}), queue.(StatefulInterface).ExportFailures(); !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	queue.(StatefulInterface).ImportFailures(map[interface{}]int{"three": 1})
+	if e, a := 1, queue.NumRequeues("three"); e != a {
+		t.Errorf("expected %v requeues, got %v", e, a)
+	}
+
+	stateless := NewRateLimitingQueue(&BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(10), 100)})
+	defer stateless.ShutDown()
+	stateless.(StatefulInterface).ImportFailures(map[interface{}]int{"one": 1})
+	if e, a := 0, len(stateless.(StatefulInterface).ExportFailures()); e != a {
+		t.Errorf("expected no failures from stateless limiters, got %v", a)
+	}
+}
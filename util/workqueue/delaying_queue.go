@@ -41,7 +41,7 @@ func NewDelayingQueue() DelayingInterface {
 // NewDelayingQueueWithCustomQueue constructs a new workqueue with ability to
 // inject custom queue Interface instead of the default one
 func NewDelayingQueueWithCustomQueue(q Interface, name string) DelayingInterface {
-	return newDelayingQueue(clock.RealClock{}, q, name)
+	return newDelayingQueue(clock.RealClock{}, q, name, nil)
 }
 
 // NewNamedDelayingQueue constructs a new named workqueue with delayed queuing ability
@@ -52,17 +52,60 @@ func NewNamedDelayingQueue(name string) DelayingInterface {
 // NewDelayingQueueWithCustomClock constructs a new named workqueue
 // with ability to inject real or fake clock for testing purposes
 func NewDelayingQueueWithCustomClock(clock clock.WithTicker, name string) DelayingInterface {
-	return newDelayingQueue(clock, NewNamed(name), name)
+	return NewDelayingQueueWithConfig(DelayingQueueConfig{Name: name, Clock: clock})
 }
 
-func newDelayingQueue(clock clock.WithTicker, q Interface, name string) *delayingType {
+// DelayingQueueConfig specifies optional configurations to customize a
+// DelayingInterface.
+type DelayingQueueConfig struct {
+	// Name for the queue. If unnamed, the metrics will not be registered.
+	Name string
+
+	// MetricsProvider optionally allows specifying a metrics provider to use
+	// for the queue instead of the global provider set by SetProvider.
+	MetricsProvider MetricsProvider
+
+	// Clock optionally allows injecting a real or fake clock for testing
+	// purposes.
+	Clock clock.WithTicker
+
+	// Queue optionally allows injecting a custom queue Interface instead of
+	// the default one.
+	Queue Interface
+
+	// AddTracker optionally counts the adds of items and samples their stack
+	// traces, to debug items added pathologically often. Adds of custom
+	// queues are not recorded, except for those with delays.
+	AddTracker *AddTracker
+}
+
+// NewDelayingQueueWithConfig constructs a new workqueue with options to
+// customize its behavior.
+func NewDelayingQueueWithConfig(config DelayingQueueConfig) DelayingInterface {
+	if config.Clock == nil {
+		config.Clock = clock.RealClock{}
+	}
+	if config.Queue == nil {
+		config.Queue = NewWithConfig(QueueConfig{
+			Name:            config.Name,
+			MetricsProvider: config.MetricsProvider,
+			Clock:           config.Clock,
+			AddTracker:      config.AddTracker,
+		})
+	}
+	q := newDelayingQueue(config.Clock, config.Queue, config.Name, config.MetricsProvider)
+	q.addTracker = config.AddTracker
+	return q
+}
+
+func newDelayingQueue(clock clock.WithTicker, q Interface, name string, provider MetricsProvider) *delayingType {
 	ret := &delayingType{
 		Interface:       q,
 		clock:           clock,
 		heartbeat:       clock.NewTicker(maxWait),
 		stopCh:          make(chan struct{}),
 		waitingForAddCh: make(chan *waitFor, 1000),
-		metrics:         newRetryMetrics(name),
+		metrics:         newRetryMetrics(name, provider),
 	}
 
 	go ret.waitingLoop()
@@ -89,6 +132,9 @@ type delayingType struct {
 
 	// metrics counts the number of retries
 	metrics retryMetrics
+
+	// addTracker, if set, records the adds of items with delays
+	addTracker *AddTracker
 }
 
 // waitFor holds the data to add and the time it should be added
@@ -97,6 +143,9 @@ type waitFor struct {
 	readyAt time.Time
 	// index in the priority queue (heap)
 	index int
+	// dropped, if set, makes the entry a request to drop data, closed once
+	// it is dropped
+	dropped chan struct{}
 }
 
 // waitForPriorityQueue implements a priority queue for waitFor items.
@@ -163,7 +212,7 @@ func (q *delayingType) AddAfter(item interface{}, duration time.Duration) {
 		return
 	}
 
-	q.metrics.retry()
+	q.metrics.retry(item, duration)
 
 	// immediately add things with no delay
 	if duration <= 0 {
@@ -171,6 +220,10 @@ func (q *delayingType) AddAfter(item interface{}, duration time.Duration) {
 		return
 	}
 
+	if q.addTracker != nil {
+		q.addTracker.record(item)
+	}
+
 	select {
 	case <-q.stopCh:
 		// unblock if ShutDown() is called
@@ -178,6 +231,22 @@ func (q *delayingType) AddAfter(item interface{}, duration time.Duration) {
 	}
 }
 
+// drop removes item from the waiting items and from the queue, returning
+// once it is removed.
+func (q *delayingType) drop(item interface{}) {
+	dropped := make(chan struct{})
+	// drops go through the waiting loop to be ordered with the waiting adds
+	select {
+	case <-q.stopCh:
+		return
+	case q.waitingForAddCh <- &waitFor{data: item, dropped: dropped}:
+	}
+	select {
+	case <-q.stopCh:
+	case <-dropped:
+	}
+}
+
 // maxWait keeps a max bound on the wait time. It's just insurance against weird things happening.
 // Checking the queue every 10 seconds isn't expensive and we know that we'll never end up with an
 // expired item sitting for more than 10 seconds.
@@ -213,7 +282,7 @@ func (q *delayingType) waitingLoop() {
 			}
 
 			entry = heap.Pop(waitingForQueue).(*waitFor)
-			q.Add(entry.data)
+			q.addReady(entry.data)
 			delete(waitingEntryByData, entry.data)
 		}
 
@@ -239,21 +308,13 @@ func (q *delayingType) waitingLoop() {
 			// continue the loop, which will add ready items
 
 		case waitEntry := <-q.waitingForAddCh:
-			if waitEntry.readyAt.After(q.clock.Now()) {
-				insert(waitingForQueue, waitingEntryByData, waitEntry)
-			} else {
-				q.Add(waitEntry.data)
-			}
+			q.handleWaitEntry(waitingForQueue, waitingEntryByData, waitEntry)
 
 			drained := false
 			for !drained {
 				select {
 				case waitEntry := <-q.waitingForAddCh:
-					if waitEntry.readyAt.After(q.clock.Now()) {
-						insert(waitingForQueue, waitingEntryByData, waitEntry)
-					} else {
-						q.Add(waitEntry.data)
-					}
+					q.handleWaitEntry(waitingForQueue, waitingEntryByData, waitEntry)
 				default:
 					drained = true
 				}
@@ -262,6 +323,32 @@ func (q *delayingType) waitingLoop() {
 	}
 }
 
+// handleWaitEntry adds the entry to the waiting items, or to the queue if it
+// is ready, or drops its data for drop requests.
+func (q *delayingType) handleWaitEntry(waitingForQueue *waitForPriorityQueue, knownEntries map[t]*waitFor, entry *waitFor) {
+	switch {
+	case entry.dropped != nil:
+		remove(waitingForQueue, knownEntries, entry.data)
+		if queue, ok := q.Interface.(dropper); ok {
+			queue.drop(entry.data)
+		}
+		close(entry.dropped)
+	case entry.readyAt.After(q.clock.Now()):
+		insert(waitingForQueue, knownEntries, entry)
+	default:
+		q.addReady(entry.data)
+	}
+}
+
+// addReady adds an item whose delay passed. Its add was recorded by AddAfter.
+func (q *delayingType) addReady(item interface{}) {
+	if queue, ok := q.Interface.(*Type); ok {
+		queue.add(item)
+		return
+	}
+	q.Add(item)
+}
+
 // insert adds the entry to the priority queue, or updates the readyAt if it already exists in the queue
 func insert(q *waitForPriorityQueue, knownEntries map[t]*waitFor, entry *waitFor) {
 	// if the entry already exists, update the time only if it would cause the item to be queued sooner
@@ -278,3 +365,11 @@ func insert(q *waitForPriorityQueue, knownEntries map[t]*waitFor, entry *waitFor
 	heap.Push(q, entry)
 	knownEntries[entry.data] = entry
 }
+
+// remove removes the entry of data from the priority queue, if any
+func remove(q *waitForPriorityQueue, knownEntries map[t]*waitFor, data t) {
+	if existing, exists := knownEntries[data]; exists {
+		heap.Remove(q, existing.index)
+		delete(knownEntries, data)
+	}
+}
@@ -0,0 +1,284 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workqueue
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrDependencyCycle is returned when a dependency would create a cycle.
+var ErrDependencyCycle = errors.New("dependency cycle")
+
+// DependencyGraph holds dependencies between the items of several queues, so
+// that an item is not dispatched while an item it depends on is pending in
+// its queue, for example to process a parent before its children.
+//
+// Queues take part in the graph through the Interface returned by Queue,
+// which all adds must go through. An item is pending from its add until Done
+// is called for it without it being added again meanwhile. The adds of items
+// with pending dependencies are deferred until all their dependencies are
+// done, and items with no pending dependencies are added immediately.
+// Dependencies on items that were never added do not defer adds.
+type DependencyGraph struct {
+	lock   sync.Mutex
+	queues map[string]Interface
+	// dependencies holds the dependencies of items, dependents the reverse
+	dependencies map[dependencyNode]map[dependencyNode]bool
+	dependents   map[dependencyNode]map[dependencyNode]bool
+	// states holds the state of pending items
+	states map[dependencyNode]*dependencyState
+}
+
+// dependencyNode is an item of a queue of a DependencyGraph.
+type dependencyNode struct {
+	queue string
+	item  t
+}
+
+type dependencyState struct {
+	// processing items were returned by Get and are not done yet
+	processing bool
+	// dirty items were added again while processing
+	dirty bool
+	// deferred items wait for their dependencies to be added to their queue
+	deferred bool
+}
+
+// NewDependencyGraph returns an empty DependencyGraph.
+func NewDependencyGraph() *DependencyGraph {
+	return &DependencyGraph{
+		queues:       map[string]Interface{},
+		dependencies: map[dependencyNode]map[dependencyNode]bool{},
+		dependents:   map[dependencyNode]map[dependencyNode]bool{},
+		states:       map[dependencyNode]*dependencyState{},
+	}
+}
+
+// Queue adds queue to the graph under name and returns the Interface to use
+// for it. To use the graph with delaying or rate limiting queues, pass the
+// returned Interface as their Queue, for example
+//
+//	workqueue.NewDelayingQueueWithConfig(workqueue.DelayingQueueConfig{
+//		Queue: graph.Queue("parents", workqueue.New()),
+//	})
+func (g *DependencyGraph) Queue(name string, queue Interface) Interface {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.queues[name] = queue
+	return &dependentQueue{Interface: queue, graph: g, name: name}
+}
+
+// AddDependency declares that item of the queue named queue must not be
+// dispatched while dependency of the queue named dependencyQueue is pending.
+// It returns ErrDependencyCycle if dependency depends on item, directly or
+// not. The dependency applies to later adds of item.
+func (g *DependencyGraph) AddDependency(queue string, item interface{}, dependencyQueue string, dependency interface{}) error {
+	from := dependencyNode{queue: queue, item: item}
+	to := dependencyNode{queue: dependencyQueue, item: dependency}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if from == to || g.dependsOnLocked(to, from, map[dependencyNode]bool{}) {
+		return fmt.Errorf("%w: %s %v depends on %s %v", ErrDependencyCycle, dependencyQueue, dependency, queue, item)
+	}
+	addEdge(g.dependencies, from, to)
+	addEdge(g.dependents, to, from)
+	return nil
+}
+
+// RemoveDependency removes a dependency added by AddDependency. A deferred add
+// of item proceeds if it has no other pending dependencies.
+func (g *DependencyGraph) RemoveDependency(queue string, item interface{}, dependencyQueue string, dependency interface{}) {
+	from := dependencyNode{queue: queue, item: item}
+	to := dependencyNode{queue: dependencyQueue, item: dependency}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	removeEdge(g.dependencies, from, to)
+	removeEdge(g.dependents, to, from)
+	g.releaseLocked(from)
+}
+
+// Forget removes all dependencies of and on item of the queue named queue,
+// for example once the object it stands for was deleted.
+func (g *DependencyGraph) Forget(queue string, item interface{}) {
+	n := dependencyNode{queue: queue, item: item}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	for to := range g.dependencies[n] {
+		removeEdge(g.dependents, to, n)
+	}
+	delete(g.dependencies, n)
+	dependents := g.dependents[n]
+	delete(g.dependents, n)
+	for from := range dependents {
+		removeEdge(g.dependencies, from, n)
+		g.releaseLocked(from)
+	}
+	g.releaseLocked(n)
+}
+
+// dependsOnLocked returns true if from depends on to, directly or not.
+func (g *DependencyGraph) dependsOnLocked(from, to dependencyNode, visited map[dependencyNode]bool) bool {
+	if visited[from] {
+		return false
+	}
+	visited[from] = true
+	for next := range g.dependencies[from] {
+		if next == to || g.dependsOnLocked(next, to, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// blockedLocked returns true if n has pending dependencies.
+func (g *DependencyGraph) blockedLocked(n dependencyNode) bool {
+	for dependency := range g.dependencies[n] {
+		if _, pending := g.states[dependency]; pending {
+			return true
+		}
+	}
+	return false
+}
+
+// addLocked adds n to its queue, or defers the add if n is blocked.
+func (g *DependencyGraph) addLocked(n dependencyNode) {
+	state, pending := g.states[n]
+	if !pending {
+		state = &dependencyState{}
+		g.states[n] = state
+	}
+	switch {
+	case state.processing:
+		// added once done, to check the dependencies again
+		state.dirty = true
+	case state.deferred:
+	case g.blockedLocked(n):
+		state.deferred = true
+	default:
+		g.queues[n.queue].Add(n.item)
+	}
+}
+
+// doneLocked marks n as done, adding it again if it was added while being
+// processed, or releasing its dependents otherwise.
+func (g *DependencyGraph) doneLocked(n dependencyNode) {
+	state, pending := g.states[n]
+	if !pending {
+		return
+	}
+	state.processing = false
+	if state.dirty {
+		state.dirty = false
+		g.addLocked(n)
+		return
+	}
+	delete(g.states, n)
+	for dependent := range g.dependents[n] {
+		g.releaseLocked(dependent)
+	}
+}
+
+// releaseLocked adds n to its queue if its add was deferred and it is no
+// longer blocked.
+func (g *DependencyGraph) releaseLocked(n dependencyNode) {
+	state, pending := g.states[n]
+	if !pending || !state.deferred || g.blockedLocked(n) {
+		return
+	}
+	state.deferred = false
+	g.queues[n.queue].Add(n.item)
+}
+
+func addEdge(edges map[dependencyNode]map[dependencyNode]bool, from, to dependencyNode) {
+	if edges[from] == nil {
+		edges[from] = map[dependencyNode]bool{}
+	}
+	edges[from][to] = true
+}
+
+func removeEdge(edges map[dependencyNode]map[dependencyNode]bool, from, to dependencyNode) {
+	delete(edges[from], to)
+	if len(edges[from]) == 0 {
+		delete(edges, from)
+	}
+}
+
+// dependentQueue is a queue of a DependencyGraph.
+type dependentQueue struct {
+	Interface
+	graph *DependencyGraph
+	name  string
+}
+
+// Add marks item as needing processing once its dependencies are done.
+func (q *dependentQueue) Add(item interface{}) {
+	if q.ShuttingDown() {
+		return
+	}
+	q.graph.lock.Lock()
+	defer q.graph.lock.Unlock()
+	q.graph.addLocked(dependencyNode{queue: q.name, item: item})
+}
+
+// drop removes item from the queue, or cancels its deferred add, releasing
+// the items depending on it unless it is being processed.
+func (q *dependentQueue) drop(item interface{}) {
+	queue, ok := q.Interface.(dropper)
+	if !ok {
+		return
+	}
+	q.graph.lock.Lock()
+	defer q.graph.lock.Unlock()
+	queue.drop(item)
+	n := dependencyNode{queue: q.name, item: item}
+	state, pending := q.graph.states[n]
+	switch {
+	case !pending:
+	case state.processing:
+		state.dirty = false
+	default:
+		delete(q.graph.states, n)
+		for dependent := range q.graph.dependents[n] {
+			q.graph.releaseLocked(dependent)
+		}
+	}
+}
+
+// Get blocks until it can return an item to be processed.
+func (q *dependentQueue) Get() (interface{}, bool) {
+	item, shutdown := q.Interface.Get()
+	if shutdown {
+		return item, shutdown
+	}
+	q.graph.lock.Lock()
+	defer q.graph.lock.Unlock()
+	if state, pending := q.graph.states[dependencyNode{queue: q.name, item: item}]; pending {
+		state.processing = true
+	}
+	return item, false
+}
+
+// Done marks item as done processing, which releases the items depending on
+// it unless it was added again meanwhile.
+func (q *dependentQueue) Done(item interface{}) {
+	q.graph.lock.Lock()
+	defer q.graph.lock.Unlock()
+	q.Interface.Done(item)
+	q.graph.doneLocked(dependencyNode{queue: q.name, item: item})
+}
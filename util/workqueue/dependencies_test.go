@@ -0,0 +1,169 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workqueue
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDependencyGraphDefersAdds(t *testing.T) {
+	g := NewDependencyGraph()
+	parents := g.Queue("parents", New())
+	children := g.Queue("children", New())
+	if err := g.AddDependency("children", "child", "parents", "parent"); err != nil {
+		t.Fatal(err)
+	}
+
+	parents.Add("parent")
+	children.Add("child")
+	children.Add("other")
+	if got := children.Len(); got != 1 {
+		t.Fatalf("expected only the independent child to be queued, got %d items", got)
+	}
+	if item, _ := children.Get(); item != "other" {
+		t.Fatalf("expected other, got %v", item)
+	}
+	children.Done("other")
+
+	item, _ := parents.Get()
+	if got := children.Len(); got != 0 {
+		t.Fatalf("expected child to wait while parent is processing, got %d items", got)
+	}
+	parents.Done(item)
+	if got := children.Len(); got != 1 {
+		t.Fatalf("expected child to be queued once parent is done, got %d items", got)
+	}
+	if item, _ := children.Get(); item != "child" {
+		t.Fatalf("expected child, got %v", item)
+	}
+	children.Done("child")
+
+	// dependencies on items that are not pending do not defer adds
+	children.Add("child")
+	if got := children.Len(); got != 1 {
+		t.Fatalf("expected child to be queued, got %d items", got)
+	}
+}
+
+func TestDependencyGraphReaddWhileProcessing(t *testing.T) {
+	g := NewDependencyGraph()
+	parents := g.Queue("parents", New())
+	children := g.Queue("children", New())
+	if err := g.AddDependency("children", "child", "parents", "parent"); err != nil {
+		t.Fatal(err)
+	}
+
+	children.Add("child")
+	item, _ := children.Get()
+	parents.Add("parent")
+	children.Add("child")
+	children.Done(item)
+	if got := children.Len(); got != 0 {
+		t.Fatalf("expected the added child to wait for parent, got %d items", got)
+	}
+
+	item, _ = parents.Get()
+	parents.Add("parent")
+	parents.Done(item)
+	if got := children.Len(); got != 0 {
+		t.Fatalf("expected child to wait for the added parent, got %d items", got)
+	}
+	item, _ = parents.Get()
+	parents.Done(item)
+	if got := children.Len(); got != 1 {
+		t.Fatalf("expected child to be queued, got %d items", got)
+	}
+}
+
+func TestDependencyGraphCycles(t *testing.T) {
+	g := NewDependencyGraph()
+	if err := g.AddDependency("a", 1, "b", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddDependency("b", 1, "c", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddDependency("c", 1, "a", 1); !errors.Is(err, ErrDependencyCycle) {
+		t.Fatalf("expected a cycle error, got %v", err)
+	}
+	if err := g.AddDependency("a", 1, "a", 1); !errors.Is(err, ErrDependencyCycle) {
+		t.Fatalf("expected a cycle error, got %v", err)
+	}
+	g.RemoveDependency("b", 1, "c", 1)
+	if err := g.AddDependency("c", 1, "a", 1); err != nil {
+		t.Fatalf("expected no cycle once removed, got %v", err)
+	}
+}
+
+func TestDependencyGraphForget(t *testing.T) {
+	g := NewDependencyGraph()
+	parents := g.Queue("parents", New())
+	children := g.Queue("children", New())
+	if err := g.AddDependency("children", "child", "parents", "parent"); err != nil {
+		t.Fatal(err)
+	}
+	parents.Add("parent")
+	children.Add("child")
+	g.Forget("parents", "parent")
+	if got := children.Len(); got != 1 {
+		t.Fatalf("expected child to be queued once parent is forgotten, got %d items", got)
+	}
+}
+
+func TestDependencyGraphWithRateLimitingQueue(t *testing.T) {
+	g := NewDependencyGraph()
+	parents := g.Queue("parents", New())
+	children := NewRateLimitingQueueWithConfig(DefaultControllerRateLimiter(), RateLimitingQueueConfig{
+		DelayingQueue: NewDelayingQueueWithConfig(DelayingQueueConfig{Queue: g.Queue("children", New())}),
+	})
+	defer children.ShutDown()
+	if err := g.AddDependency("children", "child", "parents", "parent"); err != nil {
+		t.Fatal(err)
+	}
+	parents.Add("parent")
+	children.Add("child")
+	if got := children.Len(); got != 0 {
+		t.Fatalf("expected child to wait for parent, got %d items", got)
+	}
+	item, _ := parents.Get()
+	parents.Done(item)
+	if got := children.Len(); got != 1 {
+		t.Fatalf("expected child to be queued, got %d items", got)
+	}
+}
+
+func TestDependencyGraphDrop(t *testing.T) {
+	g := NewDependencyGraph()
+	parents := NewRateLimitingQueueWithConfig(DefaultControllerRateLimiter(), RateLimitingQueueConfig{
+		DelayingQueue: NewDelayingQueueWithConfig(DelayingQueueConfig{Queue: g.Queue("parents", New())}),
+	})
+	defer parents.ShutDown()
+	children := g.Queue("children", New())
+	if err := g.AddDependency("children", "child", "parents", "parent"); err != nil {
+		t.Fatal(err)
+	}
+	parents.Add("parent")
+	children.Add("child")
+	parents.(DroppingInterface).ForgetAndDrop("parent")
+	if got := parents.Len(); got != 0 {
+		t.Fatalf("expected parent to be dropped, got %d items", got)
+	}
+	if got := children.Len(); got != 1 {
+		t.Fatalf("expected child to be queued once parent is dropped, got %d items", got)
+	}
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workqueue
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// HintedInterface is a queue whose adds can carry hints about what changed,
+// for example the fields of an object, so that the processing of an item can
+// skip the work unrelated to the changes. The hints of the adds of an item
+// are unioned until it is returned by GetWithHints.
+type HintedInterface interface {
+	Interface
+	// AddWithHints adds item with hints about what changed. Adds without
+	// hints, including the ones through Add, mean that anything may have
+	// changed.
+	AddWithHints(item interface{}, hints ...string)
+	// GetWithHints returns an item to process with the union of the hints of
+	// its adds, or nil hints if anything may have changed.
+	GetWithHints() (item interface{}, hints sets.String, shutdown bool)
+}
+
+// hintedType is a queue carrying hints.
+type hintedType struct {
+	Interface
+
+	lock sync.Mutex
+	// hints holds the hints of items added since they were last returned
+	// by GetWithHints, nil meaning that anything may have changed
+	hints map[t]sets.String
+}
+
+// NewHintedQueue returns a HintedInterface adding items to queue. To use it
+// with delaying or rate limiting queues, pass it as their Queue and call
+// AddWithHints on it, for example
+//
+//	hinted := workqueue.NewHintedQueue(workqueue.New())
+//	queue := workqueue.NewRateLimitingQueueWithConfig(rateLimiter, workqueue.RateLimitingQueueConfig{
+//		DelayingQueue: workqueue.NewDelayingQueueWithConfig(workqueue.DelayingQueueConfig{Queue: hinted}),
+//	})
+//
+// Items added through the delaying or rate limiting methods carry no hints.
+func NewHintedQueue(queue Interface) HintedInterface {
+	return &hintedType{
+		Interface: queue,
+		hints:     map[t]sets.String{},
+	}
+}
+
+// Add marks item as needing processing, without hints.
+func (q *hintedType) Add(item interface{}) {
+	q.add(item, nil)
+}
+
+// AddWithHints marks item as needing processing because of hints.
+func (q *hintedType) AddWithHints(item interface{}, hints ...string) {
+	if len(hints) == 0 {
+		q.add(item, nil)
+		return
+	}
+	q.add(item, sets.NewString(hints...))
+}
+
+func (q *hintedType) add(item interface{}, hints sets.String) {
+	if q.ShuttingDown() {
+		return
+	}
+	q.lock.Lock()
+	current, found := q.hints[item]
+	switch {
+	case !found:
+		q.hints[item] = hints
+	case current != nil && hints != nil:
+		current.Insert(hints.UnsortedList()...)
+	default:
+		q.hints[item] = nil
+	}
+	q.lock.Unlock()
+	q.Interface.Add(item)
+}
+
+// drop removes item and its hints from the queue.
+func (q *hintedType) drop(item interface{}) {
+	queue, ok := q.Interface.(dropper)
+	if !ok {
+		return
+	}
+	q.lock.Lock()
+	delete(q.hints, item)
+	q.lock.Unlock()
+	queue.drop(item)
+}
+
+// GetWithHints blocks until it can return an item to be processed, with the
+// hints of its adds.
+func (q *hintedType) GetWithHints() (interface{}, sets.String, bool) {
+	item, shutdown := q.Interface.Get()
+	if shutdown {
+		return item, nil, shutdown
+	}
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	// items missing from hints had their hints returned along an earlier
+	// Get racing with their add, anything may have changed then
+	hints := q.hints[item]
+	delete(q.hints, item)
+	return item, hints, false
+}
+
+// Get blocks until it can return an item to be processed, dropping its hints.
+func (q *hintedType) Get() (interface{}, bool) {
+	item, _, shutdown := q.GetWithHints()
+	return item, shutdown
+}
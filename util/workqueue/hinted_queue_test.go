@@ -0,0 +1,95 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workqueue
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+func TestHintedQueue(t *testing.T) {
+	q := NewHintedQueue(New())
+	defer q.ShutDown()
+
+	q.AddWithHints("foo", "status")
+	q.AddWithHints("foo", "metadata.labels", "status")
+	q.AddWithHints("bar", "spec")
+	if got := q.Len(); got != 2 {
+		t.Fatalf("expected 2 items, got %d", got)
+	}
+	item, hints, _ := q.GetWithHints()
+	if item != "foo" || !hints.Equal(sets.NewString("status", "metadata.labels")) {
+		t.Fatalf("unexpected item %v with hints %v", item, hints)
+	}
+
+	// hints added while processing are returned with the next get
+	q.AddWithHints("foo", "spec")
+	q.Done(item)
+	item, hints, _ = q.GetWithHints()
+	if item != "bar" || !hints.Equal(sets.NewString("spec")) {
+		t.Fatalf("unexpected item %v with hints %v", item, hints)
+	}
+	q.Done(item)
+	item, hints, _ = q.GetWithHints()
+	if item != "foo" || !hints.Equal(sets.NewString("spec")) {
+		t.Fatalf("unexpected item %v with hints %v", item, hints)
+	}
+	q.Done(item)
+
+	// adds without hints mean that anything may have changed
+	q.AddWithHints("foo", "status")
+	q.Add("foo")
+	q.AddWithHints("foo", "spec")
+	if item, hints, _ = q.GetWithHints(); item != "foo" || hints != nil {
+		t.Fatalf("unexpected item %v with hints %v", item, hints)
+	}
+	q.Done(item)
+}
+
+func TestHintedQueueWithRateLimitingQueue(t *testing.T) {
+	hinted := NewHintedQueue(New())
+	q := NewRateLimitingQueueWithConfig(NewItemFastSlowRateLimiter(0, 0, 0), RateLimitingQueueConfig{
+		DelayingQueue: NewDelayingQueueWithConfig(DelayingQueueConfig{Queue: hinted}),
+	})
+	defer q.ShutDown()
+
+	hinted.AddWithHints("foo", "status")
+	q.AddRateLimited("foo")
+	item, hints, _ := hinted.GetWithHints()
+	if item != "foo" || hints != nil {
+		t.Fatalf("unexpected item %v with hints %v", item, hints)
+	}
+	q.Done(item)
+}
+
+func TestHintedQueueDrop(t *testing.T) {
+	hinted := NewHintedQueue(New())
+	q := NewRateLimitingQueueWithConfig(DefaultControllerRateLimiter(), RateLimitingQueueConfig{
+		DelayingQueue: NewDelayingQueueWithConfig(DelayingQueueConfig{Queue: hinted}),
+	})
+	defer q.ShutDown()
+
+	hinted.AddWithHints("foo", "status")
+	q.(DroppingInterface).ForgetAndDrop("foo")
+	hinted.AddWithHints("foo", "spec")
+	item, hints, _ := hinted.GetWithHints()
+	if item != "foo" || !hints.Equal(sets.NewString("spec")) {
+		t.Fatalf("unexpected item %v with hints %v", item, hints)
+	}
+	q.Done(item)
+}
@@ -30,6 +30,7 @@ type queueMetrics interface {
 	add(item t)
 	get(item t)
 	done(item t)
+	drop(item t)
 	updateUnfinishedWork()
 }
 
@@ -125,6 +126,15 @@ func (m *defaultQueueMetrics) done(item t) {
 	}
 }
 
+func (m *defaultQueueMetrics) drop(item t) {
+	if m == nil {
+		return
+	}
+
+	m.depth.Dec()
+	delete(m.addTimes, item)
+}
+
 func (m *defaultQueueMetrics) updateUnfinishedWork() {
 	// Note that a summary metric would be better for this, but prometheus
 	// doesn't seem to have non-hacky ways to reset the summary metrics.
@@ -146,6 +156,7 @@ type noMetrics struct{}
 func (noMetrics) add(item t)            {}
 func (noMetrics) get(item t)            {}
 func (noMetrics) done(item t)           {}
+func (noMetrics) drop(item t)           {}
 func (noMetrics) updateUnfinishedWork() {}
 
 // Gets the time since the specified start in seconds.
@@ -154,19 +165,30 @@ func (m *defaultQueueMetrics) sinceInSeconds(start time.Time) float64 {
 }
 
 type retryMetrics interface {
-	retry()
+	retry(item t, delay time.Duration)
 }
 
 type defaultRetryMetrics struct {
 	retries CounterMetric
 }
 
-func (m *defaultRetryMetrics) retry() {
+func (m *defaultRetryMetrics) retry(item t, delay time.Duration) {
 	if m == nil {
 		return
 	}
 
 	m.retries.Inc()
+	if retries, ok := m.retries.(ItemRetryMetric); ok {
+		retries.ObserveRetry(item, delay)
+	}
+}
+
+// ItemRetryMetric is optionally implemented by the retries metric of a
+// MetricsProvider to also observe which items are retried, and after which
+// delay.
+type ItemRetryMetric interface {
+	CounterMetric
+	ObserveRetry(item interface{}, delay time.Duration)
 }
 
 // MetricsProvider generates various metrics used by the queue.
@@ -227,7 +249,10 @@ func (f *queueMetricsFactory) setProvider(mp MetricsProvider) {
 }
 
 func (f *queueMetricsFactory) newQueueMetrics(name string, clock clock.Clock) queueMetrics {
-	mp := f.metricsProvider
+	return newQueueMetrics(f.metricsProvider, name, clock)
+}
+
+func newQueueMetrics(mp MetricsProvider, name string, clock clock.Clock) queueMetrics {
 	if len(name) == 0 || mp == (noopMetricsProvider{}) {
 		return noMetrics{}
 	}
@@ -244,13 +269,16 @@ func (f *queueMetricsFactory) newQueueMetrics(name string, clock clock.Clock) qu
 	}
 }
 
-func newRetryMetrics(name string) retryMetrics {
+func newRetryMetrics(name string, provider MetricsProvider) retryMetrics {
 	var ret *defaultRetryMetrics
 	if len(name) == 0 {
 		return ret
 	}
+	if provider == nil {
+		provider = globalMetricsFactory.metricsProvider
+	}
 	return &defaultRetryMetrics{
-		retries: globalMetricsFactory.metricsProvider.NewRetriesMetric(name),
+		retries: provider.NewRetriesMetric(name),
 	}
 }
 
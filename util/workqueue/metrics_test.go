@@ -33,6 +33,7 @@ type testMetrics struct {
 func (m *testMetrics) add(item t)            { m.added++ }
 func (m *testMetrics) get(item t)            { m.gotten++ }
 func (m *testMetrics) done(item t)           { m.finished++ }
+func (m *testMetrics) drop(item t)           {}
 func (m *testMetrics) updateUnfinishedWork() { m.updateCalled <- struct{}{} }
 
 func TestMetricShutdown(t *testing.T) {
@@ -39,12 +39,43 @@ func New() *Type {
 }
 
 func NewNamed(name string) *Type {
-	rc := clock.RealClock{}
-	return newQueue(
-		rc,
-		globalMetricsFactory.newQueueMetrics(name, rc),
+	return NewWithConfig(QueueConfig{Name: name})
+}
+
+// QueueConfig specifies optional configurations to customize an Interface.
+type QueueConfig struct {
+	// Name for the queue. If unnamed, the metrics will not be registered.
+	Name string
+
+	// MetricsProvider optionally allows specifying a metrics provider to use
+	// for the queue instead of the global provider set by SetProvider.
+	MetricsProvider MetricsProvider
+
+	// Clock optionally allows injecting a real or fake clock for testing
+	// purposes.
+	Clock clock.WithTicker
+
+	// AddTracker optionally counts the adds of items and samples their stack
+	// traces, to debug items added pathologically often.
+	AddTracker *AddTracker
+}
+
+// NewWithConfig constructs a new work queue with options to customize its
+// behavior.
+func NewWithConfig(config QueueConfig) *Type {
+	if config.Clock == nil {
+		config.Clock = clock.RealClock{}
+	}
+	if config.MetricsProvider == nil {
+		config.MetricsProvider = globalMetricsFactory.metricsProvider
+	}
+	q := newQueue(
+		config.Clock,
+		newQueueMetrics(config.MetricsProvider, config.Name, config.Clock),
 		defaultUnfinishedWorkUpdatePeriod,
 	)
+	q.addTracker = config.AddTracker
+	return q
 }
 
 func newQueue(c clock.WithTicker, metrics queueMetrics, updatePeriod time.Duration) *Type {
@@ -93,6 +124,9 @@ type Type struct {
 
 	unfinishedWorkUpdatePeriod time.Duration
 	clock                      clock.WithTicker
+
+	// addTracker, if set, records the adds of items
+	addTracker *AddTracker
 }
 
 type empty struct{}
@@ -118,6 +152,15 @@ func (s set) len() int {
 
 // Add marks item as needing processing.
 func (q *Type) Add(item interface{}) {
+	if q.addTracker != nil {
+		q.addTracker.record(item)
+	}
+	q.add(item)
+}
+
+// add marks item as needing processing without recording the add, for adds
+// recorded before, like those of items with delays.
+func (q *Type) add(item interface{}) {
 	q.cond.L.Lock()
 	defer q.cond.L.Unlock()
 	if q.shuttingDown {
@@ -138,6 +181,37 @@ func (q *Type) Add(item interface{}) {
 	q.cond.Signal()
 }
 
+// dropper is implemented by the queues which can drop items.
+type dropper interface {
+	// drop removes item from the items needing processing, without
+	// affecting its ongoing processing.
+	drop(item interface{})
+}
+
+// drop removes item from the queue and the dirty set, so that it is not
+// processed again unless it is added again.
+func (q *Type) drop(item interface{}) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	if !q.dirty.has(item) {
+		return
+	}
+	q.dirty.delete(item)
+	q.metrics.drop(item)
+	if q.processing.has(item) {
+		// dirty items being processed are only queued once done
+		return
+	}
+	for i, queued := range q.queue {
+		if queued == item {
+			copy(q.queue[i:], q.queue[i+1:])
+			q.queue[len(q.queue)-1] = nil
+			q.queue = q.queue[:len(q.queue)-1]
+			return
+		}
+	}
+}
+
 // Len returns the current queue length, for informational purposes only. You
 // shouldn't e.g. gate a call to Add() or Get() on Len() being a particular
 // value, that can't be synchronized properly.
@@ -0,0 +1,219 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workqueue
+
+import (
+	"strings"
+	"sync"
+)
+
+// QuotaConfig configures a queue bounding the in-flight items of tenants.
+type QuotaConfig struct {
+	// MaxInFlight is the number of items of a tenant which may be queued or
+	// processed at once. Adds beyond it are deferred until items of the
+	// tenant are done. It must be positive.
+	MaxInFlight int
+
+	// TenantFunc optionally returns the tenant of an item. By default the
+	// tenant of "namespace/name" keys is their namespace, and other items
+	// belong to the "" tenant.
+	TenantFunc func(item interface{}) string
+}
+
+// quotaState is the state of an item of a quota queue.
+type quotaState struct {
+	// processing items were returned by Get and are not done yet
+	processing bool
+	// dirty items were added again while processing
+	dirty bool
+	// deferred items wait for their tenant to be under quota
+	deferred bool
+}
+
+// quotaQueue is a queue bounding the in-flight items of tenants.
+type quotaQueue struct {
+	Interface
+	maxInFlight int
+	tenantFunc  func(item interface{}) string
+
+	lock sync.Mutex
+	// states holds the state of pending items, inFlight the number of
+	// pending items of tenants which are not deferred, and deferred their
+	// deferred items in the order they were added
+	states   map[t]*quotaState
+	inFlight map[string]int
+	deferred map[string][]t
+}
+
+// NewQuotaQueue returns an Interface adding items to queue while bounding the
+// number of items of each tenant which are queued or processed at once, so
+// that the items of one tenant cannot take all the workers of a controller
+// shared by many. Deferred items are added in the order they were added once
+// the items of their tenant are done. To use it with delaying or rate
+// limiting queues, pass it as their Queue, for example
+//
+//	workqueue.NewRateLimitingQueueWithConfig(rateLimiter, workqueue.RateLimitingQueueConfig{
+//		DelayingQueue: workqueue.NewDelayingQueueWithConfig(workqueue.DelayingQueueConfig{
+//			Queue: workqueue.NewQuotaQueue(workqueue.New(), workqueue.QuotaConfig{MaxInFlight: 2}),
+//		}),
+//	})
+func NewQuotaQueue(queue Interface, config QuotaConfig) Interface {
+	if config.MaxInFlight <= 0 {
+		panic("workqueue: MaxInFlight must be positive")
+	}
+	if config.TenantFunc == nil {
+		config.TenantFunc = namespaceTenant
+	}
+	return &quotaQueue{
+		Interface:   queue,
+		maxInFlight: config.MaxInFlight,
+		tenantFunc:  config.TenantFunc,
+		states:      map[t]*quotaState{},
+		inFlight:    map[string]int{},
+		deferred:    map[string][]t{},
+	}
+}
+
+// namespaceTenant returns the namespace of "namespace/name" keys.
+func namespaceTenant(item interface{}) string {
+	key, ok := item.(string)
+	if !ok {
+		return ""
+	}
+	if i := strings.Index(key, "/"); i >= 0 {
+		return key[:i]
+	}
+	return ""
+}
+
+// Add marks item as needing processing once its tenant is under quota.
+func (q *quotaQueue) Add(item interface{}) {
+	if q.ShuttingDown() {
+		return
+	}
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	state, pending := q.states[item]
+	switch {
+	case !pending:
+		q.states[item] = &quotaState{}
+		q.dispatchLocked(item, q.tenantFunc(item))
+	case state.processing:
+		// added once done
+		state.dirty = true
+	}
+}
+
+// dispatchLocked adds a pending item to the queue if its tenant is under
+// quota, or defers it.
+func (q *quotaQueue) dispatchLocked(item t, tenant string) {
+	if q.inFlight[tenant] >= q.maxInFlight {
+		q.states[item].deferred = true
+		q.deferred[tenant] = append(q.deferred[tenant], item)
+		return
+	}
+	q.inFlight[tenant]++
+	q.Interface.Add(item)
+}
+
+// releaseLocked frees the quota of an item of tenant, adding its deferred
+// items while it is under quota.
+func (q *quotaQueue) releaseLocked(tenant string) {
+	q.inFlight[tenant]--
+	if q.inFlight[tenant] <= 0 {
+		delete(q.inFlight, tenant)
+	}
+	for len(q.deferred[tenant]) > 0 && q.inFlight[tenant] < q.maxInFlight {
+		item := q.deferred[tenant][0]
+		q.deferred[tenant] = q.deferred[tenant][1:]
+		q.states[item].deferred = false
+		q.inFlight[tenant]++
+		q.Interface.Add(item)
+	}
+	if len(q.deferred[tenant]) == 0 {
+		delete(q.deferred, tenant)
+	}
+}
+
+// Get blocks until it can return an item to be processed.
+func (q *quotaQueue) Get() (interface{}, bool) {
+	item, shutdown := q.Interface.Get()
+	if shutdown {
+		return item, shutdown
+	}
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if state, pending := q.states[item]; pending {
+		state.processing = true
+	}
+	return item, false
+}
+
+// Done marks item as done processing, which frees its quota unless it was
+// added again meanwhile.
+func (q *quotaQueue) Done(item interface{}) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.Interface.Done(item)
+	state, pending := q.states[item]
+	if !pending {
+		return
+	}
+	tenant := q.tenantFunc(item)
+	state.processing = false
+	if state.dirty {
+		// The item keeps its quota.
+		state.dirty = false
+		q.Interface.Add(item)
+		return
+	}
+	delete(q.states, item)
+	q.releaseLocked(tenant)
+}
+
+// drop removes item from the queue, or cancels its deferred add, freeing its
+// quota unless it is being processed.
+func (q *quotaQueue) drop(item interface{}) {
+	queue, ok := q.Interface.(dropper)
+	if !ok {
+		return
+	}
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	queue.drop(item)
+	state, pending := q.states[item]
+	switch {
+	case !pending:
+	case state.processing:
+		state.dirty = false
+	case state.deferred:
+		delete(q.states, item)
+		tenant := q.tenantFunc(item)
+		for i, deferred := range q.deferred[tenant] {
+			if deferred == item {
+				q.deferred[tenant] = append(q.deferred[tenant][:i], q.deferred[tenant][i+1:]...)
+				break
+			}
+		}
+		if len(q.deferred[tenant]) == 0 {
+			delete(q.deferred, tenant)
+		}
+	default:
+		delete(q.states, item)
+		q.releaseLocked(q.tenantFunc(item))
+	}
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workqueue
+
+import (
+	"testing"
+)
+
+func TestQuotaQueueDefersAdds(t *testing.T) {
+	q := NewQuotaQueue(New(), QuotaConfig{MaxInFlight: 2})
+	for _, item := range []string{"noisy/a", "noisy/b", "noisy/c", "quiet/a", "noisy/d"} {
+		q.Add(item)
+	}
+	if got := q.Len(); got != 3 {
+		t.Fatalf("expected 2 noisy items and the quiet one to be queued, got %d items", got)
+	}
+	for _, expected := range []string{"noisy/a", "noisy/b", "quiet/a"} {
+		if item, _ := q.Get(); item != expected {
+			t.Fatalf("expected %s, got %v", expected, item)
+		}
+	}
+
+	q.Done("noisy/a")
+	if got := q.Len(); got != 1 {
+		t.Fatalf("expected the first deferred item to be queued, got %d items", got)
+	}
+	if item, _ := q.Get(); item != "noisy/c" {
+		t.Fatalf("expected noisy/c, got %v", item)
+	}
+	q.Done("noisy/b")
+	if item, _ := q.Get(); item != "noisy/d" {
+		t.Fatalf("expected noisy/d, got %v", item)
+	}
+}
+
+func TestQuotaQueueReaddWhileProcessing(t *testing.T) {
+	q := NewQuotaQueue(New(), QuotaConfig{MaxInFlight: 1})
+	q.Add("ns/a")
+	item, _ := q.Get()
+	q.Add("ns/a")
+	q.Add("ns/b")
+	if got := q.Len(); got != 0 {
+		t.Fatalf("expected the adds to wait while ns/a is processed, got %d items", got)
+	}
+	q.Done(item)
+	if item, _ := q.Get(); item != "ns/a" {
+		t.Fatalf("expected ns/a to keep its quota when added while processing, got %v", item)
+	}
+	if got := q.Len(); got != 0 {
+		t.Fatalf("expected ns/b to stay deferred, got %d items", got)
+	}
+	q.Done("ns/a")
+	if item, _ := q.Get(); item != "ns/b" {
+		t.Fatalf("expected ns/b, got %v", item)
+	}
+}
+
+func TestQuotaQueueTenantFunc(t *testing.T) {
+	q := NewQuotaQueue(New(), QuotaConfig{
+		MaxInFlight: 1,
+		TenantFunc:  func(item interface{}) string { return item.(string)[:1] },
+	})
+	q.Add("a1")
+	q.Add("a2")
+	q.Add("b1")
+	if got := q.Len(); got != 2 {
+		t.Fatalf("expected an item per tenant to be queued, got %d items", got)
+	}
+}
+
+func TestQuotaQueueDrop(t *testing.T) {
+	q := NewRateLimitingQueueWithConfig(DefaultControllerRateLimiter(), RateLimitingQueueConfig{
+		DelayingQueue: NewDelayingQueueWithConfig(DelayingQueueConfig{
+			Queue: NewQuotaQueue(New(), QuotaConfig{MaxInFlight: 1}),
+		}),
+	})
+	defer q.ShutDown()
+	q.Add("ns/a")
+	q.Add("ns/b")
+	q.Add("ns/c")
+	q.(DroppingInterface).ForgetAndDrop("ns/b")
+	q.(DroppingInterface).ForgetAndDrop("ns/a")
+	if got := q.Len(); got != 1 {
+		t.Fatalf("expected ns/c to be queued once ns/a is dropped, got %d items", got)
+	}
+	if item, _ := q.Get(); item != "ns/c" {
+		t.Fatalf("expected ns/c, got %v", item)
+	}
+}
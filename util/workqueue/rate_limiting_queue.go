@@ -16,6 +16,13 @@ limitations under the License.
 
 package workqueue
 
+import (
+	"errors"
+	"sync"
+
+	"k8s.io/utils/clock"
+)
+
 // RateLimitingInterface is an interface that rate limits items being added to the queue.
 type RateLimitingInterface interface {
 	DelayingInterface
@@ -32,6 +39,36 @@ type RateLimitingInterface interface {
 	NumRequeues(item interface{}) int
 }
 
+// StatefulInterface is implemented by the rate limiting queues whose failure counts can be
+// exported and imported, like the ones returned by NewRateLimitingQueue.
+type StatefulInterface interface {
+	// ExportFailures returns the failure counts of the items tracked by the rate limiter, empty if it
+	// is not a StatefulRateLimiter.
+	ExportFailures() map[interface{}]int
+
+	// ImportFailures raises the failure counts of items in the rate limiter to the given ones, for
+	// example exported before a restart, if it is a StatefulRateLimiter.
+	ImportFailures(failures map[interface{}]int)
+}
+
+// ErrDropUnsupported is returned by ForgetAndDrop when the delaying queue of
+// a rate limiting queue cannot drop items.
+var ErrDropUnsupported = errors.New("the queue cannot drop items")
+
+// DroppingInterface is implemented by the rate limiting queues which can drop items, like the
+// ones returned by NewRateLimitingQueue.
+type DroppingInterface interface {
+	// ForgetAndDrop forgets the item like Forget, and removes it from the items waiting to be added
+	// and needing processing, for example to cancel the pending retries of deleted objects. An
+	// ongoing processing of the item is not affected, you still have to call `Done` on the queue.
+	//
+	// It is atomic with respect to AddRateLimited: a concurrent rate limited add of the item is
+	// either dropped and forgotten, or kept. ErrDropUnsupported is returned, once the item is
+	// forgotten, when the delaying queue was not created by this package. Queues of this package
+	// wrapping queues of other packages only drop the items waiting to be added.
+	ForgetAndDrop(item interface{}) error
+}
+
 // NewRateLimitingQueue constructs a new workqueue with rateLimited queuing ability
 // Remember to call Forget!  If you don't, you may end up tracking failures forever.
 func NewRateLimitingQueue(rateLimiter RateLimiter) RateLimitingInterface {
@@ -48,15 +85,67 @@ func NewNamedRateLimitingQueue(rateLimiter RateLimiter, name string) RateLimitin
 	}
 }
 
+// RateLimitingQueueConfig specifies optional configurations to customize a
+// RateLimitingInterface.
+type RateLimitingQueueConfig struct {
+	// Name for the queue. If unnamed, the metrics will not be registered.
+	Name string
+
+	// MetricsProvider optionally allows specifying a metrics provider to use
+	// for the queue instead of the global provider set by SetProvider.
+	MetricsProvider MetricsProvider
+
+	// Clock optionally allows injecting a real or fake clock for testing
+	// purposes.
+	Clock clock.WithTicker
+
+	// DelayingQueue optionally allows injecting a custom delaying queue
+	// DelayingInterface instead of the default one.
+	DelayingQueue DelayingInterface
+
+	// AddTracker optionally counts the adds of items and samples their stack
+	// traces, to debug items added pathologically often. It is ignored with a
+	// custom DelayingQueue.
+	AddTracker *AddTracker
+}
+
+// NewRateLimitingQueueWithConfig constructs a new workqueue with
+// rateLimited queuing ability with options to customize its behavior.
+// Remember to call Forget!  If you don't, you may end up tracking failures
+// forever.
+func NewRateLimitingQueueWithConfig(rateLimiter RateLimiter, config RateLimitingQueueConfig) RateLimitingInterface {
+	if config.DelayingQueue == nil {
+		config.DelayingQueue = NewDelayingQueueWithConfig(DelayingQueueConfig{
+			Name:            config.Name,
+			MetricsProvider: config.MetricsProvider,
+			Clock:           config.Clock,
+			AddTracker:      config.AddTracker,
+		})
+	}
+	return &rateLimitingType{
+		DelayingInterface: config.DelayingQueue,
+		rateLimiter:       rateLimiter,
+	}
+}
+
 // rateLimitingType wraps an Interface and provides rateLimited re-enquing
 type rateLimitingType struct {
 	DelayingInterface
 
 	rateLimiter RateLimiter
+
+	// dropLock orders the rate limited adds and the drops, to make drops
+	// atomic
+	dropLock sync.RWMutex
 }
 
+var _ DroppingInterface = &rateLimitingType{}
+var _ StatefulInterface = &rateLimitingType{}
+
 // AddRateLimited AddAfter's the item based on the time when the rate limiter says it's ok
 func (q *rateLimitingType) AddRateLimited(item interface{}) {
+	q.dropLock.RLock()
+	defer q.dropLock.RUnlock()
 	q.DelayingInterface.AddAfter(item, q.rateLimiter.When(item))
 }
 
@@ -67,3 +156,28 @@ func (q *rateLimitingType) NumRequeues(item interface{}) int {
 func (q *rateLimitingType) Forget(item interface{}) {
 	q.rateLimiter.Forget(item)
 }
+
+func (q *rateLimitingType) ForgetAndDrop(item interface{}) error {
+	q.dropLock.Lock()
+	defer q.dropLock.Unlock()
+	q.rateLimiter.Forget(item)
+	queue, ok := q.DelayingInterface.(dropper)
+	if !ok {
+		return ErrDropUnsupported
+	}
+	queue.drop(item)
+	return nil
+}
+
+func (q *rateLimitingType) ExportFailures() map[interface{}]int {
+	if stateful, ok := q.rateLimiter.(StatefulRateLimiter); ok {
+		return stateful.ExportFailures()
+	}
+	return map[interface{}]int{}
+}
+
+func (q *rateLimitingType) ImportFailures(failures map[interface{}]int) {
+	if stateful, ok := q.rateLimiter.(StatefulRateLimiter); ok {
+		stateful.ImportFailures(failures)
+	}
+}
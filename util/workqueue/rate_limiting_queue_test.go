@@ -33,7 +33,7 @@ func TestRateLimitingQueue(t *testing.T) {
 		heartbeat:       fakeClock.NewTicker(maxWait),
 		stopCh:          make(chan struct{}),
 		waitingForAddCh: make(chan *waitFor, 1000),
-		metrics:         newRetryMetrics(""),
+		metrics:         newRetryMetrics("", nil),
 	}
 	queue.DelayingInterface = delayingQueue
 
@@ -73,3 +73,92 @@ func TestRateLimitingQueue(t *testing.T) {
 	}
 
 }
+
+func TestRateLimitingQueueForgetAndDrop(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	queue := NewRateLimitingQueueWithConfig(NewItemExponentialFailureRateLimiter(time.Millisecond, time.Second), RateLimitingQueueConfig{
+		Clock: fakeClock,
+	})
+	defer queue.ShutDown()
+
+	queue.AddRateLimited("waiting")
+	queue.Add("queued")
+	queue.Add("processing")
+	if item, _ := queue.Get(); item != "queued" {
+		t.Fatalf("expected queued, got %v", item)
+	}
+	queue.Done("queued")
+	queue.Add("queued")
+	if item, _ := queue.Get(); item != "processing" {
+		t.Fatalf("expected processing, got %v", item)
+	}
+	queue.Add("processing")
+	queue.Add("other")
+
+	for _, item := range []string{"waiting", "queued", "processing"} {
+		if err := queue.(DroppingInterface).ForgetAndDrop(item); err != nil {
+			t.Errorf("unexpected error dropping %s: %v", item, err)
+		}
+	}
+	if requeues := queue.NumRequeues("waiting"); requeues != 0 {
+		t.Errorf("expected waiting to be forgotten, got %d requeues", requeues)
+	}
+	if length := queue.Len(); length != 1 {
+		t.Errorf("expected only other to remain, got %d items", length)
+	}
+	queue.Done("processing")
+	fakeClock.Step(time.Second)
+	time.Sleep(50 * time.Millisecond)
+	if length := queue.Len(); length != 1 {
+		t.Errorf("expected only other to remain, got %d items", length)
+	}
+	if item, _ := queue.Get(); item != "other" {
+		t.Errorf("expected other, got %v", item)
+	}
+
+	// items added again after being dropped are processed
+	queue.(DroppingInterface).ForgetAndDrop("queued")
+	queue.Add("queued")
+	if length := queue.Len(); length != 1 {
+		t.Errorf("expected queued to be added, got %d items", length)
+	}
+}
+
+func TestRateLimitingQueueForgetAndDropSteppable(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	queue := NewRateLimitingQueueWithConfig(NewItemExponentialFailureRateLimiter(time.Millisecond, time.Second), RateLimitingQueueConfig{
+		DelayingQueue: NewSteppableDelayingQueue(fakeClock, DelayingQueueConfig{}),
+	})
+	defer queue.ShutDown()
+
+	queue.AddRateLimited("one")
+	queue.AddRateLimited("two")
+	queue.(DroppingInterface).ForgetAndDrop("one")
+	queue.(*rateLimitingType).DelayingInterface.(SteppableDelayingInterface).Step(time.Second)
+	if length := queue.Len(); length != 1 {
+		t.Fatalf("expected only two to be added, got %d items", length)
+	}
+	if item, _ := queue.Get(); item != "two" {
+		t.Errorf("expected two, got %v", item)
+	}
+}
+
+// foreignDelayingQueue is a delaying queue of another package.
+type foreignDelayingQueue struct {
+	DelayingInterface
+}
+
+func TestRateLimitingQueueForgetAndDropUnsupported(t *testing.T) {
+	queue := NewRateLimitingQueueWithConfig(NewItemExponentialFailureRateLimiter(time.Millisecond, time.Second), RateLimitingQueueConfig{
+		DelayingQueue: foreignDelayingQueue{NewDelayingQueue()},
+	})
+	defer queue.ShutDown()
+
+	queue.AddRateLimited("one")
+	if err := queue.(DroppingInterface).ForgetAndDrop("one"); err != ErrDropUnsupported {
+		t.Errorf("expected ErrDropUnsupported, got %v", err)
+	}
+	if requeues := queue.NumRequeues("one"); requeues != 0 {
+		t.Errorf("expected one to be forgotten, got %d requeues", requeues)
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workqueue
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	"k8s.io/utils/clock"
+)
+
+// SteppableClock is a clock whose time is advanced by Step, like
+// k8s.io/utils/clock/testing.FakeClock.
+type SteppableClock interface {
+	clock.WithTicker
+	// Step advances the time of the clock by d.
+	Step(d time.Duration)
+}
+
+// SteppableDelayingInterface is a DelayingInterface whose time only advances
+// with Step, for deterministic simulations of retry schedules in tests.
+type SteppableDelayingInterface interface {
+	DelayingInterface
+	// Step advances the clock of the queue by d and adds the waiting items
+	// that are ready at the new time, in the order they became ready, before
+	// returning.
+	Step(d time.Duration)
+	// NextReadyAt returns the time at which the next waiting item is ready,
+	// or false if no items are waiting.
+	NextReadyAt() (time.Time, bool)
+}
+
+// NewSteppableDelayingQueue constructs a new workqueue with delayed queuing
+// ability driven by clock. Unlike the queues of NewDelayingQueueWithConfig,
+// waiting items are only added by Step, without a background goroutine, so
+// tests can fast-forward delays without sleeping. The Clock of config is
+// replaced with clock, and the queue can be passed to
+// NewRateLimitingQueueWithConfig as its DelayingQueue.
+func NewSteppableDelayingQueue(clock SteppableClock, config DelayingQueueConfig) SteppableDelayingInterface {
+	config.Clock = clock
+	if config.Queue == nil {
+		config.Queue = NewWithConfig(QueueConfig{
+			Name:            config.Name,
+			MetricsProvider: config.MetricsProvider,
+			Clock:           config.Clock,
+			AddTracker:      config.AddTracker,
+		})
+	}
+	return &steppableDelayingType{
+		Interface:          config.Queue,
+		clock:              clock,
+		metrics:            newRetryMetrics(config.Name, config.MetricsProvider),
+		addTracker:         config.AddTracker,
+		waitingEntryByData: map[t]*waitFor{},
+	}
+}
+
+// steppableDelayingType wraps an Interface and provides delayed re-enquing
+// driven by Step.
+type steppableDelayingType struct {
+	Interface
+
+	clock      SteppableClock
+	metrics    retryMetrics
+	addTracker *AddTracker
+
+	// lock guards the waiting items
+	lock               sync.Mutex
+	waitingForQueue    waitForPriorityQueue
+	waitingEntryByData map[t]*waitFor
+}
+
+// AddAfter adds the given item to the work queue once Step advanced the clock
+// by the given delay.
+func (q *steppableDelayingType) AddAfter(item interface{}, duration time.Duration) {
+	if q.ShuttingDown() {
+		return
+	}
+
+	q.metrics.retry(item, duration)
+
+	if duration <= 0 {
+		q.Add(item)
+		return
+	}
+
+	if q.addTracker != nil {
+		q.addTracker.record(item)
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	insert(&q.waitingForQueue, q.waitingEntryByData, &waitFor{data: item, readyAt: q.clock.Now().Add(duration)})
+}
+
+func (q *steppableDelayingType) Step(d time.Duration) {
+	q.clock.Step(d)
+	now := q.clock.Now()
+
+	var ready []t
+	q.lock.Lock()
+	for q.waitingForQueue.Len() > 0 {
+		entry := q.waitingForQueue.Peek().(*waitFor)
+		if entry.readyAt.After(now) {
+			break
+		}
+		heap.Pop(&q.waitingForQueue)
+		delete(q.waitingEntryByData, entry.data)
+		ready = append(ready, entry.data)
+	}
+	q.lock.Unlock()
+
+	// add outside of the lock, the queue may block
+	for _, item := range ready {
+		if queue, ok := q.Interface.(*Type); ok {
+			// the add was recorded by AddAfter
+			queue.add(item)
+		} else {
+			q.Add(item)
+		}
+	}
+}
+
+// drop removes item from the waiting items and from the queue.
+func (q *steppableDelayingType) drop(item interface{}) {
+	q.lock.Lock()
+	remove(&q.waitingForQueue, q.waitingEntryByData, item)
+	q.lock.Unlock()
+	if queue, ok := q.Interface.(dropper); ok {
+		queue.drop(item)
+	}
+}
+
+func (q *steppableDelayingType) NextReadyAt() (time.Time, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.waitingForQueue.Len() == 0 {
+		return time.Time{}, false
+	}
+	return q.waitingForQueue.Peek().(*waitFor).readyAt, true
+}
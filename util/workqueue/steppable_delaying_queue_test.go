@@ -0,0 +1,104 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workqueue
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestSteppableDelayingQueue(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	q := NewSteppableDelayingQueue(fakeClock, DelayingQueueConfig{})
+	defer q.ShutDown()
+
+	if _, ok := q.NextReadyAt(); ok {
+		t.Error("expected no waiting items")
+	}
+	start := fakeClock.Now()
+	q.AddAfter("c", 30*time.Second)
+	q.AddAfter("a", 10*time.Second)
+	q.AddAfter("b", 20*time.Second)
+	// an earlier add moves the item forward
+	q.AddAfter("c", 15*time.Second)
+	q.AddAfter("now", 0)
+
+	if next, ok := q.NextReadyAt(); !ok || !next.Equal(start.Add(10*time.Second)) {
+		t.Errorf("unexpected next ready time %v (%t)", next, ok)
+	}
+	if q.Len() != 1 {
+		t.Fatalf("expected only the item without delay to be added, got %d items", q.Len())
+	}
+	drain := func() []interface{} {
+		var items []interface{}
+		for q.Len() > 0 {
+			item, _ := q.Get()
+			q.Done(item)
+			items = append(items, item)
+		}
+		return items
+	}
+	drain()
+
+	q.Step(9 * time.Second)
+	if q.Len() != 0 {
+		t.Errorf("expected no ready items, got %d", q.Len())
+	}
+	q.Step(7 * time.Second)
+	if items := drain(); !reflect.DeepEqual(items, []interface{}{"a", "c"}) {
+		t.Errorf("unexpected items %v", items)
+	}
+	q.Step(time.Hour)
+	if items := drain(); !reflect.DeepEqual(items, []interface{}{"b"}) {
+		t.Errorf("unexpected items %v", items)
+	}
+	if _, ok := q.NextReadyAt(); ok {
+		t.Error("expected no waiting items")
+	}
+}
+
+func TestSteppableRateLimitingQueue(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	q := NewRateLimitingQueueWithConfig(NewItemExponentialFailureRateLimiter(time.Second, time.Minute), RateLimitingQueueConfig{
+		DelayingQueue: NewSteppableDelayingQueue(fakeClock, DelayingQueueConfig{}),
+	})
+	defer q.ShutDown()
+	stepper := q.(*rateLimitingType).DelayingInterface.(SteppableDelayingInterface)
+
+	// the retries of a failing item are 1s, 2s, 4s and 8s apart
+	var retries []time.Duration
+	start := fakeClock.Now()
+	q.AddRateLimited("item")
+	for len(retries) < 4 {
+		next, ok := stepper.NextReadyAt()
+		if !ok {
+			t.Fatal("expected a waiting item")
+		}
+		stepper.Step(next.Sub(fakeClock.Now()))
+		item, _ := q.Get()
+		retries = append(retries, fakeClock.Since(start))
+		q.AddRateLimited(item)
+		q.Done(item)
+	}
+	expected := []time.Duration{time.Second, 3 * time.Second, 7 * time.Second, 15 * time.Second}
+	if !reflect.DeepEqual(retries, expected) {
+		t.Errorf("expected retries at %v, got %v", expected, retries)
+	}
+}
@@ -0,0 +1,251 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package workqueuetest provides test doubles for the metrics of work
+// queues, to check how controllers use their queues without registering
+// global collectors:
+//
+//	metrics := workqueuetest.NewMetricsProvider()
+//	queue := workqueue.NewRateLimitingQueueWithConfig(rateLimiter, workqueue.RateLimitingQueueConfig{
+//		Name:            "controller",
+//		MetricsProvider: metrics,
+//	})
+//	...
+//	err := metrics.Queue("controller").CheckRetryDelays(key, workqueuetest.ExponentialDelays(5*time.Millisecond, time.Minute, 3)...)
+package workqueuetest
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+)
+
+// MetricsProvider is a workqueue.MetricsProvider recording the metrics of
+// the queues in memory.
+type MetricsProvider struct {
+	lock   sync.Mutex
+	queues map[string]*QueueMetrics
+}
+
+var _ workqueue.MetricsProvider = &MetricsProvider{}
+
+// NewMetricsProvider returns a MetricsProvider without metrics.
+func NewMetricsProvider() *MetricsProvider {
+	return &MetricsProvider{queues: map[string]*QueueMetrics{}}
+}
+
+// Queue returns the metrics of the queue with the given name.
+func (p *MetricsProvider) Queue(name string) *QueueMetrics {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	m, ok := p.queues[name]
+	if !ok {
+		m = &QueueMetrics{retryDelays: map[interface{}][]time.Duration{}}
+		p.queues[name] = m
+	}
+	return m
+}
+
+// NewDepthMetric implements workqueue.MetricsProvider.
+func (p *MetricsProvider) NewDepthMetric(name string) workqueue.GaugeMetric {
+	m := p.Queue(name)
+	return &metric{queue: m, value: &m.depth}
+}
+
+// NewAddsMetric implements workqueue.MetricsProvider.
+func (p *MetricsProvider) NewAddsMetric(name string) workqueue.CounterMetric {
+	m := p.Queue(name)
+	return &metric{queue: m, value: &m.adds}
+}
+
+// NewLatencyMetric implements workqueue.MetricsProvider.
+func (p *MetricsProvider) NewLatencyMetric(name string) workqueue.HistogramMetric {
+	m := p.Queue(name)
+	return &metric{queue: m, observations: &m.latencies}
+}
+
+// NewWorkDurationMetric implements workqueue.MetricsProvider.
+func (p *MetricsProvider) NewWorkDurationMetric(name string) workqueue.HistogramMetric {
+	m := p.Queue(name)
+	return &metric{queue: m, observations: &m.workDurations}
+}
+
+// NewUnfinishedWorkSecondsMetric implements workqueue.MetricsProvider.
+func (p *MetricsProvider) NewUnfinishedWorkSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	m := p.Queue(name)
+	return &metric{queue: m, value: &m.unfinishedWorkSeconds}
+}
+
+// NewLongestRunningProcessorSecondsMetric implements
+// workqueue.MetricsProvider.
+func (p *MetricsProvider) NewLongestRunningProcessorSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	m := p.Queue(name)
+	return &metric{queue: m, value: &m.longestRunningProcessorSeconds}
+}
+
+// NewRetriesMetric implements workqueue.MetricsProvider.
+func (p *MetricsProvider) NewRetriesMetric(name string) workqueue.CounterMetric {
+	m := p.Queue(name)
+	return &retriesMetric{metric{queue: m, value: &m.retries}}
+}
+
+// QueueMetrics are the metrics of a queue.
+type QueueMetrics struct {
+	lock                           sync.Mutex
+	depth                          float64
+	adds                           float64
+	latencies                      []time.Duration
+	workDurations                  []time.Duration
+	unfinishedWorkSeconds          float64
+	longestRunningProcessorSeconds float64
+	retries                        float64
+	retryDelays                    map[interface{}][]time.Duration
+}
+
+// Depth returns the number of items in the queue.
+func (m *QueueMetrics) Depth() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return int(m.depth)
+}
+
+// Adds returns the number of items added to the queue.
+func (m *QueueMetrics) Adds() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return int(m.adds)
+}
+
+// Latencies returns how long the items stayed in the queue before being
+// processed, in order.
+func (m *QueueMetrics) Latencies() []time.Duration {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return append([]time.Duration(nil), m.latencies...)
+}
+
+// WorkDurations returns how long processing the items took, in order.
+func (m *QueueMetrics) WorkDurations() []time.Duration {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return append([]time.Duration(nil), m.workDurations...)
+}
+
+// UnfinishedWorkSeconds returns the last reported time spent processing the
+// items not done yet.
+func (m *QueueMetrics) UnfinishedWorkSeconds() float64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.unfinishedWorkSeconds
+}
+
+// LongestRunningProcessorSeconds returns the last reported time spent
+// processing the item processed for the longest time.
+func (m *QueueMetrics) LongestRunningProcessorSeconds() float64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.longestRunningProcessorSeconds
+}
+
+// Retries returns the number of items added to the queue after a delay.
+func (m *QueueMetrics) Retries() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return int(m.retries)
+}
+
+// RetryDelays returns the delays after which item was added to the queue, in
+// order.
+func (m *QueueMetrics) RetryDelays(item interface{}) []time.Duration {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return append([]time.Duration(nil), m.retryDelays[item]...)
+}
+
+// CheckRetryDelays returns an error describing the differences between the
+// delays after which item was added to the queue and the expected ones.
+func (m *QueueMetrics) CheckRetryDelays(item interface{}, expected ...time.Duration) error {
+	delays := m.RetryDelays(item)
+	if len(delays) == 0 && len(expected) == 0 {
+		return nil
+	}
+	if !reflect.DeepEqual(delays, expected) {
+		return fmt.Errorf("expected %v to be retried after %v, got %v", item, expected, delays)
+	}
+	return nil
+}
+
+// ExponentialDelays returns the first n delays of an item with a
+// workqueue.ItemExponentialFailureRateLimiter.
+func ExponentialDelays(base, max time.Duration, n int) []time.Duration {
+	delays := make([]time.Duration, 0, n)
+	delay := base
+	for i := 0; i < n; i++ {
+		if delay > max {
+			delay = max
+		}
+		delays = append(delays, delay)
+		delay *= 2
+	}
+	return delays
+}
+
+// metric records a value or observations of a queue.
+type metric struct {
+	queue        *QueueMetrics
+	value        *float64
+	observations *[]time.Duration
+}
+
+func (m *metric) Inc() {
+	m.queue.lock.Lock()
+	defer m.queue.lock.Unlock()
+	*m.value++
+}
+
+func (m *metric) Dec() {
+	m.queue.lock.Lock()
+	defer m.queue.lock.Unlock()
+	*m.value--
+}
+
+func (m *metric) Set(value float64) {
+	m.queue.lock.Lock()
+	defer m.queue.lock.Unlock()
+	*m.value = value
+}
+
+func (m *metric) Observe(seconds float64) {
+	m.queue.lock.Lock()
+	defer m.queue.lock.Unlock()
+	*m.observations = append(*m.observations, time.Duration(seconds*float64(time.Second)))
+}
+
+// retriesMetric also records the delays of the retried items.
+type retriesMetric struct {
+	metric
+}
+
+var _ workqueue.ItemRetryMetric = &retriesMetric{}
+
+func (m *retriesMetric) ObserveRetry(item interface{}, delay time.Duration) {
+	m.queue.lock.Lock()
+	defer m.queue.lock.Unlock()
+	m.queue.retryDelays[item] = append(m.queue.retryDelays[item], delay)
+}
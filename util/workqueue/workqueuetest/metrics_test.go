@@ -0,0 +1,85 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workqueuetest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestQueueMetrics(t *testing.T) {
+	metrics := NewMetricsProvider()
+	clock := testingclock.NewFakeClock(time.Now())
+	queue := workqueue.NewWithConfig(workqueue.QueueConfig{Name: "test", MetricsProvider: metrics, Clock: clock})
+	defer queue.ShutDown()
+	m := metrics.Queue("test")
+
+	queue.Add("a")
+	queue.Add("b")
+	if adds, depth := m.Adds(), m.Depth(); adds != 2 || depth != 2 {
+		t.Errorf("expected 2 adds and a depth of 2, got %d and %d", adds, depth)
+	}
+
+	clock.Step(time.Second)
+	item, _ := queue.Get()
+	clock.Step(2 * time.Second)
+	queue.Done(item)
+	if depth := m.Depth(); depth != 1 {
+		t.Errorf("expected a depth of 1, got %d", depth)
+	}
+	if latencies := m.Latencies(); !reflect.DeepEqual(latencies, []time.Duration{time.Second}) {
+		t.Errorf("expected a latency of 1s, got %v", latencies)
+	}
+	if durations := m.WorkDurations(); !reflect.DeepEqual(durations, []time.Duration{2 * time.Second}) {
+		t.Errorf("expected a work duration of 2s, got %v", durations)
+	}
+}
+
+func TestRetryDelays(t *testing.T) {
+	metrics := NewMetricsProvider()
+	queue := workqueue.NewRateLimitingQueueWithConfig(
+		workqueue.NewItemExponentialFailureRateLimiter(5*time.Millisecond, 12*time.Millisecond),
+		workqueue.RateLimitingQueueConfig{Name: "test", MetricsProvider: metrics, Clock: testingclock.NewFakeClock(time.Now())},
+	)
+	defer queue.ShutDown()
+
+	for i := 0; i < 3; i++ {
+		queue.AddRateLimited("a")
+	}
+	queue.AddAfter("b", time.Second)
+
+	m := metrics.Queue("test")
+	if retries := m.Retries(); retries != 4 {
+		t.Errorf("expected 4 retries, got %d", retries)
+	}
+	if err := m.CheckRetryDelays("a", ExponentialDelays(5*time.Millisecond, 12*time.Millisecond, 3)...); err != nil {
+		t.Error(err)
+	}
+	if err := m.CheckRetryDelays("b", time.Second); err != nil {
+		t.Error(err)
+	}
+	if err := m.CheckRetryDelays("c"); err != nil {
+		t.Error(err)
+	}
+	if err := m.CheckRetryDelays("a", 5*time.Millisecond); err == nil {
+		t.Error("expected the retry delays to differ")
+	}
+}